@@ -77,7 +77,7 @@ func Run(ctx context.Context, options *Options) error {
 	exporterHandler := exporter.NewHandler("gems_agent", map[string]exporter.Collectorfunc{
 		"plugin":                 exporter.NewPluginCollectorFunc(c), // plugin exporter
 		"request":                exporter.NewRequestCollector(),     // http exporter
-		"cluster_component_cert": exporter.NewCertCollectorFunc(),    // cluster component cert
+		"cluster_component_cert": exporter.NewCertCollectorFunc(c),   // cluster component cert and auto issued ingress cert
 	})
 
 	if err := otel.Init(ctx, options.Otel); err != nil {
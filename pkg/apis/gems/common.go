@@ -60,6 +60,16 @@ const (
 
 	LabelGatewayType = "gateway.kubegems.io/type" // ingress-nginx
 
+	LabelNetworkPolicyRule = GroupName + "/networkpolicy-rule" // 由租户网络策略规则生成的 networkpolicy
+
 	StatusEnabled  = "enabled"
 	StatusDisabled = "disabled"
 )
+
+// 环境休眠
+const (
+	AnnotationSleepAt       = GroupName + "/sleep-at"       // 手动休眠的时间, RFC3339
+	AnnotationWakeAt        = GroupName + "/wake-at"        // 手动唤醒的时间, RFC3339
+	AnnotationSleepReplicas = GroupName + "/sleep-replicas" // 休眠前工作负载的副本数
+	AnnotationSleepSuspend  = GroupName + "/sleep-suspend"  // 休眠前 CronJob 的 suspend
+)
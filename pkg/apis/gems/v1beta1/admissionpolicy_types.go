@@ -0,0 +1,122 @@
+/*
+Copyright 2021 kubegems.io.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	AdmissionPolicyModeEnforce = "Enforce"
+	AdmissionPolicyModeWarn    = "Warn"
+	AdmissionPolicyModeAudit   = "Audit"
+
+	// 状态中保留的最近违规记录数量
+	MaxRecentAdmissionPolicyViolations = 50
+)
+
+// AdmissionPolicyMatch 策略生效的范围, 未设置的字段不做限制
+type AdmissionPolicyMatch struct {
+	// Kinds 资源类型, 如 Pod, Deployment
+	Kinds []string `json:"kinds,omitempty"`
+	// Operations 操作类型, CREATE 或者 UPDATE
+	Operations []string `json:"operations,omitempty"`
+	// Tenant 租户名称, 根据命名空间的租户标签匹配
+	Tenant string `json:"tenant,omitempty"`
+	// Project 项目名称, 根据命名空间的项目标签匹配
+	Project string `json:"project,omitempty"`
+	// Environment 环境名称, 根据命名空间的环境标签匹配
+	Environment string `json:"environment,omitempty"`
+}
+
+// AdmissionPolicySpec defines the desired state of AdmissionPolicy
+type AdmissionPolicySpec struct {
+	// Engine 规则引擎, 支持 CEL 和 Starlark
+	//+kubebuilder:validation:Enum=CEL;Starlark
+	//+kubebuilder:default=CEL
+	Engine string `json:"engine,omitempty"`
+	// Mode 违规时的处理方式, Enforce 拒绝, Warn 放行并提示, Audit 只记录
+	//+kubebuilder:validation:Enum=Enforce;Warn;Audit
+	//+kubebuilder:default=Audit
+	Mode string `json:"mode,omitempty"`
+	// Match 策略生效的范围
+	Match AdmissionPolicyMatch `json:"match,omitempty"`
+	// Rule 规则内容, CEL 为返回违规信息的表达式, Starlark 需要定义 violation(request) 函数返回违规信息
+	Rule string `json:"rule"`
+	// Message 规则没有返回违规信息时使用的提示
+	Message string `json:"message,omitempty"`
+}
+
+// AdmissionPolicyViolation 一次违规记录
+type AdmissionPolicyViolation struct {
+	Time        metav1.Time `json:"time"`
+	Operation   string      `json:"operation,omitempty"`
+	Kind        string      `json:"kind"`
+	Namespace   string      `json:"namespace,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	User        string      `json:"user,omitempty"`
+	Tenant      string      `json:"tenant,omitempty"`
+	Project     string      `json:"project,omitempty"`
+	Environment string      `json:"environment,omitempty"`
+	Mode        string      `json:"mode"`
+	Message     string      `json:"message"`
+}
+
+// AdmissionPolicyStatus defines the observed state of AdmissionPolicy
+type AdmissionPolicyStatus struct {
+	// Violations 累计违规次数
+	Violations int64 `json:"violations,omitempty"`
+	// RecentViolations 最近的违规记录, 最新的在前
+	RecentViolations []AdmissionPolicyViolation `json:"recentViolations,omitempty"`
+}
+
+//+genclient
+//+genclient:nonNamespaced
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName=apol,path=admissionpolicies
+//+kubebuilder:subresource:status
+
+// AdmissionPolicy is the Schema for the admissionpolicies API
+type AdmissionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AdmissionPolicySpec   `json:"spec,omitempty"`
+	Status AdmissionPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AdmissionPolicyList contains a list of AdmissionPolicy
+type AdmissionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AdmissionPolicy `json:"items"`
+}
+
+// AddViolation 记录违规, 只保留最近的记录
+func (s *AdmissionPolicyStatus) AddViolation(v AdmissionPolicyViolation) {
+	s.Violations++
+	s.RecentViolations = append([]AdmissionPolicyViolation{v}, s.RecentViolations...)
+	if len(s.RecentViolations) > MaxRecentAdmissionPolicyViolations {
+		s.RecentViolations = s.RecentViolations[:MaxRecentAdmissionPolicyViolations]
+	}
+}
+
+func init() {
+	SchemeBuilder.Register(&AdmissionPolicy{}, &AdmissionPolicyList{})
+}
@@ -48,12 +48,44 @@ type EnvironmentSpec struct {
 	ResourceQuotaName string `json:"resourceQuotaName,omitempty"`
 	// LimitRageName
 	LimitRageName string `json:"limitRangeName,omitempty"`
+	// SleepSchedule 定时休眠
+	SleepSchedule *SleepSchedule `json:"sleepSchedule,omitempty"`
+}
+
+// SleepSchedule 环境定时休眠, 休眠时工作负载缩容到 0, CronJob 暂停
+type SleepSchedule struct {
+	// Sleep 休眠时间, cron 表达式, 如 "0 20 * * 1-5"
+	Sleep string `json:"sleep"`
+	// Wake 唤醒时间, cron 表达式, 为空时只能手动唤醒
+	Wake string `json:"wake,omitempty"`
+	// TimeZone 时区, 如 Asia/Shanghai, 默认 UTC
+	TimeZone string `json:"timeZone,omitempty"`
+	// Suspend 暂停定时休眠, 不影响手动休眠和唤醒
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 // EnvironmentStatus defines the observed state of Environment
 type EnvironmentStatus struct {
 	// 最后更新时间
 	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Sleep 休眠状态
+	Sleep *EnvironmentSleepStatus `json:"sleep,omitempty"`
+}
+
+// EnvironmentSleepStatus 环境休眠状态
+type EnvironmentSleepStatus struct {
+	// Sleeping 是否处于休眠
+	Sleeping bool `json:"sleeping"`
+	// LastSleepTime 最后一次休眠时间
+	LastSleepTime *metav1.Time `json:"lastSleepTime,omitempty"`
+	// LastWakeTime 最后一次唤醒时间
+	LastWakeTime *metav1.Time `json:"lastWakeTime,omitempty"`
+	// NextTransitionTime 下一次定时休眠或者唤醒的时间
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty"`
+	// Freed 休眠释放的资源
+	Freed corev1.ResourceList `json:"freed,omitempty"`
+	// Message 休眠或者唤醒失败的原因
+	Message string `json:"message,omitempty"`
 }
 
 //+genclient
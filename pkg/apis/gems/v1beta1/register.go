@@ -43,6 +43,7 @@ var (
 )
 
 var (
+	SchemeAdmissionPolicy     = GroupVersion.WithKind("AdmissionPolicy")
 	SchemeTenant              = GroupVersion.WithKind("Tenant")
 	SchemeTenantResourceQuota = GroupVersion.WithKind("TenantResourceQuota")
 	SchemeTenantNetworkPolicy = GroupVersion.WithKind("TenantNetworkPolicy")
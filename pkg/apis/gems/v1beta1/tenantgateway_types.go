@@ -80,6 +80,94 @@ type TenantGatewaySpec struct {
 	// BaseDomain is a record to auto generate domain in ingress.
 	// +kubebuilder:validation:Optional
 	BaseDomain string `json:"baseDomain"`
+	// GatewayAPI 同时使用 Gateway API 提供网关, 租户可以将 HTTPRoute/GRPCRoute 挂载到该网关
+	// +kubebuilder:validation:Optional
+	// +nullable
+	GatewayAPI *GatewayAPI `json:"gatewayAPI,omitempty"`
+	// TLS 为使用该网关的 ingress 自动签发证书
+	// +kubebuilder:validation:Optional
+	// +nullable
+	TLS *GatewayTLS `json:"tls,omitempty"`
+}
+
+// GatewayTLS defines how certificates are issued for ingresses of the gateway.
+type GatewayTLS struct {
+	// Issuer 签发方式
+	// +kubebuilder:validation:Enum=ACME;CA
+	Issuer string `json:"issuer"`
+	// ACME 使用 ACME 协议签发
+	// +kubebuilder:validation:Optional
+	ACME *ACMEIssuer `json:"acme,omitempty"`
+	// CA 使用内部 CA 签发
+	// +kubebuilder:validation:Optional
+	CA *CAIssuer `json:"ca,omitempty"`
+	// RenewBefore 证书过期前多久续期, 默认 720h
+	// +kubebuilder:validation:Optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+}
+
+// ACMEIssuer defines the ACME server and challenge.
+type ACMEIssuer struct {
+	// Server ACME directory 地址
+	Server string `json:"server"`
+	// Email 注册账号使用的邮箱
+	// +kubebuilder:validation:Optional
+	Email string `json:"email,omitempty"`
+	// Challenge 验证方式
+	// +kubebuilder:validation:Enum=HTTP01;DNS01
+	Challenge string `json:"challenge"`
+	// DNS01 DNS01 验证时设置 TXT 记录的方式
+	// +kubebuilder:validation:Optional
+	DNS01 *ACMEDNS01 `json:"dns01,omitempty"`
+	// SkipTLSVerify 不校验 ACME 服务的证书, 用于本地测试的 ACME 服务
+	// +kubebuilder:validation:Optional
+	SkipTLSVerify bool `json:"skipTLSVerify,omitempty"`
+}
+
+// ACMEDNS01 defines the webhook to present DNS01 TXT records.
+type ACMEDNS01 struct {
+	// Webhook 设置和清理 TXT 记录的地址
+	Webhook string `json:"webhook"`
+	// PropagationSeconds 等待 TXT 记录生效的时间
+	// +kubebuilder:validation:Optional
+	PropagationSeconds int32 `json:"propagationSeconds,omitempty"`
+}
+
+// CAIssuer defines the internal CA.
+type CAIssuer struct {
+	// SecretName CA 证书所在的 secret, 需要位于网关所在的 namespace
+	SecretName string `json:"secretName"`
+	// Duration 证书有效期, 默认 2160h
+	// +kubebuilder:validation:Optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// AllowedDomains 允许签发证书的域名, 包含其子域名; 为空时仅允许网关的 baseDomain 及其子域名
+	// +kubebuilder:validation:Optional
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+}
+
+// GatewayAPI defines the Gateway of Gateway API for the tenant.
+type GatewayAPI struct {
+	// GatewayClassName 集群中 Gateway API 实现对应的 GatewayClass
+	GatewayClassName string `json:"gatewayClassName"`
+	// Listeners 监听端口
+	Listeners []GatewayListener `json:"listeners"`
+}
+
+// GatewayListener defines a listener of the Gateway.
+type GatewayListener struct {
+	// Name 监听名称, 路由可以通过 sectionName 选择
+	Name string `json:"name"`
+	// Port 监听端口
+	Port int32 `json:"port"`
+	// Protocol 协议
+	// +kubebuilder:validation:Enum=HTTP;HTTPS
+	Protocol string `json:"protocol"`
+	// Hostname 监听的域名, 为空时不限制
+	// +kubebuilder:validation:Optional
+	Hostname string `json:"hostname,omitempty"`
+	// TLSSecret HTTPS 使用的证书, 需要位于网关所在的 namespace
+	// +kubebuilder:validation:Optional
+	TLSSecret string `json:"tlsSecret,omitempty"`
 }
 
 // TenantGatewayStatus defines the observed state of TenantGateway
@@ -88,6 +176,8 @@ type TenantGatewayStatus struct {
 	AvailableReplicas int32 `json:"availableReplicas"`
 	// NodePort nginx service 占用的ports
 	Ports []corev1.ServicePort `json:"ports"`
+	// GatewayAddresses Gateway API 网关的地址
+	GatewayAddresses []string `json:"gatewayAddresses,omitempty"`
 }
 
 //+genclient
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	netv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -32,12 +33,48 @@ type ProjectNetworkPolicy struct {
 	Name string `json:"name,omitempty"`
 }
 
+// NetworkPolicyRule 在隔离的基础上放行的访问规则
+type NetworkPolicyRule struct {
+	// 规则名称, 租户内唯一
+	Name string `json:"name"`
+	// 规则作用的项目, 为空时作用于租户下的所有环境
+	Project string `json:"project,omitempty"`
+	// 规则作用的环境, 需要同时指定项目
+	Environment string `json:"environment,omitempty"`
+	// 允许访问的来源, 仅对开启了隔离的环境生效
+	From []NetworkPolicyRulePeer `json:"from,omitempty"`
+	// 是否隔离出站流量, 开启后作用的环境的出站流量仅允许访问同环境内、DNS 和 to 中的目标
+	EgressIsolated bool `json:"egressIsolated,omitempty"`
+	// 允许访问的目标, 需要开启 egressIsolated
+	To []NetworkPolicyRulePeer `json:"to,omitempty"`
+	// 允许的端口, 为空时允许所有端口
+	Ports []netv1.NetworkPolicyPort `json:"ports,omitempty"`
+}
+
+// NetworkPolicyRulePeer 规则的来源或者目标, tenant/project/environment、namespace、cidr、fqdn 只能选择一种
+type NetworkPolicyRulePeer struct {
+	// 租户, 可以为其他租户
+	Tenant      string `json:"tenant,omitempty"`
+	Project     string `json:"project,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	// 共享服务所在的 namespace
+	Namespace string `json:"namespace,omitempty"`
+	// 选择上面 namespace 中的 pod, 为空时为所有 pod
+	PodSelector map[string]string `json:"podSelector,omitempty"`
+	CIDR        string            `json:"cidr,omitempty"`
+	Except      []string          `json:"except,omitempty"`
+	// 域名, 仅用于出站. 控制器每 5 分钟解析一次, 只放行解析时得到的 IP,
+	// 解析结果变化后最长 5 分钟内访问会被拒绝, 不适用于 CDN 等 IP 频繁变化的域名
+	FQDN string `json:"fqdn,omitempty"`
+}
+
 // TenantNetworkPolicySpec defines the desired state of TenantNetworkPolicy
 type TenantNetworkPolicySpec struct {
 	Tenant                     string                     `json:"tenant,omitempty"`
 	TenantIsolated             bool                       `json:"tenantIsolated,omitempty"`
 	ProjectNetworkPolicies     []ProjectNetworkPolicy     `json:"projectNetworkPolicies,omitempty"`
 	EnvironmentNetworkPolicies []EnvironmentNetworkPolicy `json:"environmentNetworkPolicies,omitempty"`
+	Rules                      []NetworkPolicyRule        `json:"rules,omitempty"`
 }
 
 // TenantNetworkPolicyStatus defines the observed state of TenantNetworkPolicy
@@ -24,10 +24,32 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// ProjectResourceQuota 项目在本集群的资源限制
+type ProjectResourceQuota struct {
+	// Name 项目名称
+	Name string `json:"name"`
+	// Hard 项目在本集群的可以使用的总资源限制
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+}
+
+// ProjectResourceQuotaStatus 项目在本集群的资源统计
+type ProjectResourceQuotaStatus struct {
+	// Name 项目名称
+	Name string `json:"name"`
+	// Hard 项目在本集群的总资源限制, 未设置时只受租户限制
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+	// Allocated 已经申请了的资源
+	Allocated corev1.ResourceList `json:"allocated,omitempty"`
+	// Used 实际使用了的资源
+	Used corev1.ResourceList `json:"used,omitempty"`
+}
+
 // TenantResourceQuotaSpec defines the desired state of TenantResourceQuota
 type TenantResourceQuotaSpec struct {
 	// Hard 租户在本集群的可以使用的总资源限制
 	Hard corev1.ResourceList `json:"hard,omitempty"`
+	// Projects 租户下项目在本集群的资源限制, 总和不能超过租户的限制, 未设置的项目只受租户限制
+	Projects []ProjectResourceQuota `json:"projects,omitempty"`
 }
 
 // TenantResourceQuotaStatus defines the observed state of TenantResourceQuota
@@ -38,6 +60,8 @@ type TenantResourceQuotaStatus struct {
 	Allocated corev1.ResourceList `json:"allocated,omitempty"`
 	// Used 实际使用了的资源
 	Used corev1.ResourceList `json:"used,omitempty"`
+	// Projects 各项目的资源统计
+	Projects []ProjectResourceQuotaStatus `json:"projects,omitempty"`
 	// Deprecated: duplicate with LastUpdateTime.
 	// LastCountTime last count time
 	LastCountTime metav1.Time `json:"lastCountTime,omitempty"`
@@ -71,6 +95,26 @@ type TenantResourceQuotaList struct {
 	Items           []TenantResourceQuota `json:"items"`
 }
 
+// ProjectHard 项目在本集群的资源限制, 未设置时返回 false
+func (s *TenantResourceQuotaSpec) ProjectHard(project string) (corev1.ResourceList, bool) {
+	for _, p := range s.Projects {
+		if p.Name == project {
+			return p.Hard, true
+		}
+	}
+	return nil, false
+}
+
+// Project 项目在本集群的资源统计
+func (s *TenantResourceQuotaStatus) Project(project string) ProjectResourceQuotaStatus {
+	for _, p := range s.Projects {
+		if p.Name == project {
+			return p
+		}
+	}
+	return ProjectResourceQuotaStatus{Name: project}
+}
+
 func init() {
 	SchemeBuilder.Register(&TenantResourceQuota{}, &TenantResourceQuotaList{})
 }
@@ -20,4 +20,18 @@ const (
 	AnnotationIstioGateway  = GroupName + "/istioGateway"
 
 	LabelIngressClass = GroupName + "/ingressClass" // ingress打标签用以筛选
+
+	// 自动签发证书, ingress 设置为 "false" 时不自动添加证书
+	AnnotationTLSAuto = GroupName + "/tls-auto"
+	// 自动签发的证书 secret 标记签发的网关
+	LabelCertificateIssuer = GroupName + "/certificate-issuer"
+	// 自动签发的证书的过期时间, 续期时间和续期次数
+	AnnotationCertificateNotAfter  = GroupName + "/certificate-not-after"
+	AnnotationCertificateRenewedAt = GroupName + "/certificate-renewed-at"
+	AnnotationCertificateRenewals  = GroupName + "/certificate-renewals"
 )
+
+// AutoTLSSecretName ingress 自动签发证书的 secret
+func AutoTLSSecretName(ingress string) string {
+	return ingress + "-auto-tls"
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Gateway API 的 CRD 不一定安装在集群中, 相关资源统一使用 unstructured 操作
+const (
+	GatewayAPIGroup = "gateway.networking.k8s.io"
+
+	GatewayKind   = "Gateway"
+	HTTPRouteKind = "HTTPRoute"
+	GRPCRouteKind = "GRPCRoute"
+)
+
+var (
+	GVKGateway       = schema.GroupVersionKind{Group: GatewayAPIGroup, Version: "v1beta1", Kind: GatewayKind}
+	GVKGatewayList   = schema.GroupVersionKind{Group: GatewayAPIGroup, Version: "v1beta1", Kind: GatewayKind + "List"}
+	GVKHTTPRouteList = schema.GroupVersionKind{Group: GatewayAPIGroup, Version: "v1beta1", Kind: HTTPRouteKind + "List"}
+	GVKGRPCRouteList = schema.GroupVersionKind{Group: GatewayAPIGroup, Version: "v1alpha2", Kind: GRPCRouteKind + "List"}
+)
+
+// RouteParentRef 路由挂载的父资源
+type RouteParentRef struct {
+	Group       string
+	Kind        string
+	Namespace   string
+	Name        string
+	SectionName string
+}
+
+// IsGateway 是否挂载到指定的 Gateway
+func (ref RouteParentRef) IsGateway(namespace, name string) bool {
+	return ref.Group == GatewayAPIGroup && ref.Kind == GatewayKind && ref.Namespace == namespace && ref.Name == name
+}
+
+// RouteParentRefs 解析 HTTPRoute/GRPCRoute 的 parentRefs, 并补全默认值
+func RouteParentRefs(route *unstructured.Unstructured) []RouteParentRef {
+	refs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	ret := make([]RouteParentRef, 0, len(refs))
+	for _, item := range refs {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ref := RouteParentRef{
+			Group:       GatewayAPIGroup,
+			Kind:        GatewayKind,
+			Namespace:   route.GetNamespace(),
+			Name:        stringField(m, "name"),
+			SectionName: stringField(m, "sectionName"),
+		}
+		if _, ok := m["group"]; ok {
+			ref.Group = stringField(m, "group")
+		}
+		if kind := stringField(m, "kind"); kind != "" {
+			ref.Kind = kind
+		}
+		if ns := stringField(m, "namespace"); ns != "" {
+			ref.Namespace = ns
+		}
+		ret = append(ret, ref)
+	}
+	return ret
+}
+
+// RouteHostnames HTTPRoute/GRPCRoute 的域名
+func RouteHostnames(route *unstructured.Unstructured) []string {
+	hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	return hostnames
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
@@ -109,7 +109,10 @@ func (h *ApplicationHandler) StrategyDeploymentControl(c *gin.Context) {
 				if err := h.ApplicationProcessor.Undo(ctx, ref, revison); err != nil {
 					return nil, err
 				}
-				_ = h.ApplicationProcessor.Sync(ctx, ref)
+				// 生产环境需要审批时返回错误, 避免回滚没有同步却返回成功
+				if err := h.ApplicationProcessor.Sync(ctx, ref); err != nil {
+					return nil, err
+				}
 			} else {
 				h.asyncUndo(ctx, ref, revison)
 			}
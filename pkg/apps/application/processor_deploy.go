@@ -17,6 +17,7 @@ package application
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -42,7 +43,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"kubegems.io/kubegems/pkg/i18n"
 	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/approval"
+	"kubegems.io/kubegems/pkg/service/models"
 	"kubegems.io/kubegems/pkg/utils/agents"
 	"kubegems.io/kubegems/pkg/utils/argo"
 	"kubegems.io/kubegems/pkg/utils/database"
@@ -311,7 +315,7 @@ func (h *ApplicationProcessor) Create(ctx context.Context, ref PathRef) error {
 	}
 
 	// deploy argo app
-	if _, err := h.deployKustomizeApplication(ctx, ref, false); err != nil {
+	if _, err := h.deployKustomizeApplication(ctx, ref, false, ""); err != nil {
 		if !errors.IsAlreadyExists(err) {
 			return err
 		}
@@ -361,7 +365,8 @@ func (h *ApplicationProcessor) deployHelmApplication(ctx context.Context, ref Pa
 }
 
 // https://argo-cd.readthedocs.io/en/stable/operator-manual/declarative-setup/#declarative-setup
-func (h *ApplicationProcessor) deployKustomizeApplication(ctx context.Context, ref PathRef, sync bool) (*v1alpha1.Application, error) {
+// deployKustomizeApplication 创建或更新 argo 应用, sync 时同步到 revision, revision 为空时同步到分支的最新版本
+func (h *ApplicationProcessor) deployKustomizeApplication(ctx context.Context, ref PathRef, sync bool, revision string) (*v1alpha1.Application, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "create kustomize app")
 	defer span.Finish()
 
@@ -424,12 +429,15 @@ func (h *ApplicationProcessor) deployKustomizeApplication(ctx context.Context, r
 			},
 		}
 		if sync {
+			if revision == "" {
+				revision = app.Spec.Source.TargetRevision
+			}
 			app.Operation = &v1alpha1.Operation{
 				InitiatedBy: v1alpha1.OperationInitiator{
 					Username: AuthorFromContext(ctx).Name,
 				},
 				Sync: &v1alpha1.SyncOperation{
-					Revision:    app.Spec.Source.TargetRevision,
+					Revision:    revision,
 					SyncOptions: app.Spec.SyncPolicy.SyncOptions,
 				},
 			}
@@ -601,17 +609,49 @@ func (h *ApplicationProcessor) createArgoGitRepo(ctx context.Context, gitCloneUr
 	return existrepo, nil
 }
 
+// Sync 同步应用, 配置了生产部署审批规则时, 生产环境的同步需要使用一个已经通过的审批, 同步成功后审批才被使用
+// 审批绑定申请时应用编排的版本, 只同步该版本; 之后编排有新的提交时需要重新申请
 func (h *ApplicationProcessor) Sync(ctx context.Context, ref PathRef, resources ...v1alpha1.SyncOperationResource) error {
+	env := models.Environment{}
+	if err := h.DataBase.DB.WithContext(ctx).
+		Joins("join projects on projects.id = environments.project_id").
+		Joins("join tenants on tenants.id = projects.tenant_id").
+		Where("tenants.tenant_name = ? and projects.project_name = ? and environments.environment_name = ?", ref.Tenant, ref.Project, ref.Env).
+		Preload("Project").
+		First(&env).Error; err != nil {
+		return err
+	}
+	if env.MetaType != models.EnvironmentMetaTypeProd {
+		return h.sync(ctx, ref, "", resources...)
+	}
+	revision, err := h.Manifest.Revision(ctx, ref)
+	if err != nil {
+		return err
+	}
+	engine := &approval.Engine{DB: h.DataBase.DB}
+	err = engine.Consume(ctx, models.ApprovalKindProdDeploy, &env.Project.TenantID, env.ID, ref.Name, revision, func() error {
+		return h.sync(ctx, ref, revision, resources...)
+	})
+	switch {
+	case stderrors.Is(err, approval.ErrApprovalRequired):
+		return i18n.Errorf(ctx, "deploying application %s to production environment %s requires an approved approval", ref.Name, env.EnvironmentName)
+	case stderrors.Is(err, approval.ErrApprovalOutdated):
+		return i18n.Errorf(ctx, "application %s has changed since the approval, please apply for approval of revision %s", ref.Name, revision)
+	}
+	return err
+}
+
+func (h *ApplicationProcessor) sync(ctx context.Context, ref PathRef, revision string, resources ...v1alpha1.SyncOperationResource) error {
 	// do check in case of cluster config update
 	if _, _, _, err := h.prepareArgoApplication(ctx, ref); err != nil {
 		return err
 	}
-	if err := h.Argo.Sync(ctx, ref.FullName(), resources); err != nil {
+	if err := h.Argo.Sync(ctx, ref.FullName(), revision, resources); err != nil {
 		if !errors.IsNotFound(err) && grpcstatus.Code(err) != grpccodes.NotFound {
 			return fmt.Errorf("sync app %s: %v", ref.Name, err)
 		}
 		// if not found do a fully deploy
-		if _, err := h.deployKustomizeApplication(ctx, ref, true); err != nil {
+		if _, err := h.deployKustomizeApplication(ctx, ref, true, revision); err != nil {
 			return fmt.Errorf("deploy app %s: %w", ref.Name, err)
 		}
 		return nil
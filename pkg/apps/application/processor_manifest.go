@@ -50,6 +50,30 @@ func NewManifestProcessor(GitProvider *git.SimpleLocalProvider) (*ManifestProces
 	return &ManifestProcessor{GitProvider: GitProvider}, nil
 }
 
+var errStopHistory = errors.New("stop history")
+
+// Revision 应用编排所在路径的最新提交
+func (h *ManifestProcessor) Revision(ctx context.Context, ref PathRef) (string, error) {
+	revision := ""
+	err := h.Func(ctx, ref, Pull(), func(ctx context.Context, repository Repository) error {
+		err := repository.HistoryFunc(ctx, func(_ context.Context, commit git.Commit) error {
+			revision = commit.Hash
+			return errStopHistory
+		})
+		if err != nil && !errors.Is(err, errStopHistory) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if revision == "" {
+		return "", fmt.Errorf("application %s has no manifest commits", ref.Name)
+	}
+	return revision, nil
+}
+
 func (p *ManifestProcessor) ContentFunc(ctx context.Context, ref PathRef, fun RepositoryFileSystemFunc) error {
 	return p.Func(ctx, ref,
 		FsFunc(fun),
@@ -162,7 +162,9 @@ func Run(ctx context.Context, opts *ApplicationsOptions) error {
 
 	for _, mw := range []func(*gin.Context){
 		// authc
-		auth.NewAuthMiddleware(opts.JWT, userif, tracer).FilterFunc,
+		auth.NewAuthMiddleware(opts.JWT, db.DB(), userif, tracer).FilterFunc,
+		// 限定范围的令牌默认拒绝
+		authorization.ScopedTokenFilter,
 		// audit
 		auditInstance.Middleware(),
 	} {
@@ -19,9 +19,11 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
 	"strconv"
 	"time"
 
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	loggingv1beta1 "github.com/banzaicloud/logging-operator/pkg/sdk/logging/api/v1beta1"
 	"github.com/go-logr/logr"
 	istioclinetworkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
@@ -56,6 +58,7 @@ func init() {
 	utilruntime.Must(nginxv1beta1.SchemeBuilder.AddToScheme(scheme))
 	utilruntime.Must(istioclinetworkingv1beta1.SchemeBuilder.AddToScheme(scheme))
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	utilruntime.Must(rolloutsv1alpha1.AddToScheme(scheme))
 
 	//+kubebuilder:scaffold:scheme
 }
@@ -67,6 +70,7 @@ type Options struct {
 	EnableLeaderElection bool   `json:"enableLeaderElection,omitempty" description:"Enable leader election for controller manager."`
 	Enablewebhook        bool   `json:"enablewebhook,omitempty" description:"Enable webhook for controller manager."`
 	Repository           string `json:"repository,omitempty" description:"default image repo."`
+	ACMESolverAddr       string `json:"acmeSolverAddr,omitempty" description:"The address the acme http01 challenge solver binds to."`
 }
 
 func NewDefaultOptions() *Options {
@@ -77,6 +81,7 @@ func NewDefaultOptions() *Options {
 		EnableLeaderElection: false,
 		Enablewebhook:        true,
 		Repository:           "docker.io/kubegems/ingress-nginx-controller",
+		ACMESolverAddr:       ":8089",
 	}
 }
 
@@ -168,6 +173,21 @@ func setupControllers(mgr ctrl.Manager, options *Options, setupLog logr.Logger)
 		setupLog.Error(err, "unable to create controller", "controller", "TenantGateway")
 		return err
 	}
+	// http01 验证需要通过 pod ip 访问 controller
+	http01Solver := &gemscontroller.HTTP01Solver{Addr: options.ACMESolverAddr, PodIP: os.Getenv("POD_IP")}
+	if err := mgr.Add(http01Solver); err != nil {
+		setupLog.Error(err, "unable to add acme http01 solver")
+		return err
+	}
+	if err := (&gemscontroller.IngressCertificateReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("IngressCertificate"),
+		Recorder:     mgr.GetEventRecorderFor("IngressCertificate"),
+		HTTP01Solver: http01Solver,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IngressCertificate")
+		return err
+	}
 	if err := (&gemscontroller.EnvironmentReconciler{
 		Client: mgr.GetClient(), Scheme: mgr.GetScheme(),
 		Log:      ctrl.Log.WithName("controllers").WithName("Environment"),
@@ -217,5 +237,9 @@ func setUpWebhook(mgr ctrl.Manager, setupLog logr.Logger, repo string) error {
 	labelInjectorHandler := webhooks.GetLabelInjectorMutateHandler(&c, &labelInjectorLogger)
 	ws.Register("/label-injector", labelInjectorHandler)
 
+	policyLogger := ctrl.Log.WithName("policy-webhook")
+	policyHandler := webhooks.GetPolicyValidateHandler(&c, &policyLogger)
+	ws.Register("/policy", policyHandler)
+
 	return nil
 }
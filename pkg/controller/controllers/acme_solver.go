@@ -0,0 +1,236 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	acmeChallengePathPrefix = "/.well-known/acme-challenge/"
+	// 验证期间在 ingress 所在的 namespace 下创建, 指向 controller 的 http01 服务
+	acmeSolverServiceName = "kubegems-acme-solver"
+)
+
+// HTTP01Solver 在 controller 中提供 http-01 验证的响应
+type HTTP01Solver struct {
+	Addr  string
+	PodIP string
+
+	tokens sync.Map
+}
+
+// Start 实现 manager.Runnable
+func (s *HTTP01Solver) Start(ctx context.Context) error {
+	server := &http.Server{Addr: s.Addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *HTTP01Solver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, acmeChallengePathPrefix)
+	if value, ok := s.tokens.Load(token); ok {
+		_, _ = w.Write([]byte(value.(string)))
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (s *HTTP01Solver) port() int32 {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	p, _ := strconv.Atoi(port)
+	return int32(p)
+}
+
+// ForIngress 通过在 ingress 上临时添加验证路径完成验证,
+// 同一个域名只能由一个 ingress 处理, 不能使用单独的 ingress
+func (s *HTTP01Solver) ForIngress(cli client.Client, ingress *networkingv1.Ingress) *IngressHTTP01Solver {
+	return &IngressHTTP01Solver{solver: s, cli: cli, key: client.ObjectKeyFromObject(ingress)}
+}
+
+type IngressHTTP01Solver struct {
+	solver *HTTP01Solver
+	cli    client.Client
+	key    types.NamespacedName
+}
+
+func (s *IngressHTTP01Solver) Present(ctx context.Context, domain, token, value string) error {
+	if s.solver.PodIP == "" {
+		return fmt.Errorf("pod ip of http01 solver is unknown")
+	}
+	s.solver.tokens.Store(token, value)
+	if err := s.ensureService(ctx); err != nil {
+		return err
+	}
+	return s.updateIngress(ctx, func(ingress *networkingv1.Ingress) {
+		pathType := networkingv1.PathTypeExact
+		for i := range ingress.Spec.Rules {
+			rule := &ingress.Spec.Rules[i]
+			if rule.Host != domain {
+				continue
+			}
+			if rule.HTTP == nil {
+				rule.HTTP = &networkingv1.HTTPIngressRuleValue{}
+			}
+			rule.HTTP.Paths = append([]networkingv1.HTTPIngressPath{{
+				Path:     acmeChallengePathPrefix + token,
+				PathType: &pathType,
+				Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+					Name: acmeSolverServiceName,
+					Port: networkingv1.ServiceBackendPort{Number: 80},
+				}},
+			}}, rule.HTTP.Paths...)
+		}
+	})
+}
+
+func (s *IngressHTTP01Solver) CleanUp(ctx context.Context, domain, token string) error {
+	s.solver.tokens.Delete(token)
+	return s.updateIngress(ctx, func(ingress *networkingv1.Ingress) {
+		for i := range ingress.Spec.Rules {
+			rule := &ingress.Spec.Rules[i]
+			if rule.HTTP == nil {
+				continue
+			}
+			paths := []networkingv1.HTTPIngressPath{}
+			for _, path := range rule.HTTP.Paths {
+				if path.Path != acmeChallengePathPrefix+token {
+					paths = append(paths, path)
+				}
+			}
+			rule.HTTP.Paths = paths
+		}
+	})
+}
+
+// Close 删除验证使用的 service
+func (s *IngressHTTP01Solver) Close(ctx context.Context) {
+	for _, obj := range []client.Object{&corev1.Service{}, &corev1.Endpoints{}} {
+		obj.SetNamespace(s.key.Namespace)
+		obj.SetName(acmeSolverServiceName)
+		_ = client.IgnoreNotFound(s.cli.Delete(ctx, obj))
+	}
+}
+
+func (s *IngressHTTP01Solver) updateIngress(ctx context.Context, fn func(ingress *networkingv1.Ingress)) error {
+	ingress := &networkingv1.Ingress{}
+	if err := s.cli.Get(ctx, s.key, ingress); err != nil {
+		return err
+	}
+	fn(ingress)
+	return s.cli.Update(ctx, ingress)
+}
+
+// ensureService 使用没有 selector 的 service 指向 controller
+func (s *IngressHTTP01Solver) ensureService(ctx context.Context) error {
+	svc := &corev1.Service{}
+	svc.Namespace, svc.Name = s.key.Namespace, acmeSolverServiceName
+	if _, err := controllerutil.CreateOrUpdate(ctx, s.cli, svc, func() error {
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:       "http",
+			Port:       80,
+			TargetPort: intstr.FromInt(int(s.solver.port())),
+			Protocol:   corev1.ProtocolTCP,
+		}}
+		return nil
+	}); err != nil {
+		return err
+	}
+	ep := &corev1.Endpoints{}
+	ep.Namespace, ep.Name = s.key.Namespace, acmeSolverServiceName
+	_, err := controllerutil.CreateOrUpdate(ctx, s.cli, ep, func() error {
+		ep.Subsets = []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: s.solver.PodIP}},
+			Ports:     []corev1.EndpointPort{{Name: "http", Port: s.solver.port(), Protocol: corev1.ProtocolTCP}},
+		}}
+		return nil
+	})
+	return err
+}
+
+// WebhookDNS01Solver 调用外部的 webhook 设置 dns-01 验证的 TXT 记录
+type WebhookDNS01Solver struct {
+	Webhook     string
+	Propagation time.Duration
+}
+
+type dns01WebhookRequest struct {
+	Action string `json:"action"`
+	FQDN   string `json:"fqdn"`
+	Value  string `json:"value,omitempty"`
+}
+
+func (s *WebhookDNS01Solver) Present(ctx context.Context, domain, token, value string) error {
+	if err := s.call(ctx, dns01WebhookRequest{Action: "present", FQDN: dns01FQDN(domain), Value: value}); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(s.Propagation):
+		return nil
+	}
+}
+
+func (s *WebhookDNS01Solver) CleanUp(ctx context.Context, domain, token string) error {
+	return s.call(ctx, dns01WebhookRequest{Action: "cleanup", FQDN: dns01FQDN(domain)})
+}
+
+func (s *WebhookDNS01Solver) call(ctx context.Context, body dns01WebhookRequest) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Webhook, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("dns01 webhook %s %s: %s", body.Action, body.FQDN, resp.Status)
+	}
+	return nil
+}
+
+func dns01FQDN(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(domain, "*.") + "."
+}
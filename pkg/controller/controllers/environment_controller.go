@@ -64,6 +64,7 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		2. 创建或者更新ResourceQuota,打标签
 		3. 创建或者更新LimitRange,打标签
 		4. 创建的时候，添加finalizer;删除得时候，根据策略删除对应的ns,或者删除label
+		5. 按照定时或者手动操作休眠或唤醒环境下的工作负载
 	*/
 	log := r.Log.WithName("Environment").WithValues("Environment", req.Name)
 	var env gemsv1beta1.Environment
@@ -109,6 +110,9 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// 更新环境中的serviceaccount
 	r.handleServiceAccount(&env, nsLabel, ctx, log)
 
+	// 定时休眠和唤醒
+	requeueAfter := r.handleSleep(ctx, &env, log)
+
 	var changed bool
 	if maps.LabelChanged(env.Labels, nsLabel) {
 		env.Labels = labels.Merge(env.Labels, nsLabel)
@@ -120,10 +124,10 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	if changed {
 		r.Update(ctx, &env)
-		return ctrl.Result{}, nil
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *EnvironmentReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -0,0 +1,340 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	_ "time/tzdata" // 镜像中可能没有时区数据
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/robfig/cron/v3"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ReasonSleep = "Sleep"
+	ReasonWake  = "Wake"
+
+	// 休眠期间定期检查, 避免工作负载被其他人或者 CD 工具扩容
+	environmentSleepEnforcePeriod = 5 * time.Minute
+	// 计算最近一次定时触发时回溯的时间, 覆盖按周执行的定时
+	sleepScheduleLookback = 8 * 24 * time.Hour
+)
+
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=argoproj.io,resources=rollouts,verbs=get;list;watch;update;patch
+
+// handleSleep 按照定时和手动操作休眠或者唤醒环境, 返回下一次需要检查的间隔
+func (r *EnvironmentReconciler) handleSleep(ctx context.Context, env *gemsv1beta1.Environment, log logr.Logger) time.Duration {
+	if env.Spec.SleepSchedule == nil && env.Status.Sleep == nil &&
+		env.Annotations[gemlabels.AnnotationSleepAt] == "" && env.Annotations[gemlabels.AnnotationWakeAt] == "" {
+		return 0
+	}
+	now := time.Now()
+	status := env.Status.Sleep.DeepCopy()
+	if status == nil {
+		status = &gemsv1beta1.EnvironmentSleepStatus{}
+	}
+
+	var requeue time.Duration
+	sleeping, transition, next, err := desiredSleepState(env, now)
+	if err == nil {
+		if sleeping {
+			status.Freed, err = r.sleepNamespace(ctx, env.Spec.Namespace)
+		} else {
+			status.Freed, err = nil, r.wakeNamespace(ctx, env.Spec.Namespace)
+		}
+	}
+	if err != nil {
+		log.Error(err, "handle environment sleep")
+		r.Recorder.Eventf(env, corev1.EventTypeWarning, ReasonUnknowError, "Failed to handle sleep schedule: %v", err)
+		status.Message = err.Error()
+		requeue = environmentSleepEnforcePeriod
+	} else {
+		status.Message = ""
+		if sleeping != status.Sleeping {
+			t := metav1.NewTime(transition)
+			if sleeping {
+				status.LastSleepTime = &t
+				r.Recorder.Eventf(env, corev1.EventTypeNormal, ReasonSleep, "Environment %s is sleeping", env.Name)
+			} else {
+				status.LastWakeTime = &t
+				r.Recorder.Eventf(env, corev1.EventTypeNormal, ReasonWake, "Environment %s is awake", env.Name)
+			}
+		}
+		status.Sleeping = sleeping
+		if sleeping {
+			requeue = environmentSleepEnforcePeriod
+		}
+	}
+	status.NextTransitionTime = nil
+	if !next.IsZero() {
+		t := metav1.NewTime(next)
+		status.NextTransitionTime = &t
+		if untilNext := next.Sub(now); requeue == 0 || untilNext < requeue {
+			requeue = untilNext
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(env.Status.Sleep, status) {
+		env.Status.Sleep = status
+		if err := r.Status().Update(ctx, env); err != nil {
+			log.Error(err, "update environment sleep status")
+		}
+	}
+	return requeue
+}
+
+// desiredSleepState 定时和手动操作中最近的一次决定是否休眠, 同时返回该操作的时间及下一次定时的时间
+func desiredSleepState(env *gemsv1beta1.Environment, now time.Time) (bool, time.Time, time.Time, error) {
+	var (
+		sleeping   bool
+		transition time.Time
+		next       time.Time
+	)
+	apply := func(at time.Time, sleep bool) {
+		if !at.IsZero() && !at.After(now) && !at.Before(transition) {
+			sleeping, transition = sleep, at
+		}
+	}
+
+	if sched := env.Spec.SleepSchedule; sched != nil && !sched.Suspend {
+		loc, err := time.LoadLocation(sched.TimeZone)
+		if err != nil {
+			return false, transition, next, fmt.Errorf("invalid timezone %s: %w", sched.TimeZone, err)
+		}
+		local := now.In(loc)
+		crons := []struct {
+			spec  string
+			sleep bool
+		}{{spec: sched.Sleep, sleep: true}, {spec: sched.Wake}}
+		for _, c := range crons {
+			if c.spec == "" {
+				continue
+			}
+			schedule, err := cron.ParseStandard(c.spec)
+			if err != nil {
+				return false, transition, next, fmt.Errorf("invalid schedule %s: %w", c.spec, err)
+			}
+			apply(lastScheduleTime(schedule, local), c.sleep)
+			if n := schedule.Next(local); next.IsZero() || n.Before(next) {
+				next = n
+			}
+		}
+	}
+	// 手动操作在定时之后执行, 时间相同时以手动为准
+	for _, manual := range []struct {
+		key   string
+		sleep bool
+	}{{key: gemlabels.AnnotationSleepAt, sleep: true}, {key: gemlabels.AnnotationWakeAt}} {
+		if val := env.Annotations[manual.key]; val != "" {
+			at, err := time.Parse(time.RFC3339, val)
+			if err != nil {
+				return false, transition, next, fmt.Errorf("invalid annotation %s: %w", manual.key, err)
+			}
+			apply(at, manual.sleep)
+		}
+	}
+	return sleeping, transition, next, nil
+}
+
+func lastScheduleTime(schedule cron.Schedule, now time.Time) time.Time {
+	var last time.Time
+	for t := schedule.Next(now.Add(-sleepScheduleLookback)); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+		last = t
+	}
+	return last
+}
+
+// scalable 休眠时需要缩容的工作负载
+type scalable struct {
+	obj      client.Object
+	replicas **int32
+	template *corev1.PodSpec
+}
+
+func (r *EnvironmentReconciler) listScalables(ctx context.Context, namespace string) ([]scalable, error) {
+	ret := []scalable{}
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		ret = append(ret, scalable{obj: d, replicas: &d.Spec.Replicas, template: &d.Spec.Template.Spec})
+	}
+	statefulsets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulsets, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range statefulsets.Items {
+		s := &statefulsets.Items[i]
+		ret = append(ret, scalable{obj: s, replicas: &s.Spec.Replicas, template: &s.Spec.Template.Spec})
+	}
+	// 集群未安装 argo rollouts 时忽略
+	rollouts := &rolloutsv1alpha1.RolloutList{}
+	if err := r.List(ctx, rollouts, client.InNamespace(namespace)); err != nil {
+		if !meta.IsNoMatchError(err) && !runtime.IsNotRegisteredError(err) {
+			return nil, err
+		}
+	}
+	for i := range rollouts.Items {
+		ro := &rollouts.Items[i]
+		ret = append(ret, scalable{obj: ro, replicas: &ro.Spec.Replicas, template: &ro.Spec.Template.Spec})
+	}
+	return ret, nil
+}
+
+// sleepNamespace 工作负载缩容到 0 并在注解中记录原副本数, 暂停 CronJob, 返回释放的资源
+func (r *EnvironmentReconciler) sleepNamespace(ctx context.Context, namespace string) (corev1.ResourceList, error) {
+	scalables, err := r.listScalables(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	freed := corev1.ResourceList{}
+	for _, s := range scalables {
+		annotations := s.obj.GetAnnotations()
+		original, slept := annotations[gemlabels.AnnotationSleepReplicas]
+		current := pointer.Int32Deref(*s.replicas, 1)
+		replicas := current
+		if slept {
+			n, err := strconv.ParseInt(original, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid annotation %s on %s: %w", gemlabels.AnnotationSleepReplicas, s.obj.GetName(), err)
+			}
+			replicas = int32(n)
+		} else if current == 0 {
+			continue
+		}
+		addPodResources(freed, s.template, replicas)
+		if slept && current == 0 {
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[gemlabels.AnnotationSleepReplicas] = strconv.Itoa(int(replicas))
+		s.obj.SetAnnotations(annotations)
+		*s.replicas = pointer.Int32(0)
+		if err := r.Update(ctx, s.obj); err != nil {
+			return nil, err
+		}
+	}
+
+	cronjobs := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronjobs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range cronjobs.Items {
+		cj := &cronjobs.Items[i]
+		suspended := pointer.BoolDeref(cj.Spec.Suspend, false)
+		if _, slept := cj.Annotations[gemlabels.AnnotationSleepSuspend]; slept {
+			if suspended {
+				continue
+			}
+		} else {
+			if suspended {
+				continue
+			}
+			if cj.Annotations == nil {
+				cj.Annotations = map[string]string{}
+			}
+			cj.Annotations[gemlabels.AnnotationSleepSuspend] = strconv.FormatBool(suspended)
+		}
+		cj.Spec.Suspend = pointer.Bool(true)
+		if err := r.Update(ctx, cj); err != nil {
+			return nil, err
+		}
+	}
+	return freed, nil
+}
+
+// wakeNamespace 按注解恢复副本数和 CronJob 的 suspend
+func (r *EnvironmentReconciler) wakeNamespace(ctx context.Context, namespace string) error {
+	scalables, err := r.listScalables(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for _, s := range scalables {
+		annotations := s.obj.GetAnnotations()
+		original, slept := annotations[gemlabels.AnnotationSleepReplicas]
+		if !slept {
+			continue
+		}
+		if n, err := strconv.ParseInt(original, 10, 32); err == nil {
+			*s.replicas = pointer.Int32(int32(n))
+		}
+		delete(annotations, gemlabels.AnnotationSleepReplicas)
+		s.obj.SetAnnotations(annotations)
+		if err := r.Update(ctx, s.obj); err != nil {
+			return err
+		}
+	}
+
+	cronjobs := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronjobs, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range cronjobs.Items {
+		cj := &cronjobs.Items[i]
+		original, slept := cj.Annotations[gemlabels.AnnotationSleepSuspend]
+		if !slept {
+			continue
+		}
+		suspend, _ := strconv.ParseBool(original)
+		cj.Spec.Suspend = pointer.Bool(suspend)
+		delete(cj.Annotations, gemlabels.AnnotationSleepSuspend)
+		if err := r.Update(ctx, cj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addPodResources 累加 replicas 个 pod 的资源, 使用 ResourceQuota 中的资源名称
+func addPodResources(list corev1.ResourceList, spec *corev1.PodSpec, replicas int32) {
+	add := func(name corev1.ResourceName, q resource.Quantity) {
+		total := list[name]
+		for i := int32(0); i < replicas; i++ {
+			total.Add(q)
+		}
+		list[name] = total
+	}
+	for _, c := range spec.Containers {
+		for name, q := range c.Resources.Requests {
+			add(corev1.ResourceName("requests."+string(name)), q)
+		}
+		for name, q := range c.Resources.Limits {
+			add(corev1.ResourceName("limits."+string(name)), q)
+		}
+	}
+	add(corev1.ResourcePods, *resource.NewQuantity(1, resource.DecimalSI))
+}
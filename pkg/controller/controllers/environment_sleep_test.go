@@ -0,0 +1,145 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+)
+
+func TestDesiredSleepState(t *testing.T) {
+	// 2023-06-07 是周三
+	now := time.Date(2023, 6, 7, 22, 0, 0, 0, time.UTC)
+	weekdays := &gemsv1beta1.SleepSchedule{Sleep: "0 20 * * 1-5", Wake: "0 8 * * 1-5"}
+	tests := []struct {
+		name           string
+		schedule       *gemsv1beta1.SleepSchedule
+		annotations    map[string]string
+		now            time.Time
+		wantSleeping   bool
+		wantTransition time.Time
+		wantNext       time.Time
+		wantErr        bool
+	}{
+		{
+			name:           "night",
+			schedule:       weekdays,
+			now:            now,
+			wantSleeping:   true,
+			wantTransition: time.Date(2023, 6, 7, 20, 0, 0, 0, time.UTC),
+			wantNext:       time.Date(2023, 6, 8, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "day",
+			schedule:       weekdays,
+			now:            time.Date(2023, 6, 7, 10, 0, 0, 0, time.UTC),
+			wantTransition: time.Date(2023, 6, 7, 8, 0, 0, 0, time.UTC),
+			wantNext:       time.Date(2023, 6, 7, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "weekend",
+			schedule:       weekdays,
+			now:            time.Date(2023, 6, 10, 12, 0, 0, 0, time.UTC),
+			wantSleeping:   true,
+			wantTransition: time.Date(2023, 6, 9, 20, 0, 0, 0, time.UTC),
+			wantNext:       time.Date(2023, 6, 12, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "timezone",
+			schedule:       &gemsv1beta1.SleepSchedule{Sleep: "0 20 * * *", Wake: "0 8 * * *", TimeZone: "Asia/Shanghai"},
+			now:            time.Date(2023, 6, 7, 13, 0, 0, 0, time.UTC),
+			wantSleeping:   true,
+			wantTransition: time.Date(2023, 6, 7, 12, 0, 0, 0, time.UTC),
+			wantNext:       time.Date(2023, 6, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "woken manually",
+			schedule:       weekdays,
+			annotations:    map[string]string{gemlabels.AnnotationWakeAt: "2023-06-07T21:00:00Z"},
+			now:            now,
+			wantTransition: time.Date(2023, 6, 7, 21, 0, 0, 0, time.UTC),
+			wantNext:       time.Date(2023, 6, 8, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "slept manually without schedule",
+			annotations:    map[string]string{gemlabels.AnnotationSleepAt: "2023-06-07T21:00:00Z"},
+			now:            now,
+			wantSleeping:   true,
+			wantTransition: time.Date(2023, 6, 7, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "suspended",
+			schedule: &gemsv1beta1.SleepSchedule{Sleep: "0 20 * * *", Suspend: true},
+			now:      now,
+		},
+		{
+			name:     "invalid schedule",
+			schedule: &gemsv1beta1.SleepSchedule{Sleep: "every night"},
+			now:      now,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &gemsv1beta1.Environment{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       gemsv1beta1.EnvironmentSpec{SleepSchedule: tt.schedule},
+			}
+			sleeping, transition, next, err := desiredSleepState(env, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("desiredSleepState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sleeping != tt.wantSleeping {
+				t.Errorf("desiredSleepState() sleeping = %v, want %v", sleeping, tt.wantSleeping)
+			}
+			if !transition.Equal(tt.wantTransition) {
+				t.Errorf("desiredSleepState() transition = %v, want %v", transition, tt.wantTransition)
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("desiredSleepState() next = %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestAddPodResources(t *testing.T) {
+	list := corev1.ResourceList{}
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			},
+		}},
+	}
+	addPodResources(list, spec, 3)
+	addPodResources(list, spec, 1)
+	want := corev1.ResourceList{
+		corev1.ResourceRequestsCPU:  resource.MustParse("400m"),
+		corev1.ResourceLimitsMemory: resource.MustParse("1Gi"),
+		corev1.ResourcePods:         resource.MustParse("4"),
+	}
+	for name, q := range want {
+		if got := list[name]; got.Cmp(q) != 0 {
+			t.Errorf("%s = %s, want %s", name, got.String(), q.String())
+		}
+	}
+}
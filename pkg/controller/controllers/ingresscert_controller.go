@@ -0,0 +1,302 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/apis/networking"
+	"kubegems.io/kubegems/pkg/utils/certificate"
+	"kubegems.io/kubegems/pkg/utils/maps"
+)
+
+const (
+	defaultCertRenewBefore = 30 * 24 * time.Hour
+	defaultCertDuration    = 90 * 24 * time.Hour
+	// 签发失败后重试
+	certRetryPeriod  = 5 * time.Minute
+	certIssueTimeout = 5 * time.Minute
+)
+
+// IngressCertificateReconciler 为使用了开启自动签发证书的租户网关的 ingress 签发和续期证书
+type IngressCertificateReconciler struct {
+	client.Client
+	Log          logr.Logger
+	Recorder     record.EventRecorder
+	HTTP01Solver *HTTP01Solver
+}
+
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=services;endpoints,verbs=get;list;watch;create;update;patch;delete
+
+func (r *IngressCertificateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("ingress", req.NamespacedName)
+
+	ingress := &networkingv1.Ingress{}
+	if err := r.Get(ctx, req.NamespacedName, ingress); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !ingress.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	tg, err := r.tenantGatewayOf(ctx, ingress)
+	if err != nil || tg == nil || tg.Spec.TLS == nil {
+		return ctrl.Result{}, err
+	}
+	secretName := networking.AutoTLSSecretName(ingress.Name)
+	hosts := []string{}
+	for _, tls := range ingress.Spec.TLS {
+		if tls.SecretName == secretName {
+			hosts = append(hosts, tls.Hosts...)
+		}
+	}
+	if len(hosts) == 0 {
+		return ctrl.Result{}, nil
+	}
+	renewBefore := defaultCertRenewBefore
+	if tg.Spec.TLS.RenewBefore != nil {
+		renewBefore = tg.Spec.TLS.RenewBefore.Duration
+	}
+
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: ingress.Namespace, Name: secretName}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	exist := err == nil
+	if exist {
+		// 不覆盖用户自己创建的同名 secret
+		if _, ok := secret.Labels[networking.LabelCertificateIssuer]; !ok {
+			log.Info("secret is not managed, skip issue certificate", "secret", secretName)
+			return ctrl.Result{}, nil
+		}
+		if !certificate.NeedRenew(secret.Data[corev1.TLSCertKey], hosts, renewBefore) {
+			return ctrl.Result{RequeueAfter: renewAfter(secret.Data[corev1.TLSCertKey], renewBefore)}, nil
+		}
+	}
+
+	issueCtx, cancel := context.WithTimeout(ctx, certIssueTimeout)
+	defer cancel()
+	certPEM, keyPEM, err := r.issue(issueCtx, tg, ingress, hosts)
+	if err != nil {
+		log.Error(err, "failed to issue certificate")
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonFailedCreate, "Failed to issue certificate %s: %v", secretName, err)
+		return ctrl.Result{RequeueAfter: certRetryPeriod}, nil
+	}
+	info, err := certificate.ParseCertInfo(certPEM)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: certRetryPeriod}, err
+	}
+
+	renewals := 0
+	if exist {
+		renewals, _ = strconv.Atoi(secret.Annotations[networking.AnnotationCertificateRenewals])
+		renewals++
+	}
+	secret.Name, secret.Namespace = secretName, ingress.Namespace
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{corev1.TLSCertKey: certPEM, corev1.TLSPrivateKeyKey: keyPEM}
+	secret.Labels = maps.GetLabels(ingress.Labels, gemlabels.CommonLabels)
+	secret.Labels[networking.LabelCertificateIssuer] = tg.Name
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[networking.AnnotationCertificateNotAfter] = info.NotAfter.Format(time.RFC3339)
+	secret.Annotations[networking.AnnotationCertificateRenewedAt] = time.Now().Format(time.RFC3339)
+	secret.Annotations[networking.AnnotationCertificateRenewals] = strconv.Itoa(renewals)
+	secret.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(ingress, networkingv1.SchemeGroupVersion.WithKind("Ingress"))}
+	if exist {
+		err = r.Update(ctx, secret)
+	} else {
+		err = r.Create(ctx, secret)
+	}
+	if err != nil {
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonFailedCreate, "Failed to save certificate %s: %v", secretName, err)
+		return ctrl.Result{RequeueAfter: certRetryPeriod}, err
+	}
+	r.Recorder.Eventf(ingress, corev1.EventTypeNormal, ReasonCreated, "Successfully issue certificate %s, expires at %s", secretName, info.NotAfter.Format(time.RFC3339))
+	return ctrl.Result{RequeueAfter: renewAfter(certPEM, renewBefore)}, nil
+}
+
+func (r *IngressCertificateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&networkingv1.Ingress{}).
+		Owns(&corev1.Secret{}).
+		Complete(r)
+}
+
+// renewAfter 距离需要续期的时间
+func renewAfter(certPEM []byte, renewBefore time.Duration) time.Duration {
+	info, err := certificate.ParseCertInfo(certPEM)
+	if err != nil {
+		return certRetryPeriod
+	}
+	if d := time.Until(info.NotAfter) - renewBefore; d > certRetryPeriod {
+		return d
+	}
+	return certRetryPeriod
+}
+
+func (r *IngressCertificateReconciler) tenantGatewayOf(ctx context.Context, ingress *networkingv1.Ingress) (*gemsv1beta1.TenantGateway, error) {
+	if ingress.Spec.IngressClassName == nil {
+		return nil, nil
+	}
+	tgs := &gemsv1beta1.TenantGatewayList{}
+	if err := r.List(ctx, tgs, client.MatchingLabels{networking.LabelIngressClass: *ingress.Spec.IngressClassName}); err != nil {
+		return nil, err
+	}
+	if len(tgs.Items) == 0 {
+		return nil, nil
+	}
+	return &tgs.Items[0], nil
+}
+
+func (r *IngressCertificateReconciler) issue(ctx context.Context, tg *gemsv1beta1.TenantGateway, ingress *networkingv1.Ingress, hosts []string) ([]byte, []byte, error) {
+	switch tls := tg.Spec.TLS; tls.Issuer {
+	case "CA":
+		if tls.CA == nil {
+			return nil, nil, fmt.Errorf("ca issuer of gateway %s not configured", tg.Name)
+		}
+		// 内部 CA 签发的证书不经过域名所有权校验, 只允许签发网关所属的域名
+		allowed := tls.CA.AllowedDomains
+		if len(allowed) == 0 && tg.Spec.BaseDomain != "" {
+			allowed = []string{tg.Spec.BaseDomain}
+		}
+		for _, host := range hosts {
+			if !hostInDomains(host, allowed) {
+				return nil, nil, fmt.Errorf("host %s is not allowed by ca issuer of gateway %s", host, tg.Name)
+			}
+		}
+		ca := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: gemlabels.NamespaceGateway, Name: tls.CA.SecretName}, ca); err != nil {
+			return nil, nil, err
+		}
+		duration := defaultCertDuration
+		if tls.CA.Duration != nil {
+			duration = tls.CA.Duration.Duration
+		}
+		expireAt := time.Now().Add(duration)
+		return certificate.IssueCertificate(nil, ca.Data[corev1.TLSCertKey], ca.Data[corev1.TLSPrivateKeyKey], certificate.CertOptions{
+			CommonName: hosts[0],
+			Hosts:      hosts,
+			ExpireAt:   &expireAt,
+		})
+	case "ACME":
+		if tls.ACME == nil {
+			return nil, nil, fmt.Errorf("acme issuer of gateway %s not configured", tg.Name)
+		}
+		accountKey, err := r.acmeAccountKey(ctx, tg)
+		if err != nil {
+			return nil, nil, err
+		}
+		options := certificate.ACMEOptions{
+			Server:        tls.ACME.Server,
+			Email:         tls.ACME.Email,
+			AccountKey:    accountKey,
+			SkipTLSVerify: tls.ACME.SkipTLSVerify,
+		}
+		switch tls.ACME.Challenge {
+		case "DNS01":
+			if tls.ACME.DNS01 == nil {
+				return nil, nil, fmt.Errorf("dns01 of gateway %s not configured", tg.Name)
+			}
+			options.ChallengeType = certificate.ChallengeDNS01
+			options.Solver = &WebhookDNS01Solver{
+				Webhook:     tls.ACME.DNS01.Webhook,
+				Propagation: time.Duration(tls.ACME.DNS01.PropagationSeconds) * time.Second,
+			}
+		default:
+			if r.HTTP01Solver == nil {
+				return nil, nil, fmt.Errorf("http01 solver not enabled")
+			}
+			solver := r.HTTP01Solver.ForIngress(r.Client, ingress)
+			defer solver.Close(context.Background())
+			options.ChallengeType = certificate.ChallengeHTTP01
+			options.Solver = solver
+		}
+		return certificate.IssueACMECertificate(ctx, options, hosts)
+	default:
+		return nil, nil, fmt.Errorf("unsupported certificate issuer %s", tls.Issuer)
+	}
+}
+
+// hostInDomains host 是否为 domains 中的域名或其子域名, 通配符域名 *.a.com 按 a.com 处理
+func hostInDomains(host string, domains []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(domain, "*."), "."))
+		if domain == "" {
+			continue
+		}
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// acmeAccountKey 每个网关使用单独的 ACME 账号, 私钥保存在网关所在的 namespace
+func (r *IngressCertificateReconciler) acmeAccountKey(ctx context.Context, tg *gemsv1beta1.TenantGateway) (crypto.Signer, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: gemlabels.NamespaceGateway, Name: tg.Name + "-acme-account"}
+	err := r.Get(ctx, key, secret)
+	if err == nil {
+		return certificate.ParseACMEAccountKey(secret.Data[corev1.TLSPrivateKeyKey])
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	keyPEM, err := certificate.GenerateACMEAccountKey()
+	if err != nil {
+		return nil, err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels: map[string]string{
+				gemlabels.LabelTenant:      tg.Spec.Tenant,
+				gemlabels.LabelApplication: tg.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(tg, gemsv1beta1.SchemeTenantGateway)},
+		},
+		Data: map[string][]byte{corev1.TLSPrivateKeyKey: keyPEM},
+	}
+	if err := r.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+	return certificate.ParseACMEAccountKey(keyPEM)
+}
@@ -0,0 +1,43 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import "testing"
+
+func TestHostInDomains(t *testing.T) {
+	domains := []string{"apps.example.com", "*.internal.io"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "apps.example.com", want: true},
+		{host: "web.apps.example.com", want: true},
+		{host: "*.apps.example.com", want: true},
+		{host: "API.Internal.io.", want: true},
+		{host: "example.com", want: false},
+		{host: "evilapps.example.com", want: false},
+		{host: "apps.example.com.evil.com", want: false},
+		{host: "*.example.com", want: false},
+		{host: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := hostInDomains(tt.host, domains); got != tt.want {
+			t.Errorf("hostInDomains(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+	if hostInDomains("a.example.com", nil) {
+		t.Errorf("hostInDomains() with no domains should not allow any host")
+	}
+}
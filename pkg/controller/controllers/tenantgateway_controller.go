@@ -55,13 +55,14 @@ type TenantGatewayReconciler struct {
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch;create;update;patch;delete
 
 func (r *TenantGatewayReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	/*
 		租户网关逻辑:
 		1. 检查是否删除操作
 		2. 删除需要删除本身及对应ingress与ingressClass资源
-		3. 创建与更新需要操作对应nginxIngressController资源
+		3. 创建与更新需要操作对应nginxIngressController资源, 开启 Gateway API 时同时维护对应的 Gateway
 		4. 无论什么操作都需要为tenanrGateway检查并添加finalizer字段
 	*/
 	log := r.Log.WithValues("tenantgateway", req.NamespacedName)
@@ -142,6 +143,16 @@ func (r *TenantGatewayReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		}
 	}
 
+	// Gateway API
+	addresses, err := r.handleGatewayAPI(ctx, &tg)
+	if err != nil {
+		log.Error(err, "Error sync Gateway")
+	}
+	result := ctrl.Result{}
+	if tg.Spec.GatewayAPI != nil && len(addresses) == 0 {
+		result.RequeueAfter = gatewayAddressResyncPeriod
+	}
+
 	// 最后处理status
 	svc := &corev1.Service{}
 	if err := r.Get(ctx, types.NamespacedName{
@@ -159,16 +170,18 @@ func (r *TenantGatewayReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	if !equality.Semantic.DeepEqual(svc.Spec.Ports, tg.Status.Ports) ||
-		dep.Status.AvailableReplicas != tg.Status.AvailableReplicas {
+		dep.Status.AvailableReplicas != tg.Status.AvailableReplicas ||
+		!equality.Semantic.DeepEqual(addresses, tg.Status.GatewayAddresses) {
 		tg.Status.Ports = svc.Spec.Ports
 		tg.Status.AvailableReplicas = dep.Status.AvailableReplicas
+		tg.Status.GatewayAddresses = addresses
 		if err := r.Status().Update(ctx, &tg); err != nil {
 			log.Error(err, "failed to update tenantGateway")
-			return ctrl.Result{}, nil
+			return result, nil
 		}
 		log.Info("success to update", "gateway status", tg.Status)
 	}
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 func (r *TenantGatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -0,0 +1,183 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/apis/networking"
+)
+
+const (
+	// 默认网关的租户, 允许所有 namespace 的路由挂载
+	defaultGatewayTenant = "notenant"
+	// Gateway 没有地址时等待 Gateway API 实现分配
+	gatewayAddressResyncPeriod = 30 * time.Second
+)
+
+// GatewayForTenantGateway 由 TenantGateway 生成 Gateway API 的 Gateway, 只允许租户下 namespace 的路由挂载
+func GatewayForTenantGateway(tg *gemsv1beta1.TenantGateway) *unstructured.Unstructured {
+	allowedNamespaces := map[string]interface{}{"from": "All"}
+	if tg.Spec.Tenant != defaultGatewayTenant {
+		allowedNamespaces = map[string]interface{}{
+			"from": "Selector",
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{gemlabels.LabelTenant: tg.Spec.Tenant},
+			},
+		}
+	}
+	listeners := []interface{}{}
+	for _, l := range tg.Spec.GatewayAPI.Listeners {
+		listener := map[string]interface{}{
+			"name":          l.Name,
+			"port":          int64(l.Port),
+			"protocol":      l.Protocol,
+			"allowedRoutes": map[string]interface{}{"namespaces": allowedNamespaces},
+		}
+		if l.Hostname != "" {
+			listener["hostname"] = l.Hostname
+		}
+		if l.TLSSecret != "" {
+			listener["tls"] = map[string]interface{}{
+				"mode": "Terminate",
+				"certificateRefs": []interface{}{
+					map[string]interface{}{"group": "", "kind": "Secret", "name": l.TLSSecret},
+				},
+			}
+		}
+		listeners = append(listeners, listener)
+	}
+
+	gw := &unstructured.Unstructured{}
+	gw.SetGroupVersionKind(networking.GVKGateway)
+	gw.SetName(tg.Name)
+	gw.SetNamespace(gemlabels.NamespaceGateway)
+	gw.SetLabels(map[string]string{
+		gemlabels.LabelTenant:      tg.Spec.Tenant,
+		gemlabels.LabelApplication: tg.Name,
+	})
+	gw.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(tg, gemsv1beta1.SchemeTenantGateway)})
+	gw.Object["spec"] = map[string]interface{}{
+		"gatewayClassName": tg.Spec.GatewayAPI.GatewayClassName,
+		"listeners":        listeners,
+	}
+	return gw
+}
+
+// unstructuredContains 判断 existing 中是否包含 desired 的所有字段, 忽略 apiserver 补全的默认值
+func unstructuredContains(existing, desired interface{}) bool {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		e, ok := existing.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range d {
+			if !unstructuredContains(e[k], v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		e, ok := existing.([]interface{})
+		if !ok || len(e) != len(d) {
+			return false
+		}
+		for i := range d {
+			if !unstructuredContains(e[i], d[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return fmt.Sprint(existing) == fmt.Sprint(desired)
+	}
+}
+
+func gatewayAddresses(gw *unstructured.Unstructured) []string {
+	addresses, _, _ := unstructured.NestedSlice(gw.Object, "status", "addresses")
+	ret := []string{}
+	for _, item := range addresses {
+		if m, ok := item.(map[string]interface{}); ok {
+			if value, ok := m["value"].(string); ok && value != "" {
+				ret = append(ret, value)
+			}
+		}
+	}
+	return ret
+}
+
+// handleGatewayAPI 同步 TenantGateway 对应的 Gateway, 返回 Gateway 的地址
+func (r *TenantGatewayReconciler) handleGatewayAPI(ctx context.Context, tg *gemsv1beta1.TenantGateway) ([]string, error) {
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(networking.GVKGateway)
+	err := r.Get(ctx, types.NamespacedName{Namespace: gemlabels.NamespaceGateway, Name: tg.Name}, found)
+	if err != nil && !apierrors.IsNotFound(err) {
+		if meta.IsNoMatchError(err) {
+			// 集群未安装 Gateway API
+			if tg.Spec.GatewayAPI != nil {
+				r.Recorder.Eventf(tg, corev1.EventTypeWarning, ReasonFailedCreate, "Gateway API is not installed in the cluster")
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+	exist := err == nil
+
+	if tg.Spec.GatewayAPI == nil {
+		if exist && metav1.IsControlledBy(found, tg) {
+			if err := r.Delete(ctx, found); err != nil {
+				r.Recorder.Eventf(tg, corev1.EventTypeWarning, ReasonFailedDelete, "Failed to delete Gateway %s: %v", found.GetName(), err)
+				return nil, err
+			}
+			r.Recorder.Eventf(tg, corev1.EventTypeNormal, ReasonDeleted, "Successfully delete Gateway %s", found.GetName())
+		}
+		return nil, nil
+	}
+
+	gw := GatewayForTenantGateway(tg)
+	if !exist {
+		if err := r.Create(ctx, gw); err != nil {
+			r.Recorder.Eventf(tg, corev1.EventTypeWarning, ReasonFailedCreate, "Failed to create Gateway %s: %v", gw.GetName(), err)
+			return nil, err
+		}
+		r.Recorder.Eventf(tg, corev1.EventTypeNormal, ReasonCreated, "Successfully create Gateway %s", gw.GetName())
+		return nil, nil
+	}
+	if !unstructuredContains(found.Object["spec"], gw.Object["spec"]) ||
+		found.GetLabels()[gemlabels.LabelTenant] != tg.Spec.Tenant ||
+		!metav1.IsControlledBy(found, tg) {
+		found.Object["spec"] = gw.Object["spec"]
+		found.SetLabels(gw.GetLabels())
+		found.SetOwnerReferences(gw.GetOwnerReferences())
+		if err := r.Update(ctx, found); err != nil {
+			r.Recorder.Eventf(tg, corev1.EventTypeWarning, ReasonFailedUpdate, "Failed to update Gateway %s: %v", found.GetName(), err)
+			return nil, err
+		}
+		r.Recorder.Eventf(tg, corev1.EventTypeNormal, ReasonUpdated, "Successfully update Gateway %s", found.GetName())
+	}
+	return gatewayAddresses(found), nil
+}
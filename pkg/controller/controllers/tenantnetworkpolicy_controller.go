@@ -143,10 +143,16 @@ func (r *TenantNetworkPolicyReconciler) Reconcile(ctx context.Context, req ctrl.
 		}
 	}
 
+	// 网络策略规则
+	resync := r.handleRules(ctx, &netpol, statusMap)
+
 	if !controllerutil.ContainsFinalizer(&netpol, gemlabels.FinalizerNetworkPolicy) {
 		controllerutil.AddFinalizer(&netpol, gemlabels.FinalizerNetworkPolicy)
 		r.Update(ctx, &netpol)
 	}
+	if resync {
+		return ctrl.Result{RequeueAfter: fqdnResyncPeriod}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -222,6 +228,10 @@ func (r *TenantNetworkPolicyReconciler) handleRelatedObjectList(ctx context.Cont
 	})
 	npmap := map[string]netv1.NetworkPolicy{}
 	for _, np := range nplist.Items {
+		// 规则生成的 networkpolicy 单独处理
+		if np.Name != "default" {
+			continue
+		}
 		npmap[np.Namespace] = np
 	}
 	for _, ns := range nslist.Items {
@@ -0,0 +1,218 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/utils/maps"
+)
+
+const (
+	ruleNetworkPolicyPrefix = "rule-"
+	// 域名解析的结果会变化, 有域名规则时定期重新解析
+	fqdnResyncPeriod = 5 * time.Minute
+)
+
+func RuleNetworkPolicyName(rule string) string {
+	return ruleNetworkPolicyPrefix + rule
+}
+
+// ruleMatchNamespace 规则是否作用于 namespace
+func ruleMatchNamespace(rule *gemsv1beta1.NetworkPolicyRule, nslabels map[string]string) bool {
+	if rule.Project != "" && nslabels[gemlabels.LabelProject] != rule.Project {
+		return false
+	}
+	if rule.Environment != "" && nslabels[gemlabels.LabelEnvironment] != rule.Environment {
+		return false
+	}
+	return true
+}
+
+// RuleNetworkPolicy 由规则生成 namespace 下的 networkpolicy, 没有需要生效的内容时返回空
+// 入站规则只在开启了隔离的 namespace 下生效, 否则会反过来隔离该 namespace;
+// 出站规则只在规则显式开启了出站隔离时生效
+func RuleNetworkPolicy(namespace string, rule *gemsv1beta1.NetworkPolicyRule, isolated bool, fqdns map[string][]string) *netv1.NetworkPolicy {
+	np := &netv1.NetworkPolicy{}
+	np.Name = RuleNetworkPolicyName(rule.Name)
+	np.Namespace = namespace
+	np.Spec.PodSelector = metav1.LabelSelector{}
+
+	if isolated && len(rule.From) > 0 {
+		np.Spec.PolicyTypes = append(np.Spec.PolicyTypes, netv1.PolicyTypeIngress)
+		np.Spec.Ingress = []netv1.NetworkPolicyIngressRule{{
+			From:  ruleNetworkPolicyPeers(rule.From, nil),
+			Ports: rule.Ports,
+		}}
+	}
+	if rule.EgressIsolated {
+		np.Spec.PolicyTypes = append(np.Spec.PolicyTypes, netv1.PolicyTypeEgress)
+		np.Spec.Egress = []netv1.NetworkPolicyEgressRule{
+			// 同 namespace 内
+			{To: []netv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+			// DNS
+			{Ports: dnsNetworkPolicyPorts()},
+		}
+		if peers := ruleNetworkPolicyPeers(rule.To, fqdns); len(peers) > 0 {
+			np.Spec.Egress = append(np.Spec.Egress, netv1.NetworkPolicyEgressRule{To: peers, Ports: rule.Ports})
+		}
+	}
+	if len(np.Spec.PolicyTypes) == 0 {
+		return nil
+	}
+	return np
+}
+
+func ruleNetworkPolicyPeers(peers []gemsv1beta1.NetworkPolicyRulePeer, fqdns map[string][]string) []netv1.NetworkPolicyPeer {
+	ret := []netv1.NetworkPolicyPeer{}
+	for _, peer := range peers {
+		switch {
+		case peer.CIDR != "":
+			ret = append(ret, netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{CIDR: peer.CIDR, Except: peer.Except}})
+		case peer.FQDN != "":
+			// 未解析成功的域名跳过
+			for _, ip := range fqdns[peer.FQDN] {
+				ret = append(ret, netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{CIDR: ipToCIDR(ip)}})
+			}
+		default:
+			nssel := map[string]string{}
+			if peer.Namespace != "" {
+				nssel[corev1.LabelMetadataName] = peer.Namespace
+			}
+			if peer.Tenant != "" {
+				nssel[gemlabels.LabelTenant] = peer.Tenant
+			}
+			if peer.Project != "" {
+				nssel[gemlabels.LabelProject] = peer.Project
+			}
+			if peer.Environment != "" {
+				nssel[gemlabels.LabelEnvironment] = peer.Environment
+			}
+			p := netv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: nssel}}
+			if len(peer.PodSelector) > 0 {
+				p.PodSelector = &metav1.LabelSelector{MatchLabels: peer.PodSelector}
+			}
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+func dnsNetworkPolicyPorts() []netv1.NetworkPolicyPort {
+	udp, tcp, port := corev1.ProtocolUDP, corev1.ProtocolTCP, intstr.FromInt(53)
+	return []netv1.NetworkPolicyPort{{Protocol: &udp, Port: &port}, {Protocol: &tcp, Port: &port}}
+}
+
+func ipToCIDR(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return ip + "/128"
+	}
+	return ip + "/32"
+}
+
+// resolveRuleFQDNs 解析规则中的域名, 解析失败的域名不生效
+func (r *TenantNetworkPolicyReconciler) resolveRuleFQDNs(ctx context.Context, rules []gemsv1beta1.NetworkPolicyRule) map[string][]string {
+	ret := map[string][]string{}
+	for _, rule := range rules {
+		for _, peer := range rule.To {
+			if peer.FQDN == "" {
+				continue
+			}
+			if _, ok := ret[peer.FQDN]; ok {
+				continue
+			}
+			addrs, err := net.DefaultResolver.LookupHost(ctx, peer.FQDN)
+			if err != nil {
+				r.Log.Info("failed to resolve fqdn of networkpolicy rule", "rule", rule.Name, "fqdn", peer.FQDN, "err", err.Error())
+			}
+			ret[peer.FQDN] = addrs
+		}
+	}
+	return ret
+}
+
+// handleRules 按规则同步租户下各 namespace 的 networkpolicy, 返回是否需要定期重新同步
+func (r *TenantNetworkPolicyReconciler) handleRules(ctx context.Context, netpol *gemsv1beta1.TenantNetworkPolicy, st map[string]NetworkPolicyAction) bool {
+	log := r.Log.WithValues("tenantnetworkpolicy", netpol.Name)
+	tenantSel := labels.SelectorFromSet(map[string]string{gemlabels.LabelTenant: netpol.Spec.Tenant})
+	nslist := &corev1.NamespaceList{}
+	if err := r.List(ctx, nslist, &client.ListOptions{LabelSelector: tenantSel}); err != nil {
+		log.Error(err, "failed to list namespaces")
+		return false
+	}
+	ruleReq, _ := labels.NewRequirement(gemlabels.LabelNetworkPolicyRule, selection.Exists, nil)
+	nplist := &netv1.NetworkPolicyList{}
+	if err := r.List(ctx, nplist, &client.ListOptions{LabelSelector: tenantSel.Add(*ruleReq)}); err != nil {
+		log.Error(err, "failed to list networkpolicies")
+		return false
+	}
+	existing := map[client.ObjectKey]*netv1.NetworkPolicy{}
+	for i := range nplist.Items {
+		existing[client.ObjectKeyFromObject(&nplist.Items[i])] = &nplist.Items[i]
+	}
+
+	fqdns := r.resolveRuleFQDNs(ctx, netpol.Spec.Rules)
+	for _, ns := range nslist.Items {
+		action := st[ns.Name]
+		isolated := action.TenantISO || action.ProjectISO || action.EnvironmentISO
+		for i := range netpol.Spec.Rules {
+			rule := &netpol.Spec.Rules[i]
+			if !ruleMatchNamespace(rule, ns.Labels) {
+				continue
+			}
+			np := RuleNetworkPolicy(ns.Name, rule, isolated, fqdns)
+			if np == nil {
+				continue
+			}
+			np.Labels = maps.GetLabels(ns.Labels, gemlabels.CommonLabels)
+			np.Labels[gemlabels.LabelNetworkPolicyRule] = rule.Name
+
+			key := client.ObjectKeyFromObject(np)
+			origin, exist := existing[key]
+			delete(existing, key)
+			switch {
+			case !exist:
+				if err := r.Create(ctx, np); err != nil {
+					log.Info("Error create networkpolicy " + err.Error())
+				}
+			case !equality.Semantic.DeepEqual(origin.Spec, np.Spec) || !equality.Semantic.DeepEqual(origin.Labels, np.Labels):
+				origin.Spec, origin.Labels = np.Spec, np.Labels
+				if err := r.Update(ctx, origin); err != nil {
+					log.Info("Error update networkpolicy " + err.Error())
+				}
+			}
+		}
+	}
+	// 规则删除或者不再作用的 namespace
+	for _, np := range existing {
+		if err := r.Delete(ctx, np); err != nil {
+			log.Info("Error delete networkpolicy " + err.Error())
+		}
+	}
+	return len(fqdns) > 0
+}
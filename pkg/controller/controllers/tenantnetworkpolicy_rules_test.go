@@ -0,0 +1,124 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+)
+
+func TestRuleNetworkPolicy(t *testing.T) {
+	tcp, port := corev1.ProtocolTCP, intstr.FromInt(5432)
+	ports := []netv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}}
+	from := []gemsv1beta1.NetworkPolicyRulePeer{{Tenant: "other", Project: "q"}}
+	fromPeers := []netv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+		gemlabels.LabelTenant: "other", gemlabels.LabelProject: "q",
+	}}}}
+	to := []gemsv1beta1.NetworkPolicyRulePeer{
+		{Namespace: "shared", PodSelector: map[string]string{"app": "db"}},
+		{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}},
+		{FQDN: "api.example.com"},
+		{FQDN: "unresolved.example.com"},
+	}
+	fqdns := map[string][]string{"api.example.com": {"1.2.3.4", "2001:db8::1"}}
+	baseEgress := []netv1.NetworkPolicyEgressRule{
+		{To: []netv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+		{Ports: dnsNetworkPolicyPorts()},
+	}
+
+	tests := []struct {
+		name     string
+		rule     gemsv1beta1.NetworkPolicyRule
+		isolated bool
+		want     *netv1.NetworkPolicySpec
+	}{
+		{
+			name:     "ingress in isolated namespace",
+			rule:     gemsv1beta1.NetworkPolicyRule{Name: "r", From: from, Ports: ports},
+			isolated: true,
+			want: &netv1.NetworkPolicySpec{
+				PolicyTypes: []netv1.PolicyType{netv1.PolicyTypeIngress},
+				Ingress:     []netv1.NetworkPolicyIngressRule{{From: fromPeers, Ports: ports}},
+			},
+		},
+		{
+			name: "ingress in not isolated namespace",
+			rule: gemsv1beta1.NetworkPolicyRule{Name: "r", From: from},
+		},
+		{
+			name:     "to without egress isolation",
+			rule:     gemsv1beta1.NetworkPolicyRule{Name: "r", From: from, To: to},
+			isolated: true,
+			want: &netv1.NetworkPolicySpec{
+				PolicyTypes: []netv1.PolicyType{netv1.PolicyTypeIngress},
+				Ingress:     []netv1.NetworkPolicyIngressRule{{From: fromPeers}},
+			},
+		},
+		{
+			name: "egress isolated only",
+			rule: gemsv1beta1.NetworkPolicyRule{Name: "r", EgressIsolated: true},
+			want: &netv1.NetworkPolicySpec{
+				PolicyTypes: []netv1.PolicyType{netv1.PolicyTypeEgress},
+				Egress:      baseEgress,
+			},
+		},
+		{
+			name: "egress isolated with targets",
+			rule: gemsv1beta1.NetworkPolicyRule{Name: "r", EgressIsolated: true, To: to, Ports: ports},
+			want: &netv1.NetworkPolicySpec{
+				PolicyTypes: []netv1.PolicyType{netv1.PolicyTypeEgress},
+				Egress: append(append([]netv1.NetworkPolicyEgressRule{}, baseEgress...), netv1.NetworkPolicyEgressRule{
+					To: []netv1.NetworkPolicyPeer{
+						{
+							NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: "shared"}},
+							PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+						},
+						{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}}},
+						{IPBlock: &netv1.IPBlock{CIDR: "1.2.3.4/32"}},
+						{IPBlock: &netv1.IPBlock{CIDR: "2001:db8::1/128"}},
+					},
+					Ports: ports,
+				}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RuleNetworkPolicy("ns", &tt.rule, tt.isolated, fqdns)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("RuleNetworkPolicy() = %v, want nil", got.Spec)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("RuleNetworkPolicy() = nil, want %v", tt.want)
+			}
+			if got.Name != "rule-r" || got.Namespace != "ns" {
+				t.Errorf("RuleNetworkPolicy() = %s/%s, want ns/rule-r", got.Namespace, got.Name)
+			}
+			if !equality.Semantic.DeepEqual(got.Spec, *tt.want) {
+				t.Errorf("RuleNetworkPolicy() spec = %v, want %v", got.Spec, *tt.want)
+			}
+		})
+	}
+}
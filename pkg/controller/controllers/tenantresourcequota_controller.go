@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"sort"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -75,20 +76,56 @@ func (r *TenantResourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl.
 	}
 
 	used, hard := emptyResouces.DeepCopy(), emptyResouces.DeepCopy()
+	projects := map[string]*gemsv1beta1.ProjectResourceQuotaStatus{}
+	for _, p := range rq.Spec.Projects {
+		projects[p.Name] = &gemsv1beta1.ProjectResourceQuotaStatus{
+			Name:      p.Name,
+			Hard:      p.Hard.DeepCopy(),
+			Allocated: emptyResouces.DeepCopy(),
+			Used:      emptyResouces.DeepCopy(),
+		}
+	}
 	for _, item := range resourceQuotaList.Items {
 		statistics.AddResourceList(used, item.Status.Used)
 		statistics.AddResourceList(hard, item.Status.Hard)
+
+		// 按项目汇总
+		projectName := item.Labels[gemlabels.LabelProject]
+		if projectName == "" {
+			continue
+		}
+		project, ok := projects[projectName]
+		if !ok {
+			project = &gemsv1beta1.ProjectResourceQuotaStatus{
+				Name:      projectName,
+				Allocated: emptyResouces.DeepCopy(),
+				Used:      emptyResouces.DeepCopy(),
+			}
+			projects[projectName] = project
+		}
+		statistics.AddResourceList(project.Used, item.Status.Used)
+		statistics.AddResourceList(project.Allocated, item.Status.Hard)
 	}
 	// limits.storage is invalid in resourcequota,so it dosen't appear in .status.used
 	// just set limits.storage same with requests.storage in oder have same behavior with other resources
 	hard, used = fixInvalidResourceName(hard), fixInvalidResourceName(used)
 
-	if !equality.Semantic.DeepEqual(rq.Status.Used, used) || !equality.Semantic.DeepEqual(rq.Status.Allocated, hard) {
+	projectStatus := make([]gemsv1beta1.ProjectResourceQuotaStatus, 0, len(projects))
+	for _, project := range projects {
+		project.Allocated, project.Used = fixInvalidResourceName(project.Allocated), fixInvalidResourceName(project.Used)
+		projectStatus = append(projectStatus, *project)
+	}
+	sort.Slice(projectStatus, func(i, j int) bool { return projectStatus[i].Name < projectStatus[j].Name })
+
+	if !equality.Semantic.DeepEqual(rq.Status.Used, used) ||
+		!equality.Semantic.DeepEqual(rq.Status.Allocated, hard) ||
+		!equality.Semantic.DeepEqual(rq.Status.Projects, projectStatus) {
 		log.Info("updateing status")
 		rq.Status.LastUpdateTime = metav1.Now()
 		rq.Status.Used = used
 		rq.Status.Allocated = hard // Hard is the set of enforced hard limits for each named resource.
 		rq.Status.Hard = hard      // Hard is the set of enforced hard limits for each named resource.
+		rq.Status.Projects = projectStatus
 		if err := r.Status().Update(ctx, &rq); err != nil {
 			log.Error(err, "update resource quota status")
 			return ctrl.Result{}, err
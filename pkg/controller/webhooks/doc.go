@@ -29,6 +29,11 @@ validate:
 	4. TenantGateway: 		create/update禁止创建属于不存在的租户GATEWAY，禁止无IngressClass
 	5. Environment： 		create/update 验证资源是超过限制，验证limigrange是否合法
 	6. Namespace:			delete 禁止删除/属于环境的namespace
+	7. AdmissionPolicy:		create/update 校验规则是否合法
+
+policy:
+
+	租户下的工作负载, service, ingress 在 create/update 时执行匹配的 AdmissionPolicy 规则
 */
 
 // m1
@@ -64,6 +69,12 @@ validate:
 // v6
 //+kubebuilder:webhook:verbs=create;update,path=/validate,mutating=false,failurePolicy=fail,groups=networking.istio.io,resources=gateways,versions=v1beta1,name=validate.istiogateway.dev,sideEffects=None,admissionReviewVersions=v1
 
+// v7
+//+kubebuilder:webhook:verbs=create;update,path=/validate,mutating=false,failurePolicy=fail,groups=gems.kubegems.io,resources=admissionpolicies,versions=v1beta1,name=validate.admissionpolicy.dev,sideEffects=None,admissionReviewVersions=v1
+
+// 租户下的资源，执行准入策略
+//+kubebuilder:webhook:path=/policy,mutating=false,failurePolicy=fail,groups="";apps;batch;networking.k8s.io,resources=pods;services;deployments;statefulsets;daemonsets;jobs;cronjobs;ingresses,verbs=create;update,versions=*,name=validate.policy.dev,sideEffects=None,admissionReviewVersions=v1
+
 // 所有的环境下的资源，需要注入label
 //+kubebuilder:webhook:path=/label-injector,mutating=true,failurePolicy=ignore,groups="",resources=pods;configmaps;secrets;services;daemonsets;deployments;statefulsets;jobs;cronjobs;persistentvolumeclaims,verbs=create;update,versions=*,name=mutate.label-injector.dev,sideEffects=None,admissionReviewVersions=v1
 
@@ -31,6 +31,11 @@ var (
 		Version: gemsv1beta1.GroupVersion.Version,
 		Kind:    "Tenant",
 	}
+	gkvAdmissionPolicy = metav1.GroupVersionKind{
+		Group:   gemsv1beta1.GroupVersion.Group,
+		Version: gemsv1beta1.GroupVersion.Version,
+		Kind:    "AdmissionPolicy",
+	}
 	gkvTenantResourceQuota = metav1.GroupVersionKind{
 		Group:   gemsv1beta1.GroupVersion.Group,
 		Version: gemsv1beta1.GroupVersion.Version,
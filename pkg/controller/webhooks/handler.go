@@ -82,7 +82,12 @@ func (r *ResourceValidate) Handle(ctx context.Context, req admission.Request) ad
 		return r.ValidateNamespace(ctx, req)
 	case gvkIstioGateway:
 		return r.ValidateIstioGateway(ctx, req)
+	case gkvAdmissionPolicy:
+		return r.ValidateAdmissionPolicy(ctx, req)
 	default:
+		if isGatewayRoute(req.Kind) {
+			return r.ValidateGatewayRoute(ctx, req)
+		}
 		return admission.Allowed("pass")
 	}
 }
@@ -148,6 +153,10 @@ func GetMutateHandler(client *client.Client, log *logr.Logger, repo string) *web
 	return &webhook.Admission{Handler: &ResourceMutate{Client: *client, Log: *log, Repo: repo}}
 }
 
+func GetPolicyValidateHandler(client *client.Client, log *logr.Logger) *webhook.Admission {
+	return &webhook.Admission{Handler: &PolicyValidate{Client: *client, Log: *log}}
+}
+
 func GetValidateHandler(client *client.Client, log *logr.Logger) *webhook.Admission {
 	return &webhook.Admission{Handler: &ResourceValidate{Client: *client, Log: *log}}
 }
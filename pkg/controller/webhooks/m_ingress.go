@@ -82,6 +82,11 @@ func (r *ResourceMutate) MutateIngress(ctx context.Context, req admission.Reques
 			return admission.Denied(err.Error())
 		}
 
+		// 网关开启了自动签发证书
+		if tgs.Items[0].Spec.TLS != nil && ingress.Annotations[networking.AnnotationTLSAuto] != "false" {
+			attachAutoTLS(ingress)
+		}
+
 		for j := range ingress.Spec.TLS {
 			if len(ingress.Spec.TLS[j].Hosts) == 0 {
 				return admission.Denied("ingress tls must specify at least one host")
@@ -110,6 +115,37 @@ func (r *ResourceMutate) MutateIngress(ctx context.Context, req admission.Reques
 	}
 }
 
+// attachAutoTLS 为没有配置证书的域名使用自动签发的证书, 证书由 controller 签发
+func attachAutoTLS(ingress *networkingv1.Ingress) {
+	if ingress.Name == "" {
+		return
+	}
+	autoSecret := networking.AutoTLSSecretName(ingress.Name)
+	covered := map[string]bool{}
+	tls := []networkingv1.IngressTLS{}
+	for _, t := range ingress.Spec.TLS {
+		if t.SecretName == autoSecret {
+			continue
+		}
+		tls = append(tls, t)
+		for _, host := range t.Hosts {
+			covered[host] = true
+		}
+	}
+	hosts := []string{}
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host == "" || covered[rule.Host] {
+			continue
+		}
+		covered[rule.Host] = true
+		hosts = append(hosts, rule.Host)
+	}
+	if len(hosts) > 0 {
+		tls = append(tls, networkingv1.IngressTLS{Hosts: hosts, SecretName: autoSecret})
+	}
+	ingress.Spec.TLS = tls
+}
+
 func RandHost(old string) string {
 	tmp := strings.Split(old, ".")
 	for i := range tmp {
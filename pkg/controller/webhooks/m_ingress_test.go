@@ -0,0 +1,91 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAttachAutoTLS(t *testing.T) {
+	rules := func(hosts ...string) []networkingv1.IngressRule {
+		ret := []networkingv1.IngressRule{}
+		for _, host := range hosts {
+			ret = append(ret, networkingv1.IngressRule{Host: host})
+		}
+		return ret
+	}
+	tests := []struct {
+		name    string
+		ingress *networkingv1.Ingress
+		want    []networkingv1.IngressTLS
+	}{
+		{
+			name: "all hosts",
+			ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				Spec:       networkingv1.IngressSpec{Rules: rules("a.example.com", "b.example.com", "a.example.com")},
+			},
+			want: []networkingv1.IngressTLS{{Hosts: []string{"a.example.com", "b.example.com"}, SecretName: "web-auto-tls"}},
+		},
+		{
+			name: "keep manual tls",
+			ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				Spec: networkingv1.IngressSpec{
+					Rules: rules("a.example.com", "b.example.com"),
+					TLS:   []networkingv1.IngressTLS{{Hosts: []string{"a.example.com"}, SecretName: "manual"}},
+				},
+			},
+			want: []networkingv1.IngressTLS{
+				{Hosts: []string{"a.example.com"}, SecretName: "manual"},
+				{Hosts: []string{"b.example.com"}, SecretName: "web-auto-tls"},
+			},
+		},
+		{
+			name: "host removed",
+			ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				Spec: networkingv1.IngressSpec{
+					Rules: rules("a.example.com"),
+					TLS:   []networkingv1.IngressTLS{{Hosts: []string{"a.example.com", "b.example.com"}, SecretName: "web-auto-tls"}},
+				},
+			},
+			want: []networkingv1.IngressTLS{{Hosts: []string{"a.example.com"}, SecretName: "web-auto-tls"}},
+		},
+		{
+			name: "all covered",
+			ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				Spec: networkingv1.IngressSpec{
+					Rules: rules("a.example.com"),
+					TLS:   []networkingv1.IngressTLS{{Hosts: []string{"a.example.com"}, SecretName: "manual"}},
+				},
+			},
+			want: []networkingv1.IngressTLS{{Hosts: []string{"a.example.com"}, SecretName: "manual"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachAutoTLS(tt.ingress)
+			if !reflect.DeepEqual(tt.ingress.Spec.TLS, tt.want) {
+				t.Errorf("attachAutoTLS() = %v, want %v", tt.ingress.Spec.TLS, tt.want)
+			}
+		})
+	}
+}
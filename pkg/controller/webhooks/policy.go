@@ -0,0 +1,338 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/utils/policy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	policyEvaluateTimeout = 3 * time.Second
+	// 违规记录队列的长度, 队列满时丢弃新的记录
+	policyViolationQueueSize = 1024
+	// 违规记录按策略合并后批量写入状态的间隔
+	policyViolationFlushInterval = 5 * time.Second
+)
+
+// podTemplatePaths 工作负载中 pod 模板的路径, 这些资源更新时只有 pod 模板变化才校验,
+// 避免休眠等控制器调整副本数时被存量不合规的工作负载拒绝
+var podTemplatePaths = map[string][]string{
+	"Deployment":  {"spec", "template"},
+	"StatefulSet": {"spec", "template"},
+	"DaemonSet":   {"spec", "template"},
+	"Job":         {"spec", "template"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template"},
+}
+
+// PolicyValidate 根据 AdmissionPolicy 校验租户下的资源
+type PolicyValidate struct {
+	Client client.Client
+	Log    logr.Logger
+
+	mu sync.Mutex
+	// 按策略缓存编译后的规则, generation 变化时重新编译
+	programs map[types.UID]cachedPolicyProgram
+
+	recorderOnce sync.Once
+	violations   chan policyViolation
+}
+
+type policyViolation struct {
+	policy    string
+	violation gemsv1beta1.AdmissionPolicyViolation
+}
+
+type cachedPolicyProgram struct {
+	generation int64
+	program    policy.Program
+}
+
+func (r *PolicyValidate) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Namespace == "" || len(req.Object.Raw) == 0 {
+		return admission.Allowed("pass")
+	}
+	policies := &gemsv1beta1.AdmissionPolicyList{}
+	if err := r.Client.List(ctx, policies); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	r.pruneProgramCache(policies.Items)
+	if len(policies.Items) == 0 {
+		return admission.Allowed("pass")
+	}
+	ns := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: req.Namespace}, ns); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	preq := &policy.Request{
+		Operation:   string(req.Operation),
+		Kind:        req.Kind.Kind,
+		Namespace:   req.Namespace,
+		Name:        req.Name,
+		User:        req.UserInfo.Username,
+		Tenant:      ns.Labels[gemlabels.LabelTenant],
+		Project:     ns.Labels[gemlabels.LabelProject],
+		Environment: ns.Labels[gemlabels.LabelEnvironment],
+	}
+	if err := json.Unmarshal(req.Object.Raw, &preq.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if len(req.OldObject.Raw) > 0 {
+		if err := json.Unmarshal(req.OldObject.Raw, &preq.OldObject); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	if req.Operation == admissionv1.Update && !podTemplateChanged(preq) {
+		return admission.Allowed("pod template not changed")
+	}
+	dryRun := req.DryRun != nil && *req.DryRun
+
+	denied, warnings := []string{}, []string{}
+	for _, ap := range policies.Items {
+		if !policyMatches(&ap.Spec.Match, preq) {
+			continue
+		}
+		mode := ap.Spec.Mode
+		if mode == "" {
+			mode = gemsv1beta1.AdmissionPolicyModeAudit
+		}
+		messages, err := r.evaluate(ctx, &ap, preq)
+		if err != nil {
+			// Enforce 模式下规则执行失败时拒绝, 避免通过构造资源使规则出错来绕过策略
+			r.Log.Error(err, "evaluate admission policy", "policy", ap.Name)
+			msg := fmt.Sprintf("[%s] evaluate failed: %v", ap.Name, err)
+			if mode == gemsv1beta1.AdmissionPolicyModeEnforce {
+				denied = append(denied, msg)
+			} else {
+				warnings = append(warnings, msg)
+			}
+			continue
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		for _, msg := range messages {
+			msg = fmt.Sprintf("[%s] %s", ap.Name, msg)
+			switch mode {
+			case gemsv1beta1.AdmissionPolicyModeEnforce:
+				denied = append(denied, msg)
+			case gemsv1beta1.AdmissionPolicyModeWarn:
+				warnings = append(warnings, msg)
+			}
+		}
+		if !dryRun {
+			r.enqueueViolation(ap.Name, violationOf(preq, mode, messages))
+		}
+	}
+	if len(denied) > 0 {
+		return admission.Denied(strings.Join(denied, "; ")).WithWarnings(warnings...)
+	}
+	return admission.Allowed("pass").WithWarnings(warnings...)
+}
+
+func (r *PolicyValidate) evaluate(ctx context.Context, ap *gemsv1beta1.AdmissionPolicy, req *policy.Request) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, policyEvaluateTimeout)
+	defer cancel()
+	program, err := r.program(ap)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := program.Evaluate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		if messages[i] != "" {
+			continue
+		}
+		if ap.Spec.Message != "" {
+			messages[i] = ap.Spec.Message
+		} else {
+			messages[i] = "violates admission policy"
+		}
+	}
+	return messages, nil
+}
+
+// program 返回策略编译后的规则, 编译失败的结果不缓存
+func (r *PolicyValidate) program(ap *gemsv1beta1.AdmissionPolicy) (policy.Program, error) {
+	r.mu.Lock()
+	cached, ok := r.programs[ap.UID]
+	r.mu.Unlock()
+	if ok && cached.generation == ap.Generation {
+		return cached.program, nil
+	}
+	program, err := policy.Compile(ap.Spec.Engine, ap.Spec.Rule)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	if r.programs == nil {
+		r.programs = map[types.UID]cachedPolicyProgram{}
+	}
+	r.programs[ap.UID] = cachedPolicyProgram{generation: ap.Generation, program: program}
+	r.mu.Unlock()
+	return program, nil
+}
+
+// pruneProgramCache 删除已经不存在的策略的缓存
+func (r *PolicyValidate) pruneProgramCache(policies []gemsv1beta1.AdmissionPolicy) {
+	exists := make(map[types.UID]bool, len(policies))
+	for _, ap := range policies {
+		exists[ap.UID] = true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for uid := range r.programs {
+		if !exists[uid] {
+			delete(r.programs, uid)
+		}
+	}
+}
+
+// podTemplateChanged 工作负载更新时 pod 模板是否变化, 其他资源总是返回 true
+func podTemplateChanged(req *policy.Request) bool {
+	path, ok := podTemplatePaths[req.Kind]
+	if !ok || req.OldObject == nil {
+		return true
+	}
+	newTemplate, _, _ := unstructured.NestedFieldNoCopy(req.Object, path...)
+	oldTemplate, _, _ := unstructured.NestedFieldNoCopy(req.OldObject, path...)
+	return !equality.Semantic.DeepEqual(newTemplate, oldTemplate)
+}
+
+// enqueueViolation 将违规记录放入队列, 由后台按策略合并后批量写入状态
+func (r *PolicyValidate) enqueueViolation(name string, violation gemsv1beta1.AdmissionPolicyViolation) {
+	r.recorderOnce.Do(func() {
+		r.violations = make(chan policyViolation, policyViolationQueueSize)
+		go r.runViolationRecorder()
+	})
+	select {
+	case r.violations <- policyViolation{policy: name, violation: violation}:
+	default:
+		r.Log.Info("admission policy violation queue is full, drop violation", "policy", name)
+	}
+}
+
+func (r *PolicyValidate) runViolationRecorder() {
+	ticker := time.NewTicker(policyViolationFlushInterval)
+	defer ticker.Stop()
+	// 按策略合并的违规记录, 只保留最近的记录和累计次数
+	pending := map[string]*gemsv1beta1.AdmissionPolicyStatus{}
+	for {
+		select {
+		case v := <-r.violations:
+			if pending[v.policy] == nil {
+				pending[v.policy] = &gemsv1beta1.AdmissionPolicyStatus{}
+			}
+			pending[v.policy].AddViolation(v.violation)
+		case <-ticker.C:
+			for name, batch := range pending {
+				r.recordViolations(name, batch)
+			}
+			pending = map[string]*gemsv1beta1.AdmissionPolicyStatus{}
+		}
+	}
+}
+
+func (r *PolicyValidate) recordViolations(name string, batch *gemsv1beta1.AdmissionPolicyStatus) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ap := &gemsv1beta1.AdmissionPolicy{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, ap); err != nil {
+			return err
+		}
+		mergeViolations(&ap.Status, batch)
+		return r.Client.Status().Update(ctx, ap)
+	})
+	if err != nil {
+		r.Log.Error(err, "record admission policy violations", "policy", name)
+	}
+}
+
+// mergeViolations 将合并的违规记录写入策略状态
+func mergeViolations(status *gemsv1beta1.AdmissionPolicyStatus, batch *gemsv1beta1.AdmissionPolicyStatus) {
+	// batch 中最新的在前, 按时间顺序加入
+	for i := len(batch.RecentViolations) - 1; i >= 0; i-- {
+		status.AddViolation(batch.RecentViolations[i])
+	}
+	status.Violations += batch.Violations - int64(len(batch.RecentViolations))
+}
+
+func violationOf(req *policy.Request, mode string, messages []string) gemsv1beta1.AdmissionPolicyViolation {
+	return gemsv1beta1.AdmissionPolicyViolation{
+		Time:        metav1.Now(),
+		Operation:   req.Operation,
+		Kind:        req.Kind,
+		Namespace:   req.Namespace,
+		Name:        req.Name,
+		User:        req.User,
+		Tenant:      req.Tenant,
+		Project:     req.Project,
+		Environment: req.Environment,
+		Mode:        mode,
+		Message:     strings.Join(messages, "; "),
+	}
+}
+
+// policyMatches 策略是否作用于该请求, 未设置的条件不做限制
+func policyMatches(match *gemsv1beta1.AdmissionPolicyMatch, req *policy.Request) bool {
+	if len(match.Kinds) > 0 && !containsFold(match.Kinds, req.Kind) {
+		return false
+	}
+	if len(match.Operations) > 0 && !containsFold(match.Operations, req.Operation) {
+		return false
+	}
+	if match.Tenant != "" && match.Tenant != req.Tenant {
+		return false
+	}
+	if match.Project != "" && match.Project != req.Project {
+		return false
+	}
+	if match.Environment != "" && match.Environment != req.Environment {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/utils/policy"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestPolicyMatches(t *testing.T) {
+	req := &policy.Request{
+		Operation:   "CREATE",
+		Kind:        "Deployment",
+		Tenant:      "t1",
+		Project:     "p1",
+		Environment: "e1",
+	}
+	tests := []struct {
+		name  string
+		match gemsv1beta1.AdmissionPolicyMatch
+		want  bool
+	}{
+		{name: "empty", want: true},
+		{name: "kind", match: gemsv1beta1.AdmissionPolicyMatch{Kinds: []string{"pod", "deployment"}}, want: true},
+		{name: "kind mismatch", match: gemsv1beta1.AdmissionPolicyMatch{Kinds: []string{"Pod"}}},
+		{name: "operation mismatch", match: gemsv1beta1.AdmissionPolicyMatch{Operations: []string{"UPDATE"}}},
+		{name: "tenant and project", match: gemsv1beta1.AdmissionPolicyMatch{Tenant: "t1", Project: "p1"}, want: true},
+		{name: "project mismatch", match: gemsv1beta1.AdmissionPolicyMatch{Tenant: "t1", Project: "p2"}},
+		{name: "environment mismatch", match: gemsv1beta1.AdmissionPolicyMatch{Environment: "e2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policyMatches(&tt.match, req); got != tt.want {
+				t.Errorf("policyMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddViolation(t *testing.T) {
+	status := gemsv1beta1.AdmissionPolicyStatus{}
+	for i := 0; i < gemsv1beta1.MaxRecentAdmissionPolicyViolations+5; i++ {
+		status.AddViolation(gemsv1beta1.AdmissionPolicyViolation{Name: "latest"})
+	}
+	if status.Violations != gemsv1beta1.MaxRecentAdmissionPolicyViolations+5 {
+		t.Errorf("Violations = %d", status.Violations)
+	}
+	if len(status.RecentViolations) != gemsv1beta1.MaxRecentAdmissionPolicyViolations {
+		t.Errorf("len(RecentViolations) = %d", len(status.RecentViolations))
+	}
+}
+
+func TestPolicyValidate_Handle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = gemsv1beta1.AddToScheme(scheme)
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: map[string]string{gemlabels.LabelTenant: "t1"}}}
+	newPolicy := func(name, mode, rule string) *gemsv1beta1.AdmissionPolicy {
+		return &gemsv1beta1.AdmissionPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: "uid-" + types.UID(name), Generation: 1},
+			Spec:       gemsv1beta1.AdmissionPolicySpec{Engine: policy.EngineCEL, Mode: mode, Rule: rule},
+		}
+	}
+	// object 中没有 labels, 规则执行出错
+	broken := `request.object.metadata.labels.team == ""`
+	tests := []struct {
+		name        string
+		policy      *gemsv1beta1.AdmissionPolicy
+		wantAllowed bool
+	}{
+		{name: "enforce violation", policy: newPolicy("p", gemsv1beta1.AdmissionPolicyModeEnforce, `request.tenant == "t1"`)},
+		{name: "enforce evaluate error", policy: newPolicy("p", gemsv1beta1.AdmissionPolicyModeEnforce, broken)},
+		{name: "warn evaluate error", policy: newPolicy("p", gemsv1beta1.AdmissionPolicyModeWarn, broken), wantAllowed: true},
+		{name: "enforce passed", policy: newPolicy("p", gemsv1beta1.AdmissionPolicyModeEnforce, `request.tenant == "t2"`), wantAllowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &PolicyValidate{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(ns, tt.policy).Build(),
+				Log:    log.LogrLogger,
+			}
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Namespace: "ns",
+				Kind:      metav1.GroupVersionKind{Kind: "Deployment"},
+				Object:    runtime.RawExtension{Raw: []byte(`{"kind":"Deployment","metadata":{"name":"d"}}`)},
+				DryRun:    pointer.Bool(true),
+			}}
+			if got := r.Handle(context.Background(), req); got.Allowed != tt.wantAllowed {
+				t.Errorf("Handle() allowed = %v, want %v, result %v", got.Allowed, tt.wantAllowed, got.Result)
+			}
+		})
+	}
+}
+
+func TestPolicyValidate_program(t *testing.T) {
+	r := &PolicyValidate{}
+	ap := &gemsv1beta1.AdmissionPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "p", UID: "uid", Generation: 1},
+		Spec:       gemsv1beta1.AdmissionPolicySpec{Rule: `request.kind == "Pod"`},
+	}
+	first, err := r.program(ap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached, _ := r.program(ap); cached != first {
+		t.Errorf("program() should be cached for the same generation")
+	}
+	ap.Generation, ap.Spec.Rule = 2, `request.kind == "Service"`
+	if updated, _ := r.program(ap); updated == first {
+		t.Errorf("program() should be recompiled for a new generation")
+	}
+	r.pruneProgramCache(nil)
+	if len(r.programs) != 0 {
+		t.Errorf("pruneProgramCache() left %d programs", len(r.programs))
+	}
+}
+
+func TestPodTemplateChanged(t *testing.T) {
+	deployment := func(replicas int64, image string) map[string]interface{} {
+		return map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{"name": "app", "image": image}},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		req  *policy.Request
+		want bool
+	}{
+		{name: "scale", req: &policy.Request{Kind: "Deployment", Object: deployment(0, "nginx"), OldObject: deployment(2, "nginx")}},
+		{name: "image", req: &policy.Request{Kind: "Deployment", Object: deployment(2, "nginx:1.23"), OldObject: deployment(2, "nginx")}, want: true},
+		{name: "not workload", req: &policy.Request{Kind: "Service", Object: deployment(0, "nginx"), OldObject: deployment(0, "nginx")}, want: true},
+		{name: "create", req: &policy.Request{Kind: "Deployment", Object: deployment(0, "nginx")}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podTemplateChanged(tt.req); got != tt.want {
+				t.Errorf("podTemplateChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeViolations(t *testing.T) {
+	batch := &gemsv1beta1.AdmissionPolicyStatus{}
+	for i := 0; i < gemsv1beta1.MaxRecentAdmissionPolicyViolations+5; i++ {
+		batch.AddViolation(gemsv1beta1.AdmissionPolicyViolation{Name: fmt.Sprintf("d%d", i)})
+	}
+	status := &gemsv1beta1.AdmissionPolicyStatus{Violations: 3}
+	mergeViolations(status, batch)
+	if status.Violations != gemsv1beta1.MaxRecentAdmissionPolicyViolations+8 {
+		t.Errorf("Violations = %d", status.Violations)
+	}
+	if latest := status.RecentViolations[0].Name; latest != fmt.Sprintf("d%d", gemsv1beta1.MaxRecentAdmissionPolicyViolations+4) {
+		t.Errorf("latest violation = %s", latest)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v1 "k8s.io/api/admission/v1"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/utils/policy"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func (r *ResourceValidate) ValidateAdmissionPolicy(ctx context.Context, req admission.Request) admission.Response {
+	switch req.Operation {
+	case v1.Create, v1.Update:
+		ap := &gemsv1beta1.AdmissionPolicy{}
+		if err := r.decoder.Decode(req, ap); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if _, err := policy.Compile(ap.Spec.Engine, ap.Spec.Rule); err != nil {
+			return admission.Denied(fmt.Sprintf("invalid rule: %v", err))
+		}
+		return admission.Allowed("pass")
+	default:
+		return admission.Allowed("pass")
+	}
+}
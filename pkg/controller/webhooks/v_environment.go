@@ -33,7 +33,7 @@ import (
 func (r *ResourceValidate) ValidateEnvironment(ctx context.Context, req admission.Request) admission.Response {
 	/*
 		1. 是否存在对应的租户
-		2. 资源是否够
+		2. 租户和项目的资源是否够
 		3. LimitRange是否合法
 	*/
 
@@ -66,6 +66,9 @@ func (r *ResourceValidate) ValidateEnvironment(ctx context.Context, req admissio
 		if enough, msgs := r.tenantResourceIsEnough(&tenantRq, env, &old); !enough {
 			return admission.Denied(strings.Join(msgs, ";"))
 		}
+		if enough, msgs := r.projectResourceIsEnough(&tenantRq, env, &old); !enough {
+			return admission.Denied(strings.Join(msgs, ";"))
+		}
 
 		// 3. 检查LimitRange是否合法
 		if errmsg, invalid := resourcequota.IsLimitRangeInvalid(env.Spec.LimitRage); invalid {
@@ -83,7 +86,7 @@ func (r *ResourceValidate) ValidateEnvironment(ctx context.Context, req admissio
 		if env.Spec.Tenant != old.Spec.Tenant {
 			return admission.Denied("field Tenant is immutable")
 		}
-		if !equality.Semantic.DeepEqual(env.Spec.ResourceQuota, old.Spec.ResourceQuota) {
+		if !equality.Semantic.DeepEqual(env.Spec.ResourceQuota, old.Spec.ResourceQuota) || env.Spec.Project != old.Spec.Project {
 			var tenantRq gemsv1beta1.TenantResourceQuota
 			tenantRqKey := types.NamespacedName{
 				Name: env.Spec.Tenant,
@@ -99,6 +102,9 @@ func (r *ResourceValidate) ValidateEnvironment(ctx context.Context, req admissio
 			if enough, msgs := r.tenantResourceIsEnough(&tenantRq, env, &old); !enough {
 				return admission.Denied(strings.Join(msgs, ";"))
 			}
+			if enough, msgs := r.projectResourceIsEnough(&tenantRq, env, &old); !enough {
+				return admission.Denied(strings.Join(msgs, ";"))
+			}
 		}
 		if errmsg, invalid := resourcequota.IsLimitRangeInvalid(env.Spec.LimitRage); invalid {
 			msg := fmt.Sprintf("LimitRange format error: %v", strings.Join(errmsg, ";"))
@@ -143,6 +149,27 @@ func (r *ResourceValidate) tenantResourceIsEnough(trq *gemsv1beta1.TenantResourc
 	return resourcequota.ResourceIsEnough(trq.Spec.Hard, allocated, env.Spec.ResourceQuota, ResourceKeys(resourcequota.GetDefaultTeantResourceQuota()))
 }
 
+// projectResourceIsEnough 项目设置了资源限制时, 检查项目的资源是否足够
+func (r *ResourceValidate) projectResourceIsEnough(trq *gemsv1beta1.TenantResourceQuota, env, old *gemsv1beta1.Environment) (bool, []string) {
+	hard, ok := trq.Spec.ProjectHard(env.Spec.Project)
+	if !ok {
+		return true, nil
+	}
+	project := trq.Status.Project(env.Spec.Project)
+	allocated := corev1.ResourceList{}
+	for key := range resourcequota.GetDefaultTeantResourceQuota() {
+		allocatedv := project.Allocated[key].DeepCopy()
+		// 环境移动到其他项目时, 旧的配额不属于当前项目
+		if old != nil && old.Spec.Project == env.Spec.Project {
+			if oldv, exist := old.Spec.ResourceQuota[key]; exist {
+				allocatedv.Sub(oldv)
+			}
+		}
+		allocated[key] = allocatedv
+	}
+	return resourcequota.ProjectResourceIsEnough(hard, allocated, env.Spec.ResourceQuota, ResourceKeys(resourcequota.GetDefaultTeantResourceQuota()))
+}
+
 func ResourceKeys(list corev1.ResourceList) []corev1.ResourceName {
 	keys := make([]corev1.ResourceName, 0, len(list))
 	for k := range list {
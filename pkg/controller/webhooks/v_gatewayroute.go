@@ -0,0 +1,92 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	"kubegems.io/kubegems/pkg/apis/networking"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Gateway API 的路由有多个版本, 只按 group 和 kind 匹配
+func isGatewayRoute(gvk metav1.GroupVersionKind) bool {
+	return gvk.Group == networking.GatewayAPIGroup && (gvk.Kind == networking.HTTPRouteKind || gvk.Kind == networking.GRPCRouteKind)
+}
+
+// ValidateGatewayRoute 租户的路由只能挂载到本租户或者默认的网关
+func (r *ResourceValidate) ValidateGatewayRoute(ctx context.Context, req admission.Request) admission.Response {
+	switch req.Operation {
+	case v1.Create, v1.Update:
+		route := &unstructured.Unstructured{}
+		if err := route.UnmarshalJSON(req.Object.Raw); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if route.GetNamespace() == "" {
+			route.SetNamespace(req.Namespace)
+		}
+
+		ns := &corev1.Namespace{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: route.GetNamespace()}, ns); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		routeTenant := ns.Labels[gemlabels.LabelTenant]
+
+		errmsg := []string{}
+		for _, ref := range networking.RouteParentRefs(route) {
+			if ref.Group != networking.GatewayAPIGroup || ref.Kind != networking.GatewayKind {
+				continue
+			}
+			gw := &unstructured.Unstructured{}
+			gw.SetGroupVersionKind(networking.GVKGateway)
+			if err := r.Client.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, gw); err != nil {
+				if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+					continue
+				}
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			if gatewayTenantMismatch(routeTenant, gw.GetLabels()) {
+				errmsg = append(errmsg, fmt.Sprintf("%s %s/%s can't attach to Gateway %s/%s of other tenant",
+					req.Kind.Kind, route.GetNamespace(), route.GetName(), ref.Namespace, ref.Name))
+			}
+		}
+		if len(errmsg) > 0 {
+			return admission.Denied(strings.Join(errmsg, ";"))
+		}
+		return admission.Allowed("pass")
+	default:
+		return admission.Allowed("pass")
+	}
+}
+
+// gatewayTenantMismatch 网关属于其他租户, 非 kubegems 管理的网关和默认网关不限制
+func gatewayTenantMismatch(routeTenant string, gwLabels map[string]string) bool {
+	gwTenant, ok := gwLabels[gemlabels.LabelTenant]
+	if !ok || gwTenant == notenant {
+		return false
+	}
+	return gwTenant != routeTenant
+}
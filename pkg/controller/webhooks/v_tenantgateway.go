@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	v1 "k8s.io/api/admission/v1"
@@ -66,6 +67,13 @@ func (r *ResourceValidate) ValidateTenantGateway(ctx context.Context, req admiss
 			return admission.Denied(fmt.Sprintf("Gateway baseDomain %s must has a wildcard '*'", tg.Spec.BaseDomain))
 		}
 
+		if errmsg := gatewayAPIInvalid(tg.Spec.GatewayAPI); len(errmsg) > 0 {
+			return admission.Denied(strings.Join(errmsg, ";"))
+		}
+		if errmsg := gatewayTLSInvalid(tg.Spec.TLS); len(errmsg) > 0 {
+			return admission.Denied(strings.Join(errmsg, ";"))
+		}
+
 		// 校验gateway、ingress是否同步
 		ingressList := networkingv1.IngressList{}
 		if err := r.Client.List(ctx, &ingressList, client.MatchingLabels(map[string]string{
@@ -82,3 +90,92 @@ func (r *ResourceValidate) ValidateTenantGateway(ctx context.Context, req admiss
 		return admission.Allowed("pass")
 	}
 }
+
+// gatewayAPIInvalid 校验 Gateway API 监听配置
+func gatewayAPIInvalid(gwapi *gemsv1beta1.GatewayAPI) (errmsg []string) {
+	if gwapi == nil {
+		return nil
+	}
+	if gwapi.GatewayClassName == "" {
+		errmsg = append(errmsg, "gatewayAPI.gatewayClassName is required")
+	}
+	if len(gwapi.Listeners) == 0 {
+		errmsg = append(errmsg, "gatewayAPI.listeners is required")
+	}
+	names := map[string]bool{}
+	portProtocols := map[int32]string{}
+	portHostnames := map[string]bool{}
+	for _, l := range gwapi.Listeners {
+		if errs := validation.IsDNS1123Label(l.Name); len(errs) > 0 {
+			errmsg = append(errmsg, fmt.Sprintf("listener name %s invalid: %s", l.Name, strings.Join(errs, ", ")))
+		}
+		if names[l.Name] {
+			errmsg = append(errmsg, fmt.Sprintf("listener name %s duplicated", l.Name))
+		}
+		names[l.Name] = true
+		if errs := validation.IsValidPortNum(int(l.Port)); len(errs) > 0 {
+			errmsg = append(errmsg, fmt.Sprintf("listener %s port invalid: %s", l.Name, strings.Join(errs, ", ")))
+		}
+		switch l.Protocol {
+		case "HTTP":
+			if l.TLSSecret != "" {
+				errmsg = append(errmsg, fmt.Sprintf("listener %s with protocol HTTP can't set tlsSecret", l.Name))
+			}
+		case "HTTPS":
+			if l.TLSSecret == "" {
+				errmsg = append(errmsg, fmt.Sprintf("listener %s with protocol HTTPS must set tlsSecret", l.Name))
+			}
+		default:
+			errmsg = append(errmsg, fmt.Sprintf("listener %s protocol %s not valid, must be HTTP or HTTPS", l.Name, l.Protocol))
+		}
+		// 同一个端口只能使用同一种协议, 且域名不能重复
+		if protocol, ok := portProtocols[l.Port]; ok && protocol != l.Protocol {
+			errmsg = append(errmsg, fmt.Sprintf("listener %s port %d conflicts with protocol %s", l.Name, l.Port, protocol))
+		}
+		portProtocols[l.Port] = l.Protocol
+		key := fmt.Sprintf("%d/%s", l.Port, l.Hostname)
+		if portHostnames[key] {
+			errmsg = append(errmsg, fmt.Sprintf("listener %s port %d hostname %q duplicated", l.Name, l.Port, l.Hostname))
+		}
+		portHostnames[key] = true
+	}
+	return errmsg
+}
+
+// gatewayTLSInvalid 校验自动签发证书的配置
+func gatewayTLSInvalid(tls *gemsv1beta1.GatewayTLS) (errmsg []string) {
+	if tls == nil {
+		return nil
+	}
+	switch tls.Issuer {
+	case "ACME":
+		if tls.ACME == nil {
+			return append(errmsg, "tls.acme is required when issuer is ACME")
+		}
+		if u, err := url.Parse(tls.ACME.Server); err != nil || u.Scheme != "https" {
+			errmsg = append(errmsg, fmt.Sprintf("tls.acme.server %s must be a https url", tls.ACME.Server))
+		}
+		switch tls.ACME.Challenge {
+		case "HTTP01":
+		case "DNS01":
+			if tls.ACME.DNS01 == nil || tls.ACME.DNS01.Webhook == "" {
+				errmsg = append(errmsg, "tls.acme.dns01.webhook is required when challenge is DNS01")
+			}
+		default:
+			errmsg = append(errmsg, fmt.Sprintf("tls.acme.challenge %s not valid, must be HTTP01 or DNS01", tls.ACME.Challenge))
+		}
+	case "CA":
+		if tls.CA == nil || tls.CA.SecretName == "" {
+			errmsg = append(errmsg, "tls.ca.secretName is required when issuer is CA")
+			break
+		}
+		for _, domain := range tls.CA.AllowedDomains {
+			if errs := validation.IsDNS1123Subdomain(strings.TrimPrefix(domain, "*.")); len(errs) > 0 || !strings.Contains(domain, ".") {
+				errmsg = append(errmsg, fmt.Sprintf("tls.ca.allowedDomains %s is not a valid domain", domain))
+			}
+		}
+	default:
+		errmsg = append(errmsg, fmt.Sprintf("tls.issuer %s not valid, must be ACME or CA", tls.Issuer))
+	}
+	return errmsg
+}
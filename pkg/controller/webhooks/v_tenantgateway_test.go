@@ -0,0 +1,151 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"testing"
+
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+)
+
+func TestGatewayAPIInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		gwapi       *gemsv1beta1.GatewayAPI
+		wantInvalid bool
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name: "valid",
+			gwapi: &gemsv1beta1.GatewayAPI{
+				GatewayClassName: "istio",
+				Listeners: []gemsv1beta1.GatewayListener{
+					{Name: "http", Port: 80, Protocol: "HTTP"},
+					{Name: "https", Port: 443, Protocol: "HTTPS", TLSSecret: "tls"},
+					{Name: "https-api", Port: 443, Protocol: "HTTPS", Hostname: "api.example.com", TLSSecret: "api-tls"},
+				},
+			},
+		},
+		{
+			name:        "no listeners",
+			gwapi:       &gemsv1beta1.GatewayAPI{GatewayClassName: "istio"},
+			wantInvalid: true,
+		},
+		{
+			name: "duplicated name",
+			gwapi: &gemsv1beta1.GatewayAPI{
+				GatewayClassName: "istio",
+				Listeners: []gemsv1beta1.GatewayListener{
+					{Name: "http", Port: 80, Protocol: "HTTP"},
+					{Name: "http", Port: 8080, Protocol: "HTTP"},
+				},
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "https without tls secret",
+			gwapi: &gemsv1beta1.GatewayAPI{
+				GatewayClassName: "istio",
+				Listeners:        []gemsv1beta1.GatewayListener{{Name: "https", Port: 443, Protocol: "HTTPS"}},
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "port protocol conflict",
+			gwapi: &gemsv1beta1.GatewayAPI{
+				GatewayClassName: "istio",
+				Listeners: []gemsv1beta1.GatewayListener{
+					{Name: "http", Port: 8443, Protocol: "HTTP", Hostname: "a.example.com"},
+					{Name: "https", Port: 8443, Protocol: "HTTPS", Hostname: "b.example.com", TLSSecret: "tls"},
+				},
+			},
+			wantInvalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errmsg := gatewayAPIInvalid(tt.gwapi); (len(errmsg) > 0) != tt.wantInvalid {
+				t.Errorf("gatewayAPIInvalid() = %v, wantInvalid %v", errmsg, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestGatewayTenantMismatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		routeTenant string
+		gwLabels    map[string]string
+		want        bool
+	}{
+		{name: "same tenant", routeTenant: "a", gwLabels: map[string]string{"gems.kubegems.io/tenant": "a"}},
+		{name: "default gateway", routeTenant: "a", gwLabels: map[string]string{"gems.kubegems.io/tenant": notenant}},
+		{name: "not managed", routeTenant: "a", gwLabels: map[string]string{}},
+		{name: "other tenant", routeTenant: "a", gwLabels: map[string]string{"gems.kubegems.io/tenant": "b"}, want: true},
+		{name: "route without tenant", routeTenant: "", gwLabels: map[string]string{"gems.kubegems.io/tenant": "b"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gatewayTenantMismatch(tt.routeTenant, tt.gwLabels); got != tt.want {
+				t.Errorf("gatewayTenantMismatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGatewayTLSInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		tls         *gemsv1beta1.GatewayTLS
+		wantInvalid bool
+	}{
+		{name: "disabled"},
+		{
+			name: "acme http01",
+			tls:  &gemsv1beta1.GatewayTLS{Issuer: "ACME", ACME: &gemsv1beta1.ACMEIssuer{Server: "https://acme-v02.api.letsencrypt.org/directory", Challenge: "HTTP01"}},
+		},
+		{
+			name: "acme dns01",
+			tls: &gemsv1beta1.GatewayTLS{Issuer: "ACME", ACME: &gemsv1beta1.ACMEIssuer{
+				Server: "https://pebble:14000/dir", Challenge: "DNS01", SkipTLSVerify: true,
+				DNS01: &gemsv1beta1.ACMEDNS01{Webhook: "http://dns-webhook"},
+			}},
+		},
+		{
+			name:        "dns01 without webhook",
+			tls:         &gemsv1beta1.GatewayTLS{Issuer: "ACME", ACME: &gemsv1beta1.ACMEIssuer{Server: "https://pebble:14000/dir", Challenge: "DNS01"}},
+			wantInvalid: true,
+		},
+		{
+			name:        "acme not https",
+			tls:         &gemsv1beta1.GatewayTLS{Issuer: "ACME", ACME: &gemsv1beta1.ACMEIssuer{Server: "http://pebble/dir", Challenge: "HTTP01"}},
+			wantInvalid: true,
+		},
+		{name: "ca", tls: &gemsv1beta1.GatewayTLS{Issuer: "CA", CA: &gemsv1beta1.CAIssuer{SecretName: "ca"}}},
+		{name: "ca with allowed domains", tls: &gemsv1beta1.GatewayTLS{Issuer: "CA", CA: &gemsv1beta1.CAIssuer{SecretName: "ca", AllowedDomains: []string{"*.apps.example.com"}}}},
+		{name: "ca with top level domain", tls: &gemsv1beta1.GatewayTLS{Issuer: "CA", CA: &gemsv1beta1.CAIssuer{SecretName: "ca", AllowedDomains: []string{"com"}}}, wantInvalid: true},
+		{name: "ca without secret", tls: &gemsv1beta1.GatewayTLS{Issuer: "CA"}, wantInvalid: true},
+		{name: "unknown issuer", tls: &gemsv1beta1.GatewayTLS{Issuer: "Vault"}, wantInvalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errmsg := gatewayTLSInvalid(tt.tls); (len(errmsg) > 0) != tt.wantInvalid {
+				t.Errorf("gatewayTLSInvalid() = %v, wantInvalid %v", errmsg, tt.wantInvalid)
+			}
+		})
+	}
+}
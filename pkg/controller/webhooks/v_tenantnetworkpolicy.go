@@ -17,12 +17,15 @@ package webhooks
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 
 	v1 "k8s.io/api/admission/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -34,7 +37,12 @@ func (r *ResourceValidate) ValidateTenantNetworkPolicy(ctx context.Context, req
 	}
 	switch req.Operation {
 	case v1.Create, v1.Update:
-		// TODO:
+		if err := r.decoder.DecodeRaw(req.Object, tnetpol); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if invalid, errmsg := networkPolicyRulesInvalid(tnetpol); invalid {
+			return admission.Denied(strings.Join(errmsg, ";"))
+		}
 		return admission.Allowed("pass")
 	case v1.Delete:
 		if err := r.Client.Get(ctx, key, tnetpol); err != nil {
@@ -55,3 +63,89 @@ func (r *ResourceValidate) ValidateTenantNetworkPolicy(ctx context.Context, req
 		return admission.Allowed("pass")
 	}
 }
+
+// 规则生成的 networkpolicy 名称为 rule-<name>
+const maxNetworkPolicyRuleNameLength = validation.DNS1123LabelMaxLength - len("rule-")
+
+func networkPolicyRulesInvalid(tnetpol *gemsv1beta1.TenantNetworkPolicy) (bool, []string) {
+	errmsg := []string{}
+	names := map[string]bool{}
+	for _, rule := range tnetpol.Spec.Rules {
+		if errs := validation.IsDNS1123Label(rule.Name); len(errs) > 0 || len(rule.Name) > maxNetworkPolicyRuleNameLength {
+			errmsg = append(errmsg, fmt.Sprintf("rule name %q is invalid, must be a DNS label no longer than %d", rule.Name, maxNetworkPolicyRuleNameLength))
+			continue
+		}
+		if names[rule.Name] {
+			errmsg = append(errmsg, fmt.Sprintf("rule %s is duplicated", rule.Name))
+			continue
+		}
+		names[rule.Name] = true
+		if rule.Environment != "" && rule.Project == "" {
+			errmsg = append(errmsg, fmt.Sprintf("rule %s: project is required when environment is set", rule.Name))
+		}
+		if len(rule.From) == 0 && !rule.EgressIsolated {
+			errmsg = append(errmsg, fmt.Sprintf("rule %s: from is required when egress is not isolated", rule.Name))
+		}
+		if len(rule.To) > 0 && !rule.EgressIsolated {
+			errmsg = append(errmsg, fmt.Sprintf("rule %s: to can only be used when egress is isolated", rule.Name))
+		}
+		for _, peer := range rule.From {
+			if msg := networkPolicyRulePeerInvalid(peer, false); msg != "" {
+				errmsg = append(errmsg, fmt.Sprintf("rule %s from: %s", rule.Name, msg))
+			}
+		}
+		for _, peer := range rule.To {
+			if msg := networkPolicyRulePeerInvalid(peer, true); msg != "" {
+				errmsg = append(errmsg, fmt.Sprintf("rule %s to: %s", rule.Name, msg))
+			}
+		}
+		for _, port := range rule.Ports {
+			if port.EndPort != nil && (port.Port == nil || port.Port.IntVal == 0 || *port.EndPort < port.Port.IntVal) {
+				errmsg = append(errmsg, fmt.Sprintf("rule %s: endPort must not be less than a numeric port", rule.Name))
+			}
+		}
+	}
+	return len(errmsg) > 0, errmsg
+}
+
+func networkPolicyRulePeerInvalid(peer gemsv1beta1.NetworkPolicyRulePeer, egress bool) string {
+	kinds := 0
+	if peer.Tenant != "" || peer.Project != "" || peer.Environment != "" {
+		kinds++
+	}
+	for _, v := range []string{peer.Namespace, peer.CIDR, peer.FQDN} {
+		if v != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return "exactly one of tenant/project/environment, namespace, cidr and fqdn is required"
+	}
+	if len(peer.PodSelector) > 0 && (peer.CIDR != "" || peer.FQDN != "") {
+		return "podSelector can't be used with cidr or fqdn"
+	}
+	if len(peer.Except) > 0 && peer.CIDR == "" {
+		return "except can only be used with cidr"
+	}
+	switch {
+	case peer.CIDR != "":
+		_, ipnet, err := net.ParseCIDR(peer.CIDR)
+		if err != nil {
+			return fmt.Sprintf("cidr %s is invalid", peer.CIDR)
+		}
+		for _, except := range peer.Except {
+			ip, _, err := net.ParseCIDR(except)
+			if err != nil || !ipnet.Contains(ip) {
+				return fmt.Sprintf("except %s is not within cidr %s", except, peer.CIDR)
+			}
+		}
+	case peer.FQDN != "":
+		if !egress {
+			return "fqdn can only be used in to"
+		}
+		if errs := validation.IsDNS1123Subdomain(peer.FQDN); len(errs) > 0 {
+			return fmt.Sprintf("fqdn %s is invalid", peer.FQDN)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"testing"
+
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+)
+
+func TestNetworkPolicyRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       []gemsv1beta1.NetworkPolicyRule
+		wantInvalid bool
+	}{
+		{
+			name: "valid",
+			rules: []gemsv1beta1.NetworkPolicyRule{
+				{Name: "from-other-tenant", Project: "p", From: []gemsv1beta1.NetworkPolicyRulePeer{{Tenant: "other", Project: "q"}}},
+				{Name: "egress-only", EgressIsolated: true},
+				{Name: "to-shared", EgressIsolated: true, To: []gemsv1beta1.NetworkPolicyRulePeer{
+					{Namespace: "shared", PodSelector: map[string]string{"app": "db"}},
+					{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}},
+					{FQDN: "api.example.com"},
+				}},
+			},
+		},
+		{
+			name:        "invalid name",
+			rules:       []gemsv1beta1.NetworkPolicyRule{{Name: "Bad_Name", From: []gemsv1beta1.NetworkPolicyRulePeer{{Tenant: "t"}}}},
+			wantInvalid: true,
+		},
+		{
+			name: "duplicated",
+			rules: []gemsv1beta1.NetworkPolicyRule{
+				{Name: "a", From: []gemsv1beta1.NetworkPolicyRulePeer{{Tenant: "t"}}},
+				{Name: "a", From: []gemsv1beta1.NetworkPolicyRulePeer{{Tenant: "t"}}},
+			},
+			wantInvalid: true,
+		},
+		{
+			name:        "no peers",
+			rules:       []gemsv1beta1.NetworkPolicyRule{{Name: "a"}},
+			wantInvalid: true,
+		},
+		{
+			name:        "to without egress isolation",
+			rules:       []gemsv1beta1.NetworkPolicyRule{{Name: "a", To: []gemsv1beta1.NetworkPolicyRulePeer{{Namespace: "ns"}}}},
+			wantInvalid: true,
+		},
+		{
+			name:        "multiple peer kinds",
+			rules:       []gemsv1beta1.NetworkPolicyRule{{Name: "a", EgressIsolated: true, To: []gemsv1beta1.NetworkPolicyRulePeer{{Namespace: "ns", CIDR: "10.0.0.0/8"}}}},
+			wantInvalid: true,
+		},
+		{
+			name:        "except outside cidr",
+			rules:       []gemsv1beta1.NetworkPolicyRule{{Name: "a", EgressIsolated: true, To: []gemsv1beta1.NetworkPolicyRulePeer{{CIDR: "10.0.0.0/8", Except: []string{"192.168.0.0/16"}}}}},
+			wantInvalid: true,
+		},
+		{
+			name:        "fqdn in from",
+			rules:       []gemsv1beta1.NetworkPolicyRule{{Name: "a", From: []gemsv1beta1.NetworkPolicyRulePeer{{FQDN: "example.com"}}}},
+			wantInvalid: true,
+		},
+		{
+			name:        "environment without project",
+			rules:       []gemsv1beta1.NetworkPolicyRule{{Name: "a", Environment: "e", From: []gemsv1beta1.NetworkPolicyRulePeer{{Tenant: "t"}}}},
+			wantInvalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tnetpol := &gemsv1beta1.TenantNetworkPolicy{Spec: gemsv1beta1.TenantNetworkPolicySpec{Rules: tt.rules}}
+			if invalid, errmsg := networkPolicyRulesInvalid(tnetpol); invalid != tt.wantInvalid {
+				t.Errorf("networkPolicyRulesInvalid() = %v, %v, want %v", invalid, errmsg, tt.wantInvalid)
+			}
+		})
+	}
+}
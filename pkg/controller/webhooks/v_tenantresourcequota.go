@@ -22,6 +22,7 @@ import (
 
 	v1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -37,6 +38,9 @@ func (r *ResourceValidate) ValidateTenantResourceQuota(ctx context.Context, req
 		if err := r.decoder.DecodeRaw(req.Object, trq); err != nil {
 			return admission.Errored(http.StatusBadRequest, err)
 		}
+		if invalid, errmsg := projectResourceQuotaInvalid(trq); invalid {
+			return admission.Denied(strings.Join(errmsg, ";"))
+		}
 		capacity, err := r.getClusterCapacity(ctx)
 		if err != nil {
 			return admission.Errored(http.StatusBadRequest, err)
@@ -53,6 +57,10 @@ func (r *ResourceValidate) ValidateTenantResourceQuota(ctx context.Context, req
 			if err := r.decoder.DecodeRaw(req.OldObject, oldtrq); err != nil {
 				return admission.Errored(http.StatusBadRequest, err)
 			}
+			// 只修改了项目的资源限制
+			if equality.Semantic.DeepEqual(oldtrq.Spec.Hard, trq.Spec.Hard) {
+				return admission.Allowed("pass")
+			}
 			need = resourcequota.SubResource(oldtrq.Spec.Hard, trq.Spec.Hard)
 		}
 		enough, errmsg := resourcequota.ResourceEnough(capacity, allocated, need)
@@ -120,3 +128,38 @@ func (r *ResourceValidate) getAllocatedResource(ctx context.Context) (corev1.Res
 	}
 	return total, nil
 }
+
+// projectResourceQuotaInvalid 项目的资源限制总和不能超过租户的限制, 也不能小于项目已经申请了的资源
+func projectResourceQuotaInvalid(trq *gemsv1beta1.TenantResourceQuota) (bool, []string) {
+	errmsgs := []string{}
+	total := corev1.ResourceList{}
+	seen := map[string]bool{}
+	for _, project := range trq.Spec.Projects {
+		if seen[project.Name] {
+			errmsgs = append(errmsgs, fmt.Sprintf("duplicated project %s", project.Name))
+			continue
+		}
+		seen[project.Name] = true
+		for key, value := range project.Hard {
+			if v, exist := total[key]; exist {
+				v.Add(value)
+				total[key] = v
+			} else {
+				total[key] = value.DeepCopy()
+			}
+		}
+		allocated := trq.Status.Project(project.Name).Allocated
+		for key, value := range project.Hard {
+			if allocatedv, exist := allocated[key]; exist && value.Cmp(allocatedv) == -1 {
+				errmsgs = append(errmsgs, fmt.Sprintf("project %s %s hard %s less than allocated %s", project.Name, key, value.String(), allocatedv.String()))
+			}
+		}
+	}
+	for key, value := range total {
+		hard, exist := trq.Spec.Hard[key]
+		if exist && hard.Cmp(value) == -1 {
+			errmsgs = append(errmsgs, fmt.Sprintf("projects %s total %s exceeds tenant hard %s", key, value.String(), hard.String()))
+		}
+	}
+	return len(errmsgs) > 0, errmsgs
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	gemsv1beta1 "kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+)
+
+func TestProjectResourceQuotaInvalid(t *testing.T) {
+	cpu := func(v string) corev1.ResourceList {
+		return corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse(v)}
+	}
+	tests := []struct {
+		name        string
+		trq         gemsv1beta1.TenantResourceQuota
+		wantInvalid bool
+	}{
+		{
+			name: "no projects",
+			trq: gemsv1beta1.TenantResourceQuota{
+				Spec: gemsv1beta1.TenantResourceQuotaSpec{Hard: cpu("10")},
+			},
+		},
+		{
+			name: "projects within tenant",
+			trq: gemsv1beta1.TenantResourceQuota{
+				Spec: gemsv1beta1.TenantResourceQuotaSpec{
+					Hard:     cpu("10"),
+					Projects: []gemsv1beta1.ProjectResourceQuota{{Name: "a", Hard: cpu("4")}, {Name: "b", Hard: cpu("6")}},
+				},
+			},
+		},
+		{
+			name: "projects exceed tenant",
+			trq: gemsv1beta1.TenantResourceQuota{
+				Spec: gemsv1beta1.TenantResourceQuotaSpec{
+					Hard:     cpu("10"),
+					Projects: []gemsv1beta1.ProjectResourceQuota{{Name: "a", Hard: cpu("4")}, {Name: "b", Hard: cpu("7")}},
+				},
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "duplicated project",
+			trq: gemsv1beta1.TenantResourceQuota{
+				Spec: gemsv1beta1.TenantResourceQuotaSpec{
+					Hard:     cpu("10"),
+					Projects: []gemsv1beta1.ProjectResourceQuota{{Name: "a", Hard: cpu("1")}, {Name: "a", Hard: cpu("1")}},
+				},
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "project less than allocated",
+			trq: gemsv1beta1.TenantResourceQuota{
+				Spec: gemsv1beta1.TenantResourceQuotaSpec{
+					Hard:     cpu("10"),
+					Projects: []gemsv1beta1.ProjectResourceQuota{{Name: "a", Hard: cpu("2")}},
+				},
+				Status: gemsv1beta1.TenantResourceQuotaStatus{
+					Projects: []gemsv1beta1.ProjectResourceQuotaStatus{{Name: "a", Allocated: cpu("3")}},
+				},
+			},
+			wantInvalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if invalid, msgs := projectResourceQuotaInvalid(&tt.trq); invalid != tt.wantInvalid {
+				t.Errorf("projectResourceQuotaInvalid() = %v, %v, want %v", invalid, msgs, tt.wantInvalid)
+			}
+		})
+	}
+}
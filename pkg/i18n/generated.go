@@ -8,6 +8,7 @@ import (
 )
 // initEnUS will init en_US support.
 func initEnUS(tag language.Tag) {
+	_ = message.SetString(tag, "Apply to adjust the resources of project %s in cluster %s", "Apply to adjust the resources of project %s in cluster %s")
 	_ = message.SetString(tag, "Apply to adjust the resources of tenant %s in cluster %s", "Apply to adjust the resources of tenant %s in cluster %s")
 	_ = message.SetString(tag, "CPU or Memory can't be empty at the same time, please provide one at least", "CPU or Memory can't be empty at the same time, please provide one at least")
 	_ = message.SetString(tag, "Failed to synchronize the image registry information to the cluster: %w", "Failed to synchronize the image registry information to the cluster: %w")
@@ -21,14 +22,27 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "account", "account")
 	_ = message.SetString(tag, "add", "add")
 	_ = message.SetString(tag, "add a new cluster %s into kubegems", "add a new cluster %s into kubegems")
+	_ = message.SetString(tag, "add note", "add note")
 	_ = message.SetString(tag, "add user %s to environment %s member as role %s", "add user %s to environment %s member as role %s")
 	_ = message.SetString(tag, "add user %s to project %s members as role %s", "add user %s to project %s members as role %s")
 	_ = message.SetString(tag, "add user %s to tenant %s members as role %s", "add user %s to tenant %s members as role %s")
+	_ = message.SetString(tag, "admission policy", "admission policy")
 	_ = message.SetString(tag, "alert rule", "alert rule")
+	_ = message.SetString(tag, "alert rule %s is managed by slo, please modify the slo instead", "alert rule %s is managed by slo, please modify the slo instead")
+	_ = message.SetString(tag, "alert rule of slo %s not found", "alert rule of slo %s not found")
+	_ = message.SetString(tag, "anomaly detector", "anomaly detector")
+	_ = message.SetString(tag, "anomaly detector duration must between 1m and 1h", "anomaly detector duration must between 1m and 1h")
+	_ = message.SetString(tag, "anomaly detector weeks must between 1 and 8", "anomaly detector weeks must between 1 and 8")
 	_ = message.SetString(tag, "app %s has been collected by flow %s", "app %s has been collected by flow %s")
 	_ = message.SetString(tag, "app label %s is not valid, must be one of %v", "app label %s is not valid, must be one of %v")
+	_ = message.SetString(tag, "application %s has changed since the approval, please apply for approval of revision %s", "application %s has changed since the approval, please apply for approval of revision %s")
+	_ = message.SetString(tag, "approval", "approval")
+	_ = message.SetString(tag, "approval %s is %s", "approval %s is %s")
+	_ = message.SetString(tag, "approval rule", "approval rule")
+	_ = message.SetString(tag, "approval rule of %s already exists", "approval rule of %s already exists")
 	_ = message.SetString(tag, "auth source not exist", "auth source not exist")
 	_ = message.SetString(tag, "auth source not exists or not enabled", "auth source not exists or not enabled")
+	_ = message.SetString(tag, "backtest time range must be within %s", "backtest time range must be within %s")
 	_ = message.SetString(tag, "batch delete", "batch delete")
 	_ = message.SetString(tag, "can't add image registry, there must be only one default image registry", "can't add image registry, there must be only one default image registry")
 	_ = message.SetString(tag, "can't add log collector, must specify at least one app", "can't add log collector, must specify at least one app")
@@ -47,16 +61,28 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "can't set image registry, the project's default image registry can only exist one", "can't set image registry, the project's default image registry can only exist one")
 	_ = message.SetString(tag, "can't update image registry, the default image registry can only exist one", "can't update image registry, the default image registry can only exist one")
 	_ = message.SetString(tag, "cancel", "cancel")
+	_ = message.SetString(tag, "clone", "clone")
 	_ = message.SetString(tag, "cluster", "cluster")
+	_ = message.SetString(tag, "cluster %s is not found", "cluster %s is not found")
+	_ = message.SetString(tag, "cluster %s is not in the blueprint clusters", "cluster %s is not in the blueprint clusters")
+	_ = message.SetString(tag, "cluster and plugin are required for %s approval", "cluster and plugin are required for %s approval")
+	_ = message.SetString(tag, "cluster name is required", "cluster name is required")
 	_ = message.SetString(tag, "cluster resource quota", "cluster resource quota")
 	_ = message.SetString(tag, "cluster resource quota adjustment application", "cluster resource quota adjustment application")
 	_ = message.SetString(tag, "cluster tenant gateway", "cluster tenant gateway")
+	_ = message.SetString(tag, "comment can't be empty", "comment can't be empty")
+	_ = message.SetString(tag, "cost price", "cost price")
 	_ = message.SetString(tag, "create", "create")
 	_ = message.SetString(tag, "created environment %s in project %s", "created environment %s in project %s")
 	_ = message.SetString(tag, "created project %s in tenant %s", "created project %s in tenant %s")
 	_ = message.SetString(tag, "created tenant %s", "created tenant %s")
 	_ = message.SetString(tag, "created virtual space %s", "created virtual space %s")
 	_ = message.SetString(tag, "current environment data is abnormal, please contact the administrator", "current environment data is abnormal, please contact the administrator")
+	_ = message.SetString(tag, "current tenant has no resource quota on the cluster", "current tenant has no resource quota on the cluster")
+	_ = message.SetString(tag, "dashboard name and template are required", "dashboard name and template are required")
+	_ = message.SetString(tag, "dashboard name is required", "dashboard name is required")
+	_ = message.SetString(tag, "dashboard template %s is not found", "dashboard template %s is not found")
+	_ = message.SetString(tag, "date %s not valid", "date %s not valid")
 	_ = message.SetString(tag, "delete", "delete")
 	_ = message.SetString(tag, "delete project %s belong to tenant %s", "delete project %s belong to tenant %s")
 	_ = message.SetString(tag, "delete user %s from environment %s member", "delete user %s from environment %s member")
@@ -65,15 +91,24 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "deleted the cluster %s", "deleted the cluster %s")
 	_ = message.SetString(tag, "deleted the environment %s in the project %s", "deleted the environment %s in the project %s")
 	_ = message.SetString(tag, "deleted virtual space %s", "deleted virtual space %s")
+	_ = message.SetString(tag, "deploying application %s to production environment %s requires an approved approval", "deploying application %s to production environment %s requires an approved approval")
 	_ = message.SetString(tag, "disable", "disable")
 	_ = message.SetString(tag, "disabled tenant %s", "disabled tenant %s")
+	_ = message.SetString(tag, "duplicated cluster %s", "duplicated cluster %s")
+	_ = message.SetString(tag, "duplicated environment %s", "duplicated environment %s")
 	_ = message.SetString(tag, "duplicated name in: %s", "duplicated name in: %s")
+	_ = message.SetString(tag, "duplicated namespace %s", "duplicated namespace %s")
+	_ = message.SetString(tag, "duplicated project %s", "duplicated project %s")
 	_ = message.SetString(tag, "empty code", "empty code")
 	_ = message.SetString(tag, "enable", "enable")
 	_ = message.SetString(tag, "enabled tenant %s", "enabled tenant %s")
 	_ = message.SetString(tag, "environment", "environment")
 	_ = message.SetString(tag, "environment %s / user %s / role %s", "environment %s / user %s / role %s")
+	_ = message.SetString(tag, "environment %s is not a production environment", "environment %s is not a production environment")
+	_ = message.SetString(tag, "environment and application are required for %s approval", "environment and application are required for %s approval")
+	_ = message.SetString(tag, "environment is not cloned from other environment", "environment is not cloned from other environment")
 	_ = message.SetString(tag, "environment member", "environment member")
+	_ = message.SetString(tag, "environment name is required", "environment name is required")
 	_ = message.SetString(tag, "environment network isolation", "environment network isolation")
 	_ = message.SetString(tag, "exceeding the maximum limit of 50000", "exceeding the maximum limit of 50000")
 	_ = message.SetString(tag, "exchange oauth2 token failed", "exchange oauth2 token failed")
@@ -87,10 +122,14 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "failed to get Harbor version", "failed to get Harbor version")
 	_ = message.SetString(tag, "failed to get api-server info: %v", "failed to get api-server info: %v")
 	_ = message.SetString(tag, "failed to get auth source", "failed to get auth source")
+	_ = message.SetString(tag, "failed to get login address of source %s", "failed to get login address of source %s")
 	_ = message.SetString(tag, "failed to get userinfo from ldap", "failed to get userinfo from ldap")
 	_ = message.SetString(tag, "failed to get userinfo from ldap, more than one result", "failed to get userinfo from ldap, more than one result")
 	_ = message.SetString(tag, "failed to get userinfo from oauth provider", "failed to get userinfo from oauth provider")
+	_ = message.SetString(tag, "failed to get userinfo from oidc provider", "failed to get userinfo from oidc provider")
 	_ = message.SetString(tag, "failed to get username from oauth provider", "failed to get username from oauth provider")
+	_ = message.SetString(tag, "failed to get username from oidc provider", "failed to get username from oidc provider")
+	_ = message.SetString(tag, "failed to get username from saml response", "failed to get username from saml response")
 	_ = message.SetString(tag, "failed to init k8s client: %v", "failed to init k8s client: %v")
 	_ = message.SetString(tag, "failed to list StorageClass: %v", "failed to list StorageClass: %v")
 	_ = message.SetString(tag, "failed to recover volume snapshot to PVC %s because the PVC status is not ready to use", "failed to recover volume snapshot to PVC %s because the PVC status is not ready to use")
@@ -98,24 +137,38 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "forbidden, please login", "forbidden, please login")
 	_ = message.SetString(tag, "gateway %s already exist", "gateway %s already exist")
 	_ = message.SetString(tag, "gateway %s is not found", "gateway %s is not found")
+	_ = message.SetString(tag, "gateway name is required", "gateway name is required")
 	_ = message.SetString(tag, "get prometheus label names failed, cluster: %s, promql: %s, %w", "get prometheus label names failed, cluster: %s, promql: %s, %w")
 	_ = message.SetString(tag, "get prometheus label values failed, cluster: %s, promql: %s, %w", "get prometheus label values failed, cluster: %s, promql: %s, %w")
 	_ = message.SetString(tag, "grant", "grant")
+	_ = message.SetString(tag, "groupby %s not valid", "groupby %s not valid")
 	_ = message.SetString(tag, "helm chart repo index URL is invalid: %w", "helm chart repo index URL is invalid: %w")
 	_ = message.SetString(tag, "image registry", "image registry")
 	_ = message.SetString(tag, "import", "import")
+	_ = message.SetString(tag, "incident", "incident")
+	_ = message.SetString(tag, "instantiate", "instantiate")
 	_ = message.SetString(tag, "invalid credential", "invalid credential")
+	_ = message.SetString(tag, "invalid cron expression %s: %v", "invalid cron expression %s: %v")
 	_ = message.SetString(tag, "invalid data, the environment %s related virtual space is mismatched with the virtual space you are action", "invalid data, the environment %s related virtual space is mismatched with the virtual space you are action")
 	_ = message.SetString(tag, "invalid environment %s, in which a related cluster does not exist, may the related cluster was deleted", "invalid environment %s, in which a related cluster does not exist, may the related cluster was deleted")
+	_ = message.SetString(tag, "invalid id_token", "invalid id_token")
+	_ = message.SetString(tag, "invalid incident status: %s", "invalid incident status: %s")
+	_ = message.SetString(tag, "invalid ip or cidr %s", "invalid ip or cidr %s")
 	_ = message.SetString(tag, "invalid kubeconfig format: %v", "invalid kubeconfig format: %v")
 	_ = message.SetString(tag, "invalid kubeconfig: %v", "invalid kubeconfig: %v")
 	_ = message.SetString(tag, "invalid page number query parameter", "invalid page number query parameter")
 	_ = message.SetString(tag, "invalid page size query parameter", "invalid page size query parameter")
 	_ = message.SetString(tag, "invalid parameters: name=%s, version=%s", "invalid parameters: name=%s, version=%s")
+	_ = message.SetString(tag, "invalid saml response", "invalid saml response")
+	_ = message.SetString(tag, "invalid time zone %s", "invalid time zone %s")
+	_ = message.SetString(tag, "invalid two-factor authentication code", "invalid two-factor authentication code")
+	_ = message.SetString(tag, "log export", "log export")
+	_ = message.SetString(tag, "log metric", "log metric")
 	_ = message.SetString(tag, "log snapshot", "log snapshot")
 	_ = message.SetString(tag, "logging alert rule", "logging alert rule")
 	_ = message.SetString(tag, "login source not provide", "login source not provide")
 	_ = message.SetString(tag, "logquery history", "logquery history")
+	_ = message.SetString(tag, "maintenance window", "maintenance window")
 	_ = message.SetString(tag, "max limitation is 5000", "max limitation is 5000")
 	_ = message.SetString(tag, "message type %s is invalid", "message type %s is invalid")
 	_ = message.SetString(tag, "modify", "modify")
@@ -125,7 +178,11 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "monitoring collector", "monitoring collector")
 	_ = message.SetString(tag, "monitoring query template", "monitoring query template")
 	_ = message.SetString(tag, "namespace  %s is not allowed, it's a system retain namespace", "namespace  %s is not allowed, it's a system retain namespace")
+	_ = message.SetString(tag, "namespace %s already exists in cluster %s", "namespace %s already exists in cluster %s")
 	_ = message.SetString(tag, "namespace %s was bonded with another environment", "namespace %s was bonded with another environment")
+	_ = message.SetString(tag, "no id_token in oidc token response", "no id_token in oidc token response")
+	_ = message.SetString(tag, "no supported panels found in grafana dashboard", "no supported panels found in grafana dashboard")
+	_ = message.SetString(tag, "only system admin can list all approvals", "only system admin can list all approvals")
 	_ = message.SetString(tag, "origin password error", "origin password error")
 	_ = message.SetString(tag, "parameters missmatched", "parameters missmatched")
 	_ = message.SetString(tag, "passed", "passed")
@@ -135,19 +192,35 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "port %s not found in Service %s", "port %s not found in Service %s")
 	_ = message.SetString(tag, "prefabricated template cannot be deleted", "prefabricated template cannot be deleted")
 	_ = message.SetString(tag, "project", "project")
+	_ = message.SetString(tag, "project %s / cluster %s", "project %s / cluster %s")
 	_ = message.SetString(tag, "project %s / user %s / role %s", "project %s / user %s / role %s")
+	_ = message.SetString(tag, "project cluster resource quota", "project cluster resource quota")
+	_ = message.SetString(tag, "project cluster resource quota adjustment application", "project cluster resource quota adjustment application")
+	_ = message.SetString(tag, "project is required for %s approval", "project is required for %s approval")
 	_ = message.SetString(tag, "project member", "project member")
+	_ = message.SetString(tag, "project name is required", "project name is required")
 	_ = message.SetString(tag, "project network isolation", "project network isolation")
 	_ = message.SetString(tag, "put", "put")
 	_ = message.SetString(tag, "recover", "recover")
 	_ = message.SetString(tag, "rejected", "rejected")
 	_ = message.SetString(tag, "repo %s started syncing on background", "repo %s started syncing on background")
+	_ = message.SetString(tag, "report", "report")
+	_ = message.SetString(tag, "report channel must be an email channel", "report channel must be an email channel")
+	_ = message.SetString(tag, "resource kind %s can't be cloned", "resource kind %s can't be cloned")
+	_ = message.SetString(tag, "resource quota of cluster %s is required", "resource quota of cluster %s is required")
+	_ = message.SetString(tag, "restricted token can't be used to issue new tokens", "restricted token can't be used to issue new tokens")
+	_ = message.SetString(tag, "role %s not valid", "role %s not valid")
 	_ = message.SetString(tag, "rule %s already exist", "rule %s already exist")
+	_ = message.SetString(tag, "scope %s not valid", "scope %s not valid")
 	_ = message.SetString(tag, "scrap target %s not found", "scrap target %s not found")
+	_ = message.SetString(tag, "send", "send")
 	_ = message.SetString(tag, "set", "set")
 	_ = message.SetString(tag, "set user %s to environment %s member as role %s", "set user %s to environment %s member as role %s")
 	_ = message.SetString(tag, "set user %s to tenant %s members as role %s", "set user %s to tenant %s members as role %s")
 	_ = message.SetString(tag, "site announcement period is invalid, the end time is earlier than the start time", "site announcement period is invalid, the end time is earlier than the start time")
+	_ = message.SetString(tag, "sleep", "sleep")
+	_ = message.SetString(tag, "sleep schedule is required", "sleep schedule is required")
+	_ = message.SetString(tag, "slo window must between 7d and 90d", "slo window must between 7d and 90d")
 	_ = message.SetString(tag, "some error happend, more than one silence rule founded, please contact admin", "some error happend, more than one silence rule founded, please contact admin")
 	_ = message.SetString(tag, "source not exist", "source not exist")
 	_ = message.SetString(tag, "source not match", "source not match")
@@ -158,21 +231,29 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "tenant", "tenant")
 	_ = message.SetString(tag, "tenant %s / cluster %s", "tenant %s / cluster %s")
 	_ = message.SetString(tag, "tenant %s / user %s", "tenant %s / user %s")
+	_ = message.SetString(tag, "tenant and cluster are required for %s approval", "tenant and cluster are required for %s approval")
+	_ = message.SetString(tag, "tenant blueprint", "tenant blueprint")
+	_ = message.SetString(tag, "tenant blueprint %s / tenant %s", "tenant blueprint %s / tenant %s")
 	_ = message.SetString(tag, "tenant id not valid", "tenant id not valid")
 	_ = message.SetString(tag, "tenant is not found", "tenant is not found")
 	_ = message.SetString(tag, "tenant member", "tenant member")
 	_ = message.SetString(tag, "tenant member role", "tenant member role")
 	_ = message.SetString(tag, "tenant network isolation", "tenant network isolation")
+	_ = message.SetString(tag, "tenant network policy rules", "tenant network policy rules")
+	_ = message.SetString(tag, "tenant, project or environment is required for %s approval", "tenant, project or environment is required for %s approval")
+	_ = message.SetString(tag, "the cloned environment must use a different namespace", "the cloned environment must use a different namespace")
 	_ = message.SetString(tag, "the cluster with name %s existed, can't add the same one", "the cluster with name %s existed, can't add the same one")
 	_ = message.SetString(tag, "the cluster you are action is not found", "the cluster you are action is not found")
 	_ = message.SetString(tag, "the cluster you are quering in is not found", "the cluster you are quering in is not found")
 	_ = message.SetString(tag, "the cluster you are quering on is not found", "the cluster you are quering on is not found")
 	_ = message.SetString(tag, "the cluster you are querying doesn't exist", "the cluster you are querying doesn't exist")
+	_ = message.SetString(tag, "the current stage of this approval has already been decided", "the current stage of this approval has already been decided")
 	_ = message.SetString(tag, "the environment member role you are modifying is not exist", "the environment member role you are modifying is not exist")
 	_ = message.SetString(tag, "the gateway you are deleting is a default gateway, can't delete it", "the gateway you are deleting is a default gateway, can't delete it")
 	_ = message.SetString(tag, "the object or the parent object is not found", "the object or the parent object is not found")
 	_ = message.SetString(tag, "the passwords entered twice are inconsistent", "the passwords entered twice are inconsistent")
 	_ = message.SetString(tag, "the primary cluster existed already, more than one primary cluster is not allowed", "the primary cluster existed already, more than one primary cluster is not allowed")
+	_ = message.SetString(tag, "the project has no resource application approval in the current cluster", "the project has no resource application approval in the current cluster")
 	_ = message.SetString(tag, "the project's default image registry ", "the project's default image registry ")
 	_ = message.SetString(tag, "the tenant does not exist", "the tenant does not exist")
 	_ = message.SetString(tag, "the tenant has no enough resources in the current cluster", "the tenant has no enough resources in the current cluster")
@@ -182,6 +263,20 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "the tenant you are quering on is not found", "the tenant you are quering on is not found")
 	_ = message.SetString(tag, "the user to add is not found", "the user to add is not found")
 	_ = message.SetString(tag, "there is no cluster resource quota adjustment approval", "there is no cluster resource quota adjustment approval")
+	_ = message.SetString(tag, "token expired", "token expired")
+	_ = message.SetString(tag, "token is not allowed to be used from %s", "token is not allowed to be used from %s")
+	_ = message.SetString(tag, "token is read-only", "token is read-only")
+	_ = message.SetString(tag, "token scope %s %d not found", "token scope %s %d not found")
+	_ = message.SetString(tag, "token scopes do not allow access to this api", "token scopes do not allow access to this api")
+	_ = message.SetString(tag, "too many failed two-factor authentication attempts, please retry after %s", "too many failed two-factor authentication attempts, please retry after %s")
+	_ = message.SetString(tag, "two-factor authentication code has already been used", "two-factor authentication code has already been used")
+	_ = message.SetString(tag, "two-factor authentication code required", "two-factor authentication code required")
+	_ = message.SetString(tag, "two-factor authentication is already enabled", "two-factor authentication is already enabled")
+	_ = message.SetString(tag, "two-factor authentication is not enabled", "two-factor authentication is not enabled")
+	_ = message.SetString(tag, "two-factor authentication is not enrolled", "two-factor authentication is not enrolled")
+	_ = message.SetString(tag, "two-factor authentication is only available for local accounts", "two-factor authentication is only available for local accounts")
+	_ = message.SetString(tag, "two-factor authentication is required by the security policy and can't be disabled", "two-factor authentication is required by the security policy and can't be disabled")
+	_ = message.SetString(tag, "two-factor authentication is required by the security policy, please enroll first", "two-factor authentication is required by the security policy, please enroll first")
 	_ = message.SetString(tag, "unable to find VolumeSnapshotClass of pvc %s Provisioner=%s", "unable to find VolumeSnapshotClass of pvc %s Provisioner=%s")
 	_ = message.SetString(tag, "unauthorized, please login first", "unauthorized, please login first")
 	_ = message.SetString(tag, "unset", "unset")
@@ -189,7 +284,11 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "update", "update")
 	_ = message.SetString(tag, "update user %s to project %s members as role %s", "update user %s to project %s members as role %s")
 	_ = message.SetString(tag, "user %s / role %s", "user %s / role %s")
+	_ = message.SetString(tag, "user %s applied to adjust the ResourceQuota of project %s in cluster %s", "user %s applied to adjust the ResourceQuota of project %s in cluster %s")
 	_ = message.SetString(tag, "user %s applied to adjust the ResourceQuota of tenant %s in cluster %s", "user %s applied to adjust the ResourceQuota of tenant %s in cluster %s")
+	_ = message.SetString(tag, "username %s is already used by another user", "username %s is already used by another user")
+	_ = message.SetString(tag, "username or password error", "username or password error")
+	_ = message.SetString(tag, "validate project resource quota failed: %v", "validate project resource quota failed: %v")
 	_ = message.SetString(tag, "validate tenant resoure quota failed, can't get cluster resource statistics: %w", "validate tenant resoure quota failed, can't get cluster resource statistics: %w")
 	_ = message.SetString(tag, "validate tenant resoure quota failed: %w", "validate tenant resoure quota failed: %w")
 	_ = message.SetString(tag, "validate username and password to the registry faild: %w", "validate username and password to the registry faild: %w")
@@ -199,7 +298,11 @@ func initEnUS(tag language.Tag) {
 	_ = message.SetString(tag, "virtual space member", "virtual space member")
 	_ = message.SetString(tag, "volume snapshot", "volume snapshot")
 	_ = message.SetString(tag, "volume snapshot to PVC", "volume snapshot to PVC")
+	_ = message.SetString(tag, "wake", "wake")
 	_ = message.SetString(tag, "workload has no K8S Service: %w", "workload has no K8S Service: %w")
+	_ = message.SetString(tag, "you are not a member of the approval scope", "you are not a member of the approval scope")
+	_ = message.SetString(tag, "you can't decide the current stage of this approval", "you can't decide the current stage of this approval")
+	_ = message.SetString(tag, "you can't view this approval", "you can't view this approval")
 	_ = message.SetString(tag, "you have no permission to do this operation", "you have no permission to do this operation")
 	_ = message.SetString(tag, "you have no permission to do this operation, you must be an admin in any virtual space firstly", "you have no permission to do this operation, you must be an admin in any virtual space firstly")
 	_ = message.SetString(tag, "you have no permission to operate the environment %s", "you have no permission to operate the environment %s")
@@ -209,6 +312,7 @@ func initEnUS(tag language.Tag) {
 }
 // initJaJP will init ja_JP support.
 func initJaJP(tag language.Tag) {
+	_ = message.SetString(tag, "Apply to adjust the resources of project %s in cluster %s", "クラスター %[2]s におけるプロジェクト %[1]s のリソース調整を申請")
 	_ = message.SetString(tag, "Apply to adjust the resources of tenant %s in cluster %s", "クラスター %sのテナント %s のリソースを調整するために適用する")
 	_ = message.SetString(tag, "CPU or Memory can't be empty at the same time, please provide one at least", "CPUまたはメモリを同時に空にすることはできません。少なくとも1つを入力してください")
 	_ = message.SetString(tag, "Failed to synchronize the image registry information to the cluster: %w", "イメージレジストリ情報をクラスタに同期できませんでした: %w")
@@ -222,15 +326,28 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "account", "メンバーアカウント")
 	_ = message.SetString(tag, "add", "追加")
 	_ = message.SetString(tag, "add a new cluster %s into kubegems", "新しいクラスタ %s をkubegemsに追加する")
+	_ = message.SetString(tag, "add note", "メモを追加")
 	_ = message.SetString(tag, "add user %s to environment %s member as role %s", "ユーザー %s をロール %sとして環境 %s メンバーに追加する")
 	_ = message.SetString(tag, "add user %s to project %s members as role %s", "ロール %sとしてプロジェクト %s メンバーにユーザー %s を追加")
 	_ = message.SetString(tag, "add user %s to tenant %s members as role %s", "ユーザー %s をロール %sとしてテナント %s メンバーに追加")
+	_ = message.SetString(tag, "admission policy", "アドミッションポリシー")
 	_ = message.SetString(tag, "alert receiver", "アラート受信機")
+	_ = message.SetString(tag, "alert rule %s is managed by slo, please modify the slo instead", "アラートルール %s は SLO によって管理されています。SLO を変更してください")
 	_ = message.SetString(tag, "alert rule %s not found", "アラートルール %s が見つかりません")
+	_ = message.SetString(tag, "alert rule of slo %s not found", "SLO %s のアラートルールが見つかりません")
+	_ = message.SetString(tag, "anomaly detector", "異常検知")
+	_ = message.SetString(tag, "anomaly detector duration must between 1m and 1h", "異常検知の持続時間は 1m から 1h の間でなければなりません")
+	_ = message.SetString(tag, "anomaly detector weeks must between 1 and 8", "異常検知の学習週数は 1 から 8 の間でなければなりません")
 	_ = message.SetString(tag, "app %s has been collected by flow %s", "アプリ %s がフロー %sによって収集されました")
 	_ = message.SetString(tag, "app label %s is not valid, must be one of %v", "アプリのラベル %s が無効です。 %vのいずれかでなければなりません")
+	_ = message.SetString(tag, "application %s has changed since the approval, please apply for approval of revision %s", "アプリケーション %s は承認後に変更されました。リビジョン %s の承認を申請してください")
+	_ = message.SetString(tag, "approval", "承認")
+	_ = message.SetString(tag, "approval %s is %s", "承認 %s のステータスは %s です")
+	_ = message.SetString(tag, "approval rule", "承認ルール")
+	_ = message.SetString(tag, "approval rule of %s already exists", "%s の承認ルールは既に存在します")
 	_ = message.SetString(tag, "auth source not exist", "認証ソースが存在しません")
 	_ = message.SetString(tag, "auth source not exists or not enabled", "認証ソースが存在しないか、有効になっていません")
+	_ = message.SetString(tag, "backtest time range must be within %s", "バックテストの期間は %s 以内である必要があります")
 	_ = message.SetString(tag, "batch delete", "一括削除")
 	_ = message.SetString(tag, "can't add image registry, there must be only one default image registry", "画像レジストリを追加できません。デフォルトの画像レジストリは1つだけでなければなりません")
 	_ = message.SetString(tag, "can't add log collector, must specify at least one app", "ログコレクターを追加できません。少なくとも1つのアプリを指定する必要があります")
@@ -248,16 +365,28 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "can't set image registry, the project's default image registry can only exist one", "イメージレジストリを設定できません。プロジェクトの既定のイメージレジストリは1つしか存在できません")
 	_ = message.SetString(tag, "can't update image registry, the default image registry can only exist one", "画像レジストリを更新できません。既定の画像レジストリは1つしか存在できません")
 	_ = message.SetString(tag, "cancel", "キャンセル")
+	_ = message.SetString(tag, "clone", "クローン")
 	_ = message.SetString(tag, "cluster", "クラスター")
+	_ = message.SetString(tag, "cluster %s is not found", "クラスタ %s が見つかりません")
+	_ = message.SetString(tag, "cluster %s is not in the blueprint clusters", "クラスタ %s はテンプレートのクラスタに含まれていません")
+	_ = message.SetString(tag, "cluster and plugin are required for %s approval", "%s の承認にはクラスターとプラグインが必要です")
+	_ = message.SetString(tag, "cluster name is required", "クラスタ名は必須です")
 	_ = message.SetString(tag, "cluster resource quota", "クラスタリソースクォータ")
 	_ = message.SetString(tag, "cluster resource quota adjustment application", "クラスターリソースクォータ調整アプリケーション")
 	_ = message.SetString(tag, "cluster tenant gateway", "クラスタテナントゲートウェイ")
+	_ = message.SetString(tag, "comment can't be empty", "コメントは空にできません")
+	_ = message.SetString(tag, "cost price", "リソース単価")
 	_ = message.SetString(tag, "create", "作成")
 	_ = message.SetString(tag, "created environment %s in project %s", "プロジェクト %s で環境 %s を作成しました")
 	_ = message.SetString(tag, "created project %s in tenant %s", "テナント %sでプロジェクト %s を作成しました")
 	_ = message.SetString(tag, "created tenant %s", "作成されたテナント %s")
 	_ = message.SetString(tag, "created virtual space %s", "作成された仮想空間 %s")
 	_ = message.SetString(tag, "current environment data is abnormal, please contact the administrator", "現在の環境データが異常です。管理者に連絡してください")
+	_ = message.SetString(tag, "current tenant has no resource quota on the cluster", "現在のテナントはこのクラスターにリソースがありません")
+	_ = message.SetString(tag, "dashboard name and template are required", "ダッシュボード名とテンプレートは必須です")
+	_ = message.SetString(tag, "dashboard name is required", "ダッシュボード名は必須です")
+	_ = message.SetString(tag, "dashboard template %s is not found", "ダッシュボードテンプレート %s が見つかりません")
+	_ = message.SetString(tag, "date %s not valid", "日付 %s が不正です")
 	_ = message.SetString(tag, "delete", "削除")
 	_ = message.SetString(tag, "delete project %s belong to tenant %s", "プロジェクト %s を削除します。テナント %sに属しています")
 	_ = message.SetString(tag, "delete user %s from environment %s member", "環境 %s メンバーからユーザー %s を削除")
@@ -266,15 +395,24 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "deleted the cluster %s", "クラスター %sを削除しました")
 	_ = message.SetString(tag, "deleted the environment %s in the project %s", "プロジェクト %sの環境 %s を削除しました")
 	_ = message.SetString(tag, "deleted virtual space %s", "削除された仮想空間 %s")
+	_ = message.SetString(tag, "deploying application %s to production environment %s requires an approved approval", "アプリケーション %s を本番環境 %s にデプロイするには承認済みの承認が必要です")
 	_ = message.SetString(tag, "disable", "無効")
 	_ = message.SetString(tag, "disabled tenant %s", "無効なテナント %s")
+	_ = message.SetString(tag, "duplicated cluster %s", "クラスタ %s が重複しています")
+	_ = message.SetString(tag, "duplicated environment %s", "環境 %s が重複しています")
 	_ = message.SetString(tag, "duplicated name in: %s", "名前が重複しています: %s")
+	_ = message.SetString(tag, "duplicated namespace %s", "ネームスペース %s が重複しています")
+	_ = message.SetString(tag, "duplicated project %s", "プロジェクト %s が重複しています")
 	_ = message.SetString(tag, "empty code", "空のコード")
 	_ = message.SetString(tag, "enable", "有効")
 	_ = message.SetString(tag, "enabled tenant %s", "有効なテナント %s")
 	_ = message.SetString(tag, "environment", "環境")
 	_ = message.SetString(tag, "environment %s / user %s / role %s", "環境 %s /ユーザー %s /ロール %s")
+	_ = message.SetString(tag, "environment %s is not a production environment", "環境 %s は本番環境ではありません")
+	_ = message.SetString(tag, "environment and application are required for %s approval", "%s の承認には環境とアプリケーションが必要です")
+	_ = message.SetString(tag, "environment is not cloned from other environment", "この環境は他の環境からクローンされたものではありません")
 	_ = message.SetString(tag, "environment member", "環境部材")
+	_ = message.SetString(tag, "environment name is required", "環境名は必須です")
 	_ = message.SetString(tag, "environment network isolation", "環境ネットワーク分離")
 	_ = message.SetString(tag, "exceeding the maximum limit of 50000", "50000の上限を超えています")
 	_ = message.SetString(tag, "exchange oauth2 token failed", "交換oauth 2トークンが失敗しました")
@@ -288,10 +426,14 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "failed to get Harbor version", "港のバージョンを取得できませんでした")
 	_ = message.SetString(tag, "failed to get api-server info: %v", "aPIサーバー情報の取得に失敗しました: %v")
 	_ = message.SetString(tag, "failed to get auth source", "認証ソースの取得に失敗しました")
+	_ = message.SetString(tag, "failed to get login address of source %s", "ログインソース %s のログインアドレスを取得できませんでした")
 	_ = message.SetString(tag, "failed to get userinfo from ldap", "ldapからユーザー情報を取得できませんでした")
 	_ = message.SetString(tag, "failed to get userinfo from ldap, more than one result", "ldapからユーザー情報を取得できませんでした。複数の結果があります")
 	_ = message.SetString(tag, "failed to get userinfo from oauth provider", "oauthプロバイダーからユーザー情報を取得できませんでした")
+	_ = message.SetString(tag, "failed to get userinfo from oidc provider", "oidcプロバイダーからユーザー情報を取得できませんでした")
 	_ = message.SetString(tag, "failed to get username from oauth provider", "oauthプロバイダーからユーザー名を取得できませんでした")
+	_ = message.SetString(tag, "failed to get username from oidc provider", "oidcプロバイダーからユーザー名を取得できませんでした")
+	_ = message.SetString(tag, "failed to get username from saml response", "samlレスポンスからユーザー名を取得できませんでした")
 	_ = message.SetString(tag, "failed to init k8s client: %v", "k 8 sクライアントの初期化に失敗しました: %v")
 	_ = message.SetString(tag, "failed to list StorageClass: %v", "ストレージクラスを一覧表示できませんでした: %v")
 	_ = message.SetString(tag, "failed to recover volume snapshot to PVC %s because the PVC status is not ready to use", "pVCステータスの使用準備ができていないため、ボリュームスナップショットをPVC %s に復元できませんでした")
@@ -299,24 +441,38 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "forbidden, please login", "禁止されています。ログインしてください")
 	_ = message.SetString(tag, "gateway %s already exist", "ゲートウェイ %s は既に存在します")
 	_ = message.SetString(tag, "gateway %s is not found", "ゲートウェイ %s が見つかりません")
+	_ = message.SetString(tag, "gateway name is required", "ゲートウェイ名は必須です")
 	_ = message.SetString(tag, "get prometheus label names failed, cluster: %s, promql: %s, %w", "プロメテウスのラベル名の取得に失敗しました。クラスター: %s、プロムクル: %s、 %w")
 	_ = message.SetString(tag, "get prometheus label values failed, cluster: %s, promql: %s, %w", "プロメテウスのラベル値の取得に失敗しました。クラスター: %s、プロムクル: %s、 %w")
 	_ = message.SetString(tag, "grant", "許可")
+	_ = message.SetString(tag, "groupby %s not valid", "グループ化 %s が不正です")
 	_ = message.SetString(tag, "helm chart repo index URL is invalid: %w", "ヘルムチャートリポジトリインデックスURLが無効です: %w")
 	_ = message.SetString(tag, "image registry", "イメージレジストリ")
 	_ = message.SetString(tag, "import", "インポート")
+	_ = message.SetString(tag, "incident", "インシデント")
+	_ = message.SetString(tag, "instantiate", "インスタンス化")
 	_ = message.SetString(tag, "invalid credential", "無効な資格情報")
+	_ = message.SetString(tag, "invalid cron expression %s: %v", "無効な cron 式 %s: %v")
 	_ = message.SetString(tag, "invalid data, the environment %s related virtual space is mismatched with the virtual space you are action", "無効なデータです。環境 %s に関連する仮想空間がアクション中の仮想空間と一致しません")
 	_ = message.SetString(tag, "invalid environment %s, in which a related cluster does not exist, may the related cluster was deleted", "関連クラスタが存在しない無効な環境 %sは、関連クラスタが削除された可能性があります")
+	_ = message.SetString(tag, "invalid id_token", "無効なid_token")
+	_ = message.SetString(tag, "invalid incident status: %s", "無効なインシデントステータス: %s")
+	_ = message.SetString(tag, "invalid ip or cidr %s", "無効なIPまたはCIDR %s")
 	_ = message.SetString(tag, "invalid kubeconfig format: %v", "無効なkubeconfig形式: %v")
 	_ = message.SetString(tag, "invalid kubeconfig: %v", "無効なkubeconfig: %v")
 	_ = message.SetString(tag, "invalid page number query parameter", "ページ番号クエリパラメータが無効です")
 	_ = message.SetString(tag, "invalid page size query parameter", "ページサイズクエリパラメーターが無効です")
 	_ = message.SetString(tag, "invalid parameters: name=%s, version=%s", "無効なパラメータ: name =%s, version =%s")
+	_ = message.SetString(tag, "invalid saml response", "無効なsamlレスポンス")
+	_ = message.SetString(tag, "invalid time zone %s", "無効なタイムゾーン %s")
+	_ = message.SetString(tag, "invalid two-factor authentication code", "二要素認証コードが正しくありません")
 	_ = message.SetString(tag, "log alert rule", "ログアラート")
+	_ = message.SetString(tag, "log export", "ログエクスポート")
+	_ = message.SetString(tag, "log metric", "ログメトリクス")
 	_ = message.SetString(tag, "log snapshot", "ログスナップショット")
 	_ = message.SetString(tag, "login source not provide", "ログインソースが提供されていません")
 	_ = message.SetString(tag, "logquery history", "ログクエリ履歴")
+	_ = message.SetString(tag, "maintenance window", "メンテナンスウィンドウ")
 	_ = message.SetString(tag, "max limitation is 5000", "最大制限は5000です")
 	_ = message.SetString(tag, "message type %s is invalid", "メッセージタイプ %s が無効です")
 	_ = message.SetString(tag, "modify", "変更")
@@ -326,7 +482,11 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "monitoring collector", "モニタリングコレクター")
 	_ = message.SetString(tag, "monitoring query template", "モニタリングクエリテンプレート")
 	_ = message.SetString(tag, "namespace  %s is not allowed, it's a system retain namespace", "名前空間  %s は許可されていません、それはシステムが名前空間を保持します")
+	_ = message.SetString(tag, "namespace %s already exists in cluster %s", "クラスタ %[2]s にネームスペース %[1]s が既に存在します")
 	_ = message.SetString(tag, "namespace %s was bonded with another environment", "名前空間 %s は別の環境と結合されました")
+	_ = message.SetString(tag, "no id_token in oidc token response", "oidcトークンレスポンスにid_tokenがありません")
+	_ = message.SetString(tag, "no supported panels found in grafana dashboard", "grafana ダッシュボードに変換可能なパネルがありません")
+	_ = message.SetString(tag, "only system admin can list all approvals", "すべての承認を表示できるのはシステム管理者のみです")
 	_ = message.SetString(tag, "origin password error", "oRIGINパスワードエラー")
 	_ = message.SetString(tag, "passed", "合格")
 	_ = message.SetString(tag, "patch", "パッチ")
@@ -335,20 +495,36 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "port %s not found in Service %s", "ポート %s がサービス %sに見つかりません")
 	_ = message.SetString(tag, "prefabricated template cannot be deleted", "プレハブテンプレートは削除できません")
 	_ = message.SetString(tag, "project", "プロジェクト")
+	_ = message.SetString(tag, "project %s / cluster %s", "プロジェクト %s / クラスター %s")
 	_ = message.SetString(tag, "project %s / user %s / role %s", "プロジェクト %s /ユーザー %s /ロール %s")
+	_ = message.SetString(tag, "project cluster resource quota", "プロジェクトクラスターリソース")
+	_ = message.SetString(tag, "project cluster resource quota adjustment application", "プロジェクトクラスターリソース調整申請")
+	_ = message.SetString(tag, "project is required for %s approval", "%s の承認にはプロジェクトが必要です")
 	_ = message.SetString(tag, "project member", "プロジェクトメンバー")
+	_ = message.SetString(tag, "project name is required", "プロジェクト名は必須です")
 	_ = message.SetString(tag, "project network isolation", "プロジェクトネットワークの単離化")
 	_ = message.SetString(tag, "prometheus template %s.%s.%s %s is used by rule %s now", "プロメテウスのテンプレート %s.%s.%s %s はルール %s によって使用されます")
 	_ = message.SetString(tag, "put", "プト")
 	_ = message.SetString(tag, "recover", "回復")
 	_ = message.SetString(tag, "rejected", "拒絶されました")
 	_ = message.SetString(tag, "repo %s started syncing on background", "リポジトリ %s がバックグラウンドで同期を開始しました")
+	_ = message.SetString(tag, "report", "定期レポート")
+	_ = message.SetString(tag, "report channel must be an email channel", "レポートはメールチャネルでのみ送信できます")
+	_ = message.SetString(tag, "resource kind %s can't be cloned", "リソースの種類 %s はクローンできません")
+	_ = message.SetString(tag, "resource quota of cluster %s is required", "クラスタ %s のリソースクォータは必須です")
+	_ = message.SetString(tag, "restricted token can't be used to issue new tokens", "制限付きトークンでは新しいトークンを発行できません")
+	_ = message.SetString(tag, "role %s not valid", "ロール %s は無効です")
 	_ = message.SetString(tag, "rule %s already exist", "ルール %s は既に存在します")
+	_ = message.SetString(tag, "scope %s not valid", "スコープ %s は無効です")
 	_ = message.SetString(tag, "scrap target %s not found", "スクラップターゲット %s が見つかりません")
+	_ = message.SetString(tag, "send", "送信")
 	_ = message.SetString(tag, "set", "設定されている")
 	_ = message.SetString(tag, "set user %s to environment %s member as role %s", "ユーザー %s をロール %sとして環境 %s メンバーに設定する")
 	_ = message.SetString(tag, "set user %s to tenant %s members as role %s", "ユーザー %s をテナント %s メンバーにロール %sとして設定します。")
 	_ = message.SetString(tag, "site announcement period is invalid, the end time is earlier than the start time", "サイトのお知らせ期間が無効です。終了時刻が開始時刻よりも早くなっています")
+	_ = message.SetString(tag, "sleep", "スリープ")
+	_ = message.SetString(tag, "sleep schedule is required", "スリープスケジュールは必須です")
+	_ = message.SetString(tag, "slo window must between 7d and 90d", "SLO のウィンドウは 7d から 90d の間である必要があります")
 	_ = message.SetString(tag, "some error happend, more than one silence rule founded, please contact admin", "いくつかのエラーが発生しました。複数のサイレントルールが見つかりました。管理者にお問い合わせください。")
 	_ = message.SetString(tag, "source not exist", "ソースが存在しません")
 	_ = message.SetString(tag, "source not match", "ソースが一致しません")
@@ -359,22 +535,30 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "tenant", "テナント")
 	_ = message.SetString(tag, "tenant %s / cluster %s", "テナント %s /クラスター %s")
 	_ = message.SetString(tag, "tenant %s / user %s", "テナント %s /ユーザー %s")
+	_ = message.SetString(tag, "tenant and cluster are required for %s approval", "%s の承認にはテナントとクラスターが必要です")
+	_ = message.SetString(tag, "tenant blueprint", "テナントテンプレート")
+	_ = message.SetString(tag, "tenant blueprint %s / tenant %s", "テナントテンプレート %s / テナント %s")
 	_ = message.SetString(tag, "tenant id not valid", "テナントIDが無効です")
 	_ = message.SetString(tag, "tenant is not found", "テナントが見つかりません")
 	_ = message.SetString(tag, "tenant member", "テナントメンバー")
 	_ = message.SetString(tag, "tenant member role", "テナントメンバーの役割")
 	_ = message.SetString(tag, "tenant network isolation", "テナントネットワークの分離")
+	_ = message.SetString(tag, "tenant network policy rules", "テナントネットワークポリシールール")
+	_ = message.SetString(tag, "tenant, project or environment is required for %s approval", "%s の承認にはテナント、プロジェクト、または環境が必要です")
 	_ = message.SetString(tag, "test", "test")
+	_ = message.SetString(tag, "the cloned environment must use a different namespace", "クローンした環境は別の名前空間を使用する必要があります")
 	_ = message.SetString(tag, "the cluster with name %s existed, can't add the same one", "名前が %s のクラスターが存在しました。同じクラスターを追加することはできません")
 	_ = message.SetString(tag, "the cluster you are action is not found", "アクションを実行しているクラスタが見つかりません")
 	_ = message.SetString(tag, "the cluster you are quering in is not found", "クエリしているクラスタが見つかりません")
 	_ = message.SetString(tag, "the cluster you are quering on is not found", "クエリしているクラスターが見つかりません")
 	_ = message.SetString(tag, "the cluster you are querying doesn't exist", "クエリしているクラスターは存在しません")
+	_ = message.SetString(tag, "the current stage of this approval has already been decided", "この承認の現在の段階はすでに決定されています")
 	_ = message.SetString(tag, "the environment member role you are modifying is not exist", "変更する環境メンバーの役割が存在しません")
 	_ = message.SetString(tag, "the gateway you are deleting is a default gateway, can't delete it", "削除するゲートウェイはデフォルトのゲートウェイです。削除できません")
 	_ = message.SetString(tag, "the object or the parent object is not found", "オブジェクトまたは親オブジェクトが見つかりません")
 	_ = message.SetString(tag, "the passwords entered twice are inconsistent", "2回入力されたパスワードは一貫していない")
 	_ = message.SetString(tag, "the primary cluster existed already, more than one primary cluster is not allowed", "プライマリクラスターがすでに存在している場合、複数のプライマリクラスターを使用することはできません")
+	_ = message.SetString(tag, "the project has no resource application approval in the current cluster", "このプロジェクトには現在のクラスターでリソース申請の承認がありません")
 	_ = message.SetString(tag, "the project's default image registry ", "プロジェクトの既定のイメージレジストリ ")
 	_ = message.SetString(tag, "the tenant does not exist", "テナントが存在しません")
 	_ = message.SetString(tag, "the tenant has no enough resources in the current cluster", "テナントは現在のクラスターに十分なリソースがありません")
@@ -384,6 +568,20 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "the tenant you are quering on is not found", "クエリしているテナントが見つかりません")
 	_ = message.SetString(tag, "the user to add is not found", "追加するユーザーが見つかりません")
 	_ = message.SetString(tag, "there is no cluster resource quota adjustment approval", "クラスタリソースのクォータ調整承認がありません")
+	_ = message.SetString(tag, "token expired", "トークンの有効期限が切れています")
+	_ = message.SetString(tag, "token is not allowed to be used from %s", "%s からこのトークンを使用することはできません")
+	_ = message.SetString(tag, "token is read-only", "このトークンは読み取り専用です")
+	_ = message.SetString(tag, "token scope %s %d not found", "トークンのスコープ %s %d が見つかりません")
+	_ = message.SetString(tag, "token scopes do not allow access to this api", "トークンのスコープではこの API にアクセスできません")
+	_ = message.SetString(tag, "too many failed two-factor authentication attempts, please retry after %s", "2段階認証の失敗回数が多すぎます。%s 後に再試行してください")
+	_ = message.SetString(tag, "two-factor authentication code has already been used", "二要素認証コードは既に使用されています")
+	_ = message.SetString(tag, "two-factor authentication code required", "二要素認証コードが必要です")
+	_ = message.SetString(tag, "two-factor authentication is already enabled", "二要素認証は既に有効です")
+	_ = message.SetString(tag, "two-factor authentication is not enabled", "二要素認証が有効になっていません")
+	_ = message.SetString(tag, "two-factor authentication is not enrolled", "二要素認証が登録されていません")
+	_ = message.SetString(tag, "two-factor authentication is only available for local accounts", "二要素認証はローカルアカウントのみ利用できます")
+	_ = message.SetString(tag, "two-factor authentication is required by the security policy and can't be disabled", "セキュリティポリシーにより二要素認証が必要なため、無効にできません")
+	_ = message.SetString(tag, "two-factor authentication is required by the security policy, please enroll first", "セキュリティポリシーにより二要素認証が必要です。先に登録してください")
 	_ = message.SetString(tag, "unable to find VolumeSnapshotClass of pvc %s Provisioner=%s", "pVC %s Provisioner =%sのVolumeSnapshotClassが見つかりません")
 	_ = message.SetString(tag, "unauthorized, please login first", "認証されていません。まずログインしてください")
 	_ = message.SetString(tag, "unset", "設定されていない")
@@ -391,7 +589,11 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "update", "最新情報")
 	_ = message.SetString(tag, "update user %s to project %s members as role %s", "ユーザー %s をロール %sとしてプロジェクト %s メンバーに更新")
 	_ = message.SetString(tag, "user %s / role %s", "ユーザー %s /ロール %s")
+	_ = message.SetString(tag, "user %s applied to adjust the ResourceQuota of project %s in cluster %s", "ユーザー %s がクラスター %[3]s におけるプロジェクト %[2]s のリソース調整を申請しました")
 	_ = message.SetString(tag, "user %s applied to adjust the ResourceQuota of tenant %s in cluster %s", "ユーザー %s がクラスター %sのテナント %s のResourceQuotaを調整するために適用されました")
+	_ = message.SetString(tag, "username %s is already used by another user", "ユーザー名 %s は他のユーザーが使用しています")
+	_ = message.SetString(tag, "username or password error", "ユーザー名またはパスワードが間違っています")
+	_ = message.SetString(tag, "validate project resource quota failed: %v", "プロジェクトリソースの検証に失敗しました: %v")
 	_ = message.SetString(tag, "validate tenant resoure quota failed, can't get cluster resource statistics: %w", "テナント資源クォータの検証に失敗しました。クラスタリソースの統計情報を取得できません: %w")
 	_ = message.SetString(tag, "validate tenant resoure quota failed: %w", "テナント資源クォータの検証に失敗しました: %w")
 	_ = message.SetString(tag, "validate username and password to the registry faild: %w", "レジストリfaildへのユーザ名とパスワードの検証: %w")
@@ -401,7 +603,11 @@ func initJaJP(tag language.Tag) {
 	_ = message.SetString(tag, "virtual space member", "仮想空間部材")
 	_ = message.SetString(tag, "volume snapshot", "ボリュームスナップショット")
 	_ = message.SetString(tag, "volume snapshot to PVC", "pVCへのボリュームスナップショット")
+	_ = message.SetString(tag, "wake", "ウェイク")
 	_ = message.SetString(tag, "workload has no K8S Service: %w", "ワークロードにK 8 Sサービスがありません: %w")
+	_ = message.SetString(tag, "you are not a member of the approval scope", "あなたは申請範囲のメンバーではありません")
+	_ = message.SetString(tag, "you can't decide the current stage of this approval", "この申請の現在の段階を承認する権限がありません")
+	_ = message.SetString(tag, "you can't view this approval", "この承認を表示する権限がありません")
 	_ = message.SetString(tag, "you have no permission to do this operation", "この操作を行う権限がありません")
 	_ = message.SetString(tag, "you have no permission to do this operation, you must be an admin in any virtual space firstly", "この操作を実行する権限がない場合は、まず仮想空間の管理者である必要があります")
 	_ = message.SetString(tag, "you have no permission to operate the environment %s", "環境を操作する権限がありません %s")
@@ -411,6 +617,7 @@ func initJaJP(tag language.Tag) {
 }
 // initZhCN will init zh_CN support.
 func initZhCN(tag language.Tag) {
+	_ = message.SetString(tag, "Apply to adjust the resources of project %s in cluster %s", "申请调整项目 %s 在集群 %s 的资源")
 	_ = message.SetString(tag, "Apply to adjust the resources of tenant %s in cluster %s", "申请调整租户 %s 在集群 %s 的资源")
 	_ = message.SetString(tag, "CPU or Memory can't be empty at the same time, please provide one at least", "CPU 或内存不能同时为空，请至少提供一个")
 	_ = message.SetString(tag, "Failed to synchronize the image registry information to the cluster: %w", "无法同步镜像仓库信息到集群: %w")
@@ -424,15 +631,28 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "account", "帐户")
 	_ = message.SetString(tag, "add", "添加")
 	_ = message.SetString(tag, "add a new cluster %s into kubegems", "将 %s 新群集添加到 kubegems")
+	_ = message.SetString(tag, "add note", "添加备注")
 	_ = message.SetString(tag, "add user %s to environment %s member as role %s", "将用户 %s 添加到环境 %s 成员角色 %s")
 	_ = message.SetString(tag, "add user %s to project %s members as role %s", "将用户 %s 添加到项目 %s 成员作为角色 %s")
 	_ = message.SetString(tag, "add user %s to tenant %s members as role %s", "将用户 %s 添加到租户 %s 成员作为角色 %s")
+	_ = message.SetString(tag, "admission policy", "准入策略")
 	_ = message.SetString(tag, "alert receiver", "警报接收器")
+	_ = message.SetString(tag, "alert rule %s is managed by slo, please modify the slo instead", "告警规则 %s 由 SLO 生成, 请修改对应的 SLO")
 	_ = message.SetString(tag, "alert rule %s not found", "未找到警报规则 %s")
+	_ = message.SetString(tag, "alert rule of slo %s not found", "SLO %s 的告警规则不存在")
+	_ = message.SetString(tag, "anomaly detector", "异常检测")
+	_ = message.SetString(tag, "anomaly detector duration must between 1m and 1h", "异常检测的持续时间必须在 1m 到 1h 之间")
+	_ = message.SetString(tag, "anomaly detector weeks must between 1 and 8", "异常检测的学习周数必须在 1 到 8 之间")
 	_ = message.SetString(tag, "app %s has been collected by flow %s", "应用程序 %s 已经由 flow %s 收集。")
 	_ = message.SetString(tag, "app label %s is not valid, must be one of %v", "应用标签 %s 无效，必须是 %v 之一")
+	_ = message.SetString(tag, "application %s has changed since the approval, please apply for approval of revision %s", "应用 %s 在审批后有新的变更, 请重新申请版本 %s 的审批")
+	_ = message.SetString(tag, "approval", "审批")
+	_ = message.SetString(tag, "approval %s is %s", "审批 %s 状态为 %s")
+	_ = message.SetString(tag, "approval rule", "审批规则")
+	_ = message.SetString(tag, "approval rule of %s already exists", "%s 的审批规则已存在")
 	_ = message.SetString(tag, "auth source not exist", "身份验证源不存在")
 	_ = message.SetString(tag, "auth source not exists or not enabled", "身份验证源不存在或未启用")
+	_ = message.SetString(tag, "backtest time range must be within %s", "回测时间范围不能超过 %s")
 	_ = message.SetString(tag, "batch delete", "批量删除")
 	_ = message.SetString(tag, "can't add image registry, there must be only one default image registry", "无法添加镜像仓库，必须只有一个默认的镜像仓库")
 	_ = message.SetString(tag, "can't add log collector, must specify at least one app", "无法添加日志采集器，必须指定至少一个应用")
@@ -450,16 +670,28 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "can't set image registry, the project's default image registry can only exist one", "无法设置图像注册表，项目的默认图像注册表只能存在一个")
 	_ = message.SetString(tag, "can't update image registry, the default image registry can only exist one", "无法更新图像注册表，默认图像注册表只能存在一个")
 	_ = message.SetString(tag, "cancel", "取消")
+	_ = message.SetString(tag, "clone", "克隆")
 	_ = message.SetString(tag, "cluster", "群組")
+	_ = message.SetString(tag, "cluster %s is not found", "集群 %s 不存在")
+	_ = message.SetString(tag, "cluster %s is not in the blueprint clusters", "集群 %s 不在模板的集群中")
+	_ = message.SetString(tag, "cluster and plugin are required for %s approval", "%s 审批需要指定集群和插件")
+	_ = message.SetString(tag, "cluster name is required", "集群名不能为空")
 	_ = message.SetString(tag, "cluster resource quota", "群集资源百分比")
 	_ = message.SetString(tag, "cluster resource quota adjustment application", "群组资源配额调整应用")
 	_ = message.SetString(tag, "cluster tenant gateway", "cluster tenant gateway")
+	_ = message.SetString(tag, "comment can't be empty", "评论不能为空")
+	_ = message.SetString(tag, "cost price", "资源单价")
 	_ = message.SetString(tag, "create", "创建")
 	_ = message.SetString(tag, "created environment %s in project %s", "在项目 %s 中创建的环境 %s")
 	_ = message.SetString(tag, "created project %s in tenant %s", "在租户中创建项目 %s %s")
 	_ = message.SetString(tag, "created tenant %s", "创建租户 %s")
 	_ = message.SetString(tag, "created virtual space %s", "创建虚拟空间 %s")
 	_ = message.SetString(tag, "current environment data is abnormal, please contact the administrator", "当前环境数据异常，请联系管理员")
+	_ = message.SetString(tag, "current tenant has no resource quota on the cluster", "当前租户在该集群没有资源")
+	_ = message.SetString(tag, "dashboard name and template are required", "监控面板名和模板不能为空")
+	_ = message.SetString(tag, "dashboard name is required", "dashboard名不能为空")
+	_ = message.SetString(tag, "dashboard template %s is not found", "监控面板模板 %s 不存在")
+	_ = message.SetString(tag, "date %s not valid", "日期 %s 不合法")
 	_ = message.SetString(tag, "delete", "删除")
 	_ = message.SetString(tag, "delete project %s belong to tenant %s", "删除项目 %s 属于租户 %s")
 	_ = message.SetString(tag, "delete user %s from environment %s member", "从环境 %s 成员中删除用户 %s")
@@ -468,15 +700,24 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "deleted the cluster %s", "已删除群集 %s")
 	_ = message.SetString(tag, "deleted the environment %s in the project %s", "删除项目 %s 中的环境 %s")
 	_ = message.SetString(tag, "deleted virtual space %s", "删除虚拟空间 %s")
+	_ = message.SetString(tag, "deploying application %s to production environment %s requires an approved approval", "部署应用 %s 到生产环境 %s 需要已通过的审批")
 	_ = message.SetString(tag, "disable", "禁用")
 	_ = message.SetString(tag, "disabled tenant %s", "禁用租户 %s")
+	_ = message.SetString(tag, "duplicated cluster %s", "集群 %s 重复")
+	_ = message.SetString(tag, "duplicated environment %s", "环境 %s 重复")
 	_ = message.SetString(tag, "duplicated name in: %s", "重复的名字： %s")
+	_ = message.SetString(tag, "duplicated namespace %s", "命名空间 %s 重复")
+	_ = message.SetString(tag, "duplicated project %s", "项目 %s 重复")
 	_ = message.SetString(tag, "empty code", "code 为空")
 	_ = message.SetString(tag, "enable", "启用")
 	_ = message.SetString(tag, "enabled tenant %s", "启用租户 %s")
 	_ = message.SetString(tag, "environment", "环境")
 	_ = message.SetString(tag, "environment %s / user %s / role %s", "环境 %s / 用户 %s / 角色 %s")
+	_ = message.SetString(tag, "environment %s is not a production environment", "环境 %s 不是生产环境")
+	_ = message.SetString(tag, "environment and application are required for %s approval", "%s 审批需要指定环境和应用")
+	_ = message.SetString(tag, "environment is not cloned from other environment", "该环境不是由其他环境克隆的")
 	_ = message.SetString(tag, "environment member", "环境成员")
+	_ = message.SetString(tag, "environment name is required", "环境名不能为空")
 	_ = message.SetString(tag, "environment network isolation", "环境网络隔离")
 	_ = message.SetString(tag, "exceeding the maximum limit of 50000", "超过最大条数限制500000")
 	_ = message.SetString(tag, "exchange oauth2 token failed", "exchange oauth2 token 失败")
@@ -490,10 +731,14 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "failed to get Harbor version", "获取 Harbor 版本失败")
 	_ = message.SetString(tag, "failed to get api-server info: %v", "获取 api-server 信息失败： %v")
 	_ = message.SetString(tag, "failed to get auth source", "未能获取身份验证源")
+	_ = message.SetString(tag, "failed to get login address of source %s", "获取登录源 %s 的登录地址失败")
 	_ = message.SetString(tag, "failed to get userinfo from ldap", "从 ldap 获取用户信息失败")
 	_ = message.SetString(tag, "failed to get userinfo from ldap, more than one result", "从 ldap获取用户信息失败，多个结果")
 	_ = message.SetString(tag, "failed to get userinfo from oauth provider", "从 oauth 提供商获取用户信息失败")
+	_ = message.SetString(tag, "failed to get userinfo from oidc provider", "从 oidc 提供商获取用户信息失败")
 	_ = message.SetString(tag, "failed to get username from oauth provider", "从 oauth 提供商获取用户名失败")
+	_ = message.SetString(tag, "failed to get username from oidc provider", "从 oidc 提供商获取用户名失败")
+	_ = message.SetString(tag, "failed to get username from saml response", "从 saml 响应中获取用户名失败")
 	_ = message.SetString(tag, "failed to init k8s client: %v", "init k8s 客户端失败： %v")
 	_ = message.SetString(tag, "failed to list StorageClass: %v", "列出StorageClass失败： %v")
 	_ = message.SetString(tag, "failed to recover volume snapshot to PVC %s because the PVC status is not ready to use", "恢复PVC %s 音量快照失败，因为PVC 状态尚未准备好使用")
@@ -501,24 +746,38 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "forbidden, please login", "被禁止，请登录")
 	_ = message.SetString(tag, "gateway %s already exist", "网关 %s 已存在")
 	_ = message.SetString(tag, "gateway %s is not found", "未找到网关 %s")
+	_ = message.SetString(tag, "gateway name is required", "网关名不能为空")
 	_ = message.SetString(tag, "get prometheus label names failed, cluster: %s, promql: %s, %w", "获取 prometheus 标签名称失败，集群： %s，promql： %s， %w")
 	_ = message.SetString(tag, "get prometheus label values failed, cluster: %s, promql: %s, %w", "获取 prometheus 标签值失败，集群： %s，promql： %s， %w")
 	_ = message.SetString(tag, "grant", "授权")
+	_ = message.SetString(tag, "groupby %s not valid", "分组 %s 不合法")
 	_ = message.SetString(tag, "helm chart repo index URL is invalid: %w", "镜像仓库 index URL无效： %w")
 	_ = message.SetString(tag, "image registry", "镜像仓库")
 	_ = message.SetString(tag, "import", "导入")
+	_ = message.SetString(tag, "incident", "告警事件")
+	_ = message.SetString(tag, "instantiate", "实例化")
 	_ = message.SetString(tag, "invalid credential", "凭证无效")
+	_ = message.SetString(tag, "invalid cron expression %s: %v", "无效的 cron 表达式 %s: %v")
 	_ = message.SetString(tag, "invalid data, the environment %s related virtual space is mismatched with the virtual space you are action", "无效的数据，环境 %s 相关的虚拟空间与您所做的虚拟空间不匹配")
 	_ = message.SetString(tag, "invalid environment %s, in which a related cluster does not exist, may the related cluster was deleted", "无效的环境 %s，不存在相关集群，可能相关集群已经被删除了。")
+	_ = message.SetString(tag, "invalid id_token", "无效的 id_token")
+	_ = message.SetString(tag, "invalid incident status: %s", "无效的事件状态: %s")
+	_ = message.SetString(tag, "invalid ip or cidr %s", "无效的 IP 或 CIDR %s")
 	_ = message.SetString(tag, "invalid kubeconfig format: %v", "无效的 kubeconfig 格式： %v")
 	_ = message.SetString(tag, "invalid kubeconfig: %v", "无效的 kubeconfig： %v")
 	_ = message.SetString(tag, "invalid page number query parameter", "无效的页码查询参数")
 	_ = message.SetString(tag, "invalid page size query parameter", "无效的页面大小查询参数")
 	_ = message.SetString(tag, "invalid parameters: name=%s, version=%s", "无效参数: name=%s, version=%s")
+	_ = message.SetString(tag, "invalid saml response", "无效的 saml 响应")
+	_ = message.SetString(tag, "invalid time zone %s", "无效的时区 %s")
+	_ = message.SetString(tag, "invalid two-factor authentication code", "两步验证码错误")
 	_ = message.SetString(tag, "log alert rule", "日志警报规则")
+	_ = message.SetString(tag, "log export", "日志导出")
+	_ = message.SetString(tag, "log metric", "日志指标")
 	_ = message.SetString(tag, "log snapshot", "日志快照")
 	_ = message.SetString(tag, "login source not provide", "登录源未提供")
 	_ = message.SetString(tag, "logquery history", "日志查询历史")
+	_ = message.SetString(tag, "maintenance window", "维护窗口")
 	_ = message.SetString(tag, "max limitation is 5000", "最大限制为 5000")
 	_ = message.SetString(tag, "message type %s is invalid", "消息类型 %s 无效")
 	_ = message.SetString(tag, "modify", "修改")
@@ -528,7 +787,11 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "monitoring collector", "监控采集器")
 	_ = message.SetString(tag, "monitoring query template", "监控查询模板")
 	_ = message.SetString(tag, "namespace  %s is not allowed, it's a system retain namespace", "命名空间  %s 不被允许，它是一个系统保留命名空间")
+	_ = message.SetString(tag, "namespace %s already exists in cluster %s", "集群 %[2]s 中已经存在命名空间 %[1]s")
 	_ = message.SetString(tag, "namespace %s was bonded with another environment", "命名空间 %s 与另一个环境绑定。")
+	_ = message.SetString(tag, "no id_token in oidc token response", "oidc token 响应中没有 id_token")
+	_ = message.SetString(tag, "no supported panels found in grafana dashboard", "grafana dashboard中没有支持转换的面板")
+	_ = message.SetString(tag, "only system admin can list all approvals", "只有系统管理员可以查看所有审批")
 	_ = message.SetString(tag, "origin password error", "原始密码错误")
 	_ = message.SetString(tag, "passed", "通过")
 	_ = message.SetString(tag, "patch", "补丁")
@@ -537,20 +800,36 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "port %s not found in Service %s", "端口 %s 在服务 %s 中未找到")
 	_ = message.SetString(tag, "prefabricated template cannot be deleted", "预制模板不能删除")
 	_ = message.SetString(tag, "project", "项目")
+	_ = message.SetString(tag, "project %s / cluster %s", "项目 %s / 集群 %s")
 	_ = message.SetString(tag, "project %s / user %s / role %s", "项目 %s / 用户 %s / 角色 %s")
+	_ = message.SetString(tag, "project cluster resource quota", "项目集群资源")
+	_ = message.SetString(tag, "project cluster resource quota adjustment application", "项目集群资源调整申请")
+	_ = message.SetString(tag, "project is required for %s approval", "%s 审批需要指定项目")
 	_ = message.SetString(tag, "project member", "项目成员")
+	_ = message.SetString(tag, "project name is required", "项目名不能为空")
 	_ = message.SetString(tag, "project network isolation", "项目网络隔离模式")
 	_ = message.SetString(tag, "prometheus template %s.%s.%s %s is used by rule %s now", "prometheus模板 %s.%s.%s %s 现在被规则 %s 使用")
 	_ = message.SetString(tag, "put", "修改")
 	_ = message.SetString(tag, "recover", "恢复")
 	_ = message.SetString(tag, "rejected", "已拒绝")
 	_ = message.SetString(tag, "repo %s started syncing on background", "repo %s 在后台开始同步")
+	_ = message.SetString(tag, "report", "定时报告")
+	_ = message.SetString(tag, "report channel must be an email channel", "报告只能通过邮件渠道发送")
+	_ = message.SetString(tag, "resource kind %s can't be cloned", "资源类型 %s 不支持克隆")
+	_ = message.SetString(tag, "resource quota of cluster %s is required", "集群 %s 的资源限制不能为空")
+	_ = message.SetString(tag, "restricted token can't be used to issue new tokens", "受限的令牌不能用于签发新的令牌")
+	_ = message.SetString(tag, "role %s not valid", "角色 %s 无效")
 	_ = message.SetString(tag, "rule %s already exist", "规则 %s 已存在")
+	_ = message.SetString(tag, "scope %s not valid", "范围 %s 无效")
 	_ = message.SetString(tag, "scrap target %s not found", "找不到抓取目标 %s")
+	_ = message.SetString(tag, "send", "发送")
 	_ = message.SetString(tag, "set", "设置")
 	_ = message.SetString(tag, "set user %s to environment %s member as role %s", "设置用户 %s 为环境 %s 成员为角色 %s")
 	_ = message.SetString(tag, "set user %s to tenant %s members as role %s", "设置用户 %s 为租户成员 %s 为角色 %s")
 	_ = message.SetString(tag, "site announcement period is invalid, the end time is earlier than the start time", "站点通知时间无效，结束时间早于开始时间")
+	_ = message.SetString(tag, "sleep", "休眠")
+	_ = message.SetString(tag, "sleep schedule is required", "休眠时间不能为空")
+	_ = message.SetString(tag, "slo window must between 7d and 90d", "SLO 统计周期必须在 7d 到 90d 之间")
 	_ = message.SetString(tag, "some error happend, more than one silence rule founded, please contact admin", "出现了一些错误，创建了一个以上的静音规则，请联系管理员")
 	_ = message.SetString(tag, "source not exist", "源不存在")
 	_ = message.SetString(tag, "source not match", "源不匹配")
@@ -561,22 +840,30 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "tenant", "租户")
 	_ = message.SetString(tag, "tenant %s / cluster %s", "租户 %s / 组 %s")
 	_ = message.SetString(tag, "tenant %s / user %s", "租户 %s / 用户 %s")
+	_ = message.SetString(tag, "tenant and cluster are required for %s approval", "%s 审批需要指定租户和集群")
+	_ = message.SetString(tag, "tenant blueprint", "租户模板")
+	_ = message.SetString(tag, "tenant blueprint %s / tenant %s", "租户模板 %s / 租户 %s")
 	_ = message.SetString(tag, "tenant id not valid", "tenant id not valid")
 	_ = message.SetString(tag, "tenant is not found", "找不到租户")
 	_ = message.SetString(tag, "tenant member", "租户成员")
 	_ = message.SetString(tag, "tenant member role", "租户成员角色")
 	_ = message.SetString(tag, "tenant network isolation", "租户网络隔离")
+	_ = message.SetString(tag, "tenant network policy rules", "租户网络策略规则")
+	_ = message.SetString(tag, "tenant, project or environment is required for %s approval", "%s 审批需要指定租户、项目或环境")
 	_ = message.SetString(tag, "test", "测试")
+	_ = message.SetString(tag, "the cloned environment must use a different namespace", "克隆的环境必须使用不同的命名空间")
 	_ = message.SetString(tag, "the cluster with name %s existed, can't add the same one", "名为 %s 的集群已存在，无法添加相同的集群。")
 	_ = message.SetString(tag, "the cluster you are action is not found", "找不到您要操作的数据组")
 	_ = message.SetString(tag, "the cluster you are quering in is not found", "您正在查找的集群未找到")
 	_ = message.SetString(tag, "the cluster you are quering on is not found", "找不到您正在查询的集群")
 	_ = message.SetString(tag, "the cluster you are querying doesn't exist", "您正在查询的集群不存在")
+	_ = message.SetString(tag, "the current stage of this approval has already been decided", "该审批的当前阶段已经被审批")
 	_ = message.SetString(tag, "the environment member role you are modifying is not exist", "您正在修改的环境成员角色不存在")
 	_ = message.SetString(tag, "the gateway you are deleting is a default gateway, can't delete it", "您正在删除的网关是默认网关，不能删除它")
 	_ = message.SetString(tag, "the object or the parent object is not found", "找不到对象或父对象")
 	_ = message.SetString(tag, "the passwords entered twice are inconsistent", "两次输入的密码不一致")
 	_ = message.SetString(tag, "the primary cluster existed already, more than one primary cluster is not allowed", "主集群已经存在。不允许多个主集群。")
+	_ = message.SetString(tag, "the project has no resource application approval in the current cluster", "该项目在当前集群没有资源申请审批")
 	_ = message.SetString(tag, "the project's default image registry ", "项目的默认镜像仓库 ")
 	_ = message.SetString(tag, "the tenant does not exist", "租户不存在")
 	_ = message.SetString(tag, "the tenant has no enough resources in the current cluster", "租户在当前集群中没有足够的资源")
@@ -586,6 +873,20 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "the tenant you are quering on is not found", "找不到您正在查询的租户")
 	_ = message.SetString(tag, "the user to add is not found", "找不到要添加的用户")
 	_ = message.SetString(tag, "there is no cluster resource quota adjustment approval", "没有集群资源配额调整批准")
+	_ = message.SetString(tag, "token expired", "令牌已过期")
+	_ = message.SetString(tag, "token is not allowed to be used from %s", "不允许从 %s 使用该令牌")
+	_ = message.SetString(tag, "token is read-only", "该令牌为只读令牌")
+	_ = message.SetString(tag, "token scope %s %d not found", "令牌范围 %s %d 不存在")
+	_ = message.SetString(tag, "token scopes do not allow access to this api", "令牌的访问范围不允许访问该接口")
+	_ = message.SetString(tag, "too many failed two-factor authentication attempts, please retry after %s", "两步验证失败次数过多, 请在 %s 后重试")
+	_ = message.SetString(tag, "two-factor authentication code has already been used", "两步验证码已被使用")
+	_ = message.SetString(tag, "two-factor authentication code required", "需要两步验证码")
+	_ = message.SetString(tag, "two-factor authentication is already enabled", "两步验证已开启")
+	_ = message.SetString(tag, "two-factor authentication is not enabled", "两步验证未开启")
+	_ = message.SetString(tag, "two-factor authentication is not enrolled", "尚未绑定两步验证")
+	_ = message.SetString(tag, "two-factor authentication is only available for local accounts", "两步验证仅适用于本地账号")
+	_ = message.SetString(tag, "two-factor authentication is required by the security policy and can't be disabled", "安全策略要求开启两步验证, 不允许关闭")
+	_ = message.SetString(tag, "two-factor authentication is required by the security policy, please enroll first", "安全策略要求开启两步验证, 请先完成绑定")
 	_ = message.SetString(tag, "unable to find VolumeSnapshotClass of pvc %s Provisioner=%s", "无法找到 pvc 的 %s Provisioner=%s 的 VolumeSnapshotClass")
 	_ = message.SetString(tag, "unauthorized, please login first", "未授权, 请先登录")
 	_ = message.SetString(tag, "unset", "取消设置")
@@ -593,7 +894,11 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "update", "更新")
 	_ = message.SetString(tag, "update user %s to project %s members as role %s", "更新用户 %s 为项目 %s 成员为角色 %s")
 	_ = message.SetString(tag, "user %s / role %s", "用户 %s / 角色 %s")
+	_ = message.SetString(tag, "user %s applied to adjust the ResourceQuota of project %s in cluster %s", "用户 %s 申请调整项目 %s 在集群 %s 的资源")
 	_ = message.SetString(tag, "user %s applied to adjust the ResourceQuota of tenant %s in cluster %s", "用户 %s 申请调整租户 %s 在集群 %s 中的资源")
+	_ = message.SetString(tag, "username %s is already used by another user", "用户名 %s 已被其他用户使用")
+	_ = message.SetString(tag, "username or password error", "用户名或密码错误")
+	_ = message.SetString(tag, "validate project resource quota failed: %v", "项目资源校验失败: %v")
 	_ = message.SetString(tag, "validate tenant resoure quota failed, can't get cluster resource statistics: %w", "验证租户资源配额失败，无法获取集群资源统计： %w")
 	_ = message.SetString(tag, "validate tenant resoure quota failed: %w", "验证租户资源配额失败： %w")
 	_ = message.SetString(tag, "validate username and password to the registry faild: %w", "验证镜像仓库的用户名和密码失败： %w")
@@ -603,7 +908,11 @@ func initZhCN(tag language.Tag) {
 	_ = message.SetString(tag, "virtual space member", "虚拟空间成员")
 	_ = message.SetString(tag, "volume snapshot", "卷快照")
 	_ = message.SetString(tag, "volume snapshot to PVC", "卷快照到 PVC")
+	_ = message.SetString(tag, "wake", "唤醒")
 	_ = message.SetString(tag, "workload has no K8S Service: %w", "workload 没有 K8S Service： %w")
+	_ = message.SetString(tag, "you are not a member of the approval scope", "你不是申请范围内的成员")
+	_ = message.SetString(tag, "you can't decide the current stage of this approval", "你不能审批该申请的当前阶段")
+	_ = message.SetString(tag, "you can't view this approval", "你不能查看该审批")
 	_ = message.SetString(tag, "you have no permission to do this operation", "您没有进行此操作的权限")
 	_ = message.SetString(tag, "you have no permission to do this operation, you must be an admin in any virtual space firstly", "您没有进行此操作的权限，您必须首先是任何虚拟空间的管理员")
 	_ = message.SetString(tag, "you have no permission to operate the environment %s", "您没有权限操作环境 %s")
@@ -613,6 +922,7 @@ func initZhCN(tag language.Tag) {
 }
 // initZhTW will init zh_TW support.
 func initZhTW(tag language.Tag) {
+	_ = message.SetString(tag, "Apply to adjust the resources of project %s in cluster %s", "申請調整項目 %s 在集群 %s 的資源")
 	_ = message.SetString(tag, "Apply to adjust the resources of tenant %s in cluster %s", "申請調整集群 %s租戶 %s 的資源")
 	_ = message.SetString(tag, "CPU or Memory can't be empty at the same time, please provide one at least", "CPU 或記憶體不能同時為空，請至少提供一個")
 	_ = message.SetString(tag, "Failed to synchronize the image registry information to the cluster: %w", "無法將映像註冊表資訊同步到群集： %w")
@@ -626,15 +936,28 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "account", "帳戶")
 	_ = message.SetString(tag, "add", "加")
 	_ = message.SetString(tag, "add a new cluster %s into kubegems", "將新的集群 %s 添加到 kubegems 中")
+	_ = message.SetString(tag, "add note", "添加備註")
 	_ = message.SetString(tag, "add user %s to environment %s member as role %s", "將使用者 %s 作為角色 %s添加到環境 %s 成員")
 	_ = message.SetString(tag, "add user %s to project %s members as role %s", "將使用者 %s 作為角色 %s添加到專案 %s 成員")
 	_ = message.SetString(tag, "add user %s to tenant %s members as role %s", "將使用者 %s 作為角色 %s添加到租戶 %s 成員")
+	_ = message.SetString(tag, "admission policy", "准入策略")
 	_ = message.SetString(tag, "alert receiver", "警報接收器")
+	_ = message.SetString(tag, "alert rule %s is managed by slo, please modify the slo instead", "告警規則 %s 由 SLO 生成, 請修改對應的 SLO")
 	_ = message.SetString(tag, "alert rule %s not found", "找不到警報規則 %s")
+	_ = message.SetString(tag, "alert rule of slo %s not found", "SLO %s 的告警規則不存在")
+	_ = message.SetString(tag, "anomaly detector", "異常檢測")
+	_ = message.SetString(tag, "anomaly detector duration must between 1m and 1h", "異常檢測的持續時間必須在 1m 到 1h 之間")
+	_ = message.SetString(tag, "anomaly detector weeks must between 1 and 8", "異常檢測的學習週數必須在 1 到 8 之間")
 	_ = message.SetString(tag, "app %s has been collected by flow %s", "應用 %s 已由流 %s收集")
 	_ = message.SetString(tag, "app label %s is not valid, must be one of %v", "應用標籤 %s 無效，必須是 %v之一")
+	_ = message.SetString(tag, "application %s has changed since the approval, please apply for approval of revision %s", "應用 %s 在審批後有新的變更, 請重新申請版本 %s 的審批")
+	_ = message.SetString(tag, "approval", "審批")
+	_ = message.SetString(tag, "approval %s is %s", "審批 %s 狀態為 %s")
+	_ = message.SetString(tag, "approval rule", "審批規則")
+	_ = message.SetString(tag, "approval rule of %s already exists", "%s 的審批規則已存在")
 	_ = message.SetString(tag, "auth source not exist", "身份驗證源不存在")
 	_ = message.SetString(tag, "auth source not exists or not enabled", "身份驗證源不存在或未啟用")
+	_ = message.SetString(tag, "backtest time range must be within %s", "回測時間範圍不能超過 %s")
 	_ = message.SetString(tag, "batch delete", "批量刪除")
 	_ = message.SetString(tag, "can't add image registry, there must be only one default image registry", "無法添加映像註冊表，只能有一個預設映像註冊表")
 	_ = message.SetString(tag, "can't add log collector, must specify at least one app", "無法添加日誌收集器，必須至少指定一個應用")
@@ -652,16 +975,28 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "can't set image registry, the project's default image registry can only exist one", "無法設置映像註冊表，項目的預設映像註冊表只能存在一個")
 	_ = message.SetString(tag, "can't update image registry, the default image registry can only exist one", "無法更新映像註冊表，預設映像註冊表只能存在一個")
 	_ = message.SetString(tag, "cancel", "取消")
+	_ = message.SetString(tag, "clone", "克隆")
 	_ = message.SetString(tag, "cluster", "簇")
+	_ = message.SetString(tag, "cluster %s is not found", "叢集 %s 不存在")
+	_ = message.SetString(tag, "cluster %s is not in the blueprint clusters", "叢集 %s 不在模板的叢集中")
+	_ = message.SetString(tag, "cluster and plugin are required for %s approval", "%s 審批需要指定集群和插件")
+	_ = message.SetString(tag, "cluster name is required", "叢集名不能為空")
 	_ = message.SetString(tag, "cluster resource quota", "群集資源配額")
 	_ = message.SetString(tag, "cluster resource quota adjustment application", "集群資源配額調整應用")
 	_ = message.SetString(tag, "cluster tenant gateway", "群集租戶閘道")
+	_ = message.SetString(tag, "comment can't be empty", "評論不能為空")
+	_ = message.SetString(tag, "cost price", "資源單價")
 	_ = message.SetString(tag, "create", "創造")
 	_ = message.SetString(tag, "created environment %s in project %s", "在專案 %s中建立的環境 %s")
 	_ = message.SetString(tag, "created project %s in tenant %s", "在租戶 %s中創建的專案 %s")
 	_ = message.SetString(tag, "created tenant %s", "已創建租戶 %s")
 	_ = message.SetString(tag, "created virtual space %s", "已建立虛擬空間 %s")
 	_ = message.SetString(tag, "current environment data is abnormal, please contact the administrator", "當前環境數據異常，請聯繫管理員")
+	_ = message.SetString(tag, "current tenant has no resource quota on the cluster", "當前租戶在該集群沒有資源")
+	_ = message.SetString(tag, "dashboard name and template are required", "監控面板名和模板不能為空")
+	_ = message.SetString(tag, "dashboard name is required", "dashboard名不能為空")
+	_ = message.SetString(tag, "dashboard template %s is not found", "監控面板模板 %s 不存在")
+	_ = message.SetString(tag, "date %s not valid", "日期 %s 不合法")
 	_ = message.SetString(tag, "delete", "刪除")
 	_ = message.SetString(tag, "delete project %s belong to tenant %s", "刪除屬於租戶 %s的專案 %s")
 	_ = message.SetString(tag, "delete user %s from environment %s member", "從環境 %s 成員中刪除使用者 %s")
@@ -670,15 +1005,24 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "deleted the cluster %s", "刪除群集 %s")
 	_ = message.SetString(tag, "deleted the environment %s in the project %s", "刪除了項目 %s中的環境 %s")
 	_ = message.SetString(tag, "deleted virtual space %s", "已刪除的虛擬空間 %s")
+	_ = message.SetString(tag, "deploying application %s to production environment %s requires an approved approval", "部署應用 %s 到生產環境 %s 需要已通過的審批")
 	_ = message.SetString(tag, "disable", "禁用")
 	_ = message.SetString(tag, "disabled tenant %s", "禁用的租戶 %s")
+	_ = message.SetString(tag, "duplicated cluster %s", "叢集 %s 重複")
+	_ = message.SetString(tag, "duplicated environment %s", "環境 %s 重複")
 	_ = message.SetString(tag, "duplicated name in: %s", "重複的名稱： %s")
+	_ = message.SetString(tag, "duplicated namespace %s", "命名空間 %s 重複")
+	_ = message.SetString(tag, "duplicated project %s", "項目 %s 重複")
 	_ = message.SetString(tag, "empty code", "空代碼")
 	_ = message.SetString(tag, "enable", "使")
 	_ = message.SetString(tag, "enabled tenant %s", "啟用的租戶 %s")
 	_ = message.SetString(tag, "environment", "環境")
 	_ = message.SetString(tag, "environment %s / user %s / role %s", "環境 %s /使用者 %s /角色 %s")
+	_ = message.SetString(tag, "environment %s is not a production environment", "環境 %s 不是生產環境")
+	_ = message.SetString(tag, "environment and application are required for %s approval", "%s 審批需要指定環境和應用")
+	_ = message.SetString(tag, "environment is not cloned from other environment", "該環境不是由其他環境克隆的")
 	_ = message.SetString(tag, "environment member", "環境成員")
+	_ = message.SetString(tag, "environment name is required", "環境名不能為空")
 	_ = message.SetString(tag, "environment network isolation", "環境網路隔離")
 	_ = message.SetString(tag, "exceeding the maximum limit of 50000", "超過50000的最大限制")
 	_ = message.SetString(tag, "exchange oauth2 token failed", "交換 oauth2 令牌失敗")
@@ -692,10 +1036,14 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "failed to get Harbor version", "未能獲得海港版本")
 	_ = message.SetString(tag, "failed to get api-server info: %v", "未能取得 api 伺服器資訊： %v")
 	_ = message.SetString(tag, "failed to get auth source", "未能獲取身份驗證源")
+	_ = message.SetString(tag, "failed to get login address of source %s", "獲取登入來源 %s 的登入地址失敗")
 	_ = message.SetString(tag, "failed to get userinfo from ldap", "未能從 ldap 獲取用戶資訊")
 	_ = message.SetString(tag, "failed to get userinfo from ldap, more than one result", "未能從 ldap 獲取使用者資訊，多個結果")
 	_ = message.SetString(tag, "failed to get userinfo from oauth provider", "未能從 oauth 供應商處獲取用戶資訊")
+	_ = message.SetString(tag, "failed to get userinfo from oidc provider", "從 oidc 提供商獲取使用者資訊失敗")
 	_ = message.SetString(tag, "failed to get username from oauth provider", "未能從 oauth 提供商處獲取使用者名")
+	_ = message.SetString(tag, "failed to get username from oidc provider", "未能從 oidc 提供商處獲取使用者名")
+	_ = message.SetString(tag, "failed to get username from saml response", "未能從 saml 回應中獲取使用者名")
 	_ = message.SetString(tag, "failed to init k8s client: %v", "未能初始化 k8s 用戶端： %v")
 	_ = message.SetString(tag, "failed to list StorageClass: %v", "未能列出儲存類： %v")
 	_ = message.SetString(tag, "failed to recover volume snapshot to PVC %s because the PVC status is not ready to use", "未能將捲快照恢復到 PVC %s ，因為 PVC 狀態尚未準備好使用")
@@ -703,24 +1051,38 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "forbidden, please login", "禁止，請登錄")
 	_ = message.SetString(tag, "gateway %s already exist", "閘道 %s 已存在")
 	_ = message.SetString(tag, "gateway %s is not found", "找不到閘道 %s")
+	_ = message.SetString(tag, "gateway name is required", "閘道名不能為空")
 	_ = message.SetString(tag, "get prometheus label names failed, cluster: %s, promql: %s, %w", "取得普羅米修斯標籤名稱失敗， 集群： %s， promql： %s， %w")
 	_ = message.SetString(tag, "get prometheus label values failed, cluster: %s, promql: %s, %w", "取得普羅米修斯標籤值失敗， 聚類： %s， promql： %s， %w")
 	_ = message.SetString(tag, "grant", "授予")
+	_ = message.SetString(tag, "groupby %s not valid", "分組 %s 不合法")
 	_ = message.SetString(tag, "helm chart repo index URL is invalid: %w", "頭盔圖表存儲庫索引 URL 無效： %w")
 	_ = message.SetString(tag, "image registry", "映像註冊表")
 	_ = message.SetString(tag, "import", "進口")
+	_ = message.SetString(tag, "incident", "告警事件")
+	_ = message.SetString(tag, "instantiate", "實例化")
 	_ = message.SetString(tag, "invalid credential", "憑據無效")
+	_ = message.SetString(tag, "invalid cron expression %s: %v", "無效的 cron 表達式 %s: %v")
 	_ = message.SetString(tag, "invalid data, the environment %s related virtual space is mismatched with the virtual space you are action", "無效數據，環境 %s 相關虛擬空間與您操作的虛擬空間不匹配")
 	_ = message.SetString(tag, "invalid environment %s, in which a related cluster does not exist, may the related cluster was deleted", "%s環境無效，其中相關集群不存在，則可能已刪除相關集群")
+	_ = message.SetString(tag, "invalid id_token", "無效的 id_token")
+	_ = message.SetString(tag, "invalid incident status: %s", "無效的事件狀態: %s")
+	_ = message.SetString(tag, "invalid ip or cidr %s", "無效的 IP 或 CIDR %s")
 	_ = message.SetString(tag, "invalid kubeconfig format: %v", "無效的 kubeconfig 格式： %v")
 	_ = message.SetString(tag, "invalid kubeconfig: %v", "無效的 kubeconfig： %v")
 	_ = message.SetString(tag, "invalid page number query parameter", "頁碼查詢參數無效")
 	_ = message.SetString(tag, "invalid page size query parameter", "無效的頁面大小查詢參數")
 	_ = message.SetString(tag, "invalid parameters: name=%s, version=%s", "參數無效：名稱 =%s，版本 =%s")
+	_ = message.SetString(tag, "invalid saml response", "無效的 saml 回應")
+	_ = message.SetString(tag, "invalid time zone %s", "無效的時區 %s")
+	_ = message.SetString(tag, "invalid two-factor authentication code", "兩步驟驗證碼錯誤")
 	_ = message.SetString(tag, "log alert rule", "日誌報警規則")
+	_ = message.SetString(tag, "log export", "日誌導出")
+	_ = message.SetString(tag, "log metric", "日誌指標")
 	_ = message.SetString(tag, "log snapshot", "日誌快照")
 	_ = message.SetString(tag, "login source not provide", "登錄源不提供")
 	_ = message.SetString(tag, "logquery history", "日誌查詢歷史記錄")
+	_ = message.SetString(tag, "maintenance window", "維護窗口")
 	_ = message.SetString(tag, "max limitation is 5000", "最大限制為5000")
 	_ = message.SetString(tag, "message type %s is invalid", "消息類型 %s 無效")
 	_ = message.SetString(tag, "modify", "修改")
@@ -730,7 +1092,11 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "monitoring collector", "監控收集器")
 	_ = message.SetString(tag, "monitoring query template", "監控查詢範本")
 	_ = message.SetString(tag, "namespace  %s is not allowed, it's a system retain namespace", "命名空間  %s 不允許，它是一個系統保留命名空間")
+	_ = message.SetString(tag, "namespace %s already exists in cluster %s", "叢集 %[2]s 中已經存在命名空間 %[1]s")
 	_ = message.SetString(tag, "namespace %s was bonded with another environment", "命名空間 %s 已綁定到另一個環境")
+	_ = message.SetString(tag, "no id_token in oidc token response", "oidc token 回應中沒有 id_token")
+	_ = message.SetString(tag, "no supported panels found in grafana dashboard", "grafana dashboard中沒有支持轉換的面板")
+	_ = message.SetString(tag, "only system admin can list all approvals", "只有系統管理員可以查看所有審批")
 	_ = message.SetString(tag, "origin password error", "源密碼錯誤")
 	_ = message.SetString(tag, "passed", "通過")
 	_ = message.SetString(tag, "patch", "補丁")
@@ -739,20 +1105,36 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "port %s not found in Service %s", "在服務 %s中找不到埠 %s")
 	_ = message.SetString(tag, "prefabricated template cannot be deleted", "無法刪除預製範本")
 	_ = message.SetString(tag, "project", "專案")
+	_ = message.SetString(tag, "project %s / cluster %s", "項目 %s / 集群 %s")
 	_ = message.SetString(tag, "project %s / user %s / role %s", "專案 %s /使用者 %s /角色 %s")
+	_ = message.SetString(tag, "project cluster resource quota", "項目集群資源")
+	_ = message.SetString(tag, "project cluster resource quota adjustment application", "項目集群資源調整申請")
+	_ = message.SetString(tag, "project is required for %s approval", "%s 審批需要指定項目")
 	_ = message.SetString(tag, "project member", "項目成員")
+	_ = message.SetString(tag, "project name is required", "項目名不能為空")
 	_ = message.SetString(tag, "project network isolation", "項目網路隔離")
 	_ = message.SetString(tag, "prometheus template %s.%s.%s %s is used by rule %s now", "普羅米修斯範本 %s.%s.%s %s 現在由規則 %s 使用")
 	_ = message.SetString(tag, "put", "放")
 	_ = message.SetString(tag, "recover", "恢復")
 	_ = message.SetString(tag, "rejected", "拒絕")
 	_ = message.SetString(tag, "repo %s started syncing on background", "存儲庫 %s 開始在後台同步")
+	_ = message.SetString(tag, "report", "定時報告")
+	_ = message.SetString(tag, "report channel must be an email channel", "報告只能通過郵件渠道發送")
+	_ = message.SetString(tag, "resource kind %s can't be cloned", "資源類型 %s 不支持克隆")
+	_ = message.SetString(tag, "resource quota of cluster %s is required", "叢集 %s 的資源限制不能為空")
+	_ = message.SetString(tag, "restricted token can't be used to issue new tokens", "受限的令牌不能用於簽發新的令牌")
+	_ = message.SetString(tag, "role %s not valid", "角色 %s 無效")
 	_ = message.SetString(tag, "rule %s already exist", "規則 %s 已存在")
+	_ = message.SetString(tag, "scope %s not valid", "範圍 %s 無效")
 	_ = message.SetString(tag, "scrap target %s not found", "找不到報廢目標 %s")
+	_ = message.SetString(tag, "send", "發送")
 	_ = message.SetString(tag, "set", "設置")
 	_ = message.SetString(tag, "set user %s to environment %s member as role %s", "將使用者 %s 設置為角色 %s%s 成員的環境")
 	_ = message.SetString(tag, "set user %s to tenant %s members as role %s", "將租戶 %s 成員的使用者 %s 設置為角色 %s")
 	_ = message.SetString(tag, "site announcement period is invalid, the end time is earlier than the start time", "網站公告週期無效，結束時間早於開始時間")
+	_ = message.SetString(tag, "sleep", "休眠")
+	_ = message.SetString(tag, "sleep schedule is required", "休眠時間不能為空")
+	_ = message.SetString(tag, "slo window must between 7d and 90d", "SLO 統計週期必須在 7d 到 90d 之間")
 	_ = message.SetString(tag, "some error happend, more than one silence rule founded, please contact admin", "發生了一些錯誤，建立了多個靜音規則，請聯繫管理員")
 	_ = message.SetString(tag, "source not exist", "源不存在")
 	_ = message.SetString(tag, "source not match", "源不匹配")
@@ -763,22 +1145,30 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "tenant", "房客")
 	_ = message.SetString(tag, "tenant %s / cluster %s", "租戶 %s /群集 %s")
 	_ = message.SetString(tag, "tenant %s / user %s", "租戶 %s /使用者 %s")
+	_ = message.SetString(tag, "tenant and cluster are required for %s approval", "%s 審批需要指定租戶和集群")
+	_ = message.SetString(tag, "tenant blueprint", "租戶模板")
+	_ = message.SetString(tag, "tenant blueprint %s / tenant %s", "租戶模板 %s / 租戶 %s")
 	_ = message.SetString(tag, "tenant id not valid", "租戶ID無效")
 	_ = message.SetString(tag, "tenant is not found", "找不到租戶")
 	_ = message.SetString(tag, "tenant member", "租戶成員")
 	_ = message.SetString(tag, "tenant member role", "租戶成員角色")
 	_ = message.SetString(tag, "tenant network isolation", "租戶網路隔離")
+	_ = message.SetString(tag, "tenant network policy rules", "租戶網絡策略規則")
+	_ = message.SetString(tag, "tenant, project or environment is required for %s approval", "%s 審批需要指定租戶、項目或環境")
 	_ = message.SetString(tag, "test", "測試")
+	_ = message.SetString(tag, "the cloned environment must use a different namespace", "克隆的環境必須使用不同的命名空間")
 	_ = message.SetString(tag, "the cluster with name %s existed, can't add the same one", "名稱 %s 存在的群集，無法添加相同的群集")
 	_ = message.SetString(tag, "the cluster you are action is not found", "未找到您要操作的群集")
 	_ = message.SetString(tag, "the cluster you are quering in is not found", "未找到您正在查詢的叢集")
 	_ = message.SetString(tag, "the cluster you are quering on is not found", "未找到您正在查詢的叢集")
 	_ = message.SetString(tag, "the cluster you are querying doesn't exist", "您正在查詢的集群不存在")
+	_ = message.SetString(tag, "the current stage of this approval has already been decided", "該審批的當前階段已經被審批")
 	_ = message.SetString(tag, "the environment member role you are modifying is not exist", "您正在修改的環境成員角色不存在")
 	_ = message.SetString(tag, "the gateway you are deleting is a default gateway, can't delete it", "要刪除的閘道是預設閘道，無法將其刪除")
 	_ = message.SetString(tag, "the object or the parent object is not found", "找不到物件或父物件")
 	_ = message.SetString(tag, "the passwords entered twice are inconsistent", "輸入兩次的密碼不一致")
 	_ = message.SetString(tag, "the primary cluster existed already, more than one primary cluster is not allowed", "主集群已經存在，不允許多個主集群")
+	_ = message.SetString(tag, "the project has no resource application approval in the current cluster", "該項目在當前集群沒有資源申請審批")
 	_ = message.SetString(tag, "the project's default image registry ", "項目的預設映像註冊表 ")
 	_ = message.SetString(tag, "the tenant does not exist", "租戶不存在")
 	_ = message.SetString(tag, "the tenant has no enough resources in the current cluster", "租戶在當前群集中沒有足夠的資源")
@@ -788,6 +1178,20 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "the tenant you are quering on is not found", "未找到您正在查詢的租戶")
 	_ = message.SetString(tag, "the user to add is not found", "找不到要添加的使用者")
 	_ = message.SetString(tag, "there is no cluster resource quota adjustment approval", "沒有集群資源配額調整審批")
+	_ = message.SetString(tag, "token expired", "令牌已過期")
+	_ = message.SetString(tag, "token is not allowed to be used from %s", "不允許從 %s 使用該令牌")
+	_ = message.SetString(tag, "token is read-only", "該令牌為唯讀令牌")
+	_ = message.SetString(tag, "token scope %s %d not found", "令牌範圍 %s %d 不存在")
+	_ = message.SetString(tag, "token scopes do not allow access to this api", "令牌的訪問範圍不允許訪問該接口")
+	_ = message.SetString(tag, "too many failed two-factor authentication attempts, please retry after %s", "兩步驗證失敗次數過多, 請在 %s 後重試")
+	_ = message.SetString(tag, "two-factor authentication code has already been used", "兩步驟驗證碼已被使用")
+	_ = message.SetString(tag, "two-factor authentication code required", "需要兩步驟驗證碼")
+	_ = message.SetString(tag, "two-factor authentication is already enabled", "兩步驟驗證已開啟")
+	_ = message.SetString(tag, "two-factor authentication is not enabled", "兩步驟驗證未開啟")
+	_ = message.SetString(tag, "two-factor authentication is not enrolled", "尚未綁定兩步驟驗證")
+	_ = message.SetString(tag, "two-factor authentication is only available for local accounts", "兩步驟驗證僅適用於本機帳號")
+	_ = message.SetString(tag, "two-factor authentication is required by the security policy and can't be disabled", "安全策略要求開啟兩步驟驗證, 不允許關閉")
+	_ = message.SetString(tag, "two-factor authentication is required by the security policy, please enroll first", "安全策略要求開啟兩步驟驗證, 請先完成綁定")
 	_ = message.SetString(tag, "unable to find VolumeSnapshotClass of pvc %s Provisioner=%s", "找不到捲快照 pvc %s 提供器的類 =%s")
 	_ = message.SetString(tag, "unauthorized, please login first", "未經授權，請先登錄")
 	_ = message.SetString(tag, "unset", "未凝固的")
@@ -795,7 +1199,11 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "update", "更新")
 	_ = message.SetString(tag, "update user %s to project %s members as role %s", "將使用者 %s 更新為 %s 成員作為角色 %s")
 	_ = message.SetString(tag, "user %s / role %s", "使用者 %s /角色 %s")
+	_ = message.SetString(tag, "user %s applied to adjust the ResourceQuota of project %s in cluster %s", "用戶 %s 申請調整項目 %s 在集群 %s 的資源")
 	_ = message.SetString(tag, "user %s applied to adjust the ResourceQuota of tenant %s in cluster %s", "應用使用者 %s 調整群集 %s中租戶 %s 的資源庫")
+	_ = message.SetString(tag, "username %s is already used by another user", "用戶名 %s 已被其他用戶使用")
+	_ = message.SetString(tag, "username or password error", "使用者名稱或密碼錯誤")
+	_ = message.SetString(tag, "validate project resource quota failed: %v", "項目資源校驗失敗: %v")
 	_ = message.SetString(tag, "validate tenant resoure quota failed, can't get cluster resource statistics: %w", "驗證租戶資源配額失敗，無法獲取群集資源統計資訊： %w")
 	_ = message.SetString(tag, "validate tenant resoure quota failed: %w", "驗證租戶資源配額失敗： %w")
 	_ = message.SetString(tag, "validate username and password to the registry faild: %w", "驗證註冊表的使用者名和密碼失敗： %w")
@@ -805,7 +1213,11 @@ func initZhTW(tag language.Tag) {
 	_ = message.SetString(tag, "virtual space member", "虛擬空間成員")
 	_ = message.SetString(tag, "volume snapshot", "捲快照")
 	_ = message.SetString(tag, "volume snapshot to PVC", "到 PVC 的捲快照")
+	_ = message.SetString(tag, "wake", "喚醒")
 	_ = message.SetString(tag, "workload has no K8S Service: %w", "工作負載沒有 K8S 服務： %w")
+	_ = message.SetString(tag, "you are not a member of the approval scope", "你不是申請範圍內的成員")
+	_ = message.SetString(tag, "you can't decide the current stage of this approval", "你不能審批該申請的當前階段")
+	_ = message.SetString(tag, "you can't view this approval", "你不能查看該審批")
 	_ = message.SetString(tag, "you have no permission to do this operation", "您沒有執行此操作的許可權")
 	_ = message.SetString(tag, "you have no permission to do this operation, you must be an admin in any virtual space firstly", "您沒有權限執行此操作，您必須首先成為任何虛擬空間中的管理員")
 	_ = message.SetString(tag, "you have no permission to operate the environment %s", "您無權操作環境 %s")
@@ -59,6 +59,10 @@ func NewGinLoggerMideare(logger *zap.Logger) gin.HandlerFunc {
 			zap.Int("code", statusCode),
 			zap.Duration("latency", latency),
 		}
+		// trace id 由 otel 中间件设置
+		if traceID := c.GetString(TraceIDKey); traceID != "" {
+			fields = append(fields, zap.String(TraceIDKey, traceID))
+		}
 
 		if len(c.Errors) != 0 {
 			logger.Error(c.Errors.String(), fields...)
@@ -79,6 +79,7 @@ func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (stri
 	if l.SourceField != "" {
 		fields = append(fields, zap.String(l.SourceField, filepath.Base(utils.FileWithLineNum())))
 	}
+	fields = append(fields, TraceFields(ctx)...)
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound) && l.SkipErrRecordNotFound) {
 		l.logger.Error(err.Error(), fields...)
 		return
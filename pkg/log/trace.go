@@ -0,0 +1,55 @@
+// Copyright 2023 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// 日志中 trace id 和 span id 的字段名, 日志查询时按 trace_id 识别并关联到 trace
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// TraceFields 当前 span 的 trace id 和 span id, 没有 span 时返回空
+func TraceFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String(TraceIDKey, sc.TraceID().String()),
+		zap.String(SpanIDKey, sc.SpanID().String()),
+	}
+}
+
+// WithTraceContext 在 context 的 logger 中加入当前 span 的 trace id,
+// 之后通过 FromContextOrDiscard 获取的 logger 输出的日志都带有 trace id
+func WithTraceContext(ctx context.Context) context.Context {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ctx
+	}
+	logger, err := logr.FromContext(ctx)
+	if err != nil {
+		logger = LogrLogger
+	}
+	return NewContext(ctx, logger.WithValues(TraceIDKey, sc.TraceID().String(), SpanIDKey, sc.SpanID().String()))
+}
@@ -23,6 +23,7 @@ import (
 	"kubegems.io/kubegems/pkg/msgbus/switcher"
 	"kubegems.io/kubegems/pkg/service/aaa"
 	"kubegems.io/kubegems/pkg/service/aaa/auth"
+	"kubegems.io/kubegems/pkg/service/aaa/authorization"
 	"kubegems.io/kubegems/pkg/utils/database"
 	"kubegems.io/kubegems/pkg/utils/system"
 )
@@ -30,9 +31,10 @@ import (
 func NewGinServer(opts *options.Options, database *database.Database, ms *switcher.MessageSwitcher) (*gin.Engine, error) {
 	r := gin.Default()
 	// 初始化需要注册的中间件
-	authMiddleware := auth.NewAuthMiddleware(opts.JWT, aaa.NewUserInfoHandler(), otel.GetTracerProvider().Tracer("kubegems.io/kubegems"))
+	authMiddleware := auth.NewAuthMiddleware(opts.JWT, database.DB(), aaa.NewUserInfoHandler(), otel.GetTracerProvider().Tracer("kubegems.io/kubegems"))
 	middlewares := []func(*gin.Context){
 		authMiddleware.FilterFunc,
+		authorization.ScopedTokenFilter,
 	}
 
 	r.GET("/healthz", func(c *gin.Context) { c.JSON(200, gin.H{"healthy": "ok"}) })
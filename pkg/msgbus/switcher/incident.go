@@ -0,0 +1,244 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package switcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+)
+
+const (
+	incidentTitleMaxLen       = 200
+	incidentCorrelateAttempts = 3
+)
+
+// correlateIncidents 将告警关联到事件, 返回需要通知用户的告警, 即创建了新事件或者使事件恢复的告警
+func (ms *MessageSwitcher) correlateIncidents(msgs []models.AlertMessage) []models.AlertMessage {
+	// 同一实例内串行关联, 多实例之间由事件的唯一键保证不会重复创建
+	ms.incidentMu.Lock()
+	defer ms.incidentMu.Unlock()
+
+	notify := []models.AlertMessage{}
+	for i := range msgs {
+		msg := &msgs[i]
+		if msg.AlertInfo == nil {
+			continue
+		}
+		labels := map[string]string{}
+		_ = json.Unmarshal(msg.AlertInfo.Labels, &labels)
+
+		var needNotify bool
+		var err error
+		// 其他实例并发创建了相同关联键的事件时唯一键冲突, 重试即可关联到该事件
+		for attempt := 0; attempt < incidentCorrelateAttempts; attempt++ {
+			if err = ms.DataBase.DB().Transaction(func(tx *gorm.DB) error {
+				var err error
+				if msg.Status == "resolved" {
+					needNotify, err = resolveIncidentAlert(tx, msg)
+				} else {
+					needNotify, err = attachIncidentAlert(tx, msg, labels)
+				}
+				return err
+			}); err == nil {
+				break
+			}
+			msg.IncidentID = nil
+		}
+		if err != nil {
+			log.Error(err, "correlate alert to incident", "fingerprint", msg.InfoFingerprint)
+			// 关联失败时仍然按告警通知
+			needNotify = true
+		}
+		if needNotify {
+			notify = append(notify, *msg)
+		}
+	}
+	return notify
+}
+
+// openIncidentOf 告警所在的未恢复的事件
+func openIncidentOf(tx *gorm.DB, fingerprint string) (*models.Incident, error) {
+	incidents := []*models.Incident{}
+	if err := tx.Joins("join alert_messages on alert_messages.incident_id = incidents.id").
+		Where("alert_messages.fingerprint = ? and incidents.status <> ?", fingerprint, models.IncidentStatusResolved).
+		Order("incidents.id desc").Limit(1).Find(&incidents).Error; err != nil {
+		return nil, err
+	}
+	if len(incidents) == 0 {
+		return nil, nil
+	}
+	return incidents[0], nil
+}
+
+func attachIncidentAlert(tx *gorm.DB, msg *models.AlertMessage, labels map[string]string) (bool, error) {
+	now := time.Now()
+	info := msg.AlertInfo
+	// 已经在事件中的告警重复发送时只更新时间
+	incident, err := openIncidentOf(tx, msg.InfoFingerprint)
+	if err != nil {
+		return false, err
+	}
+	if incident != nil {
+		msg.IncidentID = &incident.ID
+		if err := tx.Model(msg).Update("incident_id", incident.ID).Error; err != nil {
+			return false, err
+		}
+		return false, tx.Model(incident).Update("last_alert_at", now).Error
+	}
+
+	correlationKey := models.IncidentCorrelationKey(labels)
+	openKey := models.IncidentOpenKey(info.ClusterName, info.Namespace, correlationKey, info.Fingerprint)
+	severity := labels[prometheus.SeverityLabel]
+	// 关联键相同的未恢复事件
+	sameKey := []*models.Incident{}
+	if err := tx.Where("open_key = ?", openKey).Limit(1).Find(&sameKey).Error; err != nil {
+		return false, err
+	}
+	if len(sameKey) > 0 {
+		return false, addIncidentAlert(tx, sameKey[0], msg, labels, severity, now)
+	}
+	candidates := []*models.Incident{}
+	if err := tx.Where("cluster_name = ? and namespace = ? and status <> ? and last_alert_at >= ?",
+		info.ClusterName, info.Namespace, models.IncidentStatusResolved, now.Add(-models.IncidentCorrelationWindow)).
+		Order("last_alert_at desc").Find(&candidates).Error; err != nil {
+		return false, err
+	}
+	for _, candidate := range candidates {
+		if candidate.Correlated(labels, correlationKey) {
+			return false, addIncidentAlert(tx, candidate, msg, labels, severity, now)
+		}
+	}
+
+	// 没有相关的事件, 创建新事件
+	incident = &models.Incident{
+		Title:           incidentTitle(info.Name, msg.Message),
+		Status:          models.IncidentStatusOpen,
+		Severity:        severity,
+		ClusterName:     info.ClusterName,
+		Namespace:       info.Namespace,
+		TenantName:      info.TenantName,
+		ProjectName:     info.ProjectName,
+		EnvironmentName: info.EnvironmentName,
+		Workload:        models.IncidentWorkload(labels),
+		CorrelationKey:  correlationKey,
+		OpenKey:         &openKey,
+		CommonLabels:    gormdatatypes.JSONMap(labels),
+		AlertCount:      1,
+		StartsAt:        msg.StartsAt,
+		LastAlertAt:     &now,
+	}
+	if incident.StartsAt == nil {
+		incident.StartsAt = &now
+	}
+	if err := tx.Create(incident).Error; err != nil {
+		return false, err
+	}
+	msg.IncidentID = &incident.ID
+	if err := tx.Model(msg).Update("incident_id", incident.ID).Error; err != nil {
+		return false, err
+	}
+	return true, tx.Create(&models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventCreated,
+		Content:    fmt.Sprintf("[%s] %s", info.Name, strings.TrimSpace(msg.Message)),
+		CreatedAt:  &now,
+	}).Error
+}
+
+// addIncidentAlert 将新告警加入已有的事件
+func addIncidentAlert(tx *gorm.DB, incident *models.Incident, msg *models.AlertMessage, labels map[string]string, severity string, now time.Time) error {
+	incident.AddAlert(labels, severity, now)
+	if err := tx.Select("common_labels", "severity", "alert_count", "last_alert_at").Updates(incident).Error; err != nil {
+		return err
+	}
+	msg.IncidentID = &incident.ID
+	if err := tx.Model(msg).Update("incident_id", incident.ID).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventAlert,
+		Content:    fmt.Sprintf("[%s] %s", msg.AlertInfo.Name, strings.TrimSpace(msg.Message)),
+		CreatedAt:  &now,
+	}).Error
+}
+
+// resolveIncidentAlert 告警恢复, 事件中所有的告警都恢复时事件自动恢复
+func resolveIncidentAlert(tx *gorm.DB, msg *models.AlertMessage) (bool, error) {
+	now := time.Now()
+	incident, err := openIncidentOf(tx, msg.InfoFingerprint)
+	if err != nil || incident == nil {
+		return false, err
+	}
+	msg.IncidentID = &incident.ID
+	if err := tx.Model(msg).Update("incident_id", incident.ID).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Create(&models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventAlertResolved,
+		Content:    fmt.Sprintf("[%s] %s", msg.AlertInfo.Name, strings.TrimSpace(msg.Message)),
+		CreatedAt:  &now,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	// 每个指纹最后一条消息的状态
+	alerts := []models.AlertMessage{}
+	if err := tx.Select("fingerprint", "status").Where("incident_id = ?", incident.ID).Order("id").Find(&alerts).Error; err != nil {
+		return false, err
+	}
+	latest := map[string]string{}
+	for _, alert := range alerts {
+		latest[alert.InfoFingerprint] = alert.Status
+	}
+	for _, status := range latest {
+		if status != "resolved" {
+			return false, nil
+		}
+	}
+	if err := tx.Model(incident).Updates(map[string]interface{}{
+		"status":      models.IncidentStatusResolved,
+		"resolved_at": now,
+		"open_key":    nil,
+	}).Error; err != nil {
+		return false, err
+	}
+	return true, tx.Create(&models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventStatus,
+		Content:    models.IncidentStatusResolved,
+		CreatedAt:  &now,
+	}).Error
+}
+
+func incidentTitle(alertname, message string) string {
+	title := strings.TrimSpace(message)
+	if title == "" {
+		title = alertname
+	}
+	if runes := []rune(title); len(runes) > incidentTitleMaxLen {
+		title = string(runes[:incidentTitleMaxLen]) + "..."
+	}
+	return title
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package switcher
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+func newTestAlert(t *testing.T, db *gorm.DB, fingerprint, status string, labels map[string]string) *models.AlertMessage {
+	bts, _ := json.Marshal(labels)
+	info := &models.AlertInfo{
+		Fingerprint: fingerprint,
+		Name:        "test",
+		Namespace:   "default",
+		ClusterName: "cluster",
+		Labels:      bts,
+	}
+	if err := db.Save(info).Error; err != nil {
+		t.Fatal(err)
+	}
+	msg := &models.AlertMessage{InfoFingerprint: fingerprint, Status: status, AlertInfo: info}
+	if err := db.Omit("AlertInfo").Create(msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestAttachIncidentAlert(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.AlertInfo{}, &models.AlertMessage{}, &models.Incident{}, &models.IncidentEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	attach := func(msg *models.AlertMessage, labels map[string]string) bool {
+		notify, err := attachIncidentAlert(db, msg, labels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return notify
+	}
+
+	// 相同工作负载的告警聚合到同一事件, 只通知一次
+	nginx1 := map[string]string{"pod": "nginx-7d9f8b6c5d-x2k4p"}
+	nginx2 := map[string]string{"pod": "nginx-7d9f8b6c5d-z9k2p"}
+	a1 := newTestAlert(t, db, "a1", "firing", nginx1)
+	a2 := newTestAlert(t, db, "a2", "firing", nginx2)
+	if !attach(a1, nginx1) || attach(a2, nginx2) {
+		t.Fatal("alerts of the same workload should notify once")
+	}
+	if *a1.IncidentID != *a2.IncidentID {
+		t.Errorf("alerts of the same workload in different incidents: %d, %d", *a1.IncidentID, *a2.IncidentID)
+	}
+
+	// 没有关联键也没有共享标签的告警不再聚合
+	b1 := newTestAlert(t, db, "b1", "firing", map[string]string{"alertname": "x"})
+	b2 := newTestAlert(t, db, "b2", "firing", map[string]string{"alertname": "y"})
+	if !attach(b1, map[string]string{"alertname": "x"}) || !attach(b2, map[string]string{"alertname": "y"}) {
+		t.Fatal("uncorrelated alerts should create their own incidents")
+	}
+	if *b1.IncidentID == *b2.IncidentID {
+		t.Error("uncorrelated alerts in the same incident")
+	}
+
+	// 超出关联窗口但仍未恢复的事件按关联键关联
+	old := time.Now().Add(-2 * models.IncidentCorrelationWindow)
+	if err := db.Model(&models.Incident{}).Where("id = ?", *a1.IncidentID).Update("last_alert_at", old).Error; err != nil {
+		t.Fatal(err)
+	}
+	a3 := newTestAlert(t, db, "a3", "firing", nginx1)
+	if attach(a3, nginx1) || *a3.IncidentID != *a1.IncidentID {
+		t.Error("alert should attach to the open incident with the same correlation key")
+	}
+
+	// 相同关联键的未恢复事件只能有一个
+	dup := models.IncidentOpenKey("cluster", "default", "nginx", "")
+	if err := db.Create(&models.Incident{Title: "dup", OpenKey: &dup}).Error; err == nil {
+		t.Error("duplicate open incident created")
+	}
+
+	// 事件恢复后释放唯一键, 新的告警创建新事件
+	resolved := newTestAlert(t, db, "b1", "resolved", map[string]string{"alertname": "x"})
+	if notify, err := resolveIncidentAlert(db, resolved); err != nil || !notify {
+		t.Fatalf("resolve incident notify = %v, err = %v", notify, err)
+	}
+	incident := &models.Incident{}
+	if err := db.First(incident, *b1.IncidentID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if incident.Status != models.IncidentStatusResolved || incident.OpenKey != nil {
+		t.Errorf("resolved incident status = %s, open key = %v", incident.Status, incident.OpenKey)
+	}
+}
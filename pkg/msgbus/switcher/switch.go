@@ -17,6 +17,7 @@ package switcher
 import (
 	"context"
 	"encoding/json"
+	"sync"
 
 	"kubegems.io/kubegems/pkg/log"
 	"kubegems.io/kubegems/pkg/service/models"
@@ -36,6 +37,8 @@ func NewMessageSwitch(_ context.Context, db *database.Database) *MessageSwitcher
 type MessageSwitcher struct {
 	DataBase *database.Database
 	Users    []*NotifyUser
+
+	incidentMu sync.Mutex
 }
 
 func (ms *MessageSwitcher) RegistUser(user *NotifyUser) {
@@ -72,6 +75,8 @@ func (ms *MessageSwitcher) DispatchMessage(msg *msgbus.NotifyMessage) {
 		// 存告警消息表
 		fingerprintMap := webhookAlert.FingerprintMap()
 		dbalertMsgs := ms.saveFingerprintMapToDB(fingerprintMap)
+		// 关联到事件, 只有新事件和恢复的事件通知用户
+		dbalertMsgs = ms.correlateIncidents(dbalertMsgs)
 
 		// 发消息并存用户消息表
 		_, isMonitor := webhookAlert.CommonLabels["prometheus"]
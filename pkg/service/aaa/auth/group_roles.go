@@ -0,0 +1,238 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+)
+
+// ValidGroupRole 校验角色是否属于对应层级
+func ValidGroupRole(kind, role string) bool {
+	switch kind {
+	case models.GroupMappingKindTenant:
+		return role == models.TenantRoleAdmin || role == models.TenantRoleOrdinary
+	case models.GroupMappingKindProject:
+		switch role {
+		case models.ProjectRoleAdmin, models.ProjectRoleDev, models.ProjectRoleTest, models.ProjectRoleOps:
+			return true
+		}
+	case models.GroupMappingKindEnvironment:
+		return role == models.EnvironmentRoleReader || role == models.EnvironmentRoleOperator
+	}
+	return false
+}
+
+// 同一资源匹配到多个角色时, 取权限最大的角色
+var groupRoleRank = map[string]int{
+	models.TenantRoleAdmin:         3,
+	models.ProjectRoleOps:          2,
+	models.EnvironmentRoleOperator: 2,
+	models.ProjectRoleDev:          1,
+}
+
+type groupRoleKey struct {
+	Kind       string
+	ResourceID uint
+}
+
+type groupRoleTarget struct {
+	Role string
+}
+
+// GroupMatches 判断用户的组中是否包含 group, 不区分大小写; LDAP 的组 DN 也可以使用 CN 匹配
+func GroupMatches(group string, groups []string) bool {
+	for _, g := range groups {
+		if strings.EqualFold(g, group) {
+			return true
+		}
+		if first, _, _ := strings.Cut(g, ","); len(first) > 3 && strings.EqualFold(first[:3], "cn=") {
+			if strings.EqualFold(strings.TrimSpace(first[3:]), group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SyncGroupRoles 根据用户在登录源中的组同步租户、项目和环境角色, 返回成员关系是否有变更。
+//
+// 只有开启了 SyncGroupRoles 的登录源才会同步, 期望的角色只来自登录源配置的组映射(AuthSourceGroupMapping)。
+// 同步授予的成员关系记录在 GroupSyncedRole 中, 用户不再属于对应的组时会被回收; 手动添加的成员关系不受影响。
+func SyncGroupRoles(ctx context.Context, db *gorm.DB, user *models.User, groups []string) (bool, error) {
+	if user.Source == "" || user.Source == AccountLoginName {
+		return false, nil
+	}
+	source := &models.AuthSource{}
+	if err := db.WithContext(ctx).First(source, "name = ?", user.Source).Error; err != nil {
+		return false, err
+	}
+	if !source.Config.SyncGroupRoles {
+		return false, nil
+	}
+	changed := false
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		state := &models.UserSourceGroups{UserID: user.ID, Groups: gormdatatypes.JSONSlice(groups), SyncedAt: &now}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"groups", "synced_at"}),
+		}).Create(state).Error; err != nil {
+			return err
+		}
+		desired, err := desiredGroupRoles(tx, source.ID, groups)
+		if err != nil {
+			return err
+		}
+		var synced []models.GroupSyncedRole
+		if err := tx.Find(&synced, "user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		syncedmap := map[groupRoleKey]*models.GroupSyncedRole{}
+		for i := range synced {
+			syncedmap[groupRoleKey{Kind: synced[i].Kind, ResourceID: synced[i].ResourceID}] = &synced[i]
+		}
+		for key, target := range desired {
+			updated, err := applyGroupRole(tx, user.ID, key, target, syncedmap[key])
+			if err != nil {
+				return err
+			}
+			changed = changed || updated
+		}
+		// 回收不再属于的组授予的成员关系
+		for key, record := range syncedmap {
+			if _, ok := desired[key]; ok {
+				continue
+			}
+			// 手动修改过角色的成员关系不再由同步管理
+			rel, column := userRelModel(key.Kind)
+			if err := tx.Where(column+" = ? and user_id = ? and role = ?", key.ResourceID, user.ID, record.Role).Delete(rel).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(record).Error; err != nil {
+				return err
+			}
+			log.Info("revoke group synced role", "user", user.Username, "kind", key.Kind, "id", key.ResourceID, "role", record.Role)
+			changed = true
+		}
+		return nil
+	})
+	return changed, err
+}
+
+func desiredGroupRoles(tx *gorm.DB, sourceID uint, groups []string) (map[groupRoleKey]groupRoleTarget, error) {
+	desired := map[groupRoleKey]groupRoleTarget{}
+	var mappings []models.AuthSourceGroupMapping
+	if err := tx.Find(&mappings, "auth_source_id = ?", sourceID).Error; err != nil {
+		return nil, err
+	}
+	for _, mapping := range mappings {
+		if !ValidGroupRole(mapping.Kind, mapping.Role) || !GroupMatches(mapping.Group, groups) {
+			continue
+		}
+		key := groupRoleKey{Kind: mapping.Kind, ResourceID: mapping.ResourceID}
+		if exist, ok := desired[key]; !ok || groupRoleRank[mapping.Role] > groupRoleRank[exist.Role] {
+			desired[key] = groupRoleTarget{Role: mapping.Role}
+		}
+	}
+	// 项目和环境的成员需要是租户成员
+	tenantIDs := map[uint]bool{}
+	for key := range desired {
+		switch key.Kind {
+		case models.GroupMappingKindProject:
+			project := &models.Project{}
+			if err := tx.First(project, key.ResourceID).Error; err != nil {
+				delete(desired, key)
+				continue
+			}
+			tenantIDs[project.TenantID] = true
+		case models.GroupMappingKindEnvironment:
+			env := &models.Environment{}
+			if err := tx.Preload("Project").First(env, key.ResourceID).Error; err != nil || env.Project == nil {
+				delete(desired, key)
+				continue
+			}
+			tenantIDs[env.Project.TenantID] = true
+		}
+	}
+	for tenantID := range tenantIDs {
+		key := groupRoleKey{Kind: models.GroupMappingKindTenant, ResourceID: tenantID}
+		if _, ok := desired[key]; !ok {
+			desired[key] = groupRoleTarget{Role: models.TenantRoleOrdinary}
+		}
+	}
+	return desired, nil
+}
+
+// applyGroupRole 只修改由同步创建的成员关系(record 不为空); 已经存在的手动添加的成员关系保持不变
+func applyGroupRole(tx *gorm.DB, userID uint, key groupRoleKey, target groupRoleTarget, record *models.GroupSyncedRole) (bool, error) {
+	rel, column := userRelModel(key.Kind)
+	var roles []string
+	if err := tx.Model(rel).Where(column+" = ? and user_id = ?", key.ResourceID, userID).Pluck("role", &roles).Error; err != nil {
+		return false, err
+	}
+	switch {
+	case len(roles) == 0:
+		if err := tx.Create(newUserRel(key.Kind, key.ResourceID, userID, target.Role)).Error; err != nil {
+			return false, err
+		}
+	case record == nil:
+		// 手动添加的成员关系
+		return false, nil
+	case roles[0] != record.Role:
+		// 同步创建后被手动修改过角色, 交由手动管理
+		return false, tx.Delete(record).Error
+	case roles[0] == target.Role:
+		return false, nil
+	default:
+		if err := tx.Model(rel).Where(column+" = ? and user_id = ?", key.ResourceID, userID).Update("role", target.Role).Error; err != nil {
+			return false, err
+		}
+	}
+	if record == nil {
+		record = &models.GroupSyncedRole{UserID: userID, Kind: key.Kind, ResourceID: key.ResourceID}
+	}
+	record.Role = target.Role
+	return true, tx.Save(record).Error
+}
+
+func userRelModel(kind string) (any, string) {
+	switch kind {
+	case models.GroupMappingKindProject:
+		return &models.ProjectUserRels{}, "project_id"
+	case models.GroupMappingKindEnvironment:
+		return &models.EnvironmentUserRels{}, "environment_id"
+	default:
+		return &models.TenantUserRels{}, "tenant_id"
+	}
+}
+
+func newUserRel(kind string, resourceID, userID uint, role string) any {
+	switch kind {
+	case models.GroupMappingKindProject:
+		return &models.ProjectUserRels{ProjectID: resourceID, UserID: userID, Role: role}
+	case models.GroupMappingKindEnvironment:
+		return &models.EnvironmentUserRels{EnvironmentID: resourceID, UserID: userID, Role: role}
+	default:
+		return &models.TenantUserRels{TenantID: resourceID, UserID: userID, Role: role}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+func TestGroupMatches(t *testing.T) {
+	groups := []string{"CN=Devs,OU=Groups,DC=example,DC=com", "ops"}
+	tests := []struct {
+		group string
+		want  bool
+	}{
+		{group: "cn=devs,ou=groups,dc=example,dc=com", want: true},
+		{group: "devs", want: true},
+		{group: "OPS", want: true},
+		{group: "groups", want: false},
+		{group: "admins", want: false},
+	}
+	for _, tt := range tests {
+		if got := GroupMatches(tt.group, groups); got != tt.want {
+			t.Errorf("GroupMatches(%s) = %v, want %v", tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestSyncGroupRoles(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{}, &models.Tenant{}, &models.TenantUserRels{}, &models.Project{}, &models.ProjectUserRels{},
+		&models.AuthSource{}, &models.AuthSourceGroupMapping{}, &models.UserSourceGroups{}, &models.GroupSyncedRole{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	source := &models.AuthSource{Name: "ldap", Kind: "LDAP", TokenType: "Bearer", Enabled: true}
+	user := &models.User{Username: "alice", Source: source.Name}
+	t1, t2 := &models.Tenant{TenantName: "t1"}, &models.Tenant{TenantName: "t2"}
+	for _, obj := range []any{source, user, t1, t2} {
+		if err := db.Create(obj).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	p1 := &models.Project{ProjectName: "p1", TenantID: t1.ID}
+	if err := db.Create(p1).Error; err != nil {
+		t.Fatal(err)
+	}
+	manual := &models.TenantUserRels{TenantID: t2.ID, UserID: user.ID, Role: models.TenantRoleOrdinary}
+	mappings := []models.AuthSourceGroupMapping{
+		{AuthSourceID: source.ID, Group: "devs", Kind: models.GroupMappingKindProject, ResourceID: p1.ID, Role: models.ProjectRoleDev},
+		{AuthSourceID: source.ID, Group: "ops", Kind: models.GroupMappingKindTenant, ResourceID: t2.ID, Role: models.TenantRoleAdmin},
+	}
+	for _, obj := range []any{manual, &mappings} {
+		if err := db.Create(obj).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	groups := []string{"CN=devs,OU=Groups,DC=example,DC=com", "ops"}
+
+	tenantRole := func(tenantID uint) string {
+		rel := &models.TenantUserRels{}
+		if err := db.First(rel, "tenant_id = ? and user_id = ?", tenantID, user.ID).Error; err != nil {
+			return ""
+		}
+		return rel.Role
+	}
+	projectRole := func() string {
+		rel := &models.ProjectUserRels{}
+		if err := db.First(rel, "project_id = ? and user_id = ?", p1.ID, user.ID).Error; err != nil {
+			return ""
+		}
+		return rel.Role
+	}
+
+	// 登录源未开启同步
+	if changed, err := SyncGroupRoles(ctx, db, user, groups); err != nil || changed {
+		t.Fatalf("SyncGroupRoles() with sync disabled = %v, %v", changed, err)
+	}
+	if projectRole() != "" {
+		t.Fatalf("project role granted while sync disabled")
+	}
+
+	source.Config.SyncGroupRoles = true
+	if err := db.Save(source).Error; err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := SyncGroupRoles(ctx, db, user, groups); err != nil || !changed {
+		t.Fatalf("SyncGroupRoles() = %v, %v", changed, err)
+	}
+	if got := projectRole(); got != models.ProjectRoleDev {
+		t.Errorf("project role = %q, want %q", got, models.ProjectRoleDev)
+	}
+	if got := tenantRole(t1.ID); got != models.TenantRoleOrdinary {
+		t.Errorf("implicit tenant role = %q, want %q", got, models.TenantRoleOrdinary)
+	}
+	if got := tenantRole(t2.ID); got != models.TenantRoleOrdinary {
+		t.Errorf("manual tenant role = %q, should not be changed by sync", got)
+	}
+
+	// 移出所有组后只回收同步授予的成员关系
+	if changed, err := SyncGroupRoles(ctx, db, user, nil); err != nil || !changed {
+		t.Fatalf("SyncGroupRoles() revoke = %v, %v", changed, err)
+	}
+	if got := projectRole(); got != "" {
+		t.Errorf("project role = %q after revoke", got)
+	}
+	if got := tenantRole(t1.ID); got != "" {
+		t.Errorf("implicit tenant role = %q after revoke", got)
+	}
+	if got := tenantRole(t2.ID); got != models.TenantRoleOrdinary {
+		t.Errorf("manual tenant role = %q after revoke, want %q", got, models.TenantRoleOrdinary)
+	}
+	var count int64
+	db.Model(&models.GroupSyncedRole{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("synced role records = %d after revoke", count)
+	}
+}
@@ -35,14 +35,20 @@ type Credential struct {
 	Password string `json:"password" form:"password"`
 	Code     string `json:"code" form:"code"`
 	Source   string `json:"source" form:"source"`
+	// 账号登录开启两步验证时的验证码或者恢复码
+	OTP string `json:"otp" form:"otp"`
 }
 
 type UserInfo struct {
 	Username string `json:"username"`
-	Email    string `json:"email"`
-	Name     string `json:"name"`
-	Source   string `json:"-"`
-	Vendor   string `json:"vendor"`
+	// 用户在外部登录源中的唯一标识, 为空时使用用户名
+	Subject string `json:"subject,omitempty"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Source  string `json:"-"`
+	Vendor  string `json:"vendor"`
+	// 用户在 IdP 中的组, 用于映射 kubegems 中的角色
+	Groups []string `json:"groups,omitempty"`
 }
 
 // AuthenticateIface 所有登录插件需要实现AuthenticateIface接口
@@ -88,15 +94,7 @@ func (l *AuthenticateModule) GetAuthenticateModule(ctx context.Context, sourceNa
 	}
 	switch authSource.Kind {
 	case "LDAP":
-		ldapUt := &LdapLoginUtils{
-			Vendor:       authSource.Vendor,
-			BaseDN:       authSource.Config.BaseDN,
-			Name:         authSource.Name,
-			BindUsername: authSource.Config.BindUsername,
-			BindPassword: authSource.Config.BindPassword,
-			LdapAddr:     authSource.Config.LdapAddr,
-			EnableTLS:    authSource.Config.EnableTLS,
-		}
+		ldapUt := NewLdapLoginUtils(&authSource)
 		return ldapUt
 	case "OAUTH":
 		opt := &OauthOption{
@@ -109,6 +107,29 @@ func (l *AuthenticateModule) GetAuthenticateModule(ctx context.Context, sourceNa
 			Scopes:      authSource.Config.Scopes,
 		}
 		return NewOauthUtils(authSource.Name, authSource.Vendor, opt)
+	case "OIDC":
+		opt := &OIDCOption{
+			Issuer:        authSource.Config.Issuer,
+			RedirectURL:   authSource.Config.RedirectURL,
+			AppID:         authSource.Config.AppID,
+			AppSecret:     authSource.Config.AppSecret,
+			Scopes:        authSource.Config.Scopes,
+			UsernameClaim: authSource.Config.UsernameClaim,
+			EmailClaim:    authSource.Config.EmailClaim,
+			GroupsClaim:   authSource.Config.GroupsClaim,
+		}
+		return NewOIDCUtils(authSource.Name, authSource.Vendor, opt)
+	case "SAML":
+		opt := &SAMLOption{
+			SSOURL:            authSource.Config.SSOURL,
+			EntityID:          authSource.Config.EntityID,
+			ACSURL:            authSource.Config.ACSURL,
+			IdPCertificate:    authSource.Config.IdPCertificate,
+			UsernameAttribute: authSource.Config.UsernameClaim,
+			EmailAttribute:    authSource.Config.EmailClaim,
+			GroupsAttribute:   authSource.Config.GroupsClaim,
+		}
+		return NewSAMLUtils(authSource.Name, authSource.Vendor, opt, l.DB)
 	}
 	return nil
 }
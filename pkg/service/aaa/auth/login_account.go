@@ -37,11 +37,11 @@ func (ut *AccountLoginUtil) LoginAddr() string {
 }
 
 func (ut *AccountLoginUtil) GetUserInfo(ctx context.Context, cred *Credential) (*UserInfo, error) {
-	user := &models.User{}
-	if err := ut.DB.WithContext(ctx).Where("username = ?", cred.Username).First(user).Error; err != nil {
+	user, err := ut.Authenticate(ctx, cred.Username, cred.Password)
+	if err != nil {
 		return nil, err
 	}
-	if err := utils.ValidatePassword(cred.Password, user.Password); err != nil {
+	if err := VerifyTwoFactor(ctx, ut.DB, user, cred.OTP); err != nil {
 		return nil, err
 	}
 	now := time.Now()
@@ -49,3 +49,15 @@ func (ut *AccountLoginUtil) GetUserInfo(ctx context.Context, cred *Credential) (
 
 	return &UserInfo{Username: user.Username, Email: user.Email}, nil
 }
+
+// Authenticate 校验用户名和密码, 不包含两步验证
+func (ut *AccountLoginUtil) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	user := &models.User{}
+	if err := ut.DB.WithContext(ctx).Where("username = ?", username).First(user).Error; err != nil {
+		return nil, err
+	}
+	if err := utils.ValidatePassword(password, user.Password); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
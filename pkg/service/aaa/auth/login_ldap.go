@@ -17,6 +17,7 @@ package auth
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -25,6 +26,7 @@ import (
 	"github.com/go-ldap/ldap/v3"
 	"kubegems.io/kubegems/pkg/i18n"
 	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
 )
 
 type LdapLoginUtils struct {
@@ -36,6 +38,23 @@ type LdapLoginUtils struct {
 	Filter       string `json:"filter"`
 	BindUsername string `yaml:"binduser" json:"binduser"`
 	BindPassword string `yaml:"bindpass" json:"password"`
+	// 用户条目中表示所属组的属性, 默认为 memberOf
+	GroupAttribute string `json:"groupAttribute"`
+}
+
+const DefaultLdapGroupAttribute = "memberOf"
+
+func NewLdapLoginUtils(authSource *models.AuthSource) *LdapLoginUtils {
+	return &LdapLoginUtils{
+		Vendor:         authSource.Vendor,
+		BaseDN:         authSource.Config.BaseDN,
+		Name:           authSource.Name,
+		BindUsername:   authSource.Config.BindUsername,
+		BindPassword:   authSource.Config.BindPassword,
+		LdapAddr:       authSource.Config.LdapAddr,
+		EnableTLS:      authSource.Config.EnableTLS,
+		GroupAttribute: authSource.Config.GroupsClaim,
+	}
 }
 
 func (ut *LdapLoginUtils) GetName() string {
@@ -50,7 +69,82 @@ func (ut *LdapLoginUtils) GetUserInfo(ctx context.Context, cred *Credential) (re
 	if !ut.ValidateCredential(cred) {
 		return nil, i18n.Errorf(ctx, "invalid credential")
 	}
-	var ldapConn *ldap.Conn
+	ldapConn, err := ut.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ldapConn.Close()
+
+	info, err := ut.searchUser(ldapConn, cred.Username)
+	if err != nil {
+		log.Error(err, "search user in ldap failed", "username", cred.Username)
+		if errors.Is(err, errLdapMultipleEntries) {
+			return nil, i18n.Error(ctx, "failed to get userinfo from ldap, more than one result")
+		}
+		return nil, i18n.Error(ctx, "failed to get userinfo from ldap")
+	}
+	if info == nil {
+		log.Error(fmt.Errorf("user not found"), "search user in ldap failed", "username", cred.Username)
+		return nil, i18n.Error(ctx, "failed to get userinfo from ldap")
+	}
+	uinfo := UserInfo{}
+	uinfo.Username = cred.Username
+	uinfo.Subject = info.DN
+	uinfo.Vendor = ut.Vendor
+	mailstr := info.GetAttributeValue("mail")
+	emailstr := info.GetAttributeValue("email")
+	if emailstr != "" {
+		uinfo.Email = emailstr
+	} else {
+		uinfo.Email = mailstr
+	}
+	uinfo.Groups = info.GetAttributeValues(ut.groupAttribute())
+	uinfo.Source = cred.Source
+	ret = &uinfo
+	return
+}
+
+// SearchUserGroups 使用绑定用户查询用户所属的组, 用于定时同步;
+// 目录中不存在的用户返回空的组, 匹配到多个条目的用户无法确定身份, 不会出现在返回结果中
+func (ut *LdapLoginUtils) SearchUserGroups(ctx context.Context, usernames []string) (map[string][]string, error) {
+	ldapConn, err := ut.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ldapConn.Close()
+
+	ret := map[string][]string{}
+	for _, username := range usernames {
+		info, err := ut.searchUser(ldapConn, username)
+		if err != nil {
+			if errors.Is(err, errLdapMultipleEntries) {
+				log.Error(err, "skip ldap user", "username", username)
+				continue
+			}
+			return nil, err
+		}
+		if info == nil {
+			ret[username] = []string{}
+			continue
+		}
+		ret[username] = info.GetAttributeValues(ut.groupAttribute())
+	}
+	return ret, nil
+}
+
+func (ut *LdapLoginUtils) groupAttribute() string {
+	if ut.GroupAttribute == "" {
+		return DefaultLdapGroupAttribute
+	}
+	return ut.GroupAttribute
+}
+
+// dial 连接 ldap 并使用绑定用户认证
+func (ut *LdapLoginUtils) dial(ctx context.Context) (*ldap.Conn, error) {
+	var (
+		ldapConn *ldap.Conn
+		err      error
+	)
 	ldap.DefaultTimeout = time.Second * 5
 	if strings.HasPrefix(ut.LdapAddr, "ldap") {
 		ldapConn, err = ldap.DialURL(
@@ -60,7 +154,6 @@ func (ut *LdapLoginUtils) GetUserInfo(ctx context.Context, cred *Credential) (re
 	} else {
 		ldapConn, err = ldap.Dial("tcp", ut.LdapAddr)
 	}
-	defer ldapConn.Close()
 	if err != nil {
 		log.Error(err, "connect to ldap server failed")
 		return nil, i18n.Error(ctx, "failed to connect ldap server")
@@ -68,50 +161,46 @@ func (ut *LdapLoginUtils) GetUserInfo(ctx context.Context, cred *Credential) (re
 
 	if ut.EnableTLS {
 		if err = ldapConn.StartTLS(&tls.Config{InsecureSkipVerify: true}); err != nil {
+			ldapConn.Close()
 			log.Error(err, "failed to connect ldap server with tls")
 			return nil, i18n.Error(ctx, "failed to connect ldap server with tls")
 		}
 	}
 
 	if err = ldapConn.Bind(ut.BindUsername, ut.BindPassword); err != nil {
+		ldapConn.Close()
 		log.Error(err, "failed to connect server with tls")
 		return nil, i18n.Error(ctx, "failed to connect ldap server with tls")
 	}
+	return ldapConn, nil
+}
 
+var errLdapMultipleEntries = errors.New("more than one ldap entry matched")
+
+// searchUser 查找用户条目, 不存在时返回 nil, 匹配到多个条目时返回 errLdapMultipleEntries
+func (ut *LdapLoginUtils) searchUser(ldapConn *ldap.Conn, username string) (*ldap.Entry, error) {
 	searchRequest := ldap.NewSearchRequest(
 		ut.BaseDN,
 		ldap.ScopeWholeSubtree,
 		ldap.NeverDerefAliases,
-		1,
+		2,
 		0,
 		false,
-		fmt.Sprintf("(cn=%s)", cred.Username),
-		[]string{"mail"},
+		fmt.Sprintf("(cn=%s)", ldap.EscapeFilter(username)),
+		[]string{"mail", "email", ut.groupAttribute()},
 		nil,
 	)
 	result, err := ldapConn.Search(searchRequest)
-	if err != nil {
-		log.Error(err, "search user in ldap failed")
-		return nil, i18n.Error(ctx, "failed to get userinfo from ldap")
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, err
 	}
-	if len(result.Entries) != 1 {
-		log.Error(fmt.Errorf("more than one search result returnd"), "username", cred.Username)
-		return nil, i18n.Error(ctx, "failed to get userinfo from ldap, more than one result")
+	if result == nil || len(result.Entries) == 0 {
+		return nil, nil
 	}
-	uinfo := UserInfo{}
-	info := result.Entries[0]
-	uinfo.Username = cred.Username
-	uinfo.Vendor = ut.Vendor
-	mailstr := info.GetAttributeValue("mail")
-	emailstr := info.GetAttributeValue("email")
-	if emailstr != "" {
-		uinfo.Email = emailstr
-	} else {
-		uinfo.Email = mailstr
+	if len(result.Entries) > 1 || ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, errLdapMultipleEntries
 	}
-	uinfo.Source = cred.Source
-	ret = &uinfo
-	return
+	return result.Entries[0], nil
 }
 
 func (ut *LdapLoginUtils) ValidateCredential(cred *Credential) bool {
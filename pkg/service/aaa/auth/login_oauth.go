@@ -16,6 +16,7 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -54,9 +55,11 @@ type OauthLoginUtils struct {
 
 // OauthCommonUserInfo adaptor all source
 type OauthCommonUserInfo struct {
-	Username string `json:"username"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
+	// 不同 IdP 的 id 可能是数字或字符串
+	ID       json.RawMessage `json:"id"`
+	Username string          `json:"username"`
+	Name     string          `json:"name"`
+	Email    string          `json:"email"`
 }
 
 func NewOauthUtils(name, vendor string, opts *OauthOption) *OauthLoginUtils {
@@ -111,6 +114,7 @@ func (ot *OauthLoginUtils) GetUserInfo(ctx context.Context, cred *Credential) (*
 	}
 	return &UserInfo{
 		Username: ret.Username,
+		Subject:  strings.Trim(string(ret.ID), `"`),
 		Email:    ret.Email,
 		Source:   cred.Source,
 		Vendor:   ot.Vendor,
@@ -0,0 +1,194 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/log"
+)
+
+const (
+	DefaultOIDCUsernameClaim = "preferred_username"
+	DefaultOIDCEmailClaim    = "email"
+	DefaultOIDCGroupsClaim   = "groups"
+)
+
+type OIDCOption struct {
+	Issuer        string   `json:"issuer"`
+	RedirectURL   string   `json:"redirectURL"`
+	AppID         string   `json:"appID"`
+	AppSecret     string   `json:"appSecret"`
+	Scopes        []string `json:"scopes"`
+	UsernameClaim string   `json:"usernameClaim"`
+	EmailClaim    string   `json:"emailClaim"`
+	GroupsClaim   string   `json:"groupsClaim"`
+}
+
+// oidcProviders 缓存 discovery 结果, 避免每次登录都请求 issuer
+var oidcProviders sync.Map
+
+type OIDCLoginUtils struct {
+	Name   string
+	Vendor string
+	opts   *OIDCOption
+	client *http.Client
+}
+
+func NewOIDCUtils(name, vendor string, opts *OIDCOption) *OIDCLoginUtils {
+	if opts.UsernameClaim == "" {
+		opts.UsernameClaim = DefaultOIDCUsernameClaim
+	}
+	if opts.EmailClaim == "" {
+		opts.EmailClaim = DefaultOIDCEmailClaim
+	}
+	if opts.GroupsClaim == "" {
+		opts.GroupsClaim = DefaultOIDCGroupsClaim
+	}
+	return &OIDCLoginUtils{
+		Name:   name,
+		Vendor: vendor,
+		opts:   opts,
+		client: &http.Client{},
+	}
+}
+
+func (ot *OIDCLoginUtils) GetName() string {
+	return ot.Name
+}
+
+func (ot *OIDCLoginUtils) LoginAddr() string {
+	config, _, err := ot.config(context.Background())
+	if err != nil {
+		log.Error(err, "oidc discovery", "issuer", ot.opts.Issuer)
+		return ""
+	}
+	return config.AuthCodeURL(generateState(ot.Name))
+}
+
+func (ot *OIDCLoginUtils) GetUserInfo(ctx context.Context, cred *Credential) (*UserInfo, error) {
+	config, provider, err := ot.config(ctx)
+	if err != nil {
+		log.Error(err, "oidc discovery", "issuer", ot.opts.Issuer)
+		return nil, i18n.Error(ctx, "failed to get userinfo from oidc provider")
+	}
+	ctxinner := oidc.ClientContext(ctx, ot.client)
+	token, err := config.Exchange(ctxinner, cred.Code)
+	if err != nil {
+		log.Debugf("oidc exchange token failed: %v", err)
+		return nil, i18n.Error(ctx, "exchange oauth2 token failed")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, i18n.Error(ctx, "no id_token in oidc token response")
+	}
+	idtoken, err := provider.Verifier(&oidc.Config{ClientID: ot.opts.AppID}).Verify(ctxinner, rawIDToken)
+	if err != nil {
+		log.Debugf("oidc verify id_token failed: %v", err)
+		return nil, i18n.Error(ctx, "invalid id_token")
+	}
+	claims := map[string]any{}
+	if err := idtoken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	// 部分 IdP 的 id_token 中不包含 profile 信息, 从 userinfo 补充
+	if _, ok := claims[ot.opts.UsernameClaim]; !ok && provider.UserInfoEndpoint() != "" {
+		if userinfo, err := provider.UserInfo(ctxinner, config.TokenSource(ctxinner, token)); err == nil {
+			extra := map[string]any{}
+			if err := userinfo.Claims(&extra); err == nil {
+				for k, v := range extra {
+					if _, ok := claims[k]; !ok {
+						claims[k] = v
+					}
+				}
+			}
+		}
+	}
+	username := claimString(claims, ot.opts.UsernameClaim)
+	if username == "" {
+		return nil, i18n.Error(ctx, "failed to get username from oidc provider")
+	}
+	return &UserInfo{
+		Username: username,
+		Subject:  idtoken.Subject,
+		Email:    claimString(claims, ot.opts.EmailClaim),
+		Source:   cred.Source,
+		Vendor:   ot.Vendor,
+		Groups:   claimStrings(claims, ot.opts.GroupsClaim),
+	}, nil
+}
+
+func (ot *OIDCLoginUtils) config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	var provider *oidc.Provider
+	if cached, ok := oidcProviders.Load(ot.opts.Issuer); ok {
+		provider = cached.(*oidc.Provider)
+	} else {
+		p, err := oidc.NewProvider(oidc.ClientContext(ctx, ot.client), ot.opts.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		oidcProviders.Store(ot.opts.Issuer, p)
+		provider = p
+	}
+	scopes := ot.opts.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	} else if !slices.Contains(scopes, oidc.ScopeOpenID) {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+	config := &oauth2.Config{
+		ClientID:     ot.opts.AppID,
+		ClientSecret: ot.opts.AppSecret,
+		Scopes:       scopes,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  ot.opts.RedirectURL,
+	}
+	return config, provider, nil
+}
+
+func claimString(claims map[string]any, key string) string {
+	switch val := claims[key].(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func claimStrings(claims map[string]any, key string) []string {
+	switch val := claims[key].(type) {
+	case string:
+		return []string{val}
+	case []any:
+		ret := make([]string, 0, len(val))
+		for _, v := range val {
+			if s, ok := v.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,436 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/google/uuid"
+	xrv "github.com/mattermost/xml-roundtrip-validator"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+const (
+	samlNamespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlNamespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlBindingHTTPPost    = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlStatusSuccess      = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlNameIDUnspecified  = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	samlClockSkew          = 3 * time.Minute
+	// 发出的 AuthnRequest 的有效期, 超过后 IdP 返回的 Response 不再接受
+	samlRequestTTL = 10 * time.Minute
+
+	// ADFS 默认的 claim 类型
+	DefaultSAMLEmailAttribute  = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"
+	DefaultSAMLGroupsAttribute = "http://schemas.xmlsoap.org/claims/Group"
+)
+
+type SAMLOption struct {
+	SSOURL         string `json:"ssoURL"`
+	EntityID       string `json:"entityID"`
+	ACSURL         string `json:"acsURL"`
+	IdPCertificate string `json:"idpCertificate"`
+	// 为空时使用 NameID 作为用户名
+	UsernameAttribute string `json:"usernameAttribute"`
+	EmailAttribute    string `json:"emailAttribute"`
+	GroupsAttribute   string `json:"groupsAttribute"`
+}
+
+// SAMLLoginUtils SAML 2.0 SP, 使用 HTTP-Redirect 发送 AuthnRequest, 使用 HTTP-POST 接收 Response;
+// 只接受 SP 发起的登录, 发出的请求 ID 和已使用的 assertion ID 记录在数据库中
+type SAMLLoginUtils struct {
+	Name   string
+	Vendor string
+	opts   *SAMLOption
+	db     *gorm.DB
+}
+
+func NewSAMLUtils(name, vendor string, opts *SAMLOption, db *gorm.DB) *SAMLLoginUtils {
+	if opts.EmailAttribute == "" {
+		opts.EmailAttribute = DefaultSAMLEmailAttribute
+	}
+	if opts.GroupsAttribute == "" {
+		opts.GroupsAttribute = DefaultSAMLGroupsAttribute
+	}
+	return &SAMLLoginUtils{Name: name, Vendor: vendor, opts: opts, db: db}
+}
+
+func (s *SAMLLoginUtils) GetName() string {
+	return s.Name
+}
+
+type samlAuthnRequest struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	NameIDPolicy                struct {
+		AllowCreate bool   `xml:"AllowCreate,attr"`
+		Format      string `xml:"Format,attr"`
+	} `xml:"NameIDPolicy"`
+}
+
+func (s *SAMLLoginUtils) LoginAddr() string {
+	now := time.Now()
+	// 清理过期的请求和 assertion 记录
+	if err := s.db.Where("expire_at < ?", now).Delete(&models.AuthNonce{}).Error; err != nil {
+		log.Error(err, "clean expired saml nonces")
+	}
+	req := samlAuthnRequest{
+		ID:                          "id-" + uuid.NewString(),
+		Version:                     "2.0",
+		IssueInstant:                now.UTC().Format(time.RFC3339),
+		Destination:                 s.opts.SSOURL,
+		AssertionConsumerServiceURL: s.opts.ACSURL,
+		ProtocolBinding:             samlBindingHTTPPost,
+		Issuer:                      s.opts.EntityID,
+	}
+	req.NameIDPolicy.AllowCreate = true
+	req.NameIDPolicy.Format = samlNameIDUnspecified
+	// 记录发出的请求, 用于校验 Response 的 InResponseTo
+	if err := s.db.Create(&models.AuthNonce{
+		Kind:     models.AuthNonceKindSAMLRequest,
+		Value:    req.ID,
+		Source:   s.Name,
+		ExpireAt: now.Add(samlRequestTTL),
+	}).Error; err != nil {
+		log.Error(err, "save saml authn request", "source", s.Name)
+		return ""
+	}
+	data, err := xml.Marshal(req)
+	if err != nil {
+		log.Error(err, "marshal saml authn request")
+		return ""
+	}
+	buf := &bytes.Buffer{}
+	w, _ := flate.NewWriter(buf, flate.DefaultCompression)
+	w.Write(data)
+	w.Close()
+
+	u, err := url.Parse(s.opts.SSOURL)
+	if err != nil {
+		log.Error(err, "parse saml sso url", "url", s.opts.SSOURL)
+		return ""
+	}
+	q := u.Query()
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	q.Set("RelayState", generateState(s.Name))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+type samlAssertion struct {
+	ID      string `xml:"ID,attr"`
+	Issuer  string `xml:"Issuer"`
+	Subject struct {
+		NameID              string `xml:"NameID"`
+		SubjectConfirmation []struct {
+			Data struct {
+				NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+				Recipient    string    `xml:"Recipient,attr"`
+				InResponseTo string    `xml:"InResponseTo,attr"`
+			} `xml:"SubjectConfirmationData"`
+		} `xml:"SubjectConfirmation"`
+	} `xml:"Subject"`
+	Conditions struct {
+		NotBefore           time.Time `xml:"NotBefore,attr"`
+		NotOnOrAfter        time.Time `xml:"NotOnOrAfter,attr"`
+		AudienceRestriction []struct {
+			Audience []string `xml:"Audience"`
+		} `xml:"AudienceRestriction"`
+	} `xml:"Conditions"`
+	Attributes []struct {
+		Name   string   `xml:"Name,attr"`
+		Values []string `xml:"AttributeValue"`
+	} `xml:"AttributeStatement>Attribute"`
+}
+
+// GetUserInfo cred.Code 为 IdP POST 到 ACS 的 SAMLResponse
+func (s *SAMLLoginUtils) GetUserInfo(ctx context.Context, cred *Credential) (*UserInfo, error) {
+	assertion, err := s.parseResponse(ctx, cred.Code)
+	if err != nil {
+		log.Error(err, "validate saml response", "source", s.Name)
+		return nil, i18n.Error(ctx, "invalid saml response")
+	}
+	username := assertion.Subject.NameID
+	if s.opts.UsernameAttribute != "" {
+		username = firstValue(assertion.attribute(s.opts.UsernameAttribute))
+	}
+	if username == "" {
+		return nil, i18n.Error(ctx, "failed to get username from saml response")
+	}
+	return &UserInfo{
+		Username: username,
+		Subject:  assertion.Subject.NameID,
+		Email:    firstValue(assertion.attribute(s.opts.EmailAttribute)),
+		Source:   cred.Source,
+		Vendor:   s.Vendor,
+		Groups:   assertion.attribute(s.opts.GroupsAttribute),
+	}, nil
+}
+
+func (a *samlAssertion) attribute(name string) []string {
+	for _, attr := range a.Attributes {
+		if attr.Name == name {
+			return attr.Values
+		}
+	}
+	return nil
+}
+
+func firstValue(vals []string) string {
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+func (s *SAMLLoginUtils) parseResponse(ctx context.Context, encoded string) (*samlAssertion, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return nil, err
+	}
+	// encoding/xml 与 etree 解析结果不一致的文档可以绕过签名校验
+	if err := xrv.Validate(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	certs, err := parseCertificates(s.opts.IdPCertificate)
+	if err != nil {
+		return nil, err
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, err
+	}
+	for _, token := range doc.Child {
+		if _, ok := token.(*etree.Directive); ok {
+			return nil, errors.New("doctype is not allowed")
+		}
+	}
+	root := doc.Root()
+	if root == nil {
+		return nil, errors.New("not a saml response")
+	}
+	nsctx, err := etreeutils.NewDefaultNSContext().SubContext(root)
+	if err != nil {
+		return nil, err
+	}
+	if ns, err := nsctx.LookupPrefix(root.Space); err != nil || ns != samlNamespaceProtocol || root.Tag != "Response" {
+		return nil, errors.New("not a saml response")
+	}
+	status, _ := etreeutils.NSFindOneChildCtx(nsctx, root, samlNamespaceProtocol, "Status")
+	if status == nil {
+		return nil, errors.New("saml response status is not success")
+	}
+	statusCode, _ := etreeutils.NSFindOneChildCtx(nsctx, status, samlNamespaceProtocol, "StatusCode")
+	if statusCode == nil || statusCode.SelectAttrValue("Value", "") != samlStatusSuccess {
+		return nil, errors.New("saml response status is not success")
+	}
+	if encrypted, _ := etreeutils.NSFindOneChildCtx(nsctx, root, samlNamespaceAssertion, "EncryptedAssertion"); encrypted != nil {
+		return nil, errors.New("encrypted assertion is not supported")
+	}
+
+	// 仅从签名覆盖的内容中读取 assertion
+	vctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certs})
+	vctx.IdAttribute = "ID"
+	signed, err := vctx.Validate(root)
+	switch {
+	case err == nil:
+	case errors.Is(err, dsig.ErrMissingSignature):
+		assertions := []*etree.Element{}
+		if err := etreeutils.NSFindChildrenIterateCtx(nsctx, root, samlNamespaceAssertion, "Assertion",
+			func(ctx etreeutils.NSContext, el *etree.Element) error {
+				// 带上祖先元素中声明的命名空间
+				detached, err := etreeutils.NSDetatch(ctx, el)
+				if err != nil {
+					return err
+				}
+				assertions = append(assertions, detached)
+				return nil
+			}); err != nil {
+			return nil, err
+		}
+		if len(assertions) != 1 {
+			return nil, errors.New("exactly one assertion is required")
+		}
+		assertion, err := vctx.Validate(assertions[0])
+		if err != nil {
+			return nil, fmt.Errorf("verify assertion: %w", err)
+		}
+		signed = etree.NewElement("Response")
+		signed.CreateAttr("xmlns", samlNamespaceProtocol)
+		signed.AddChild(assertion)
+	default:
+		return nil, fmt.Errorf("verify response: %w", err)
+	}
+	signedDoc := etree.NewDocument()
+	signedDoc.SetRoot(signed)
+	data, err := signedDoc.WriteToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := struct {
+		Assertions []samlAssertion `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+	}{}
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Assertions) != 1 {
+		return nil, errors.New("exactly one assertion is required")
+	}
+	assertion := &resp.Assertions[0]
+	inResponseTo := root.SelectAttrValue("InResponseTo", "")
+	if err := s.validateAssertion(assertion, inResponseTo, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.useNonces(ctx, assertion, inResponseTo); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+func (s *SAMLLoginUtils) validateAssertion(assertion *samlAssertion, inResponseTo string, now time.Time) error {
+	if assertion.ID == "" {
+		return errors.New("assertion id is required")
+	}
+	if inResponseTo == "" {
+		return errors.New("unsolicited saml response is not supported")
+	}
+	conditions := assertion.Conditions
+	if !conditions.NotBefore.IsZero() && now.Add(samlClockSkew).Before(conditions.NotBefore) {
+		return errors.New("assertion is not yet valid")
+	}
+	if !conditions.NotOnOrAfter.IsZero() && !now.Add(-samlClockSkew).Before(conditions.NotOnOrAfter) {
+		return errors.New("assertion has expired")
+	}
+	if len(conditions.AudienceRestriction) == 0 {
+		return errors.New("assertion has no audience restriction")
+	}
+	// 每个 AudienceRestriction 都需要包含 SP
+	for _, restriction := range conditions.AudienceRestriction {
+		matched := false
+		for _, audience := range restriction.Audience {
+			if s.opts.EntityID != "" && audience == s.opts.EntityID {
+				matched = true
+			}
+		}
+		if !matched {
+			return errors.New("assertion audience mismatch")
+		}
+	}
+	for _, confirmation := range assertion.Subject.SubjectConfirmation {
+		data := confirmation.Data
+		if s.opts.ACSURL != "" && data.Recipient != "" && data.Recipient != s.opts.ACSURL {
+			return errors.New("assertion recipient mismatch")
+		}
+		if data.InResponseTo != "" && data.InResponseTo != inResponseTo {
+			return errors.New("assertion InResponseTo mismatch")
+		}
+		if !data.NotOnOrAfter.IsZero() && !now.Add(-samlClockSkew).Before(data.NotOnOrAfter) {
+			return errors.New("subject confirmation has expired")
+		}
+	}
+	return nil
+}
+
+// useNonces 使用发出的请求 ID, 并记录 assertion ID 防止 assertion 在有效期内被重放
+func (s *SAMLLoginUtils) useNonces(ctx context.Context, assertion *samlAssertion, inResponseTo string) error {
+	now := time.Now()
+	db := s.db.WithContext(ctx)
+	ret := db.Where("kind = ? and value = ? and source = ? and expire_at > ?", models.AuthNonceKindSAMLRequest, inResponseTo, s.Name, now).
+		Delete(&models.AuthNonce{})
+	if ret.Error != nil {
+		return ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return errors.New("saml response is not in response to a pending request")
+	}
+	expire := assertion.Conditions.NotOnOrAfter
+	if expire.IsZero() {
+		expire = now.Add(samlClockSkew)
+	}
+	ret = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.AuthNonce{
+		Kind:     models.AuthNonceKindSAMLAssertion,
+		Value:    assertion.ID,
+		Source:   s.Name,
+		ExpireAt: expire.Add(samlClockSkew),
+	})
+	if ret.Error != nil {
+		return ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return errors.New("assertion has been used")
+	}
+	return nil
+}
+
+// parseCertificates 支持 PEM 格式, 或者 IdP metadata 中的 base64 证书内容
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) > 0 {
+		return certs, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid idp certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return []*x509.Certificate{cert}, nil
+}
+
+// ValidateSAMLCertificate 校验 IdP 证书格式
+func ValidateSAMLCertificate(data string) error {
+	_, err := parseCertificates(data)
+	return err
+}
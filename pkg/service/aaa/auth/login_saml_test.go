@@ -0,0 +1,150 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/glebarez/sqlite"
+	dsig "github.com/russellhaering/goxmldsig"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+type samlTestIdP struct {
+	t   *testing.T
+	sig *dsig.SigningContext
+}
+
+// response 生成签名的 assertion, audience 为空时不包含 AudienceRestriction
+func (idp *samlTestIdP) response(requestID, assertionID, audience string, tamper bool) string {
+	now := time.Now().UTC()
+	assertion := etree.NewElement("saml:Assertion")
+	assertion.CreateAttr("xmlns:saml", samlNamespaceAssertion)
+	assertion.CreateAttr("ID", assertionID)
+	assertion.CreateAttr("Version", "2.0")
+	assertion.CreateAttr("IssueInstant", now.Format(time.RFC3339))
+	assertion.CreateElement("saml:Issuer").SetText("https://idp.example.com")
+	subject := assertion.CreateElement("saml:Subject")
+	subject.CreateElement("saml:NameID").SetText("alice")
+	data := subject.CreateElement("saml:SubjectConfirmation").CreateElement("saml:SubjectConfirmationData")
+	data.CreateAttr("Recipient", "https://kubegems.example.com/api/v1/saml/acs")
+	data.CreateAttr("NotOnOrAfter", now.Add(5*time.Minute).Format(time.RFC3339))
+	conditions := assertion.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", now.Add(-time.Minute).Format(time.RFC3339))
+	conditions.CreateAttr("NotOnOrAfter", now.Add(5*time.Minute).Format(time.RFC3339))
+	if audience != "" {
+		conditions.CreateElement("saml:AudienceRestriction").CreateElement("saml:Audience").SetText(audience)
+	}
+	attr := assertion.CreateElement("saml:AttributeStatement").CreateElement("saml:Attribute")
+	attr.CreateAttr("Name", DefaultSAMLEmailAttribute)
+	attr.CreateElement("saml:AttributeValue").SetText("alice@example.com")
+
+	signed, err := idp.sig.SignEnveloped(assertion)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	if tamper {
+		signed.FindElement("./Subject/NameID").SetText("admin")
+	}
+	resp := etree.NewElement("samlp:Response")
+	resp.CreateAttr("xmlns:samlp", samlNamespaceProtocol)
+	resp.CreateAttr("ID", "resp-"+assertionID)
+	resp.CreateAttr("Version", "2.0")
+	resp.CreateAttr("InResponseTo", requestID)
+	resp.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", samlStatusSuccess)
+	resp.AddChild(signed)
+	doc := etree.NewDocument()
+	doc.SetRoot(resp)
+	raw, err := doc.WriteToBytes()
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestSAMLLoginUtils_GetUserInfo(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.AuthNonce{}); err != nil {
+		t.Fatal(err)
+	}
+	ks := dsig.RandomKeyStoreForTest()
+	_, cert, _ := ks.GetKeyPair()
+	sig := dsig.NewDefaultSigningContext(ks)
+	sig.IdAttribute = "ID"
+	// 与常见 IdP 一致, 使用 exclusive c14n, 签名不受 Response 中命名空间声明的影响
+	sig.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	idp := &samlTestIdP{t: t, sig: sig}
+
+	const entityID = "https://kubegems.example.com"
+	s := NewSAMLUtils("saml", "adfs", &SAMLOption{
+		SSOURL:         "https://idp.example.com/sso",
+		EntityID:       entityID,
+		ACSURL:         "https://kubegems.example.com/api/v1/saml/acs",
+		IdPCertificate: base64.StdEncoding.EncodeToString(cert),
+	}, db)
+	newRequest := func() string {
+		if s.LoginAddr() == "" {
+			t.Fatal("empty login addr")
+		}
+		nonce := &models.AuthNonce{}
+		if err := db.Order("expire_at desc").First(nonce, "kind = ?", models.AuthNonceKindSAMLRequest).Error; err != nil {
+			t.Fatal(err)
+		}
+		return nonce.Value
+	}
+	ctx := context.Background()
+	login := func(resp string) (*UserInfo, error) {
+		return s.GetUserInfo(ctx, &Credential{Source: "saml", Code: resp})
+	}
+
+	first := idp.response(newRequest(), "a-1", entityID, false)
+	uinfo, err := login(first)
+	if err != nil {
+		t.Fatalf("GetUserInfo() error = %v", err)
+	}
+	if uinfo.Username != "alice" || uinfo.Subject != "alice" || uinfo.Email != "alice@example.com" {
+		t.Errorf("GetUserInfo() = %+v", uinfo)
+	}
+
+	tests := []struct {
+		name string
+		resp string
+	}{
+		{name: "replayed response", resp: first},
+		{name: "replayed assertion", resp: idp.response(newRequest(), "a-1", entityID, false)},
+		{name: "unknown request", resp: idp.response("id-unknown", "a-2", entityID, false)},
+		{name: "unsolicited", resp: idp.response("", "a-3", entityID, false)},
+		{name: "no audience restriction", resp: idp.response(newRequest(), "a-4", "", false)},
+		{name: "other audience", resp: idp.response(newRequest(), "a-5", "https://other.example.com", false)},
+		{name: "tampered", resp: idp.response(newRequest(), "a-6", entityID, true)},
+		{name: "not base64", resp: strings.Repeat("!", 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := login(tt.resp); err == nil {
+				t.Errorf("GetUserInfo() expected error")
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 	"kubegems.io/kubegems/pkg/i18n"
 	"kubegems.io/kubegems/pkg/log"
 	"kubegems.io/kubegems/pkg/service/aaa"
@@ -35,67 +36,81 @@ import (
 )
 
 type AuthMiddleware struct {
+	bearer  *BearerTokenUserLoader
 	getters []UserGetterIface
 	uif     aaa.ContextUserOperator
 }
 
-func NewAuthMiddleware(opts *jwt.Options, userif aaa.ContextUserOperator, tracer trace.Tracer) *AuthMiddleware {
+func NewAuthMiddleware(opts *jwt.Options, db *gorm.DB, userif aaa.ContextUserOperator, tracer trace.Tracer) *AuthMiddleware {
 	var getters []UserGetterIface
-	getters = append(getters, &BearerTokenUserLoader{
-		JWT:    opts.ToJWT(),
-		Tracer: tracer,
-	})
 	getters = append(getters, &PrivateTokenUserLoader{})
 	return &AuthMiddleware{
+		bearer: &BearerTokenUserLoader{
+			JWT:    opts.ToJWT(),
+			DB:     db,
+			Tracer: tracer,
+		},
 		getters: getters,
 		uif:     userif,
 	}
 }
 
 func (l *AuthMiddleware) FilterFunc(c *gin.Context) {
-	if len(l.getters) > 0 {
-		var (
-			loaded bool
-			user   models.CommonUserIface
-		)
-		for idx := range l.getters {
-			user, loaded = l.getters[idx].GetUser(c.Request)
-			if loaded {
-				break
-			}
-		}
-		if !loaded {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, i18n.Sprintf(c, "please login first"))
+	user, token, loaded := l.loadUser(c.Request)
+	if !loaded {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, i18n.Sprintf(c, "please login first"))
+		return
+	}
+	if token != nil {
+		clientIP := c.ClientIP()
+		if err := CheckUserToken(c, token, c.Request.Method, clientIP); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, err.Error())
 			return
 		}
-		l.uif.SetContextUser(c, user)
+		touchUserToken(c.Request.Context(), l.bearer.DB, token, clientIP)
+		aaa.SetContextUserToken(c, token)
 	}
+	l.uif.SetContextUser(c, user)
 	c.Next()
 }
 
 func (l *AuthMiddleware) GoRestfulMiddleware(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
-	if len(l.getters) > 0 {
-		var (
-			loaded bool
-			user   models.CommonUserIface
-		)
-		for idx := range l.getters {
-			user, loaded = l.getters[idx].GetUser(req.Request)
-			if loaded {
-				break
-			}
+	user, token, loaded := l.loadUser(req.Request)
+	if !loaded {
+		resp.WriteErrorString(http.StatusUnauthorized, "")
+		return
+	}
+	if token != nil {
+		clientIP := requestClientIP(req.Request)
+		if err := CheckUserToken(req.Request.Context(), token, req.Request.Method, clientIP); err != nil {
+			resp.WriteErrorString(http.StatusForbidden, err.Error())
+			return
 		}
-		if !loaded {
-			resp.WriteErrorString(http.StatusUnauthorized, "")
+		// 这里无法确定请求的租户、项目和环境, 限定范围的令牌不能访问
+		if len(token.Scopes) > 0 {
+			resp.WriteErrorString(http.StatusForbidden, i18n.Sprintf(req.Request.Context(), "token scopes do not allow access to this api"))
 			return
 		}
-		// To get username
-		// req.Attribute("username").(string)
-		req.SetAttribute("username", user.GetUsername())
+		touchUserToken(req.Request.Context(), l.bearer.DB, token, clientIP)
 	}
+	// To get username
+	// req.Attribute("username").(string)
+	req.SetAttribute("username", user.GetUsername())
 	chain.ProcessFilter(req, resp)
 }
 
+func (l *AuthMiddleware) loadUser(req *http.Request) (models.CommonUserIface, *models.UserToken, bool) {
+	if user, token, loaded := l.bearer.GetUserWithToken(req); loaded {
+		return user, token, true
+	}
+	for idx := range l.getters {
+		if user, loaded := l.getters[idx].GetUser(req); loaded {
+			return user, nil, true
+		}
+	}
+	return nil, nil, false
+}
+
 // UserGetterIface
 type UserGetterIface interface {
 	GetUser(req *http.Request) (u user.CommonUserIface, exist bool)
@@ -103,31 +118,59 @@ type UserGetterIface interface {
 
 // BearerTokenUserLoader  bearer type
 type BearerTokenUserLoader struct {
-	JWT    *jwt.JWT
+	JWT *jwt.JWT
+	// 用于加载个人访问令牌, 为空时不接受个人访问令牌
+	DB     *gorm.DB
 	Tracer trace.Tracer
 }
 
+// GetUser 不接受受限的个人访问令牌, 受限令牌需要通过 GetUserWithToken 获取并校验令牌限制
 func (l *BearerTokenUserLoader) GetUser(req *http.Request) (u user.CommonUserIface, exist bool) {
-	htype, token := parseAuthorizationHeader(req)
+	u, token, exist := l.GetUserWithToken(req)
+	if !exist {
+		return nil, false
+	}
+	if token != nil && token.Restricted() {
+		log.Info("restricted token not allowed", "user", u.GetUsername(), "token", token.ID)
+		return nil, false
+	}
+	return u, true
+}
+
+// GetUserWithToken 解析 bearer token, 如果是个人访问令牌(带有 jti)同时返回令牌记录
+func (l *BearerTokenUserLoader) GetUserWithToken(req *http.Request) (u user.CommonUserIface, token *models.UserToken, exist bool) {
+	htype, tokenStr := parseAuthorizationHeader(req)
 	_, span := l.Tracer.Start(req.Context(), "GetUser")
 	defer span.End()
 	if strings.ToLower(htype) != "bearer" {
-		log.Warnf("token %s not valid", token)
-		return nil, false
+		log.Warnf("token %s not valid", tokenStr)
+		return nil, nil, false
 	}
-	claims, err := l.JWT.ParseToken(token)
+	claims, err := l.JWT.ParseToken(tokenStr)
 	if err != nil {
 		log.Error(err, "parse jwt token")
-		return nil, false
+		return nil, nil, false
 	}
 	bts, _ := json.Marshal(claims.Payload)
 	var user models.User
 	err = json.Unmarshal(bts, &user)
 	if err != nil {
 		log.Error(err, "failed to load userinfo", "data", string(bts))
+		return nil, nil, false
 	}
 	span.SetAttributes(attribute.Int("user.id", int(user.ID)), attribute.String("user.name", user.Username))
-	return &user, err == nil
+	if claims.Id != "" {
+		if l.DB == nil {
+			log.Info("personal access token not supported", "user", user.Username)
+			return nil, nil, false
+		}
+		token, err = loadUserToken(req.Context(), l.DB, claims.Id, user.ID)
+		if err != nil {
+			log.Error(err, "load user token", "user", user.Username, "jti", claims.Id)
+			return nil, nil, false
+		}
+	}
+	return &user, token, true
 }
 
 // PrivateTokenUserLoader private-token
@@ -0,0 +1,318 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils"
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+	"kubegems.io/kubegems/pkg/utils/totp"
+)
+
+const (
+	TwoFactorIssuer = "kubegems"
+
+	// TwoFactorReasonRequired 已开启两步验证, 需要提供验证码
+	TwoFactorReasonRequired = "required"
+	// TwoFactorReasonEnroll 安全策略要求开启两步验证, 需要先完成绑定
+	TwoFactorReasonEnroll = "enroll"
+
+	totpSkew          = 1
+	recoveryCodeCount = 10
+
+	// 连续失败 twoFactorMaxFailures 次后锁定, 每次锁定的时间翻倍
+	twoFactorMaxFailures = 5
+	twoFactorLockBase    = time.Minute
+	twoFactorLockMax     = time.Hour
+)
+
+// TwoFactorError 账号登录时缺少两步验证, 前端根据 Reason 引导用户输入验证码或者完成绑定
+type TwoFactorError struct {
+	Reason  string `json:"twoFactor"`
+	message string
+}
+
+func (e *TwoFactorError) Error() string {
+	return e.message
+}
+
+// TOTPEnrollment 绑定验证器所需的信息
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+}
+
+// GetSecurityPolicy 获取系统安全策略, 未配置时返回默认策略
+func GetSecurityPolicy(ctx context.Context, db *gorm.DB) (*models.SecurityPolicy, error) {
+	policy := &models.SecurityPolicy{}
+	if err := db.WithContext(ctx).First(policy).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// TwoFactorRequired 判断安全策略是否要求用户开启两步验证
+func TwoFactorRequired(ctx context.Context, db *gorm.DB, user *models.User) (bool, error) {
+	policy, err := GetSecurityPolicy(ctx, db)
+	if err != nil {
+		return false, err
+	}
+	if policy.RequireTwoFactorForSysAdmin {
+		role := &models.SystemRole{}
+		if err := db.WithContext(ctx).First(role, user.SystemRoleID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+		if role.RoleCode == models.SystemRoleAdmin {
+			return true, nil
+		}
+	}
+	if policy.RequireTwoFactorForTenantAdmin {
+		var count int64
+		if err := db.WithContext(ctx).Model(&models.TenantUserRels{}).
+			Where("user_id = ? and role = ?", user.ID, models.TenantRoleAdmin).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// VerifyTwoFactor 账号登录时的两步验证, code 可以是验证器中的验证码或者恢复码
+func VerifyTwoFactor(ctx context.Context, db *gorm.DB, user *models.User, code string) error {
+	tf := &models.UserTOTP{}
+	if err := db.WithContext(ctx).First(tf, "user_id = ?", user.ID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if !tf.Enabled {
+		required, err := TwoFactorRequired(ctx, db, user)
+		if err != nil {
+			return err
+		}
+		if required {
+			return &TwoFactorError{
+				Reason:  TwoFactorReasonEnroll,
+				message: i18n.Sprintf(ctx, "two-factor authentication is required by the security policy, please enroll first"),
+			}
+		}
+		return nil
+	}
+	if strings.TrimSpace(code) == "" {
+		return &TwoFactorError{
+			Reason:  TwoFactorReasonRequired,
+			message: i18n.Sprintf(ctx, "two-factor authentication code required"),
+		}
+	}
+	return checkTwoFactorCode(ctx, db, tf.ID, code, true)
+}
+
+// EnrollTOTP 为用户生成新的密钥, 需要调用 ActivateTOTP 校验验证码后才会开启
+func EnrollTOTP(ctx context.Context, db *gorm.DB, user *models.User) (*TOTPEnrollment, error) {
+	tf := &models.UserTOTP{}
+	if err := db.WithContext(ctx).First(tf, "user_id = ?", user.ID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, i18n.Errorf(ctx, "two-factor authentication is already enabled")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	tf.UserID = user.ID
+	tf.Secret = secret
+	tf.RecoveryCodes = nil
+	tf.LastUsedStep = 0
+	if err := db.WithContext(ctx).Save(tf).Error; err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{Secret: secret, URL: totp.URL(TwoFactorIssuer, user.Username, secret)}, nil
+}
+
+// ActivateTOTP 校验验证码并开启两步验证, 返回恢复码明文, 仅返回这一次
+func ActivateTOTP(ctx context.Context, db *gorm.DB, user *models.User, code string) ([]string, error) {
+	tf := &models.UserTOTP{}
+	if err := db.WithContext(ctx).First(tf, "user_id = ?", user.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, i18n.Errorf(ctx, "two-factor authentication is not enrolled")
+		}
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, i18n.Errorf(ctx, "two-factor authentication is already enabled")
+	}
+	now := time.Now()
+	if err := checkTwoFactorLocked(ctx, tf, now); err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(tf.Secret, code, now, totpSkew)
+	if !ok {
+		if err := recordTwoFactorFailure(db.WithContext(ctx), tf, now); err != nil {
+			return nil, err
+		}
+		return nil, i18n.Errorf(ctx, "invalid two-factor authentication code")
+	}
+	codes, hashed, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	tf.Enabled = true
+	tf.EnabledAt = &now
+	tf.LastUsedStep = step
+	tf.RecoveryCodes = hashed
+	tf.FailedAttempts = 0
+	tf.LockedUntil = nil
+	if err := db.WithContext(ctx).Save(tf).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码, 旧的恢复码全部失效
+func RegenerateRecoveryCodes(ctx context.Context, db *gorm.DB, user *models.User, code string) ([]string, error) {
+	tf := &models.UserTOTP{}
+	if err := db.WithContext(ctx).First(tf, "user_id = ? and enabled = ?", user.ID, true).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, i18n.Errorf(ctx, "two-factor authentication is not enabled")
+		}
+		return nil, err
+	}
+	if err := checkTwoFactorCode(ctx, db, tf.ID, code, false); err != nil {
+		return nil, err
+	}
+	codes, hashed, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.WithContext(ctx).Model(tf).Update("recovery_codes", hashed).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP 关闭两步验证, verify 为 false 时不校验验证码, 用于管理员重置
+func DisableTOTP(ctx context.Context, db *gorm.DB, user *models.User, code string, verify bool) error {
+	tf := &models.UserTOTP{}
+	if err := db.WithContext(ctx).First(tf, "user_id = ?", user.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if verify && tf.Enabled {
+		if err := checkTwoFactorCode(ctx, db, tf.ID, code, true); err != nil {
+			return err
+		}
+	}
+	return db.WithContext(ctx).Delete(tf).Error
+}
+
+// checkTwoFactorCode 校验验证码, 使用行锁保证同一个验证码或恢复码只能使用一次, 连续失败过多时锁定
+func checkTwoFactorCode(ctx context.Context, db *gorm.DB, id uint, code string, allowRecovery bool) error {
+	code = strings.TrimSpace(code)
+	// 校验失败时需要提交失败次数, 因此失败的原因不作为事务的错误返回
+	var verifyErr error
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tf := &models.UserTOTP{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(tf, id).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if verifyErr = checkTwoFactorLocked(ctx, tf, now); verifyErr != nil {
+			return nil
+		}
+		success := map[string]interface{}{"failed_attempts": 0, "locked_until": nil}
+		if step, ok := totp.Validate(tf.Secret, code, now, totpSkew); ok {
+			if step > tf.LastUsedStep {
+				success["last_used_step"] = step
+				return tx.Model(tf).Updates(success).Error
+			}
+			verifyErr = i18n.Errorf(ctx, "two-factor authentication code has already been used")
+			return recordTwoFactorFailure(tx, tf, now)
+		}
+		if allowRecovery {
+			for i, hashed := range tf.RecoveryCodes {
+				if utils.ValidatePassword(normalizeRecoveryCode(code), hashed) != nil {
+					continue
+				}
+				success["recovery_codes"] = append(tf.RecoveryCodes[:i:i], tf.RecoveryCodes[i+1:]...)
+				return tx.Model(tf).Updates(success).Error
+			}
+		}
+		verifyErr = i18n.Errorf(ctx, "invalid two-factor authentication code")
+		return recordTwoFactorFailure(tx, tf, now)
+	})
+	if err != nil {
+		return err
+	}
+	return verifyErr
+}
+
+// checkTwoFactorLocked 连续失败过多被锁定时返回错误
+func checkTwoFactorLocked(ctx context.Context, tf *models.UserTOTP, now time.Time) error {
+	if tf.LockedUntil != nil && now.Before(*tf.LockedUntil) {
+		return i18n.Errorf(ctx, "too many failed two-factor authentication attempts, please retry after %s",
+			tf.LockedUntil.Sub(now).Round(time.Second).String())
+	}
+	return nil
+}
+
+// recordTwoFactorFailure 记录一次校验失败, 每连续失败 twoFactorMaxFailures 次锁定一次
+func recordTwoFactorFailure(db *gorm.DB, tf *models.UserTOTP, now time.Time) error {
+	failures := tf.FailedAttempts + 1
+	updates := map[string]interface{}{"failed_attempts": failures}
+	if failures%twoFactorMaxFailures == 0 {
+		lock := twoFactorLockMax
+		if n := failures/twoFactorMaxFailures - 1; n < 6 {
+			lock = min(twoFactorLockBase<<n, twoFactorLockMax)
+		}
+		updates["locked_until"] = now.Add(lock)
+	}
+	return db.Model(tf).Updates(updates).Error
+}
+
+func generateRecoveryCodes() ([]string, gormdatatypes.JSONSlice, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashed := make(gormdatatypes.JSONSlice, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(buf)
+		h, err := utils.MakePassword(code)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashed = append(hashed, h)
+	}
+	return codes, hashed, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
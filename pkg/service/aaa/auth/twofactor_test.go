@@ -0,0 +1,77 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/totp"
+)
+
+func TestCheckTwoFactorCodeLock(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.UserTOTP{}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf := &models.UserTOTP{UserID: 1, Secret: secret, Enabled: true}
+	if err := db.Create(tf).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for i := 0; i < twoFactorMaxFailures; i++ {
+		if err := checkTwoFactorCode(ctx, db, tf.ID, "000000x", true); err == nil {
+			t.Fatal("invalid code should be rejected")
+		}
+	}
+	if err := db.First(tf, tf.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if tf.FailedAttempts != twoFactorMaxFailures || tf.LockedUntil == nil || !tf.LockedUntil.After(time.Now()) {
+		t.Fatalf("should be locked after %d failures, got failures %d locked until %v", twoFactorMaxFailures, tf.FailedAttempts, tf.LockedUntil)
+	}
+	// 锁定期间正确的验证码也会被拒绝
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTwoFactorCode(ctx, db, tf.ID, code, true); err == nil {
+		t.Fatal("locked two-factor should reject valid code")
+	}
+	// 锁定结束后验证成功, 失败次数清零
+	if err := db.Model(tf).Update("locked_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTwoFactorCode(ctx, db, tf.ID, code, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.First(tf, tf.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if tf.FailedAttempts != 0 || tf.LockedUntil != nil {
+		t.Errorf("failures should be reset after success, got %d %v", tf.FailedAttempts, tf.LockedUntil)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/slice"
+)
+
+// 令牌最后使用时间的记录间隔, 避免每个请求都写数据库
+const tokenTouchInterval = time.Minute
+
+var readOnlyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+}
+
+// loadUserToken 根据 jti 加载个人访问令牌, 令牌删除后立即失效
+func loadUserToken(ctx context.Context, db *gorm.DB, jti string, userID uint) (*models.UserToken, error) {
+	token := &models.UserToken{}
+	if err := db.WithContext(ctx).First(token, "id = ? and user_id = ?", jti, userID).Error; err != nil {
+		return nil, err
+	}
+	if token.ExpireAt != nil && time.Now().After(*token.ExpireAt) {
+		return nil, i18n.Errorf(ctx, "token expired")
+	}
+	return token, nil
+}
+
+// CheckUserToken 校验令牌的来源 IP 白名单和只读限制
+func CheckUserToken(ctx context.Context, token *models.UserToken, method, clientIP string) error {
+	if len(token.AllowedIPs) > 0 && !IPAllowed(token.AllowedIPs, clientIP) {
+		return i18n.Errorf(ctx, "token is not allowed to be used from %s", clientIP)
+	}
+	if token.ReadOnly && !slice.ContainStr(readOnlyMethods, method) {
+		return i18n.Errorf(ctx, "token is read-only")
+	}
+	return nil
+}
+
+// IPAllowed 判断 ip 是否在白名单中, 白名单支持单个 IP 或者 CIDR
+func IPAllowed(allowed []string, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, item := range allowed {
+		if strings.Contains(item, "/") {
+			if _, cidr, err := net.ParseCIDR(item); err == nil && cidr.Contains(parsed) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(item); allowedIP != nil && allowedIP.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// touchUserToken 记录令牌最后使用时间和来源 IP
+func touchUserToken(ctx context.Context, db *gorm.DB, token *models.UserToken, clientIP string) {
+	now := time.Now()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < tokenTouchInterval && token.LastUsedIP == clientIP {
+		return
+	}
+	db.WithContext(ctx).Model(token).UpdateColumns(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": clientIP,
+	})
+}
+
+// requestClientIP 非 gin 场景下获取请求来源 IP, 不信任 X-Forwarded-For 等请求头,
+// 经由 gin 转发的请求 RemoteAddr 已经按照 TrustedProxies 设置为真实 IP
+func requestClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(req.RemoteAddr))
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+func TestIPAllowed(t *testing.T) {
+	allowed := []string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.1.2.3", want: true},
+		{ip: "192.168.1.10", want: true},
+		{ip: "192.168.1.11", want: false},
+		{ip: "fd00::1", want: true},
+		{ip: "invalid", want: false},
+	}
+	for _, tt := range tests {
+		if got := IPAllowed(allowed, tt.ip); got != tt.want {
+			t.Errorf("IPAllowed(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckUserToken(t *testing.T) {
+	token := &models.UserToken{ReadOnly: true, AllowedIPs: []string{"10.0.0.0/8"}}
+	ctx := context.Background()
+	if err := CheckUserToken(ctx, token, http.MethodGet, "10.0.0.1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckUserToken(ctx, token, http.MethodPost, "10.0.0.1"); err == nil {
+		t.Error("read-only token should reject write requests")
+	}
+	if err := CheckUserToken(ctx, token, http.MethodGet, "172.16.0.1"); err == nil {
+		t.Error("token should reject requests from ip not in allow-list")
+	}
+}
+
+func TestRequestClientIP(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:34567"
+	req.Header.Set("X-Forwarded-For", "192.168.1.10")
+	req.Header.Set("X-Real-Ip", "192.168.1.10")
+	if got := requestClientIP(req); got != "10.0.0.1" {
+		t.Errorf("requestClientIP() = %s, forwarded headers should not be trusted", got)
+	}
+}
@@ -52,6 +52,37 @@ type PermissionManager interface {
 	CheckIsATenantAdmin(c *gin.Context)
 	// CheckCanDeployEnvironment  判断是否有对应环境的部署权限
 	CheckCanDeployEnvironment(c *gin.Context)
+	// CheckNotScopedToken 判断是否使用了限定范围的个人访问令牌, 用于不属于任何环境的资源
+	CheckNotScopedToken(c *gin.Context)
+	// HasEnvPerm 判断是否有 cluster 和 namespace 关联环境的权限
+	HasEnvPerm(c *gin.Context, cluster, namespace string) (hasPerm bool, objname string, currentrole string)
+}
+
+// scopedTokenRouteParams 按这些路径参数鉴权的接口会检查令牌范围
+var scopedTokenRouteParams = []string{"tenant_id", "project_id", "environment_id", "virtualspace_id", "namespace"}
+
+// scopedTokenRoutes 在 handler 中自行检查令牌范围的接口
+var scopedTokenRoutes = []string{
+	"/v1/proxy/cluster/:cluster/*action",
+	"/v1/tenants/:tenant/projects/:project/environments/:environment/applications/:name/images",
+}
+
+// ScopedTokenFilter 限定范围的个人访问令牌默认拒绝, 只放行按租户/项目/环境/虚拟空间鉴权的接口,
+// 避免令牌访问个人信息、其它令牌等不属于任何资源的接口
+func ScopedTokenFilter(c *gin.Context) {
+	if !tokenScoped(c) {
+		return
+	}
+	if slice.ContainStr(scopedTokenRoutes, c.FullPath()) {
+		return
+	}
+	for _, param := range c.Params {
+		if slice.ContainStr(scopedTokenRouteParams, param.Key) {
+			return
+		}
+	}
+	handlers.Forbidden(c, i18n.Errorf(c, "you have no permission to do this operation"))
+	c.Abort()
 }
 
 type DefaultPermissionManager struct {
@@ -65,11 +96,14 @@ func (defaultPermChecker *DefaultPermissionManager) HasEnvPerm(c *gin.Context, c
 		return false, "", ""
 	}
 	userAuthoriy := defaultPermChecker.Cache.GetUserAuthority(user)
+	env := defaultPermChecker.Cache.FindEnvironment(cluster, namespace)
+	if (env == nil && tokenScoped(c)) || (env != nil && !defaultPermChecker.tokenPermits(c, env.GetKind(), env.GetID())) {
+		return false, namespace, ""
+	}
 	if userAuthoriy.IsSystemAdmin() {
 		return true, "", "admin"
 	}
 
-	env := defaultPermChecker.Cache.FindEnvironment(cluster, namespace)
 	if env == nil {
 		return false, "", ""
 	}
@@ -86,6 +120,12 @@ func (defaultPermChecker *DefaultPermissionManager) HasObjectPerm(c *gin.Context
 		currentrole = ""
 		return
 	}
+	if !defaultPermChecker.tokenPermits(c, kind, pk) {
+		if res := defaultPermChecker.Cache.FindResource(kind, pk); res != nil {
+			objname = res.GetName()
+		}
+		return false, objname, ""
+	}
 	userAuthoriy := defaultPermChecker.Cache.GetUserAuthority(user)
 	if userAuthoriy.IsSystemAdmin() {
 		hasPerm = true
@@ -103,6 +143,33 @@ func (defaultPermChecker *DefaultPermissionManager) HasObjectPerm(c *gin.Context
 	return
 }
 
+// tokenPermits 使用个人访问令牌时, 资源必须在令牌的范围内, 只读令牌只允许读操作
+func (defaultPermChecker *DefaultPermissionManager) tokenPermits(c *gin.Context, kind string, pk uint) bool {
+	token, exist := aaa.GetContextUserToken(c)
+	if !exist {
+		return true
+	}
+	if token.ReadOnly && !slice.ContainStr(normalActions, c.Request.Method) {
+		return false
+	}
+	if len(token.Scopes) == 0 {
+		return true
+	}
+	// FindParents 包含资源自身
+	for _, res := range defaultPermChecker.Cache.FindParents(kind, pk) {
+		if token.Scopes.Contains(res.GetKind(), res.GetID()) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenScoped 当前请求是否使用了限定范围的个人访问令牌, 这类令牌不能访问全局管理接口
+func tokenScoped(c *gin.Context) bool {
+	token, exist := aaa.GetContextUserToken(c)
+	return exist && len(token.Scopes) > 0
+}
+
 func (defaultPermChecker *DefaultPermissionManager) canDo(userAuthority *cache.UserAuthority, kind string, pk uint, action string) (hasPerm bool, currenrole string) {
 	parents := defaultPermChecker.Cache.FindParents(kind, pk)
 	if len(parents) == 0 {
@@ -191,6 +258,14 @@ func (defaultPermissionChecker *DefaultPermissionManager) CheckByClusterNamespac
 	}
 }
 
+func (defaultPermissionChecker *DefaultPermissionManager) CheckNotScopedToken(c *gin.Context) {
+	if tokenScoped(c) {
+		handlers.Forbidden(c, i18n.Errorf(c, "you have no permission to do this operation"))
+		c.Abort()
+		return
+	}
+}
+
 func (defaultPermissionChecker *DefaultPermissionManager) CheckByEnvironmentID(c *gin.Context) {
 	envid := utils.ToUint(c.Param("environment_id"))
 	hasPerm, objname, _ := defaultPermissionChecker.HasObjectPerm(c, models.ResEnvironment, envid)
@@ -247,7 +322,7 @@ func (defaultPermissionChecker *DefaultPermissionManager) CheckIsSysADMIN(c *gin
 		return
 	}
 	userAuthoriy := defaultPermissionChecker.Cache.GetUserAuthority(user)
-	if !userAuthoriy.IsSystemAdmin() {
+	if !userAuthoriy.IsSystemAdmin() || tokenScoped(c) {
 		handlers.Forbidden(c, i18n.Errorf(c, "you have no permission to do this operation"))
 		c.Abort()
 		return
@@ -261,6 +336,11 @@ func (defaultPermissionChecker *DefaultPermissionManager) CheckIsATenantAdmin(c
 		c.Abort()
 		return
 	}
+	if tokenScoped(c) {
+		handlers.Forbidden(c, i18n.Errorf(c, "you have no permission to do this operation"))
+		c.Abort()
+		return
+	}
 	userAuthoriy := defaultPermissionChecker.Cache.GetUserAuthority(user)
 	if userAuthoriy.IsSystemAdmin() {
 		return
@@ -284,6 +364,11 @@ func (defaultPermissionChecker *DefaultPermissionManager) CheckIsVirtualSpaceAdm
 		c.Abort()
 		return
 	}
+	if tokenScoped(c) {
+		handlers.Forbidden(c, i18n.Errorf(c, "you have no permission to do this operation"))
+		c.Abort()
+		return
+	}
 	userAuthoriy := defaultPermissionChecker.Cache.GetUserAuthority(user)
 	if userAuthoriy.IsSystemAdmin() {
 		return
@@ -312,20 +397,27 @@ func (defaultPermChecker *DefaultPermissionManager) CheckCanDeployEnvironment(c
 		return
 	}
 	userAuthoriy := defaultPermChecker.Cache.GetUserAuthority(user)
-	// 系统管理员. pass
-	if userAuthoriy.IsSystemAdmin() {
-		return
-	}
-
 	envid := utils.ToUint(c.Param("environment_id"))
 	if envid == 0 {
 		envid = utils.ToUint(c.Query("environment_id"))
 	}
 	if envid == 0 {
 		// 如果拿不到环境，就根据项目ID判断
+		if userAuthoriy.IsSystemAdmin() && !tokenScoped(c) {
+			return
+		}
 		defaultPermChecker.CheckByProjectID(c)
 		return
 	}
+	if !defaultPermChecker.tokenPermits(c, models.ResEnvironment, envid) {
+		handlers.Forbidden(c, i18n.Errorf(c, "you have no permission to deploy in the current environment"))
+		c.Abort()
+		return
+	}
+	// 系统管理员. pass
+	if userAuthoriy.IsSystemAdmin() {
+		return
+	}
 	parents := defaultPermChecker.Cache.FindParents(models.ResEnvironment, envid)
 	if len(parents) == 0 {
 		c.Abort()
@@ -0,0 +1,68 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorization
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/service/aaa"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+func TestScopedTokenFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	scoped := &models.UserToken{Scopes: models.UserTokenScopes{{Kind: models.ResEnvironment, ID: 1}}}
+	tests := []struct {
+		name   string
+		token  *models.UserToken
+		method string
+		path   string
+		want   int
+	}{
+		{name: "login token", method: http.MethodGet, path: "/v1/oauth/token", want: http.StatusOK},
+		{name: "unscoped token", token: &models.UserToken{}, method: http.MethodDelete, path: "/v1/oauth/token/1", want: http.StatusOK},
+		{name: "scoped token list tokens", token: scoped, method: http.MethodGet, path: "/v1/oauth/token", want: http.StatusForbidden},
+		{name: "scoped token delete token", token: scoped, method: http.MethodDelete, path: "/v1/oauth/token/1", want: http.StatusForbidden},
+		{name: "scoped token myinfo", token: scoped, method: http.MethodGet, path: "/v1/my/info", want: http.StatusForbidden},
+		{name: "scoped token environment", token: scoped, method: http.MethodGet, path: "/v1/environment/1", want: http.StatusOK},
+		{name: "scoped token proxy", token: scoped, method: http.MethodGet, path: "/v1/proxy/cluster/dev/core/v1/nodes", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			rg := r.Group("v1")
+			rg.Use(func(c *gin.Context) {
+				if tt.token != nil {
+					aaa.SetContextUserToken(c, tt.token)
+				}
+			}, ScopedTokenFilter)
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			rg.GET("/oauth/token", ok)
+			rg.DELETE("/oauth/token/:token_id", ok)
+			rg.GET("/my/info", ok)
+			rg.GET("/environment/:environment_id", ok)
+			rg.Any("/proxy/cluster/:cluster/*action", ok)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("ScopedTokenFilter() status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
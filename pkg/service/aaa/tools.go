@@ -19,6 +19,8 @@ import (
 	"kubegems.io/kubegems/pkg/service/models"
 )
 
+const ContextUserTokenKey = "current_user_token"
+
 type ContextUserGetter interface {
 	GetContextUser(c *gin.Context) (models.CommonUserIface, bool)
 }
@@ -52,3 +54,18 @@ func (i *UserInfoHandler) GetContextUser(c *gin.Context) (models.CommonUserIface
 	}
 	return nil, false
 }
+
+// SetContextUserToken 记录当前请求使用的个人访问令牌
+func SetContextUserToken(c *gin.Context, token *models.UserToken) {
+	c.Set(ContextUserTokenKey, token)
+}
+
+// GetContextUserToken 获取当前请求使用的个人访问令牌, 使用登录 token 时不存在
+func GetContextUserToken(c *gin.Context) (*models.UserToken, bool) {
+	token, exist := c.Get(ContextUserTokenKey)
+	if !exist {
+		return nil, false
+	}
+	ut, ok := token.(*models.UserToken)
+	return ut, ok
+}
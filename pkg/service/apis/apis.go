@@ -20,6 +20,8 @@ import (
 	"strings"
 
 	"github.com/emicklei/go-restful/v3"
+	"go.opentelemetry.io/otel"
+	"kubegems.io/kubegems/pkg/service/aaa/auth"
 	"kubegems.io/kubegems/pkg/service/apis/oidc"
 	"kubegems.io/kubegems/pkg/service/apis/plugins"
 	"kubegems.io/kubegems/pkg/service/options"
@@ -41,10 +43,17 @@ func InitAPI(ctx context.Context, deps Dependencies) (http.Handler, error) {
 	if err != nil {
 		return nil, err
 	}
-	op, err := oidc.NewProvider(ctx, &oidc.OIDCOptions{
-		Issuer:   deps.Opts.JWT.IssuerAddr,
-		CertFile: deps.Opts.JWT.Cert,
-		KeyFile:  deps.Opts.JWT.Key,
+	users := &auth.BearerTokenUserLoader{
+		JWT:    deps.Opts.JWT.ToJWT(),
+		DB:     deps.Database.DB(),
+		Tracer: otel.GetTracerProvider().Tracer("kubegems.io/kubegems"),
+	}
+	op, err := oidc.NewProvider(ctx, deps.Database.DB(), users, &oidc.OIDCOptions{
+		Issuer:              deps.Opts.JWT.IssuerAddr,
+		CertFile:            deps.Opts.JWT.Cert,
+		KeyFile:             deps.Opts.JWT.Key,
+		LoginURL:            deps.Opts.JWT.LoginURL,
+		AllowInsecureIssuer: deps.Opts.JWT.AllowInsecureIssuer,
 	})
 	if err != nil {
 		return nil, err
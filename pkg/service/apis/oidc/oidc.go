@@ -16,12 +16,19 @@ package oidc
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 
+	"github.com/zitadel/oidc/pkg/op"
 	"gopkg.in/square/go-jose.v2"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/aaa/auth/user"
 	"kubegems.io/library/rest/api"
 	"kubegems.io/library/rest/response"
 )
@@ -29,8 +36,17 @@ import (
 const (
 	DiscoveryEndpoint = "/.well-known/openid-configuration"
 	JWKSPath          = "/keys"
+
+	// ProviderPathPrefix 内置 OIDC Provider (authorize, token, userinfo ...) 的路径前缀, issuer 为 Issuer + ProviderPathPrefix
+	ProviderPathPrefix = "/oidc"
+	LoginPath          = "/login"
 )
 
+// UserGetter 获取请求中已登录的 kubegems 用户
+type UserGetter interface {
+	GetUser(req *http.Request) (u user.CommonUserIface, exist bool)
+}
+
 // nolint: tagliatelle
 type DiscoveryConfiguration struct {
 	Issuer                           string   `json:"issuer,omitempty"`
@@ -42,9 +58,17 @@ type OIDCProvider struct {
 	issuerPrefix string
 	keys         *jose.JSONWebKeySet
 	discovery    DiscoveryConfiguration
+
+	storage  *LocalStorage
+	provider op.OpenIDProvider
+	users    UserGetter
+	options  *OIDCOptions
 }
 
-func NewProvider(ctx context.Context, options *OIDCOptions) (*OIDCProvider, error) {
+func NewProvider(ctx context.Context, db *gorm.DB, users UserGetter, options *OIDCOptions) (*OIDCProvider, error) {
+	if err := options.validateLoginURL(); err != nil {
+		return nil, err
+	}
 	tlscert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
 	if err != nil {
 		return nil, err
@@ -54,7 +78,32 @@ func NewProvider(ctx context.Context, options *OIDCOptions) (*OIDCProvider, erro
 			(&jose.JSONWebKey{Key: tlscert.PrivateKey, Algorithm: string(jose.RS256), Use: "sig"}).Public(),
 		},
 	}
-	return &OIDCProvider{keys: keys}, nil
+	storage, err := NewLocalStorage(ctx, db, options)
+	if err != nil {
+		return nil, err
+	}
+	// the crypto key encrypts authorization codes and opaque access tokens,
+	// derive it from the signing key so that all replicas share the same one.
+	privkey, err := x509.MarshalPKCS8PrivateKey(tlscert.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	config := &op.Config{
+		Issuer:                strings.TrimSuffix(options.Issuer, "/") + ProviderPathPrefix,
+		CryptoKey:             sha256.Sum256(privkey),
+		CodeMethodS256:        true,
+		AuthMethodPost:        true,
+		GrantTypeRefreshToken: true,
+	}
+	if options.AllowInsecureIssuer {
+		// zitadel/oidc 只通过该环境变量允许 http 的 issuer
+		os.Setenv(op.OidcDevMode, "true")
+	}
+	provider, err := op.NewOpenIDProvider(ctx, config, storage)
+	if err != nil {
+		return nil, fmt.Errorf("builtin oidc provider issuer %s: %w, use an https issuer or enable jwt allowInsecureIssuer for development", config.Issuer, err)
+	}
+	return &OIDCProvider{keys: keys, storage: storage, provider: provider, users: users, options: options}, nil
 }
 
 func (m *OIDCProvider) Discovery(w http.ResponseWriter, r *http.Request) {
@@ -92,9 +141,43 @@ func (m *OIDCProvider) dynamicIssuer(r *http.Request) string {
 	return scheme + "://" + host + prefix
 }
 
+// Login 在用户登录 kubegems 后完成授权请求, 并返回 OIDC Provider 的 callback 地址由前端跳转。
+// 浏览器跳转的 GET 请求不会携带 token, 重定向到 kubegems 登录页面, 登录后由页面 POST 完成授权。
+func (m *OIDCProvider) Login(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("authRequestID")
+	if id == "" {
+		response.BadRequest(w, "authRequestID not provide")
+		return
+	}
+	if r.Method == http.MethodGet {
+		http.Redirect(w, r, loginPageURL(m.options.LoginURL, m.options.returnURL(), id), http.StatusFound)
+		return
+	}
+	u, exist := m.users.GetUser(r)
+	if !exist {
+		response.Raw(w, http.StatusUnauthorized, response.Response{Message: "please login first"}, nil)
+		return
+	}
+	if err := m.storage.CompleteAuthRequest(r.Context(), id, u.GetUsername()); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	response.OK(w, map[string]string{"redirect": op.AuthCallbackURL(m.provider)(id)})
+}
+
+// ServeProvider 处理 authorize, token, userinfo, revoke 等 OIDC Provider 标准端点
+func (m *OIDCProvider) ServeProvider(w http.ResponseWriter, r *http.Request) {
+	http.StripPrefix(ProviderPathPrefix, m.provider.HttpHandler()).ServeHTTP(w, r)
+}
+
 func (m *OIDCProvider) RegisterRoute(g *api.Group) {
 	g.AddRoutes(
 		api.GET(JWKSPath).To(m.JWKS),
 		api.GET(DiscoveryEndpoint).To(m.Discovery),
+		api.GET(ProviderPathPrefix+LoginPath).To(m.Login),
+		api.POST(ProviderPathPrefix+LoginPath).To(m.Login),
+		api.GET(ProviderPathPrefix+"/{path}*").To(m.ServeProvider),
+		api.POST(ProviderPathPrefix+"/{path}*").To(m.ServeProvider),
+		api.OPTIONS(ProviderPathPrefix+"/{path}*").To(m.ServeProvider),
 	)
 }
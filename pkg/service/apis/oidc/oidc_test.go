@@ -0,0 +1,298 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/zitadel/oidc/pkg/op"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/aaa/auth/user"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils"
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+)
+
+const (
+	testIssuer      = "http://kubegems.local"
+	testRedirectURI = "https://grafana.local/login/generic_oauth"
+	testToken       = "kubegems-token"
+)
+
+type testUserGetter struct {
+	user *models.User
+}
+
+func (g testUserGetter) GetUser(req *http.Request) (user.CommonUserIface, bool) {
+	if req.Header.Get("Authorization") != "Bearer "+testToken {
+		return nil, false
+	}
+	return g.user, true
+}
+
+func writeTestCert(t *testing.T) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certfile, keyfile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certfile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyfile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certfile, keyfile
+}
+
+func setupProvider(t *testing.T) *OIDCProvider {
+	// 测试结束后恢复 AllowInsecureIssuer 设置的环境变量
+	t.Setenv(op.OidcDevMode, "")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{}, &models.OIDCClient{}, &models.OIDCAuthRequest{}, &models.OIDCToken{}, &models.OIDCRefreshToken{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := utils.MakePassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &models.OIDCClient{
+		ClientID:        "grafana",
+		ClientSecret:    secret,
+		Name:            "grafana",
+		ApplicationType: models.OIDCApplicationTypeWeb,
+		RedirectURIs:    gormdatatypes.JSONSlice{testRedirectURI},
+		AccessTokenType: models.OIDCAccessTokenTypeBearer,
+		Enabled:         true,
+	}
+	u := &models.User{Username: "admin", Email: "admin@kubegems.io"}
+	if err := db.Create(client).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	certfile, keyfile := writeTestCert(t)
+	m, err := NewProvider(context.Background(), db, testUserGetter{user: u}, &OIDCOptions{
+		Issuer:              testIssuer,
+		CertFile:            certfile,
+		KeyFile:             keyfile,
+		LoginURL:            "/login",
+		AllowInsecureIssuer: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestOIDCOptions_validateLoginURL(t *testing.T) {
+	tests := []struct {
+		loginURL string
+		wantErr  bool
+	}{
+		{loginURL: "/login"},
+		{loginURL: testIssuer + "/login"},
+		{loginURL: "login", wantErr: true},
+		{loginURL: "//evil.example.com/login", wantErr: true},
+		{loginURL: "https://evil.example.com/login", wantErr: true},
+		{loginURL: "https://kubegems.local/login", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.loginURL, func(t *testing.T) {
+			o := &OIDCOptions{Issuer: testIssuer, LoginURL: tt.loginURL}
+			if err := o.validateLoginURL(); (err != nil) != tt.wantErr {
+				t.Errorf("validateLoginURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func serve(handler http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// authorize 发起授权请求, 返回授权请求 ID
+func authorize(t *testing.T, m *OIDCProvider) string {
+	query := url.Values{
+		"client_id":     {"grafana"},
+		"redirect_uri":  {testRedirectURI},
+		"response_type": {"code"},
+		"scope":         {"openid profile email"},
+		"state":         {"state"},
+	}
+	rec := serve(m.ServeProvider, httptest.NewRequest(http.MethodGet, ProviderPathPrefix+"/authorize?"+query.Encode(), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("authorize status = %d, body: %s", rec.Code, rec.Body.String())
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Path != LoginPath {
+		t.Fatalf("authorize should redirect to login page, got %s", location)
+	}
+	if redirect := location.Query().Get("redirect"); redirect != testIssuer+ProviderPathPrefix+LoginPath {
+		t.Errorf("login page redirect = %s, want %s", redirect, testIssuer+ProviderPathPrefix+LoginPath)
+	}
+	id := location.Query().Get("authRequestID")
+	if id == "" {
+		t.Fatalf("authorize redirect without authRequestID: %s", location)
+	}
+	return id
+}
+
+func TestOIDCProvider_Login(t *testing.T) {
+	m := setupProvider(t)
+	id := authorize(t, m)
+
+	// 浏览器跳转不携带 token, 重定向到登录页面
+	rec := serve(m.Login, httptest.NewRequest(http.MethodGet, ProviderPathPrefix+LoginPath+"?authRequestID="+id, nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("GET login status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	if location := rec.Header().Get("Location"); !strings.HasPrefix(location, LoginPath+"?") || !strings.Contains(location, "authRequestID="+id) {
+		t.Errorf("GET login should redirect to login page, got %s", location)
+	}
+
+	isDone := func() bool {
+		req := &models.OIDCAuthRequest{}
+		if err := m.storage.LocalAuthStorage.DB.First(req, "id = ?", id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return req.Done
+	}
+	serve(m.Login, httptest.NewRequest(http.MethodPost, ProviderPathPrefix+LoginPath+"?authRequestID="+id, nil))
+	if isDone() {
+		t.Fatal("auth request should not be completed without login")
+	}
+	req := httptest.NewRequest(http.MethodPost, ProviderPathPrefix+LoginPath+"?authRequestID="+id, nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	serve(m.Login, req)
+	if !isDone() {
+		t.Fatal("auth request should be completed after login")
+	}
+}
+
+func TestOIDCProvider_CodeFlow(t *testing.T) {
+	m := setupProvider(t)
+	id := authorize(t, m)
+	if err := m.storage.CompleteAuthRequest(context.Background(), id, "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// callback 签发授权码并重定向回客户端
+	callback, err := url.Parse(op.AuthCallbackURL(m.provider)(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(m.ServeProvider, httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("callback status = %d, body: %s", rec.Code, rec.Body.String())
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), testRedirectURI) || location.Query().Get("state") != "state" {
+		t.Fatalf("callback redirect = %s", location)
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		t.Fatalf("callback redirect without code: %s", location)
+	}
+
+	// token
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {testRedirectURI},
+	}
+	req := httptest.NewRequest(http.MethodPost, ProviderPathPrefix+"/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("grafana", "wrong")
+	if rec := serve(m.ServeProvider, req); rec.Code == http.StatusOK {
+		t.Fatal("token request with invalid client secret should fail")
+	}
+	req = httptest.NewRequest(http.MethodPost, ProviderPathPrefix+"/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("grafana", "secret")
+	rec = serve(m.ServeProvider, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("token status = %d, body: %s", rec.Code, rec.Body.String())
+	}
+	tokens := struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil {
+		t.Fatal(err)
+	}
+	if tokens.AccessToken == "" || tokens.IDToken == "" {
+		t.Fatalf("token response missing tokens: %s", rec.Body.String())
+	}
+
+	// userinfo
+	req = httptest.NewRequest(http.MethodGet, ProviderPathPrefix+"/userinfo", nil)
+	if rec := serve(m.ServeProvider, req); rec.Code == http.StatusOK {
+		t.Fatal("userinfo request without access token should fail")
+	}
+	req = httptest.NewRequest(http.MethodGet, ProviderPathPrefix+"/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	rec = serve(m.ServeProvider, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("userinfo status = %d, body: %s", rec.Code, rec.Body.String())
+	}
+	userinfo := map[string]interface{}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &userinfo); err != nil {
+		t.Fatal(err)
+	}
+	if userinfo["sub"] != "admin" || userinfo["email"] != "admin@kubegems.io" || userinfo["preferred_username"] != "admin" {
+		t.Errorf("userinfo = %v", userinfo)
+	}
+}
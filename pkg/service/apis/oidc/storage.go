@@ -17,11 +17,32 @@ package oidc
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/zitadel/oidc/pkg/oidc"
 	"github.com/zitadel/oidc/pkg/op"
 	"gopkg.in/square/go-jose.v2"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils"
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+)
+
+const (
+	// ScopeGroups 请求该 scope 时, 在 id_token 和 userinfo 中返回用户的租户/项目/环境角色
+	ScopeGroups = "groups"
+	ClaimGroups = "groups"
+
+	DefaultAccessTokenExpire  = time.Hour
+	DefaultRefreshTokenExpire = 7 * 24 * time.Hour
+	DefaultIDTokenExpire      = time.Hour
+	// 未完成登录的授权请求的有效期
+	AuthRequestExpire = 10 * time.Minute
 )
 
 type LocalStorage struct {
@@ -33,15 +54,57 @@ type OIDCOptions struct {
 	Issuer   string
 	CertFile string
 	KeyFile  string
+	// LoginURL 用户登录页面, 授权请求会携带 authRequestID 和 redirect 参数重定向到该地址,
+	// 登录后由页面携带 token POST 到 redirect 完成授权
+	LoginURL string
+	// AllowInsecureIssuer 允许 http 的 issuer 和客户端回调地址, 仅用于开发测试
+	AllowInsecureIssuer bool
+	AccessTokenExpire   time.Duration
+	RefreshTokenExpire  time.Duration
+}
+
+// returnURL 登录页面完成授权请求的地址
+func (o *OIDCOptions) returnURL() string {
+	return strings.TrimSuffix(o.Issuer, "/") + ProviderPathPrefix + LoginPath
 }
 
-func NewLocalStorage(ctx context.Context, options *OIDCOptions) (*LocalStorage, error) {
+// validateLoginURL 登录页面只能是路径或者与 issuer 同源的地址, 避免授权请求被重定向到其它站点
+func (o *OIDCOptions) validateLoginURL() error {
+	login, err := url.Parse(o.LoginURL)
+	if err != nil {
+		return fmt.Errorf("invalid oidc login url %s: %w", o.LoginURL, err)
+	}
+	if !login.IsAbs() {
+		if login.Host != "" || !strings.HasPrefix(login.Path, "/") {
+			return fmt.Errorf("oidc login url %s must be an absolute path or an url on the same origin as issuer", o.LoginURL)
+		}
+		return nil
+	}
+	issuer, err := url.Parse(o.Issuer)
+	if err != nil {
+		return fmt.Errorf("invalid oidc issuer %s: %w", o.Issuer, err)
+	}
+	if login.Scheme != issuer.Scheme || login.Host != issuer.Host {
+		return fmt.Errorf("oidc login url %s must be on the same origin as issuer %s", o.LoginURL, o.Issuer)
+	}
+	return nil
+}
+
+func NewLocalStorage(ctx context.Context, db *gorm.DB, options *OIDCOptions) (*LocalStorage, error) {
 	tlscert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
 	if err != nil {
 		return nil, err
 	}
+	if options.AccessTokenExpire == 0 {
+		options.AccessTokenExpire = DefaultAccessTokenExpire
+	}
+	if options.RefreshTokenExpire == 0 {
+		options.RefreshTokenExpire = DefaultRefreshTokenExpire
+	}
 	auth := LocalAuthStorage{
-		Certs: tlscert,
+		DB:      db,
+		Options: options,
+		Certs:   tlscert,
 		jwks: &jose.JSONWebKeySet{
 			Keys: []jose.JSONWebKey{
 				(&jose.JSONWebKey{
@@ -57,74 +120,212 @@ func NewLocalStorage(ctx context.Context, options *OIDCOptions) (*LocalStorage,
 			Key:       tlscert.PrivateKey,
 		},
 	}
-	return &LocalStorage{LocalAuthStorage: auth}, nil
+	return &LocalStorage{LocalAuthStorage: auth, LocalOPStorage: LocalOPStorage{DB: db, Options: options}}, nil
+}
+
+func (s *LocalStorage) Health(ctx context.Context) error {
+	sqldb, err := s.LocalAuthStorage.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqldb.PingContext(ctx)
 }
 
-func (s *LocalStorage) Health(context.Context) error {
+// CompleteAuthRequest 标记授权请求已由 subject 完成登录
+func (s *LocalStorage) CompleteAuthRequest(ctx context.Context, id string, subject string) error {
+	now := time.Now()
+	result := s.LocalAuthStorage.DB.WithContext(ctx).Model(&models.OIDCAuthRequest{}).
+		Where("id = ? and created_at > ?", id, now.Add(-AuthRequestExpire)).
+		Updates(map[string]interface{}{
+			"subject":   subject,
+			"auth_time": now,
+			"amr":       gormdatatypes.JSONSlice{"pwd"},
+			"done":      true,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("auth request %s not found or expired", id)
+	}
 	return nil
 }
 
-type LocalOPStorage struct{}
+type LocalOPStorage struct {
+	DB      *gorm.DB
+	Options *OIDCOptions
+}
 
 func (s LocalOPStorage) GetClientByClientID(ctx context.Context, clientID string) (op.Client, error) {
-	panic("not implemented") // TODO: Implement
+	client := &models.OIDCClient{}
+	if err := s.DB.WithContext(ctx).First(client, "client_id = ? and enabled = ?", clientID, true).Error; err != nil {
+		return nil, err
+	}
+	return &Client{
+		OIDCClient: client,
+		loginURL:   s.Options.LoginURL,
+		returnURL:  s.Options.returnURL(),
+		devMode:    s.Options.AllowInsecureIssuer,
+	}, nil
 }
 
 func (s LocalOPStorage) AuthorizeClientIDSecret(ctx context.Context, clientID string, clientSecret string) error {
-	panic("not implemented") // TODO: Implement
+	client := &models.OIDCClient{}
+	if err := s.DB.WithContext(ctx).First(client, "client_id = ? and enabled = ?", clientID, true).Error; err != nil {
+		return err
+	}
+	if err := utils.ValidatePassword(clientSecret, client.ClientSecret); err != nil {
+		return fmt.Errorf("invalid client secret")
+	}
+	return nil
 }
 
 func (s LocalOPStorage) SetUserinfoFromScopes(ctx context.Context, userinfo oidc.UserInfoSetter, userID string, clientID string, scopes []string) error {
-	panic("not implemented") // TODO: Implement
+	return s.setUserinfo(ctx, userinfo, userID, scopes)
 }
 
 func (s LocalOPStorage) SetUserinfoFromToken(ctx context.Context, userinfo oidc.UserInfoSetter, tokenID string, subject string, origin string) error {
-	panic("not implemented") // TODO: Implement
+	token := &models.OIDCToken{}
+	if err := s.DB.WithContext(ctx).First(token, "id = ?", tokenID).Error; err != nil {
+		return fmt.Errorf("token is invalid or has expired")
+	}
+	if token.Expiration.Before(time.Now()) {
+		return fmt.Errorf("token is invalid or has expired")
+	}
+	return s.setUserinfo(ctx, userinfo, token.Subject, token.Scopes)
 }
 
 func (s LocalOPStorage) SetIntrospectionFromToken(
-	ctx context.Context, userinfo oidc.IntrospectionResponse, tokenID string, subject string, clientID string,
+	ctx context.Context, introspection oidc.IntrospectionResponse, tokenID string, subject string, clientID string,
 ) error {
-	panic("not implemented") // TODO: Implement
+	token := &models.OIDCToken{}
+	if err := s.DB.WithContext(ctx).First(token, "id = ?", tokenID).Error; err != nil {
+		return fmt.Errorf("token is invalid or has expired")
+	}
+	for _, aud := range token.Audience {
+		if aud != clientID {
+			continue
+		}
+		if err := s.setUserinfo(ctx, introspection, subject, token.Scopes); err != nil {
+			return err
+		}
+		introspection.SetScopes(token.Scopes)
+		introspection.SetClientID(token.ClientID)
+		return nil
+	}
+	return fmt.Errorf("token is not valid for this client")
 }
 
 func (s LocalOPStorage) GetPrivateClaimsFromScopes(ctx context.Context, userID string, clientID string, scopes []string) (map[string]interface{}, error) {
-	panic("not implemented") // TODO: Implement
+	for _, scope := range scopes {
+		if scope != ScopeGroups {
+			continue
+		}
+		groups, err := UserGroups(ctx, s.DB, userID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{ClaimGroups: groups}, nil
+	}
+	return nil, nil
 }
 
 func (s LocalOPStorage) GetKeyByIDAndUserID(ctx context.Context, keyID string, userID string) (*jose.JSONWebKey, error) {
-	panic("not implemented") // TODO: Implement
+	return nil, errors.New("jwt profile grant not supported")
 }
 
 func (s LocalOPStorage) ValidateJWTProfileScopes(ctx context.Context, userID string, scopes []string) ([]string, error) {
-	panic("not implemented") // TODO: Implement
+	return nil, errors.New("jwt profile grant not supported")
+}
+
+func (s LocalOPStorage) setUserinfo(ctx context.Context, userinfo oidc.UserInfoSetter, subject string, scopes []string) error {
+	user := &models.User{}
+	if err := s.DB.WithContext(ctx).First(user, "username = ?", subject).Error; err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if user.IsActive != nil && !*user.IsActive {
+		return fmt.Errorf("user %s is not active", subject)
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case oidc.ScopeOpenID:
+			userinfo.SetSubject(user.Username)
+		case oidc.ScopeEmail:
+			userinfo.SetEmail(user.Email, false)
+		case oidc.ScopeProfile:
+			userinfo.SetPreferredUsername(user.Username)
+			userinfo.SetName(user.Username)
+		case oidc.ScopePhone:
+			userinfo.SetPhone(user.Phone, false)
+		case ScopeGroups:
+			groups, err := UserGroups(ctx, s.DB, user.Username)
+			if err != nil {
+				return err
+			}
+			userinfo.AppendClaims(ClaimGroups, groups)
+		}
+	}
+	return nil
 }
 
 type LocalAuthStorage struct {
-	Certs tls.Certificate
+	DB      *gorm.DB
+	Options *OIDCOptions
+	Certs   tls.Certificate
 
 	signkey jose.SigningKey
 	jwks    *jose.JSONWebKeySet
 }
 
-func (s LocalAuthStorage) CreateAuthRequest(_ context.Context, _ *oidc.AuthRequest, _ string) (op.AuthRequest, error) {
-	panic("not implemented") // TODO: Implement
+func (s LocalAuthStorage) CreateAuthRequest(ctx context.Context, authReq *oidc.AuthRequest, userID string) (op.AuthRequest, error) {
+	now := time.Now()
+	req := &models.OIDCAuthRequest{
+		ID:                  uuid.NewString(),
+		ClientID:            authReq.ClientID,
+		RedirectURI:         authReq.RedirectURI,
+		State:               authReq.State,
+		Nonce:               authReq.Nonce,
+		ResponseType:        string(authReq.ResponseType),
+		ResponseMode:        string(authReq.ResponseMode),
+		Scopes:              gormdatatypes.JSONSlice(authReq.Scopes),
+		CodeChallenge:       authReq.CodeChallenge,
+		CodeChallengeMethod: string(authReq.CodeChallengeMethod),
+		Subject:             userID,
+		CreatedAt:           &now,
+	}
+	if err := s.DB.WithContext(ctx).Create(req).Error; err != nil {
+		return nil, err
+	}
+	return &AuthRequest{req}, nil
 }
 
-func (s LocalAuthStorage) AuthRequestByID(_ context.Context, _ string) (op.AuthRequest, error) {
-	panic("not implemented") // TODO: Implement
+func (s LocalAuthStorage) AuthRequestByID(ctx context.Context, id string) (op.AuthRequest, error) {
+	req := &models.OIDCAuthRequest{}
+	if err := s.DB.WithContext(ctx).First(req, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("auth request not found")
+	}
+	return &AuthRequest{req}, nil
 }
 
-func (s LocalAuthStorage) AuthRequestByCode(_ context.Context, _ string) (op.AuthRequest, error) {
-	panic("not implemented") // TODO: Implement
+func (s LocalAuthStorage) AuthRequestByCode(ctx context.Context, code string) (op.AuthRequest, error) {
+	if code == "" {
+		return nil, fmt.Errorf("code invalid or expired")
+	}
+	req := &models.OIDCAuthRequest{}
+	if err := s.DB.WithContext(ctx).First(req, "code = ?", code).Error; err != nil {
+		return nil, fmt.Errorf("code invalid or expired")
+	}
+	return &AuthRequest{req}, nil
 }
 
-func (s LocalAuthStorage) SaveAuthCode(_ context.Context, _ string, _ string) error {
-	panic("not implemented") // TODO: Implement
+func (s LocalAuthStorage) SaveAuthCode(ctx context.Context, id string, code string) error {
+	return s.DB.WithContext(ctx).Model(&models.OIDCAuthRequest{}).Where("id = ?", id).Update("code", code).Error
 }
 
-func (s LocalAuthStorage) DeleteAuthRequest(_ context.Context, _ string) error {
-	panic("not implemented") // TODO: Implement
+func (s LocalAuthStorage) DeleteAuthRequest(ctx context.Context, id string) error {
+	// 顺便清理过期未完成的授权请求
+	s.DB.WithContext(ctx).Delete(&models.OIDCAuthRequest{}, "created_at < ?", time.Now().Add(-AuthRequestExpire))
+	return s.DB.WithContext(ctx).Delete(&models.OIDCAuthRequest{}, "id = ?", id).Error
 }
 
 // The TokenRequest parameter of CreateAccessToken can be any of:
@@ -133,10 +334,15 @@ func (s LocalAuthStorage) DeleteAuthRequest(_ context.Context, _ string) error {
 //
 // * AuthRequest as returned by AuthRequestByID or AuthRequestByCode (above)
 //
-// * *oidc.JWTTokenRequest from a JWT that is the assertion value of a JWT Profile
-//   Grant: https://datatracker.ietf.org/doc/html/rfc7523#section-2.1
-func (s LocalAuthStorage) CreateAccessToken(_ context.Context, _ op.TokenRequest) (accessTokenID string, expiration time.Time, err error) {
-	panic("not implemented") // TODO: Implement
+//   - *oidc.JWTTokenRequest from a JWT that is the assertion value of a JWT Profile
+//     Grant: https://datatracker.ietf.org/doc/html/rfc7523#section-2.1
+func (s LocalAuthStorage) CreateAccessToken(ctx context.Context, request op.TokenRequest) (accessTokenID string, expiration time.Time, err error) {
+	clientID, _, _ := infoFromRequest(request)
+	token, err := s.createAccessToken(s.DB.WithContext(ctx), clientID, "", request)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token.ID, token.Expiration, nil
 }
 
 // The TokenRequest parameter of CreateAccessAndRefreshTokens can be any of:
@@ -145,24 +351,106 @@ func (s LocalAuthStorage) CreateAccessToken(_ context.Context, _ op.TokenRequest
 //
 // * RefreshTokenRequest as returned by AuthStorage.TokenRequestByRefreshToken
 //
-// * AuthRequest as by returned by the AuthRequestByID or AuthRequestByCode (above).
-//   Used for the authorization code flow which requested offline_access scope and
-//   registered the refresh_token grant type in advance
+//   - AuthRequest as by returned by the AuthRequestByID or AuthRequestByCode (above).
+//     Used for the authorization code flow which requested offline_access scope and
+//     registered the refresh_token grant type in advance
 func (s LocalAuthStorage) CreateAccessAndRefreshTokens(
 	ctx context.Context, request op.TokenRequest, currentRefreshToken string,
 ) (accessTokenID string, newRefreshTokenID string, expiration time.Time, err error) {
-	panic("not implemented") // TODO: Implement
+	clientID, authTime, amr := infoFromRequest(request)
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		refresh := &models.OIDCRefreshToken{}
+		if currentRefreshToken == "" {
+			// authorization code flow
+			now := time.Now()
+			refresh = &models.OIDCRefreshToken{
+				ID:         uuid.NewString(),
+				Token:      uuid.NewString(),
+				ClientID:   clientID,
+				Subject:    request.GetSubject(),
+				Audience:   request.GetAudience(),
+				Scopes:     request.GetScopes(),
+				AMR:        amr,
+				AuthTime:   authTime,
+				Expiration: now.Add(s.Options.RefreshTokenExpire),
+				CreatedAt:  &now,
+			}
+			if err := tx.Create(refresh).Error; err != nil {
+				return err
+			}
+		} else {
+			// refresh token flow, rotate the refresh token and revoke access tokens issued by the old one
+			if err := tx.First(refresh, "token = ?", currentRefreshToken).Error; err != nil {
+				return fmt.Errorf("invalid refresh token")
+			}
+			if err := tx.Delete(&models.OIDCToken{}, "refresh_token_id = ?", refresh.ID).Error; err != nil {
+				return err
+			}
+			refresh.Token = uuid.NewString()
+			refresh.Scopes = request.GetScopes()
+			refresh.Expiration = time.Now().Add(s.Options.RefreshTokenExpire)
+			if err := tx.Save(refresh).Error; err != nil {
+				return err
+			}
+		}
+		token, err := s.createAccessToken(tx, clientID, refresh.ID, request)
+		if err != nil {
+			return err
+		}
+		accessTokenID, newRefreshTokenID, expiration = token.ID, refresh.Token, token.Expiration
+		return nil
+	})
+	return accessTokenID, newRefreshTokenID, expiration, err
 }
 
 func (s LocalAuthStorage) TokenRequestByRefreshToken(ctx context.Context, refreshTokenID string) (op.RefreshTokenRequest, error) {
-	panic("not implemented") // TODO: Implement
+	refresh := &models.OIDCRefreshToken{}
+	if err := s.DB.WithContext(ctx).First(refresh, "token = ?", refreshTokenID).Error; err != nil {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+	if refresh.Expiration.Before(time.Now()) {
+		return nil, fmt.Errorf("refresh token expired")
+	}
+	return &RefreshTokenRequest{refresh}, nil
 }
 
 func (s LocalAuthStorage) TerminateSession(ctx context.Context, userID string, clientID string) error {
-	return nil
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.OIDCToken{}, "subject = ? and client_id = ?", userID, clientID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.OIDCRefreshToken{}, "subject = ? and client_id = ?", userID, clientID).Error
+	})
 }
 
 func (s LocalAuthStorage) RevokeToken(ctx context.Context, tokenID string, userID string, clientID string) *oidc.Error {
+	db := s.DB.WithContext(ctx)
+	token := &models.OIDCToken{}
+	if err := db.First(token, "id = ?", tokenID).Error; err == nil {
+		if token.ClientID != clientID {
+			return oidc.ErrInvalidClient().WithDescription("token was not issued for this client")
+		}
+		if err := db.Delete(token).Error; err != nil {
+			return oidc.ErrServerError().WithParent(err)
+		}
+		return nil
+	}
+	refresh := &models.OIDCRefreshToken{}
+	if err := db.First(refresh, "token = ?", tokenID).Error; err != nil {
+		// neither an access token nor a refresh token, it's already not valid
+		return nil
+	}
+	if refresh.ClientID != clientID {
+		return oidc.ErrInvalidClient().WithDescription("token was not issued for this client")
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.OIDCToken{}, "refresh_token_id = ?", refresh.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(refresh).Error
+	}); err != nil {
+		return oidc.ErrServerError().WithParent(err)
+	}
 	return nil
 }
 
@@ -173,3 +461,30 @@ func (s LocalAuthStorage) GetSigningKey(ctx context.Context, signkey chan<- jose
 func (s LocalAuthStorage) GetKeySet(ctx context.Context) (*jose.JSONWebKeySet, error) {
 	return s.jwks, nil
 }
+
+func (s LocalAuthStorage) createAccessToken(tx *gorm.DB, clientID, refreshTokenID string, request op.TokenRequest) (*models.OIDCToken, error) {
+	now := time.Now()
+	token := &models.OIDCToken{
+		ID:             uuid.NewString(),
+		ClientID:       clientID,
+		Subject:        request.GetSubject(),
+		RefreshTokenID: refreshTokenID,
+		Audience:       request.GetAudience(),
+		Scopes:         request.GetScopes(),
+		Expiration:     now.Add(s.Options.AccessTokenExpire),
+		CreatedAt:      &now,
+	}
+	// 顺便清理过期的 token
+	tx.Delete(&models.OIDCToken{}, "expiration < ?", now)
+	return token, tx.Create(token).Error
+}
+
+func infoFromRequest(req op.TokenRequest) (clientID string, authTime time.Time, amr []string) {
+	switch r := req.(type) {
+	case *AuthRequest:
+		return r.GetClientID(), r.GetAuthTime(), r.GetAMR()
+	case *RefreshTokenRequest:
+		return r.GetClientID(), r.GetAuthTime(), r.GetAMR()
+	}
+	return "", time.Time{}, nil
+}
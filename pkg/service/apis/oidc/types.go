@@ -0,0 +1,296 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/zitadel/oidc/pkg/oidc"
+	"github.com/zitadel/oidc/pkg/op"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+// Client implements op.Client
+type Client struct {
+	*models.OIDCClient
+	loginURL  string
+	returnURL string
+	devMode   bool
+}
+
+func (c *Client) GetID() string {
+	return c.ClientID
+}
+
+func (c *Client) RedirectURIs() []string {
+	return c.OIDCClient.RedirectURIs
+}
+
+func (c *Client) PostLogoutRedirectURIs() []string {
+	return c.OIDCClient.PostLogoutRedirectURIs
+}
+
+func (c *Client) ApplicationType() op.ApplicationType {
+	switch c.OIDCClient.ApplicationType {
+	case models.OIDCApplicationTypeNative:
+		return op.ApplicationTypeNative
+	case models.OIDCApplicationTypeUserAgent:
+		return op.ApplicationTypeUserAgent
+	default:
+		return op.ApplicationTypeWeb
+	}
+}
+
+// AuthMethod 只有 web 类型的客户端持有密钥, 其它类型的公开客户端需要使用 PKCE
+func (c *Client) AuthMethod() oidc.AuthMethod {
+	if c.ApplicationType() == op.ApplicationTypeWeb {
+		return oidc.AuthMethodBasic
+	}
+	return oidc.AuthMethodNone
+}
+
+func (c *Client) ResponseTypes() []oidc.ResponseType {
+	return []oidc.ResponseType{oidc.ResponseTypeCode}
+}
+
+func (c *Client) GrantTypes() []oidc.GrantType {
+	if len(c.OIDCClient.GrantTypes) == 0 {
+		return []oidc.GrantType{oidc.GrantTypeCode, oidc.GrantTypeRefreshToken}
+	}
+	ret := make([]oidc.GrantType, 0, len(c.OIDCClient.GrantTypes))
+	for _, gt := range c.OIDCClient.GrantTypes {
+		ret = append(ret, oidc.GrantType(gt))
+	}
+	return ret
+}
+
+func (c *Client) LoginURL(id string) string {
+	return loginPageURL(c.loginURL, c.returnURL, id)
+}
+
+// loginPageURL 登录页面地址, 携带 authRequestID 以及登录后完成授权的地址 redirect
+func loginPageURL(loginURL, returnURL, id string) string {
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		return loginURL + "?authRequestID=" + url.QueryEscape(id) + "&redirect=" + url.QueryEscape(returnURL)
+	}
+	q := u.Query()
+	q.Set("authRequestID", id)
+	q.Set("redirect", returnURL)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (c *Client) AccessTokenType() op.AccessTokenType {
+	if c.OIDCClient.AccessTokenType == models.OIDCAccessTokenTypeJWT {
+		return op.AccessTokenTypeJWT
+	}
+	return op.AccessTokenTypeBearer
+}
+
+func (c *Client) IDTokenLifetime() time.Duration {
+	return DefaultIDTokenExpire
+}
+
+// DevMode 允许 http 的回调地址
+func (c *Client) DevMode() bool {
+	return c.devMode
+}
+
+func (c *Client) RestrictAdditionalIdTokenScopes() func(scopes []string) []string {
+	return func(scopes []string) []string { return scopes }
+}
+
+func (c *Client) RestrictAdditionalAccessTokenScopes() func(scopes []string) []string {
+	return func(scopes []string) []string { return scopes }
+}
+
+func (c *Client) IsScopeAllowed(scope string) bool {
+	return scope == ScopeGroups
+}
+
+// IDTokenUserinfoClaimsAssertion 将 userinfo 中的 claims 写入 id_token, 方便 grafana 等只解析 id_token 的客户端
+func (c *Client) IDTokenUserinfoClaimsAssertion() bool {
+	return true
+}
+
+func (c *Client) ClockSkew() time.Duration {
+	return 0
+}
+
+// AuthRequest implements op.AuthRequest
+type AuthRequest struct {
+	*models.OIDCAuthRequest
+}
+
+func (a *AuthRequest) GetID() string {
+	return a.ID
+}
+
+func (a *AuthRequest) GetACR() string {
+	return ""
+}
+
+func (a *AuthRequest) GetAMR() []string {
+	return a.AMR
+}
+
+func (a *AuthRequest) GetAudience() []string {
+	return []string{a.ClientID}
+}
+
+func (a *AuthRequest) GetAuthTime() time.Time {
+	if a.AuthTime == nil {
+		return time.Time{}
+	}
+	return *a.AuthTime
+}
+
+func (a *AuthRequest) GetClientID() string {
+	return a.ClientID
+}
+
+func (a *AuthRequest) GetCodeChallenge() *oidc.CodeChallenge {
+	if a.CodeChallenge == "" {
+		return nil
+	}
+	method := oidc.CodeChallengeMethodPlain
+	if a.CodeChallengeMethod == string(oidc.CodeChallengeMethodS256) {
+		method = oidc.CodeChallengeMethodS256
+	}
+	return &oidc.CodeChallenge{Challenge: a.CodeChallenge, Method: method}
+}
+
+func (a *AuthRequest) GetNonce() string {
+	return a.Nonce
+}
+
+func (a *AuthRequest) GetRedirectURI() string {
+	return a.RedirectURI
+}
+
+func (a *AuthRequest) GetResponseType() oidc.ResponseType {
+	return oidc.ResponseType(a.ResponseType)
+}
+
+func (a *AuthRequest) GetResponseMode() oidc.ResponseMode {
+	return oidc.ResponseMode(a.ResponseMode)
+}
+
+func (a *AuthRequest) GetScopes() []string {
+	return a.Scopes
+}
+
+func (a *AuthRequest) GetState() string {
+	return a.State
+}
+
+func (a *AuthRequest) GetSubject() string {
+	return a.Subject
+}
+
+func (a *AuthRequest) Done() bool {
+	return a.OIDCAuthRequest.Done
+}
+
+// RefreshTokenRequest implements op.RefreshTokenRequest
+type RefreshTokenRequest struct {
+	*models.OIDCRefreshToken
+}
+
+func (r *RefreshTokenRequest) GetAMR() []string {
+	return r.AMR
+}
+
+func (r *RefreshTokenRequest) GetAudience() []string {
+	return r.Audience
+}
+
+func (r *RefreshTokenRequest) GetAuthTime() time.Time {
+	return r.AuthTime
+}
+
+func (r *RefreshTokenRequest) GetClientID() string {
+	return r.ClientID
+}
+
+func (r *RefreshTokenRequest) GetScopes() []string {
+	return r.Scopes
+}
+
+func (r *RefreshTokenRequest) GetSubject() string {
+	return r.Subject
+}
+
+func (r *RefreshTokenRequest) SetCurrentScopes(scopes []string) {
+	r.Scopes = scopes
+}
+
+// UserGroups 返回用户在 kubegems 中的角色, 格式如下:
+//
+//	system:sysadmin
+//	tenant:<tenant>:<role>
+//	project:<tenant>/<project>:<role>
+//	environment:<tenant>/<project>/<environment>:<role>
+func UserGroups(ctx context.Context, db *gorm.DB, username string) ([]string, error) {
+	user := &models.User{}
+	if err := db.WithContext(ctx).Preload("SystemRole").First(user, "username = ?", username).Error; err != nil {
+		return nil, err
+	}
+	groups := []string{}
+	if user.SystemRole != nil {
+		groups = append(groups, "system:"+user.SystemRole.RoleCode)
+	}
+
+	var turs []models.TenantUserRels
+	if err := db.WithContext(ctx).Preload("Tenant").Find(&turs, "user_id = ?", user.ID).Error; err != nil {
+		return nil, err
+	}
+	for _, rel := range turs {
+		if rel.Tenant == nil {
+			continue
+		}
+		groups = append(groups, fmt.Sprintf("tenant:%s:%s", rel.Tenant.TenantName, rel.Role))
+	}
+
+	var purs []models.ProjectUserRels
+	if err := db.WithContext(ctx).Preload("Project.Tenant").Find(&purs, "user_id = ?", user.ID).Error; err != nil {
+		return nil, err
+	}
+	for _, rel := range purs {
+		if rel.Project == nil || rel.Project.Tenant == nil {
+			continue
+		}
+		groups = append(groups, fmt.Sprintf("project:%s/%s:%s", rel.Project.Tenant.TenantName, rel.Project.ProjectName, rel.Role))
+	}
+
+	var eurs []models.EnvironmentUserRels
+	if err := db.WithContext(ctx).Preload("Environment.Project.Tenant").Find(&eurs, "user_id = ?", user.ID).Error; err != nil {
+		return nil, err
+	}
+	for _, rel := range eurs {
+		env := rel.Environment
+		if env == nil || env.Project == nil || env.Project.Tenant == nil {
+			continue
+		}
+		groups = append(groups, fmt.Sprintf("environment:%s/%s/%s:%s",
+			env.Project.Tenant.TenantName, env.Project.ProjectName, env.EnvironmentName, rel.Role))
+	}
+	return groups, nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package approval 通用的多级审批, 审批通过后执行对应类型的操作
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/service/models/cache"
+)
+
+var (
+	ErrApprovalRequired = errors.New("approval required")
+	ErrApprovalDecided  = errors.New("approval has been decided")
+	// ErrApprovalOutdated 存在通过的申请, 但申请的版本不是当前版本
+	ErrApprovalOutdated = errors.New("approved revision is outdated")
+)
+
+// Executor 审批类型对应的操作
+type Executor interface {
+	// Validate 提交申请时校验申请的内容
+	Validate(ctx context.Context, a *models.Approval) error
+	// Execute 审批通过后执行
+	Execute(ctx context.Context, a *models.Approval) error
+}
+
+type Engine struct {
+	DB        *gorm.DB
+	Executors map[string]Executor
+}
+
+// Rule 申请适用的审批规则, 租户的规则优先于全局规则, 没有启用的规则时返回空
+func (e *Engine) Rule(ctx context.Context, kind string, tenantID *uint) (*models.ApprovalRule, error) {
+	rules := []models.ApprovalRule{}
+	query := e.DB.WithContext(ctx).Where("kind = ? and enabled = ?", kind, true)
+	if tenantID != nil {
+		query = query.Where("tenant_id = ? or tenant_id is null", *tenantID)
+	} else {
+		query = query.Where("tenant_id is null")
+	}
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	var ret *models.ApprovalRule
+	for i := range rules {
+		if ret == nil || rules[i].TenantID != nil {
+			ret = &rules[i]
+		}
+	}
+	return ret, nil
+}
+
+// Submit 校验并提交申请
+func (e *Engine) Submit(ctx context.Context, a *models.Approval) error {
+	executor, ok := e.Executors[a.Kind]
+	if !ok {
+		return fmt.Errorf("approval kind %s not supported", a.Kind)
+	}
+	// 版本由 Validate 按申请的对象确定, 不使用提交的值
+	a.Revision = ""
+	if err := executor.Validate(ctx, a); err != nil {
+		return err
+	}
+	rule, err := e.Rule(ctx, a.Kind, a.TenantID)
+	if err != nil {
+		return err
+	}
+	a.ID = 0
+	a.Message = ""
+	a.Comments = nil
+	a.Start(rule, time.Now())
+	return e.DB.WithContext(ctx).Create(a).Error
+}
+
+// CanDecide 用户是否可以审批当前阶段, 申请人不能审批自己的申请
+func CanDecide(auth *cache.UserAuthority, username string, a *models.Approval) bool {
+	stage := a.Stage()
+	if stage == nil || a.Applicant == username {
+		return false
+	}
+	if auth.IsSystemAdmin() {
+		return true
+	}
+	isTenantAdmin := a.TenantID != nil && auth.IsTenantAdmin(*a.TenantID)
+	switch stage.Approver {
+	case models.ApproverTenantAdmin:
+		return isTenantAdmin
+	case models.ApproverProjectAdmin:
+		return isTenantAdmin || (a.ProjectID != nil && auth.IsProjectAdmin(*a.ProjectID))
+	}
+	return false
+}
+
+// Decide 审批当前阶段, 全部阶段通过后执行对应的操作, 执行失败时状态为 failed
+// 仅当申请仍处于当前阶段的 pending 状态时才会更新, 避免并发审批重复执行
+func (e *Engine) Decide(ctx context.Context, a *models.Approval, username string, approve bool, comment string) error {
+	now := time.Now()
+	stage := a.CurrentStage
+	if err := a.Decide(username, approve, now); err != nil {
+		return err
+	}
+	if err := e.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ret := tx.Model(a).Where("status = ? and current_stage = ?", models.ApprovalStatusPending, stage).
+			Select("status", "stages", "current_stage", "message").Updates(a)
+		if ret.Error != nil {
+			return ret.Error
+		}
+		if ret.RowsAffected == 0 {
+			return ErrApprovalDecided
+		}
+		if comment != "" {
+			return tx.Create(&models.ApprovalComment{ApprovalID: a.ID, Username: username, Stage: stage, Content: comment}).Error
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if a.Status != models.ApprovalStatusApproved {
+		return nil
+	}
+	if err := e.Executors[a.Kind].Execute(ctx, a); err != nil {
+		a.Status = models.ApprovalStatusFailed
+		a.Message = err.Error()
+	}
+	return e.DB.WithContext(ctx).Model(a).Select("status", "message", "environment_id").Updates(a).Error
+}
+
+// Expire 将过期的申请设置为 expired
+func (e *Engine) Expire(ctx context.Context, now time.Time) (int64, error) {
+	ret := e.DB.WithContext(ctx).Model(&models.Approval{}).
+		Where("status = ? and expire_at < ?", models.ApprovalStatusPending, now).
+		Update("status", models.ApprovalStatusExpired)
+	return ret.RowsAffected, ret.Error
+}
+
+// Consume 使用一个已经通过的申请执行 fn, 如生产环境的部署, 每个申请只能使用一次
+// 没有启用的规则时直接执行; 需要审批但没有通过的申请时返回 ErrApprovalRequired,
+// 通过的申请的版本不是 revision 时返回 ErrApprovalOutdated
+// 申请在执行前被占用, fn 执行失败时恢复为 approved 以便再次使用
+func (e *Engine) Consume(ctx context.Context, kind string, tenantID *uint, environmentID uint, target, revision string, fn func() error) error {
+	rule, err := e.Rule(ctx, kind, tenantID)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return fn()
+	}
+	approved := []models.Approval{}
+	if err := e.DB.WithContext(ctx).Select("id", "revision").
+		Where("kind = ? and environment_id = ? and target = ? and status = ?", kind, environmentID, target, models.ApprovalStatusApproved).
+		Order("id").Find(&approved).Error; err != nil {
+		return err
+	}
+	ids := []uint{}
+	for _, a := range approved {
+		if a.Revision == revision {
+			ids = append(ids, a.ID)
+		}
+	}
+	if len(ids) == 0 && len(approved) > 0 {
+		return ErrApprovalOutdated
+	}
+	var claimed uint
+	for _, id := range ids {
+		ret := e.DB.WithContext(ctx).Model(&models.Approval{}).
+			Where("id = ? and status = ?", id, models.ApprovalStatusApproved).
+			Update("status", models.ApprovalStatusDone)
+		if ret.Error != nil {
+			return ret.Error
+		}
+		if ret.RowsAffected == 1 {
+			claimed = id
+			break
+		}
+	}
+	if claimed == 0 {
+		return ErrApprovalRequired
+	}
+	if err := fn(); err != nil {
+		if rerr := e.DB.WithContext(ctx).Model(&models.Approval{}).
+			Where("id = ? and status = ?", claimed, models.ApprovalStatusDone).
+			Update("status", models.ApprovalStatusApproved).Error; rerr != nil {
+			return fmt.Errorf("%w, restore approval %d: %v", err, claimed, rerr)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+type countExecutor struct {
+	count int
+}
+
+func (e *countExecutor) Validate(ctx context.Context, a *models.Approval) error { return nil }
+
+func (e *countExecutor) Execute(ctx context.Context, a *models.Approval) error {
+	e.count++
+	return nil
+}
+
+func newTestEngine(t *testing.T) (*Engine, *countExecutor) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.ApprovalRule{}, &models.Approval{}, &models.ApprovalComment{}); err != nil {
+		t.Fatal(err)
+	}
+	executor := &countExecutor{}
+	return &Engine{DB: db, Executors: map[string]Executor{models.ApprovalKindProdDeploy: executor}}, executor
+}
+
+func TestEngine_Decide(t *testing.T) {
+	ctx := context.Background()
+	engine, executor := newTestEngine(t)
+	a := &models.Approval{Kind: models.ApprovalKindProdDeploy, Applicant: "dev", Target: "app"}
+	if err := engine.Submit(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	// 两个审批人基于同一份数据并发审批, 只有一个生效
+	stale := *a
+	stale.Stages = append(models.ApprovalStages{}, a.Stages...)
+	if err := engine.Decide(ctx, a, "admin", true, "lgtm"); err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	if err := engine.Decide(ctx, &stale, "admin2", true, ""); !errors.Is(err, ErrApprovalDecided) {
+		t.Fatalf("Decide() on decided approval error = %v, want %v", err, ErrApprovalDecided)
+	}
+	if executor.count != 1 {
+		t.Errorf("executed %d times, want 1", executor.count)
+	}
+	saved := &models.Approval{}
+	if err := engine.DB.First(saved, a.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != models.ApprovalStatusApproved || saved.Stages[0].Username != "admin" {
+		t.Errorf("saved approval = %s by %s", saved.Status, saved.Stages[0].Username)
+	}
+}
+
+func TestEngine_Consume(t *testing.T) {
+	ctx := context.Background()
+	engine, _ := newTestEngine(t)
+	envID := uint(1)
+	succeed := func() error { return nil }
+
+	// 没有规则时直接执行
+	if err := engine.Consume(ctx, models.ApprovalKindProdDeploy, nil, envID, "app", "rev1", succeed); err != nil {
+		t.Fatalf("Consume() without rule error = %v", err)
+	}
+	rule := &models.ApprovalRule{Kind: models.ApprovalKindProdDeploy, Stages: models.ApprovalDefaultStages, ExpireHours: 1, Enabled: true}
+	if err := engine.DB.Create(rule).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Consume(ctx, models.ApprovalKindProdDeploy, nil, envID, "app", "rev1", succeed); !errors.Is(err, ErrApprovalRequired) {
+		t.Fatalf("Consume() without approval error = %v, want %v", err, ErrApprovalRequired)
+	}
+	now := time.Now()
+	a := &models.Approval{Kind: models.ApprovalKindProdDeploy, EnvironmentID: &envID, Target: "app", Revision: "rev1", Status: models.ApprovalStatusApproved, ExpireAt: &now}
+	if err := engine.DB.Create(a).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 编排有新的提交后, 之前版本的申请不能使用
+	if err := engine.Consume(ctx, models.ApprovalKindProdDeploy, nil, envID, "app", "rev2", succeed); !errors.Is(err, ErrApprovalOutdated) {
+		t.Fatalf("Consume() new revision error = %v, want %v", err, ErrApprovalOutdated)
+	}
+	// 执行失败时审批可以再次使用
+	failed := errors.New("sync failed")
+	if err := engine.Consume(ctx, models.ApprovalKindProdDeploy, nil, envID, "app", "rev1", func() error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("Consume() error = %v, want %v", err, failed)
+	}
+	if err := engine.Consume(ctx, models.ApprovalKindProdDeploy, nil, envID, "app", "rev1", succeed); err != nil {
+		t.Fatalf("Consume() after failure error = %v", err)
+	}
+	if err := engine.Consume(ctx, models.ApprovalKindProdDeploy, nil, envID, "app", "rev1", succeed); !errors.Is(err, ErrApprovalRequired) {
+		t.Fatalf("Consume() used approval error = %v, want %v", err, ErrApprovalRequired)
+	}
+}
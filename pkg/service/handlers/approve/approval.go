@@ -0,0 +1,373 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approveHandler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	msgclient "kubegems.io/kubegems/pkg/msgbus/client"
+	"kubegems.io/kubegems/pkg/service/approval"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/service/models/cache"
+	"kubegems.io/kubegems/pkg/utils/msgbus"
+)
+
+// ApprovalDecision 审批意见
+type ApprovalDecision struct {
+	Comment string `json:"comment"`
+}
+
+// ListApprovals 审批申请列表
+//
+//	@Tags			Approve
+//	@Summary		审批申请列表
+//	@Description	审批申请列表, scope 为 mine 时为自己提交的申请, 为 all 时为所有申请(仅系统管理员)
+//	@Accept			json
+//	@Produce		json
+//	@Param			scope	query		string																	false	"mine, all"
+//	@Param			kind	query		string																	false	"审批类型"
+//	@Param			status	query		string																	false	"审批状态"
+//	@Param			page	query		int																		false	"page"
+//	@Param			size	query		int																		false	"size"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=handlers.PageData{List=[]models.Approval}}	"resp"
+//	@Router			/v1/approvals [get]
+//	@Security		JWT
+func (h *ApproveHandler) ListApprovals(c *gin.Context) {
+	u, _ := h.GetContextUser(c)
+	query, err := handlers.GetQuery(c, nil)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	cond := &handlers.PageQueryCond{
+		Model:        "Approval",
+		SearchFields: []string{"title", "target"},
+		SortFields:   []string{"id", "created_at"},
+	}
+	switch c.DefaultQuery("scope", "mine") {
+	case "all":
+		if !h.ModelCache().GetUserAuthority(u).IsSystemAdmin() {
+			handlers.Forbidden(c, i18n.Errorf(c, "only system admin can list all approvals"))
+			return
+		}
+	case "mine":
+		cond.Where = append(cond.Where, handlers.Args("applicant = ?", u.GetUsername()))
+	default:
+		handlers.NotOK(c, i18n.Errorf(c, "scope %s not valid", c.Query("scope")))
+		return
+	}
+	if kind := c.Query("kind"); kind != "" {
+		cond.Where = append(cond.Where, handlers.Args("kind = ?", kind))
+	}
+	if status := c.Query("status"); status != "" {
+		cond.Where = append(cond.Where, handlers.Args("status = ?", status))
+	}
+	list := []models.Approval{}
+	total, page, size, err := query.PageList(h.GetDB().WithContext(c.Request.Context()).Order("id desc"), cond, &list)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, handlers.Page(total, list, page, size))
+}
+
+// ListTodoApprovals 待我审批的申请
+//
+//	@Tags			Approve
+//	@Summary		待我审批的申请
+//	@Description	待我审批的申请
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	handlers.ResponseStruct{Data=[]models.Approval}	"resp"
+//	@Router			/v1/approvals/todo [get]
+//	@Security		JWT
+func (h *ApproveHandler) ListTodoApprovals(c *gin.Context) {
+	list, err := h.todoApprovals(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, list)
+}
+
+func (h *ApproveHandler) todoApprovals(c *gin.Context) ([]models.Approval, error) {
+	u, _ := h.GetContextUser(c)
+	auth := h.ModelCache().GetUserAuthority(u)
+	pendings := []models.Approval{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Order("id desc").
+		Find(&pendings, "status = ?", models.ApprovalStatusPending).Error; err != nil {
+		return nil, err
+	}
+	ret := []models.Approval{}
+	for _, a := range pendings {
+		if approval.CanDecide(auth, u.GetUsername(), &a) {
+			ret = append(ret, a)
+		}
+	}
+	return ret, nil
+}
+
+// GetApproval 审批申请详情
+//
+//	@Tags			Approve
+//	@Summary		审批申请详情
+//	@Description	审批申请详情, 包含评论
+//	@Accept			json
+//	@Produce		json
+//	@Param			approval_id	path		uint											true	"approval_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.Approval}	"resp"
+//	@Router			/v1/approvals/{approval_id} [get]
+//	@Security		JWT
+func (h *ApproveHandler) GetApproval(c *gin.Context) {
+	a, ok := h.getApproval(c)
+	if !ok {
+		return
+	}
+	handlers.OK(c, a)
+}
+
+// PostApproval 提交审批申请
+//
+//	@Tags			Approve
+//	@Summary		提交审批申请
+//	@Description	提交审批申请, 按审批规则逐级审批, 全部通过后执行申请的操作
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		models.Approval									true	"申请"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=models.Approval}	"resp"
+//	@Router			/v1/approvals [post]
+//	@Security		JWT
+func (h *ApproveHandler) PostApproval(c *gin.Context) {
+	req := &models.Approval{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.fillApprovalScope(ctx, req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	u, _ := h.GetContextUser(c)
+	if !canApply(h.ModelCache().GetUserAuthority(u), req) {
+		handlers.Forbidden(c, i18n.Errorf(c, "you are not a member of the approval scope"))
+		return
+	}
+	req.Applicant, req.ApplicantID = u.GetUsername(), u.GetID()
+	if err := h.engine().Submit(ctx, req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	h.SetAuditData(c, i18n.Sprintf(context.TODO(), "create"), i18n.Sprintf(context.TODO(), "approval"), req.Title)
+	h.notifyApproval(c, req, msgbus.Add)
+	handlers.OK(c, req)
+}
+
+// ApproveApproval 通过当前阶段的审批
+//
+//	@Tags			Approve
+//	@Summary		通过当前阶段的审批
+//	@Description	通过当前阶段的审批, 最后一个阶段通过后执行申请的操作
+//	@Accept			json
+//	@Produce		json
+//	@Param			approval_id	path		uint											true	"approval_id"
+//	@Param			param		body		ApprovalDecision								false	"审批意见"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.Approval}	"resp"
+//	@Router			/v1/approvals/{approval_id}/approve [post]
+//	@Security		JWT
+func (h *ApproveHandler) ApproveApproval(c *gin.Context) {
+	h.decide(c, true)
+}
+
+// RejectApproval 拒绝审批
+//
+//	@Tags			Approve
+//	@Summary		拒绝审批
+//	@Description	拒绝审批
+//	@Accept			json
+//	@Produce		json
+//	@Param			approval_id	path		uint											true	"approval_id"
+//	@Param			param		body		ApprovalDecision								false	"审批意见"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.Approval}	"resp"
+//	@Router			/v1/approvals/{approval_id}/reject [post]
+//	@Security		JWT
+func (h *ApproveHandler) RejectApproval(c *gin.Context) {
+	h.decide(c, false)
+}
+
+func (h *ApproveHandler) decide(c *gin.Context, approve bool) {
+	req := ApprovalDecision{}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			handlers.NotOK(c, err)
+			return
+		}
+	}
+	a, ok := h.getApproval(c)
+	if !ok {
+		return
+	}
+	u, _ := h.GetContextUser(c)
+	if !approval.CanDecide(h.ModelCache().GetUserAuthority(u), u.GetUsername(), a) {
+		handlers.Forbidden(c, i18n.Errorf(c, "you can't decide the current stage of this approval"))
+		return
+	}
+	if err := h.engine().Decide(c.Request.Context(), a, u.GetUsername(), approve, req.Comment); err != nil {
+		if errors.Is(err, approval.ErrApprovalDecided) {
+			err = i18n.Errorf(c, "the current stage of this approval has already been decided")
+		}
+		handlers.NotOK(c, err)
+		return
+	}
+
+	action := i18n.Sprintf(context.TODO(), "passed")
+	if !approve {
+		action = i18n.Sprintf(context.TODO(), "rejected")
+	}
+	h.SetAuditData(c, action, i18n.Sprintf(context.TODO(), "approval"), a.Title)
+	h.notifyApproval(c, a, msgbus.Update)
+	handlers.OK(c, a)
+}
+
+// PostApprovalComment 评论审批申请
+//
+//	@Tags			Approve
+//	@Summary		评论审批申请
+//	@Description	评论审批申请
+//	@Accept			json
+//	@Produce		json
+//	@Param			approval_id	path		uint													true	"approval_id"
+//	@Param			param		body		ApprovalDecision										true	"评论"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.ApprovalComment}	"resp"
+//	@Router			/v1/approvals/{approval_id}/comments [post]
+//	@Security		JWT
+func (h *ApproveHandler) PostApprovalComment(c *gin.Context) {
+	req := ApprovalDecision{}
+	if err := c.BindJSON(&req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if req.Comment == "" {
+		handlers.NotOK(c, i18n.Errorf(c, "comment can't be empty"))
+		return
+	}
+	a, ok := h.getApproval(c)
+	if !ok {
+		return
+	}
+	u, _ := h.GetContextUser(c)
+	comment := &models.ApprovalComment{ApprovalID: a.ID, Username: u.GetUsername(), Stage: a.CurrentStage, Content: req.Comment}
+	if err := h.GetDB().WithContext(c.Request.Context()).Create(comment).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, comment)
+}
+
+// getApproval 获取申请, 仅申请人和申请范围内的管理员可见
+func (h *ApproveHandler) getApproval(c *gin.Context) (*models.Approval, bool) {
+	a := &models.Approval{}
+	if err := h.GetDB().WithContext(c.Request.Context()).
+		Preload("Comments", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		First(a, "id = ?", c.Param("approval_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return nil, false
+	}
+	u, _ := h.GetContextUser(c)
+	if !canView(h.ModelCache().GetUserAuthority(u), u.GetUsername(), a) {
+		handlers.Forbidden(c, i18n.Errorf(c, "you can't view this approval"))
+		return nil, false
+	}
+	return a, true
+}
+
+// fillApprovalScope 按环境和项目补全申请的项目、集群和租户, 避免提交不一致的范围
+func (h *ApproveHandler) fillApprovalScope(ctx context.Context, a *models.Approval) error {
+	db := h.GetDB().WithContext(ctx)
+	if a.EnvironmentID != nil {
+		env := models.Environment{}
+		if err := db.First(&env, *a.EnvironmentID).Error; err != nil {
+			return err
+		}
+		a.ProjectID, a.ClusterID = &env.ProjectID, &env.ClusterID
+	}
+	if a.ProjectID != nil {
+		project := models.Project{}
+		if err := db.First(&project, *a.ProjectID).Error; err != nil {
+			return err
+		}
+		a.TenantID = &project.TenantID
+	}
+	if a.TenantID != nil {
+		if err := db.First(&models.Tenant{}, *a.TenantID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyApproval 通知申请人和当前阶段的审批人
+func (h *ApproveHandler) notifyApproval(c *gin.Context, a *models.Approval, kind msgbus.EventKind) {
+	h.SendToMsgbus(c, func(msg *msgclient.MsgRequest) {
+		msg.MessageType = msgbus.Approve
+		msg.EventKind = kind
+		msg.ResourceType = msgbus.Approval
+		msg.ResourceID = a.ID
+		msg.Detail = i18n.Sprintf(context.TODO(), "approval %s is %s", a.Title, a.Status)
+		msg.ToUsers.Append(a.ApplicantID)
+		if stage := a.Stage(); stage != nil {
+			msg.ToUsers.Append(h.GetDataBase().SystemAdmins()...)
+			if a.TenantID != nil && stage.Approver != models.ApproverSystemAdmin {
+				msg.ToUsers.Append(h.GetDataBase().TenantAdmins(*a.TenantID)...)
+			}
+			if a.ProjectID != nil && stage.Approver == models.ApproverProjectAdmin {
+				msg.ToUsers.Append(h.GetDataBase().ProjectAdmins(*a.ProjectID)...)
+			}
+		}
+	})
+}
+
+// canApply 申请人需要是申请范围内的成员
+func canApply(auth *cache.UserAuthority, a *models.Approval) bool {
+	if auth.IsSystemAdmin() {
+		return true
+	}
+	if a.TenantID != nil && auth.IsTenantAdmin(*a.TenantID) {
+		return true
+	}
+	switch {
+	case a.ProjectID != nil:
+		return auth.GetResourceRole(models.ResProject, *a.ProjectID) != ""
+	case a.TenantID != nil:
+		return auth.GetResourceRole(models.ResTenant, *a.TenantID) != ""
+	}
+	return true
+}
+
+func canView(auth *cache.UserAuthority, username string, a *models.Approval) bool {
+	if a.Applicant == username || auth.IsSystemAdmin() {
+		return true
+	}
+	if a.TenantID != nil && auth.IsTenantAdmin(*a.TenantID) {
+		return true
+	}
+	return a.ProjectID != nil && auth.IsProjectAdmin(*a.ProjectID)
+}
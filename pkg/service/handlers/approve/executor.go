@@ -0,0 +1,334 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approveHandler
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/apps/application"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/installer/pluginmanager"
+	"kubegems.io/kubegems/pkg/service/approval"
+	"kubegems.io/kubegems/pkg/service/handlers/base"
+	"kubegems.io/kubegems/pkg/service/handlers/environment"
+	tenanthandler "kubegems.io/kubegems/pkg/service/handlers/tenant"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/slice"
+)
+
+func (h *ApproveHandler) engine() *approval.Engine {
+	return &approval.Engine{
+		DB: h.GetDB(),
+		Executors: map[string]approval.Executor{
+			models.ApprovalKindEnvironmentCreate: &environmentCreateExecutor{h.BaseHandler},
+			models.ApprovalKindProdDeploy:        &prodDeployExecutor{BaseHandler: h.BaseHandler, Manifest: &application.ManifestProcessor{GitProvider: h.GitProvider}},
+			models.ApprovalKindUserRoleGrant:     &userRoleGrantExecutor{h.BaseHandler},
+			models.ApprovalKindQuotaIncrease:     &quotaIncreaseExecutor{h.BaseHandler},
+			models.ApprovalKindPluginEnable:      &pluginEnableExecutor{h.BaseHandler},
+		},
+	}
+}
+
+// environmentCreateExecutor 创建环境, Content 为 models.Environment
+type environmentCreateExecutor struct {
+	base.BaseHandler
+}
+
+func (e *environmentCreateExecutor) environment(ctx context.Context, a *models.Approval) (*models.Environment, *models.Cluster, error) {
+	if a.ProjectID == nil {
+		return nil, nil, i18n.Errorf(ctx, "project is required for %s approval", a.Kind)
+	}
+	env := &models.Environment{}
+	if err := json.Unmarshal(a.Content, env); err != nil {
+		return nil, nil, err
+	}
+	cluster := &models.Cluster{}
+	if err := e.GetDB().WithContext(ctx).First(cluster, env.ClusterID).Error; err != nil {
+		return nil, nil, err
+	}
+	env.ID = 0
+	env.ProjectID = *a.ProjectID
+	env.CreatorID = a.ApplicantID
+	env.LimitRange = models.FillDefaultLimigrange(env)
+	a.ClusterID = &cluster.ID
+	a.Target = env.EnvironmentName
+	return env, cluster, nil
+}
+
+func (e *environmentCreateExecutor) Validate(ctx context.Context, a *models.Approval) error {
+	env, cluster, err := e.environment(ctx, a)
+	if err != nil {
+		return err
+	}
+	return environment.ValidateEnvironmentNamespace(ctx, e.BaseHandler, e.GetDB().WithContext(ctx), env.Namespace, env.EnvironmentName, cluster.ClusterName)
+}
+
+func (e *environmentCreateExecutor) Execute(ctx context.Context, a *models.Approval) error {
+	env, cluster, err := e.environment(ctx, a)
+	if err != nil {
+		return err
+	}
+	if err := environment.ValidateEnvironmentNamespace(ctx, e.BaseHandler, e.GetDB().WithContext(ctx), env.Namespace, env.EnvironmentName, cluster.ClusterName); err != nil {
+		return err
+	}
+	if err := e.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(env).Error; err != nil {
+			return err
+		}
+		return environment.AfterEnvironmentSave(ctx, e.BaseHandler, tx, env)
+	}); err != nil {
+		return err
+	}
+	a.EnvironmentID = &env.ID
+	e.ModelCache().UpsertEnvironment(env.ProjectID, env.ID, env.EnvironmentName, cluster.ClusterName, env.Namespace)
+	return nil
+}
+
+// prodDeployExecutor 生产环境部署, 审批通过后由部署时使用, Target 为应用名称, Revision 为申请时应用编排的版本
+type prodDeployExecutor struct {
+	base.BaseHandler
+	Manifest *application.ManifestProcessor
+}
+
+func (e *prodDeployExecutor) Validate(ctx context.Context, a *models.Approval) error {
+	if a.EnvironmentID == nil || a.Target == "" {
+		return i18n.Errorf(ctx, "environment and application are required for %s approval", a.Kind)
+	}
+	env := models.Environment{}
+	if err := e.GetDB().WithContext(ctx).Preload("Project.Tenant").First(&env, *a.EnvironmentID).Error; err != nil {
+		return err
+	}
+	if env.MetaType != models.EnvironmentMetaTypeProd {
+		return i18n.Errorf(ctx, "environment %s is not a production environment", env.EnvironmentName)
+	}
+	ref := application.PathRef{
+		Tenant:  env.Project.Tenant.TenantName,
+		Project: env.Project.ProjectName,
+		Env:     env.EnvironmentName,
+		Name:    a.Target,
+	}
+	revision, err := e.Manifest.Revision(ctx, ref)
+	if err != nil {
+		return err
+	}
+	a.Revision = revision
+	return nil
+}
+
+func (e *prodDeployExecutor) Execute(ctx context.Context, a *models.Approval) error {
+	return nil
+}
+
+// userRoleGrant 授予用户的角色, 授予的范围按环境、项目、租户的顺序确定
+type userRoleGrant struct {
+	UserID uint   `json:"userID"`
+	Role   string `json:"role"`
+}
+
+type userRoleGrantExecutor struct {
+	base.BaseHandler
+}
+
+func (e *userRoleGrantExecutor) grant(ctx context.Context, a *models.Approval) (*userRoleGrant, *models.User, error) {
+	grant := &userRoleGrant{}
+	if err := json.Unmarshal(a.Content, grant); err != nil {
+		return nil, nil, err
+	}
+	if grant.UserID == 0 {
+		grant.UserID = a.ApplicantID
+	}
+	var roles []string
+	switch {
+	case a.EnvironmentID != nil:
+		roles = []string{models.EnvironmentRoleReader, models.EnvironmentRoleOperator}
+	case a.ProjectID != nil:
+		roles = []string{models.ProjectRoleAdmin, models.ProjectRoleDev, models.ProjectRoleTest, models.ProjectRoleOps}
+	case a.TenantID != nil:
+		roles = []string{models.TenantRoleAdmin, models.TenantRoleOrdinary}
+	default:
+		return nil, nil, i18n.Errorf(ctx, "tenant, project or environment is required for %s approval", a.Kind)
+	}
+	if !slice.ContainStr(roles, grant.Role) {
+		return nil, nil, i18n.Errorf(ctx, "role %s not valid", grant.Role)
+	}
+	user := &models.User{}
+	if err := e.GetDB().WithContext(ctx).First(user, grant.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	a.Target = user.Username
+	return grant, user, nil
+}
+
+func (e *userRoleGrantExecutor) Validate(ctx context.Context, a *models.Approval) error {
+	_, _, err := e.grant(ctx, a)
+	return err
+}
+
+func (e *userRoleGrantExecutor) Execute(ctx context.Context, a *models.Approval) error {
+	grant, user, err := e.grant(ctx, a)
+	if err != nil {
+		return err
+	}
+	if err := e.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		switch {
+		case a.EnvironmentID != nil:
+			rel := models.EnvironmentUserRels{}
+			if err := tx.Where(models.EnvironmentUserRels{EnvironmentID: *a.EnvironmentID, UserID: user.ID}).FirstOrInit(&rel).Error; err != nil {
+				return err
+			}
+			rel.Role = grant.Role
+			return tx.Save(&rel).Error
+		case a.ProjectID != nil:
+			rel := models.ProjectUserRels{}
+			if err := tx.Where(models.ProjectUserRels{ProjectID: *a.ProjectID, UserID: user.ID}).FirstOrInit(&rel).Error; err != nil {
+				return err
+			}
+			rel.Role = grant.Role
+			return tx.Save(&rel).Error
+		default:
+			rel := models.TenantUserRels{}
+			if err := tx.Where(models.TenantUserRels{TenantID: *a.TenantID, UserID: user.ID}).FirstOrInit(&rel).Error; err != nil {
+				return err
+			}
+			rel.Role = grant.Role
+			return tx.Save(&rel).Error
+		}
+	}); err != nil {
+		return err
+	}
+	e.ModelCache().FlushUserAuthority(user)
+	return nil
+}
+
+// quotaIncreaseExecutor 调整资源配额, 有项目时调整项目的资源配额, 否则调整租户的资源配额
+// Content 为 v1.ResourceList
+type quotaIncreaseExecutor struct {
+	base.BaseHandler
+}
+
+func (e *quotaIncreaseExecutor) Validate(ctx context.Context, a *models.Approval) error {
+	if a.TenantID == nil || a.ClusterID == nil {
+		return i18n.Errorf(ctx, "tenant and cluster are required for %s approval", a.Kind)
+	}
+	return e.apply(ctx, a, false)
+}
+
+func (e *quotaIncreaseExecutor) Execute(ctx context.Context, a *models.Approval) error {
+	return e.apply(ctx, a, true)
+}
+
+func (e *quotaIncreaseExecutor) apply(ctx context.Context, a *models.Approval, save bool) error {
+	db := e.GetDB().WithContext(ctx)
+	if a.ProjectID != nil {
+		prq := &models.ProjectResourceQuota{}
+		if err := db.Where(models.ProjectResourceQuota{ProjectID: *a.ProjectID, ClusterID: *a.ClusterID}).FirstOrInit(prq).Error; err != nil {
+			return err
+		}
+		prq.Project = &models.Project{}
+		if err := db.First(prq.Project, *a.ProjectID).Error; err != nil {
+			return err
+		}
+		if err := tenanthandler.ValidateProjectResourceQuota(ctx, db, prq, a.Content); err != nil {
+			return err
+		}
+		if !save {
+			return nil
+		}
+		prq.Content = []byte(a.Content)
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Project", "Cluster").Save(prq).Error; err != nil {
+				return err
+			}
+			return tenanthandler.AfterProjectResourceQuotaSave(ctx, e.BaseHandler, tx, prq)
+		})
+	}
+
+	trq := &models.TenantResourceQuota{}
+	if err := db.Preload("Cluster", func(tx *gorm.DB) *gorm.DB { return tx.Select("id, cluster_name, oversold_config") }).
+		First(trq, "tenant_id = ? and cluster_id = ?", *a.TenantID, *a.ClusterID).Error; err != nil {
+		return i18n.Errorf(ctx, "current tenant has no resource quota on the cluster")
+	}
+	th := &tenanthandler.TenantHandler{BaseHandler: e.BaseHandler}
+	if err := th.ValidateTenantResourceQuota(ctx, trq.Cluster.ClusterName, trq.Cluster.OversoldConfig, trq.Content, a.Content); err != nil {
+		return err
+	}
+	if !save {
+		return nil
+	}
+	trq.Content = []byte(a.Content)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tenant", "Cluster").Save(trq).Error; err != nil {
+			return err
+		}
+		return tenanthandler.AfterTenantResourceQuotaSave(ctx, e.BaseHandler, tx, trq)
+	})
+}
+
+// pluginEnable 启用集群插件, Target 为插件名称
+type pluginEnable struct {
+	Version string         `json:"version"`
+	Values  map[string]any `json:"values"`
+}
+
+type pluginEnableExecutor struct {
+	base.BaseHandler
+}
+
+func (e *pluginEnableExecutor) plugin(ctx context.Context, a *models.Approval) (*pluginEnable, *models.Cluster, error) {
+	if a.ClusterID == nil || a.Target == "" {
+		return nil, nil, i18n.Errorf(ctx, "cluster and plugin are required for %s approval", a.Kind)
+	}
+	plugin := &pluginEnable{}
+	if len(a.Content) > 0 {
+		if err := json.Unmarshal(a.Content, plugin); err != nil {
+			return nil, nil, err
+		}
+	}
+	cluster := &models.Cluster{}
+	if err := e.GetDB().WithContext(ctx).Select("id, cluster_name").First(cluster, *a.ClusterID).Error; err != nil {
+		return nil, nil, err
+	}
+	return plugin, cluster, nil
+}
+
+func (e *pluginEnableExecutor) Validate(ctx context.Context, a *models.Approval) error {
+	plugin, cluster, err := e.plugin(ctx, a)
+	if err != nil {
+		return err
+	}
+	return e.execute(ctx, cluster.ClusterName, func(ctx context.Context, pm *pluginmanager.PluginManager) error {
+		_, err := pm.GetPluginVersion(ctx, a.Target, plugin.Version, false, false)
+		return err
+	})
+}
+
+func (e *pluginEnableExecutor) Execute(ctx context.Context, a *models.Approval) error {
+	plugin, cluster, err := e.plugin(ctx, a)
+	if err != nil {
+		return err
+	}
+	return e.execute(ctx, cluster.ClusterName, func(ctx context.Context, pm *pluginmanager.PluginManager) error {
+		return pm.Install(ctx, a.Target, plugin.Version, plugin.Values)
+	})
+}
+
+func (e *pluginEnableExecutor) execute(ctx context.Context, cluster string, fun func(ctx context.Context, pm *pluginmanager.PluginManager) error) error {
+	return e.BaseHandler.Execute(ctx, cluster, func(ctx context.Context, cli agents.Client) error {
+		return fun(ctx, &pluginmanager.PluginManager{Client: cli})
+	})
+}
@@ -33,13 +33,15 @@ import (
 
 type Approve struct {
 	msgbus.ResourceType
-	ID          uint // 目前记录的是quota id
+	ID          uint // 资源配额申请为quota id, 通用审批为approval id
 	Title       string
 	Content     interface{}
 	TenantID    uint   `json:",omitempty"`
 	TenantName  string `json:",omitempty"`
 	ClusterID   uint   `json:",omitempty"`
 	ClusterName string `json:",omitempty"`
+	ProjectID   uint   `json:",omitempty"`
+	ProjectName string `json:",omitempty"`
 	CreatedAt   time.Time
 	Status      string
 }
@@ -91,8 +93,42 @@ func (h *ApproveHandler) ListApproves(c *gin.Context) {
 				})
 			}
 		}
-		sort.Sort(ret)
+		projectApproves, err := h.listProjectApproves(c)
+		if err != nil {
+			handlers.NotOK(c, err)
+			return
+		}
+		ret = append(ret, projectApproves...)
+	}
+	// 通用审批中待当前用户审批的
+	approvals, err := h.todoApprovals(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	for _, a := range approvals {
+		item := Approve{
+			ResourceType: msgbus.Approval,
+			ID:           a.ID,
+			Title:        a.Title,
+			Content:      a.Content,
+			Status:       a.Status,
+		}
+		if a.CreatedAt != nil {
+			item.CreatedAt = *a.CreatedAt
+		}
+		if a.TenantID != nil {
+			item.TenantID = *a.TenantID
+		}
+		if a.ProjectID != nil {
+			item.ProjectID = *a.ProjectID
+		}
+		if a.ClusterID != nil {
+			item.ClusterID = *a.ClusterID
+		}
+		ret = append(ret, item)
 	}
+	sort.Sort(ret)
 
 	handlers.OK(c, ret)
 }
@@ -204,3 +240,135 @@ func (h *ApproveHandler) Reject(c *gin.Context) {
 
 	handlers.OK(c, quota)
 }
+
+// 审批中的项目资源申请
+func (h *ApproveHandler) listProjectApproves(c *gin.Context) (ApprovesList, error) {
+	var quotas []models.ProjectResourceQuota
+	if err := h.GetDB().WithContext(c.Request.Context()).
+		Preload("Project.Tenant").
+		Preload("Cluster").
+		Preload("TenantResourceQuotaApply").
+		Where("tenant_resource_quota_apply_id is not null").
+		Find(&quotas).Error; err != nil {
+		return nil, err
+	}
+	ret := ApprovesList{}
+	for _, v := range quotas {
+		if v.TenantResourceQuotaApply == nil || v.TenantResourceQuotaApply.Status != models.QuotaStatusPending {
+			continue
+		}
+		ret = append(ret, Approve{
+			ResourceType: msgbus.ProjectResourceQuota,
+			ID:           v.ID,
+			Title:        i18n.Sprintf(c, "user %s applied to adjust the ResourceQuota of project %s in cluster %s", v.TenantResourceQuotaApply.Username, v.Project.ProjectName, v.Cluster.ClusterName),
+			Content:      v.TenantResourceQuotaApply.Content,
+			TenantID:     v.Project.TenantID,
+			TenantName:   v.Project.Tenant.TenantName,
+			ProjectID:    v.ProjectID,
+			ProjectName:  v.Project.ProjectName,
+			ClusterID:    v.ClusterID,
+			ClusterName:  v.Cluster.ClusterName,
+			CreatedAt:    v.TenantResourceQuotaApply.UpdatedAt,
+			Status:       models.QuotaStatusPending,
+		})
+	}
+	return ret, nil
+}
+
+// PassProject 批准项目集群资源配额申请
+//	@Tags			Approve
+//	@Summary		批准项目集群资源配额申请
+//	@Description	批准项目集群资源配额申请
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		uint									true	"project resource quota id"
+//	@Param			param	body		Approve									true	"通过的内容"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=string}	""
+//	@Router			/v1/approve/projectresourcequota/{id}/pass [post]
+//	@Security		JWT
+func (h *ApproveHandler) PassProject(c *gin.Context) {
+	quota := models.ProjectResourceQuota{}
+	ctx := c.Request.Context()
+	if err := h.GetDB().WithContext(ctx).Preload("TenantResourceQuotaApply").Preload("Project").Preload("Cluster").
+		First(&quota, "id = ?", c.Param("id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if quota.TenantResourceQuotaApply == nil {
+		handlers.NotOK(c, i18n.Errorf(c, "the project has no resource application approval in the current cluster"))
+		return
+	}
+
+	req := Approve{}
+	if err := c.BindJSON(&req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	content, err := json.Marshal(req.Content)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := tenanthandler.ValidateProjectResourceQuota(ctx, h.GetDB().WithContext(ctx), &quota, content); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	// 应用新的resource quota
+	apply := quota.TenantResourceQuotaApply
+	quota.Content = content
+	quota.TenantResourceQuotaApply, quota.TenantResourceQuotaApplyID = nil, nil
+	if err := h.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Project", "Cluster").Save(&quota).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(apply).Error; err != nil {
+			return err
+		}
+		return tenanthandler.AfterProjectResourceQuotaSave(ctx, h.BaseHandler, tx, &quota)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	action := i18n.Sprintf(context.TODO(), "passed")
+	module := i18n.Sprintf(context.TODO(), "project cluster resource quota adjustment application")
+	h.SetAuditData(c, action, module, quota.Project.ProjectName+"/"+quota.Cluster.ClusterName)
+
+	handlers.OK(c, quota)
+}
+
+// RejectProject 拒绝项目集群资源配额申请
+//	@Tags			Approve
+//	@Summary		拒绝项目集群资源配额申请
+//	@Description	拒绝项目集群资源配额申请
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		uint									true	"project resource quota id"
+//	@Success		200	{object}	handlers.ResponseStruct{Data=string}	""
+//	@Router			/v1/approve/projectresourcequota/{id}/reject [post]
+//	@Security		JWT
+func (h *ApproveHandler) RejectProject(c *gin.Context) {
+	quota := models.ProjectResourceQuota{}
+	ctx := c.Request.Context()
+	if err := h.GetDB().WithContext(ctx).Preload("TenantResourceQuotaApply").Preload("Project").Preload("Cluster").
+		First(&quota, "id = ?", c.Param("id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if quota.TenantResourceQuotaApply == nil {
+		handlers.NotOK(c, i18n.Errorf(c, "the project has no resource application approval in the current cluster"))
+		return
+	}
+	// 外键是SET NULL，直接删除记录即可
+	if err := h.GetDB().WithContext(ctx).Delete(quota.TenantResourceQuotaApply).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	action := i18n.Sprintf(context.TODO(), "rejected")
+	module := i18n.Sprintf(context.TODO(), "project cluster resource quota adjustment application")
+	h.SetAuditData(c, action, module, quota.Project.ProjectName+"/"+quota.Cluster.ClusterName)
+
+	handlers.OK(c, quota)
+}
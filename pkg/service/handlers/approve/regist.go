@@ -17,14 +17,33 @@ package approveHandler
 import (
 	"github.com/gin-gonic/gin"
 	"kubegems.io/kubegems/pkg/service/handlers/base"
+	"kubegems.io/kubegems/pkg/utils/git"
 )
 
 type ApproveHandler struct {
 	base.BaseHandler
+	// GitProvider 用于确定生产部署申请时应用编排的版本
+	GitProvider git.Provider
 }
 
 func (h *ApproveHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.GET("/approve", h.ListApproves)
 	rg.POST("/approve/:id/pass", h.CheckIsSysADMIN, h.Pass)
 	rg.POST("/approve/:id/reject", h.CheckIsSysADMIN, h.Reject)
+	rg.POST("/approve/projectresourcequota/:id/pass", h.CheckIsSysADMIN, h.PassProject)
+	rg.POST("/approve/projectresourcequota/:id/reject", h.CheckIsSysADMIN, h.RejectProject)
+
+	// 通用审批, 审批人的权限在审批时按规则检查
+	rg.GET("/approvals", h.ListApprovals)
+	rg.GET("/approvals/todo", h.ListTodoApprovals)
+	rg.POST("/approvals", h.PostApproval)
+	rg.GET("/approvals/:approval_id", h.GetApproval)
+	rg.POST("/approvals/:approval_id/approve", h.ApproveApproval)
+	rg.POST("/approvals/:approval_id/reject", h.RejectApproval)
+	rg.POST("/approvals/:approval_id/comments", h.PostApprovalComment)
+
+	rg.GET("/approvalrules", h.CheckIsSysADMIN, h.ListApprovalRules)
+	rg.POST("/approvalrules", h.CheckIsSysADMIN, h.PostApprovalRule)
+	rg.PUT("/approvalrules/:rule_id", h.CheckIsSysADMIN, h.PutApprovalRule)
+	rg.DELETE("/approvalrules/:rule_id", h.CheckIsSysADMIN, h.DeleteApprovalRule)
 }
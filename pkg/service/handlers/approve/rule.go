@@ -0,0 +1,165 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approveHandler
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+// ListApprovalRules 审批规则列表
+//
+//	@Tags			Approve
+//	@Summary		审批规则列表
+//	@Description	审批规则列表
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	handlers.ResponseStruct{Data=[]models.ApprovalRule}	"resp"
+//	@Router			/v1/approvalrules [get]
+//	@Security		JWT
+func (h *ApproveHandler) ListApprovalRules(c *gin.Context) {
+	rules := []models.ApprovalRule{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("Tenant", func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("id", "tenant_name")
+	}).Order("kind, id").Find(&rules).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, rules)
+}
+
+// PostApprovalRule 创建审批规则
+//
+//	@Tags			Approve
+//	@Summary		创建审批规则
+//	@Description	创建审批规则, 不指定租户时为全局规则, 每种审批类型在每个租户下只能有一条规则
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		models.ApprovalRule									true	"规则"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=models.ApprovalRule}	"resp"
+//	@Router			/v1/approvalrules [post]
+//	@Security		JWT
+func (h *ApproveHandler) PostApprovalRule(c *gin.Context) {
+	rule := &models.ApprovalRule{}
+	if err := c.BindJSON(rule); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	rule.ID = 0
+	if err := h.validateApprovalRule(c.Request.Context(), rule); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := h.GetDB().WithContext(c.Request.Context()).Omit("Tenant").Create(rule).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(context.TODO(), "create"), i18n.Sprintf(context.TODO(), "approval rule"), rule.Kind)
+	handlers.OK(c, rule)
+}
+
+// PutApprovalRule 修改审批规则
+//
+//	@Tags			Approve
+//	@Summary		修改审批规则
+//	@Description	修改审批规则, 不影响已经提交的申请
+//	@Accept			json
+//	@Produce		json
+//	@Param			rule_id	path		uint												true	"rule_id"
+//	@Param			param	body		models.ApprovalRule									true	"规则"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=models.ApprovalRule}	"resp"
+//	@Router			/v1/approvalrules/{rule_id} [put]
+//	@Security		JWT
+func (h *ApproveHandler) PutApprovalRule(c *gin.Context) {
+	ctx := c.Request.Context()
+	rule := &models.ApprovalRule{}
+	if err := h.GetDB().WithContext(ctx).First(rule, "id = ?", c.Param("rule_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := &models.ApprovalRule{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	// 类型和租户不能修改
+	req.ID, req.Kind, req.TenantID = rule.ID, rule.Kind, rule.TenantID
+	if err := h.validateApprovalRule(ctx, req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	rule.Stages, rule.ExpireHours, rule.Enabled = req.Stages, req.ExpireHours, req.Enabled
+	if err := h.GetDB().WithContext(ctx).Omit("Tenant").Save(rule).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(context.TODO(), "update"), i18n.Sprintf(context.TODO(), "approval rule"), rule.Kind)
+	handlers.OK(c, rule)
+}
+
+// DeleteApprovalRule 删除审批规则
+//
+//	@Tags			Approve
+//	@Summary		删除审批规则
+//	@Description	删除审批规则
+//	@Accept			json
+//	@Produce		json
+//	@Param			rule_id	path		uint									true	"rule_id"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/approvalrules/{rule_id} [delete]
+//	@Security		JWT
+func (h *ApproveHandler) DeleteApprovalRule(c *gin.Context) {
+	ctx := c.Request.Context()
+	rule := &models.ApprovalRule{}
+	if err := h.GetDB().WithContext(ctx).First(rule, "id = ?", c.Param("rule_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := h.GetDB().WithContext(ctx).Delete(rule).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(context.TODO(), "delete"), i18n.Sprintf(context.TODO(), "approval rule"), rule.Kind)
+	handlers.OK(c, "ok")
+}
+
+// validateApprovalRule 全局规则的租户为空, 唯一索引无法约束, 需要单独检查
+func (h *ApproveHandler) validateApprovalRule(ctx context.Context, rule *models.ApprovalRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	db := h.GetDB().WithContext(ctx).Model(&models.ApprovalRule{}).Where("kind = ? and id <> ?", rule.Kind, rule.ID)
+	if rule.TenantID != nil {
+		if err := h.GetDB().WithContext(ctx).First(&models.Tenant{}, *rule.TenantID).Error; err != nil {
+			return err
+		}
+		db = db.Where("tenant_id = ?", *rule.TenantID)
+	} else {
+		db = db.Where("tenant_id is null")
+	}
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return i18n.Errorf(ctx, "approval rule of %s already exists", rule.Kind)
+	}
+	return nil
+}
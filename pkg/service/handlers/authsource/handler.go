@@ -15,16 +15,19 @@
 package authsource
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"strings"
 	"time"
 
+	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gin-gonic/gin"
 	"github.com/go-ldap/ldap/v3"
 	"golang.org/x/oauth2/endpoints"
 	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/aaa/auth"
 	"kubegems.io/kubegems/pkg/service/handlers"
 	"kubegems.io/kubegems/pkg/service/models"
 	"kubegems.io/kubegems/pkg/utils"
@@ -83,6 +86,11 @@ func (h *AuthSourceHandler) GetAuthSourcePredifinedVar(c *gin.Context) {
 			Scopes:      []string{"email", "read_user"},
 		})
 		return
+	case strings.EqualFold(vendor, "oidc"):
+		handlers.OK(c, vendorData{
+			Scopes: []string{"openid", "profile", "email", "groups"},
+		})
+		return
 	default:
 		handlers.OK(c, vendorData{
 			Scopes: []string{},
@@ -122,7 +130,7 @@ func (h *AuthSourceHandler) ListAuthSource(c *gin.Context) {
 // Create create authsource
 //	@Tags			AuthSource
 //	@Summary		create AuthSource
-//	@Description	create AuthSource  oauth(authURL,tokenURL,userInfoURL,redirectURL,appID,appSecret,scopes) ldap(basedn,ldapaddr,binduser,password)
+//	@Description	create AuthSource  oauth(authURL,tokenURL,userInfoURL,redirectURL,appID,appSecret,scopes) ldap(basedn,ldapaddr,binduser,password) oidc(issuer,redirectURL,appID,appSecret,scopes) saml(ssoURL,entityID,acsURL,idpCertificate,redirectURL)
 //	@Accept			json
 //	@Produce		json
 //	@Param			param	body		models.AuthSource								true	"表单"
@@ -249,6 +257,39 @@ func validateAuthConfig(source *models.AuthSource) error {
 			errs = append(errs, "userInfoURL can't empty")
 		}
 	}
+	if source.Kind == "OIDC" {
+		if source.Config.Issuer == "" {
+			errs = append(errs, "issuer can't empty")
+		} else if err := validateOIDCConfig(source.Config); err != nil {
+			errs = append(errs, fmt.Sprintf("oidc discovery error: %v", err))
+		}
+		if source.Config.AppID == "" {
+			errs = append(errs, "appID can't empty")
+		}
+		if source.Config.AppSecret == "" {
+			errs = append(errs, "appSecret can't empty")
+		}
+		if source.Config.RedirectURL == "" {
+			errs = append(errs, "redirectURL can't empty")
+		}
+	}
+	if source.Kind == "SAML" {
+		if source.Config.SSOURL == "" {
+			errs = append(errs, "ssoURL can't empty")
+		}
+		if source.Config.EntityID == "" {
+			errs = append(errs, "entityID can't empty")
+		}
+		if source.Config.ACSURL == "" {
+			errs = append(errs, "acsURL can't empty")
+		}
+		if source.Config.RedirectURL == "" {
+			errs = append(errs, "redirectURL can't empty")
+		}
+		if err := auth.ValidateSAMLCertificate(source.Config.IdPCertificate); err != nil {
+			errs = append(errs, fmt.Sprintf("idpCertificate error: %v", err))
+		}
+	}
 	if len(errs) > 0 {
 		return fmt.Errorf(strings.Join(errs, ";"))
 	}
@@ -285,6 +326,13 @@ func validateLdapConfig(cfg models.AuthSourceConfig) error {
 	return err
 }
 
+func validateOIDCConfig(cfg models.AuthSourceConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := oidc.NewProvider(ctx, cfg.Issuer)
+	return err
+}
+
 func basednIsValid(basedn string) bool {
 	seps := strings.Split(basedn, ",")
 	for _, sep := range seps {
@@ -310,3 +358,95 @@ func filterIsValid(filter string) error {
 	}
 	return nil
 }
+
+// ListGroupMapping list authsource group mappings
+//	@Tags			AuthSource
+//	@Summary		登录源组映射列表
+//	@Description	登录源组映射列表
+//	@Accept			json
+//	@Produce		json
+//	@Param			source_id	path		uint														true	"source_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.AuthSourceGroupMapping}	"AuthSourceGroupMapping"
+//	@Router			/v1/authsource/{source_id}/groupmappings [get]
+//	@Security		JWT
+func (h *AuthSourceHandler) ListGroupMapping(c *gin.Context) {
+	list := []models.AuthSourceGroupMapping{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Find(&list, "auth_source_id = ?", c.Param("source_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, list)
+}
+
+// CreateGroupMapping create authsource group mapping
+//	@Tags			AuthSource
+//	@Summary		创建登录源组映射
+//	@Description	创建登录源组映射, 组内用户登录或定时同步时授予对应租户、项目或环境的角色, 移出组后回收
+//	@Accept			json
+//	@Produce		json
+//	@Param			source_id	path		uint													true	"source_id"
+//	@Param			param		body		models.AuthSourceGroupMapping							true	"表单"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.AuthSourceGroupMapping}	"AuthSourceGroupMapping"
+//	@Router			/v1/authsource/{source_id}/groupmappings [post]
+//	@Security		JWT
+func (h *AuthSourceHandler) CreateGroupMapping(c *gin.Context) {
+	ctx := c.Request.Context()
+	source := &models.AuthSource{}
+	if err := h.GetDB().WithContext(ctx).First(source, c.Param("source_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	mapping := &models.AuthSourceGroupMapping{}
+	if err := c.BindJSON(mapping); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	mapping.ID = 0
+	mapping.AuthSourceID = source.ID
+	if !auth.ValidGroupRole(mapping.Kind, mapping.Role) {
+		handlers.NotOK(c, fmt.Errorf("invalid role %s for %s", mapping.Role, mapping.Kind))
+		return
+	}
+	var resource any
+	switch mapping.Kind {
+	case models.GroupMappingKindTenant:
+		resource = &models.Tenant{}
+	case models.GroupMappingKindProject:
+		resource = &models.Project{}
+	default:
+		resource = &models.Environment{}
+	}
+	if err := h.GetDB().WithContext(ctx).First(resource, mapping.ResourceID).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := h.GetDB().WithContext(ctx).Create(mapping).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "创建", "登录源组映射", fmt.Sprintf("%s/%s", source.Name, mapping.Group))
+	handlers.Created(c, mapping)
+}
+
+// DeleteGroupMapping delete authsource group mapping
+//	@Tags			AuthSource
+//	@Summary		删除登录源组映射
+//	@Description	删除登录源组映射, 已授予的角色在下次登录或定时同步时回收
+//	@Accept			json
+//	@Produce		json
+//	@Param			source_id	path		uint									true	"source_id"
+//	@Param			mapping_id	path		uint									true	"mapping_id"
+//	@Success		204			{object}	handlers.ResponseStruct{Data=object}	"AuthSourceGroupMapping"
+//	@Router			/v1/authsource/{source_id}/groupmappings/{mapping_id} [delete]
+//	@Security		JWT
+func (h *AuthSourceHandler) DeleteGroupMapping(c *gin.Context) {
+	mapping := &models.AuthSourceGroupMapping{}
+	if err := h.GetDB().WithContext(c.Request.Context()).
+		Where("auth_source_id = ? and id = ?", c.Param("source_id"), c.Param("mapping_id")).
+		Delete(mapping).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "删除", "登录源组映射", c.Param("mapping_id"))
+	handlers.NoContent(c, nil)
+}
@@ -28,4 +28,8 @@ func (h *AuthSourceHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.POST("/authsource", h.CheckIsSysADMIN, h.Create)
 	rg.PUT("/authsource/:source_id", h.CheckIsSysADMIN, h.Modify)
 	rg.DELETE("/authsource/:source_id", h.CheckIsSysADMIN, h.Delete)
+
+	rg.GET("/authsource/:source_id/groupmappings", h.CheckIsSysADMIN, h.ListGroupMapping)
+	rg.POST("/authsource/:source_id/groupmappings", h.CheckIsSysADMIN, h.CreateGroupMapping)
+	rg.DELETE("/authsource/:source_id/groupmappings/:mapping_id", h.CheckIsSysADMIN, h.DeleteGroupMapping)
 }
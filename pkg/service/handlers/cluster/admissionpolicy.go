@@ -0,0 +1,226 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterhandler
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/policy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ListAdmissionPolicy 集群准入策略列表
+//
+//	@Tags			Cluster
+//	@Summary		集群准入策略列表
+//	@Description	集群准入策略列表
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster_id	path		int													true	"cluster_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]v1beta1.AdmissionPolicy}	"policies"
+//	@Router			/v1/cluster/{cluster_id}/admissionpolicies [get]
+//	@Security		JWT
+func (h *ClusterHandler) ListAdmissionPolicy(c *gin.Context) {
+	h.cluster(c, func(ctx context.Context, _ models.Cluster, cli agents.Client) (interface{}, error) {
+		list := &v1beta1.AdmissionPolicyList{}
+		if err := cli.List(ctx, list); err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	})
+}
+
+// GetAdmissionPolicy 集群准入策略详情
+//
+//	@Tags			Cluster
+//	@Summary		集群准入策略详情
+//	@Description	集群准入策略详情, 包含最近的违规记录
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster_id	path		int													true	"cluster_id"
+//	@Param			name		path		string												true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=v1beta1.AdmissionPolicy}	"policy"
+//	@Router			/v1/cluster/{cluster_id}/admissionpolicies/{name} [get]
+//	@Security		JWT
+func (h *ClusterHandler) GetAdmissionPolicy(c *gin.Context) {
+	h.cluster(c, func(ctx context.Context, _ models.Cluster, cli agents.Client) (interface{}, error) {
+		ap := &v1beta1.AdmissionPolicy{}
+		if err := cli.Get(ctx, client.ObjectKey{Name: c.Param("name")}, ap); err != nil {
+			return nil, err
+		}
+		return ap, nil
+	})
+}
+
+// PostAdmissionPolicy 创建集群准入策略
+//
+//	@Tags			Cluster
+//	@Summary		创建集群准入策略
+//	@Description	创建集群准入策略
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster_id	path		int													true	"cluster_id"
+//	@Param			param		body		v1beta1.AdmissionPolicy								true	"表单"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=v1beta1.AdmissionPolicy}	"policy"
+//	@Router			/v1/cluster/{cluster_id}/admissionpolicies [post]
+//	@Security		JWT
+func (h *ClusterHandler) PostAdmissionPolicy(c *gin.Context) {
+	ap := &v1beta1.AdmissionPolicy{}
+	if err := c.BindJSON(ap); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "create"), i18n.Sprintf(c, "admission policy"), ap.Name)
+	h.cluster(c, func(ctx context.Context, _ models.Cluster, cli agents.Client) (interface{}, error) {
+		if err := cli.Create(ctx, ap); err != nil {
+			return nil, err
+		}
+		return ap, nil
+	})
+}
+
+// PutAdmissionPolicy 修改集群准入策略
+//
+//	@Tags			Cluster
+//	@Summary		修改集群准入策略
+//	@Description	修改集群准入策略的规则, 模式和范围
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster_id	path		int													true	"cluster_id"
+//	@Param			name		path		string												true	"name"
+//	@Param			param		body		v1beta1.AdmissionPolicy								true	"表单"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=v1beta1.AdmissionPolicy}	"policy"
+//	@Router			/v1/cluster/{cluster_id}/admissionpolicies/{name} [put]
+//	@Security		JWT
+func (h *ClusterHandler) PutAdmissionPolicy(c *gin.Context) {
+	newap := &v1beta1.AdmissionPolicy{}
+	if err := c.BindJSON(newap); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	name := c.Param("name")
+	if newap.Name != name {
+		handlers.NotOK(c, i18n.Errorf(c, "URL parameter mismatched with body"))
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "admission policy"), name)
+	h.cluster(c, func(ctx context.Context, _ models.Cluster, cli agents.Client) (interface{}, error) {
+		ap := &v1beta1.AdmissionPolicy{}
+		if err := cli.Get(ctx, client.ObjectKey{Name: name}, ap); err != nil {
+			return nil, err
+		}
+		ap.Spec = newap.Spec
+		if err := cli.Update(ctx, ap); err != nil {
+			return nil, err
+		}
+		return ap, nil
+	})
+}
+
+// DeleteAdmissionPolicy 删除集群准入策略
+//
+//	@Tags			Cluster
+//	@Summary		删除集群准入策略
+//	@Description	删除集群准入策略
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster_id	path		int						true	"cluster_id"
+//	@Param			name		path		string					true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct	"resp"
+//	@Router			/v1/cluster/{cluster_id}/admissionpolicies/{name} [delete]
+//	@Security		JWT
+func (h *ClusterHandler) DeleteAdmissionPolicy(c *gin.Context) {
+	name := c.Param("name")
+	h.SetAuditData(c, i18n.Sprintf(c, "delete"), i18n.Sprintf(c, "admission policy"), name)
+	h.cluster(c, func(ctx context.Context, _ models.Cluster, cli agents.Client) (interface{}, error) {
+		ap := &v1beta1.AdmissionPolicy{}
+		ap.Name = name
+		if err := cli.Delete(ctx, ap); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+// AdmissionPolicyTest 试运行准入策略
+type AdmissionPolicyTest struct {
+	Spec        v1beta1.AdmissionPolicySpec `json:"spec"`
+	Operation   string                      `json:"operation"`
+	Tenant      string                      `json:"tenant"`
+	Project     string                      `json:"project"`
+	Environment string                      `json:"environment"`
+	Object      map[string]interface{}      `json:"object"`
+}
+
+// AdmissionPolicyTestResult 试运行结果
+type AdmissionPolicyTestResult struct {
+	Violations []string `json:"violations"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// TestAdmissionPolicy 试运行准入策略
+//
+//	@Tags			Cluster
+//	@Summary		试运行准入策略
+//	@Description	使用给定的资源试运行准入策略规则, 不会记录违规
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster_id	path		int													true	"cluster_id"
+//	@Param			param		body		AdmissionPolicyTest									true	"表单"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=AdmissionPolicyTestResult}	"result"
+//	@Router			/v1/cluster/{cluster_id}/admissionpolicies/_/test [post]
+//	@Security		JWT
+func (h *ClusterHandler) TestAdmissionPolicy(c *gin.Context) {
+	test := &AdmissionPolicyTest{}
+	if err := c.BindJSON(test); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	operation := test.Operation
+	if operation == "" {
+		operation = "CREATE"
+	}
+	req := &policy.Request{
+		Operation:   operation,
+		Tenant:      test.Tenant,
+		Project:     test.Project,
+		Environment: test.Environment,
+		Object:      test.Object,
+	}
+	if kind, ok := test.Object["kind"].(string); ok {
+		req.Kind = kind
+	}
+	if metadata, ok := test.Object["metadata"].(map[string]interface{}); ok {
+		req.Name, _ = metadata["name"].(string)
+		req.Namespace, _ = metadata["namespace"].(string)
+	}
+	violations, err := policy.Evaluate(c.Request.Context(), test.Spec.Engine, test.Spec.Rule, req)
+	if err != nil {
+		handlers.OK(c, AdmissionPolicyTestResult{Violations: []string{}, Error: err.Error()})
+		return
+	}
+	for i := range violations {
+		if violations[i] == "" {
+			violations[i] = test.Spec.Message
+		}
+	}
+	handlers.OK(c, AdmissionPolicyTestResult{Violations: violations})
+}
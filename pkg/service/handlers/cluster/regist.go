@@ -38,4 +38,11 @@ func (h *ClusterHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.GET("/cluster/:cluster_id/logqueryhistoryv2", h.ListClusterLogQueryHistoryv2)
 	rg.GET("/cluster/:cluster_id/logquerysnapshot", h.ListClusterLogQuerySnapshot)
 	rg.GET("/cluster/:cluster_id/quota", h.ListClusterQuota)
+
+	rg.GET("/cluster/:cluster_id/admissionpolicies", h.CheckIsSysADMIN, h.ListAdmissionPolicy)
+	rg.GET("/cluster/:cluster_id/admissionpolicies/:name", h.CheckIsSysADMIN, h.GetAdmissionPolicy)
+	rg.POST("/cluster/:cluster_id/admissionpolicies", h.CheckIsSysADMIN, h.PostAdmissionPolicy)
+	rg.PUT("/cluster/:cluster_id/admissionpolicies/:name", h.CheckIsSysADMIN, h.PutAdmissionPolicy)
+	rg.DELETE("/cluster/:cluster_id/admissionpolicies/:name", h.CheckIsSysADMIN, h.DeleteAdmissionPolicy)
+	rg.POST("/cluster/:cluster_id/admissionpolicies/_/test", h.CheckIsSysADMIN, h.TestAdmissionPolicy)
 }
@@ -0,0 +1,313 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cost
+
+import (
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+// 分组字段对应的列
+var groupByColumns = map[string]string{
+	"tenant":      "tenant_name",
+	"project":     "project_name",
+	"environment": "environment_name",
+	"cluster":     "cluster_name",
+	"namespace":   "namespace",
+	"date":        "date",
+}
+
+// ShowbackItem 一个分组在时间范围内的用量和费用, 不同货币的费用分别统计
+type ShowbackItem struct {
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	models.CostUsage
+	CPUCost     float64 `json:"cpuCost"`
+	MemoryCost  float64 `json:"memoryCost"`
+	StorageCost float64 `json:"storageCost"`
+	GPUCost     float64 `json:"gpuCost"`
+	Cost        float64 `json:"cost"`
+}
+
+type Showback struct {
+	Start   string         `json:"start"`
+	End     string         `json:"end"`
+	GroupBy string         `json:"groupBy"`
+	Items   []ShowbackItem `json:"items"`
+}
+
+// ListCostPrice 集群资源单价列表
+//
+//	@Tags			Cost
+//	@Summary		集群资源单价列表
+//	@Description	集群资源单价列表
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	handlers.ResponseStruct{Data=[]models.CostPrice}	"resp"
+//	@Router			/v1/cost/prices [get]
+//	@Security		JWT
+func (h *CostHandler) ListCostPrice(c *gin.Context) {
+	prices := []models.CostPrice{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("Cluster", func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("id", "cluster_name")
+	}).Find(&prices).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, prices)
+}
+
+// PutCostPrice 设置集群资源单价
+//
+//	@Tags			Cost
+//	@Summary		设置集群资源单价
+//	@Description	设置集群资源单价, 从设置后的下一个小时开始统计费用, 修改单价不影响已经统计的费用
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster_id	path		uint											true	"集群ID"
+//	@Param			param		body		models.CostPrice								true	"单价"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.CostPrice}	"resp"
+//	@Router			/v1/cost/prices/{cluster_id} [put]
+//	@Security		JWT
+func (h *CostHandler) PutCostPrice(c *gin.Context) {
+	req := &models.CostPrice{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	cluster := &models.Cluster{}
+	if err := h.GetDB().WithContext(ctx).First(cluster, "id = ?", c.Param("cluster_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "cost price"), cluster.ClusterName)
+
+	price := &models.CostPrice{}
+	if err := h.GetDB().WithContext(ctx).Where(models.CostPrice{ClusterID: cluster.ID}).FirstOrInit(price).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	price.Currency = req.Currency
+	price.CPUCoreHour = req.CPUCoreHour
+	price.MemoryGBHour = req.MemoryGBHour
+	price.StorageGBHour = req.StorageGBHour
+	price.GPUHour = req.GPUHour
+	if err := h.GetDB().WithContext(ctx).Save(price).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, price)
+}
+
+// DeleteCostPrice 删除集群资源单价
+//
+//	@Tags			Cost
+//	@Summary		删除集群资源单价
+//	@Description	删除集群资源单价, 停止统计该集群的费用, 已经统计的费用保留
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster_id	path		uint									true	"集群ID"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/cost/prices/{cluster_id} [delete]
+//	@Security		JWT
+func (h *CostHandler) DeleteCostPrice(c *gin.Context) {
+	ctx := c.Request.Context()
+	price := &models.CostPrice{}
+	if err := h.GetDB().WithContext(ctx).Preload("Cluster").First(price, "cluster_id = ?", c.Param("cluster_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if price.Cluster != nil {
+		h.SetAuditData(c, i18n.Sprintf(c, "delete"), i18n.Sprintf(c, "cost price"), price.Cluster.ClusterName)
+	}
+	if err := h.GetDB().WithContext(ctx).Delete(price).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// Showback 所有租户的费用
+//
+//	@Tags			Cost
+//	@Summary		所有租户的费用
+//	@Description	所有租户的费用, 默认按租户分组
+//	@Accept			json
+//	@Produce		json
+//	@Param			start	query		string									false	"开始日期(包含), 2006-01-02, 默认本月第一天"
+//	@Param			end		query		string									false	"结束日期(包含), 2006-01-02, 默认今天"
+//	@Param			groupby	query		string									false	"分组(tenant, project, environment, cluster, namespace, date)"
+//	@Param			format	query		string									false	"csv 时导出为 csv 文件"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=Showback}	"resp"
+//	@Router			/v1/cost/showback [get]
+//	@Security		JWT
+func (h *CostHandler) Showback(c *gin.Context) {
+	h.showback(c, "all", "tenant", h.GetDB())
+}
+
+// TenantShowback 租户的费用
+//
+//	@Tags			Cost
+//	@Summary		租户的费用
+//	@Description	租户的费用, 默认按项目分组
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		uint									true	"租户ID"
+//	@Param			start		query		string									false	"开始日期(包含), 2006-01-02, 默认本月第一天"
+//	@Param			end			query		string									false	"结束日期(包含), 2006-01-02, 默认今天"
+//	@Param			groupby		query		string									false	"分组(tenant, project, environment, cluster, namespace, date)"
+//	@Param			format		query		string									false	"csv 时导出为 csv 文件"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=Showback}	"resp"
+//	@Router			/v1/cost/tenant/{tenant_id}/showback [get]
+//	@Security		JWT
+func (h *CostHandler) TenantShowback(c *gin.Context) {
+	tenant := &models.Tenant{}
+	if err := h.GetDB().WithContext(c.Request.Context()).First(tenant, "id = ?", c.Param("tenant_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.showback(c, tenant.TenantName, "project", h.GetDB().Where("tenant_name = ?", tenant.TenantName))
+}
+
+// ProjectShowback 项目的费用
+//
+//	@Tags			Cost
+//	@Summary		项目的费用
+//	@Description	项目的费用, 默认按环境分组
+//	@Accept			json
+//	@Produce		json
+//	@Param			project_id	path		uint									true	"项目ID"
+//	@Param			start		query		string									false	"开始日期(包含), 2006-01-02, 默认本月第一天"
+//	@Param			end			query		string									false	"结束日期(包含), 2006-01-02, 默认今天"
+//	@Param			groupby		query		string									false	"分组(tenant, project, environment, cluster, namespace, date)"
+//	@Param			format		query		string									false	"csv 时导出为 csv 文件"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=Showback}	"resp"
+//	@Router			/v1/cost/project/{project_id}/showback [get]
+//	@Security		JWT
+func (h *CostHandler) ProjectShowback(c *gin.Context) {
+	project := &models.Project{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("Tenant").First(project, "id = ?", c.Param("project_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.showback(c, project.ProjectName, "environment", h.GetDB().
+		Where("tenant_name = ?", project.Tenant.TenantName).
+		Where("project_name = ?", project.ProjectName))
+}
+
+// EnvironmentShowback 环境的费用
+//
+//	@Tags			Cost
+//	@Summary		环境的费用
+//	@Description	环境的费用, 默认按日期分组
+//	@Accept			json
+//	@Produce		json
+//	@Param			environment_id	path		uint									true	"环境ID"
+//	@Param			start			query		string									false	"开始日期(包含), 2006-01-02, 默认本月第一天"
+//	@Param			end				query		string									false	"结束日期(包含), 2006-01-02, 默认今天"
+//	@Param			groupby			query		string									false	"分组(tenant, project, environment, cluster, namespace, date)"
+//	@Param			format			query		string									false	"csv 时导出为 csv 文件"
+//	@Success		200				{object}	handlers.ResponseStruct{Data=Showback}	"resp"
+//	@Router			/v1/cost/environment/{environment_id}/showback [get]
+//	@Security		JWT
+func (h *CostHandler) EnvironmentShowback(c *gin.Context) {
+	env := &models.Environment{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("Cluster").First(env, "id = ?", c.Param("environment_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.showback(c, env.EnvironmentName, "date", h.GetDB().
+		Where("cluster_name = ?", env.Cluster.ClusterName).
+		Where("namespace = ?", env.Namespace))
+}
+
+func (h *CostHandler) showback(c *gin.Context, scope, defaultGroupBy string, query *gorm.DB) {
+	now := time.Now().UTC()
+	start := c.DefaultQuery("start", now.AddDate(0, 0, 1-now.Day()).Format(models.CostDateLayout))
+	end := c.DefaultQuery("end", now.Format(models.CostDateLayout))
+	for _, date := range []string{start, end} {
+		if _, err := time.Parse(models.CostDateLayout, date); err != nil {
+			handlers.NotOK(c, i18n.Errorf(c, "date %s not valid", date))
+			return
+		}
+	}
+	groupBy := c.DefaultQuery("groupby", defaultGroupBy)
+	column, ok := groupByColumns[groupBy]
+	if !ok {
+		handlers.NotOK(c, i18n.Errorf(c, "groupby %s not valid", groupBy))
+		return
+	}
+
+	ret := Showback{Start: start, End: end, GroupBy: groupBy, Items: []ShowbackItem{}}
+	if err := query.WithContext(c.Request.Context()).Model(&models.CostDaily{}).
+		Select(fmt.Sprintf(`%s as name, currency,
+			sum(cpu_usage_core_hours) as cpu_usage_core_hours, sum(cpu_request_core_hours) as cpu_request_core_hours,
+			sum(memory_usage_gb_hours) as memory_usage_gb_hours, sum(memory_request_gb_hours) as memory_request_gb_hours,
+			sum(storage_gb_hours) as storage_gb_hours, sum(gpu_hours) as gpu_hours,
+			sum(cpu_cost) as cpu_cost, sum(memory_cost) as memory_cost, sum(storage_cost) as storage_cost,
+			sum(gpu_cost) as gpu_cost, sum(cost) as cost`, column)).
+		Where("date >= ? and date <= ?", start, end).
+		Group(column).Group("currency").
+		Order("name").
+		Scan(&ret.Items).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		writeShowbackCSV(c, fmt.Sprintf("cost-%s-%s-%s.csv", scope, start, end), &ret)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+func writeShowbackCSV(c *gin.Context, filename string, showback *Showback) {
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		showback.GroupBy, "currency",
+		"cpu_usage_core_hours", "cpu_request_core_hours", "memory_usage_gb_hours", "memory_request_gb_hours",
+		"storage_gb_hours", "gpu_hours", "cpu_cost", "memory_cost", "storage_cost", "gpu_cost", "cost",
+	})
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 2, 64)
+	}
+	for _, item := range showback.Items {
+		w.Write([]string{
+			item.Name, item.Currency,
+			formatFloat(item.CPUUsageCoreHours), formatFloat(item.CPURequestCoreHours),
+			formatFloat(item.MemoryUsageGBHours), formatFloat(item.MemoryRequestGBHours),
+			formatFloat(item.StorageGBHours), formatFloat(item.GPUHours),
+			formatFloat(item.CPUCost), formatFloat(item.MemoryCost), formatFloat(item.StorageCost),
+			formatFloat(item.GPUCost), formatFloat(item.Cost),
+		})
+	}
+	w.Flush()
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cost
+
+import (
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/service/handlers/base"
+)
+
+// CostHandler 集群资源单价和租户、项目、环境的费用分摊
+type CostHandler struct {
+	base.BaseHandler
+}
+
+func (h *CostHandler) RegistRouter(rg *gin.RouterGroup) {
+	rg.GET("/cost/prices", h.CheckIsSysADMIN, h.ListCostPrice)
+	rg.PUT("/cost/prices/:cluster_id", h.CheckIsSysADMIN, h.PutCostPrice)
+	rg.DELETE("/cost/prices/:cluster_id", h.CheckIsSysADMIN, h.DeleteCostPrice)
+
+	rg.GET("/cost/showback", h.CheckIsSysADMIN, h.Showback)
+	rg.GET("/cost/tenant/:tenant_id/showback", h.CheckByTenantID, h.TenantShowback)
+	rg.GET("/cost/project/:project_id/showback", h.CheckByProjectID, h.ProjectShowback)
+	rg.GET("/cost/environment/:environment_id/showback", h.CheckByEnvironmentID, h.EnvironmentShowback)
+}
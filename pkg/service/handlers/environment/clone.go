@@ -0,0 +1,467 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package environment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/slice"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	CloneStatusCreated = "Created"
+	CloneStatusSkipped = "Skipped"
+	CloneStatusFailed  = "Failed"
+	CloneStatusCleaned = "Cleaned"
+
+	TaskGroupEnvironment                   = "environment"
+	TaskFunction_CloneEnvironmentResources = "clone-environment-resources"
+	TaskFunction_CleanupCloneSnapshots     = "cleanup-clone-snapshots"
+
+	cloneNamespaceTimeout = 30 * time.Second
+	// 复制数据时每个 PVC 最多等待 cloneSnapshotTimeout, 整个步骤的超时
+	cloneDataTimeout = 30 * time.Minute
+)
+
+// 按依赖顺序克隆, 工作负载依赖的配置和存储先创建
+var cloneableKinds = []string{
+	"ConfigMap",
+	"Secret",
+	"PersistentVolumeClaim",
+	"Service",
+	"Deployment",
+	"StatefulSet",
+	"DaemonSet",
+	"Ingress",
+}
+
+// EnvironmentCloneForm 克隆环境的参数
+type EnvironmentCloneForm struct {
+	// EnvironmentName 新环境名称
+	EnvironmentName string `json:"environmentName" binding:"required"`
+	// Namespace 新环境的命名空间
+	Namespace string `json:"namespace" binding:"required"`
+	// ClusterID 新环境所在集群, 默认与源环境相同
+	ClusterID uint `json:"clusterID"`
+	// Kinds 需要克隆的资源类型, 默认为除 Secret 以外的所有类型
+	Kinds []string `json:"kinds"`
+	// WithUsers 是否复制环境成员
+	WithUsers bool `json:"withUsers"`
+	// WithData 是否复制 PVC 的数据, 仅支持同集群并需要存储支持快照
+	WithData bool `json:"withData"`
+}
+
+// CloneResourceResult 单个资源的克隆结果
+type CloneResourceResult struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	TargetName string `json:"targetName,omitempty"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+}
+
+// EnvironmentCloneStatus 克隆资源任务的状态和每个资源的克隆结果
+type EnvironmentCloneStatus struct {
+	Status          workflow.TaskStatusCode `json:"status"`
+	Message         string                  `json:"message,omitempty"`
+	StartTimestamp  metav1.Time             `json:"startTimestamp,omitempty"`
+	FinishTimestamp metav1.Time             `json:"finishTimestamp,omitempty"`
+	Resources       []CloneResourceResult   `json:"resources"`
+}
+
+// EnvironmentCloneResult 克隆结果, 资源由后台任务克隆, 结果通过克隆状态接口查询
+type EnvironmentCloneResult struct {
+	Environment models.Environment     `json:"environment"`
+	Clone       EnvironmentCloneStatus `json:"clone"`
+}
+
+// CloneTaskArgs 克隆资源任务的参数
+type CloneTaskArgs struct {
+	SrcCluster     string `json:"srcCluster"`
+	SrcNamespace   string `json:"srcNamespace"`
+	SrcEnvironment string `json:"srcEnvironment"`
+	DstCluster     string `json:"dstCluster"`
+	DstNamespace   string `json:"dstNamespace"`
+	DstEnvironment string `json:"dstEnvironment"`
+	WithData       bool   `json:"withData"`
+}
+
+func cloneTaskName(envid uint) string {
+	return fmt.Sprintf("%d/clone", envid)
+}
+
+// CloneEnvironment 克隆环境
+//
+//	@Tags			Environment
+//	@Summary		克隆环境
+//	@Description	克隆环境的配额, LimitRange, 成员, 并提交后台任务克隆选定类型的资源到新的命名空间或集群, 资源的克隆结果通过克隆状态接口查询
+//	@Accept			json
+//	@Produce		json
+//	@Param			project_id		path		uint													true	"project_id"
+//	@Param			environment_id	path		uint													true	"environment_id"
+//	@Param			param			body		EnvironmentCloneForm									true	"表单"
+//	@Success		200				{object}	handlers.ResponseStruct{Data=EnvironmentCloneResult}	"result"
+//	@Router			/v1/project/{project_id}/environment/{environment_id}/clone [post]
+//	@Security		JWT
+func (h *EnvironmentHandler) CloneEnvironment(c *gin.Context) {
+	form := &EnvironmentCloneForm{}
+	if err := c.BindJSON(form); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	src := &models.Environment{}
+	if err := h.GetDB().WithContext(ctx).Preload("Cluster", clusterSensitiveFunc).Preload("Project").
+		First(src, "id = ? and project_id = ?", c.Param(PrimaryKeyName), c.Param("project_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if form.ClusterID == 0 {
+		form.ClusterID = src.ClusterID
+	}
+	if len(form.Kinds) == 0 {
+		form.Kinds = slice.RemoveStrInReplace(append([]string{}, cloneableKinds...), "Secret")
+	}
+	for _, kind := range form.Kinds {
+		if !slice.ContainStr(cloneableKinds, kind) {
+			handlers.NotOK(c, i18n.Errorf(c, "resource kind %s can't be cloned", kind))
+			return
+		}
+	}
+	dstCluster := &models.Cluster{}
+	if err := h.GetDB().WithContext(ctx).Select("id, cluster_name").First(dstCluster, form.ClusterID).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	sameCluster := dstCluster.ID == src.ClusterID
+	if sameCluster && form.Namespace == src.Namespace {
+		handlers.NotOK(c, i18n.Errorf(c, "the cloned environment must use a different namespace"))
+		return
+	}
+
+	h.SetAuditData(c, i18n.Sprintf(c, "clone"), i18n.Sprintf(c, "environment"), src.EnvironmentName+" -> "+form.EnvironmentName)
+	h.SetExtraAuditData(c, models.ResEnvironment, src.ID)
+
+	if err := ValidateEnvironmentNamespace(ctx, h.BaseHandler, h.GetDB().WithContext(ctx), form.Namespace, form.EnvironmentName, dstCluster.ClusterName); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	user, _ := h.GetContextUser(c)
+	dst := models.Environment{
+		EnvironmentName: form.EnvironmentName,
+		Namespace:       form.Namespace,
+		Remark:          src.Remark,
+		MetaType:        src.MetaType,
+		DeletePolicy:    src.DeletePolicy,
+		ResourceQuota:   src.ResourceQuota,
+		LimitRange:      src.LimitRange,
+		SleepSchedule:   src.SleepSchedule,
+		ProjectID:       src.ProjectID,
+		ClusterID:       dstCluster.ID,
+		CreatorID:       user.GetID(),
+	}
+	err := h.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&dst).Error; err != nil {
+			return err
+		}
+		if form.WithUsers {
+			rels := []models.EnvironmentUserRels{}
+			if err := tx.Find(&rels, "environment_id = ?", src.ID).Error; err != nil {
+				return err
+			}
+			for i := range rels {
+				rels[i].ID = 0
+				rels[i].EnvironmentID = dst.ID
+			}
+			if len(rels) > 0 {
+				if err := tx.Create(&rels).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return AfterEnvironmentSave(ctx, h.BaseHandler, tx, &dst)
+	})
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.ModelCache().UpsertEnvironment(dst.ProjectID, dst.ID, dst.EnvironmentName, dstCluster.ClusterName, dst.Namespace)
+	if form.WithUsers {
+		h.flushEnvironmentUsers(ctx, dst.ID)
+	}
+
+	// 资源的克隆需要等待命名空间创建和 PVC 快照, 在 worker 中异步执行
+	args := CloneTaskArgs{
+		SrcCluster:     src.Cluster.ClusterName,
+		SrcNamespace:   src.Namespace,
+		SrcEnvironment: src.EnvironmentName,
+		DstCluster:     dstCluster.ClusterName,
+		DstNamespace:   dst.Namespace,
+		DstEnvironment: dst.EnvironmentName,
+		WithData:       form.WithData && sameCluster,
+	}
+	steps := []workflow.Step{}
+	for _, kind := range cloneableKinds {
+		if !slice.ContainStr(form.Kinds, kind) {
+			continue
+		}
+		step := workflow.Step{
+			Name:     "clone " + kind,
+			Function: TaskFunction_CloneEnvironmentResources,
+			Args:     workflow.ArgsOf(args, kind),
+		}
+		if kind == "PersistentVolumeClaim" && args.WithData {
+			step.Timeout = cloneDataTimeout
+		}
+		steps = append(steps, step)
+	}
+	if args.WithData && slice.ContainStr(form.Kinds, "PersistentVolumeClaim") {
+		steps = append(steps, workflow.Step{
+			Name:     "cleanup snapshots",
+			Function: TaskFunction_CleanupCloneSnapshots,
+			Args:     workflow.ArgsOf(args),
+			Timeout:  cloneDataTimeout,
+		})
+	}
+	result := EnvironmentCloneResult{
+		Environment: dst,
+		Clone:       EnvironmentCloneStatus{Status: workflow.TaskStatusPending, Resources: []CloneResourceResult{}},
+	}
+	if err := h.Workflowcli.SubmitTask(ctx, workflow.Task{
+		Name:  cloneTaskName(dst.ID),
+		Group: TaskGroupEnvironment,
+		Steps: steps,
+		Addtionals: map[string]string{
+			"source": src.EnvironmentName,
+		},
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, result)
+}
+
+// GetEnvironmentCloneStatus 环境克隆状态
+//
+//	@Tags			Environment
+//	@Summary		环境克隆状态
+//	@Description	查询克隆出的环境中资源的克隆任务状态和每个资源的克隆结果
+//	@Accept			json
+//	@Produce		json
+//	@Param			environment_id	path		uint													true	"environment_id"
+//	@Success		200				{object}	handlers.ResponseStruct{Data=EnvironmentCloneStatus}	"result"
+//	@Router			/v1/environment/{environment_id}/clone [get]
+//	@Security		JWT
+func (h *EnvironmentHandler) GetEnvironmentCloneStatus(c *gin.Context) {
+	envid, _ := strconv.Atoi(c.Param(PrimaryKeyName))
+	tasks, err := h.Workflowcli.ListTasks(c.Request.Context(), TaskGroupEnvironment, cloneTaskName(uint(envid)))
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if len(tasks) == 0 {
+		handlers.NotOK(c, i18n.Errorf(c, "environment is not cloned from other environment"))
+		return
+	}
+	// 最近的一次克隆
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].CreationTimestamp.After(tasks[j].CreationTimestamp.Time)
+	})
+	handlers.OK(c, cloneStatusOf(&tasks[0]))
+}
+
+// cloneStatusOf 汇总克隆任务每个步骤返回的资源克隆结果
+func cloneStatusOf(task *workflow.Task) EnvironmentCloneStatus {
+	ret := EnvironmentCloneStatus{Status: workflow.TaskStatusPending, Resources: []CloneResourceResult{}}
+	if task.Status != nil {
+		ret.Status = task.Status.Status
+		ret.Message = task.Status.Message
+		ret.StartTimestamp = task.Status.StartTimestamp
+		ret.FinishTimestamp = task.Status.FinishTimestamp
+	}
+	for _, step := range task.Steps {
+		if step.Status == nil || len(step.Status.Result) == 0 {
+			continue
+		}
+		bts, err := json.Marshal(step.Status.Result[0])
+		if err != nil {
+			continue
+		}
+		results := []CloneResourceResult{}
+		if err := json.Unmarshal(bts, &results); err != nil {
+			continue
+		}
+		ret.Resources = append(ret.Resources, results...)
+	}
+	return ret
+}
+
+// CloneEnvironmentResources 克隆源环境中一种类型的资源, 由 worker 执行
+func CloneEnvironmentResources(ctx context.Context, cs *agents.ClientSet, args CloneTaskArgs, kind string) ([]CloneResourceResult, error) {
+	srcCli, err := cs.ClientOf(ctx, args.SrcCluster)
+	if err != nil {
+		return nil, err
+	}
+	dstCli, err := cs.ClientOf(ctx, args.DstCluster)
+	if err != nil {
+		return nil, err
+	}
+	// 命名空间由环境控制器异步创建
+	if err := WaitNamespace(ctx, dstCli, args.DstNamespace); err != nil {
+		return nil, err
+	}
+	sameCluster := args.SrcCluster == args.DstCluster
+	cloner := &environmentCloner{
+		src:         srcCli,
+		dst:         dstCli,
+		srcNs:       args.SrcNamespace,
+		rewriter:    newCloneRewriter(args.SrcEnvironment, args.DstEnvironment, args.DstNamespace, sameCluster),
+		withData:    args.WithData,
+		sameCluster: sameCluster,
+	}
+	return cloner.cloneKind(ctx, kind), nil
+}
+
+func (h *EnvironmentHandler) flushEnvironmentUsers(ctx context.Context, envid uint) {
+	users := []models.User{}
+	h.GetDB().WithContext(ctx).Preload("SystemRole").
+		Joins("join environment_user_rels on environment_user_rels.user_id = users.id").
+		Find(&users, "environment_user_rels.environment_id = ?", envid)
+	for i := range users {
+		h.ModelCache().FlushUserAuthority(&users[i])
+	}
+}
+
+// WaitNamespace 等待环境控制器创建命名空间
+func WaitNamespace(ctx context.Context, cli agents.Client, namespace string) error {
+	return wait.PollImmediateWithContext(ctx, time.Second, cloneNamespaceTimeout, func(ctx context.Context) (bool, error) {
+		ns := &corev1.Namespace{}
+		if err := cli.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+type environmentCloner struct {
+	src         agents.Client
+	dst         agents.Client
+	srcNs       string
+	rewriter    *cloneRewriter
+	withData    bool
+	sameCluster bool
+}
+
+func newCloneList(kind string) client.ObjectList {
+	switch kind {
+	case "ConfigMap":
+		return &corev1.ConfigMapList{}
+	case "Secret":
+		return &corev1.SecretList{}
+	case "PersistentVolumeClaim":
+		return &corev1.PersistentVolumeClaimList{}
+	case "Service":
+		return &corev1.ServiceList{}
+	case "Deployment":
+		return &appsv1.DeploymentList{}
+	case "StatefulSet":
+		return &appsv1.StatefulSetList{}
+	case "DaemonSet":
+		return &appsv1.DaemonSetList{}
+	case "Ingress":
+		return &networkingv1.IngressList{}
+	default:
+		return nil
+	}
+}
+
+func (c *environmentCloner) cloneKind(ctx context.Context, kind string) []CloneResourceResult {
+	list := newCloneList(kind)
+	if err := c.src.List(ctx, list, client.InNamespace(c.srcNs)); err != nil {
+		return []CloneResourceResult{{Kind: kind, Status: CloneStatusFailed, Message: err.Error()}}
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return []CloneResourceResult{{Kind: kind, Status: CloneStatusFailed, Message: err.Error()}}
+	}
+	ret := make([]CloneResourceResult, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok {
+			continue
+		}
+		ret = append(ret, c.cloneObject(ctx, kind, obj))
+	}
+	return ret
+}
+
+func (c *environmentCloner) cloneObject(ctx context.Context, kind string, obj client.Object) CloneResourceResult {
+	result := CloneResourceResult{Kind: kind, Name: obj.GetName()}
+	if reason := c.rewriter.Skip(obj); reason != "" {
+		result.Status, result.Message = CloneStatusSkipped, reason
+		return result
+	}
+	srcPVC, isPVC := obj.DeepCopyObject().(*corev1.PersistentVolumeClaim)
+	c.rewriter.Rewrite(obj)
+	result.TargetName = obj.GetName()
+
+	if isPVC && c.withData {
+		if !c.sameCluster {
+			result.Message = "data is not copied across clusters"
+		} else {
+			pvc := obj.(*corev1.PersistentVolumeClaim)
+			source, err := snapshotForClone(ctx, c.src, srcPVC, pvc.Namespace, pvc.Name)
+			if err != nil {
+				result.Status, result.Message = CloneStatusFailed, err.Error()
+				return result
+			}
+			pvc.Spec.DataSource = source
+		}
+	}
+
+	if err := c.dst.Create(ctx, obj); err != nil {
+		if errors.IsAlreadyExists(err) {
+			result.Status, result.Message = CloneStatusSkipped, "already exists"
+			return result
+		}
+		result.Status, result.Message = CloneStatusFailed, err.Error()
+		return result
+	}
+	result.Status = CloneStatusCreated
+	return result
+}
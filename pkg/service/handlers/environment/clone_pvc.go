@@ -0,0 +1,232 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package environment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/pointer"
+	"kubegems.io/kubegems/pkg/apis/gems"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	cloneSnapshotTimeout = 2 * time.Minute
+
+	labelCloneSnapshot         = gems.GroupName + "/clone-snapshot"
+	annotationCloneSrcSnapshot = gems.GroupName + "/clone-source-snapshot"
+	annotationCloneSrcContent  = gems.GroupName + "/clone-source-content"
+)
+
+// snapshotForClone 为源 PVC 创建快照并将快照转移到目标命名空间, 返回新 PVC 的数据源.
+// 源和目标的 VolumeSnapshotContent 指向同一个存储快照, 目标使用 Retain 策略,
+// 新 PVC 绑定后由 cleanupCloneSnapshot 删除两边的快照.
+func snapshotForClone(ctx context.Context, cli agents.Client, pvc *corev1.PersistentVolumeClaim, namespace, name string) (*corev1.TypedLocalObjectReference, error) {
+	if pvc.Spec.StorageClassName == nil {
+		return nil, fmt.Errorf("pvc %s has no storageclass", pvc.Name)
+	}
+	sc := &storagev1.StorageClass{}
+	if err := cli.Get(ctx, client.ObjectKey{Name: *pvc.Spec.StorageClassName}, sc); err != nil {
+		return nil, err
+	}
+	classes := &snapshotv1.VolumeSnapshotClassList{}
+	if err := cli.List(ctx, classes); err != nil {
+		return nil, err
+	}
+	var className *string
+	for _, class := range classes.Items {
+		if class.Driver == sc.Provisioner {
+			className = pointer.String(class.Name)
+			break
+		}
+	}
+	if className == nil {
+		return nil, fmt.Errorf("unable to find VolumeSnapshotClass of pvc %s provisioner %s", pvc.Name, sc.Provisioner)
+	}
+
+	snapshotName := fmt.Sprintf("%s-clone-%s", pvc.Name, time.Now().Format("20060102150405"))
+	snapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: snapshotName, Namespace: pvc.Namespace},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source:                  snapshotv1.VolumeSnapshotSource{PersistentVolumeClaimName: pointer.String(pvc.Name)},
+			VolumeSnapshotClassName: className,
+		},
+	}
+	if err := cli.Create(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	if err := wait.PollImmediateWithContext(ctx, 2*time.Second, cloneSnapshotTimeout, func(ctx context.Context) (bool, error) {
+		if err := cli.Get(ctx, client.ObjectKeyFromObject(snapshot), snapshot); err != nil {
+			return false, err
+		}
+		status := snapshot.Status
+		if status == nil {
+			return false, nil
+		}
+		if status.Error != nil && status.Error.Message != nil {
+			return false, fmt.Errorf("snapshot %s: %s", snapshot.Name, *status.Error.Message)
+		}
+		return status.ReadyToUse != nil && *status.ReadyToUse && status.BoundVolumeSnapshotContentName != nil, nil
+	}); err != nil {
+		return nil, fmt.Errorf("wait snapshot of pvc %s: %w", pvc.Name, err)
+	}
+	content := &snapshotv1.VolumeSnapshotContent{}
+	if err := cli.Get(ctx, client.ObjectKey{Name: *snapshot.Status.BoundVolumeSnapshotContentName}, content); err != nil {
+		return nil, err
+	}
+	if content.Status == nil || content.Status.SnapshotHandle == nil {
+		return nil, fmt.Errorf("snapshot content %s has no snapshot handle", content.Name)
+	}
+
+	dstSnapshotName := name + "-clone"
+	dstContent := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%s", namespace, snapshotName)},
+		Spec: snapshotv1.VolumeSnapshotContentSpec{
+			VolumeSnapshotRef:       corev1.ObjectReference{Namespace: namespace, Name: dstSnapshotName},
+			DeletionPolicy:          snapshotv1.VolumeSnapshotContentRetain,
+			Driver:                  content.Spec.Driver,
+			VolumeSnapshotClassName: className,
+			Source:                  snapshotv1.VolumeSnapshotContentSource{SnapshotHandle: content.Status.SnapshotHandle},
+		},
+	}
+	if err := cli.Create(ctx, dstContent); err != nil {
+		return nil, err
+	}
+	dstSnapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dstSnapshotName,
+			Namespace: namespace,
+			Labels:    map[string]string{labelCloneSnapshot: "true"},
+			Annotations: map[string]string{
+				annotationCloneSrcSnapshot: snapshot.Namespace + "/" + snapshot.Name,
+				annotationCloneSrcContent:  content.Name,
+			},
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source:                  snapshotv1.VolumeSnapshotSource{VolumeSnapshotContentName: pointer.String(dstContent.Name)},
+			VolumeSnapshotClassName: className,
+		},
+	}
+	if err := cli.Create(ctx, dstSnapshot); err != nil {
+		return nil, err
+	}
+	return &corev1.TypedLocalObjectReference{
+		APIGroup: pointer.String(snapshotv1.SchemeGroupVersion.Group),
+		Kind:     "VolumeSnapshot",
+		Name:     dstSnapshotName,
+	}, nil
+}
+
+// CleanupCloneSnapshots 等待目标命名空间中由快照创建的 PVC 绑定后删除克隆使用的快照, 由 worker 执行
+func CleanupCloneSnapshots(ctx context.Context, cs *agents.ClientSet, args CloneTaskArgs) ([]CloneResourceResult, error) {
+	cli, err := cs.ClientOf(ctx, args.DstCluster)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := &snapshotv1.VolumeSnapshotList{}
+	if err := cli.List(ctx, snapshots, client.InNamespace(args.DstNamespace), client.HasLabels{labelCloneSnapshot}); err != nil {
+		return nil, err
+	}
+	ret := make([]CloneResourceResult, 0, len(snapshots.Items))
+	for i := range snapshots.Items {
+		snapshot := &snapshots.Items[i]
+		result := CloneResourceResult{
+			Kind:       "VolumeSnapshot",
+			Name:       snapshot.Annotations[annotationCloneSrcSnapshot],
+			TargetName: snapshot.Name,
+			Status:     CloneStatusCleaned,
+		}
+		if err := cleanupCloneSnapshot(ctx, cli, snapshot); err != nil {
+			result.Status, result.Message = CloneStatusFailed, err.Error()
+		}
+		ret = append(ret, result)
+	}
+	return ret, nil
+}
+
+// cleanupCloneSnapshot 目标 PVC 绑定后, 先将源 VolumeSnapshotContent 改为 Retain 并删除源快照,
+// 存储快照只由目标 VolumeSnapshotContent 引用, 再将其改为 Delete 并删除目标快照, 存储快照随之删除
+func cleanupCloneSnapshot(ctx context.Context, cli client.Client, snapshot *snapshotv1.VolumeSnapshot) error {
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := cli.List(ctx, pvcs, client.InNamespace(snapshot.Namespace)); err != nil {
+		return err
+	}
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		source := pvc.Spec.DataSource
+		if source == nil || source.Kind != "VolumeSnapshot" || source.Name != snapshot.Name {
+			continue
+		}
+		if err := wait.PollImmediateWithContext(ctx, 2*time.Second, cloneSnapshotTimeout, func(ctx context.Context) (bool, error) {
+			if err := cli.Get(ctx, client.ObjectKeyFromObject(pvc), pvc); err != nil {
+				return false, err
+			}
+			return pvc.Status.Phase == corev1.ClaimBound, nil
+		}); err != nil {
+			return fmt.Errorf("wait pvc %s bound, snapshot is kept: %w", pvc.Name, err)
+		}
+	}
+
+	if srcContentName := snapshot.Annotations[annotationCloneSrcContent]; srcContentName != "" {
+		if err := setSnapshotContentDeletionPolicy(ctx, cli, srcContentName, snapshotv1.VolumeSnapshotContentRetain); err != nil {
+			return err
+		}
+		if namespace, name, ok := strings.Cut(snapshot.Annotations[annotationCloneSrcSnapshot], "/"); ok {
+			srcSnapshot := &snapshotv1.VolumeSnapshot{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+			if err := cli.Delete(ctx, srcSnapshot); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		srcContent := &snapshotv1.VolumeSnapshotContent{ObjectMeta: metav1.ObjectMeta{Name: srcContentName}}
+		if err := cli.Delete(ctx, srcContent); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	if snapshot.Spec.Source.VolumeSnapshotContentName != nil {
+		if err := setSnapshotContentDeletionPolicy(ctx, cli, *snapshot.Spec.Source.VolumeSnapshotContentName, snapshotv1.VolumeSnapshotContentDelete); err != nil {
+			return err
+		}
+	}
+	if err := cli.Delete(ctx, snapshot); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func setSnapshotContentDeletionPolicy(ctx context.Context, cli client.Client, name string, policy snapshotv1.DeletionPolicy) error {
+	content := &snapshotv1.VolumeSnapshotContent{}
+	if err := cli.Get(ctx, client.ObjectKey{Name: name}, content); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if content.Spec.DeletionPolicy == policy {
+		return nil
+	}
+	patch := client.MergeFrom(content.DeepCopy())
+	content.Spec.DeletionPolicy = policy
+	return cli.Patch(ctx, content, patch)
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package environment
+
+import (
+	"context"
+	"testing"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCleanupCloneSnapshot(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = snapshotv1.AddToScheme(scheme)
+
+	srcSnapshot := &snapshotv1.VolumeSnapshot{ObjectMeta: metav1.ObjectMeta{Namespace: "dev", Name: "data-clone-1"}}
+	srcContent := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: "snapcontent-src"},
+		Spec:       snapshotv1.VolumeSnapshotContentSpec{DeletionPolicy: snapshotv1.VolumeSnapshotContentDelete},
+	}
+	dstContent := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-data-clone-1"},
+		Spec:       snapshotv1.VolumeSnapshotContentSpec{DeletionPolicy: snapshotv1.VolumeSnapshotContentRetain},
+	}
+	dstSnapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test",
+			Name:      "data-clone",
+			Labels:    map[string]string{labelCloneSnapshot: "true"},
+			Annotations: map[string]string{
+				annotationCloneSrcSnapshot: "dev/data-clone-1",
+				annotationCloneSrcContent:  "snapcontent-src",
+			},
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{VolumeSnapshotContentName: pointer.String("test-data-clone-1")},
+		},
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "data"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			DataSource: &corev1.TypedLocalObjectReference{APIGroup: pointer.String(snapshotv1.GroupName), Kind: "VolumeSnapshot", Name: "data-clone"},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(srcSnapshot, srcContent, dstContent, dstSnapshot, pvc).Build()
+
+	ctx := context.Background()
+	if err := cleanupCloneSnapshot(ctx, cli, dstSnapshot); err != nil {
+		t.Fatal(err)
+	}
+	// 两边的快照都删除, 存储快照只由目标 VolumeSnapshotContent 引用且随之删除
+	for _, obj := range []client.Object{srcSnapshot, srcContent, dstSnapshot} {
+		if err := cli.Get(ctx, client.ObjectKeyFromObject(obj), obj); !apierrors.IsNotFound(err) {
+			t.Errorf("%s should be deleted, got err: %v", obj.GetName(), err)
+		}
+	}
+	content := &snapshotv1.VolumeSnapshotContent{}
+	if err := cli.Get(ctx, client.ObjectKeyFromObject(dstContent), content); err != nil {
+		t.Fatal(err)
+	}
+	if content.Spec.DeletionPolicy != snapshotv1.VolumeSnapshotContentDelete {
+		t.Errorf("target content deletion policy = %s, want Delete", content.Spec.DeletionPolicy)
+	}
+}
+
+func TestCloneStatusOf(t *testing.T) {
+	task := &workflow.Task{
+		Status: &workflow.TaskStatus{Status: workflow.TaskStatusRunning},
+		Steps: []workflow.Step{
+			{Status: &workflow.TaskStatus{Result: []interface{}{
+				[]interface{}{map[string]interface{}{"kind": "ConfigMap", "name": "app", "status": CloneStatusCreated}},
+				nil,
+			}}},
+			{},
+		},
+	}
+	status := cloneStatusOf(task)
+	if status.Status != workflow.TaskStatusRunning {
+		t.Errorf("cloneStatusOf() status = %s, want %s", status.Status, workflow.TaskStatusRunning)
+	}
+	if len(status.Resources) != 1 || status.Resources[0].Name != "app" || status.Resources[0].Status != CloneStatusCreated {
+		t.Errorf("cloneStatusOf() resources = %v", status.Resources)
+	}
+}
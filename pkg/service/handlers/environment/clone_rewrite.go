@@ -0,0 +1,246 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package environment
+
+import (
+	"regexp"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubegems.io/kubegems/pkg/apis/gems"
+	"kubegems.io/kubegems/pkg/controller/webhooks"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 克隆时不复制的注解
+var cloneDropAnnotations = []string{
+	corev1.LastAppliedConfigAnnotation,
+	"deployment.kubernetes.io/revision",
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// 集群自动生成的 ConfigMap
+var cloneSkipConfigMaps = []string{"kube-root-ca.crt", "istio-ca-root-cert"}
+
+// cloneRewriter 将源环境的资源改写为目标环境的资源
+type cloneRewriter struct {
+	srcEnv       string
+	dstEnv       string
+	dstNamespace string
+	// 同集群克隆时 ingress 的域名需要避免冲突
+	deconflictHosts bool
+
+	envPattern *regexp.Regexp
+	hosts      map[string]string
+}
+
+func newCloneRewriter(srcEnv, dstEnv, dstNamespace string, deconflictHosts bool) *cloneRewriter {
+	return &cloneRewriter{
+		srcEnv:          srcEnv,
+		dstEnv:          dstEnv,
+		dstNamespace:    dstNamespace,
+		deconflictHosts: deconflictHosts,
+		// 只替换以 - 或 . 分隔的环境名, 避免误改包含环境名的单词
+		envPattern: regexp.MustCompile(`(^|[.-])` + regexp.QuoteMeta(srcEnv) + `($|[.-])`),
+		hosts:      map[string]string{},
+	}
+}
+
+// Name 将名称中的源环境名替换为目标环境名
+func (r *cloneRewriter) Name(name string) string {
+	if r.srcEnv == "" || r.srcEnv == r.dstEnv {
+		return name
+	}
+	return r.envPattern.ReplaceAllString(name, "${1}"+r.dstEnv+"${2}")
+}
+
+// Host 改写域名, 同集群时域名没有变化则加上随机后缀, 同一个域名改写结果相同
+func (r *cloneRewriter) Host(host string) string {
+	if host == "" {
+		return host
+	}
+	if newhost, ok := r.hosts[host]; ok {
+		return newhost
+	}
+	newhost := r.Name(host)
+	if newhost == host && r.deconflictHosts {
+		parts := strings.SplitN(host, ".", 2)
+		if parts[0] != "*" {
+			parts[0] = parts[0] + "-" + webhooks.RandHost("*")
+			newhost = strings.Join(parts, ".")
+		}
+	}
+	r.hosts[host] = newhost
+	return newhost
+}
+
+// Skip 返回不需要克隆的原因
+func (r *cloneRewriter) Skip(obj client.Object) string {
+	if len(obj.GetOwnerReferences()) > 0 {
+		return "managed by " + obj.GetOwnerReferences()[0].Kind
+	}
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		for _, name := range cloneSkipConfigMaps {
+			if o.Name == name {
+				return "generated by cluster"
+			}
+		}
+	case *corev1.Secret:
+		if o.Type == corev1.SecretTypeServiceAccountToken {
+			return "generated by cluster"
+		}
+	}
+	return ""
+}
+
+// Rewrite 清理源资源的状态并改写名称, 命名空间和引用
+func (r *cloneRewriter) Rewrite(obj client.Object) {
+	obj.SetName(r.Name(obj.GetName()))
+	obj.SetNamespace(r.dstNamespace)
+	obj.SetResourceVersion("")
+	obj.SetUID("")
+	obj.SetGeneration(0)
+	obj.SetSelfLink("")
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetManagedFields(nil)
+	obj.SetOwnerReferences(nil)
+	obj.SetFinalizers(nil)
+	if labels := obj.GetLabels(); labels != nil {
+		if _, ok := labels[gems.LabelEnvironment]; ok {
+			labels[gems.LabelEnvironment] = r.dstEnv
+		}
+	}
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		for _, key := range cloneDropAnnotations {
+			delete(annotations, key)
+		}
+	}
+
+	switch o := obj.(type) {
+	case *corev1.Service:
+		o.Spec.ClusterIP = ""
+		o.Spec.ClusterIPs = nil
+		o.Spec.HealthCheckNodePort = 0
+		for i := range o.Spec.Ports {
+			o.Spec.Ports[i].NodePort = 0
+		}
+		o.Status = corev1.ServiceStatus{}
+	case *corev1.PersistentVolumeClaim:
+		o.Spec.VolumeName = ""
+		o.Spec.DataSource = nil
+		o.Spec.DataSourceRef = nil
+		o.Status = corev1.PersistentVolumeClaimStatus{}
+	case *appsv1.Deployment:
+		r.podSpec(&o.Spec.Template.Spec)
+		o.Status = appsv1.DeploymentStatus{}
+	case *appsv1.StatefulSet:
+		o.Spec.ServiceName = r.Name(o.Spec.ServiceName)
+		r.podSpec(&o.Spec.Template.Spec)
+		o.Status = appsv1.StatefulSetStatus{}
+	case *appsv1.DaemonSet:
+		r.podSpec(&o.Spec.Template.Spec)
+		o.Status = appsv1.DaemonSetStatus{}
+	case *networkingv1.Ingress:
+		r.ingress(o)
+	}
+}
+
+func (r *cloneRewriter) podSpec(spec *corev1.PodSpec) {
+	for i := range spec.Volumes {
+		v := &spec.Volumes[i]
+		switch {
+		case v.ConfigMap != nil:
+			v.ConfigMap.Name = r.Name(v.ConfigMap.Name)
+		case v.Secret != nil:
+			v.Secret.SecretName = r.Name(v.Secret.SecretName)
+		case v.PersistentVolumeClaim != nil:
+			v.PersistentVolumeClaim.ClaimName = r.Name(v.PersistentVolumeClaim.ClaimName)
+		case v.Projected != nil:
+			for j := range v.Projected.Sources {
+				source := &v.Projected.Sources[j]
+				if source.ConfigMap != nil {
+					source.ConfigMap.Name = r.Name(source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					source.Secret.Name = r.Name(source.Secret.Name)
+				}
+			}
+		}
+	}
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			r.container(&containers[i])
+		}
+	}
+}
+
+func (r *cloneRewriter) container(c *corev1.Container) {
+	for i := range c.EnvFrom {
+		from := &c.EnvFrom[i]
+		if from.ConfigMapRef != nil {
+			from.ConfigMapRef.Name = r.Name(from.ConfigMapRef.Name)
+		}
+		if from.SecretRef != nil {
+			from.SecretRef.Name = r.Name(from.SecretRef.Name)
+		}
+	}
+	for i := range c.Env {
+		from := c.Env[i].ValueFrom
+		if from == nil {
+			continue
+		}
+		if from.ConfigMapKeyRef != nil {
+			from.ConfigMapKeyRef.Name = r.Name(from.ConfigMapKeyRef.Name)
+		}
+		if from.SecretKeyRef != nil {
+			from.SecretKeyRef.Name = r.Name(from.SecretKeyRef.Name)
+		}
+	}
+}
+
+func (r *cloneRewriter) ingress(ingress *networkingv1.Ingress) {
+	backend := func(b *networkingv1.IngressBackend) {
+		if b != nil && b.Service != nil {
+			b.Service.Name = r.Name(b.Service.Name)
+		}
+	}
+	backend(ingress.Spec.DefaultBackend)
+	for i := range ingress.Spec.Rules {
+		rule := &ingress.Spec.Rules[i]
+		rule.Host = r.Host(rule.Host)
+		if rule.HTTP == nil {
+			continue
+		}
+		for j := range rule.HTTP.Paths {
+			backend(&rule.HTTP.Paths[j].Backend)
+		}
+	}
+	for i := range ingress.Spec.TLS {
+		tls := &ingress.Spec.TLS[i]
+		tls.SecretName = r.Name(tls.SecretName)
+		for j := range tls.Hosts {
+			tls.Hosts[j] = r.Host(tls.Hosts[j])
+		}
+	}
+	ingress.Status = networkingv1.IngressStatus{}
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package environment
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubegems.io/kubegems/pkg/apis/gems"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCloneRewriterName(t *testing.T) {
+	r := newCloneRewriter("dev", "test", "ns-test", false)
+	tests := map[string]string{
+		"app-dev":        "app-test",
+		"dev":            "test",
+		"dev-app.dev.io": "test-app.test.io",
+		"devops":         "devops",
+		"app-devops":     "app-devops",
+	}
+	for name, want := range tests {
+		if got := r.Name(name); got != want {
+			t.Errorf("Name(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCloneRewriterHost(t *testing.T) {
+	r := newCloneRewriter("dev", "test", "ns-test", true)
+	if got := r.Host("app.dev.example.com"); got != "app.test.example.com" {
+		t.Errorf("Host() = %s, want rewritten env name", got)
+	}
+	host := r.Host("app.example.com")
+	if !strings.HasPrefix(host, "app-") || !strings.HasSuffix(host, ".example.com") || len(host) != len("app-xxxxx.example.com") {
+		t.Errorf("Host() = %s, want deconflicted host", host)
+	}
+	if again := r.Host("app.example.com"); again != host {
+		t.Errorf("Host() = %s, want same result %s", again, host)
+	}
+}
+
+func TestCloneRewriterRewrite(t *testing.T) {
+	r := newCloneRewriter("dev", "test", "ns-test", true)
+
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "app-dev",
+			Namespace:       "ns-dev",
+			ResourceVersion: "1",
+			UID:             "uid",
+			Labels:          map[string]string{gems.LabelEnvironment: "dev"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{
+						{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "config-dev"}}}},
+					},
+					Containers: []corev1.Container{{
+						Name:    "app",
+						Image:   "app:v1",
+						EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "secret-dev"}}}},
+					}},
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 1},
+	}
+	r.Rewrite(deploy)
+	if deploy.Name != "app-test" || deploy.Namespace != "ns-test" || deploy.ResourceVersion != "" || deploy.UID != "" {
+		t.Errorf("unexpected metadata %v", deploy.ObjectMeta)
+	}
+	if deploy.Labels[gems.LabelEnvironment] != "test" {
+		t.Errorf("environment label = %s", deploy.Labels[gems.LabelEnvironment])
+	}
+	podspec := deploy.Spec.Template.Spec
+	if podspec.Volumes[0].ConfigMap.Name != "config-test" || podspec.Containers[0].EnvFrom[0].SecretRef.Name != "secret-test" {
+		t.Errorf("references not rewritten: %v", podspec)
+	}
+	if podspec.Containers[0].Image != "app:v1" || deploy.Status.Replicas != 0 {
+		t.Errorf("unexpected image or status")
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:  "10.0.0.1",
+			ClusterIPs: []string{"10.0.0.1"},
+			Ports:      []corev1.ServicePort{{Port: 80, NodePort: 30080}},
+		},
+	}
+	r.Rewrite(svc)
+	if svc.Spec.ClusterIP != "" || svc.Spec.ClusterIPs != nil || svc.Spec.Ports[0].NodePort != 0 {
+		t.Errorf("service not reset: %v", svc.Spec)
+	}
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: networkingv1.IngressSpec{
+			TLS: []networkingv1.IngressTLS{{Hosts: []string{"app.example.com"}, SecretName: "tls-dev"}},
+			Rules: []networkingv1.IngressRule{{
+				Host: "app.example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:    "/",
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "app-dev"}},
+					}},
+				}},
+			}},
+		},
+	}
+	r.Rewrite(ingress)
+	rule := ingress.Spec.Rules[0]
+	if rule.Host == "app.example.com" || ingress.Spec.TLS[0].Hosts[0] != rule.Host {
+		t.Errorf("ingress host not deconflicted: %s, tls %s", rule.Host, ingress.Spec.TLS[0].Hosts[0])
+	}
+	if ingress.Spec.TLS[0].SecretName != "tls-test" || rule.HTTP.Paths[0].Backend.Service.Name != "app-test" {
+		t.Errorf("ingress references not rewritten")
+	}
+}
+
+func TestCloneRewriterSkip(t *testing.T) {
+	r := newCloneRewriter("dev", "test", "ns-test", false)
+	tests := []struct {
+		name string
+		obj  client.Object
+		skip bool
+	}{
+		{name: "root ca", obj: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt"}}, skip: true},
+		{name: "token", obj: &corev1.Secret{Type: corev1.SecretTypeServiceAccountToken}, skip: true},
+		{name: "owned", obj: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Kind: "Ingress"}}}}, skip: true},
+		{name: "config", obj: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Skip(tt.obj) != ""; got != tt.skip {
+				t.Errorf("Skip() = %v, want %v", got, tt.skip)
+			}
+		})
+	}
+}
@@ -266,6 +266,16 @@ func AfterEnvironmentSave(ctx context.Context, h base.BaseHandler, tx *gorm.DB,
 	if len(limitRange) > 0 {
 		spec.LimitRage = limitRange
 	}
+	if len(env.SleepSchedule) > 0 && string(env.SleepSchedule) != "null" {
+		schedule := &v1beta1.SleepSchedule{}
+		if e := json.Unmarshal(env.SleepSchedule, schedule); e != nil {
+			return e
+		}
+		if e := ValidateSleepSchedule(ctx, schedule); e != nil {
+			return e
+		}
+		spec.SleepSchedule = schedule
+	}
 
 	if e := createOrUpdateEnvironment(ctx, h, cluster.ClusterName, env.EnvironmentName, spec); e != nil {
 		return e
@@ -736,13 +746,16 @@ func (h *EnvironmentHandler) EnvironmentObservabilityDetails(c *gin.Context) {
 
 	for _, v := range alertrules {
 		var key string
-		if v.AlertType == prometheus.AlertTypeMonitor {
+		switch v.AlertType {
+		case prometheus.AlertTypeMonitor:
 			if v.PromqlGenerator != nil {
 				key = v.PromqlGenerator.Resource
 			} else {
 				key = "raw promql"
 			}
-		} else {
+		case prometheus.AlertTypeAnomaly:
+			key = "anomaly"
+		default:
 			key = "logging"
 		}
 		if count, ok := ret.AlertResourceMap[key]; ok {
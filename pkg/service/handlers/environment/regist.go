@@ -17,10 +17,12 @@ package environment
 import (
 	"github.com/gin-gonic/gin"
 	"kubegems.io/kubegems/pkg/service/handlers/base"
+	"kubegems.io/kubegems/pkg/utils/workflow"
 )
 
 type EnvironmentHandler struct {
 	base.BaseHandler
+	Workflowcli workflow.Client
 }
 
 func (h *EnvironmentHandler) RegistRouter(rg *gin.RouterGroup) {
@@ -36,7 +38,12 @@ func (h *EnvironmentHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.DELETE("/environment/:environment_id/user/:user_id", h.CheckByEnvironmentID, h.DeleteEnvironmentUser)
 
 	rg.POST("/environment/:environment_id/action/networkisolate", h.CheckByEnvironmentID, h.EnvironmentSwitch)
+	rg.POST("/environment/:environment_id/action/sleep", h.CheckByEnvironmentID, h.SleepEnvironment)
+	rg.POST("/environment/:environment_id/action/wake", h.CheckByEnvironmentID, h.WakeEnvironment)
+	rg.GET("/environment/:environment_id/sleep", h.CheckByEnvironmentID, h.GetEnvironmentSleepStatus)
 	rg.GET("/environment/:environment_id/resources", h.CheckByEnvironmentID, h.GetEnvironmentResource)
+	rg.POST("/project/:project_id/environment/:environment_id/clone", h.CheckByProjectID, h.CloneEnvironment)
+	rg.GET("/environment/:environment_id/clone", h.CheckByEnvironmentID, h.GetEnvironmentCloneStatus)
 
 	rg.GET("/environment/:environment_id/observability", h.CheckByEnvironmentID, h.EnvironmentObservabilityDetails)
 }
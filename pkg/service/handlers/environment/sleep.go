@@ -0,0 +1,136 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package environment
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ValidateSleepSchedule 校验定时休眠配置
+func ValidateSleepSchedule(ctx context.Context, schedule *v1beta1.SleepSchedule) error {
+	if schedule.Sleep == "" {
+		return i18n.Errorf(ctx, "sleep schedule is required")
+	}
+	for _, spec := range []string{schedule.Sleep, schedule.Wake} {
+		if spec == "" {
+			continue
+		}
+		if _, err := cron.ParseStandard(spec); err != nil {
+			return i18n.Errorf(ctx, "invalid cron expression %s: %v", spec, err)
+		}
+	}
+	if schedule.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			return i18n.Errorf(ctx, "invalid time zone %s", schedule.TimeZone)
+		}
+	}
+	return nil
+}
+
+// @Tags			Environment
+// @Summary		立即休眠环境
+// @Description	立即休眠环境, 将工作负载副本数缩容至0并暂停CronJob, 直到下一次定时唤醒或手动唤醒
+// @Accept			json
+// @Produce		json
+// @Param			environment_id	path		uint												true	"environment_id"
+// @Success		200				{object}	handlers.ResponseStruct{Data=v1beta1.Environment}	"object"
+// @Router			/v1/environment/{environment_id}/action/sleep [post]
+// @Security		JWT
+func (h *EnvironmentHandler) SleepEnvironment(c *gin.Context) {
+	h.setSleepAnnotation(c, i18n.Sprintf(c, "sleep"), gemlabels.AnnotationSleepAt)
+}
+
+// @Tags			Environment
+// @Summary		立即唤醒环境
+// @Description	立即唤醒环境, 恢复休眠前的工作负载副本数和CronJob状态, 直到下一次定时休眠
+// @Accept			json
+// @Produce		json
+// @Param			environment_id	path		uint												true	"environment_id"
+// @Success		200				{object}	handlers.ResponseStruct{Data=v1beta1.Environment}	"object"
+// @Router			/v1/environment/{environment_id}/action/wake [post]
+// @Security		JWT
+func (h *EnvironmentHandler) WakeEnvironment(c *gin.Context) {
+	h.setSleepAnnotation(c, i18n.Sprintf(c, "wake"), gemlabels.AnnotationWakeAt)
+}
+
+// setSleepAnnotation 在环境CR上记录手动休眠/唤醒时间, 由控制器执行
+func (h *EnvironmentHandler) setSleepAnnotation(c *gin.Context, action, annotation string) {
+	var env models.Environment
+	ctx := c.Request.Context()
+	if e := h.GetDB().WithContext(ctx).Preload("Cluster", clusterSensitiveFunc).First(&env, "id = ?", c.Param(PrimaryKeyName)).Error; e != nil {
+		handlers.NotOK(c, e)
+		return
+	}
+	h.SetAuditData(c, action, i18n.Sprintf(ctx, "environment"), env.EnvironmentName)
+
+	envobj := &v1beta1.Environment{}
+	err := h.Execute(ctx, env.Cluster.ClusterName, func(ctx context.Context, cli agents.Client) error {
+		if err := cli.Get(ctx, client.ObjectKey{Name: env.EnvironmentName}, envobj); err != nil {
+			return err
+		}
+		if envobj.Annotations == nil {
+			envobj.Annotations = map[string]string{}
+		}
+		envobj.Annotations[annotation] = time.Now().Format(time.RFC3339)
+		return cli.Update(ctx, envobj)
+	})
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, envobj)
+}
+
+// @Tags			Environment
+// @Summary		环境休眠状态
+// @Description	获取环境休眠状态及休眠释放的资源
+// @Accept			json
+// @Produce		json
+// @Param			environment_id	path		uint															true	"environment_id"
+// @Success		200				{object}	handlers.ResponseStruct{Data=v1beta1.EnvironmentSleepStatus}	"object"
+// @Router			/v1/environment/{environment_id}/sleep [get]
+// @Security		JWT
+func (h *EnvironmentHandler) GetEnvironmentSleepStatus(c *gin.Context) {
+	var env models.Environment
+	ctx := c.Request.Context()
+	if e := h.GetDB().WithContext(ctx).Preload("Cluster", clusterSensitiveFunc).First(&env, "id = ?", c.Param(PrimaryKeyName)).Error; e != nil {
+		handlers.NotOK(c, e)
+		return
+	}
+	envobj := &v1beta1.Environment{}
+	err := h.Execute(ctx, env.Cluster.ClusterName, func(ctx context.Context, cli agents.Client) error {
+		return cli.Get(ctx, client.ObjectKey{Name: env.EnvironmentName}, envobj)
+	})
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	status := envobj.Status.Sleep
+	if status == nil {
+		status = &v1beta1.EnvironmentSleepStatus{}
+	}
+	handlers.OK(c, status)
+}
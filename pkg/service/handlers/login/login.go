@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -28,17 +29,27 @@ import (
 	auth "kubegems.io/kubegems/pkg/service/aaa/auth"
 	"kubegems.io/kubegems/pkg/service/handlers"
 	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/service/models/cache"
 	"kubegems.io/kubegems/pkg/utils/jwt"
 )
 
 type LoginForm struct {
 	Username string `form:"username" json:"username" binding:"required"`
 	Password string `form:"password" json:"password" binding:"required"`
+	// 开启两步验证后需要提供验证码或者恢复码
+	OTP string `form:"otp" json:"otp"`
+}
+
+type twoFactorEnrollForm struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"`
 }
 type OAuthHandler struct {
 	DB         *gorm.DB
 	AuthModule auth.AuthenticateModule
 	JWTOptions *jwt.Options
+	ModelCache cache.ModelCache
 }
 
 // FakeLogin 实际上这个没有用的，只是为了生成swagger文档
@@ -56,6 +67,68 @@ func (h *OAuthHandler) LoginHandler(c *gin.Context) {
 	h.commonLogin(c)
 }
 
+// LoginTwoFactorEnroll 安全策略要求开启两步验证但尚未绑定的账号, 在登录前生成验证器密钥
+//
+//	@Summary		登录前绑定两步验证
+//	@Tags			AAAAA
+//	@Description	安全策略要求开启两步验证但尚未绑定的账号, 校验密码后生成验证器密钥
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		twoFactorEnrollForm										true	"表单"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=auth.TOTPEnrollment}	"密钥"
+//	@Router			/v1/login/2fa/enroll [post]
+func (h *OAuthHandler) LoginTwoFactorEnroll(c *gin.Context) {
+	ctx := c.Request.Context()
+	form := &twoFactorEnrollForm{}
+	if err := c.BindJSON(form); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	accountUtil := &auth.AccountLoginUtil{Name: auth.AccountLoginName, DB: h.DB}
+	user, err := accountUtil.Authenticate(ctx, form.Username, form.Password)
+	if err != nil {
+		handlers.Unauthorized(c, i18n.Errorf(c, "username or password error"))
+		return
+	}
+	enrollment, err := auth.EnrollTOTP(ctx, h.DB, user)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, enrollment)
+}
+
+// LoginTwoFactorActivate 登录前校验验证码并开启两步验证
+//
+//	@Summary		登录前开启两步验证
+//	@Tags			AAAAA
+//	@Description	校验密码和验证器中的验证码后开启两步验证, 返回恢复码, 恢复码仅返回这一次
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		twoFactorEnrollForm							true	"表单"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=[]string}	"恢复码"
+//	@Router			/v1/login/2fa/activate [post]
+func (h *OAuthHandler) LoginTwoFactorActivate(c *gin.Context) {
+	ctx := c.Request.Context()
+	form := &twoFactorEnrollForm{}
+	if err := c.BindJSON(form); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	accountUtil := &auth.AccountLoginUtil{Name: auth.AccountLoginName, DB: h.DB}
+	user, err := accountUtil.Authenticate(ctx, form.Username, form.Password)
+	if err != nil {
+		handlers.Unauthorized(c, i18n.Errorf(c, "username or password error"))
+		return
+	}
+	codes, err := auth.ActivateTOTP(ctx, h.DB, user, form.Code)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, codes)
+}
+
 // @Summary		获取OAUTH登录地址
 // @Description	获取OAUTH登录地址
 // @Tags			AAAAA
@@ -81,7 +154,12 @@ func (h *OAuthHandler) GetOauthAddr(c *gin.Context) {
 		handlers.NotOK(c, i18n.Errorf(c, "source not match"))
 		return
 	}
-	handlers.OK(c, sourceUtil.LoginAddr())
+	addr := sourceUtil.LoginAddr()
+	if addr == "" {
+		handlers.NotOK(c, i18n.Errorf(c, "failed to get login address of source %s", source))
+		return
+	}
+	handlers.OK(c, addr)
 }
 
 // @Summary		OAUTH登录callback
@@ -96,27 +174,92 @@ func (h *OAuthHandler) GetOauthToken(c *gin.Context) {
 	h.commonLogin(c)
 }
 
+// @Summary		SAML登录ACS
+// @Description	SAML登录ACS, IdP 以 HTTP-POST 方式提交 SAMLResponse, 登录成功后重定向到登录源配置的 redirectURL, token 在 URL fragment 中
+// @Tags			AAAAA
+// @Accept			x-www-form-urlencoded
+// @Produce		json
+// @Param			SAMLResponse	formData	string	true	"SAMLResponse"
+// @Param			RelayState		formData	string	true	"RelayState"
+// @Success		302				{string}	string	"重定向"
+// @Router			/v1/saml/acs [post]
+func (h *OAuthHandler) SAMLACS(c *gin.Context) {
+	ctx := c.Request.Context()
+	source, err := h.AuthModule.GetNameFromState(c.PostForm("RelayState"))
+	if err != nil {
+		handlers.Unauthorized(c, i18n.Errorf(c, "failed to get auth source"))
+		return
+	}
+	authSource := &models.AuthSource{}
+	if err := h.DB.WithContext(ctx).First(authSource, "name = ? and kind = ? and enabled = ?", source, "SAML", true).Error; err != nil {
+		handlers.Unauthorized(c, i18n.Error(c, "auth source not exists or not enabled"))
+		return
+	}
+	cred := &auth.Credential{Code: c.PostForm("SAMLResponse"), Source: source}
+	token, err := h.login(ctx, cred)
+	if err != nil {
+		handlers.Unauthorized(c, err)
+		return
+	}
+	redirect, err := url.Parse(authSource.Config.RedirectURL)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	// token 放在 fragment 中, 不会发送到服务端, 也不会出现在访问日志和 Referer 中
+	redirect.Fragment = "token=" + token
+	c.Redirect(http.StatusFound, redirect.String())
+}
+
+var errUsernameConflict = errors.New("username is used by another user")
+
+// getOrCreateUser 外部登录源的用户按 登录源+外部唯一标识 匹配, 避免不同登录源中的同名用户登录为同一个用户
 func (h *OAuthHandler) getOrCreateUser(ctx context.Context, uinfo *auth.UserInfo) (*models.User, error) {
+	db := h.DB.WithContext(ctx)
 	u := &models.User{}
-	if err := h.DB.WithContext(ctx).First(u, "username = ?", uinfo.Username).Error; err != nil {
-		if err != gorm.ErrRecordNotFound {
-			return nil, err
+	if uinfo.Source == "" || uinfo.Source == auth.AccountLoginName {
+		err := db.First(u, "username = ?", uinfo.Username).Error
+		return u, err
+	}
+	subject := uinfo.Subject
+	if subject == "" {
+		subject = uinfo.Username
+	}
+	err := db.First(u, "source = ? and source_subject = ?", uinfo.Source, subject).Error
+	if err == nil {
+		return u, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	err = db.First(u, "username = ?", uinfo.Username).Error
+	switch {
+	case err == nil:
+		// 记录外部唯一标识之前, 由同一登录源创建的用户
+		if u.Source != uinfo.Source || u.SourceSubject != "" {
+			return nil, errUsernameConflict
 		}
-		active := true
-		newUser := &models.User{
-			Username:     uinfo.Username,
-			Email:        uinfo.Email,
-			IsActive:     &active,
-			Source:       uinfo.Source,
-			SourceVendor: uinfo.Vendor,
-			// todo: get systemrole via code from db
-			SystemRoleID: 2,
+		u.SourceSubject = subject
+		if err := db.Model(u).Update("source_subject", subject).Error; err != nil {
+			return nil, err
 		}
-		err := h.DB.WithContext(ctx).Create(newUser).Error
-		return newUser, err
-	} else {
 		return u, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+	active := true
+	newUser := &models.User{
+		Username:      uinfo.Username,
+		Email:         uinfo.Email,
+		IsActive:      &active,
+		Source:        uinfo.Source,
+		SourceVendor:  uinfo.Vendor,
+		SourceSubject: subject,
+		// todo: get systemrole via code from db
+		SystemRoleID: 2,
 	}
+	err = db.Create(newUser).Error
+	return newUser, err
 }
 
 func (h *OAuthHandler) commonLogin(c *gin.Context) {
@@ -150,31 +293,51 @@ func (h *OAuthHandler) commonLogin(c *gin.Context) {
 		}
 		cred.Source = source
 	}
+	token, err := h.login(ctx, cred)
+	if err != nil {
+		handlers.Unauthorized(c, err)
+		return
+	}
+	data := map[string]string{"token": token}
+	handlers.OK(c, data)
+}
+
+// login 校验凭据, 同步用户和 IdP 组对应的角色, 并签发 JWT
+func (h *OAuthHandler) login(ctx context.Context, cred *auth.Credential) (string, error) {
 	authenticator := h.AuthModule.GetAuthenticateModule(ctx, cred.Source)
 	if authenticator == nil {
-		handlers.Unauthorized(c, i18n.Errorf(c, "auth source not exist"))
-		return
+		return "", i18n.Errorf(ctx, "auth source not exist")
 	}
 	if cred.Source != authenticator.GetName() {
-		handlers.Unauthorized(c, i18n.Error(c, "auth source not exists or not enabled"))
-		return
+		return "", i18n.Error(ctx, "auth source not exists or not enabled")
 	}
 	uinfo, err := authenticator.GetUserInfo(ctx, cred)
 	if err != nil {
 		log.Error(err, "get user info", "source", cred.Source, "username", cred.Username)
-		handlers.Unauthorized(c, err)
-		return
+		return "", err
 	}
 	uinternel, err := h.getOrCreateUser(ctx, uinfo)
 	if err != nil {
 		log.Error(err, "update user", "username", uinfo.Username)
-		handlers.Unauthorized(c, i18n.Error(c, "system error"))
-		return
+		if errors.Is(err, errUsernameConflict) {
+			return "", i18n.Errorf(ctx, "username %s is already used by another user", uinfo.Username)
+		}
+		return "", i18n.Error(ctx, "system error")
 	}
 	now := time.Now()
 	uinternel.LastLoginAt = &now
 	h.DB.WithContext(ctx).Updates(uinternel)
 
+	// 外部登录源的用户, 每次登录时根据组同步角色
+	if cred.Source != auth.AccountLoginName {
+		changed, err := auth.SyncGroupRoles(ctx, h.DB, uinternel, uinfo.Groups)
+		if err != nil {
+			log.Error(err, "sync group roles", "username", uinternel.Username, "groups", uinfo.Groups)
+		} else if changed && h.ModelCache != nil {
+			h.ModelCache.FlushUserAuthority(uinternel)
+		}
+	}
+
 	userpayload := &models.User{
 		Username:     uinternel.Username,
 		Email:        uinternel.Email,
@@ -186,9 +349,7 @@ func (h *OAuthHandler) commonLogin(c *gin.Context) {
 	token, _, err := h.JWTOptions.ToJWT().GenerateToken(userpayload,
 		userpayload.Username, uinternel.SystemRoleID == 1, h.JWTOptions.Expire)
 	if err != nil {
-		handlers.Unauthorized(c, err)
-		return
+		return "", err
 	}
-	data := map[string]string{"token": token}
-	handlers.OK(c, data)
+	return token, nil
 }
@@ -0,0 +1,217 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lokilog
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+func (l *LogHandler) getLogExport(c *gin.Context) (*models.LogExport, error) {
+	e := &models.LogExport{}
+	if err := l.GetDB().WithContext(c.Request.Context()).
+		First(e, "cluster = ? and namespace = ? and name = ?", c.Param("cluster"), c.Param("namespace"), c.Param("name")).Error; err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// hideLogExportSecret 不返回对象存储的密钥
+func hideLogExportSecret(e *models.LogExport) *models.LogExport {
+	e.Destination.SecretAccessKey = ""
+	return e
+}
+
+// ListLogExport 定时日志导出列表
+//
+//	@Tags			Log
+//	@Summary		定时日志导出列表
+//	@Description	定时日志导出列表
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string												true	"cluster"
+//	@Param			namespace	path		string												true	"namespace"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.LogExport}	"resp"
+//	@Router			/v1/log/cluster/{cluster}/namespaces/{namespace}/exports [get]
+//	@Security		JWT
+func (l *LogHandler) ListLogExport(c *gin.Context) {
+	ret := []*models.LogExport{}
+	if err := l.GetDB().WithContext(c.Request.Context()).Order("name").
+		Find(&ret, "cluster = ? and namespace = ?", c.Param("cluster"), c.Param("namespace")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	for _, e := range ret {
+		hideLogExportSecret(e)
+	}
+	handlers.OK(c, ret)
+}
+
+// GetLogExport 定时日志导出详情
+//
+//	@Tags			Log
+//	@Summary		定时日志导出详情
+//	@Description	定时日志导出详情, 包括导出进度和最后一次执行的错误
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string											true	"cluster"
+//	@Param			namespace	path		string											true	"namespace"
+//	@Param			name		path		string											true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.LogExport}	"resp"
+//	@Router			/v1/log/cluster/{cluster}/namespaces/{namespace}/exports/{name} [get]
+//	@Security		JWT
+func (l *LogHandler) GetLogExport(c *gin.Context) {
+	e, err := l.getLogExport(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, hideLogExportSecret(e))
+}
+
+// CreateLogExport 创建定时日志导出
+//
+//	@Tags			Log
+//	@Summary		创建定时日志导出
+//	@Description	创建定时日志导出, 按 cron 定时将日志按小时导出为 gzip 压缩的 ndjson 文件, 保存到 s3 兼容的对象存储或者 pvc
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string											true	"cluster"
+//	@Param			namespace	path		string											true	"namespace"
+//	@Param			form		body		models.LogExport								true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.LogExport}	"resp"
+//	@Router			/v1/log/cluster/{cluster}/namespaces/{namespace}/exports [post]
+//	@Security		JWT
+func (l *LogHandler) CreateLogExport(c *gin.Context) {
+	req := &models.LogExport{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = 0
+	req.Cluster = c.Param("cluster")
+	req.Namespace = c.Param("namespace")
+	req.ExportedUntil = nil
+	req.LastRunAt = nil
+	req.LastError = ""
+	if err := req.Validate(); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if user, exist := l.GetContextUser(c); exist {
+		req.Creator = user.GetUsername()
+	}
+	l.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	l.SetAuditData(c, i18n.Sprintf(c, "create"), i18n.Sprintf(c, "log export"), req.Name)
+
+	if err := l.GetDB().WithContext(c.Request.Context()).Create(req).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, hideLogExportSecret(req))
+}
+
+// UpdateLogExport 修改定时日志导出
+//
+//	@Tags			Log
+//	@Summary		修改定时日志导出
+//	@Description	修改定时日志导出, 密钥为空时保持不变, 修改开始时间后重新开始导出
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string											true	"cluster"
+//	@Param			namespace	path		string											true	"namespace"
+//	@Param			name		path		string											true	"name"
+//	@Param			form		body		models.LogExport								true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.LogExport}	"resp"
+//	@Router			/v1/log/cluster/{cluster}/namespaces/{namespace}/exports/{name} [put]
+//	@Security		JWT
+func (l *LogHandler) UpdateLogExport(c *gin.Context) {
+	e, err := l.getLogExport(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := &models.LogExport{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if req.Destination.SecretAccessKey == "" {
+		req.Destination.SecretAccessKey = e.Destination.SecretAccessKey
+	}
+	req.ID = e.ID
+	req.Cluster = e.Cluster
+	req.Namespace = e.Namespace
+	req.Name = e.Name
+	req.Creator = e.Creator
+	req.ExportedUntil = e.ExportedUntil
+	if !timeEqual(req.StartAt, e.StartAt) {
+		req.ExportedUntil = nil
+	}
+	if err := req.Validate(); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	l.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	l.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "log export"), req.Name)
+
+	if err := l.GetDB().WithContext(c.Request.Context()).
+		Select("description", "query", "schedule", "destination", "retention", "start_at", "enabled", "exported_until").
+		Updates(req).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, hideLogExportSecret(req))
+}
+
+// DeleteLogExport 删除定时日志导出
+//
+//	@Tags			Log
+//	@Summary		删除定时日志导出
+//	@Description	删除定时日志导出, 已经导出的文件不会删除
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			name		path		string									true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/log/cluster/{cluster}/namespaces/{namespace}/exports/{name} [delete]
+//	@Security		JWT
+func (l *LogHandler) DeleteLogExport(c *gin.Context) {
+	e, err := l.getLogExport(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	l.SetExtraAuditDataByClusterNamespace(c, e.Cluster, e.Namespace)
+	l.SetAuditData(c, i18n.Sprintf(c, "delete"), i18n.Sprintf(c, "log export"), e.Name)
+
+	if err := l.GetDB().WithContext(c.Request.Context()).Delete(e).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -30,6 +30,7 @@ import (
 	"kubegems.io/kubegems/pkg/service/handlers"
 	"kubegems.io/kubegems/pkg/utils"
 	"kubegems.io/kubegems/pkg/utils/loki"
+	"kubegems.io/kubegems/pkg/utils/slice"
 )
 
 const LokiExportDir = "lokiExport"
@@ -37,7 +38,7 @@ const LokiExportDir = "lokiExport"
 // QueryRange 获取loki查询结果
 //	@Tags			Log
 //	@Summary		获取loki查询结果
-//	@Description	获取loki查询结果
+//	@Description	获取loki查询结果, 识别日志中的 trace id 并返回 trace 详情的链接
 //	@Accept			json
 //	@Produce		json
 //	@Param			cluster_name	path		string									true	"cluster_name"
@@ -87,6 +88,7 @@ func (l *LogHandler) QueryRange(c *gin.Context) {
 	chartResult := make(map[string]interface{})
 	podResults := []interface{}{}
 	podSetStr := ""
+	traceIDs := []string{}
 	resultType := queryData.ResultType
 	results := queryData.Result
 	if resultType == loki.ResultTypeMatrix {
@@ -164,6 +166,13 @@ func (l *LogHandler) QueryRange(c *gin.Context) {
 				// 正则匹配出日志类型
 				logLevel := loki.LogLevel(message)
 				info.Level = logLevel
+				if traceID := loki.TraceID(message); traceID != "" {
+					info.TraceID = traceID
+					info.TraceLink = fmt.Sprintf("/v1/observability/cluster/%s/traces/%s", c.Param("cluster_name"), traceID)
+					if !slice.ContainStr(traceIDs, traceID) {
+						traceIDs = append(traceIDs, traceID)
+					}
+				}
 				info.Animation = ""
 				info.Index = fmt.Sprintf("%s-%d", timestamp, index)
 				item.Info = info
@@ -186,6 +195,8 @@ func (l *LogHandler) QueryRange(c *gin.Context) {
 		data["pod"] = podResults
 	}
 	data["resultType"] = resultType
+	// 日志中出现的 trace id, 可以跳转到 trace 详情
+	data["traces"] = traceIDs
 
 	handlers.OK(c, data)
 }
@@ -31,4 +31,10 @@ func (h *LogHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.GET("/log/:cluster_name/querylanguage", h.QueryLanguage)
 	rg.GET("/log/:cluster_name/series", h.Series)
 	rg.GET("/log/:cluster_name/context", h.Context)
+
+	rg.GET("/log/cluster/:cluster/namespaces/:namespace/exports", h.CheckByClusterNamespace, h.ListLogExport)
+	rg.GET("/log/cluster/:cluster/namespaces/:namespace/exports/:name", h.CheckByClusterNamespace, h.GetLogExport)
+	rg.POST("/log/cluster/:cluster/namespaces/:namespace/exports", h.CheckByClusterNamespace, h.CreateLogExport)
+	rg.PUT("/log/cluster/:cluster/namespaces/:namespace/exports/:name", h.CheckByClusterNamespace, h.UpdateLogExport)
+	rg.DELETE("/log/cluster/:cluster/namespaces/:namespace/exports/:name", h.CheckByClusterNamespace, h.DeleteLogExport)
 }
@@ -28,4 +28,10 @@ func (h *MyHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.GET("/my/auth", h.MyAuthority)
 	rg.GET("/my/tenants", h.MyTenants)
 	rg.POST("/my/reset_password", h.ResetPassword)
+
+	rg.GET("/my/2fa", h.MyTwoFactor)
+	rg.POST("/my/2fa/enroll", h.EnrollTwoFactor)
+	rg.POST("/my/2fa/activate", h.ActivateTwoFactor)
+	rg.POST("/my/2fa/disable", h.DisableTwoFactor)
+	rg.POST("/my/2fa/recovery_codes", h.RegenerateRecoveryCodes)
 }
@@ -0,0 +1,212 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package myinfohandler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/aaa/auth"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+type twoFactorStatus struct {
+	Enabled bool `json:"enabled"`
+	// 安全策略是否要求开启
+	Required bool `json:"required"`
+	// 剩余可用的恢复码数量
+	RecoveryCodes int `json:"recoveryCodes"`
+}
+
+type twoFactorCodeForm struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MyTwoFactor 获取当前用户两步验证状态
+//
+//	@Tags			User
+//	@Summary		获取当前用户两步验证状态
+//	@Description	获取当前用户两步验证状态
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	handlers.ResponseStruct{Data=twoFactorStatus}	"两步验证状态"
+//	@Router			/v1/my/2fa [get]
+//	@Security		JWT
+func (h *MyHandler) MyTwoFactor(c *gin.Context) {
+	user, err := h.localAccount(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	status := &twoFactorStatus{}
+	tf := &models.UserTOTP{}
+	if err := h.GetDB().WithContext(ctx).First(tf, "user_id = ?", user.ID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		handlers.NotOK(c, err)
+		return
+	}
+	status.Enabled = tf.Enabled
+	status.RecoveryCodes = len(tf.RecoveryCodes)
+	if status.Required, err = auth.TwoFactorRequired(ctx, h.GetDB(), user); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, status)
+}
+
+// EnrollTwoFactor 生成两步验证密钥
+//
+//	@Tags			User
+//	@Summary		生成两步验证密钥
+//	@Description	生成两步验证密钥, 使用验证器扫码后调用 activate 接口开启
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	handlers.ResponseStruct{Data=auth.TOTPEnrollment}	"密钥"
+//	@Router			/v1/my/2fa/enroll [post]
+//	@Security		JWT
+func (h *MyHandler) EnrollTwoFactor(c *gin.Context) {
+	user, err := h.localAccount(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	enrollment, err := auth.EnrollTOTP(c.Request.Context(), h.GetDB(), user)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, enrollment)
+}
+
+// ActivateTwoFactor 开启两步验证
+//
+//	@Tags			User
+//	@Summary		开启两步验证
+//	@Description	校验验证码后开启两步验证, 返回恢复码, 恢复码仅返回这一次
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		twoFactorCodeForm						true	"验证码"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=[]string}	"恢复码"
+//	@Router			/v1/my/2fa/activate [post]
+//	@Security		JWT
+func (h *MyHandler) ActivateTwoFactor(c *gin.Context) {
+	user, err := h.localAccount(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	form := &twoFactorCodeForm{}
+	if err := c.BindJSON(form); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	codes, err := auth.ActivateTOTP(c.Request.Context(), h.GetDB(), user, form.Code)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "开启", "两步验证", user.Username)
+	handlers.OK(c, codes)
+}
+
+// DisableTwoFactor 关闭两步验证
+//
+//	@Tags			User
+//	@Summary		关闭两步验证
+//	@Description	校验验证码或恢复码后关闭两步验证, 安全策略要求开启时不允许关闭
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		twoFactorCodeForm			true	"验证码"
+//	@Success		200		{object}	handlers.ResponseStruct{}	""
+//	@Router			/v1/my/2fa/disable [post]
+//	@Security		JWT
+func (h *MyHandler) DisableTwoFactor(c *gin.Context) {
+	user, err := h.localAccount(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	form := &twoFactorCodeForm{}
+	if err := c.BindJSON(form); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	required, err := auth.TwoFactorRequired(ctx, h.GetDB(), user)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if required {
+		handlers.NotOK(c, i18n.Errorf(c, "two-factor authentication is required by the security policy and can't be disabled"))
+		return
+	}
+	if err := auth.DisableTOTP(ctx, h.GetDB(), user, form.Code, true); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "关闭", "两步验证", user.Username)
+	handlers.OK(c, nil)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+//
+//	@Tags			User
+//	@Summary		重新生成恢复码
+//	@Description	校验验证码后重新生成恢复码, 旧的恢复码全部失效
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		twoFactorCodeForm						true	"验证码"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=[]string}	"恢复码"
+//	@Router			/v1/my/2fa/recovery_codes [post]
+//	@Security		JWT
+func (h *MyHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, err := h.localAccount(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	form := &twoFactorCodeForm{}
+	if err := c.BindJSON(form); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	codes, err := auth.RegenerateRecoveryCodes(c.Request.Context(), h.GetDB(), user, form.Code)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "重新生成", "两步验证恢复码", user.Username)
+	handlers.OK(c, codes)
+}
+
+// localAccount 两步验证只对本地账号生效, 外部登录源的用户由 IdP 负责
+func (h *MyHandler) localAccount(c *gin.Context) (*models.User, error) {
+	u, exist := h.GetContextUser(c)
+	if !exist {
+		return nil, i18n.Errorf(c, "unauthorized, please login first")
+	}
+	user := &models.User{}
+	if err := h.GetDB().WithContext(c.Request.Context()).First(user, u.GetID()).Error; err != nil {
+		return nil, err
+	}
+	if user.Source != "" && user.Source != auth.AccountLoginName {
+		return nil, i18n.Errorf(c, "two-factor authentication is only available for local accounts")
+	}
+	return user, nil
+}
@@ -17,6 +17,7 @@ package oauthserver
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
 	"sync"
@@ -30,6 +31,9 @@ import (
 	"github.com/go-oauth2/oauth2/v4/store"
 	"github.com/golang-jwt/jwt"
 	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/aaa"
 	"kubegems.io/kubegems/pkg/service/aaa/auth"
 	"kubegems.io/kubegems/pkg/service/handlers"
 	"kubegems.io/kubegems/pkg/service/handlers/base"
@@ -77,7 +81,7 @@ func NewOauthServer(opts *kjwt.Options, base base.BaseHandler, tracer trace.Trac
 
 	s.srv = server.NewServer(server.NewConfig(), s.manager)
 	s.srv.SetClientInfoHandler(func(r *http.Request) (clientID string, clientSecret string, err error) {
-		loader := auth.BearerTokenUserLoader{JWT: opts.ToJWT(), Tracer: tracer}
+		loader := auth.BearerTokenUserLoader{JWT: opts.ToJWT(), DB: base.GetDB(), Tracer: tracer}
 		user, exist := loader.GetUser(r)
 		if !exist {
 			err = fmt.Errorf("user not exist")
@@ -167,16 +171,22 @@ func (s *OauthServer) DeleteToken(c *gin.Context) {
 
 // @Tags			Oauth
 // @Summary		签发oauth jwt token
-// @Description	签发oauth jwt token
+// @Description	签发oauth jwt token, client_credentials 方式可以通过 body 限制令牌的访问范围、只读和来源IP
 // @Accept			json
 // @Produce		json
 // @Param			grant_type	query		string									true	"授权方式，目前只支持client_credentials"
 // @Param			scope		query		string									true	"授权范围，目前只支持validate"
 // @Param			expire		query		int										true	"授权时长，单位秒"
+// @Param			param		body		tokenOptions							false	"令牌限制"
 // @Success		200			{object}	handlers.ResponseStruct{Data=object}	"resp"
 // @Router			/v1/oauth/token [post]
 // @Security		JWT
 func (s *OauthServer) Token(c *gin.Context) {
+	// 受限的令牌不能签发新的令牌, 否则可以绕过限制
+	if token, ok := aaa.GetContextUserToken(c); ok && token.Restricted() {
+		handlers.Forbidden(c, i18n.Errorf(c, "restricted token can't be used to issue new tokens"))
+		return
+	}
 	if c.Request.FormValue("grant_type") == "client_credentials" {
 		s.DirectToken(c)
 		return
@@ -229,6 +239,17 @@ func (s *OauthServer) Token(c *gin.Context) {
 	handlers.OK(c, s.srv.GetTokenData(ti))
 }
 
+// tokenOptions 个人访问令牌的限制, 均为空时令牌拥有用户的全部权限
+type tokenOptions struct {
+	Name string `json:"name"`
+	// 可访问的租户、项目、环境
+	Scopes kmodels.UserTokenScopes `json:"scopes" binding:"dive"`
+	// 只允许读操作
+	ReadOnly bool `json:"readOnly"`
+	// 来源 IP 或 CIDR 白名单
+	AllowedIPs []string `json:"allowedIPs"`
+}
+
 func (s *OauthServer) DirectToken(c *gin.Context) {
 	u, _ := c.Get("current_user")
 	user, ok := u.(*kmodels.User)
@@ -236,28 +257,54 @@ func (s *OauthServer) DirectToken(c *gin.Context) {
 		handlers.NotOK(c, fmt.Errorf("user info invalid"))
 		return
 	}
+	opts := &tokenOptions{}
+	if c.Request.ContentLength > 0 && c.ContentType() == gin.MIMEJSON {
+		if err := c.ShouldBindJSON(opts); err != nil {
+			handlers.NotOK(c, err)
+			return
+		}
+	}
+	if err := s.validateTokenOptions(c, opts); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
 	expireSeconds, _ := strconv.ParseInt(c.Query("expire"), 10, 64)
 	expires := time.Duration(expireSeconds) * time.Second
 	if expireSeconds == 0 {
 		expires = NeverExpireDuration
 	}
-	// assume systemroleid 1 is admin
-	token, claims, err := s.jwt.GenerateToken(user, user.Username, user.SystemRoleID == 1, expires)
-	if err != nil {
-		handlers.NotOK(c, err)
-		return
-	}
-	issuedAt := time.Unix(claims.IssuedAt, 0)
-	expiresAt := time.Unix(claims.ExpiresAt, 0)
 	t := kmodels.UserToken{
-		Token:     token,
-		GrantType: "default",
-		Scope:     "default",
-		ExpireAt:  &expiresAt,
-		UserID:    &user.ID,
-		CreatedAt: &issuedAt,
+		Name:       opts.Name,
+		GrantType:  "default",
+		Scope:      "default",
+		Scopes:     opts.Scopes,
+		ReadOnly:   opts.ReadOnly,
+		AllowedIPs: opts.AllowedIPs,
+		UserID:     &user.ID,
 	}
-	if err := s.GetDB().Create(&t).Error; err != nil {
+	var (
+		token  string
+		claims kjwt.JWTClaims
+	)
+	err := s.GetDB().WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&t).Error; err != nil {
+			return err
+		}
+		// 使用令牌ID作为 jti, 鉴权时据此加载令牌的限制, 删除令牌后立即失效
+		var err error
+		// assume systemroleid 1 is admin
+		token, claims, err = s.jwt.GenerateTokenWithID(strconv.FormatUint(uint64(t.ID), 10), user, user.Username, user.SystemRoleID == 1, expires)
+		if err != nil {
+			return err
+		}
+		issuedAt := time.Unix(claims.IssuedAt, 0)
+		expiresAt := time.Unix(claims.ExpiresAt, 0)
+		t.Token = token
+		t.CreatedAt = &issuedAt
+		t.ExpireAt = &expiresAt
+		return tx.Save(&t).Error
+	})
+	if err != nil {
 		handlers.NotOK(c, err)
 		return
 	}
@@ -267,6 +314,23 @@ func (s *OauthServer) DirectToken(c *gin.Context) {
 	})
 }
 
+func (s *OauthServer) validateTokenOptions(c *gin.Context, opts *tokenOptions) error {
+	for _, scope := range opts.Scopes {
+		if s.ModelCache().FindResource(scope.Kind, scope.ID) == nil {
+			return i18n.Errorf(c, "token scope %s %d not found", scope.Kind, scope.ID)
+		}
+	}
+	for _, item := range opts.AllowedIPs {
+		if _, _, err := net.ParseCIDR(item); err == nil {
+			continue
+		}
+		if net.ParseIP(item) == nil {
+			return i18n.Errorf(c, "invalid ip or cidr %s", item)
+		}
+	}
+	return nil
+}
+
 func (s *OauthServer) RegistRouter(rg *gin.RouterGroup) {
 	rg.GET("/oauth/token", s.ListToken)
 	rg.POST("/oauth/token", s.Token)
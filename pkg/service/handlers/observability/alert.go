@@ -418,6 +418,8 @@ type AlertMessageGroup struct {
 	Status         string     // firing or resolved
 	Labels         datatypes.JSON
 	SilenceCreator string
+	// 被维护窗口静默时为维护窗口名称
+	MaintenanceWindow string
 	// 计数
 	Count int64
 }
@@ -462,6 +464,7 @@ func (h *ObservabilityHandler) AlertHistory(c *gin.Context) {
 			max(created_at) as created_at,
 			max(status) as status,
 			max(labels) as labels,
+			max(maintenance_window) as maintenance_window,
 			count(created_at) as count`).
 		Joins("join alert_infos on alert_messages.fingerprint = alert_infos.fingerprint").
 		Where("cluster_name = ?", cluster).
@@ -1134,6 +1137,17 @@ func (h *ObservabilityHandler) withAlertRuleProcessor(ctx context.Context, clust
 }
 
 func (p *AlertRuleProcessor) MutateAlertRule(ctx context.Context, alertrule *models.AlertRule) error {
+	if err := p.mutateAlertRuleExpr(ctx, alertrule); err != nil {
+		return err
+	}
+	if err := SetReceivers(alertrule, p.db.DB().WithContext(ctx)); err != nil {
+		return err
+	}
+	return checkAlertLevels(alertrule)
+}
+
+// mutateAlertRuleExpr 校验并生成告警消息和表达式, 不处理接收器
+func (p *AlertRuleProcessor) mutateAlertRuleExpr(ctx context.Context, alertrule *models.AlertRule) error {
 	if err := models.IsValidAlertRuleName(alertrule.Name); err != nil {
 		return err
 	}
@@ -1164,11 +1178,7 @@ func (p *AlertRuleProcessor) MutateAlertRule(ctx context.Context, alertrule *mod
 		return err
 	}
 	alertrule.Expr = generatedExpr
-
-	if err := SetReceivers(alertrule, p.db.DB().WithContext(ctx)); err != nil {
-		return err
-	}
-	return checkAlertLevels(alertrule)
+	return nil
 }
 
 func (p *AlertRuleProcessor) getAlertRuleReq(c *gin.Context) (*models.AlertRule, error) {
@@ -1198,7 +1208,10 @@ func genarateMessage(alertrule *models.AlertRule) (string, error) {
 	var ret string
 	switch alertrule.AlertType {
 	case prometheus.AlertTypeMonitor:
-		if alertrule.PromqlGenerator != nil {
+		if alertrule.SLOGenerator != nil {
+			ret = fmt.Sprintf("%s: [cluster:{{ $externalLabels.%s }}] SLO %s error budget is burning too fast, target: %s%%, window: %s, error ratio: {{ $value | humanizePercentage }}",
+				alertrule.Name, prometheus.AlertClusterKey, alertrule.SLOGenerator.SLO, formatFloat(alertrule.SLOGenerator.Target), alertrule.SLOGenerator.Window)
+		} else if alertrule.PromqlGenerator != nil {
 			ret = fmt.Sprintf("%s: [cluster:{{ $externalLabels.%s }}] ", alertrule.Name, prometheus.AlertClusterKey)
 			for _, label := range alertrule.PromqlGenerator.Tpl.Labels {
 				ret += fmt.Sprintf("[%s:{{ $labels.%s }}] ", label, label)
@@ -1221,6 +1234,9 @@ func genarateMessage(alertrule *models.AlertRule) (string, error) {
 		} else {
 			ret = fmt.Sprintf("%s: [cluster:{{ $labels.%s }}] trigger alert, value: %s", alertrule.Name, prometheus.AlertClusterKey, prometheus.ValueAnnotationExpr)
 		}
+	case prometheus.AlertTypeAnomaly:
+		// 实际的告警消息由 worker 根据异常的序列生成
+		ret = fmt.Sprintf("%s: [cluster:%s] metric deviates from its seasonal baseline", alertrule.Name, alertrule.Cluster)
 	default:
 		return "", errors.Errorf("unknown alert type: %s", alertrule.AlertType)
 	}
@@ -1231,7 +1247,9 @@ func GenerateExpr(alertrule *models.AlertRule) (string, error) {
 	var generatedExpr string
 	switch alertrule.AlertType {
 	case prometheus.AlertTypeMonitor:
-		if alertrule.PromqlGenerator != nil {
+		if alertrule.SLOGenerator != nil {
+			generatedExpr = strings.ReplaceAll(alertrule.SLOGenerator.ErrorRatio, models.SLOWindowPlaceholder, "5m")
+		} else if alertrule.PromqlGenerator != nil {
 			q, err := promql.New(alertrule.PromqlGenerator.Tpl.Expr)
 			if err != nil {
 				return "", err
@@ -1338,7 +1356,7 @@ func checkAlertLevels(alertrule *models.AlertRule) error {
 		if len(alertrule.InhibitLabels) == 0 {
 			return fmt.Errorf("有多个告警级别时，告警抑制标签不能为空!")
 		}
-		if alertrule.PromqlGenerator == nil && alertrule.LogqlGenerator == nil {
+		if alertrule.PromqlGenerator == nil && alertrule.LogqlGenerator == nil && alertrule.SLOGenerator == nil {
 			return fmt.Errorf("原生表达式不支持配置多个告警级别")
 		}
 	}
@@ -1428,6 +1446,9 @@ func (p *AlertRuleProcessor) syncEmailSecret(ctx context.Context, alertrule *mod
 
 func GenerateRuleGroup(alertrule *models.AlertRule) monitoringv1.RuleGroup {
 	exprFunc := func(level models.AlertLevel) string {
+		if alertrule.SLOGenerator != nil {
+			return sloBurnRateExpr(alertrule, level.Severity)
+		}
 		if alertrule.PromqlGenerator == nil && alertrule.LogqlGenerator == nil {
 			// use expr directly if from raw promql or logql
 			return alertrule.Expr
@@ -1460,8 +1481,9 @@ func GenerateRuleGroup(alertrule *models.AlertRule) monitoringv1.RuleGroup {
 func GenerateAmcfgSpec(alertrule *models.AlertRule) v1alpha1.AlertmanagerConfigSpec {
 	ret := v1alpha1.AlertmanagerConfigSpec{
 		Route: &v1alpha1.Route{
-			Receiver:      prometheus.NullReceiverName,
-			GroupBy:       []string{prometheus.AlertNamespaceLabel, prometheus.AlertNameLabel},
+			Receiver: prometheus.NullReceiverName,
+			// 带有事件关联键的告警按关联键分组, 同一事件的告警合并为一条通知
+			GroupBy:       []string{prometheus.AlertNamespaceLabel, prometheus.AlertNameLabel, models.IncidentCorrelationKeyLabel},
 			GroupWait:     "30s",
 			GroupInterval: "30s",
 			Routes:        []apiextensionsv1.JSON{},
@@ -1562,6 +1584,9 @@ func (p *AlertRuleProcessor) syncPrometheusRule(ctx context.Context, alertrule *
 	}
 	_, err := controllerutil.CreateOrUpdate(ctx, p.cli, prule, func() error {
 		prule.Spec.Groups = []monitoringv1.RuleGroup{GenerateRuleGroup(alertrule)}
+		if alertrule.SLOGenerator != nil {
+			prule.Spec.Groups = append(prule.Spec.Groups, GenerateSLORecordingGroup(alertrule))
+		}
 		return nil
 	})
 	return err
@@ -1614,6 +1639,17 @@ func (p *AlertRuleProcessor) syncLoggingAlertRule(ctx context.Context, alertrule
 	return nil
 }
 
+// syncAnomalyAlertRule 异常告警由 worker 直接发送到 alertmanager, 只需要同步路由和接收器
+func (p *AlertRuleProcessor) syncAnomalyAlertRule(ctx context.Context, alertrule *models.AlertRule) error {
+	if err := p.syncEmailSecret(ctx, alertrule); err != nil {
+		return errors.Wrap(err, "sync secret failed")
+	}
+	if err := p.syncAlertmanagerConfig(ctx, alertrule); err != nil {
+		return errors.Wrap(err, "sync alertmanagerconfig failed")
+	}
+	return nil
+}
+
 func (p *AlertRuleProcessor) CreateAlertRule(ctx context.Context, req *models.AlertRule) error {
 	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
 		allRules := []models.AlertRule{}
@@ -1658,6 +1694,10 @@ func (p *AlertRuleProcessor) SyncAlertRule(ctx context.Context, alertrule *model
 		if err := p.syncLoggingAlertRule(ctx, alertrule); err != nil {
 			return errors.Wrapf(err, "sync logging alertrule: %s", alertrule.FullName())
 		}
+	case prometheus.AlertTypeAnomaly:
+		if err := p.syncAnomalyAlertRule(ctx, alertrule); err != nil {
+			return errors.Wrapf(err, "sync anomaly alertrule: %s", alertrule.FullName())
+		}
 	default:
 		return errors.Errorf("unknown alerttype: %v", alertrule)
 	}
@@ -1671,6 +1711,8 @@ func (p *AlertRuleProcessor) SyncAlertRule(ctx context.Context, alertrule *model
 const (
 	LoggingAlertRuleCMName = "kubegems-loki-rules"
 	LokiRecordingRulesKey  = "kubegems-loki-recording-rules.yaml"
+	// 日志指标的 recording rules, 需要 Loki ruler 开启 remote write 写入 Prometheus, 见 docs/log-metrics.md
+	LokiLogMetricsKey = "kubegems-loki-log-metrics.yaml"
 )
 
 func mutateLokiRuleGroups(
@@ -1820,7 +1862,7 @@ func (p *AlertRuleProcessor) GetK8sAlertCfg(ctx context.Context) (map[string]K8s
 	}
 	for k, v := range lokiruleCm.Data {
 		// skip recording rule
-		if k == LokiRecordingRulesKey {
+		if k == LokiRecordingRulesKey || k == LokiLogMetricsKey {
 			continue
 		}
 		groups := monitoringv1.PrometheusRuleSpec{}
@@ -0,0 +1,308 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	prommodel "github.com/prometheus/common/model"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/loki"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+)
+
+const (
+	backtestTimeLayout   = "2006-01-02T15:04:05Z"
+	backtestDefaultRange = 24 * time.Hour
+	backtestMaxRange     = 7 * 24 * time.Hour
+	backtestDefaultStep  = time.Minute
+	// prometheus/loki 单条序列最多返回 11000 个点
+	backtestMaxPoints = 11000
+)
+
+// BacktestInterval 模拟的告警触发区间
+type BacktestInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// 回测结束时仍在触发
+	Active bool `json:"active"`
+}
+
+type BacktestSeries struct {
+	Labels    map[string]string  `json:"labels"`
+	Intervals []BacktestInterval `json:"intervals"`
+}
+
+type BacktestLevel struct {
+	Severity string           `json:"severity"`
+	Expr     string           `json:"expr"`
+	Firings  int              `json:"firings"`  // 触发次数
+	Duration string           `json:"duration"` // 累计触发时长
+	Series   []BacktestSeries `json:"series"`
+}
+
+type BacktestResult struct {
+	Start  time.Time       `json:"start"`
+	End    time.Time       `json:"end"`
+	Step   string          `json:"step"`
+	For    string          `json:"for"`
+	Levels []BacktestLevel `json:"levels"`
+}
+
+// BacktestAlertRule 告警规则回测
+//
+//	@Tags			Observability
+//	@Summary		告警规则回测
+//	@Description	使用历史数据模拟告警规则在指定时间范围内的触发情况, 按告警级别和标签返回触发区间, 不会保存告警规则
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string											true	"cluster"
+//	@Param			namespace	path		string											true	"namespace"
+//	@Param			start		query		string											false	"开始时间, 默认结束时间前24h, eg. 2022-01-01T00:00:00Z"
+//	@Param			end			query		string											false	"结束时间, 默认现在"
+//	@Param			step		query		int												false	"step, 单位秒, 默认60"
+//	@Param			form		body		models.AlertRule								true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=BacktestResult}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/monitor/alerts/_/backtest [post]
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/logging/alerts/_/backtest [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) BacktestAlertRule(c *gin.Context) {
+	req := &models.AlertRule{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.Cluster = c.Param("cluster")
+	req.Namespace = c.Param("namespace")
+	if strings.Contains(c.FullPath(), "monitor/alerts") {
+		req.AlertType = prometheus.AlertTypeMonitor
+	} else {
+		req.AlertType = prometheus.AlertTypeLogging
+	}
+	if req.Name == "" {
+		req.Name = "backtest"
+	}
+	start, end, step, err := parseBacktestRange(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	var ret *BacktestResult
+	if err := h.withAlertRuleProcessor(c.Request.Context(), req.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		if err := p.mutateAlertRuleExpr(ctx, req); err != nil {
+			return err
+		}
+		if err := checkAlertLevels(req); err != nil {
+			return err
+		}
+		ret, err = p.BacktestAlertRule(ctx, req, start, end, step)
+		return err
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+func parseBacktestRange(c *gin.Context) (start, end time.Time, step time.Duration, err error) {
+	end = time.Now().UTC().Truncate(time.Second)
+	if s := c.Query("end"); s != "" {
+		if end, err = time.Parse(backtestTimeLayout, s); err != nil {
+			return
+		}
+	}
+	start = end.Add(-backtestDefaultRange)
+	if s := c.Query("start"); s != "" {
+		if start, err = time.Parse(backtestTimeLayout, s); err != nil {
+			return
+		}
+	}
+	if !start.Before(end) || end.Sub(start) > backtestMaxRange {
+		err = i18n.Errorf(c, "backtest time range must be within %s", backtestMaxRange)
+		return
+	}
+	step = backtestDefaultStep
+	if s := c.Query("step"); s != "" {
+		var seconds int
+		if seconds, err = strconv.Atoi(s); err != nil {
+			return
+		}
+		if seconds > 0 {
+			step = time.Duration(seconds) * time.Second
+		}
+	}
+	// 点数过多时自动增大 step
+	if minStep := end.Sub(start) / backtestMaxPoints; step < minStep {
+		step = time.Duration(math.Ceil(minStep.Seconds())) * time.Second
+	}
+	return
+}
+
+// BacktestAlertRule 对每个告警级别做范围查询, 表达式有结果即认为条件满足, 再按 For 模拟 pending/firing
+func (p *AlertRuleProcessor) BacktestAlertRule(ctx context.Context, alertrule *models.AlertRule, start, end time.Time, step time.Duration) (*BacktestResult, error) {
+	hold := time.Duration(0)
+	if alertrule.For != "" {
+		d, err := prommodel.ParseDuration(alertrule.For)
+		if err != nil {
+			return nil, errors.Wrapf(err, "for %s not valid", alertrule.For)
+		}
+		hold = time.Duration(d)
+	}
+	ret := &BacktestResult{
+		Start: start,
+		End:   end,
+		Step:  prommodel.Duration(step).String(),
+		For:   alertrule.For,
+	}
+	for _, rule := range GenerateRuleGroup(alertrule).Rules {
+		expr := rule.Expr.String()
+		series, err := p.backtestQuery(ctx, alertrule.AlertType, expr, start, end, step)
+		if err != nil {
+			return nil, errors.Wrapf(err, "query %s", expr)
+		}
+		level := BacktestLevel{
+			Severity: rule.Labels[prometheus.SeverityLabel],
+			Expr:     expr,
+			Series:   []BacktestSeries{},
+		}
+		total := time.Duration(0)
+		for _, s := range series {
+			intervals := simulateFiring(s.timestamps, end, step, hold)
+			if len(intervals) == 0 {
+				continue
+			}
+			for _, in := range intervals {
+				total += in.End.Sub(in.Start)
+			}
+			level.Firings += len(intervals)
+			level.Series = append(level.Series, BacktestSeries{Labels: s.labels, Intervals: intervals})
+		}
+		level.Duration = prommodel.Duration(total).String()
+		sort.Slice(level.Series, func(i, j int) bool {
+			return level.Series[i].Intervals[0].Start.Before(level.Series[j].Intervals[0].Start)
+		})
+		ret.Levels = append(ret.Levels, level)
+	}
+	return ret, nil
+}
+
+type backtestSeries struct {
+	labels     map[string]string
+	timestamps []time.Time
+}
+
+func (p *AlertRuleProcessor) backtestQuery(ctx context.Context, alerttype, expr string, start, end time.Time, step time.Duration) ([]backtestSeries, error) {
+	ret := []backtestSeries{}
+	switch alerttype {
+	case prometheus.AlertTypeMonitor:
+		matrix, err := p.cli.Extend().PrometheusQueryRange(ctx, expr,
+			start.UTC().Format(backtestTimeLayout), end.UTC().Format(backtestTimeLayout), strconv.Itoa(int(step.Seconds())))
+		if err != nil {
+			return nil, err
+		}
+		for _, stream := range matrix {
+			s := backtestSeries{labels: map[string]string{}}
+			for k, v := range stream.Metric {
+				if k != prommodel.MetricNameLabel {
+					s.labels[string(k)] = string(v)
+				}
+			}
+			for _, v := range stream.Values {
+				s.timestamps = append(s.timestamps, v.Timestamp.Time())
+			}
+			ret = append(ret, s)
+		}
+	case prometheus.AlertTypeLogging:
+		data, err := p.cli.Extend().LokiQueryRange(ctx, expr,
+			start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339), fmt.Sprintf("%ds", int(step.Seconds())))
+		if err != nil {
+			return nil, err
+		}
+		if data.ResultType != loki.ResultTypeMatrix {
+			return nil, errors.Errorf("unexpected result type %s, logging alert expr must be a metric query", data.ResultType)
+		}
+		for _, item := range data.Result {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			stream := (&loki.SampleStream{}).ToStruct(m)
+			s := backtestSeries{labels: stream.Metric}
+			for _, v := range stream.Values {
+				if len(v) == 0 {
+					continue
+				}
+				if ts, ok := v[0].(float64); ok {
+					sec, frac := math.Modf(ts)
+					s.timestamps = append(s.timestamps, time.Unix(int64(sec), int64(frac*1e9)))
+				}
+			}
+			ret = append(ret, s)
+		}
+	default:
+		return nil, errors.Errorf("unknown alert type: %s", alerttype)
+	}
+	return ret, nil
+}
+
+// simulateFiring 按 prometheus 的 for 语义计算触发区间: 表达式连续有结果的时长达到 hold 后进入 firing,
+// 出现没有结果的评估点时恢复. timestamps 为表达式有结果的评估时间, 间隔超过 step 视为中断
+func simulateFiring(timestamps []time.Time, end time.Time, step, hold time.Duration) []BacktestInterval {
+	ret := []BacktestInterval{}
+	if len(timestamps) == 0 {
+		return ret
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+	// 允许 prometheus 返回时间戳的少量偏差
+	tolerance := step + step/2
+
+	flush := func(activeAt, last time.Time) {
+		firingAt := activeAt.Add(hold)
+		if last.Before(firingAt) {
+			return
+		}
+		// 下一个评估点没有结果时恢复
+		resolvedAt := last.Add(step)
+		if resolvedAt.After(end) {
+			resolvedAt = end
+		}
+		ret = append(ret, BacktestInterval{
+			Start:  firingAt,
+			End:    resolvedAt,
+			Active: end.Sub(last) < tolerance,
+		})
+	}
+	activeAt, last := timestamps[0], timestamps[0]
+	for _, ts := range timestamps[1:] {
+		if ts.Sub(last) > tolerance {
+			flush(activeAt, last)
+			activeAt = ts
+		}
+		last = ts
+	}
+	flush(activeAt, last)
+	return ret
+}
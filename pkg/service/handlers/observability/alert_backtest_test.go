@@ -0,0 +1,84 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_simulateFiring(t *testing.T) {
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes ...int) []time.Time {
+		ret := []time.Time{}
+		for _, m := range minutes {
+			ret = append(ret, base.Add(time.Duration(m)*time.Minute))
+		}
+		return ret
+	}
+	min := func(m int) time.Time { return base.Add(time.Duration(m) * time.Minute) }
+	end := min(60)
+
+	tests := []struct {
+		name       string
+		timestamps []time.Time
+		hold       time.Duration
+		want       []BacktestInterval
+	}{
+		{
+			name:       "no data",
+			timestamps: nil,
+			want:       []BacktestInterval{},
+		},
+		{
+			name:       "without for",
+			timestamps: at(1, 2, 3, 10),
+			want: []BacktestInterval{
+				{Start: min(1), End: min(4)},
+				{Start: min(10), End: min(11)},
+			},
+		},
+		{
+			name:       "shorter than for",
+			timestamps: at(1, 2, 3, 10),
+			hold:       5 * time.Minute,
+			want:       []BacktestInterval{},
+		},
+		{
+			name:       "pending then firing",
+			timestamps: at(1, 2, 3, 4, 5, 6, 7, 20, 21),
+			hold:       5 * time.Minute,
+			want: []BacktestInterval{
+				{Start: min(6), End: min(8)},
+			},
+		},
+		{
+			name:       "still active at end",
+			timestamps: at(57, 58, 59, 60),
+			hold:       2 * time.Minute,
+			want: []BacktestInterval{
+				{Start: min(59), End: min(60), Active: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := simulateFiring(tt.timestamps, end, time.Minute, tt.hold); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("simulateFiring() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
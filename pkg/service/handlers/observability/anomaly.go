@@ -0,0 +1,421 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	prommodel "github.com/prometheus/common/model"
+	promlabels "github.com/prometheus/prometheus/pkg/labels"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+	"kubegems.io/kubegems/pkg/utils/prometheus/promql"
+)
+
+const (
+	anomalyDefaultSensitivity = 3
+	anomalyDefaultFor         = "5m"
+	anomalyDefaultWeeks       = 4
+	anomalyMaxWeeks           = 8
+	anomalyMaxFor             = time.Hour
+)
+
+// GenerateAnomalyExpr 在检测表达式中加入命名空间
+func GenerateAnomalyExpr(d *models.AnomalyDetector) (string, error) {
+	q, err := promql.New(d.Expr)
+	if err != nil {
+		return "", errors.Wrap(err, "expr not valid")
+	}
+	q.AddLabelMatchers(&promlabels.Matcher{
+		Type:  promlabels.MatchEqual,
+		Name:  "namespace",
+		Value: d.Namespace,
+	})
+	return q.String(), nil
+}
+
+func (p *AlertRuleProcessor) mutateAnomalyDetector(ctx context.Context, d *models.AnomalyDetector) (*models.AlertRule, error) {
+	if err := models.IsValidAlertRuleName(d.Name); err != nil {
+		return nil, err
+	}
+	if d.Sensitivity <= 0 {
+		d.Sensitivity = anomalyDefaultSensitivity
+	}
+	switch d.Direction {
+	case "":
+		d.Direction = models.AnomalyDirectionBoth
+	case models.AnomalyDirectionUp, models.AnomalyDirectionDown, models.AnomalyDirectionBoth:
+	default:
+		return nil, errors.Errorf("direction %s not valid", d.Direction)
+	}
+	if d.For == "" {
+		d.For = anomalyDefaultFor
+	}
+	dur, err := prommodel.ParseDuration(d.For)
+	if err != nil {
+		return nil, errors.Wrapf(err, "for %s not valid", d.For)
+	}
+	if time.Duration(dur) < time.Minute || time.Duration(dur) > anomalyMaxFor {
+		return nil, i18n.Errorf(ctx, "anomaly detector duration must between 1m and 1h")
+	}
+	d.For = dur.String()
+	if d.Weeks == 0 {
+		d.Weeks = anomalyDefaultWeeks
+	}
+	if d.Weeks < 1 || d.Weeks > anomalyMaxWeeks {
+		return nil, i18n.Errorf(ctx, "anomaly detector weeks must between 1 and 8")
+	}
+	switch d.Severity {
+	case "":
+		d.Severity = prometheus.SeverityError
+	case prometheus.SeverityError, prometheus.SeverityCritical:
+	default:
+		return nil, errors.Errorf("severity %s not valid", d.Severity)
+	}
+	if _, err := prometheus.ParseUnit(d.Unit); err != nil {
+		return nil, err
+	}
+	expr, err := GenerateAnomalyExpr(d)
+	if err != nil {
+		return nil, err
+	}
+
+	alertrule := &models.AlertRule{
+		Cluster:     d.Cluster,
+		Namespace:   d.Namespace,
+		Name:        d.Name,
+		AlertType:   prometheus.AlertTypeAnomaly,
+		Expr:        expr,
+		For:         d.For,
+		AlertLevels: models.AlertLevels{{Severity: d.Severity}},
+		Receivers:   d.Receivers,
+		IsOpen:      true,
+	}
+	if alertrule.Message, err = genarateMessage(alertrule); err != nil {
+		return nil, err
+	}
+	if err := SetReceivers(alertrule, p.DBWithCtx(ctx)); err != nil {
+		return nil, err
+	}
+	return alertrule, checkAlertLevels(alertrule)
+}
+
+func (p *AlertRuleProcessor) CreateAnomalyDetector(ctx context.Context, d *models.AnomalyDetector) error {
+	alertrule, err := p.mutateAnomalyDetector(ctx, d)
+	if err != nil {
+		return err
+	}
+	d.State = models.AnomalyStateLearning
+	d.TrainedAt = nil
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.AlertRule{}).Where("cluster = ? and namespace = ? and name = ?", d.Cluster, d.Namespace, d.Name).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.Errorf("alert rule %s is already exist", d.Name)
+		}
+		if err := tx.Omit("Receivers.AlertChannel").Create(alertrule).Error; err != nil {
+			return err
+		}
+		d.AlertRuleID = &alertrule.ID
+		if err := tx.Omit("AlertRule").Create(d).Error; err != nil {
+			return err
+		}
+		return p.SyncAlertRule(ctx, alertrule)
+	})
+}
+
+func (p *AlertRuleProcessor) UpdateAnomalyDetector(ctx context.Context, d *models.AnomalyDetector) error {
+	alertrule, err := p.mutateAnomalyDetector(ctx, d)
+	if err != nil {
+		return err
+	}
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		oldDetector := &models.AnomalyDetector{}
+		if err := tx.First(oldDetector, d.ID).Error; err != nil {
+			return err
+		}
+		d.State, d.TrainedAt = oldDetector.State, oldDetector.TrainedAt
+		if oldDetector.Expr != d.Expr {
+			// 序列变化后原有的基线不再适用
+			if err := tx.Where("detector_id = ?", d.ID).Delete(&models.AnomalyBaseline{}).Error; err != nil {
+				return err
+			}
+			d.State, d.TrainedAt = models.AnomalyStateLearning, nil
+		} else if oldDetector.Weeks != d.Weeks {
+			d.TrainedAt = nil
+		}
+
+		old := &models.AlertRule{}
+		if d.AlertRuleID != nil {
+			if err := tx.First(old, *d.AlertRuleID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		if old.ID == 0 {
+			// 告警规则丢失时重新创建
+			if err := tx.Omit("Receivers.AlertChannel").Create(alertrule).Error; err != nil {
+				return err
+			}
+		} else {
+			alertrule.ID = old.ID
+			alertrule.IsOpen = old.IsOpen
+			for _, rec := range alertrule.Receivers {
+				rec.AlertRuleID = old.ID
+			}
+			if err := updateReceiversInDB(alertrule, tx); err != nil {
+				return errors.Wrap(err, "update receivers")
+			}
+			if err := tx.Select("expr", "for", "message", "alert_levels").Updates(alertrule).Error; err != nil {
+				return err
+			}
+		}
+		d.AlertRuleID = &alertrule.ID
+		if err := tx.Select("description", "expr", "sensitivity", "direction", "for", "weeks", "severity", "enabled", "unit",
+			"state", "trained_at", "alert_rule_id").Updates(d).Error; err != nil {
+			return err
+		}
+		return p.SyncAlertRule(ctx, alertrule)
+	})
+}
+
+func (p *AlertRuleProcessor) DeleteAnomalyDetector(ctx context.Context, d *models.AnomalyDetector) error {
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("detector_id = ?", d.ID).Delete(&models.AnomalyBaseline{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(d).Error; err != nil {
+			return err
+		}
+		if d.AlertRuleID == nil {
+			return nil
+		}
+		alertrule := &models.AlertRule{}
+		if err := tx.First(alertrule, *d.AlertRuleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := tx.Delete(alertrule).Error; err != nil {
+			return err
+		}
+		return p.deleteMonitorAlertRule(ctx, alertrule)
+	})
+}
+
+func (h *ObservabilityHandler) getAnomalyDetector(c *gin.Context) (*models.AnomalyDetector, error) {
+	d := &models.AnomalyDetector{}
+	if err := h.GetDB().WithContext(c.Request.Context()).
+		Preload("AlertRule.Receivers.AlertChannel").
+		First(d, "cluster = ? and namespace = ? and name = ?", c.Param("cluster"), c.Param("namespace"), c.Param("name")).Error; err != nil {
+		return nil, err
+	}
+	if d.AlertRule != nil {
+		d.Receivers = d.AlertRule.Receivers
+	}
+	return d, nil
+}
+
+// ListAnomalyDetector 异常检测列表
+//
+//	@Tags			Observability
+//	@Summary		异常检测列表
+//	@Description	异常检测列表
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string													true	"cluster"
+//	@Param			namespace	path		string													true	"namespace"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.AnomalyDetector}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/anomalies [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ListAnomalyDetector(c *gin.Context) {
+	ret := []*models.AnomalyDetector{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("AlertRule").Order("name").
+		Find(&ret, "cluster = ? and namespace = ?", c.Param("cluster"), c.Param("namespace")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+// GetAnomalyDetector 异常检测详情
+//
+//	@Tags			Observability
+//	@Summary		异常检测详情
+//	@Description	异常检测详情
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string												true	"cluster"
+//	@Param			namespace	path		string												true	"namespace"
+//	@Param			name		path		string												true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.AnomalyDetector}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/anomalies/{name} [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) GetAnomalyDetector(c *gin.Context) {
+	d, err := h.getAnomalyDetector(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, d)
+}
+
+// CreateAnomalyDetector 创建异常检测
+//
+//	@Tags			Observability
+//	@Summary		创建异常检测
+//	@Description	创建异常检测, 学习最近几周的数据作为基线, 样本不足两周时只学习不检测
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			form		body		models.AnomalyDetector					true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/anomalies [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) CreateAnomalyDetector(c *gin.Context) {
+	req := &models.AnomalyDetector{Enabled: true}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = 0
+	req.Cluster = c.Param("cluster")
+	req.Namespace = c.Param("namespace")
+	h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "create"), i18n.Sprintf(c, "anomaly detector"), req.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), req.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.CreateAnomalyDetector(ctx, req)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// UpdateAnomalyDetector 修改异常检测
+//
+//	@Tags			Observability
+//	@Summary		修改异常检测
+//	@Description	修改异常检测, 修改表达式后重新学习基线
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			name		path		string									true	"name"
+//	@Param			form		body		models.AnomalyDetector					true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/anomalies/{name} [put]
+//	@Security		JWT
+func (h *ObservabilityHandler) UpdateAnomalyDetector(c *gin.Context) {
+	d, err := h.getAnomalyDetector(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := &models.AnomalyDetector{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = d.ID
+	req.Cluster = d.Cluster
+	req.Namespace = d.Namespace
+	req.Name = d.Name
+	req.AlertRuleID = d.AlertRuleID
+	h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "anomaly detector"), req.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), req.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.UpdateAnomalyDetector(ctx, req)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// DeleteAnomalyDetector 删除异常检测
+//
+//	@Tags			Observability
+//	@Summary		删除异常检测
+//	@Description	删除异常检测, 同时删除学习的基线和生成的告警规则
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			name		path		string									true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/anomalies/{name} [delete]
+//	@Security		JWT
+func (h *ObservabilityHandler) DeleteAnomalyDetector(c *gin.Context) {
+	d, err := h.getAnomalyDetector(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetExtraAuditDataByClusterNamespace(c, d.Cluster, d.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "delete"), i18n.Sprintf(c, "anomaly detector"), d.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), d.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.DeleteAnomalyDetector(ctx, d)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// ListAnomalyBaseline 异常检测的基线
+//
+//	@Tags			Observability
+//	@Summary		异常检测的基线
+//	@Description	异常检测学习到的每条序列在一周中每个小时的中位数和 MAD
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string													true	"cluster"
+//	@Param			namespace	path		string													true	"namespace"
+//	@Param			name		path		string													true	"name"
+//	@Param			seriesKey	query		string													false	"序列标识, 为空时返回所有序列"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.AnomalyBaseline}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/anomalies/{name}/baselines [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ListAnomalyBaseline(c *gin.Context) {
+	d, err := h.getAnomalyDetector(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	query := h.GetDB().WithContext(c.Request.Context()).Where("detector_id = ?", d.ID)
+	if key := c.Query("seriesKey"); key != "" {
+		query = query.Where("series_key = ?", key)
+	}
+	ret := []*models.AnomalyBaseline{}
+	if err := query.Order("series_key").Order("hour_of_week").Find(&ret).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
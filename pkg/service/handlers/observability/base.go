@@ -35,15 +35,42 @@ func (h *ObservabilityHandler) RegistRouter(rg *gin.RouterGroup) {
 
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/monitor/alerts", h.CheckByClusterNamespace, h.ListMonitorAlertRule)
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/monitor/alerts/_/status", h.CheckByClusterNamespace, h.ListMonitorAlertRulesStatus)
+	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/monitor/alerts/_/backtest", h.CheckByClusterNamespace, h.BacktestAlertRule)
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/monitor/alerts/:name", h.CheckByClusterNamespace, h.GetMonitorAlertRule)
 	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/monitor/alerts", h.CheckByClusterNamespace, h.CreateMonitorAlertRule)
 	rg.PUT("/observability/cluster/:cluster/namespaces/:namespace/monitor/alerts/:name", h.CheckByClusterNamespace, h.UpdateMonitorAlertRule)
 	rg.DELETE("/observability/cluster/:cluster/namespaces/:namespace/monitor/alerts/:name", h.CheckByClusterNamespace, h.DeleteMonitorAlertRule)
 
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/slos", h.CheckByClusterNamespace, h.ListSLO)
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/slos/:name", h.CheckByClusterNamespace, h.GetSLO)
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/slos/:name/errorbudget", h.CheckByClusterNamespace, h.SLOErrorBudget)
+	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/slos", h.CheckByClusterNamespace, h.CreateSLO)
+	rg.PUT("/observability/cluster/:cluster/namespaces/:namespace/slos/:name", h.CheckByClusterNamespace, h.UpdateSLO)
+	rg.DELETE("/observability/cluster/:cluster/namespaces/:namespace/slos/:name", h.CheckByClusterNamespace, h.DeleteSLO)
+
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/anomalies", h.CheckByClusterNamespace, h.ListAnomalyDetector)
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/anomalies/:name", h.CheckByClusterNamespace, h.GetAnomalyDetector)
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/anomalies/:name/baselines", h.CheckByClusterNamespace, h.ListAnomalyBaseline)
+	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/anomalies", h.CheckByClusterNamespace, h.CreateAnomalyDetector)
+	rg.PUT("/observability/cluster/:cluster/namespaces/:namespace/anomalies/:name", h.CheckByClusterNamespace, h.UpdateAnomalyDetector)
+	rg.DELETE("/observability/cluster/:cluster/namespaces/:namespace/anomalies/:name", h.CheckByClusterNamespace, h.DeleteAnomalyDetector)
+
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/maintenancewindows", h.CheckByClusterNamespace, h.ListMaintenanceWindow)
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/maintenancewindows/:name", h.CheckByClusterNamespace, h.GetMaintenanceWindow)
+	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/maintenancewindows", h.CheckByClusterNamespace, h.CreateMaintenanceWindow)
+	rg.PUT("/observability/cluster/:cluster/namespaces/:namespace/maintenancewindows/:name", h.CheckByClusterNamespace, h.UpdateMaintenanceWindow)
+	rg.DELETE("/observability/cluster/:cluster/namespaces/:namespace/maintenancewindows/:name", h.CheckByClusterNamespace, h.DeleteMaintenanceWindow)
+
 	rg.PUT("/observability/cluster/:cluster/namespaces/:namespace/logging", h.CheckByClusterNamespace, h.NamespaceLogCollector)
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/logging/apps", h.CheckByClusterNamespace, h.ListLogApps)
 	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/logging/apps", h.CheckByClusterNamespace, h.AddAppLogCollector)
 
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/logging/metrics", h.CheckByClusterNamespace, h.ListLogMetric)
+	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/logging/metrics/:name", h.CheckByClusterNamespace, h.GetLogMetric)
+	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/logging/metrics", h.CheckByClusterNamespace, h.CreateLogMetric)
+	rg.PUT("/observability/cluster/:cluster/namespaces/:namespace/logging/metrics/:name", h.CheckByClusterNamespace, h.UpdateLogMetric)
+	rg.DELETE("/observability/cluster/:cluster/namespaces/:namespace/logging/metrics/:name", h.CheckByClusterNamespace, h.DeleteLogMetric)
+
 	rg.GET("/observability/tenant/:tenant_id/channels", h.CheckByTenantID, h.ListChannels)
 	rg.GET("/observability/tenant/:tenant_id/channels/:channel_id", h.CheckByTenantID, h.GetChannel)
 	rg.POST("/observability/tenant/:tenant_id/channels", h.CheckByTenantID, h.CreateChannel)
@@ -53,6 +80,7 @@ func (h *ObservabilityHandler) RegistRouter(rg *gin.RouterGroup) {
 
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/logging/alerts", h.CheckByClusterNamespace, h.ListLoggingAlertRule)
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/logging/alerts/_/status", h.CheckByClusterNamespace, h.ListLoggingAlertRulesStatus)
+	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/logging/alerts/_/backtest", h.CheckByClusterNamespace, h.BacktestAlertRule)
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/logging/alerts/:name", h.CheckByClusterNamespace, h.GetLoggingAlertRule)
 	rg.POST("/observability/cluster/:cluster/namespaces/:namespace/logging/alerts", h.CheckByClusterNamespace, h.CreateLoggingAlertRule)
 	rg.PUT("/observability/cluster/:cluster/namespaces/:namespace/logging/alerts/:name", h.CheckByClusterNamespace, h.UpdateLoggingAlertRule)
@@ -71,6 +99,19 @@ func (h *ObservabilityHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.GET("/observability/tenant/:tenant_id/alerts/group", h.CheckByTenantID, h.AlertByGroup)
 	rg.GET("/observability/tenant/:tenant_id/alerts/search", h.CheckByTenantID, h.SearchAlert)
 
+	rg.GET("/observability/tenant/:tenant_id/incidents", h.CheckByTenantID, h.ListIncident)
+	rg.GET("/observability/tenant/:tenant_id/incidents/:incident_id", h.CheckByTenantID, h.GetIncident)
+	rg.PUT("/observability/tenant/:tenant_id/incidents/:incident_id", h.CheckByTenantID, h.UpdateIncident)
+	rg.POST("/observability/tenant/:tenant_id/incidents/:incident_id/notes", h.CheckByTenantID, h.AddIncidentNote)
+
+	rg.GET("/observability/tenant/:tenant_id/reports", h.CheckByTenantID, h.ListReport)
+	rg.GET("/observability/tenant/:tenant_id/reports/:report_id", h.CheckByTenantID, h.GetReport)
+	rg.GET("/observability/tenant/:tenant_id/reports/:report_id/preview", h.CheckByTenantID, h.PreviewReport)
+	rg.POST("/observability/tenant/:tenant_id/reports", h.CheckByTenantID, h.CreateReport)
+	rg.PUT("/observability/tenant/:tenant_id/reports/:report_id", h.CheckByTenantID, h.UpdateReport)
+	rg.DELETE("/observability/tenant/:tenant_id/reports/:report_id", h.CheckByTenantID, h.DeleteReport)
+	rg.POST("/observability/tenant/:tenant_id/reports/:report_id/send", h.CheckByTenantID, h.SendReport)
+
 	// metrics
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/monitor/metrics/queryrange", h.CheckByClusterNamespace, h.QueryRange)
 	rg.GET("/observability/cluster/:cluster/namespaces/:namespace/monitor/metrics/labelvalues", h.CheckByClusterNamespace, h.LabelValues)
@@ -94,12 +135,16 @@ func (h *ObservabilityHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.PUT("/observability/environment/:environment_id/monitor/dashboard/:dashboard_id", h.CheckByEnvironmentID, h.UpdateDashboard)
 	rg.DELETE("/observability/environment/:environment_id/monitor/dashboard/:dashboard_id", h.CheckByEnvironmentID, h.DeleteDashboard)
 	rg.GET("/observability/environment/:environment_id/monitor/dashboard/:dashboard_id/query", h.CheckByEnvironmentID, h.DashboardQuery)
+	rg.POST("/observability/environment/:environment_id/monitor/dashboard/_/grafana", h.CheckByEnvironmentID, h.ImportGrafanaDashboard)
+	rg.GET("/observability/environment/:environment_id/monitor/dashboard/:dashboard_id/grafana", h.CheckByEnvironmentID, h.ExportGrafanaDashboard)
 
 	rg.GET("/observability/template/dashboard", h.ListDashboardTemplates)
 	rg.GET("/observability/template/dashboard/:name", h.GetDashboardTemplate)
 	rg.POST("/observability/template/dashboard", h.CheckIsSysADMIN, h.AddDashboardTemplates)
 	rg.PUT("/observability/template/dashboard/:name", h.CheckIsSysADMIN, h.UpdateDashboardTemplates)
 	rg.DELETE("/observability/template/dashboard/:name", h.CheckIsSysADMIN, h.DeleteDashboardTemplate)
+	rg.POST("/observability/template/dashboard/_/grafana", h.CheckIsSysADMIN, h.ImportGrafanaDashboardTemplate)
+	rg.GET("/observability/template/dashboard/:name/grafana", h.ExportGrafanaDashboardTemplate)
 
 	// exporter
 	rg.GET("/observability/monitor/exporters/:name/schema", h.ExporterSchema)
@@ -242,6 +242,19 @@ func (h *ObservabilityHandler) DeleteChannel(c *gin.Context) {
 		handlers.NotOK(c, fmt.Errorf("该告警渠道正在被告警规则: [%s] 使用", strings.Join(tmp, ",")))
 		return
 	}
+	reports := []models.Report{}
+	if err := h.GetDB().WithContext(ctx).Find(&reports, "channel_id = ?", ch.ID).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if len(reports) > 0 {
+		tmp := make([]string, len(reports))
+		for i, v := range reports {
+			tmp[i] = v.Name
+		}
+		handlers.NotOK(c, fmt.Errorf("该告警渠道正在被定时报告: [%s] 使用", strings.Join(tmp, ",")))
+		return
+	}
 	if err := h.GetDB().WithContext(ctx).Delete(ch).Error; err != nil {
 		handlers.NotOK(c, err)
 		return
@@ -25,6 +25,7 @@ import (
 	"kubegems.io/kubegems/pkg/service/handlers"
 	"kubegems.io/kubegems/pkg/service/models"
 	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
@@ -142,6 +143,10 @@ func (h *ObservabilityHandler) getMetricQuerysByDashboard(c *gin.Context) ([]*Me
 	globalLabelPairs := c.QueryMap("labelpairs")
 	if len(globalLabelPairs) > 0 {
 		for _, query := range queries {
+			// 原生promql也支持按变量过滤
+			if query.PromqlGenerator == nil {
+				query.PromqlGenerator = &prometheus.PromqlGenerator{}
+			}
 			for k, v := range globalLabelPairs {
 				if query.PromqlGenerator.LabelPairs == nil {
 					query.PromqlGenerator.LabelPairs = make(map[string]string)
@@ -363,11 +368,19 @@ func (h *ObservabilityHandler) getDashboardReq(c *gin.Context) (*models.MonitorD
 	if err := c.BindJSON(&req); err != nil {
 		return nil, err
 	}
+	if err := h.completeDashboard(c, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// completeDashboard 填充模板、环境、创建者和默认值, 并校验图表
+func (h *ObservabilityHandler) completeDashboard(c *gin.Context, req *models.MonitorDashboard) error {
 	ctx := c.Request.Context()
 	if c.Request.Method == http.MethodPost && req.Template != "" {
 		tpl := models.MonitorDashboardTpl{Name: req.Template}
 		if err := h.GetDB().WithContext(ctx).First(&tpl).Error; err != nil {
-			return nil, errors.Wrapf(err, "get template: %s failed", req.Template)
+			return errors.Wrapf(err, "get template: %s failed", req.Template)
 		}
 		req.Start = tpl.Start
 		req.End = tpl.End
@@ -377,19 +390,19 @@ func (h *ObservabilityHandler) getDashboardReq(c *gin.Context) (*models.MonitorD
 
 	envid, err := strconv.Atoi(c.Param("environment_id"))
 	if err != nil {
-		return nil, errors.Wrap(err, "environment_id")
+		return errors.Wrap(err, "environment_id")
 	}
 	uintid := uint(envid)
 	req.EnvironmentID = &uintid
 	u, exist := h.GetContextUser(c)
 	if !exist {
-		return nil, fmt.Errorf("not login")
+		return fmt.Errorf("not login")
 	}
 	req.Creator = u.GetUsername()
 
 	env := models.Environment{}
 	if err := h.GetDB().WithContext(ctx).First(&env, "id = ?", req.EnvironmentID).Error; err != nil {
-		return nil, err
+		return err
 	}
 
 	// 默认查近30m
@@ -406,7 +419,7 @@ func (h *ObservabilityHandler) getDashboardReq(c *gin.Context) (*models.MonitorD
 
 	tplGetter := h.GetDataBase().NewPromqlTplMapperFromDB().FindPromqlTpl
 	if err := models.CheckGraphs(req.Graphs, env.Namespace, tplGetter); err != nil {
-		return nil, err
+		return err
 	}
-	return &req, nil
+	return nil
 }
@@ -0,0 +1,223 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+)
+
+type GrafanaImportResult struct {
+	Dashboard   *models.MonitorDashboard             `json:"dashboard,omitempty"`
+	Template    *models.MonitorDashboardTpl          `json:"template,omitempty"`
+	Unsupported []prometheus.GrafanaUnsupportedPanel `json:"unsupported"` // 未能转换的面板
+}
+
+func (h *ObservabilityHandler) parseGrafanaDashboard(c *gin.Context) (*prometheus.DashboardContent, []prometheus.GrafanaUnsupportedPanel, error) {
+	data, err := c.GetRawData()
+	if err != nil {
+		return nil, nil, err
+	}
+	dash, err := prometheus.ParseGrafanaDashboard(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, unsupported := prometheus.ImportGrafanaDashboard(dash)
+	if name := c.Query("name"); name != "" {
+		content.Title = name
+	}
+	if content.Title == "" {
+		return nil, nil, i18n.Errorf(c, "dashboard name is required")
+	}
+	if len(content.Graphs) == 0 {
+		return nil, nil, i18n.Errorf(c, "no supported panels found in grafana dashboard")
+	}
+	return content, unsupported, nil
+}
+
+// ImportGrafanaDashboard 从grafana导入监控dashboard
+//
+//	@Tags			Observability
+//	@Summary		从grafana导入监控dashboard
+//	@Description	将grafana dashboard json转换为监控dashboard, 支持prometheus数据源的timeseries/graph/stat面板, 返回未能转换的面板
+//	@Accept			json
+//	@Produce		json
+//	@Param			environment_id	path		string												true	"环境ID"
+//	@Param			name			query		string												false	"dashboard名, 默认使用grafana dashboard的title"
+//	@Param			preview			query		bool												false	"只返回转换结果, 不保存"
+//	@Param			form			body		prometheus.GrafanaDashboard							true	"grafana dashboard json"
+//	@Success		200				{object}	handlers.ResponseStruct{Data=GrafanaImportResult}	"resp"
+//	@Router			/v1/observability/environment/{environment_id}/monitor/dashboard/_/grafana [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) ImportGrafanaDashboard(c *gin.Context) {
+	content, unsupported, err := h.parseGrafanaDashboard(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := &models.MonitorDashboard{
+		Name:      content.Title,
+		Refresh:   content.Refresh,
+		Start:     content.Start,
+		End:       content.End,
+		Graphs:    content.Graphs,
+		Variables: gormdatatypes.JSONMap(content.Variables),
+	}
+	if err := h.completeDashboard(c, req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ret := GrafanaImportResult{Dashboard: req, Unsupported: unsupported}
+	if c.Query("preview") == "true" {
+		handlers.OK(c, ret)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "import"), i18n.Sprintf(c, "monitor dashboard"), req.Name)
+	h.SetExtraAuditData(c, models.ResEnvironment, *req.EnvironmentID)
+	if err := h.GetDB().WithContext(c.Request.Context()).Create(req).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+// ExportGrafanaDashboard 导出监控dashboard为grafana格式
+//
+//	@Tags			Observability
+//	@Summary		导出监控dashboard为grafana格式
+//	@Description	导出监控dashboard为grafana dashboard json, 模板指标会展开为promql, 变量导出为label_values变量
+//	@Accept			json
+//	@Produce		json
+//	@Param			environment_id	path		string														true	"环境ID"
+//	@Param			dashboard_id	path		uint														true	"dashboard id"
+//	@Success		200				{object}	handlers.ResponseStruct{Data=prometheus.GrafanaDashboard}	"resp"
+//	@Router			/v1/observability/environment/{environment_id}/monitor/dashboard/{dashboard_id}/grafana [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ExportGrafanaDashboard(c *gin.Context) {
+	dash := models.MonitorDashboard{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("Environment").
+		First(&dash, "id = ? and environment_id = ?", c.Param("dashboard_id"), c.Param("environment_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	namespace := ""
+	if dash.Environment != nil {
+		namespace = dash.Environment.Namespace
+	}
+	ret, err := prometheus.ExportGrafanaDashboard(&prometheus.DashboardContent{
+		Title:     dash.Name,
+		Refresh:   dash.Refresh,
+		Start:     dash.Start,
+		End:       dash.End,
+		Graphs:    dash.Graphs,
+		Variables: dash.Variables,
+	}, namespace, h.GetDataBase().NewPromqlTplMapperFromDB().FindPromqlTpl)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+// ImportGrafanaDashboardTemplate 从grafana导入监控面板模板
+//
+//	@Tags			Observability
+//	@Summary		从grafana导入监控面板模板
+//	@Description	将grafana dashboard json转换为监控面板模板, 支持prometheus数据源的timeseries/graph/stat面板, 返回未能转换的面板
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	query		string												false	"模板名, 默认使用grafana dashboard的title"
+//	@Param			preview	query		bool												false	"只返回转换结果, 不保存"
+//	@Param			form	body		prometheus.GrafanaDashboard							true	"grafana dashboard json"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=GrafanaImportResult}	"resp"
+//	@Router			/v1/observability/template/dashboard/_/grafana [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) ImportGrafanaDashboardTemplate(c *gin.Context) {
+	content, unsupported, err := h.parseGrafanaDashboard(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	tpl := &models.MonitorDashboardTpl{
+		Name:        content.Title,
+		Description: content.Description,
+		Step:        "30s",
+		Refresh:     content.Refresh,
+		Start:       content.Start,
+		End:         content.End,
+		Graphs:      content.Graphs,
+		Variables:   gormdatatypes.JSONMap(content.Variables),
+	}
+	if tpl.Start == "" || tpl.End == "" {
+		tpl.Start = "now-30m"
+		tpl.End = "now"
+	}
+	if tpl.Refresh == "" {
+		tpl.Refresh = "30s"
+	}
+	tplGetter := h.GetDataBase().NewPromqlTplMapperFromDB().FindPromqlTpl
+	if err := models.CheckGraphs(tpl.Graphs, "", tplGetter); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ret := GrafanaImportResult{Template: tpl, Unsupported: unsupported}
+	if c.Query("preview") == "true" {
+		handlers.OK(c, ret)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "import"), i18n.Sprintf(c, "monitor dashboard template"), tpl.Name)
+	if err := h.GetDB().WithContext(c.Request.Context()).Create(tpl).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+// ExportGrafanaDashboardTemplate 导出监控面板模板为grafana格式
+//
+//	@Tags			Observability
+//	@Summary		导出监控面板模板为grafana格式
+//	@Description	导出监控面板模板为grafana dashboard json, 模板指标会展开为promql, 变量导出为label_values变量
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path		string														true	"模板名"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=prometheus.GrafanaDashboard}	"resp"
+//	@Router			/v1/observability/template/dashboard/{name}/grafana [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ExportGrafanaDashboardTemplate(c *gin.Context) {
+	tpl := models.MonitorDashboardTpl{}
+	if err := h.GetDB().WithContext(c.Request.Context()).First(&tpl, "name = ?", c.Param("name")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ret, err := prometheus.ExportGrafanaDashboard(&prometheus.DashboardContent{
+		Title:       tpl.Name,
+		Description: tpl.Description,
+		Refresh:     tpl.Refresh,
+		Start:       tpl.Start,
+		End:         tpl.End,
+		Graphs:      tpl.Graphs,
+		Variables:   tpl.Variables,
+	}, "", h.GetDataBase().NewPromqlTplMapperFromDB().FindPromqlTpl)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
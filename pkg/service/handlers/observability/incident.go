@@ -0,0 +1,258 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+type UpdateIncidentReq struct {
+	Status   string  `json:"status"`
+	Assignee *string `json:"assignee"`
+}
+
+type IncidentNoteReq struct {
+	Content string `json:"content" binding:"required"`
+}
+
+func (h *ObservabilityHandler) incidentQuery(c *gin.Context) *gorm.DB {
+	query := h.GetDB().WithContext(c.Request.Context())
+	if tenantID := c.Param("tenant_id"); tenantID != "_all" {
+		t := models.Tenant{}
+		h.GetDB().WithContext(c.Request.Context()).First(&t, "id = ?", tenantID)
+		query = query.Where("tenant_name = ?", t.TenantName)
+	}
+	return query
+}
+
+func (h *ObservabilityHandler) getIncident(c *gin.Context) (*models.Incident, error) {
+	incident := &models.Incident{}
+	if err := h.incidentQuery(c).First(incident, "id = ?", c.Param("incident_id")).Error; err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// ListIncident 告警事件列表
+//
+//	@Tags			Observability
+//	@Summary		告警事件列表
+//	@Description	告警事件列表, 相关的告警按时间窗口、共有标签和所属环境/工作负载聚合为事件
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string																			true	"租户ID，所有租户为_all"
+//	@Param			project		query		string																			false	"项目名，默认所有"
+//	@Param			environment	query		string																			false	"环境名，默认所有"
+//	@Param			cluster		query		string																			false	"集群名，默认所有"
+//	@Param			namespace	query		string																			false	"命名空间，默认所有"
+//	@Param			status		query		string																			false	"状态(open, acknowledged, resolved)"
+//	@Param			assignee	query		string																			false	"处理人"
+//	@Param			start		query		string																			false	"开始时间"
+//	@Param			end			query		string																			false	"结束时间"
+//	@Param			page		query		int																				false	"page"
+//	@Param			size		query		int																				false	"size"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=response.Page[models.Incident]{List=[]models.Incident}}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/incidents [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ListIncident(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+	query := h.incidentQuery(c)
+	for param, column := range map[string]string{
+		"project":     "project_name",
+		"environment": "environment_name",
+		"cluster":     "cluster_name",
+		"namespace":   "namespace",
+		"status":      "status",
+		"assignee":    "assignee",
+	} {
+		if v := c.Query(param); v != "" {
+			query = query.Where(fmt.Sprintf("%s = ?", column), v)
+		}
+	}
+	if start := c.Query("start"); start != "" {
+		query = query.Where("last_alert_at >= ?", start)
+	}
+	if end := c.Query("end"); end != "" {
+		query = query.Where("starts_at <= ?", end)
+	}
+
+	var total int64
+	incidents := []models.Incident{}
+	if err := query.Model(&models.Incident{}).Count(&total).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := query.Order("id desc").Limit(size).Offset((page - 1) * size).Find(&incidents).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, handlers.Page(total, incidents, int64(page), int64(size)))
+}
+
+// GetIncident 告警事件详情
+//
+//	@Tags			Observability
+//	@Summary		告警事件详情
+//	@Description	告警事件详情, 包括关联的告警和时间线
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string										true	"租户ID，所有租户为_all"
+//	@Param			incident_id	path		uint										true	"事件ID"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.Incident}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/incidents/{incident_id} [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) GetIncident(c *gin.Context) {
+	incident := &models.Incident{}
+	if err := h.incidentQuery(c).
+		Preload("Alerts", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Preload("Alerts.AlertInfo").
+		Preload("Events", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		First(incident, "id = ?", c.Param("incident_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, incident)
+}
+
+// UpdateIncident 修改告警事件状态和处理人
+//
+//	@Tags			Observability
+//	@Summary		修改告警事件状态和处理人
+//	@Description	修改告警事件状态(open, acknowledged, resolved)和处理人, 变更记录到时间线
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string										true	"租户ID，所有租户为_all"
+//	@Param			incident_id	path		uint										true	"事件ID"
+//	@Param			form		body		UpdateIncidentReq							true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.Incident}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/incidents/{incident_id} [put]
+//	@Security		JWT
+func (h *ObservabilityHandler) UpdateIncident(c *gin.Context) {
+	req := UpdateIncidentReq{}
+	if err := c.BindJSON(&req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if req.Status != "" && !models.IsValidIncidentStatus(req.Status) {
+		handlers.NotOK(c, i18n.Errorf(c, "invalid incident status: %s", req.Status))
+		return
+	}
+	incident, err := h.getIncident(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	username := ""
+	if user, exist := h.GetContextUser(c); exist {
+		username = user.GetUsername()
+	}
+	now := time.Now()
+	events := []*models.IncidentEvent{}
+	if req.Status != "" && req.Status != incident.Status {
+		incident.Status = req.Status
+		if req.Status == models.IncidentStatusResolved {
+			incident.ResolvedAt = &now
+			// 恢复后释放唯一键, 后续相同关联键的告警创建新事件. 重新打开的事件只按指纹和共享标签关联告警
+			incident.OpenKey = nil
+		} else {
+			incident.ResolvedAt = nil
+		}
+		events = append(events, &models.IncidentEvent{
+			IncidentID: incident.ID,
+			Type:       models.IncidentEventStatus,
+			Creator:    username,
+			Content:    req.Status,
+			CreatedAt:  &now,
+		})
+	}
+	if req.Assignee != nil && *req.Assignee != incident.Assignee {
+		incident.Assignee = *req.Assignee
+		events = append(events, &models.IncidentEvent{
+			IncidentID: incident.ID,
+			Type:       models.IncidentEventAssign,
+			Creator:    username,
+			Content:    incident.Assignee,
+			CreatedAt:  &now,
+		})
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "incident"), incident.Title)
+	if len(events) == 0 {
+		handlers.OK(c, incident)
+		return
+	}
+	if err := h.GetDB().WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("status", "resolved_at", "assignee", "open_key").Updates(incident).Error; err != nil {
+			return err
+		}
+		return tx.Create(events).Error
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, incident)
+}
+
+// AddIncidentNote 添加告警事件备注
+//
+//	@Tags			Observability
+//	@Summary		添加告警事件备注
+//	@Description	添加告警事件备注, 备注记录到时间线
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string												true	"租户ID，所有租户为_all"
+//	@Param			incident_id	path		uint												true	"事件ID"
+//	@Param			form		body		IncidentNoteReq										true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.IncidentEvent}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/incidents/{incident_id}/notes [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) AddIncidentNote(c *gin.Context) {
+	req := IncidentNoteReq{}
+	if err := c.BindJSON(&req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	incident, err := h.getIncident(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	username := ""
+	if user, exist := h.GetContextUser(c); exist {
+		username = user.GetUsername()
+	}
+	now := time.Now()
+	event := &models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventNote,
+		Creator:    username,
+		Content:    req.Content,
+		CreatedAt:  &now,
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "add note"), i18n.Sprintf(c, "incident"), incident.Title)
+	if err := h.GetDB().WithContext(c.Request.Context()).Create(event).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, event)
+}
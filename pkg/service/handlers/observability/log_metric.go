@@ -0,0 +1,389 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"gorm.io/gorm"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"kubegems.io/kubegems/pkg/apis/gems"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// 日志指标在指标目录中的位置
+	logMetricTplScope            = "others"
+	logMetricTplScopeShowName    = "其他"
+	logMetricTplResource         = "logmetrics"
+	logMetricTplResourceShowName = "日志指标"
+	logMetricTplRuleNameMaxLen   = 50
+)
+
+func logMetricRuleGroupName(metric *models.LogMetric) string {
+	return fmt.Sprintf("%s/%s", metric.Namespace, metric.Name)
+}
+
+// logMetricTplRuleName 日志指标模板名, 模板按名字全局查找, 加上集群和命名空间的摘要避免不同环境的日志指标冲突
+func logMetricTplRuleName(metric *models.LogMetric) string {
+	sum := sha256.Sum256([]byte(metric.Cluster + "/" + metric.Namespace + "/" + metric.Name))
+	suffix := "-" + hex.EncodeToString(sum[:])[:8]
+	name := metric.Name
+	if len(name)+len(suffix) > logMetricTplRuleNameMaxLen {
+		name = name[:logMetricTplRuleNameMaxLen-len(suffix)]
+	}
+	return name + suffix
+}
+
+// GenerateLogMetricRuleGroup 生成日志指标的 loki recording rule
+func GenerateLogMetricRuleGroup(metric *models.LogMetric) (monitoringv1.RuleGroup, error) {
+	expr, err := metric.LogQL()
+	if err != nil {
+		return monitoringv1.RuleGroup{}, err
+	}
+	return monitoringv1.RuleGroup{
+		Name: logMetricRuleGroupName(metric),
+		Rules: []monitoringv1.Rule{{
+			Record: metric.RecordName(),
+			Expr:   intstr.FromString(expr),
+		}},
+	}, nil
+}
+
+// syncLogMetricRule 更新 loki 中日志指标的 recording rule, rg 为空时删除.
+// loki ruler 需要开启 remote write 将结果写入集群的 prometheus, 配置见 deploy/plugins/kubegems-local/README.md
+func (p *AlertRuleProcessor) syncLogMetricRule(ctx context.Context, name string, rg *monitoringv1.RuleGroup) error {
+	lokiRuleCM := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: gems.NamespaceLogging,
+			Name:      LoggingAlertRuleCMName,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, p.cli, lokiRuleCM, func() error {
+		if lokiRuleCM.Data == nil {
+			lokiRuleCM.Data = map[string]string{}
+		}
+		return mutateLokiRuleGroups(lokiRuleCM.Data, LokiLogMetricsKey, func(spec *monitoringv1.PrometheusRuleSpec) {
+			groups := []monitoringv1.RuleGroup{}
+			for _, v := range spec.Groups {
+				if v.Name != name {
+					groups = append(groups, v)
+				}
+			}
+			if rg != nil {
+				groups = append(groups, *rg)
+			}
+			spec.Groups = groups
+		})
+	})
+	return err
+}
+
+// logMetricTplResourceOf 日志指标所在的指标目录, 不存在时创建
+func logMetricTplResourceOf(tx *gorm.DB) (*models.PromqlTplResource, error) {
+	scope := &models.PromqlTplScope{}
+	if err := tx.Where(models.PromqlTplScope{Name: logMetricTplScope}).
+		Attrs(models.PromqlTplScope{ShowName: logMetricTplScopeShowName, Namespaced: true}).
+		FirstOrCreate(scope).Error; err != nil {
+		return nil, err
+	}
+	resource := &models.PromqlTplResource{}
+	if err := tx.Where(models.PromqlTplResource{Name: logMetricTplResource}).
+		Attrs(models.PromqlTplResource{ShowName: logMetricTplResourceShowName, ScopeID: &scope.ID}).
+		FirstOrCreate(resource).Error; err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+// upsertLogMetricTplRule 创建或更新日志指标对应的指标目录模板
+func (p *AlertRuleProcessor) upsertLogMetricTplRule(ctx context.Context, tx *gorm.DB, metric *models.LogMetric) error {
+	pos, err := p.db.GetAlertPosition(metric.Cluster, metric.Namespace, metric.Name, false)
+	if err != nil {
+		return err
+	}
+	resource, err := logMetricTplResourceOf(tx)
+	if err != nil {
+		return err
+	}
+	rule := &models.PromqlTplRule{}
+	if metric.PromqlTplRuleID != nil {
+		if err := tx.First(rule, *metric.PromqlTplRuleID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	if rule.ID == 0 {
+		rule.Name = logMetricTplRuleName(metric)
+	}
+	rule.ShowName = metric.Name
+	rule.Description = metric.Description
+	rule.Expr = metric.RecordName()
+	rule.Unit = metric.Unit
+	rule.Labels = metric.TplLabels()
+	rule.ResourceID = &resource.ID
+	rule.TenantID = &pos.TenantID
+	if rule.ID != 0 {
+		return tx.Select("show_name", "description", "expr", "unit", "labels").Updates(rule).Error
+	}
+
+	// 模板名带有命名空间的摘要, 只会和手动添加的同名模板冲突
+	var count int64
+	if err := tx.Model(&models.PromqlTplRule{}).Where("resource_id = ? and name = ?", resource.ID, rule.Name).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return i18n.Errorf(ctx, "rule %s already exist", rule.Name)
+	}
+	if err := tx.Create(rule).Error; err != nil {
+		return err
+	}
+	metric.PromqlTplRuleID = &rule.ID
+	return nil
+}
+
+func (p *AlertRuleProcessor) CreateLogMetric(ctx context.Context, metric *models.LogMetric) error {
+	if err := models.IsValidAlertRuleName(metric.Name); err != nil {
+		return err
+	}
+	rg, err := GenerateLogMetricRuleGroup(metric)
+	if err != nil {
+		return err
+	}
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := p.upsertLogMetricTplRule(ctx, tx, metric); err != nil {
+			return err
+		}
+		if err := tx.Omit("PromqlTplRule").Create(metric).Error; err != nil {
+			return err
+		}
+		return p.syncLogMetricRule(ctx, rg.Name, &rg)
+	})
+}
+
+func (p *AlertRuleProcessor) UpdateLogMetric(ctx context.Context, metric *models.LogMetric) error {
+	rg, err := GenerateLogMetricRuleGroup(metric)
+	if err != nil {
+		return err
+	}
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := p.upsertLogMetricTplRule(ctx, tx, metric); err != nil {
+			return err
+		}
+		if err := tx.Select("description", "unit", "type", "query", "promql_tpl_rule_id").
+			Updates(metric).Error; err != nil {
+			return err
+		}
+		return p.syncLogMetricRule(ctx, rg.Name, &rg)
+	})
+}
+
+func (p *AlertRuleProcessor) DeleteLogMetric(ctx context.Context, metric *models.LogMetric) error {
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if metric.PromqlTplRuleID != nil {
+			rule := &models.PromqlTplRule{}
+			if err := tx.Preload("Resource.Scope").First(rule, *metric.PromqlTplRuleID).Error; err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+			} else {
+				if err := checkPromqlTplRuleUsage(tx, rule); err != nil {
+					return err
+				}
+				if err := tx.Delete(rule).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Delete(metric).Error; err != nil {
+			return err
+		}
+		return p.syncLogMetricRule(ctx, logMetricRuleGroupName(metric), nil)
+	})
+}
+
+func (h *ObservabilityHandler) getLogMetric(c *gin.Context) (*models.LogMetric, error) {
+	metric := &models.LogMetric{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("PromqlTplRule.Resource.Scope").
+		First(metric, "cluster = ? and namespace = ? and name = ?", c.Param("cluster"), c.Param("namespace"), c.Param("name")).Error; err != nil {
+		return nil, err
+	}
+	return metric, nil
+}
+
+// ListLogMetric 日志指标列表
+//
+//	@Tags			Observability
+//	@Summary		日志指标列表
+//	@Description	日志指标列表
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string												true	"cluster"
+//	@Param			namespace	path		string												true	"namespace"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.LogMetric}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/logging/metrics [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ListLogMetric(c *gin.Context) {
+	ret := []*models.LogMetric{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("PromqlTplRule.Resource.Scope").Order("name").
+		Find(&ret, "cluster = ? and namespace = ?", c.Param("cluster"), c.Param("namespace")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+// GetLogMetric 日志指标详情
+//
+//	@Tags			Observability
+//	@Summary		日志指标详情
+//	@Description	日志指标详情
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string											true	"cluster"
+//	@Param			namespace	path		string											true	"namespace"
+//	@Param			name		path		string											true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.LogMetric}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/logging/metrics/{name} [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) GetLogMetric(c *gin.Context) {
+	metric, err := h.getLogMetric(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, metric)
+}
+
+// CreateLogMetric 创建日志指标
+//
+//	@Tags			Observability
+//	@Summary		创建日志指标
+//	@Description	创建日志指标, 生成 loki recording rule, 并添加到指标目录的 others.logmetrics 下
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			form		body		models.LogMetric						true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/logging/metrics [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) CreateLogMetric(c *gin.Context) {
+	req := &models.LogMetric{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = 0
+	req.Cluster = c.Param("cluster")
+	req.Namespace = c.Param("namespace")
+	req.PromqlTplRuleID = nil
+	req.PromqlTplRule = nil
+	h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "create"), i18n.Sprintf(c, "log metric"), req.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), req.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.CreateLogMetric(ctx, req)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// UpdateLogMetric 修改日志指标
+//
+//	@Tags			Observability
+//	@Summary		修改日志指标
+//	@Description	修改日志指标, 同时更新 loki recording rule 和指标目录
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			name		path		string									true	"name"
+//	@Param			form		body		models.LogMetric						true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/logging/metrics/{name} [put]
+//	@Security		JWT
+func (h *ObservabilityHandler) UpdateLogMetric(c *gin.Context) {
+	metric, err := h.getLogMetric(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := &models.LogMetric{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = metric.ID
+	req.Cluster = metric.Cluster
+	req.Namespace = metric.Namespace
+	req.Name = metric.Name
+	req.PromqlTplRuleID = metric.PromqlTplRuleID
+	req.PromqlTplRule = nil
+	h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "log metric"), req.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), req.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.UpdateLogMetric(ctx, req)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// DeleteLogMetric 删除日志指标
+//
+//	@Tags			Observability
+//	@Summary		删除日志指标
+//	@Description	删除日志指标, 同时删除 loki recording rule 和指标目录中的模板, 模板被使用时不能删除
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			name		path		string									true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/logging/metrics/{name} [delete]
+//	@Security		JWT
+func (h *ObservabilityHandler) DeleteLogMetric(c *gin.Context) {
+	metric, err := h.getLogMetric(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetExtraAuditDataByClusterNamespace(c, metric.Cluster, metric.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "delete"), i18n.Sprintf(c, "log metric"), metric.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), metric.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.DeleteLogMetric(ctx, metric)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
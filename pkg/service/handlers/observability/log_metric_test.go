@@ -0,0 +1,37 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"strings"
+	"testing"
+
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+func TestLogMetricTplRuleName(t *testing.T) {
+	a := logMetricTplRuleName(&models.LogMetric{Cluster: "c1", Namespace: "ns1", Name: "errors"})
+	b := logMetricTplRuleName(&models.LogMetric{Cluster: "c1", Namespace: "ns2", Name: "errors"})
+	if a == b {
+		t.Errorf("log metrics in different namespaces have the same template name: %s", a)
+	}
+	if !strings.HasPrefix(a, "errors-") {
+		t.Errorf("logMetricTplRuleName() = %s, want prefix errors-", a)
+	}
+	long := logMetricTplRuleName(&models.LogMetric{Cluster: "c1", Namespace: "ns1", Name: strings.Repeat("a", 50)})
+	if len(long) > logMetricTplRuleNameMaxLen {
+		t.Errorf("logMetricTplRuleName() length = %d, want <= %d", len(long), logMetricTplRuleNameMaxLen)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+// 集群级别的维护窗口使用 _all 作为 namespace, 只有系统管理员可以操作
+const allNamespaces = "_all"
+
+func maintenanceWindowNamespace(c *gin.Context) string {
+	if ns := c.Param("namespace"); ns != allNamespaces {
+		return ns
+	}
+	return ""
+}
+
+func fillNextStartsAt(w *models.MaintenanceWindow, now time.Time) {
+	if start, _, ok := w.Occurrence(now); ok && w.Enabled {
+		w.NextStartsAt = &start
+	}
+}
+
+func (h *ObservabilityHandler) getMaintenanceWindow(c *gin.Context) (*models.MaintenanceWindow, error) {
+	w := &models.MaintenanceWindow{}
+	if err := h.GetDB().WithContext(c.Request.Context()).First(w, "cluster = ? and namespace = ? and name = ?",
+		c.Param("cluster"), maintenanceWindowNamespace(c), c.Param("name")).Error; err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ListMaintenanceWindow 维护窗口列表
+//
+//	@Tags			Observability
+//	@Summary		维护窗口列表
+//	@Description	维护窗口列表, namespace 为 _all 时返回集群内所有的维护窗口
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string													true	"cluster"
+//	@Param			namespace	path		string													true	"namespace, 支持_all"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.MaintenanceWindow}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/maintenancewindows [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ListMaintenanceWindow(c *gin.Context) {
+	ret := []*models.MaintenanceWindow{}
+	query := h.GetDB().WithContext(c.Request.Context()).Where("cluster = ?", c.Param("cluster"))
+	if c.Param("namespace") != allNamespaces {
+		query = query.Where("namespace = ?", c.Param("namespace"))
+	}
+	if err := query.Order("namespace").Order("name").Find(&ret).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	now := time.Now()
+	for _, w := range ret {
+		fillNextStartsAt(w, now)
+	}
+	handlers.OK(c, ret)
+}
+
+// GetMaintenanceWindow 维护窗口详情
+//
+//	@Tags			Observability
+//	@Summary		维护窗口详情
+//	@Description	维护窗口详情
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string												true	"cluster"
+//	@Param			namespace	path		string												true	"namespace, 集群级别的维护窗口为_all"
+//	@Param			name		path		string												true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.MaintenanceWindow}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/maintenancewindows/{name} [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) GetMaintenanceWindow(c *gin.Context) {
+	w, err := h.getMaintenanceWindow(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	fillNextStartsAt(w, time.Now())
+	handlers.OK(c, w)
+}
+
+// CreateMaintenanceWindow 创建维护窗口
+//
+//	@Tags			Observability
+//	@Summary		创建维护窗口
+//	@Description	创建维护窗口, worker 会在每次维护开始时自动创建静默规则, 结束时自动过期
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string												true	"cluster"
+//	@Param			namespace	path		string												true	"namespace, 集群级别的维护窗口为_all"
+//	@Param			form		body		models.MaintenanceWindow							true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.MaintenanceWindow}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/maintenancewindows [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) CreateMaintenanceWindow(c *gin.Context) {
+	req := &models.MaintenanceWindow{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = 0
+	req.Cluster = c.Param("cluster")
+	req.Namespace = maintenanceWindowNamespace(c)
+	if err := req.Validate(); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if user, exist := h.GetContextUser(c); exist {
+		req.Creator = user.GetUsername()
+	}
+	h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "create"), i18n.Sprintf(c, "maintenance window"), req.Name)
+	if err := h.GetDB().WithContext(c.Request.Context()).Create(req).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, req)
+}
+
+// UpdateMaintenanceWindow 修改维护窗口
+//
+//	@Tags			Observability
+//	@Summary		修改维护窗口
+//	@Description	修改维护窗口, 进行中的静默规则会在下次同步时按新的配置重新创建
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string												true	"cluster"
+//	@Param			namespace	path		string												true	"namespace, 集群级别的维护窗口为_all"
+//	@Param			name		path		string												true	"name"
+//	@Param			form		body		models.MaintenanceWindow							true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.MaintenanceWindow}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/maintenancewindows/{name} [put]
+//	@Security		JWT
+func (h *ObservabilityHandler) UpdateMaintenanceWindow(c *gin.Context) {
+	w, err := h.getMaintenanceWindow(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := &models.MaintenanceWindow{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = w.ID
+	req.Cluster = w.Cluster
+	req.Namespace = w.Namespace
+	req.Name = w.Name
+	req.Creator = w.Creator
+	req.CreatedAt = w.CreatedAt
+	if err := req.Validate(); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "maintenance window"), req.Name)
+	if err := h.GetDB().WithContext(c.Request.Context()).Save(req).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, req)
+}
+
+// DeleteMaintenanceWindow 删除维护窗口
+//
+//	@Tags			Observability
+//	@Summary		删除维护窗口
+//	@Description	删除维护窗口, 对应的静默规则会在下次同步时过期
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace, 集群级别的维护窗口为_all"
+//	@Param			name		path		string									true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/maintenancewindows/{name} [delete]
+//	@Security		JWT
+func (h *ObservabilityHandler) DeleteMaintenanceWindow(c *gin.Context) {
+	w, err := h.getMaintenanceWindow(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetExtraAuditDataByClusterNamespace(c, w.Cluster, w.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "delete"), i18n.Sprintf(c, "maintenance window"), w.Name)
+	if err := h.GetDB().WithContext(c.Request.Context()).Delete(w).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
@@ -597,42 +597,9 @@ func (h *ObservabilityHandler) DeleteRules(c *gin.Context) {
 	}
 
 	if err := h.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		dashborads := []models.MonitorDashboard{}
-		if err := tx.Preload("Environment").Find(&dashborads).Error; err != nil {
+		if err := checkPromqlTplRuleUsage(tx, rule); err != nil {
 			return err
 		}
-		for _, dash := range dashborads {
-			if dash.Graphs.IsUsingTpl(rule.Resource.Scope.Name, rule.Resource.Name, rule.Name) {
-				return fmt.Errorf("此模板正在被环境: %s 中的监控大盘: %s 使用", dash.Environment.EnvironmentName, dash.Name)
-			}
-		}
-
-		tpls := []models.MonitorDashboardTpl{}
-		if err := tx.Find(&tpls).Error; err != nil {
-			return err
-		}
-		for _, tpl := range tpls {
-			if tpl.Graphs.IsUsingTpl(rule.Resource.Scope.Name, rule.Resource.Name, rule.Name) {
-				return fmt.Errorf("此模板正在被监控大盘模板: %s 使用", tpl.Name)
-			}
-		}
-
-		alertrules := []*models.AlertRule{}
-		if err := tx.Find(&alertrules,
-			`promql_generator -> "$.scope" = ? and promql_generator -> "$.resource" = ? and promql_generator -> "$.rule" = ?`,
-			rule.Resource.Scope.Name,
-			rule.Resource.Name,
-			rule.Name,
-		).Error; err != nil {
-			return err
-		}
-		if len(alertrules) > 0 {
-			tmp := make([]string, len(alertrules))
-			for i, v := range alertrules {
-				tmp[i] = v.FullName()
-			}
-			return fmt.Errorf("此模板正在被告警规则: [%s] 使用", strings.Join(tmp, ","))
-		}
 		return tx.Delete(rule).Error
 	}); err != nil {
 		handlers.NotOK(c, err)
@@ -641,6 +608,47 @@ func (h *ObservabilityHandler) DeleteRules(c *gin.Context) {
 	handlers.OK(c, "ok")
 }
 
+// checkPromqlTplRuleUsage 检查模板是否被监控大盘、大盘模板或告警规则使用
+func checkPromqlTplRuleUsage(tx *gorm.DB, rule *models.PromqlTplRule) error {
+	dashborads := []models.MonitorDashboard{}
+	if err := tx.Preload("Environment").Find(&dashborads).Error; err != nil {
+		return err
+	}
+	for _, dash := range dashborads {
+		if dash.Graphs.IsUsingTpl(rule.Resource.Scope.Name, rule.Resource.Name, rule.Name) {
+			return fmt.Errorf("此模板正在被环境: %s 中的监控大盘: %s 使用", dash.Environment.EnvironmentName, dash.Name)
+		}
+	}
+
+	tpls := []models.MonitorDashboardTpl{}
+	if err := tx.Find(&tpls).Error; err != nil {
+		return err
+	}
+	for _, tpl := range tpls {
+		if tpl.Graphs.IsUsingTpl(rule.Resource.Scope.Name, rule.Resource.Name, rule.Name) {
+			return fmt.Errorf("此模板正在被监控大盘模板: %s 使用", tpl.Name)
+		}
+	}
+
+	alertrules := []*models.AlertRule{}
+	if err := tx.Find(&alertrules,
+		`promql_generator -> "$.scope" = ? and promql_generator -> "$.resource" = ? and promql_generator -> "$.rule" = ?`,
+		rule.Resource.Scope.Name,
+		rule.Resource.Name,
+		rule.Name,
+	).Error; err != nil {
+		return err
+	}
+	if len(alertrules) > 0 {
+		tmp := make([]string, len(alertrules))
+		for i, v := range alertrules {
+			tmp[i] = v.FullName()
+		}
+		return fmt.Errorf("此模板正在被告警规则: [%s] 使用", strings.Join(tmp, ","))
+	}
+	return nil
+}
+
 func (h *ObservabilityHandler) getRuleReq(c *gin.Context) (*models.PromqlTplRule, error) {
 	req := models.PromqlTplRule{}
 	if err := c.BindJSON(&req); err != nil {
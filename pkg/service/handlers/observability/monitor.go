@@ -426,6 +426,9 @@ func (h *ObservabilityHandler) UpdateMonitorAlertRule(c *gin.Context) {
 		if err != nil {
 			return err
 		}
+		if err := checkSLOManaged(ctx, p.DBWithCtx(ctx), req.Cluster, req.Namespace, req.Name); err != nil {
+			return err
+		}
 		h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
 		action := i18n.Sprintf(ctx, "update")
 		module := i18n.Sprintf(ctx, "monitor alert rule")
@@ -463,6 +466,9 @@ func (h *ObservabilityHandler) DeleteMonitorAlertRule(c *gin.Context) {
 		module := i18n.Sprintf(ctx, "monitor alert rule")
 		h.SetAuditData(c, action, module, req.Name)
 
+		if err := checkSLOManaged(ctx, p.DBWithCtx(ctx), req.Cluster, req.Namespace, req.Name); err != nil {
+			return err
+		}
 		return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
 			if err := tx.First(req, "cluster = ? and namespace = ? and name = ?", req.Cluster, req.Namespace, req.Name).Error; err != nil {
 				return err
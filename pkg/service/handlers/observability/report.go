@@ -0,0 +1,308 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/service/report"
+	"kubegems.io/kubegems/pkg/utils/prometheus/channels"
+)
+
+func (h *ObservabilityHandler) reporter() *report.Reporter {
+	return &report.Reporter{DB: h.GetDB(), Agents: h.GetAgents()}
+}
+
+func (h *ObservabilityHandler) getReport(c *gin.Context) (*models.Report, error) {
+	query := h.GetDB().WithContext(c.Request.Context())
+	if tenantID := c.Param("tenant_id"); tenantID != "_all" {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	r := &models.Report{}
+	if err := query.First(r, "id = ?", c.Param("report_id")).Error; err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// checkReport 校验报告的项目属于租户, 发送渠道为租户或者系统的邮件渠道
+func (h *ObservabilityHandler) checkReport(c *gin.Context, r *models.Report) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	ctx := c.Request.Context()
+	if r.ProjectID != nil {
+		if err := h.GetDB().WithContext(ctx).First(&models.Project{}, "id = ? and tenant_id = ?", *r.ProjectID, r.TenantID).Error; err != nil {
+			return err
+		}
+	}
+	ch := &models.AlertChannel{}
+	if err := h.GetDB().WithContext(ctx).First(ch, "id = ? and (tenant_id = ? or tenant_id is null)", r.ChannelID, r.TenantID).Error; err != nil {
+		return err
+	}
+	if _, ok := ch.ChannelConfig.ChannelIf.(*channels.Email); !ok {
+		return i18n.Errorf(c, "report channel must be an email channel")
+	}
+	return nil
+}
+
+// ListReport 定时报告列表
+//
+//	@Tags			Observability
+//	@Summary		定时报告列表
+//	@Description	定时报告列表
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string											true	"租户ID，所有租户为_all"
+//	@Param			project_id	query		uint											false	"项目ID"
+//	@Param			search		query		string											false	"search in (name)"
+//	@Param			page		query		int												false	"page"
+//	@Param			size		query		int												false	"size"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.Report}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/reports [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ListReport(c *gin.Context) {
+	list := []models.Report{}
+	query, err := handlers.GetQuery(c, nil)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	cond := &handlers.PageQueryCond{
+		Model:         "Report",
+		SearchFields:  []string{"name"},
+		PreloadFields: []string{"Project"},
+	}
+	if tenantID := c.Param("tenant_id"); tenantID != "_all" {
+		cond.Where = append(cond.Where, handlers.Args("tenant_id = ?", tenantID))
+	}
+	if projectID := c.Query("project_id"); projectID != "" {
+		cond.Where = append(cond.Where, handlers.Args("project_id = ?", projectID))
+	}
+	total, page, size, err := query.PageList(h.GetDB().WithContext(c.Request.Context()), cond, &list)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, handlers.Page(total, list, page, size))
+}
+
+// GetReport 定时报告详情
+//
+//	@Tags			Observability
+//	@Summary		定时报告详情
+//	@Description	定时报告详情
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string										true	"租户ID，所有租户为_all"
+//	@Param			report_id	path		uint										true	"报告ID"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.Report}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/reports/{report_id} [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) GetReport(c *gin.Context) {
+	r, err := h.getReport(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, r)
+}
+
+// CreateReport 创建定时报告
+//
+//	@Tags			Observability
+//	@Summary		创建定时报告
+//	@Description	创建定时报告, 默认每周一 9 点发送最近一周的报告
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		uint									true	"租户ID"
+//	@Param			form		body		models.Report							true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/reports [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) CreateReport(c *gin.Context) {
+	req := &models.Report{Enabled: true}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	tenantID, _ := strconv.Atoi(c.Param("tenant_id"))
+	if tenantID == 0 {
+		handlers.NotOK(c, i18n.Errorf(c, "tenant id not valid"))
+		return
+	}
+	req.ID = 0
+	req.TenantID = uint(tenantID)
+	req.LastRunAt, req.LastError = nil, ""
+	if u, exist := h.GetContextUser(c); exist {
+		req.Creator = u.GetUsername()
+	}
+	h.SetExtraAuditData(c, models.ResTenant, req.TenantID)
+	h.SetAuditData(c, i18n.Sprintf(c, "create"), i18n.Sprintf(c, "report"), req.Name)
+
+	if err := h.checkReport(c, req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := h.GetDB().WithContext(c.Request.Context()).Create(req).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// UpdateReport 修改定时报告
+//
+//	@Tags			Observability
+//	@Summary		修改定时报告
+//	@Description	修改定时报告
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string									true	"租户ID，所有租户为_all"
+//	@Param			report_id	path		uint									true	"报告ID"
+//	@Param			form		body		models.Report							true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/reports/{report_id} [put]
+//	@Security		JWT
+func (h *ObservabilityHandler) UpdateReport(c *gin.Context) {
+	r, err := h.getReport(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := &models.Report{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetExtraAuditData(c, models.ResTenant, r.TenantID)
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "report"), r.Name)
+
+	r.Name = req.Name
+	r.Description = req.Description
+	r.ProjectID = req.ProjectID
+	r.Schedule = req.Schedule
+	r.Period = req.Period
+	r.ChannelID = req.ChannelID
+	r.Recipients = req.Recipients
+	r.Enabled = req.Enabled
+	r.Project, r.Channel, r.Tenant = nil, nil, nil
+	if err := h.checkReport(c, r); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := h.GetDB().WithContext(c.Request.Context()).Select("*").Omit("created_at", "last_run_at", "last_error").Updates(r).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// DeleteReport 删除定时报告
+//
+//	@Tags			Observability
+//	@Summary		删除定时报告
+//	@Description	删除定时报告
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string									true	"租户ID，所有租户为_all"
+//	@Param			report_id	path		uint									true	"报告ID"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/reports/{report_id} [delete]
+//	@Security		JWT
+func (h *ObservabilityHandler) DeleteReport(c *gin.Context) {
+	r, err := h.getReport(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetExtraAuditData(c, models.ResTenant, r.TenantID)
+	h.SetAuditData(c, i18n.Sprintf(c, "delete"), i18n.Sprintf(c, "report"), r.Name)
+	if err := h.GetDB().WithContext(c.Request.Context()).Delete(r).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// PreviewReport 预览定时报告
+//
+//	@Tags			Observability
+//	@Summary		预览定时报告
+//	@Description	按当前时间统计报告并返回 html, 不发送邮件
+//	@Accept			json
+//	@Produce		html
+//	@Param			tenant_id	path		string	true	"租户ID，所有租户为_all"
+//	@Param			report_id	path		uint	true	"报告ID"
+//	@Param			sections	query		string	false	"包含的内容, 逗号分隔, 默认所有"
+//	@Success		200			{string}	string	"html"
+//	@Router			/v1/observability/tenant/{tenant_id}/reports/{report_id}/preview [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) PreviewReport(c *gin.Context) {
+	r, err := h.getReport(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	data, err := h.reporter().Build(c.Request.Context(), r, time.Now())
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	var sections []string
+	if s := c.Query("sections"); s != "" {
+		sections = strings.Split(s, ",")
+	}
+	html, err := report.Render(data, sections)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
+
+// SendReport 立即发送定时报告
+//
+//	@Tags			Observability
+//	@Summary		立即发送定时报告
+//	@Description	立即统计并发送报告, 不影响定时发送
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		string									true	"租户ID，所有租户为_all"
+//	@Param			report_id	path		uint									true	"报告ID"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/tenant/{tenant_id}/reports/{report_id}/send [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) SendReport(c *gin.Context) {
+	r, err := h.getReport(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetExtraAuditData(c, models.ResTenant, r.TenantID)
+	h.SetAuditData(c, i18n.Sprintf(c, "send"), i18n.Sprintf(c, "report"), r.Name)
+	if err := h.reporter().Send(c.Request.Context(), r, time.Now()); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
@@ -0,0 +1,586 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	prommodel "github.com/prometheus/common/model"
+	promlabels "github.com/prometheus/prometheus/pkg/labels"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+	"kubegems.io/kubegems/pkg/utils/prometheus/promql"
+)
+
+const (
+	SLOLabel = "slo"
+
+	sloRecordPrefix = "slo:sli_error:ratio_rate"
+	sloAlertFor     = "2m"
+	sloMinWindow    = 7 * 24 * time.Hour
+	sloMaxWindow    = 90 * 24 * time.Hour
+)
+
+// sloRecordWindows 燃烧率告警用到的时间窗口
+var sloRecordWindows = []string{"5m", "30m", "1h", "2h", "6h", "1d", "3d"}
+
+// sloBurnRateWindow 多窗口多燃烧率告警, 长窗口和短窗口同时超过阈值时告警,
+// 阈值 = 统计周期内消耗的预算比例 * 统计周期 / 长窗口, 30d 周期下分别为 14.4, 6, 3, 1
+type sloBurnRateWindow struct {
+	Long     string
+	Short    string
+	Consumed float64
+	Severity string
+}
+
+var sloBurnRateWindows = []sloBurnRateWindow{
+	{Long: "1h", Short: "5m", Consumed: 0.02, Severity: prometheus.SeverityCritical},
+	{Long: "6h", Short: "30m", Consumed: 0.05, Severity: prometheus.SeverityCritical},
+	{Long: "1d", Short: "2h", Consumed: 0.1, Severity: prometheus.SeverityError},
+	{Long: "3d", Short: "6h", Consumed: 0.1, Severity: prometheus.SeverityError},
+}
+
+func sloRecordName(window string) string {
+	return sloRecordPrefix + window
+}
+
+func sloRecordSelector(alertrule *models.AlertRule, window string) string {
+	return fmt.Sprintf(`%s{%s="%s",%s="%s"}`, sloRecordName(window),
+		prometheus.AlertNamespaceLabel, alertrule.Namespace, SLOLabel, alertrule.SLOGenerator.SLO)
+}
+
+// sloErrorBudget 允许的错误率, eg. 99.9 -> 0.001
+func sloErrorBudget(target float64) float64 {
+	return (100 - target) / 100
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+// GenerateSLOErrorRatio 根据 SLI 生成带 {{.window}} 占位符的错误率表达式
+func GenerateSLOErrorRatio(slo *models.SLO) (string, error) {
+	sli := slo.SLI
+	var ratio string
+	switch {
+	case sli.PromqlGenerator != nil:
+		if sli.PromqlGenerator.Tpl == nil {
+			return "", errors.New("promql template not set")
+		}
+		if sli.CompareOp == "" || sli.CompareValue == "" {
+			return "", errors.New("compareOp and compareValue are required when using promql template")
+		}
+		q, err := promql.New(sli.PromqlGenerator.Tpl.Expr)
+		if err != nil {
+			return "", err
+		}
+		q.AddLabelMatchers(&promlabels.Matcher{
+			Type:  promlabels.MatchEqual,
+			Name:  "namespace",
+			Value: slo.Namespace,
+		})
+		for _, m := range sli.PromqlGenerator.LabelMatchers {
+			q.AddLabelMatchers(m.ToPromqlLabelMatcher())
+		}
+		// 指标满足比较条件(即不达标)的时间占比
+		ratio = fmt.Sprintf("avg_over_time(((%s) %s bool (%s))[%s:1m])", q.String(), sli.CompareOp, sli.CompareValue, models.SLOWindowPlaceholder)
+	case sli.ErrorQuery != "" && sli.TotalQuery != "":
+		for _, query := range []string{sli.ErrorQuery, sli.TotalQuery} {
+			if !strings.Contains(query, models.SLOWindowPlaceholder) {
+				return "", errors.Errorf("query %s must contains window placeholder %s", query, models.SLOWindowPlaceholder)
+			}
+		}
+		ratio = fmt.Sprintf("(%s) / (%s)", sli.ErrorQuery, sli.TotalQuery)
+	default:
+		return "", errors.New("sli must set errorQuery and totalQuery, or promqlGenerator")
+	}
+	if _, err := promql.New(strings.ReplaceAll(ratio, models.SLOWindowPlaceholder, "5m")); err != nil {
+		return "", errors.Wrap(err, "sli expr not valid")
+	}
+	return ratio, nil
+}
+
+// GenerateSLORecordingGroup 生成 SLO 的 recording rules, 与告警规则写入同一个 PrometheusRule
+func GenerateSLORecordingGroup(alertrule *models.AlertRule) monitoringv1.RuleGroup {
+	gen := alertrule.SLOGenerator
+	labels := map[string]string{
+		prometheus.AlertNamespaceLabel: alertrule.Namespace,
+		SLOLabel:                       gen.SLO,
+	}
+	rg := monitoringv1.RuleGroup{Name: "slo:" + alertrule.Name}
+	for _, w := range sloRecordWindows {
+		rg.Rules = append(rg.Rules, monitoringv1.Rule{
+			Record: sloRecordName(w),
+			Expr:   intstr.FromString(strings.ReplaceAll(gen.ErrorRatio, models.SLOWindowPlaceholder, w)),
+			Labels: labels,
+		})
+	}
+	// 统计周期的错误率使用 5m 的结果计算, 避免长时间范围的查询
+	rg.Rules = append(rg.Rules, monitoringv1.Rule{
+		Record: sloRecordName(gen.Window),
+		Expr:   intstr.FromString(fmt.Sprintf("avg_over_time(%s[%s])", sloRecordSelector(alertrule, "5m"), gen.Window)),
+		Labels: labels,
+	})
+	return rg
+}
+
+// sloBurnRateExpr 生成对应告警级别的多窗口燃烧率告警表达式
+func sloBurnRateExpr(alertrule *models.AlertRule, severity string) string {
+	gen := alertrule.SLOGenerator
+	period, _ := prommodel.ParseDuration(gen.Window)
+	budget := sloErrorBudget(gen.Target)
+	exprs := []string{}
+	for _, w := range sloBurnRateWindows {
+		if w.Severity != severity {
+			continue
+		}
+		long, _ := prommodel.ParseDuration(w.Long)
+		threshold := formatFloat(w.Consumed * float64(period) / float64(long) * budget)
+		exprs = append(exprs, fmt.Sprintf("(%s > %s and %s > %s)",
+			sloRecordSelector(alertrule, w.Long), threshold,
+			sloRecordSelector(alertrule, w.Short), threshold,
+		))
+	}
+	return strings.Join(exprs, " or ")
+}
+
+func (p *AlertRuleProcessor) mutateSLO(ctx context.Context, slo *models.SLO) (*models.AlertRule, error) {
+	if err := models.IsValidAlertRuleName(slo.Name); err != nil {
+		return nil, err
+	}
+	if slo.Window == "" {
+		slo.Window = "30d"
+	}
+	// 保留用户填写的周期, 如 30d 不会被格式化为 4w2d, 解析结果仅用于校验
+	window, err := prommodel.ParseDuration(slo.Window)
+	if err != nil {
+		return nil, errors.Wrapf(err, "window %s not valid", slo.Window)
+	}
+	if time.Duration(window) < sloMinWindow || time.Duration(window) > sloMaxWindow {
+		return nil, i18n.Errorf(ctx, "slo window must between 7d and 90d")
+	}
+
+	if gen := slo.SLI.PromqlGenerator; gen != nil {
+		tpl, err := p.db.FindPromqlTpl(gen.Scope, gen.Resource, gen.Rule)
+		if err != nil {
+			return nil, err
+		}
+		gen.Tpl = tpl
+	}
+	ratio, err := GenerateSLOErrorRatio(slo)
+	if err != nil {
+		return nil, err
+	}
+
+	alertrule := &models.AlertRule{
+		Cluster:       slo.Cluster,
+		Namespace:     slo.Namespace,
+		Name:          slo.Name,
+		AlertType:     prometheus.AlertTypeMonitor,
+		For:           sloAlertFor,
+		InhibitLabels: []string{SLOLabel},
+		AlertLevels: models.AlertLevels{
+			{Severity: prometheus.SeverityCritical},
+			{Severity: prometheus.SeverityError},
+		},
+		Receivers: slo.Receivers,
+		SLOGenerator: &models.SLOGenerator{
+			SLO:        slo.Name,
+			ErrorRatio: ratio,
+			Target:     slo.Target,
+			Window:     slo.Window,
+		},
+		IsOpen: true,
+	}
+	if alertrule.Message, err = genarateMessage(alertrule); err != nil {
+		return nil, err
+	}
+	if alertrule.Expr, err = GenerateExpr(alertrule); err != nil {
+		return nil, err
+	}
+	if err := SetReceivers(alertrule, p.DBWithCtx(ctx)); err != nil {
+		return nil, err
+	}
+	return alertrule, checkAlertLevels(alertrule)
+}
+
+func (p *AlertRuleProcessor) CreateSLO(ctx context.Context, slo *models.SLO) error {
+	alertrule, err := p.mutateSLO(ctx, slo)
+	if err != nil {
+		return err
+	}
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.AlertRule{}).Where("cluster = ? and namespace = ? and name = ?", slo.Cluster, slo.Namespace, slo.Name).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.Errorf("alert rule %s is already exist", slo.Name)
+		}
+		if err := tx.Omit("Receivers.AlertChannel").Create(alertrule).Error; err != nil {
+			return err
+		}
+		slo.AlertRuleID = &alertrule.ID
+		if err := tx.Omit("AlertRule").Create(slo).Error; err != nil {
+			return err
+		}
+		return p.SyncAlertRule(ctx, alertrule)
+	})
+}
+
+func (p *AlertRuleProcessor) UpdateSLO(ctx context.Context, slo *models.SLO) error {
+	alertrule, err := p.mutateSLO(ctx, slo)
+	if err != nil {
+		return err
+	}
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		old := &models.AlertRule{}
+		if slo.AlertRuleID != nil {
+			if err := tx.First(old, *slo.AlertRuleID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		if old.ID == 0 {
+			// 告警规则丢失时重新创建
+			if err := tx.Omit("Receivers.AlertChannel").Create(alertrule).Error; err != nil {
+				return err
+			}
+		} else {
+			alertrule.ID = old.ID
+			alertrule.IsOpen = old.IsOpen
+			for _, rec := range alertrule.Receivers {
+				rec.AlertRuleID = old.ID
+			}
+			if err := updateReceiversInDB(alertrule, tx); err != nil {
+				return errors.Wrap(err, "update receivers")
+			}
+			if err := tx.Select("expr", "for", "message", "inhibit_labels", "alert_levels", "slo_generator").
+				Updates(alertrule).Error; err != nil {
+				return err
+			}
+		}
+		slo.AlertRuleID = &alertrule.ID
+		if err := tx.Select("service", "description", "sli_type", "sli", "target", "window", "alert_rule_id").
+			Updates(slo).Error; err != nil {
+			return err
+		}
+		return p.SyncAlertRule(ctx, alertrule)
+	})
+}
+
+func (p *AlertRuleProcessor) DeleteSLO(ctx context.Context, slo *models.SLO) error {
+	return p.DBWithCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(slo).Error; err != nil {
+			return err
+		}
+		if slo.AlertRuleID == nil {
+			return nil
+		}
+		alertrule := &models.AlertRule{}
+		if err := tx.First(alertrule, *slo.AlertRuleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := tx.Delete(alertrule).Error; err != nil {
+			return err
+		}
+		return p.deleteMonitorAlertRule(ctx, alertrule)
+	})
+}
+
+// checkSLOManaged 由 SLO 生成的告警规则只能通过 SLO 修改
+func checkSLOManaged(ctx context.Context, db *gorm.DB, cluster, namespace, name string) error {
+	var count int64
+	if err := db.Model(&models.AlertRule{}).
+		Where("cluster = ? and namespace = ? and name = ? and slo_generator is not null", cluster, namespace, name).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return i18n.Errorf(ctx, "alert rule %s is managed by slo, please modify the slo instead", name)
+	}
+	return nil
+}
+
+func (h *ObservabilityHandler) getSLO(c *gin.Context) (*models.SLO, error) {
+	slo := &models.SLO{}
+	if err := h.GetDB().WithContext(c.Request.Context()).
+		Preload("AlertRule.Receivers.AlertChannel").
+		First(slo, "cluster = ? and namespace = ? and name = ?", c.Param("cluster"), c.Param("namespace"), c.Param("name")).Error; err != nil {
+		return nil, err
+	}
+	if slo.AlertRule != nil {
+		slo.Receivers = slo.AlertRule.Receivers
+	}
+	return slo, nil
+}
+
+// ListSLO SLO列表
+//
+//	@Tags			Observability
+//	@Summary		SLO列表
+//	@Description	SLO列表
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string										true	"cluster"
+//	@Param			namespace	path		string										true	"namespace"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.SLO}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/slos [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) ListSLO(c *gin.Context) {
+	ret := []*models.SLO{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload("AlertRule").Order("name").
+		Find(&ret, "cluster = ? and namespace = ?", c.Param("cluster"), c.Param("namespace")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+// GetSLO SLO详情
+//
+//	@Tags			Observability
+//	@Summary		SLO详情
+//	@Description	SLO详情
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			name		path		string									true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.SLO}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/slos/{name} [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) GetSLO(c *gin.Context) {
+	slo, err := h.getSLO(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, slo)
+}
+
+// CreateSLO 创建SLO
+//
+//	@Tags			Observability
+//	@Summary		创建SLO
+//	@Description	创建SLO, 同时生成 recording rules 和多窗口多燃烧率告警
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			form		body		models.SLO								true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/slos [post]
+//	@Security		JWT
+func (h *ObservabilityHandler) CreateSLO(c *gin.Context) {
+	req := &models.SLO{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = 0
+	req.Cluster = c.Param("cluster")
+	req.Namespace = c.Param("namespace")
+	h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "create"), "SLO", req.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), req.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.CreateSLO(ctx, req)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// UpdateSLO 修改SLO
+//
+//	@Tags			Observability
+//	@Summary		修改SLO
+//	@Description	修改SLO, 同时更新生成的 recording rules 和告警
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			name		path		string									true	"name"
+//	@Param			form		body		models.SLO								true	"body"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/slos/{name} [put]
+//	@Security		JWT
+func (h *ObservabilityHandler) UpdateSLO(c *gin.Context) {
+	slo, err := h.getSLO(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := &models.SLO{}
+	if err := c.BindJSON(req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = slo.ID
+	req.Cluster = slo.Cluster
+	req.Namespace = slo.Namespace
+	req.Name = slo.Name
+	req.AlertRuleID = slo.AlertRuleID
+	h.SetExtraAuditDataByClusterNamespace(c, req.Cluster, req.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), "SLO", req.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), req.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.UpdateSLO(ctx, req)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// DeleteSLO 删除SLO
+//
+//	@Tags			Observability
+//	@Summary		删除SLO
+//	@Description	删除SLO, 同时删除生成的 recording rules 和告警
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string									true	"cluster"
+//	@Param			namespace	path		string									true	"namespace"
+//	@Param			name		path		string									true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/slos/{name} [delete]
+//	@Security		JWT
+func (h *ObservabilityHandler) DeleteSLO(c *gin.Context) {
+	slo, err := h.getSLO(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetExtraAuditDataByClusterNamespace(c, slo.Cluster, slo.Namespace)
+	h.SetAuditData(c, i18n.Sprintf(c, "delete"), "SLO", slo.Name)
+
+	if err := h.withAlertRuleProcessor(c.Request.Context(), slo.Cluster, func(ctx context.Context, p *AlertRuleProcessor) error {
+		return p.DeleteSLO(ctx, slo)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+type SLOErrorBudget struct {
+	Target float64 `json:"target"` // 目标, 百分比
+	Window string  `json:"window"` // 统计周期
+	// 统计周期内的实际达成率, 百分比, 没有数据时为空
+	SLI *float64 `json:"sli"`
+	// 统计周期内允许的错误率
+	ErrorBudget float64 `json:"errorBudget"`
+	// 剩余错误预算占比, 小于 0 表示预算已耗尽
+	ErrorBudgetRemaining *float64 `json:"errorBudgetRemaining"`
+	// 各个时间窗口的燃烧率, 1 表示正好在统计周期结束时耗尽预算
+	BurnRates map[string]*float64 `json:"burnRates"`
+	// 剩余错误预算的变化, 指定 start/end 时返回
+	History prommodel.Matrix `json:"history,omitempty"`
+}
+
+// SLOErrorBudget SLO错误预算
+//
+//	@Tags			Observability
+//	@Summary		SLO错误预算
+//	@Description	SLO错误预算, 包括统计周期内的达成率、剩余错误预算和各窗口的燃烧率
+//	@Accept			json
+//	@Produce		json
+//	@Param			cluster		path		string											true	"cluster"
+//	@Param			namespace	path		string											true	"namespace"
+//	@Param			name		path		string											true	"name"
+//	@Param			start		query		string											false	"剩余错误预算变化的开始时间"
+//	@Param			end			query		string											false	"剩余错误预算变化的结束时间"
+//	@Param			step		query		string											false	"step"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=SLOErrorBudget}	"resp"
+//	@Router			/v1/observability/cluster/{cluster}/namespaces/{namespace}/slos/{name}/errorbudget [get]
+//	@Security		JWT
+func (h *ObservabilityHandler) SLOErrorBudget(c *gin.Context) {
+	slo, err := h.getSLO(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if slo.AlertRule == nil || slo.AlertRule.SLOGenerator == nil {
+		handlers.NotOK(c, i18n.Errorf(c, "alert rule of slo %s not found", slo.Name))
+		return
+	}
+	alertrule := slo.AlertRule
+	budget := sloErrorBudget(alertrule.SLOGenerator.Target)
+	ret := &SLOErrorBudget{
+		Target:      alertrule.SLOGenerator.Target,
+		Window:      alertrule.SLOGenerator.Window,
+		ErrorBudget: budget,
+		BurnRates:   map[string]*float64{},
+	}
+	ctx := c.Request.Context()
+	cli, err := h.GetAgents().ClientOf(ctx, slo.Cluster)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	queryValue := func(query string) (*float64, error) {
+		vec, err := cli.Extend().PrometheusVector(ctx, query)
+		if err != nil || len(vec) == 0 {
+			return nil, err
+		}
+		v := float64(vec[0].Value)
+		return &v, nil
+	}
+
+	periodQuery := fmt.Sprintf("max(%s)", sloRecordSelector(alertrule, alertrule.SLOGenerator.Window))
+	errorRatio, err := queryValue(periodQuery)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if errorRatio != nil {
+		sli := (1 - *errorRatio) * 100
+		remaining := 1 - *errorRatio/budget
+		ret.SLI, ret.ErrorBudgetRemaining = &sli, &remaining
+	}
+	for _, w := range sloRecordWindows {
+		v, err := queryValue(fmt.Sprintf("max(%s) / %s", sloRecordSelector(alertrule, w), formatFloat(budget)))
+		if err != nil {
+			handlers.NotOK(c, err)
+			return
+		}
+		ret.BurnRates[w] = v
+	}
+	if start, end := c.Query("start"), c.Query("end"); start != "" && end != "" {
+		ret.History, err = cli.Extend().PrometheusQueryRange(ctx, fmt.Sprintf("1 - %s / %s", periodQuery, formatFloat(budget)), start, end, c.Query("step"))
+		if err != nil {
+			handlers.NotOK(c, err)
+			return
+		}
+	}
+	handlers.OK(c, ret)
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"testing"
+
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+)
+
+func TestGenerateSLOErrorRatio(t *testing.T) {
+	tests := []struct {
+		name    string
+		sli     models.SLOIndicator
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ratio",
+			sli: models.SLOIndicator{
+				ErrorQuery: `sum(rate(http_requests_total{namespace="ns",code=~"5.."}[{{.window}}]))`,
+				TotalQuery: `sum(rate(http_requests_total{namespace="ns"}[{{.window}}]))`,
+			},
+			want: `(sum(rate(http_requests_total{namespace="ns",code=~"5.."}[{{.window}}]))) / (sum(rate(http_requests_total{namespace="ns"}[{{.window}}])))`,
+		},
+		{
+			name: "missing window placeholder",
+			sli: models.SLOIndicator{
+				ErrorQuery: `sum(rate(http_requests_total{namespace="ns",code=~"5.."}[5m]))`,
+				TotalQuery: `sum(rate(http_requests_total{namespace="ns"}[{{.window}}]))`,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateSLOErrorRatio(&models.SLO{Namespace: "ns", SLI: tt.sli})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateSLOErrorRatio() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateSLOErrorRatio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSLOBurnRateExpr(t *testing.T) {
+	alertrule := &models.AlertRule{
+		Namespace: "ns",
+		Name:      "api",
+		SLOGenerator: &models.SLOGenerator{
+			SLO:    "api",
+			Target: 99.9,
+			Window: "30d",
+		},
+	}
+	sel := func(w string) string {
+		return `slo:sli_error:ratio_rate` + w + `{gems_namespace="ns",slo="api"}`
+	}
+	tests := []struct {
+		severity string
+		want     string
+	}{
+		{
+			severity: prometheus.SeverityCritical,
+			want: "(" + sel("1h") + " > 0.0144 and " + sel("5m") + " > 0.0144) or (" +
+				sel("6h") + " > 0.006 and " + sel("30m") + " > 0.006)",
+		},
+		{
+			severity: prometheus.SeverityError,
+			want: "(" + sel("1d") + " > 0.003 and " + sel("2h") + " > 0.003) or (" +
+				sel("3d") + " > 0.001 and " + sel("6h") + " > 0.001)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			if got := sloBurnRateExpr(alertrule, tt.severity); got != tt.want {
+				t.Errorf("sloBurnRateExpr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	rg := GenerateSLORecordingGroup(alertrule)
+	if len(rg.Rules) != len(sloRecordWindows)+1 {
+		t.Fatalf("recording rules = %d, want %d", len(rg.Rules), len(sloRecordWindows)+1)
+	}
+	if last := rg.Rules[len(rg.Rules)-1]; last.Record != "slo:sli_error:ratio_rate30d" {
+		t.Errorf("period recording rule = %s", last.Record)
+	}
+}
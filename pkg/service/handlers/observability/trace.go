@@ -16,6 +16,7 @@ package observability
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"kubegems.io/kubegems/pkg/service/handlers"
@@ -23,28 +24,46 @@ import (
 	"kubegems.io/kubegems/pkg/utils/agents"
 )
 
+// TraceDetail trace 以及每个服务的 pod 中包含 trace id 的日志
+type TraceDetail struct {
+	*observe.Trace
+	Logs []observe.TraceLogs `json:"logs"`
+}
+
 // GetTrace GetTrace by trace_id
 //	@Tags			Observability
 //	@Summary		GetTrace by trace_id
-//	@Description	GetTrace by trace_id
+//	@Description	GetTrace by trace_id, 同时返回每个服务的 pod 中包含 trace id 的日志, 仅包含有环境权限的命名空间
 //	@Accept			json
 //	@Produce		json
 //	@Param			cluster		path		string										true	"集群名"
 //	@Param			trace_id	path		string										true	"trace id"
-//	@Success		200			{object}	handlers.ResponseStruct{Data=observe.Trace}	"resp"
+//	@Param			logs		query		bool										false	"是否查询关联的日志, 默认为 true"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=TraceDetail}	"resp"
 //	@Router			/v1/observability/cluster/{cluster}/traces/{trace_id} [get]
 //	@Security		JWT
 func (h *ObservabilityHandler) GetTrace(c *gin.Context) {
 	// 前端传来的是UTC时间
-	var trace *observe.Trace
+	ret := &TraceDetail{Logs: []observe.TraceLogs{}}
+	withLogs, _ := strconv.ParseBool(c.DefaultQuery("logs", "true"))
 	if err := h.Execute(c.Request.Context(), c.Param("cluster"), func(ctx context.Context, cli agents.Client) error {
 		observecli := observe.NewClient(cli, h.GetDB().WithContext(ctx))
 		var err error
-		trace, err = observecli.GetTrace(ctx, c.Param("trace_id"))
-		return err
+		ret.Trace, err = observecli.GetTrace(ctx, c.Param("trace_id"))
+		if err != nil {
+			return err
+		}
+		if withLogs {
+			// 只返回有环境权限的命名空间中的日志
+			ret.Logs = observecli.GetTraceLogs(ctx, ret.Trace, func(namespace string) bool {
+				allowed, _, _ := h.HasEnvPerm(c, c.Param("cluster"), namespace)
+				return allowed
+			})
+		}
+		return nil
 	}); err != nil {
 		handlers.NotOK(c, err)
 		return
 	}
-	handlers.OK(c, trace)
+	handlers.OK(c, ret)
 }
@@ -0,0 +1,241 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidcclient
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils"
+)
+
+// OIDCClientWithSecret 创建和重置密钥时返回, 明文密钥仅返回这一次
+type OIDCClientWithSecret struct {
+	*models.OIDCClient
+	ClientSecret string `json:"clientSecret"`
+}
+
+// OIDCClientForm 修改客户端的表单, 未传 enabled 时不修改启用状态
+type OIDCClientForm struct {
+	models.OIDCClient
+	Enabled *bool `json:"enabled"`
+}
+
+// ListOIDCClient list oidc clients
+//
+//	@Tags			OIDCClient
+//	@Summary		OIDC客户端列表
+//	@Description	OIDC客户端列表
+//	@Accept			json
+//	@Produce		json
+//	@Param			page	query		int																			false	"page"
+//	@Param			size	query		int																			false	"size"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=handlers.PageData{List=[]models.OIDCClient}}	"OIDCClient"
+//	@Router			/v1/oidcclients [get]
+//	@Security		JWT
+func (h *OIDCClientHandler) ListOIDCClient(c *gin.Context) {
+	var list []models.OIDCClient
+	query, err := handlers.GetQuery(c, nil)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	cond := &handlers.PageQueryCond{
+		Model:        "OIDCClient",
+		SearchFields: []string{"ClientID", "Name"},
+		SortFields:   []string{"ClientID", "Name", "CreatedAt"},
+	}
+	total, page, size, err := query.PageList(h.GetDB().WithContext(c.Request.Context()), cond, &list)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, handlers.Page(total, list, int64(page), int64(size)))
+}
+
+// RetrieveOIDCClient get oidc client
+//
+//	@Tags			OIDCClient
+//	@Summary		OIDC客户端详情
+//	@Description	OIDC客户端详情
+//	@Accept			json
+//	@Produce		json
+//	@Param			client_id	path		string											true	"client_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.OIDCClient}	"OIDCClient"
+//	@Router			/v1/oidcclients/{client_id} [get]
+//	@Security		JWT
+func (h *OIDCClientHandler) RetrieveOIDCClient(c *gin.Context) {
+	client := &models.OIDCClient{}
+	if err := h.GetDB().WithContext(c.Request.Context()).First(client, "client_id = ?", c.Param("client_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, client)
+}
+
+// CreateOIDCClient create oidc client
+//
+//	@Tags			OIDCClient
+//	@Summary		创建OIDC客户端
+//	@Description	创建OIDC客户端, 返回的 clientSecret 仅展示一次
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		models.OIDCClient									true	"表单"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=OIDCClientWithSecret}	"OIDCClient"
+//	@Router			/v1/oidcclients [post]
+//	@Security		JWT
+func (h *OIDCClientHandler) CreateOIDCClient(c *gin.Context) {
+	client := &models.OIDCClient{}
+	if err := c.BindJSON(client); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	client.ID = 0
+	client.Enabled = true
+	secret, err := setClientSecret(client)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := h.GetDB().WithContext(c.Request.Context()).Create(client).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "创建", "OIDC客户端", client.ClientID)
+	handlers.Created(c, OIDCClientWithSecret{OIDCClient: client, ClientSecret: secret})
+}
+
+// ModifyOIDCClient modify oidc client
+//
+//	@Tags			OIDCClient
+//	@Summary		修改OIDC客户端
+//	@Description	修改OIDC客户端, 不会修改密钥
+//	@Accept			json
+//	@Produce		json
+//	@Param			client_id	path		string											true	"client_id"
+//	@Param			param		body		OIDCClientForm									true	"表单"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.OIDCClient}	"OIDCClient"
+//	@Router			/v1/oidcclients/{client_id} [put]
+//	@Security		JWT
+func (h *OIDCClientHandler) ModifyOIDCClient(c *gin.Context) {
+	client := &models.OIDCClient{}
+	ctx := c.Request.Context()
+	if err := h.GetDB().WithContext(ctx).First(client, "client_id = ?", c.Param("client_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	newOne := &OIDCClientForm{}
+	if err := c.BindJSON(newOne); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	now := time.Now()
+	client.Name = newOne.Name
+	client.ApplicationType = newOne.ApplicationType
+	client.RedirectURIs = newOne.RedirectURIs
+	client.PostLogoutRedirectURIs = newOne.PostLogoutRedirectURIs
+	client.GrantTypes = newOne.GrantTypes
+	client.AccessTokenType = newOne.AccessTokenType
+	if newOne.Enabled != nil {
+		client.Enabled = *newOne.Enabled
+	}
+	client.UpdatedAt = &now
+	if err := h.GetDB().WithContext(ctx).Save(client).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "修改", "OIDC客户端", client.ClientID)
+	handlers.OK(c, client)
+}
+
+// DeleteOIDCClient delete oidc client
+//
+//	@Tags			OIDCClient
+//	@Summary		删除OIDC客户端
+//	@Description	删除OIDC客户端, 同时吊销该客户端签发的所有token
+//	@Accept			json
+//	@Produce		json
+//	@Param			client_id	path		string									true	"client_id"
+//	@Success		204			{object}	handlers.ResponseStruct{Data=object}	"OIDCClient"
+//	@Router			/v1/oidcclients/{client_id} [delete]
+//	@Security		JWT
+func (h *OIDCClientHandler) DeleteOIDCClient(c *gin.Context) {
+	clientID := c.Param("client_id")
+	err := h.GetDB().WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for _, obj := range []any{&models.OIDCToken{}, &models.OIDCRefreshToken{}, &models.OIDCAuthRequest{}, &models.OIDCClient{}} {
+			if err := tx.Where("client_id = ?", clientID).Delete(obj).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "删除", "OIDC客户端", clientID)
+	handlers.NoContent(c, nil)
+}
+
+// ResetOIDCClientSecret reset oidc client secret
+//
+//	@Tags			OIDCClient
+//	@Summary		重置OIDC客户端密钥
+//	@Description	重置OIDC客户端密钥, 返回的 clientSecret 仅展示一次
+//	@Accept			json
+//	@Produce		json
+//	@Param			client_id	path		string												true	"client_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=OIDCClientWithSecret}	"OIDCClient"
+//	@Router			/v1/oidcclients/{client_id}/actions/reset-secret [post]
+//	@Security		JWT
+func (h *OIDCClientHandler) ResetOIDCClientSecret(c *gin.Context) {
+	client := &models.OIDCClient{}
+	ctx := c.Request.Context()
+	if err := h.GetDB().WithContext(ctx).First(client, "client_id = ?", c.Param("client_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	secret, err := setClientSecret(client)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := h.GetDB().WithContext(ctx).Model(client).Update("client_secret", client.ClientSecret).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "重置密钥", "OIDC客户端", client.ClientID)
+	handlers.OK(c, OIDCClientWithSecret{OIDCClient: client, ClientSecret: secret})
+}
+
+// setClientSecret 生成随机密钥, 仅保存hash, 返回明文
+func setClientSecret(client *models.OIDCClient) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(buf)
+	hashed, err := utils.MakePassword(secret)
+	if err != nil {
+		return "", err
+	}
+	client.ClientSecret = hashed
+	return secret, nil
+}
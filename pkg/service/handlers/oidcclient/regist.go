@@ -0,0 +1,34 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidcclient
+
+import (
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/service/handlers/base"
+)
+
+// OIDCClientHandler 管理注册到内置 OIDC Provider 的客户端
+type OIDCClientHandler struct {
+	base.BaseHandler
+}
+
+func (h *OIDCClientHandler) RegistRouter(rg *gin.RouterGroup) {
+	rg.GET("/oidcclients", h.CheckIsSysADMIN, h.ListOIDCClient)
+	rg.GET("/oidcclients/:client_id", h.CheckIsSysADMIN, h.RetrieveOIDCClient)
+	rg.POST("/oidcclients", h.CheckIsSysADMIN, h.CreateOIDCClient)
+	rg.PUT("/oidcclients/:client_id", h.CheckIsSysADMIN, h.ModifyOIDCClient)
+	rg.DELETE("/oidcclients/:client_id", h.CheckIsSysADMIN, h.DeleteOIDCClient)
+	rg.POST("/oidcclients/:client_id/actions/reset-secret", h.CheckIsSysADMIN, h.ResetOIDCClientSecret)
+}
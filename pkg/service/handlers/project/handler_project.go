@@ -30,6 +30,7 @@ import (
 	"kubegems.io/kubegems/pkg/i18n"
 	msgclient "kubegems.io/kubegems/pkg/msgbus/client"
 	"kubegems.io/kubegems/pkg/service/handlers"
+	tenanthandler "kubegems.io/kubegems/pkg/service/handlers/tenant"
 	"kubegems.io/kubegems/pkg/service/models"
 	"kubegems.io/kubegems/pkg/utils"
 	"kubegems.io/kubegems/pkg/utils/agents"
@@ -204,6 +205,7 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 /*
 删除项目后
 删除各个集群的环境(tenv),tenv本身删除是Controller自带垃圾回收的，其ns下所有资源将清空
+删除各个集群中项目的资源限制
 */
 func (h *ProjectHandler) afterProjectDelete(ctx context.Context, tx *gorm.DB, p *models.Project) error {
 	for _, env := range p.Environments {
@@ -217,6 +219,16 @@ func (h *ProjectHandler) afterProjectDelete(ctx context.Context, tx *gorm.DB, p
 			return err
 		}
 	}
+	// 删除集群中项目的资源限制
+	var trqs []models.TenantResourceQuota
+	if err := tx.Preload("Cluster").Find(&trqs, "tenant_id = ?", p.TenantID).Error; err != nil {
+		return err
+	}
+	for _, trq := range trqs {
+		if err := tenanthandler.RemoveProjectResourceQuota(ctx, h.BaseHandler, trq.Cluster.ClusterName, p.Tenant.TenantName, p.ProjectName); err != nil {
+			return err
+		}
+	}
 	// TODO: 删除 GIT 中的数据
 	// TODO: 删除 ARGO 中的数据
 	return nil
@@ -584,6 +584,8 @@ func (h *ProjectHandler) getProjectNoAggretateQuota(ctx context.Context, project
 type projectRes struct {
 	ResourceQuotaStatus *v1.ResourceQuotaStatus `json:"quota"`
 	Resource            map[string]interface{}  `json:"resource"`
+	// 项目在各个集群的资源限制、已申请和已使用的资源, 没有设置项目资源限制时 hard 为空
+	ClusterQuotas map[string]gemsv1beta1.ProjectResourceQuotaStatus `json:"clusterQuotas"`
 }
 
 // 获取项目在各个环境下的资源的聚合数据
@@ -621,11 +623,29 @@ func (h *ProjectHandler) getProjectAggretateQuota(ctx context.Context, projectId
 			"person":       personCount,
 		},
 	}
+	// 设置了项目资源限制但还没有环境的集群
+	projectQuotas := []models.ProjectResourceQuota{}
+	h.GetDB().WithContext(ctx).Preload("Cluster", func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("id, cluster_name")
+	}).Find(&projectQuotas, "project_id = ?", projectId)
+	for _, quota := range projectQuotas {
+		if _, exist := clusterMap[quota.Cluster.ClusterName]; !exist {
+			clusterMap[quota.Cluster.ClusterName] = nil
+		}
+	}
+	ret.ClusterQuotas = map[string]gemsv1beta1.ProjectResourceQuotaStatus{}
+
 	labels := map[string]string{
 		gemlabels.LabelTenant:  proj.Tenant.TenantName,
 		gemlabels.LabelProject: proj.ProjectName,
 	}
 	for cluster := range clusterMap {
+		tquota := &gemsv1beta1.TenantResourceQuota{}
+		if err := h.Execute(ctx, cluster, func(ctx context.Context, cli agents.Client) error {
+			return cli.Get(ctx, client.ObjectKey{Name: proj.Tenant.TenantName}, tquota)
+		}); err == nil {
+			ret.ClusterQuotas[cluster] = tquota.Status.Project(proj.ProjectName)
+		}
 
 		quotas := &v1.ResourceQuotaList{}
 		err := h.Execute(ctx, cluster, func(ctx context.Context, cli agents.Client) error {
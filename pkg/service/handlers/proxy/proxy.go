@@ -64,9 +64,12 @@ func (h *ProxyHandler) ProxyHTTP(c *gin.Context) {
 	// 权限
 	if proxyobj.InNamespace() {
 		h.CheckByClusterNamespace(c)
-		if c.IsAborted() {
-			return
-		}
+	} else {
+		// 集群级别资源不属于任何环境, 限定范围的令牌不能访问
+		h.CheckNotScopedToken(c)
+	}
+	if c.IsAborted() {
+		return
 	}
 	cli, err := h.GetAgents().ClientOf(c.Request.Context(), cluster)
 	if err != nil {
@@ -87,9 +90,12 @@ func (h *ProxyHandler) ProxyWebsocket(c *gin.Context) {
 	proxyobj := ParseProxyObj(c, proxyPath)
 	if proxyobj.InNamespace() {
 		h.CheckByClusterNamespace(c)
-		if c.IsAborted() {
-			return
-		}
+	} else {
+		// 集群级别资源不属于任何环境, 限定范围的令牌不能访问
+		h.CheckNotScopedToken(c)
+	}
+	if c.IsAborted() {
+		return
 	}
 
 	// NOTICE:
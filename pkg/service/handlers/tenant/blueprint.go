@@ -0,0 +1,313 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenanthandler
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/handlers/environment"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+// BlueprintInstantiateForm 使用租户模板创建租户
+type BlueprintInstantiateForm struct {
+	TenantName string          `json:"tenantName" binding:"required"`
+	Remark     string          `json:"remark"`
+	Users      []BlueprintUser `json:"users"`
+}
+
+type BlueprintUser struct {
+	UserID      uint   `json:"userID" binding:"required"`
+	TenantRole  string `json:"tenantRole" binding:"required,eq=admin|eq=ordinary"`
+	ProjectRole string `json:"projectRole" binding:"omitempty,eq=admin|eq=dev|eq=test|eq=ops"` // 为空时不加入模板中的项目
+}
+
+// ListTenantBlueprint 租户模板列表
+//
+//	@Tags			Tenant
+//	@Summary		租户模板列表
+//	@Description	租户模板列表
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	handlers.ResponseStruct{Data=[]models.TenantBlueprint}	"resp"
+//	@Router			/v1/tenantblueprint [get]
+//	@Security		JWT
+func (h *TenantHandler) ListTenantBlueprint(c *gin.Context) {
+	ret := []models.TenantBlueprint{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Order("name").Find(&ret).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, ret)
+}
+
+// GetTenantBlueprint 租户模板详情
+//
+//	@Tags			Tenant
+//	@Summary		租户模板详情
+//	@Description	租户模板详情
+//	@Accept			json
+//	@Produce		json
+//	@Param			blueprint_id	path		uint												true	"blueprint_id"
+//	@Success		200				{object}	handlers.ResponseStruct{Data=models.TenantBlueprint}	"resp"
+//	@Router			/v1/tenantblueprint/{blueprint_id} [get]
+//	@Security		JWT
+func (h *TenantHandler) GetTenantBlueprint(c *gin.Context) {
+	blueprint := models.TenantBlueprint{}
+	if err := h.GetDB().WithContext(c.Request.Context()).First(&blueprint, "id = ?", c.Param("blueprint_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, blueprint)
+}
+
+// CreateTenantBlueprint 创建租户模板
+//
+//	@Tags			Tenant
+//	@Summary		创建租户模板
+//	@Description	创建租户模板, 名称和命名空间中可以使用 {tenant} {project} {environment} 占位符
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		models.TenantBlueprint								true	"表单"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=models.TenantBlueprint}	"resp"
+//	@Router			/v1/tenantblueprint [post]
+//	@Security		JWT
+func (h *TenantHandler) CreateTenantBlueprint(c *gin.Context) {
+	req := models.TenantBlueprint{}
+	if err := c.BindJSON(&req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req.ID = 0
+	if err := ValidateTenantBlueprint(c, &req.Spec); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if user, exist := h.GetContextUser(c); exist {
+		req.Creator = user.GetUsername()
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "create"), i18n.Sprintf(c, "tenant blueprint"), req.Name)
+	if err := h.GetDB().WithContext(c.Request.Context()).Create(&req).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.Created(c, req)
+}
+
+// UpdateTenantBlueprint 修改租户模板
+//
+//	@Tags			Tenant
+//	@Summary		修改租户模板
+//	@Description	修改租户模板, 不影响已经创建的租户
+//	@Accept			json
+//	@Produce		json
+//	@Param			blueprint_id	path		uint												true	"blueprint_id"
+//	@Param			param			body		models.TenantBlueprint								true	"表单"
+//	@Success		200				{object}	handlers.ResponseStruct{Data=models.TenantBlueprint}	"resp"
+//	@Router			/v1/tenantblueprint/{blueprint_id} [put]
+//	@Security		JWT
+func (h *TenantHandler) UpdateTenantBlueprint(c *gin.Context) {
+	ctx := c.Request.Context()
+	blueprint := models.TenantBlueprint{}
+	if err := h.GetDB().WithContext(ctx).First(&blueprint, "id = ?", c.Param("blueprint_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	req := models.TenantBlueprint{}
+	if err := c.BindJSON(&req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := ValidateTenantBlueprint(c, &req.Spec); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	blueprint.Name = req.Name
+	blueprint.Description = req.Description
+	blueprint.Spec = req.Spec
+	h.SetAuditData(c, i18n.Sprintf(c, "update"), i18n.Sprintf(c, "tenant blueprint"), blueprint.Name)
+	if err := h.GetDB().WithContext(ctx).Save(&blueprint).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, blueprint)
+}
+
+// DeleteTenantBlueprint 删除租户模板
+//
+//	@Tags			Tenant
+//	@Summary		删除租户模板
+//	@Description	删除租户模板, 不影响已经创建的租户
+//	@Accept			json
+//	@Produce		json
+//	@Param			blueprint_id	path		uint					true	"blueprint_id"
+//	@Success		204				{object}	handlers.ResponseStruct	"resp"
+//	@Router			/v1/tenantblueprint/{blueprint_id} [delete]
+//	@Security		JWT
+func (h *TenantHandler) DeleteTenantBlueprint(c *gin.Context) {
+	ctx := c.Request.Context()
+	blueprint := models.TenantBlueprint{}
+	if err := h.GetDB().WithContext(ctx).First(&blueprint, "id = ?", c.Param("blueprint_id")).Error; err != nil {
+		handlers.NoContent(c, nil)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "delete"), i18n.Sprintf(c, "tenant blueprint"), blueprint.Name)
+	if err := h.GetDB().WithContext(ctx).Delete(&blueprint).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.NoContent(c, nil)
+}
+
+// InstantiateTenantBlueprint 使用租户模板创建租户
+//
+//	@Tags			Tenant
+//	@Summary		使用租户模板创建租户
+//	@Description	按模板创建租户、集群配额、项目、环境、网络策略、网关、镜像仓库、告警规则和监控面板, 任意一步失败都会回滚
+//	@Accept			json
+//	@Produce		json
+//	@Param			blueprint_id	path		uint										true	"blueprint_id"
+//	@Param			param			body		BlueprintInstantiateForm					true	"表单"
+//	@Success		200				{object}	handlers.ResponseStruct{Data=models.Tenant}	"Tenant"
+//	@Router			/v1/tenantblueprint/{blueprint_id}/instantiate [post]
+//	@Security		JWT
+func (h *TenantHandler) InstantiateTenantBlueprint(c *gin.Context) {
+	ctx := c.Request.Context()
+	blueprint := models.TenantBlueprint{}
+	if err := h.GetDB().WithContext(ctx).First(&blueprint, "id = ?", c.Param("blueprint_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	form := BlueprintInstantiateForm{}
+	if err := c.BindJSON(&form); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := ValidateTenantBlueprint(c, &blueprint.Spec); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, i18n.Sprintf(c, "instantiate"), i18n.Sprintf(c, "tenant blueprint"), i18n.Sprintf(c, "tenant blueprint %s / tenant %s", blueprint.Name, form.TenantName))
+
+	ins := &blueprintInstantiator{
+		h:         h,
+		blueprint: &blueprint,
+		form:      &form,
+	}
+	if user, exist := h.GetContextUser(c); exist {
+		ins.creatorID = user.GetID()
+		ins.creator = user.GetUsername()
+	}
+	err := h.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ins.tx = tx
+		if err := ins.instantiate(ctx); err != nil {
+			ins.rollback(ctx)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := ins.apply(ctx); err != nil {
+		ins.compensate(ctx)
+		handlers.NotOK(c, err)
+		return
+	}
+	ins.afterCommit(c)
+	h.SetExtraAuditData(c, models.ResTenant, ins.tenant.ID)
+	handlers.Created(c, ins.tenant)
+}
+
+// ValidateTenantBlueprint 校验租户模板本身, 不检查集群和数据库中的资源
+func ValidateTenantBlueprint(ctx context.Context, spec *models.TenantBlueprintSpec) error {
+	clusters := map[string]bool{}
+	for _, cluster := range spec.Clusters {
+		if cluster.Cluster == "" {
+			return i18n.Errorf(ctx, "cluster name is required")
+		}
+		if clusters[cluster.Cluster] {
+			return i18n.Errorf(ctx, "duplicated cluster %s", cluster.Cluster)
+		}
+		clusters[cluster.Cluster] = true
+		if len(cluster.ResourceQuota) == 0 {
+			return i18n.Errorf(ctx, "resource quota of cluster %s is required", cluster.Cluster)
+		}
+		for _, gateway := range cluster.Gateways {
+			if gateway.Name == "" {
+				return i18n.Errorf(ctx, "gateway name is required")
+			}
+		}
+	}
+
+	projects := map[string]bool{}
+	environments := map[string]bool{}
+	namespaces := map[string]bool{}
+	for _, project := range spec.Projects {
+		if project.Name == "" {
+			return i18n.Errorf(ctx, "project name is required")
+		}
+		if projects[project.Name] {
+			return i18n.Errorf(ctx, "duplicated project %s", project.Name)
+		}
+		projects[project.Name] = true
+		for cluster := range project.ResourceQuotas {
+			if !clusters[cluster] {
+				return i18n.Errorf(ctx, "cluster %s is not in the blueprint clusters", cluster)
+			}
+		}
+		for _, env := range project.Environments {
+			if env.Name == "" {
+				return i18n.Errorf(ctx, "environment name is required")
+			}
+			if !clusters[env.Cluster] {
+				return i18n.Errorf(ctx, "cluster %s is not in the blueprint clusters", env.Cluster)
+			}
+			// 租户名在实例化时才能确定, 保留占位符判断重复
+			name, namespace := blueprintEnvironmentName(models.BlueprintPlaceholderTenant, project.Name, env)
+			if environments[name] {
+				return i18n.Errorf(ctx, "duplicated environment %s", name)
+			}
+			environments[name] = true
+			namespace = env.Cluster + "/" + namespace
+			if namespaces[namespace] {
+				return i18n.Errorf(ctx, "duplicated namespace %s", namespace)
+			}
+			namespaces[namespace] = true
+			if env.SleepSchedule != nil {
+				if err := environment.ValidateSleepSchedule(ctx, env.SleepSchedule); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, dashboard := range spec.Dashboards {
+		if dashboard.Name == "" || dashboard.Template == "" {
+			return i18n.Errorf(ctx, "dashboard name and template are required")
+		}
+	}
+	for _, rule := range spec.AlertRules {
+		if err := models.IsValidAlertRuleName(rule.AlertRule.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
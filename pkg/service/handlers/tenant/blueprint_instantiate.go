@@ -0,0 +1,591 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenanthandler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/log"
+	msgclient "kubegems.io/kubegems/pkg/msgbus/client"
+	"kubegems.io/kubegems/pkg/service/handlers/environment"
+	"kubegems.io/kubegems/pkg/service/handlers/observability"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/msgbus"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// 等待租户控制器创建 TenantNetworkPolicy 的时间
+	blueprintNetworkPolicyTimeout = 30 * time.Second
+	// 事务提交后等待集群资源就绪并同步网络策略和告警规则的总时间
+	blueprintApplyTimeout = 2 * time.Minute
+)
+
+// blueprintInstantiator 在同一个数据库事务中按模板创建租户, 需要等待集群的步骤在事务提交后执行;
+// 集群中的资源无法随事务回滚, 每创建一个就记录对应的删除操作, 失败时逆序执行
+type blueprintInstantiator struct {
+	h         *TenantHandler
+	tx        *gorm.DB
+	blueprint *models.TenantBlueprint
+	form      *BlueprintInstantiateForm
+	creatorID uint
+	creator   string
+
+	tenant       *models.Tenant
+	clusters     map[string]*models.Cluster
+	users        []*models.User
+	projects     []*models.Project
+	environments []*blueprintEnvironment
+	rollbacks    []func(ctx context.Context) error
+}
+
+type blueprintEnvironment struct {
+	*models.Environment
+	cluster    string
+	isolated   bool
+	alertRules []*models.AlertRule
+}
+
+// blueprintEnvironmentName 返回替换占位符后的环境名和命名空间, 命名空间为空时和环境名相同
+func blueprintEnvironmentName(tenant, project string, env models.BlueprintEnvironment) (string, string) {
+	name := models.RenderBlueprintName(env.Name, tenant, project, "")
+	if env.Namespace == "" {
+		return name, name
+	}
+	return name, models.RenderBlueprintName(env.Namespace, tenant, project, name)
+}
+
+func (ins *blueprintInstantiator) instantiate(ctx context.Context) error {
+	steps := []func(ctx context.Context) error{
+		ins.prepare,
+		ins.createTenant,
+		ins.createTenantResourceQuotas,
+		ins.createProjects,
+		ins.createEnvironments,
+		ins.createGateways,
+		ins.createObservability,
+	}
+	for _, step := range steps {
+		if err := step(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply 事务提交后等待租户和命名空间就绪, 更新网络策略并同步告警规则, 总时间不超过 blueprintApplyTimeout
+func (ins *blueprintInstantiator) apply(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, blueprintApplyTimeout)
+	defer cancel()
+	for _, step := range []func(ctx context.Context) error{
+		ins.applyNetworkPolicies,
+		ins.syncAlertRules,
+	} {
+		if err := step(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ins *blueprintInstantiator) onRollback(cluster string, f func(ctx context.Context, cli agents.Client) error) {
+	ins.rollbacks = append(ins.rollbacks, func(ctx context.Context) error {
+		return ins.h.Execute(ctx, cluster, func(ctx context.Context, cli agents.Client) error {
+			return client.IgnoreNotFound(f(ctx, cli))
+		})
+	})
+}
+
+// rollback 逆序删除已经在集群中创建的资源, 数据库中的记录由事务回滚
+func (ins *blueprintInstantiator) rollback(ctx context.Context) {
+	// 请求可能已经被取消, 回滚仍然需要执行
+	ctx = context.WithoutCancel(ctx)
+	for i := len(ins.rollbacks) - 1; i >= 0; i-- {
+		if err := ins.rollbacks[i](ctx); err != nil {
+			log.Error(err, "rollback tenant blueprint", "blueprint", ins.blueprint.Name, "tenant", ins.form.TenantName)
+		}
+	}
+}
+
+// compensate 事务提交后的步骤失败时删除集群中的资源和已经提交的租户
+func (ins *blueprintInstantiator) compensate(ctx context.Context) {
+	ins.rollback(ctx)
+	ctx = context.WithoutCancel(ctx)
+	err := ins.h.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, env := range ins.environments {
+			if err := tx.Where("environment_id = ?", env.ID).Delete(&models.MonitorDashboard{}).Error; err != nil {
+				return err
+			}
+			for _, rule := range env.alertRules {
+				if err := tx.Select("Receivers").Delete(rule).Error; err != nil {
+					return err
+				}
+			}
+		}
+		// 项目、环境等随租户级联删除
+		return tx.Delete(ins.tenant).Error
+	})
+	if err != nil {
+		log.Error(err, "compensate tenant blueprint", "blueprint", ins.blueprint.Name, "tenant", ins.form.TenantName)
+	}
+}
+
+// prepare 检查模板引用的集群、用户、监控面板模板是否存在, 以及命名空间是否已经被占用
+func (ins *blueprintInstantiator) prepare(ctx context.Context) error {
+	spec := &ins.blueprint.Spec
+	ins.clusters = map[string]*models.Cluster{}
+	for _, bc := range spec.Clusters {
+		cluster := &models.Cluster{}
+		if err := ins.tx.Select("id, cluster_name").First(cluster, "cluster_name = ?", bc.Cluster).Error; err != nil {
+			return i18n.Errorf(ctx, "cluster %s is not found", bc.Cluster)
+		}
+		ins.clusters[bc.Cluster] = cluster
+	}
+	for _, bu := range ins.form.Users {
+		user := &models.User{}
+		if err := ins.tx.Preload("SystemRole").First(user, "id = ?", bu.UserID).Error; err != nil {
+			return i18n.Errorf(ctx, "the user to add is not found")
+		}
+		ins.users = append(ins.users, user)
+	}
+	for _, dashboard := range spec.Dashboards {
+		if err := ins.tx.First(&models.MonitorDashboardTpl{}, "name = ?", dashboard.Template).Error; err != nil {
+			return i18n.Errorf(ctx, "dashboard template %s is not found", dashboard.Template)
+		}
+	}
+	// 回滚时会删除环境的命名空间, 所以只允许使用新的命名空间
+	for _, bp := range spec.Projects {
+		project := models.RenderBlueprintName(bp.Name, ins.form.TenantName, "", "")
+		for _, be := range bp.Environments {
+			_, namespace := blueprintEnvironmentName(ins.form.TenantName, project, be)
+			err := ins.h.Execute(ctx, be.Cluster, func(ctx context.Context, cli agents.Client) error {
+				return cli.Get(ctx, client.ObjectKey{Name: namespace}, &v1.Namespace{})
+			})
+			if err == nil {
+				return i18n.Errorf(ctx, "namespace %s already exists in cluster %s", namespace, be.Cluster)
+			}
+			if !kerrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ins *blueprintInstantiator) createTenant(ctx context.Context) error {
+	ins.tenant = &models.Tenant{
+		TenantName: ins.form.TenantName,
+		Remark:     ins.form.Remark,
+		IsActive:   true,
+	}
+	if err := ins.tx.Create(ins.tenant).Error; err != nil {
+		return err
+	}
+	for i, bu := range ins.form.Users {
+		rel := &models.TenantUserRels{TenantID: ins.tenant.ID, UserID: ins.users[i].ID, Role: bu.TenantRole}
+		if err := ins.tx.Create(rel).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ins *blueprintInstantiator) createTenantResourceQuotas(ctx context.Context) error {
+	for _, bc := range ins.blueprint.Spec.Clusters {
+		content, err := json.Marshal(bc.ResourceQuota)
+		if err != nil {
+			return err
+		}
+		trq := &models.TenantResourceQuota{
+			TenantID:  ins.tenant.ID,
+			ClusterID: ins.clusters[bc.Cluster].ID,
+			Content:   content,
+		}
+		if err := ins.tx.Create(trq).Error; err != nil {
+			return err
+		}
+		// 租户的配额和网络策略随租户一起删除
+		tenantName := ins.tenant.TenantName
+		ins.onRollback(bc.Cluster, func(ctx context.Context, cli agents.Client) error {
+			return cli.Delete(ctx, &v1beta1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: tenantName}})
+		})
+		if err := AfterTenantResourceQuotaSave(ctx, ins.h.BaseHandler, ins.tx, trq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ins *blueprintInstantiator) createProjects(ctx context.Context) error {
+	for _, bp := range ins.blueprint.Spec.Projects {
+		project := &models.Project{
+			ProjectName:  models.RenderBlueprintName(bp.Name, ins.tenant.TenantName, "", ""),
+			ProjectAlias: bp.Alias,
+			Remark:       bp.Remark,
+			TenantID:     ins.tenant.ID,
+		}
+		if err := ins.tx.Create(project).Error; err != nil {
+			return err
+		}
+		ins.projects = append(ins.projects, project)
+
+		for i, bu := range ins.form.Users {
+			if bu.ProjectRole == "" {
+				continue
+			}
+			rel := &models.ProjectUserRels{ProjectID: project.ID, UserID: ins.users[i].ID, Role: bu.ProjectRole}
+			if err := ins.tx.Create(rel).Error; err != nil {
+				return err
+			}
+		}
+		for _, br := range ins.blueprint.Spec.Registries {
+			registry := &models.Registry{
+				RegistryName:    br.Name,
+				RegistryAddress: br.Address,
+				Username:        br.Username,
+				Password:        br.Password,
+				IsDefault:       br.IsDefault,
+				UpdateTime:      time.Now(),
+				CreatorID:       ins.creatorID,
+				ProjectID:       project.ID,
+			}
+			if err := ins.tx.Create(registry).Error; err != nil {
+				return err
+			}
+		}
+		for cluster, hard := range bp.ResourceQuotas {
+			content, err := json.Marshal(hard)
+			if err != nil {
+				return err
+			}
+			prq := &models.ProjectResourceQuota{
+				ProjectID: project.ID,
+				ClusterID: ins.clusters[cluster].ID,
+				Project:   project,
+			}
+			if err := ValidateProjectResourceQuota(ctx, ins.tx, prq, content); err != nil {
+				return err
+			}
+			prq.Content = content
+			if err := ins.tx.Omit("Project", "Cluster", "TenantResourceQuotaApply").Create(prq).Error; err != nil {
+				return err
+			}
+			if err := AfterProjectResourceQuotaSave(ctx, ins.h.BaseHandler, ins.tx, prq); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ins *blueprintInstantiator) createEnvironments(ctx context.Context) error {
+	for i, bp := range ins.blueprint.Spec.Projects {
+		project := ins.projects[i]
+		for _, be := range bp.Environments {
+			name, namespace := blueprintEnvironmentName(ins.tenant.TenantName, project.ProjectName, be)
+			env := &models.Environment{
+				EnvironmentName: name,
+				Namespace:       namespace,
+				Remark:          be.Remark,
+				MetaType:        be.MetaType,
+				DeletePolicy:    be.DeletePolicy,
+				ProjectID:       project.ID,
+				ClusterID:       ins.clusters[be.Cluster].ID,
+				CreatorID:       ins.creatorID,
+			}
+			if env.DeletePolicy == "" {
+				env.DeletePolicy = v1beta1.DeletePolicyDelNamespace
+			}
+			if len(be.ResourceQuota) > 0 {
+				content, err := json.Marshal(be.ResourceQuota)
+				if err != nil {
+					return err
+				}
+				env.ResourceQuota = content
+			}
+			if be.SleepSchedule != nil {
+				content, err := json.Marshal(be.SleepSchedule)
+				if err != nil {
+					return err
+				}
+				env.SleepSchedule = content
+			}
+			env.LimitRange = models.FillDefaultLimigrange(env)
+			if err := ins.tx.Create(env).Error; err != nil {
+				return err
+			}
+			ins.environments = append(ins.environments, &blueprintEnvironment{Environment: env, cluster: be.Cluster, isolated: be.NetworkIsolated})
+
+			// 命名空间是本次新建的, 回滚时不论删除策略都一并删除
+			ins.onRollback(be.Cluster, func(ctx context.Context, cli agents.Client) error {
+				envobj := &v1beta1.Environment{}
+				if err := cli.Get(ctx, client.ObjectKey{Name: name}, envobj); err != nil {
+					return err
+				}
+				if envobj.Spec.DeletePolicy != v1beta1.DeletePolicyDelNamespace {
+					envobj.Spec.DeletePolicy = v1beta1.DeletePolicyDelNamespace
+					if err := cli.Update(ctx, envobj); err != nil {
+						return err
+					}
+				}
+				return cli.Delete(ctx, envobj)
+			})
+			if err := environment.AfterEnvironmentSave(ctx, ins.h.BaseHandler, ins.tx, env); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ins *blueprintInstantiator) applyNetworkPolicies(ctx context.Context) error {
+	for _, bc := range ins.blueprint.Spec.Clusters {
+		spec := v1beta1.TenantNetworkPolicySpec{}
+		if bc.NetworkPolicy != nil {
+			spec.TenantIsolated = bc.NetworkPolicy.TenantIsolated
+			spec.Rules = bc.NetworkPolicy.Rules
+		}
+		for i, bp := range ins.blueprint.Spec.Projects {
+			if bp.NetworkIsolated {
+				spec.ProjectNetworkPolicies = append(spec.ProjectNetworkPolicies, v1beta1.ProjectNetworkPolicy{Name: ins.projects[i].ProjectName})
+			}
+		}
+		for _, env := range ins.environments {
+			if env.isolated && env.cluster == bc.Cluster {
+				spec.EnvironmentNetworkPolicies = append(spec.EnvironmentNetworkPolicies, v1beta1.EnvironmentNetworkPolicy{
+					Name:    env.EnvironmentName,
+					Project: ins.projectOf(env).ProjectName,
+				})
+			}
+		}
+		if !spec.TenantIsolated && len(spec.Rules) == 0 && len(spec.ProjectNetworkPolicies) == 0 && len(spec.EnvironmentNetworkPolicies) == 0 {
+			continue
+		}
+		err := ins.h.Execute(ctx, bc.Cluster, func(ctx context.Context, cli agents.Client) error {
+			tnetpol := &v1beta1.TenantNetworkPolicy{}
+			// TenantNetworkPolicy 由租户控制器创建
+			if err := wait.PollImmediateWithContext(ctx, time.Second, blueprintNetworkPolicyTimeout, func(ctx context.Context) (bool, error) {
+				if err := cli.Get(ctx, client.ObjectKey{Name: ins.tenant.TenantName}, tnetpol); err != nil {
+					return false, client.IgnoreNotFound(err)
+				}
+				return true, nil
+			}); err != nil {
+				return err
+			}
+			tnetpol.Spec.TenantIsolated = spec.TenantIsolated
+			tnetpol.Spec.Rules = spec.Rules
+			tnetpol.Spec.ProjectNetworkPolicies = spec.ProjectNetworkPolicies
+			tnetpol.Spec.EnvironmentNetworkPolicies = spec.EnvironmentNetworkPolicies
+			return cli.Update(ctx, tnetpol)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ins *blueprintInstantiator) createGateways(ctx context.Context) error {
+	for _, bc := range ins.blueprint.Spec.Clusters {
+		for _, bg := range bc.Gateways {
+			tg := &v1beta1.TenantGateway{
+				ObjectMeta: metav1.ObjectMeta{Name: models.RenderBlueprintName(bg.Name, ins.tenant.TenantName, "", "")},
+				Spec:       bg.Spec,
+			}
+			tg.Spec.Tenant = ins.tenant.TenantName
+			if err := ins.h.createGateway(ctx, bc.Cluster, tg); err != nil {
+				return err
+			}
+			name := tg.Name
+			ins.onRollback(bc.Cluster, func(ctx context.Context, cli agents.Client) error {
+				return cli.Delete(ctx, &v1beta1.TenantGateway{ObjectMeta: metav1.ObjectMeta{Name: name}})
+			})
+		}
+	}
+	return nil
+}
+
+// createObservability 在环境中创建告警规则和监控面板, 告警规则在事务提交后由 syncAlertRules 同步到集群
+func (ins *blueprintInstantiator) createObservability(ctx context.Context) error {
+	spec := &ins.blueprint.Spec
+	if len(spec.AlertRules) == 0 && len(spec.Dashboards) == 0 {
+		return nil
+	}
+	tplGetter := ins.h.GetDataBase().NewPromqlTplMapperFromDB().FindPromqlTpl
+	for _, env := range ins.environments {
+		for _, bd := range spec.Dashboards {
+			if !models.MatchMetaType(bd.MetaTypes, env.MetaType) {
+				continue
+			}
+			tpl := &models.MonitorDashboardTpl{}
+			if err := ins.tx.First(tpl, "name = ?", bd.Template).Error; err != nil {
+				return err
+			}
+			dashboard := &models.MonitorDashboard{
+				Name:          bd.Name,
+				Template:      tpl.Name,
+				Step:          tpl.Step,
+				Refresh:       tpl.Refresh,
+				Start:         tpl.Start,
+				End:           tpl.End,
+				Graphs:        tpl.Graphs,
+				Variables:     tpl.Variables,
+				Creator:       ins.creator,
+				EnvironmentID: &env.ID,
+			}
+			if dashboard.Start == "" || dashboard.End == "" {
+				dashboard.Start = "now-30m"
+				dashboard.End = "now"
+			}
+			if dashboard.Refresh == "" {
+				dashboard.Refresh = "30s"
+			}
+			if dashboard.Step == "" {
+				dashboard.Step = "30s"
+			}
+			if err := models.CheckGraphs(dashboard.Graphs, env.Namespace, tplGetter); err != nil {
+				return err
+			}
+			if err := ins.tx.Create(dashboard).Error; err != nil {
+				return err
+			}
+		}
+
+		rules := []*models.AlertRule{}
+		for _, br := range spec.AlertRules {
+			if !models.MatchMetaType(br.MetaTypes, env.MetaType) {
+				continue
+			}
+			rule, err := copyBlueprintAlertRule(&br.AlertRule)
+			if err != nil {
+				return err
+			}
+			rule.Cluster = env.cluster
+			rule.Namespace = env.Namespace
+			rules = append(rules, rule)
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		cli, err := ins.h.GetAgents().ClientOf(ctx, env.cluster)
+		if err != nil {
+			return err
+		}
+		processor := observability.NewAlertRuleProcessor(cli, ins.h.GetDataBase())
+		for _, rule := range rules {
+			if err := processor.MutateAlertRule(ctx, rule); err != nil {
+				return err
+			}
+			if err := ins.tx.Omit("Receivers.AlertChannel").Create(rule).Error; err != nil {
+				return err
+			}
+		}
+		env.alertRules = rules
+	}
+	return nil
+}
+
+// syncAlertRules 等待命名空间创建完成后同步告警规则, 告警相关的资源都在环境的命名空间中, 随环境一起回滚
+func (ins *blueprintInstantiator) syncAlertRules(ctx context.Context) error {
+	for _, env := range ins.environments {
+		if len(env.alertRules) == 0 {
+			continue
+		}
+		cli, err := ins.h.GetAgents().ClientOf(ctx, env.cluster)
+		if err != nil {
+			return err
+		}
+		if err := environment.WaitNamespace(ctx, cli, env.Namespace); err != nil {
+			return err
+		}
+		processor := observability.NewAlertRuleProcessor(cli, ins.h.GetDataBase())
+		for _, rule := range env.alertRules {
+			if err := processor.SyncAlertRule(ctx, rule); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyBlueprintAlertRule 复制模板中的告警规则, 每个环境使用独立的规则和接收器
+func copyBlueprintAlertRule(in *models.AlertRule) (*models.AlertRule, error) {
+	content, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	rule := &models.AlertRule{}
+	if err := json.Unmarshal(content, rule); err != nil {
+		return nil, err
+	}
+	rule.ID = 0
+	rule.State = ""
+	rule.CreatedAt, rule.UpdatedAt = nil, nil
+	for _, rec := range rule.Receivers {
+		rec.ID, rec.AlertRuleID, rec.AlertChannel = 0, 0, nil
+	}
+	if rule.AlertType == "" {
+		rule.AlertType = prometheus.AlertTypeMonitor
+	}
+	return rule, nil
+}
+
+func (ins *blueprintInstantiator) projectOf(env *blueprintEnvironment) *models.Project {
+	for _, project := range ins.projects {
+		if project.ID == env.ProjectID {
+			return project
+		}
+	}
+	return nil
+}
+
+// afterCommit 事务提交后刷新缓存和用户权限, 并通知管理员
+func (ins *blueprintInstantiator) afterCommit(c *gin.Context) {
+	cache := ins.h.ModelCache()
+	cache.UpsertTenant(ins.tenant.ID, ins.tenant.TenantName)
+	for _, project := range ins.projects {
+		_ = cache.UpsertProject(ins.tenant.ID, project.ID, project.ProjectName)
+	}
+	for _, env := range ins.environments {
+		cache.UpsertEnvironment(env.ProjectID, env.ID, env.EnvironmentName, env.cluster, env.Namespace)
+	}
+	for _, user := range ins.users {
+		cache.FlushUserAuthority(user)
+	}
+
+	ins.h.SendToMsgbus(c, func(msg *msgclient.MsgRequest) {
+		msg.EventKind = msgbus.Add
+		msg.ResourceType = msgbus.Tenant
+		msg.ResourceID = ins.tenant.ID
+		msg.Detail = i18n.Sprintf(context.TODO(), "created tenant %s", ins.tenant.TenantName)
+		msg.ToUsers.Append(ins.h.GetDataBase().SystemAdmins()...)
+		for _, user := range ins.users {
+			msg.ToUsers.Append(user.ID)
+			msg.AffectedUsers.Append(user.ID)
+		}
+	})
+}
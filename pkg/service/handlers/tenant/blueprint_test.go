@@ -0,0 +1,126 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenanthandler
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+func TestValidateTenantBlueprint(t *testing.T) {
+	quota := v1.ResourceList{v1.ResourceCPU: resource.MustParse("10")}
+	env := func(name, namespace, cluster string) models.BlueprintEnvironment {
+		return models.BlueprintEnvironment{Name: name, Namespace: namespace, Cluster: cluster, MetaType: "dev"}
+	}
+	tests := []struct {
+		name    string
+		spec    models.TenantBlueprintSpec
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			spec: models.TenantBlueprintSpec{
+				Clusters: []models.BlueprintCluster{{Cluster: "c1", ResourceQuota: quota}},
+				Projects: []models.BlueprintProject{
+					{
+						Name:           "web",
+						ResourceQuotas: map[string]v1.ResourceList{"c1": quota},
+						Environments: []models.BlueprintEnvironment{
+							env("{tenant}-{project}-dev", "", "c1"),
+							env("{tenant}-{project}-test", "", "c1"),
+						},
+					},
+					{Name: "api", Environments: []models.BlueprintEnvironment{env("{tenant}-{project}-dev", "", "c1")}},
+				},
+			},
+		},
+		{
+			name: "environment on unknown cluster",
+			spec: models.TenantBlueprintSpec{
+				Clusters: []models.BlueprintCluster{{Cluster: "c1", ResourceQuota: quota}},
+				Projects: []models.BlueprintProject{{Name: "web", Environments: []models.BlueprintEnvironment{env("dev", "", "c2")}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated environment across projects",
+			spec: models.TenantBlueprintSpec{
+				Clusters: []models.BlueprintCluster{{Cluster: "c1", ResourceQuota: quota}},
+				Projects: []models.BlueprintProject{
+					{Name: "web", Environments: []models.BlueprintEnvironment{env("{tenant}-dev", "", "c1")}},
+					{Name: "api", Environments: []models.BlueprintEnvironment{env("{tenant}-dev", "", "c1")}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated namespace",
+			spec: models.TenantBlueprintSpec{
+				Clusters: []models.BlueprintCluster{{Cluster: "c1", ResourceQuota: quota}},
+				Projects: []models.BlueprintProject{{Name: "web", Environments: []models.BlueprintEnvironment{
+					env("{project}-dev", "{tenant}", "c1"),
+					env("{project}-test", "{tenant}", "c1"),
+				}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing cluster quota",
+			spec: models.TenantBlueprintSpec{
+				Clusters: []models.BlueprintCluster{{Cluster: "c1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid sleep schedule",
+			spec: models.TenantBlueprintSpec{
+				Clusters: []models.BlueprintCluster{{Cluster: "c1", ResourceQuota: quota}},
+				Projects: []models.BlueprintProject{{Name: "web", Environments: []models.BlueprintEnvironment{
+					{Name: "dev", Cluster: "c1", SleepSchedule: &v1beta1.SleepSchedule{Sleep: "every night"}},
+				}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTenantBlueprint(context.Background(), &tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTenantBlueprint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBlueprintEnvironmentName(t *testing.T) {
+	tests := []struct {
+		env           models.BlueprintEnvironment
+		wantName      string
+		wantNamespace string
+	}{
+		{env: models.BlueprintEnvironment{Name: "{tenant}-{project}-dev"}, wantName: "team-web-dev", wantNamespace: "team-web-dev"},
+		{env: models.BlueprintEnvironment{Name: "{project}-dev", Namespace: "{tenant}-{environment}"}, wantName: "web-dev", wantNamespace: "team-web-dev"},
+	}
+	for _, tt := range tests {
+		name, namespace := blueprintEnvironmentName("team", "web", tt.env)
+		if name != tt.wantName || namespace != tt.wantNamespace {
+			t.Errorf("blueprintEnvironmentName() = %s, %s, want %s, %s", name, namespace, tt.wantName, tt.wantNamespace)
+		}
+	}
+}
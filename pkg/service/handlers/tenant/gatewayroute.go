@@ -0,0 +1,172 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenanthandler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	gemlabels "kubegems.io/kubegems/pkg/apis/gems"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/apis/networking"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var routeListGVKs = []schema.GroupVersionKind{networking.GVKHTTPRouteList, networking.GVKGRPCRouteList}
+
+// GatewayRoute 挂载到网关的 HTTPRoute/GRPCRoute
+type GatewayRoute struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Hostnames []string
+	Addresses []string
+}
+
+// ListTenantGatewayRoutes 挂载到租户网关的路由
+//
+//	@Tags			Tenant
+//	@Summary		挂载到租户网关的 Gateway API 路由及访问地址
+//	@Description	挂载到租户网关的 Gateway API 路由及访问地址
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		uint											true	"tenant_id"
+//	@Param			cluster_id	path		uint											true	"cluster_id"
+//	@Param			name		path		string											true	"name"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]GatewayRoute}	"routes"
+//	@Router			/v1/tenant/{tenant_id}/cluster/{cluster_id}/tenantgateways/{name}/routes [get]
+//	@Security		JWT
+func (h *TenantHandler) ListTenantGatewayRoutes(c *gin.Context) {
+	tenant, cluster, err := h.getTenantCluster(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	tg, err := h.getGateway(ctx, cluster.ClusterName, c.Param("name"))
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	routes := []unstructured.Unstructured{}
+	nslist := &v1.NamespaceList{}
+	if err := h.Execute(ctx, cluster.ClusterName, func(ctx context.Context, cli agents.Client) error {
+		if err := cli.List(ctx, nslist, client.MatchingLabels{gemlabels.LabelTenant: tenant.TenantName}); err != nil {
+			return err
+		}
+		for _, gvk := range routeListGVKs {
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(gvk)
+			// 集群未安装对应的 CRD 时忽略
+			if err := cli.List(ctx, list); err != nil {
+				if kerrors.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+			routes = append(routes, list.Items...)
+		}
+		return nil
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	// 只返回本租户的路由
+	tenantNamespaces := map[string]bool{}
+	for _, ns := range nslist.Items {
+		tenantNamespaces[ns.Name] = true
+	}
+	ret := []GatewayRoute{}
+	for i := range routes {
+		route := &routes[i]
+		if !tenantNamespaces[route.GetNamespace()] {
+			continue
+		}
+		if r, ok := gatewayRouteOf(tg, route); ok {
+			ret = append(ret, r)
+		}
+	}
+	handlers.OK(c, ret)
+}
+
+// gatewayRouteOf 路由挂载到网关时, 由路由的域名和挂载的监听生成访问地址
+func gatewayRouteOf(tg *v1beta1.TenantGateway, route *unstructured.Unstructured) (GatewayRoute, bool) {
+	ret := GatewayRoute{
+		Kind:      route.GetKind(),
+		Namespace: route.GetNamespace(),
+		Name:      route.GetName(),
+		Hostnames: networking.RouteHostnames(route),
+		Addresses: []string{},
+	}
+	attached := false
+	for _, ref := range networking.RouteParentRefs(route) {
+		if !ref.IsGateway(gemlabels.NamespaceGateway, tg.Name) {
+			continue
+		}
+		attached = true
+		if tg.Spec.GatewayAPI == nil {
+			continue
+		}
+		for _, l := range tg.Spec.GatewayAPI.Listeners {
+			if ref.SectionName != "" && ref.SectionName != l.Name {
+				continue
+			}
+			hostnames := ret.Hostnames
+			if len(hostnames) == 0 && l.Hostname != "" {
+				hostnames = []string{l.Hostname}
+			}
+			for _, hostname := range hostnames {
+				ret.Addresses = append(ret.Addresses, listenerURL(l, hostname))
+			}
+		}
+	}
+	return ret, attached
+}
+
+// gatewayAPIAddrs Gateway API 网关各监听的访问地址
+func gatewayAPIAddrs(tg *v1beta1.TenantGateway) []GatewayAddr {
+	ret := []GatewayAddr{}
+	if tg.Spec.GatewayAPI == nil {
+		return ret
+	}
+	for _, addr := range tg.Status.GatewayAddresses {
+		for _, l := range tg.Spec.GatewayAPI.Listeners {
+			ret = append(ret, GatewayAddr{
+				Addr:   listenerURL(l, addr),
+				Ready:  true,
+				Status: "ready",
+			})
+		}
+	}
+	return ret
+}
+
+func listenerURL(l v1beta1.GatewayListener, host string) string {
+	scheme := strings.ToLower(l.Protocol)
+	if (scheme == "http" && l.Port == 80) || (scheme == "https" && l.Port == 443) {
+		return fmt.Sprintf("%s://%s", scheme, host)
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, host, l.Port)
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenanthandler
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/i18n"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ListTenantNetworkPolicyRules 租户在集群下的网络策略规则
+//
+//	@Tags			NetworkIsolated
+//	@Summary		租户在集群下的网络策略规则
+//	@Description	租户在集群下的网络策略规则
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		uint														true	"tenant_id"
+//	@Param			cluster_id	path		uint														true	"cluster_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]v1beta1.NetworkPolicyRule}	"rules"
+//	@Router			/v1/tenant/{tenant_id}/cluster/{cluster_id}/networkpolicyrules [get]
+//	@Security		JWT
+func (h *TenantHandler) ListTenantNetworkPolicyRules(c *gin.Context) {
+	tenant, cluster, err := h.getTenantCluster(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	tnetpol := &v1beta1.TenantNetworkPolicy{}
+	if err := h.Execute(ctx, cluster.ClusterName, func(ctx context.Context, cli agents.Client) error {
+		return cli.Get(ctx, client.ObjectKey{Name: tenant.TenantName}, tnetpol)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	rules := tnetpol.Spec.Rules
+	if rules == nil {
+		rules = []v1beta1.NetworkPolicyRule{}
+	}
+	handlers.OK(c, rules)
+}
+
+// PutTenantNetworkPolicyRules 设置租户在集群下的网络策略规则
+//
+//	@Tags			NetworkIsolated
+//	@Summary		设置租户在集群下的网络策略规则
+//	@Description	设置租户在集群下的网络策略规则, 使用提交的规则替换所有的规则
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		uint														true	"tenant_id"
+//	@Param			cluster_id	path		uint														true	"cluster_id"
+//	@Param			param		body		[]v1beta1.NetworkPolicyRule									true	"rules"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]v1beta1.NetworkPolicyRule}	"rules"
+//	@Router			/v1/tenant/{tenant_id}/cluster/{cluster_id}/networkpolicyrules [put]
+//	@Security		JWT
+func (h *TenantHandler) PutTenantNetworkPolicyRules(c *gin.Context) {
+	rules := []v1beta1.NetworkPolicyRule{}
+	if err := c.BindJSON(&rules); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	tenant, cluster, err := h.getTenantCluster(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	action := i18n.Sprintf(context.TODO(), "update")
+	module := i18n.Sprintf(context.TODO(), "tenant network policy rules")
+	h.SetAuditData(c, action, module, tenant.TenantName+"/"+cluster.ClusterName)
+	h.SetExtraAuditData(c, models.ResTenant, tenant.ID)
+
+	ctx := c.Request.Context()
+	tnetpol := &v1beta1.TenantNetworkPolicy{}
+	if err := h.Execute(ctx, cluster.ClusterName, func(ctx context.Context, cli agents.Client) error {
+		if err := cli.Get(ctx, client.ObjectKey{Name: tenant.TenantName}, tnetpol); err != nil {
+			return err
+		}
+		tnetpol.Spec.Rules = rules
+		return cli.Update(ctx, tnetpol)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, rules)
+}
+
+func (h *TenantHandler) getTenantCluster(c *gin.Context) (*models.Tenant, *models.Cluster, error) {
+	tenant, cluster := &models.Tenant{}, &models.Cluster{}
+	ctx := c.Request.Context()
+	if err := h.GetDB().WithContext(ctx).First(tenant, "id = ?", c.Param(PrimaryKeyName)).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := h.GetDB().WithContext(ctx).Select("id, cluster_name").First(cluster, "id = ?", c.Param("cluster_id")).Error; err != nil {
+		return nil, nil, err
+	}
+	return tenant, cluster, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenanthandler
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/utils/agents"
+)
+
+// PolicyViolation 租户下资源的违规记录
+type PolicyViolation struct {
+	Policy string
+	v1beta1.AdmissionPolicyViolation
+}
+
+// ListTenantPolicyViolations 租户在集群中的准入策略违规记录
+//
+//	@Tags			Tenant
+//	@Summary		租户在集群中的准入策略违规记录
+//	@Description	租户在集群中的准入策略违规记录, 最新的在前
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		uint											true	"tenant_id"
+//	@Param			cluster_id	path		uint											true	"cluster_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]PolicyViolation}	"violations"
+//	@Router			/v1/tenant/{tenant_id}/cluster/{cluster_id}/policyviolations [get]
+//	@Security		JWT
+func (h *TenantHandler) ListTenantPolicyViolations(c *gin.Context) {
+	tenant, cluster, err := h.getTenantCluster(c)
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	list := &v1beta1.AdmissionPolicyList{}
+	if err := h.Execute(c.Request.Context(), cluster.ClusterName, func(ctx context.Context, cli agents.Client) error {
+		return cli.List(ctx, list)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, tenantPolicyViolations(list.Items, tenant.TenantName))
+}
+
+func tenantPolicyViolations(policies []v1beta1.AdmissionPolicy, tenant string) []PolicyViolation {
+	ret := []PolicyViolation{}
+	for _, ap := range policies {
+		for _, v := range ap.Status.RecentViolations {
+			if v.Tenant == tenant {
+				ret = append(ret, PolicyViolation{Policy: ap.Name, AdmissionPolicyViolation: v})
+			}
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[j].Time.Before(&ret[i].Time)
+	})
+	return ret
+}
@@ -0,0 +1,366 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenanthandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/i18n"
+	msgclient "kubegems.io/kubegems/pkg/msgbus/client"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/handlers/base"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/msgbus"
+	"kubegems.io/kubegems/pkg/utils/resourcequota"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ListProjectResourceQuota 项目在各个集群的资源限制
+//
+//	@Tags			Project
+//	@Summary		项目在各个集群的资源限制
+//	@Description	项目在各个集群的资源限制, 没有设置的集群只受租户的资源限制
+//	@Accept			json
+//	@Produce		json
+//	@Param			project_id	path		uint													true	"project_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=[]models.ProjectResourceQuota}	"models.ProjectResourceQuota"
+//	@Router			/v1/project/{project_id}/projectresourcequota [get]
+//	@Security		JWT
+func (h *TenantHandler) ListProjectResourceQuota(c *gin.Context) {
+	list := []models.ProjectResourceQuota{}
+	if err := h.GetDB().WithContext(c.Request.Context()).Preload(
+		"Cluster",
+		func(tx *gorm.DB) *gorm.DB { return tx.Select("id, cluster_name") },
+	).Preload("TenantResourceQuotaApply").Find(&list, "project_id = ?", c.Param("project_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, list)
+}
+
+// PutProjectResourceQuota 租户管理员设置项目在集群的资源限制
+//
+//	@Tags			Tenant
+//	@Summary		设置项目在集群的资源限制
+//	@Description	设置项目在集群的资源限制, 项目资源限制的总和不能超过租户在该集群的资源限制
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		uint														true	"tenant_id"
+//	@Param			project_id	path		uint														true	"project_id"
+//	@Param			cluster_id	path		uint														true	"cluster_id"
+//	@Param			param		body		models.ProjectResourceQuota									true	"表单"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.ProjectResourceQuota}	"models.ProjectResourceQuota"
+//	@Router			/v1/tenant/{tenant_id}/project/{project_id}/projectresourcequota/{cluster_id} [put]
+//	@Security		JWT
+func (h *TenantHandler) PutProjectResourceQuota(c *gin.Context) {
+	req := models.ProjectResourceQuota{}
+	if err := c.ShouldBind(&req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	prq, err := h.getProjectResourceQuota(ctx, c.Param(PrimaryKeyName), c.Param("project_id"), c.Param("cluster_id"))
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := ValidateProjectResourceQuota(ctx, h.GetDB().WithContext(ctx), prq, req.Content); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	prq.Content = req.Content
+
+	action := i18n.Sprintf(context.TODO(), "update")
+	module := i18n.Sprintf(context.TODO(), "project cluster resource quota")
+	h.SetAuditData(c, action, module, i18n.Sprintf(c, "project %s / cluster %s", prq.Project.ProjectName, prq.Cluster.ClusterName))
+	h.SetExtraAuditData(c, models.ResProject, prq.ProjectID)
+
+	if err := h.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Project", "Cluster", "TenantResourceQuotaApply").Save(prq).Error; err != nil {
+			return err
+		}
+		return AfterProjectResourceQuotaSave(ctx, h.BaseHandler, tx, prq)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, prq)
+}
+
+// DeleteProjectResourceQuota 删除项目在集群的资源限制
+//
+//	@Tags			Tenant
+//	@Summary		删除项目在集群的资源限制
+//	@Description	删除项目在集群的资源限制, 删除后项目只受租户的资源限制
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant_id	path		uint									true	"tenant_id"
+//	@Param			project_id	path		uint									true	"project_id"
+//	@Param			cluster_id	path		uint									true	"cluster_id"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=string}	"ok"
+//	@Router			/v1/tenant/{tenant_id}/project/{project_id}/projectresourcequota/{cluster_id} [delete]
+//	@Security		JWT
+func (h *TenantHandler) DeleteProjectResourceQuota(c *gin.Context) {
+	ctx := c.Request.Context()
+	prq, err := h.getProjectResourceQuota(ctx, c.Param(PrimaryKeyName), c.Param("project_id"), c.Param("cluster_id"))
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if prq.ID == 0 {
+		handlers.OK(c, "ok")
+		return
+	}
+
+	action := i18n.Sprintf(context.TODO(), "delete")
+	module := i18n.Sprintf(context.TODO(), "project cluster resource quota")
+	h.SetAuditData(c, action, module, i18n.Sprintf(c, "project %s / cluster %s", prq.Project.ProjectName, prq.Cluster.ClusterName))
+	h.SetExtraAuditData(c, models.ResProject, prq.ProjectID)
+
+	if err := h.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(prq).Error; err != nil {
+			return err
+		}
+		if prq.TenantResourceQuotaApplyID != nil {
+			if err := tx.Delete(&models.TenantResourceQuotaApply{}, *prq.TenantResourceQuotaApplyID).Error; err != nil {
+				return err
+			}
+		}
+		return RemoveProjectResourceQuota(ctx, h.BaseHandler, prq.Cluster.ClusterName, prq.Project.Tenant.TenantName, prq.Project.ProjectName)
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, "ok")
+}
+
+// CreateProjectResourceQuotaApply 创建or修改项目集群资源变更申请
+//
+//	@Tags			Project
+//	@Summary		创建or修改项目集群资源变更申请
+//	@Description	创建or修改项目集群资源变更申请, 审批通过后生效
+//	@Accept			json
+//	@Produce		json
+//	@Param			project_id	path		uint															true	"project_id"
+//	@Param			cluster_id	path		uint															true	"cluster_id"
+//	@Param			param		body		models.TenantResourceQuotaApply									true	"表单"
+//	@Success		200			{object}	handlers.ResponseStruct{Data=models.TenantResourceQuotaApply}	"models.TenantResourceQuotaApply"
+//	@Router			/v1/project/{project_id}/cluster/{cluster_id}/resourceApply [post]
+//	@Security		JWT
+func (h *TenantHandler) CreateProjectResourceQuotaApply(c *gin.Context) {
+	req := models.TenantResourceQuotaApply{}
+	if err := c.BindJSON(&req); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	project := models.Project{}
+	if err := h.GetDB().WithContext(ctx).First(&project, "id = ?", c.Param("project_id")).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	prq, err := h.getProjectResourceQuota(ctx, project.TenantID, project.ID, c.Param("cluster_id"))
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := ValidateProjectResourceQuota(ctx, h.GetDB().WithContext(ctx), prq, req.Content); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	u, _ := h.GetContextUser(c)
+	if u == nil {
+		u = &models.User{}
+	}
+	// 没有就新建，有就更新
+	if prq.TenantResourceQuotaApply == nil {
+		prq.TenantResourceQuotaApply = &models.TenantResourceQuotaApply{}
+	}
+	prq.TenantResourceQuotaApply.Status = models.QuotaStatusPending
+	prq.TenantResourceQuotaApply.Content = req.Content
+	prq.TenantResourceQuotaApply.Username = u.GetUsername()
+
+	if err := h.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(prq.TenantResourceQuotaApply).Error; err != nil {
+			return err
+		}
+		prq.TenantResourceQuotaApplyID = &prq.TenantResourceQuotaApply.ID
+		// 首次申请时项目还没有资源限制
+		if prq.Content == nil {
+			prq.Content = []byte("{}")
+		}
+		return tx.Omit("Project", "Cluster", "TenantResourceQuotaApply").Save(prq).Error
+	}); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+
+	action := i18n.Sprintf(context.TODO(), "create")
+	module := i18n.Sprintf(context.TODO(), "project cluster resource quota")
+	h.SetAuditData(c, action, module, i18n.Sprintf(c, "project %s / cluster %s", prq.Project.ProjectName, prq.Cluster.ClusterName))
+	h.SetExtraAuditData(c, models.ResProject, prq.ProjectID)
+
+	// 申请消息给系统管理员、租户管理员和当前用户
+	h.SendToMsgbus(c, func(msg *msgclient.MsgRequest) {
+		msg.MessageType = msgbus.Approve
+		msg.EventKind = msgbus.Update
+		msg.ResourceType = msgbus.ProjectResourceQuota
+		msg.ResourceID = prq.ID
+		msg.Detail = i18n.Sprintf(context.TODO(), "Apply to adjust the resources of project %s in cluster %s", prq.Project.ProjectName, prq.Cluster.ClusterName)
+		msg.ToUsers.Append(h.GetDataBase().SystemAdmins()...).Append(h.GetDataBase().TenantAdmins(project.TenantID)...).Append(u.GetID())
+	})
+	handlers.OK(c, prq.TenantResourceQuotaApply)
+}
+
+// getProjectResourceQuota 获取项目在集群的资源限制, 不存在时返回未保存的记录
+func (h *TenantHandler) getProjectResourceQuota(ctx context.Context, tenantID, projectID, clusterID interface{}) (*models.ProjectResourceQuota, error) {
+	project := &models.Project{}
+	if err := h.GetDB().WithContext(ctx).Preload("Tenant").First(project, "id = ? and tenant_id = ?", projectID, tenantID).Error; err != nil {
+		return nil, err
+	}
+	cluster := &models.Cluster{}
+	if err := h.GetDB().WithContext(ctx).Select("id, cluster_name").First(cluster, "id = ?", clusterID).Error; err != nil {
+		return nil, err
+	}
+	prq := &models.ProjectResourceQuota{}
+	if err := h.GetDB().WithContext(ctx).Preload("TenantResourceQuotaApply").
+		Where(models.ProjectResourceQuota{ProjectID: project.ID, ClusterID: cluster.ID}).
+		FirstOrInit(prq).Error; err != nil {
+		return nil, err
+	}
+	prq.Project, prq.Cluster = project, cluster
+	return prq, nil
+}
+
+// ValidateProjectResourceQuota 项目资源限制的总和不能超过租户在该集群的资源限制
+func ValidateProjectResourceQuota(ctx context.Context, tx *gorm.DB, prq *models.ProjectResourceQuota, need []byte) error {
+	needlist := v1.ResourceList{}
+	if err := json.Unmarshal(need, &needlist); err != nil {
+		return err
+	}
+	resourcequota.SetSameRequestWithLimit(needlist)
+
+	trq := models.TenantResourceQuota{}
+	if err := tx.First(&trq, "tenant_id = ? and cluster_id = ?", prq.Project.TenantID, prq.ClusterID).Error; err != nil {
+		return i18n.Errorf(ctx, "current tenant has no resource quota on the cluster")
+	}
+	tenantHard := v1.ResourceList{}
+	if err := json.Unmarshal(trq.Content, &tenantHard); err != nil {
+		return err
+	}
+	resourcequota.SetSameRequestWithLimit(tenantHard)
+
+	// 租户下其他项目的资源限制
+	others := []models.ProjectResourceQuota{}
+	projects := tx.Model(&models.Project{}).Select("id").Where("tenant_id = ? and id <> ?", prq.Project.TenantID, prq.ProjectID)
+	if err := tx.Where("project_id in (?) and cluster_id = ?", projects, prq.ClusterID).Find(&others).Error; err != nil {
+		return err
+	}
+	allocated := v1.ResourceList{}
+	for _, other := range others {
+		hard := v1.ResourceList{}
+		if err := json.Unmarshal(other.Content, &hard); err != nil {
+			continue
+		}
+		resourcequota.SetSameRequestWithLimit(hard)
+		for k, v := range hard {
+			if allocatedv, ok := allocated[k]; ok {
+				allocatedv.Add(v)
+				allocated[k] = allocatedv
+			} else {
+				allocated[k] = v.DeepCopy()
+			}
+		}
+	}
+
+	msgs := []string{}
+	for k, needv := range needlist {
+		hardv, ok := tenantHard[k]
+		if !ok {
+			continue
+		}
+		available := Sub(hardv, allocated[k])
+		if needv.Cmp(available) == 1 {
+			msgs = append(msgs, fmt.Sprintf("resource [%s] available %s ,but request %s", k, available.String(), needv.String()))
+		}
+	}
+	if len(msgs) > 0 {
+		return i18n.Errorf(ctx, "validate project resource quota failed: %v", msgs)
+	}
+	return nil
+}
+
+// AfterProjectResourceQuotaSave 更新集群中租户资源限制里的项目资源限制
+func AfterProjectResourceQuotaSave(ctx context.Context, h base.BaseHandler, tx *gorm.DB, prq *models.ProjectResourceQuota) error {
+	var (
+		project models.Project
+		cluster models.Cluster
+	)
+	if err := tx.Preload("Tenant").First(&project, "id = ?", prq.ProjectID).Error; err != nil {
+		return err
+	}
+	if err := tx.First(&cluster, "id = ?", prq.ClusterID).Error; err != nil {
+		return err
+	}
+	hard := v1.ResourceList{}
+	if err := json.Unmarshal(prq.Content, &hard); err != nil {
+		return err
+	}
+	resourcequota.SetSameRequestWithLimit(hard)
+
+	return h.Execute(ctx, cluster.ClusterName, func(ctx context.Context, cli agents.Client) error {
+		tquota := &v1beta1.TenantResourceQuota{}
+		if err := cli.Get(ctx, client.ObjectKey{Name: project.Tenant.TenantName}, tquota); err != nil {
+			return err
+		}
+		for i, p := range tquota.Spec.Projects {
+			if p.Name == project.ProjectName {
+				tquota.Spec.Projects[i].Hard = hard
+				return cli.Update(ctx, tquota)
+			}
+		}
+		tquota.Spec.Projects = append(tquota.Spec.Projects, v1beta1.ProjectResourceQuota{Name: project.ProjectName, Hard: hard})
+		return cli.Update(ctx, tquota)
+	})
+}
+
+// RemoveProjectResourceQuota 删除集群中租户资源限制里的项目资源限制
+func RemoveProjectResourceQuota(ctx context.Context, h base.BaseHandler, clustername, tenantname, projectname string) error {
+	return h.Execute(ctx, clustername, func(ctx context.Context, cli agents.Client) error {
+		tquota := &v1beta1.TenantResourceQuota{}
+		if err := cli.Get(ctx, client.ObjectKey{Name: tenantname}, tquota); err != nil {
+			if kerrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		for i, p := range tquota.Spec.Projects {
+			if p.Name == projectname {
+				tquota.Spec.Projects = append(tquota.Spec.Projects[:i], tquota.Spec.Projects[i+1:]...)
+				return cli.Update(ctx, tquota)
+			}
+		}
+		return nil
+	})
+}
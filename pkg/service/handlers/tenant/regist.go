@@ -48,6 +48,8 @@ func (h *TenantHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.PUT("/tenant/:tenant_id/action/enable", h.CheckByTenantID, h.EnableTenant)
 	rg.PUT("/tenant/:tenant_id/action/disable", h.CheckByTenantID, h.DisableTenant)
 	rg.POST("/tenant/:tenant_id/action/networkisolate", h.CheckByTenantID, h.TenantSwitch)
+	rg.GET("/tenant/:tenant_id/cluster/:cluster_id/networkpolicyrules", h.CheckByTenantID, h.ListTenantNetworkPolicyRules)
+	rg.PUT("/tenant/:tenant_id/cluster/:cluster_id/networkpolicyrules", h.CheckByTenantID, h.PutTenantNetworkPolicyRules)
 
 	rg.GET("/tenant/:tenant_id/environment_with_quotas", h.CheckByTenantID, h.TenantEnvironments)
 	rg.GET("/tenant/:tenant_id/environment", h.CheckByTenantID, h.ListEnvironment)
@@ -59,10 +61,25 @@ func (h *TenantHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.POST("/tenant/:tenant_id/cluster/:cluster_id/resourceApply", h.CheckByTenantID, h.CreateTenantResourceQuotaApply)
 	rg.GET("/tenant/:tenant_id/tenantresourcequotaapply/:tenantresourcequotaapply_id", h.CheckByTenantID, h.GetTenantTenantResourceQuotaApply)
 
+	rg.GET("/project/:project_id/projectresourcequota", h.CheckByProjectID, h.ListProjectResourceQuota)
+	rg.POST("/project/:project_id/cluster/:cluster_id/resourceApply", h.CheckByProjectID, h.CreateProjectResourceQuotaApply)
+	rg.PUT("/tenant/:tenant_id/project/:project_id/projectresourcequota/:cluster_id", h.CheckByTenantID, h.PutProjectResourceQuota)
+	rg.DELETE("/tenant/:tenant_id/project/:project_id/projectresourcequota/:cluster_id", h.CheckByTenantID, h.DeleteProjectResourceQuota)
+
 	rg.GET("/tenant/:tenant_id/cluster/:cluster_id/tenantgateways", h.CheckByTenantID, h.ListTenantGateway)
 	rg.GET("/tenant/:tenant_id/cluster/:cluster_id/tenantgateways/:name", h.CheckByTenantID, h.GetTenantGateway)
 	rg.POST("/tenant/:tenant_id/cluster/:cluster_id/tenantgateways", h.CheckByTenantID, h.CreateTenantGateway)
 	rg.PUT("/tenant/:tenant_id/cluster/:cluster_id/tenantgateways/:name", h.CheckByTenantID, h.UpdateTenantGateway)
 	rg.DELETE("/tenant/:tenant_id/cluster/:cluster_id/tenantgateways/:name", h.CheckByTenantID, h.DeleteTenantGateway)
 	rg.GET("/tenant/:tenant_id/cluster/:cluster_id/tenantgateways/:name/addresses", h.CheckByTenantID, h.GetObjectTenantGatewayAddr)
+	rg.GET("/tenant/:tenant_id/cluster/:cluster_id/tenantgateways/:name/routes", h.CheckByTenantID, h.ListTenantGatewayRoutes)
+
+	rg.GET("/tenant/:tenant_id/cluster/:cluster_id/policyviolations", h.CheckByTenantID, h.ListTenantPolicyViolations)
+
+	rg.GET("/tenantblueprint", h.CheckIsSysADMIN, h.ListTenantBlueprint)
+	rg.GET("/tenantblueprint/:blueprint_id", h.CheckIsSysADMIN, h.GetTenantBlueprint)
+	rg.POST("/tenantblueprint", h.CheckIsSysADMIN, h.CreateTenantBlueprint)
+	rg.PUT("/tenantblueprint/:blueprint_id", h.CheckIsSysADMIN, h.UpdateTenantBlueprint)
+	rg.DELETE("/tenantblueprint/:blueprint_id", h.CheckIsSysADMIN, h.DeleteTenantBlueprint)
+	rg.POST("/tenantblueprint/:blueprint_id/instantiate", h.CheckIsSysADMIN, h.InstantiateTenantBlueprint)
 }
@@ -923,7 +923,8 @@ func CreateOrUpdateTenantResourceQuota(ctx context.Context, h base.BaseHandler,
 			ObjectMeta: metav1.ObjectMeta{Name: tenantname},
 		}
 		_, err := controllerutil.CreateOrUpdate(ctx, cli, tquota, func() error {
-			tquota.Spec = v1beta1.TenantResourceQuotaSpec{Hard: hard}
+			// 保留项目的资源限制
+			tquota.Spec.Hard = hard
 			return nil
 		})
 		return err
@@ -1228,6 +1229,9 @@ type tenantStatisticsData struct {
 	DaemonSets   int64 `json:"count.daemonsets"`
 	Pods         int64 `json:"count.pods"`
 	Workloads    int64 `json:"count.workloads"`
+	// 休眠中的环境数及休眠释放的资源
+	SleepingEnvironments int64           `json:"count.sleepingEnvironments"`
+	SleepFreed           v1.ResourceList `json:"sleep.freed"`
 }
 
 // TenantStatistics 租户非资源类型统计
@@ -1251,6 +1255,8 @@ func (h *TenantHandler) TenantStatistics(c *gin.Context) {
 		daemonsetsCount   int64
 		statefulsetsCount int64
 		podCount          int64
+		sleepingCount     int64
+		sleepFreed        = v1.ResourceList{}
 	)
 	ctx := c.Request.Context()
 	if err := h.GetDB().WithContext(ctx).Preload("ResourceQuotas.Cluster").Preload("Projects").First(&tenant, "id = ?", c.Param("tenant_id")).Error; err != nil {
@@ -1269,11 +1275,16 @@ func (h *TenantHandler) TenantStatistics(c *gin.Context) {
 	if len(tenant.ResourceQuotas) != 0 {
 		wg := sync.WaitGroup{}
 		allQuotas := make(chan *[]v1.ResourceQuota, len(tenant.ResourceQuotas))
+		allEnvs := make(chan *[]v1beta1.Environment, len(tenant.ResourceQuotas))
 		for _, trq := range tenant.ResourceQuotas {
 			wg.Add(1)
 			go func(clustername string) {
 				quotas := &v1.ResourceQuotaList{}
+				envs := &v1beta1.EnvironmentList{}
 				err := h.Execute(ctx, clustername, func(ctx context.Context, cli agents.Client) error {
+					if err := cli.List(ctx, envs); err != nil {
+						log.Error(err, "get environments failed")
+					}
 					return cli.List(ctx, quotas, client.MatchingLabels{
 						gemlabels.LabelTenant: tenant.TenantName,
 					})
@@ -1282,6 +1293,7 @@ func (h *TenantHandler) TenantStatistics(c *gin.Context) {
 					log.Error(err, "get resource quotas failed")
 				}
 				allQuotas <- &quotas.Items
+				allEnvs <- &envs.Items
 				wg.Done()
 			}(trq.Cluster.ClusterName)
 		}
@@ -1289,6 +1301,23 @@ func (h *TenantHandler) TenantStatistics(c *gin.Context) {
 		// 1.在遍历时，如果 channel 没有关闭，则回出现 deadlock 的错误。
 		// 2.在遍历时，如果 channel 已经关闭，则会正常遍历数据，遍历完后，就会退出遍历。
 		close(allQuotas)
+		close(allEnvs)
+		for envs := range allEnvs {
+			for _, env := range *envs {
+				if env.Spec.Tenant != tenant.TenantName || env.Status.Sleep == nil || !env.Status.Sleep.Sleeping {
+					continue
+				}
+				sleepingCount++
+				for k, v := range env.Status.Sleep.Freed {
+					if total, ok := sleepFreed[k]; ok {
+						total.Add(v)
+						sleepFreed[k] = total
+					} else {
+						sleepFreed[k] = v.DeepCopy()
+					}
+				}
+			}
+		}
 		for quotas := range allQuotas {
 			for _, quota := range *quotas {
 				for k, v := range quota.Status.Used {
@@ -1330,6 +1359,9 @@ func (h *TenantHandler) TenantStatistics(c *gin.Context) {
 		DaemonSets:   daemonsetsCount,
 		Pods:         podCount,
 		Workloads:    deploymentsCount + statefulsetsCount + daemonsetsCount,
+
+		SleepingEnvironments: sleepingCount,
+		SleepFreed:           sleepFreed,
 	}
 	handlers.OK(c, ret)
 }
@@ -1899,6 +1931,7 @@ func (h *TenantHandler) GetObjectTenantGatewayAddr(c *gin.Context) {
 			}
 		}
 	}
+	ret = append(ret, gatewayAPIAddrs(tg)...)
 	handlers.OK(c, ret)
 }
 
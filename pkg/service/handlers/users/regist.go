@@ -32,5 +32,8 @@ func (h *UserHandler) RegistRouter(rg *gin.RouterGroup) {
 	rg.DELETE("/user/:user_id", h.CheckIsSysADMIN, h.DeleteUser)
 	rg.GET("/user/:user_id/tenant", h.ListUserTenant)
 	rg.POST("/user/:user_id/reset_password", h.CheckIsSysADMIN, h.ResetUserPassword)
+	rg.DELETE("/user/:user_id/2fa", h.CheckIsSysADMIN, h.ResetUserTwoFactor)
+	rg.GET("/system/securitypolicy", h.CheckIsSysADMIN, h.GetSecurityPolicy)
+	rg.PUT("/system/securitypolicy", h.CheckIsSysADMIN, h.PutSecurityPolicy)
 	rg.GET("/user/_/environment/:environment_id", h.ListEnvironmentUser) // TODO: 严格来说，应该校验这些环境是否在用户当前的虚拟空间中
 }
@@ -0,0 +1,97 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package userhandler
+
+import (
+	"github.com/gin-gonic/gin"
+	"kubegems.io/kubegems/pkg/service/aaa/auth"
+	"kubegems.io/kubegems/pkg/service/handlers"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+// ResetUserTwoFactor 重置用户两步验证
+//
+//	@Tags			User
+//	@Summary		重置用户两步验证
+//	@Description	用户丢失验证器和恢复码时由管理员重置, 重置后用户需要重新绑定
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id	path		uint						true	"user_id"
+//	@Success		200		{object}	handlers.ResponseStruct{}	""
+//	@Router			/v1/user/{user_id}/2fa [delete]
+//	@Security		JWT
+func (h *UserHandler) ResetUserTwoFactor(c *gin.Context) {
+	var user models.User
+	ctx := c.Request.Context()
+	if err := h.GetDB().WithContext(ctx).First(&user, c.Param(PrimaryKeyName)).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := auth.DisableTOTP(ctx, h.GetDB(), &user, "", false); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "重置", "两步验证", user.Username)
+	handlers.OK(c, nil)
+}
+
+// GetSecurityPolicy 获取系统安全策略
+//
+//	@Tags			User
+//	@Summary		获取系统安全策略
+//	@Description	获取系统安全策略
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	handlers.ResponseStruct{Data=models.SecurityPolicy}	"安全策略"
+//	@Router			/v1/system/securitypolicy [get]
+//	@Security		JWT
+func (h *UserHandler) GetSecurityPolicy(c *gin.Context) {
+	policy, err := auth.GetSecurityPolicy(c.Request.Context(), h.GetDB())
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	handlers.OK(c, policy)
+}
+
+// PutSecurityPolicy 修改系统安全策略
+//
+//	@Tags			User
+//	@Summary		修改系统安全策略
+//	@Description	修改系统安全策略, 开启后对应的管理员下次使用账号登录时必须完成两步验证
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		models.SecurityPolicy								true	"安全策略"
+//	@Success		200		{object}	handlers.ResponseStruct{Data=models.SecurityPolicy}	"安全策略"
+//	@Router			/v1/system/securitypolicy [put]
+//	@Security		JWT
+func (h *UserHandler) PutSecurityPolicy(c *gin.Context) {
+	ctx := c.Request.Context()
+	policy, err := auth.GetSecurityPolicy(ctx, h.GetDB())
+	if err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := c.BindJSON(policy); err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	if err := h.GetDB().WithContext(ctx).Save(policy).Error; err != nil {
+		handlers.NotOK(c, err)
+		return
+	}
+	h.SetAuditData(c, "修改", "安全策略", "")
+	handlers.OK(c, policy)
+}
@@ -190,6 +190,8 @@ func MigrateModels(db *gorm.DB) error {
 		&AuditLog{},
 		// 用户表
 		&User{}, &UserToken{},
+		// 两步验证和安全策略
+		&UserTOTP{}, &SecurityPolicy{},
 		// 系统角色表
 		&SystemRole{},
 		// 租户表
@@ -198,10 +200,14 @@ func MigrateModels(db *gorm.DB) error {
 		&TenantUserRels{},
 		// 租户集群资源表
 		&TenantResourceQuota{},
+		// 租户模板表
+		&TenantBlueprint{},
 		// 项目表
 		&Project{},
 		// 项目成员关系表
 		&ProjectUserRels{},
+		// 项目集群资源表
+		&ProjectResourceQuota{},
 		// 环境表
 		&Environment{},
 		// 环境成员关系表
@@ -238,18 +244,43 @@ func MigrateModels(db *gorm.DB) error {
 		&VirtualDomain{},
 		// 告警规则
 		&AlertRule{}, &AlertReceiver{},
+		// SLO
+		&SLO{},
+		// 指标异常检测
+		&AnomalyDetector{},
+		&AnomalyBaseline{},
+		// 维护窗口
+		&MaintenanceWindow{},
 		// 告警信息表
 		&AlertInfo{}, &AlertMessage{},
+		// 告警事件
+		&Incident{}, &IncidentEvent{},
 		// alert channels
 		&AlertChannel{},
 		// 监控面板表
 		&MonitorDashboard{}, &MonitorDashboardTpl{},
 		// 登陆源
 		&AuthSource{},
+		// 登陆源组到角色的映射及同步状态
+		&AuthSourceGroupMapping{}, &UserSourceGroups{}, &GroupSyncedRole{},
+		// SAML 等登录流程的一次性标识
+		&AuthNonce{},
 		// promql templates
 		&PromqlTplScope{}, &PromqlTplResource{}, &PromqlTplRule{},
+		// 日志指标
+		&LogMetric{},
+		// 定时日志导出
+		&LogExport{},
+		// 定时报告
+		&Report{},
+		// 费用分摊
+		&CostPrice{}, &CostDaily{},
+		// 审批
+		&ApprovalRule{}, &Approval{}, &ApprovalComment{},
 		// 公告
 		&Announcement{},
+		// 内置 OIDC Provider
+		&OIDCClient{}, &OIDCAuthRequest{}, &OIDCToken{}, &OIDCRefreshToken{},
 	)
 }
 
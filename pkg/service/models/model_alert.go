@@ -54,6 +54,9 @@ type AlertMessage struct {
 	EndsAt    *time.Time // 告警结束时间
 	CreatedAt *time.Time `gorm:"index"` // 本次告警产生时间
 	Status    string     // firing or resolved
+
+	MaintenanceWindow string `gorm:"type:varchar(50)"` // 被维护窗口静默时记录维护窗口名称
+	IncidentID        *uint  `gorm:"index"`            // 关联的事件
 }
 
 func (a *AlertMessage) ToNormalMessage() Message {
@@ -43,6 +43,7 @@ type AlertRule struct {
 
 	PromqlGenerator *PromqlGenerator `json:"promqlGenerator"`
 	LogqlGenerator  *LogqlGenerator  `json:"logqlGenerator"`
+	SLOGenerator    *SLOGenerator    `json:"sloGenerator"` // 由 SLO 生成的告警规则
 
 	IsOpen         bool            `gorm:"default:true" json:"isOpen"` // 是否启用
 	State          string          `json:"state"`                      // 状态
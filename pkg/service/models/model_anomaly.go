@@ -0,0 +1,181 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+)
+
+const (
+	AnomalyDirectionUp   = "up"
+	AnomalyDirectionDown = "down"
+	AnomalyDirectionBoth = "both"
+
+	// 样本不足两周时只学习不检测
+	AnomalyStateLearning = "learning"
+	AnomalyStateReady    = "ready"
+
+	// AnomalyBaselineStep 学习基线时的采样间隔
+	AnomalyBaselineStep = 5 * time.Minute
+	// AnomalyMinSamples 基线的最少样本数, 即至少两周的数据, 样本不足时不检测
+	AnomalyMinSamples = 24
+	// AnomalyRetrainInterval 基线重新学习的间隔
+	AnomalyRetrainInterval = 24 * time.Hour
+
+	// 正态分布下 MAD 与标准差的换算系数
+	anomalyMADScale = 1.4826
+	// MAD 的下限为中位数的 1%, 避免平稳的序列出现微小变化就告警
+	anomalyMinMADRatio = 0.01
+	anomalyMinMAD      = 1e-6
+)
+
+// AnomalyDetector 指标异常检测, worker 按一周中的小时学习序列的季节性基线(中位数和 MAD),
+// 当前值偏离基线时产生 anomaly 类型的告警, 通过生成的告警规则的接收器发送
+type AnomalyDetector struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Cluster     string `gorm:"type:varchar(50);uniqueIndex:uniq_anomaly_detector" json:"cluster"`
+	Namespace   string `gorm:"type:varchar(50);uniqueIndex:uniq_anomaly_detector" json:"namespace"`
+	Name        string `gorm:"type:varchar(50);uniqueIndex:uniq_anomaly_detector" binding:"min=1,max=50" json:"name"`
+	Description string `json:"description"`
+
+	// promql, 每条序列单独学习基线, eg. sum(rate(http_requests_total[5m])) by (deployment), 自动限定在当前命名空间
+	Expr        string     `binding:"required" json:"expr"`
+	Sensitivity float64    `gorm:"default:3" json:"sensitivity"`                   // 稳健 z-score 超过该值时认为异常
+	Direction   string     `gorm:"type:varchar(10);default:both" json:"direction"` // 检测的方向, up/down/both
+	For         string     `gorm:"type:varchar(10);default:5m" json:"for"`         // 持续异常的时间
+	Weeks       int        `gorm:"default:4" json:"weeks"`                         // 学习最近几周的数据, 1-8
+	Severity    string     `gorm:"type:varchar(20);default:error" json:"severity"` // 告警级别
+	Enabled     bool       `gorm:"default:true" json:"enabled"`                    // 是否检测, 不影响告警规则的启用状态
+	Unit        string     `gorm:"type:varchar(50)" json:"unit"`                   // 告警消息中值的单位
+	State       string     `gorm:"type:varchar(20)" json:"state"`                  // 检测状态, learning/ready
+	LastError   string     `json:"lastError"`                                      // 最后一次学习或检测的错误
+	TrainedAt   *time.Time `json:"trainedAt"`                                      // 最后一次学习基线的时间
+
+	AlertRuleID *uint      `json:"alertRuleID"`
+	AlertRule   *AlertRule `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"alertRule,omitempty"`
+	// 告警接收器, 保存在生成的告警规则上
+	Receivers []*AlertReceiver `gorm:"-" json:"receivers,omitempty"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// AnomalyBaseline 某条序列在一周中某个小时的基线
+type AnomalyBaseline struct {
+	ID         uint             `gorm:"primarykey" json:"id"`
+	DetectorID uint             `gorm:"uniqueIndex:uniq_anomaly_baseline" json:"detectorID"`
+	Detector   *AnomalyDetector `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	SeriesKey  string           `gorm:"type:varchar(20);uniqueIndex:uniq_anomaly_baseline" json:"seriesKey"` // 序列标签的指纹
+	HourOfWeek int              `gorm:"uniqueIndex:uniq_anomaly_baseline" json:"hourOfWeek"`
+
+	Labels  gormdatatypes.JSONMap `json:"labels"`
+	Median  float64               `json:"median"`
+	MAD     float64               `json:"mad"`
+	Samples int                   `json:"samples"`
+
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// AnomalySeriesKey 序列的唯一标识, 忽略指标名
+func AnomalySeriesKey(metric prommodel.Metric) string {
+	ls := prommodel.LabelSet{}
+	for k, v := range metric {
+		if k != prommodel.MetricNameLabel {
+			ls[k] = v
+		}
+	}
+	return ls.Fingerprint().String()
+}
+
+// AnomalyHourOfWeek 时间在一周中的小时, 0 为周日 0 点, 统一使用 UTC
+func AnomalyHourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// MedianMAD 中位数和中位数绝对偏差
+func MedianMAD(values []float64) (median, mad float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	median = medianOf(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	return median, medianOf(deviations)
+}
+
+func medianOf(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// LearnAnomalyBaselines 按一周中的小时计算一条序列的基线, NaN 和 Inf 的样本会被忽略
+func LearnAnomalyBaselines(samples []prommodel.SamplePair) map[int]*AnomalyBaseline {
+	grouped := map[int][]float64{}
+	for _, s := range samples {
+		v := float64(s.Value)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		hour := AnomalyHourOfWeek(s.Timestamp.Time())
+		grouped[hour] = append(grouped[hour], v)
+	}
+	ret := map[int]*AnomalyBaseline{}
+	for hour, values := range grouped {
+		median, mad := MedianMAD(values)
+		ret[hour] = &AnomalyBaseline{HourOfWeek: hour, Median: median, MAD: mad, Samples: len(values)}
+	}
+	return ret
+}
+
+// Ready 样本数是否足够用于检测
+func (b *AnomalyBaseline) Ready() bool {
+	return b.Samples >= AnomalyMinSamples
+}
+
+// Score 稳健 z-score, 正数表示高于基线
+func (b *AnomalyBaseline) Score(v float64) float64 {
+	mad := math.Max(b.MAD, math.Max(math.Abs(b.Median)*anomalyMinMADRatio, anomalyMinMAD))
+	return (v - b.Median) / (anomalyMADScale * mad)
+}
+
+// IsAnomaly 按检测方向和灵敏度判断是否异常
+func (d *AnomalyDetector) IsAnomaly(score float64) bool {
+	switch d.Direction {
+	case AnomalyDirectionUp:
+		return score > d.Sensitivity
+	case AnomalyDirectionDown:
+		return -score > d.Sensitivity
+	default:
+		return math.Abs(score) > d.Sensitivity
+	}
+}
+
+// NeedTrain 从未学习或者距离上次学习超过 AnomalyRetrainInterval
+func (d *AnomalyDetector) NeedTrain(now time.Time) bool {
+	return d.TrainedAt == nil || now.Sub(*d.TrainedAt) >= AnomalyRetrainInterval
+}
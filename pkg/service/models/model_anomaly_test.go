@@ -0,0 +1,90 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+)
+
+func TestMedianMAD(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []float64
+		wantMedian float64
+		wantMAD    float64
+	}{
+		{name: "empty", values: nil},
+		{name: "odd", values: []float64{1, 2, 3, 4, 100}, wantMedian: 3, wantMAD: 1},
+		{name: "even", values: []float64{4, 1, 3, 2}, wantMedian: 2.5, wantMAD: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			median, mad := MedianMAD(tt.values)
+			if median != tt.wantMedian || mad != tt.wantMAD {
+				t.Errorf("MedianMAD() = %v, %v, want %v, %v", median, mad, tt.wantMedian, tt.wantMAD)
+			}
+		})
+	}
+}
+
+func TestLearnAnomalyBaselines(t *testing.T) {
+	// 2022-01-02 是周日, 学习两周每天 10 点和 11 点的数据
+	start := time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC)
+	samples := []prommodel.SamplePair{}
+	for week := 0; week < 2; week++ {
+		for i := 0; i < 24; i++ {
+			ts := start.AddDate(0, 0, 7*week).Add(time.Duration(i) * AnomalyBaselineStep)
+			v := 100.0
+			if ts.Hour() == 11 {
+				v = 10
+			}
+			if i%2 == 0 {
+				v++
+			}
+			samples = append(samples, prommodel.SamplePair{Timestamp: prommodel.TimeFromUnixNano(ts.UnixNano()), Value: prommodel.SampleValue(v)})
+		}
+	}
+	samples = append(samples, prommodel.SamplePair{Timestamp: prommodel.TimeFromUnixNano(start.UnixNano()), Value: prommodel.SampleValue(math.NaN())})
+
+	baselines := LearnAnomalyBaselines(samples)
+	if len(baselines) != 2 {
+		t.Fatalf("LearnAnomalyBaselines() got %d hours, want 2", len(baselines))
+	}
+	b := baselines[10]
+	if b == nil || b.Median != 100.5 || b.MAD != 0.5 || b.Samples != 24 || !b.Ready() {
+		t.Fatalf("LearnAnomalyBaselines() hour 10 = %+v", b)
+	}
+	if baselines[11].Median != 10.5 {
+		t.Errorf("LearnAnomalyBaselines() hour 11 median = %v, want 10.5", baselines[11].Median)
+	}
+
+	d := &AnomalyDetector{Sensitivity: 3, Direction: AnomalyDirectionUp}
+	if d.IsAnomaly(b.Score(101)) || !d.IsAnomaly(b.Score(110)) || d.IsAnomaly(b.Score(50)) {
+		t.Errorf("IsAnomaly() with direction up not match")
+	}
+	d.Direction = AnomalyDirectionBoth
+	if !d.IsAnomaly(b.Score(50)) {
+		t.Errorf("IsAnomaly() with direction both should detect drop")
+	}
+	// MAD 为 0 时使用中位数的 1% 作为下限
+	flat := &AnomalyBaseline{Median: 100, Samples: AnomalyMinSamples}
+	if d.IsAnomaly(flat.Score(102)) || !d.IsAnomaly(flat.Score(110)) {
+		t.Errorf("IsAnomaly() with zero MAD not match")
+	}
+}
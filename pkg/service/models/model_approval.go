@@ -0,0 +1,230 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/datatypes"
+	"kubegems.io/kubegems/pkg/utils/slice"
+)
+
+// 审批类型
+const (
+	ApprovalKindEnvironmentCreate = "environmentCreate"
+	ApprovalKindProdDeploy        = "prodDeploy"
+	ApprovalKindUserRoleGrant     = "userRoleGrant"
+	ApprovalKindQuotaIncrease     = "quotaIncrease"
+	ApprovalKindPluginEnable      = "pluginEnable"
+)
+
+var ApprovalKinds = []string{
+	ApprovalKindEnvironmentCreate,
+	ApprovalKindProdDeploy,
+	ApprovalKindUserRoleGrant,
+	ApprovalKindQuotaIncrease,
+	ApprovalKindPluginEnable,
+}
+
+// 审批状态
+const (
+	ApprovalStatusPending  = "pending"
+	ApprovalStatusApproved = "approved"
+	ApprovalStatusRejected = "rejected"
+	ApprovalStatusExpired  = "expired"
+	// 审批通过但执行失败
+	ApprovalStatusFailed = "failed"
+	// 审批通过后已经被使用, 如生产环境的部署
+	ApprovalStatusDone = "done"
+)
+
+// 审批人角色
+const (
+	ApproverSystemAdmin  = "systemAdmin"
+	ApproverTenantAdmin  = "tenantAdmin"
+	ApproverProjectAdmin = "projectAdmin"
+)
+
+// ApprovalDefaultExpireHours 审批默认的有效期
+const ApprovalDefaultExpireHours = 72
+
+// ApprovalDefaultStages 没有配置审批规则时由系统管理员审批
+var ApprovalDefaultStages = ApprovalStages{{Name: "system", Approver: ApproverSystemAdmin}}
+
+// ApprovalRule 审批规则, 租户的规则优先于全局规则
+type ApprovalRule struct {
+	ID   uint   `gorm:"primarykey" json:"id"`
+	Kind string `gorm:"type:varchar(30);uniqueIndex:uniq_approval_rule" json:"kind"`
+	// 为空时为全局规则
+	TenantID *uint   `gorm:"uniqueIndex:uniq_approval_rule" json:"tenantID"`
+	Tenant   *Tenant `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;" json:"tenant,omitempty"`
+	// 按顺序逐级审批
+	Stages ApprovalStages `json:"stages"`
+	// 超过有效期未完成审批的申请自动过期
+	ExpireHours int  `json:"expireHours"`
+	Enabled     bool `json:"enabled"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// Approval 审批申请
+type Approval struct {
+	ID    uint   `gorm:"primarykey" json:"id"`
+	Kind  string `gorm:"type:varchar(30);index" json:"kind"`
+	Title string `json:"title"`
+
+	// 申请的范围, 决定由哪个租户或项目的管理员审批
+	TenantID      *uint `gorm:"index" json:"tenantID"`
+	ProjectID     *uint `json:"projectID"`
+	EnvironmentID *uint `json:"environmentID"`
+	ClusterID     *uint `json:"clusterID"`
+	// 申请的对象, 如应用名称、插件名称
+	Target string `gorm:"type:varchar(100)" json:"target"`
+	// 各类型申请的内容
+	Content datatypes.JSON `json:"content"`
+	// 申请时对象的版本, 如生产部署时应用编排的 git commit, 部署时只能同步该版本
+	Revision string `gorm:"type:varchar(64)" json:"revision"`
+
+	Applicant   string `gorm:"type:varchar(50);index" json:"applicant"`
+	ApplicantID uint   `json:"applicantID"`
+
+	Status       string         `gorm:"type:varchar(20);index" json:"status"`
+	Stages       ApprovalStages `json:"stages"`
+	CurrentStage int            `json:"currentStage"`
+	// 执行结果
+	Message  string     `json:"message"`
+	ExpireAt *time.Time `json:"expireAt"`
+
+	Comments []*ApprovalComment `json:"comments,omitempty"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// ApprovalComment 审批评论
+type ApprovalComment struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	ApprovalID uint      `gorm:"index" json:"approvalID"`
+	Approval   *Approval `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;" json:"-"`
+	Username   string    `gorm:"type:varchar(50)" json:"username"`
+	// 评论时所在的审批阶段
+	Stage     int        `json:"stage"`
+	Content   string     `json:"content"`
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+// ApprovalStage 审批阶段
+type ApprovalStage struct {
+	Name     string `json:"name"`
+	Approver string `json:"approver"`
+
+	Decision  string     `json:"decision,omitempty"`
+	Username  string     `json:"username,omitempty"`
+	DecidedAt *time.Time `json:"decidedAt,omitempty"`
+}
+
+type ApprovalStages []ApprovalStage
+
+func (s ApprovalStages) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *ApprovalStages) Scan(src any) error {
+	return scanJSON(src, s)
+}
+
+func (ApprovalStages) GormDataType() string {
+	return "json"
+}
+
+func (r *ApprovalRule) Validate() error {
+	if !slice.ContainStr(ApprovalKinds, r.Kind) {
+		return fmt.Errorf("approval kind %s not supported", r.Kind)
+	}
+	if len(r.Stages) == 0 {
+		return errors.New("approval rule must have at least one stage")
+	}
+	for i, stage := range r.Stages {
+		switch stage.Approver {
+		case ApproverSystemAdmin, ApproverTenantAdmin, ApproverProjectAdmin:
+		default:
+			return fmt.Errorf("approver %s of stage %d not supported", stage.Approver, i+1)
+		}
+		if stage.Name == "" {
+			r.Stages[i].Name = stage.Approver
+		}
+		r.Stages[i].Decision, r.Stages[i].Username, r.Stages[i].DecidedAt = "", "", nil
+	}
+	if r.ExpireHours < 0 {
+		return errors.New("expire hours can't be negative")
+	}
+	if r.ExpireHours == 0 {
+		r.ExpireHours = ApprovalDefaultExpireHours
+	}
+	return nil
+}
+
+// Start 按规则开始审批, rule 为空时使用默认规则
+func (a *Approval) Start(rule *ApprovalRule, now time.Time) {
+	stages, expireHours := ApprovalDefaultStages, ApprovalDefaultExpireHours
+	if rule != nil {
+		stages, expireHours = rule.Stages, rule.ExpireHours
+	}
+	a.Stages = append(ApprovalStages{}, stages...)
+	a.CurrentStage = 0
+	a.Status = ApprovalStatusPending
+	expireAt := now.Add(time.Duration(expireHours) * time.Hour)
+	a.ExpireAt = &expireAt
+}
+
+// Stage 当前的审批阶段, 审批结束后为空
+func (a *Approval) Stage() *ApprovalStage {
+	if a.Status != ApprovalStatusPending || a.CurrentStage >= len(a.Stages) {
+		return nil
+	}
+	return &a.Stages[a.CurrentStage]
+}
+
+// Decide 当前阶段的审批, 所有阶段都通过后为 approved, 任一阶段拒绝为 rejected
+func (a *Approval) Decide(username string, approve bool, now time.Time) error {
+	stage := a.Stage()
+	if stage == nil {
+		return fmt.Errorf("approval is %s", a.Status)
+	}
+	if a.Expired(now) {
+		return errors.New("approval is expired")
+	}
+	stage.Username, stage.DecidedAt = username, &now
+	if !approve {
+		stage.Decision = ApprovalStatusRejected
+		a.Status = ApprovalStatusRejected
+		return nil
+	}
+	stage.Decision = ApprovalStatusApproved
+	a.CurrentStage++
+	if a.CurrentStage >= len(a.Stages) {
+		a.Status = ApprovalStatusApproved
+	}
+	return nil
+}
+
+func (a *Approval) Expired(now time.Time) bool {
+	return a.Status == ApprovalStatusPending && a.ExpireAt != nil && now.After(*a.ExpireAt)
+}
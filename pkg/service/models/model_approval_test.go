@@ -0,0 +1,96 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApprovalRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    ApprovalRule
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			rule: ApprovalRule{Kind: ApprovalKindProdDeploy, Stages: ApprovalStages{{Approver: ApproverProjectAdmin}, {Approver: ApproverTenantAdmin}}},
+		},
+		{
+			name:    "unknown kind",
+			rule:    ApprovalRule{Kind: "unknown", Stages: ApprovalStages{{Approver: ApproverSystemAdmin}}},
+			wantErr: true,
+		},
+		{
+			name:    "no stages",
+			rule:    ApprovalRule{Kind: ApprovalKindPluginEnable},
+			wantErr: true,
+		},
+		{
+			name:    "unknown approver",
+			rule:    ApprovalRule{Kind: ApprovalKindPluginEnable, Stages: ApprovalStages{{Approver: "someone"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ApprovalRule.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (tt.rule.ExpireHours != ApprovalDefaultExpireHours || tt.rule.Stages[0].Name != tt.rule.Stages[0].Approver) {
+				t.Errorf("ApprovalRule.Validate() defaults not set: %+v", tt.rule)
+			}
+		})
+	}
+}
+
+func TestApproval_Decide(t *testing.T) {
+	now := time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC)
+	rule := &ApprovalRule{ExpireHours: 1, Stages: ApprovalStages{{Approver: ApproverProjectAdmin}, {Approver: ApproverTenantAdmin}}}
+
+	a := &Approval{}
+	a.Start(rule, now)
+	if err := a.Decide("pa", true, now); err != nil || a.Status != ApprovalStatusPending || a.CurrentStage != 1 {
+		t.Fatalf("first stage: err = %v, status = %s, stage = %d", err, a.Status, a.CurrentStage)
+	}
+	if err := a.Decide("ta", true, now); err != nil || a.Status != ApprovalStatusApproved {
+		t.Fatalf("second stage: err = %v, status = %s", err, a.Status)
+	}
+	if err := a.Decide("ta", true, now); err == nil {
+		t.Errorf("decide on approved approval should fail")
+	}
+
+	rejected := &Approval{}
+	rejected.Start(rule, now)
+	if err := rejected.Decide("pa", false, now); err != nil || rejected.Status != ApprovalStatusRejected {
+		t.Errorf("reject: err = %v, status = %s", err, rejected.Status)
+	}
+
+	expired := &Approval{}
+	expired.Start(rule, now)
+	if err := expired.Decide("pa", true, now.Add(2*time.Hour)); err == nil || !expired.Expired(now.Add(2*time.Hour)) {
+		t.Errorf("decide on expired approval should fail")
+	}
+
+	// 没有规则时使用默认规则, 且不修改默认规则
+	def := &Approval{}
+	def.Start(nil, now)
+	_ = def.Decide("admin", true, now)
+	if def.Status != ApprovalStatusApproved || ApprovalDefaultStages[0].Decision != "" {
+		t.Errorf("default stages: status = %s, default = %+v", def.Status, ApprovalDefaultStages)
+	}
+}
@@ -20,13 +20,15 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
 )
 
 type AuthSource struct {
 	ID        uint             `json:"id"`
 	Name      string           `gorm:"unique" json:"name"`
-	Kind      string           `json:"kind" binding:"oneof=LDAP OAUTH"`
-	Vendor    string           `gorm:"type:varchar(30)" json:"vendor" binding:"omitempty,oneof=github gitlab oauth ldap"`
+	Kind      string           `json:"kind" binding:"oneof=LDAP OAUTH OIDC SAML"`
+	Vendor    string           `gorm:"type:varchar(30)" json:"vendor" binding:"omitempty,oneof=github gitlab oauth ldap oidc saml"`
 	Config    AuthSourceConfig `json:"config" binding:"required,json"`
 	TokenType string           `json:"tokenType" binding:"required,oneof=Bearer"`
 	Enabled   bool             `json:"enabled"`
@@ -47,14 +49,30 @@ func (AuthSourceSimple) TableName() string {
 }
 
 type AuthSourceConfig struct {
-	AuthURL     string   `json:"authURL,omitempty" binding:"omitempty,url,required_with=TokenURL UserInfoURL RedirectURL AppID AppSecret"`
-	TokenURL    string   `json:"tokenURL,omitempty" binding:"omitempty,url,required_with=AuthURL UserInfoURL RedirectURL AppID AppSecret"`
-	UserInfoURL string   `json:"userInfoURL,omitempty" binding:"omitempty,url,required_with=AuthURL TokenURL RedirectURL AppID AppSecret"`
-	RedirectURL string   `json:"redirectURL,omitempty" binding:"omitempty,url,required_with=AuthURL TokenURL UserInfoURL AppID AppSecret"`
-	AppID       string   `json:"appID,omitempty" binding:"required_with=AuthURL TokenURL UserInfoURL RedirectURL AppSecret"`
-	AppSecret   string   `json:"appSecret,omitempty" binding:"required_with=AuthURL TokenURL UserInfoURL RedirectURL AppID"`
+	AuthURL     string   `json:"authURL,omitempty" binding:"omitempty,url,required_with=TokenURL UserInfoURL"`
+	TokenURL    string   `json:"tokenURL,omitempty" binding:"omitempty,url,required_with=AuthURL UserInfoURL"`
+	UserInfoURL string   `json:"userInfoURL,omitempty" binding:"omitempty,url,required_with=AuthURL TokenURL"`
+	RedirectURL string   `json:"redirectURL,omitempty" binding:"omitempty,url"`
+	AppID       string   `json:"appID,omitempty" binding:"required_with=AppSecret"`
+	AppSecret   string   `json:"appSecret,omitempty" binding:"required_with=AppID"`
 	Scopes      []string `json:"scopes,omitempty"`
 
+	// oidc, 通过 issuer 的 discovery 获取 endpoints 并校验 id_token
+	Issuer string `json:"issuer,omitempty" binding:"omitempty,url"`
+
+	// saml, RedirectURL 为登录完成后跳转的前端地址
+	SSOURL         string `json:"ssoURL,omitempty" binding:"omitempty,url"`
+	EntityID       string `json:"entityID,omitempty"`
+	ACSURL         string `json:"acsURL,omitempty" binding:"omitempty,url"`
+	IdPCertificate string `json:"idpCertificate,omitempty"`
+
+	// oidc 的 claim 名称或 saml 的 attribute 名称, 为空时使用默认值; LDAP 使用 GroupsClaim 作为组属性(默认 memberOf)
+	UsernameClaim string `json:"usernameClaim,omitempty"`
+	EmailClaim    string `json:"emailClaim,omitempty"`
+	GroupsClaim   string `json:"groupsClaim,omitempty"`
+	// 根据组映射(AuthSourceGroupMapping)同步租户、项目和环境角色, 默认关闭
+	SyncGroupRoles bool `json:"syncGroupRoles,omitempty"`
+
 	// ldap
 	Name         string `json:"name,omitempty"`
 	LdapAddr     string `json:"ldapaddr,omitempty" binding:"omitempty,hostname_port,required_with=BaseDN BindUsername BindPassword"`
@@ -80,3 +98,57 @@ func (cfg *AuthSourceConfig) Scan(value interface{}) error {
 func (cfg AuthSourceConfig) Value() (driver.Value, error) {
 	return json.Marshal(cfg)
 }
+
+const (
+	GroupMappingKindTenant      = "tenant"
+	GroupMappingKindProject     = "project"
+	GroupMappingKindEnvironment = "environment"
+)
+
+// AuthSourceGroupMapping 登录源(LDAP/OIDC/SAML)中的组到 kubegems 角色的映射
+type AuthSourceGroupMapping struct {
+	ID           uint        `gorm:"primarykey" json:"id"`
+	AuthSourceID uint        `gorm:"uniqueIndex:uniq_idx_group_mapping" json:"authSourceID"`
+	AuthSource   *AuthSource `json:"authSource,omitempty" gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;"`
+	// 目录或 IdP 中的组名; LDAP 可以是组的 DN 或 CN, 不区分大小写
+	Group string `gorm:"type:varchar(255);uniqueIndex:uniq_idx_group_mapping" json:"group" binding:"required"`
+	// tenant, project, environment
+	Kind string `gorm:"type:varchar(20);uniqueIndex:uniq_idx_group_mapping" json:"kind" binding:"required,oneof=tenant project environment"`
+	// 租户、项目或环境的ID
+	ResourceID uint `gorm:"uniqueIndex:uniq_idx_group_mapping" json:"resourceID" binding:"required"`
+	// 对应层级的角色, 租户(admin, ordinary), 项目(admin, dev, test, ops), 环境(reader, operator)
+	Role      string     `gorm:"type:varchar(30)" json:"role" binding:"required"`
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+// UserSourceGroups 用户最近一次登录或同步时在登录源中的组
+type UserSourceGroups struct {
+	ID       uint                    `gorm:"primarykey" json:"id"`
+	UserID   uint                    `gorm:"uniqueIndex" json:"userID"`
+	User     *User                   `json:"user,omitempty" gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;"`
+	Groups   gormdatatypes.JSONSlice `json:"groups"`
+	SyncedAt *time.Time              `json:"syncedAt"`
+}
+
+// GroupSyncedRole 由组同步授予的成员关系; 用户移出组后只回收这些关系, 不影响手动添加的成员
+type GroupSyncedRole struct {
+	ID         uint   `gorm:"primarykey" json:"id"`
+	UserID     uint   `gorm:"uniqueIndex:uniq_idx_group_synced_role" json:"userID"`
+	User       *User  `json:"user,omitempty" gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;"`
+	Kind       string `gorm:"type:varchar(20);uniqueIndex:uniq_idx_group_synced_role" json:"kind"`
+	ResourceID uint   `gorm:"uniqueIndex:uniq_idx_group_synced_role" json:"resourceID"`
+	Role       string `gorm:"type:varchar(30)" json:"role"`
+}
+
+const (
+	AuthNonceKindSAMLRequest   = "samlRequest"
+	AuthNonceKindSAMLAssertion = "samlAssertion"
+)
+
+// AuthNonce 登录过程中的一次性标识, 如已发出的 SAML AuthnRequest ID 和已使用的 Assertion ID, 多副本之间共享
+type AuthNonce struct {
+	Kind     string    `gorm:"type:varchar(30);primaryKey" json:"kind"`
+	Value    string    `gorm:"type:varchar(255);primaryKey" json:"value"`
+	Source   string    `gorm:"type:varchar(50)" json:"source"`
+	ExpireAt time.Time `gorm:"index" json:"expireAt"`
+}
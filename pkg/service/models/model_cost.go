@@ -0,0 +1,140 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CostDateLayout 日汇总的日期格式, 统一使用 UTC
+	CostDateLayout = "2006-01-02"
+
+	costGigabyte = 1 << 30
+)
+
+// CostPrice 集群的资源单价, 配置了单价的集群才会统计费用
+type CostPrice struct {
+	ID        uint     `gorm:"primarykey" json:"id"`
+	ClusterID uint     `gorm:"uniqueIndex" json:"clusterID"`
+	Cluster   *Cluster `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;" json:"cluster,omitempty"`
+
+	Currency      string  `gorm:"type:varchar(10)" json:"currency"`
+	CPUCoreHour   float64 `json:"cpuCoreHour"`   // 每核每小时
+	MemoryGBHour  float64 `json:"memoryGBHour"`  // 每 GB 内存每小时
+	StorageGBHour float64 `json:"storageGBHour"` // 每 GB 存储每小时
+	GPUHour       float64 `json:"gpuHour"`       // 每卡每小时
+
+	// 已经统计到的时间, 失败后从这里继续
+	SampledUntil *time.Time `json:"sampledUntil"`
+	LastError    string     `json:"lastError"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// CostDaily 环境命名空间每天的资源用量和费用, 按采样时的单价计算, 修改单价不影响已经统计的费用
+type CostDaily struct {
+	ID              uint   `gorm:"primarykey" json:"id"`
+	Date            string `gorm:"type:varchar(10);uniqueIndex:uniq_cost_daily" json:"date"`
+	ClusterName     string `gorm:"type:varchar(50);uniqueIndex:uniq_cost_daily" json:"cluster"`
+	Namespace       string `gorm:"type:varchar(50);uniqueIndex:uniq_cost_daily" json:"namespace"`
+	TenantName      string `gorm:"type:varchar(50);index" json:"tenant"`
+	ProjectName     string `gorm:"type:varchar(50);index" json:"project"`
+	EnvironmentName string `gorm:"type:varchar(50);index" json:"environment"`
+	Currency        string `gorm:"type:varchar(10)" json:"currency"`
+	Hours           int    `json:"hours"` // 已统计的小时数
+
+	CostUsage `gorm:"embedded"`
+
+	CPUCost     float64 `json:"cpuCost"`
+	MemoryCost  float64 `json:"memoryCost"`
+	StorageCost float64 `json:"storageCost"`
+	GPUCost     float64 `json:"gpuCost"`
+	Cost        float64 `json:"cost"`
+}
+
+// CostUsage 资源用量, 单位为核时、GB 时和卡时
+type CostUsage struct {
+	CPUUsageCoreHours    float64 `json:"cpuUsageCoreHours"`
+	CPURequestCoreHours  float64 `json:"cpuRequestCoreHours"`
+	MemoryUsageGBHours   float64 `json:"memoryUsageGBHours"`
+	MemoryRequestGBHours float64 `json:"memoryRequestGBHours"`
+	StorageGBHours       float64 `json:"storageGBHours"`
+	GPUHours             float64 `json:"gpuHours"`
+}
+
+// CostSample 命名空间一个小时的平均用量, 内存和存储的单位为字节
+type CostSample struct {
+	CPUUsageCores      float64
+	CPURequestCores    float64
+	MemoryUsageBytes   float64
+	MemoryRequestBytes float64
+	StorageBytes       float64
+	GPUs               float64
+}
+
+func (p *CostPrice) Validate() error {
+	if p.CPUCoreHour < 0 || p.MemoryGBHour < 0 || p.StorageGBHour < 0 || p.GPUHour < 0 {
+		return errors.New("prices can't be negative")
+	}
+	if p.Currency == "" {
+		p.Currency = "CNY"
+	}
+	return nil
+}
+
+// Add 累加一个小时的用量, cpu 和内存按请求量和实际使用量中较大的计费, 存储和 gpu 按请求量计费
+func (d *CostDaily) Add(s CostSample, price *CostPrice) {
+	cpu := math.Max(s.CPUUsageCores, s.CPURequestCores)
+	memory := math.Max(s.MemoryUsageBytes, s.MemoryRequestBytes) / costGigabyte
+
+	d.Hours++
+	d.Currency = price.Currency
+	d.CPUUsageCoreHours += s.CPUUsageCores
+	d.CPURequestCoreHours += s.CPURequestCores
+	d.MemoryUsageGBHours += s.MemoryUsageBytes / costGigabyte
+	d.MemoryRequestGBHours += s.MemoryRequestBytes / costGigabyte
+	d.StorageGBHours += s.StorageBytes / costGigabyte
+	d.GPUHours += s.GPUs
+
+	d.CPUCost += cpu * price.CPUCoreHour
+	d.MemoryCost += memory * price.MemoryGBHour
+	d.StorageCost += s.StorageBytes / costGigabyte * price.StorageGBHour
+	d.GPUCost += s.GPUs * price.GPUHour
+	d.Cost = d.CPUCost + d.MemoryCost + d.StorageCost + d.GPUCost
+}
+
+// CostDate 一个小时的用量所属的日期, hourEnd 为这个小时的结束时间
+func CostDate(hourEnd time.Time) string {
+	return hourEnd.Add(-time.Hour).UTC().Format(CostDateLayout)
+}
+
+// PendingHours 需要统计的小时的结束时间, 从已统计的进度开始到当前完整的小时为止, 首次统计从上一个小时开始
+func (p *CostPrice) PendingHours(now time.Time, max int) []time.Time {
+	until := now.Truncate(time.Hour)
+	from := until.Add(-time.Hour)
+	if p.SampledUntil != nil {
+		from = p.SampledUntil.Truncate(time.Hour)
+	}
+	hours := []time.Time{}
+	for h := from.Add(time.Hour); !h.After(until) && len(hours) < max; h = h.Add(time.Hour) {
+		hours = append(hours, h)
+	}
+	return hours
+}
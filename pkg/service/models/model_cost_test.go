@@ -0,0 +1,97 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCostDaily_Add(t *testing.T) {
+	price := &CostPrice{Currency: "CNY", CPUCoreHour: 0.5, MemoryGBHour: 0.25, StorageGBHour: 0.125, GPUHour: 1}
+	d := &CostDaily{}
+	// cpu 使用量超过请求量, 内存请求量超过使用量
+	d.Add(CostSample{
+		CPUUsageCores:      2,
+		CPURequestCores:    1,
+		MemoryUsageBytes:   1 << 30,
+		MemoryRequestBytes: 4 << 30,
+		StorageBytes:       10 << 30,
+		GPUs:               1,
+	}, price)
+	d.Add(CostSample{CPURequestCores: 1}, price)
+
+	want := CostDaily{
+		Currency: "CNY",
+		Hours:    2,
+		CostUsage: CostUsage{
+			CPUUsageCoreHours:    2,
+			CPURequestCoreHours:  2,
+			MemoryUsageGBHours:   1,
+			MemoryRequestGBHours: 4,
+			StorageGBHours:       10,
+			GPUHours:             1,
+		},
+		CPUCost:     1.5,
+		MemoryCost:  1,
+		StorageCost: 1.25,
+		GPUCost:     1,
+		Cost:        4.75,
+	}
+	if *d != want {
+		t.Errorf("CostDaily.Add() = %+v, want %+v", *d, want)
+	}
+}
+
+func TestCostPrice_PendingHours(t *testing.T) {
+	now := time.Date(2022, 10, 1, 10, 30, 0, 0, time.UTC)
+	at := func(hour int) *time.Time {
+		t := time.Date(2022, 10, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	tests := []struct {
+		name         string
+		sampledUntil *time.Time
+		max          int
+		want         int
+	}{
+		{name: "first run", want: 1},
+		{name: "up to date", sampledUntil: at(10), want: 0},
+		{name: "behind", sampledUntil: at(6), want: 4},
+		{name: "limited", sampledUntil: at(0), max: 3, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			max := tt.max
+			if max == 0 {
+				max = 24
+			}
+			p := &CostPrice{SampledUntil: tt.sampledUntil}
+			got := p.PendingHours(now, max)
+			if len(got) != tt.want {
+				t.Fatalf("PendingHours() = %v, want %d hours", got, tt.want)
+			}
+			if len(got) > 0 && !got[len(got)-1].After(*at(0)) {
+				t.Errorf("PendingHours() = %v, not after start", got)
+			}
+		})
+	}
+}
+
+func TestCostDate(t *testing.T) {
+	if got := CostDate(time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC)); got != "2022-10-01" {
+		t.Errorf("CostDate() = %s, want 2022-10-01", got)
+	}
+}
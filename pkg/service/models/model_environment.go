@@ -27,6 +27,9 @@ const (
 	EnvironmentRoleOperator = "operator"
 
 	ResEnvironment = "environment"
+
+	// 生产环境
+	EnvironmentMetaTypeProd = "prod"
 )
 
 // Environment 环境表
@@ -54,6 +57,8 @@ type Environment struct {
 	ResourceQuota datatypes.JSON
 	// 环境下的limitrage
 	LimitRange datatypes.JSON
+	// 定时休眠, 参考 v1beta1.SleepSchedule
+	SleepSchedule datatypes.JSON
 	// 所属项目ID
 	ProjectID uint `gorm:"uniqueIndex:uniq_idx_project_env"`
 	// 所属集群ID
@@ -0,0 +1,186 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"time"
+
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+)
+
+const (
+	IncidentStatusOpen         = "open"
+	IncidentStatusAcknowledged = "acknowledged"
+	IncidentStatusResolved     = "resolved"
+
+	IncidentEventCreated       = "created"
+	IncidentEventAlert         = "alert"
+	IncidentEventAlertResolved = "alertResolved"
+	IncidentEventStatus        = "status"
+	IncidentEventAssign        = "assign"
+	IncidentEventNote          = "note"
+
+	// 超过该时间没有新告警的事件不再关联新的告警
+	IncidentCorrelationWindow = 30 * time.Minute
+	// 告警规则可以通过该标签显式指定关联键, 关联键相同的告警聚合到同一事件
+	IncidentCorrelationKeyLabel = "incident_key"
+
+	incidentOpenKeyMaxLen = 255
+)
+
+var (
+	// 工作负载相关的标签, 按优先级排列
+	incidentWorkloadLabels = []string{"deployment", "statefulset", "daemonset", "workload"}
+	// 值相同时认为告警相关的标签
+	incidentCorrelationLabels = []string{"node", "host", "instance", "service", "job"}
+
+	// 由 pod 名推断工作负载名: deployment 的 pod 带 replicaset hash, statefulset 的 pod 带序号, daemonset 的 pod 带随机后缀.
+	// kubernetes 生成的随机后缀只使用 bcdfghjklmnpqrstvwxz2456789 这些字符
+	podOfDeploymentReg  = regexp.MustCompile(`^(.+)-[bcdfghjklmnpqrstvwxz2456789]{6,10}-[bcdfghjklmnpqrstvwxz2456789]{5}$`)
+	podOfStatefulSetReg = regexp.MustCompile(`^(.+)-\d+$`)
+	podOfDaemonSetReg   = regexp.MustCompile(`^(.+)-[bcdfghjklmnpqrstvwxz2456789]{5}$`)
+)
+
+// Incident 事件, 将同一环境内相关的告警聚合在一起处理和通知
+type Incident struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	Title    string `gorm:"type:varchar(255)" json:"title"`
+	Status   string `gorm:"type:varchar(20);index" json:"status"`
+	Severity string `gorm:"type:varchar(20)" json:"severity"`
+	Assignee string `gorm:"type:varchar(50)" json:"assignee"` // 处理人
+
+	ClusterName     string `gorm:"type:varchar(50)" json:"clusterName"`
+	Namespace       string `gorm:"type:varchar(50)" json:"namespace"`
+	TenantName      string `gorm:"type:varchar(50);index" json:"tenantName"`
+	ProjectName     string `gorm:"type:varchar(50);index" json:"projectName"`
+	EnvironmentName string `gorm:"type:varchar(50);index" json:"environmentName"`
+	Workload        string `gorm:"type:varchar(255)" json:"workload"`       // 关联的工作负载名
+	CorrelationKey  string `gorm:"type:varchar(255)" json:"correlationKey"` // 关联键, 为空时只按共享标签关联
+	// 未恢复事件的唯一键, 防止并发的告警重复创建事件, 事件恢复后置空
+	OpenKey *string `gorm:"type:varchar(255);uniqueIndex" json:"-"`
+
+	CommonLabels gormdatatypes.JSONMap `json:"commonLabels"` // 所有告警共有的标签
+	AlertCount   int                   `json:"alertCount"`   // 关联的告警数, 按指纹计算
+
+	StartsAt    *time.Time `json:"startsAt"`
+	LastAlertAt *time.Time `gorm:"index" json:"lastAlertAt"`
+	ResolvedAt  *time.Time `json:"resolvedAt"`
+	CreatedAt   *time.Time `json:"createdAt"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+
+	Alerts []*AlertMessage  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"alerts,omitempty"`
+	Events []*IncidentEvent `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"events,omitempty"`
+}
+
+// IncidentEvent 事件时间线, 包括告警的加入和恢复、状态和处理人的变更以及备注
+type IncidentEvent struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	IncidentID uint       `gorm:"index" json:"incidentID"`
+	Type       string     `gorm:"type:varchar(20)" json:"type"`
+	Creator    string     `gorm:"type:varchar(50)" json:"creator"` // 为空时由系统产生
+	Content    string     `json:"content"`
+	CreatedAt  *time.Time `json:"createdAt"`
+}
+
+func IsValidIncidentStatus(status string) bool {
+	switch status {
+	case IncidentStatusOpen, IncidentStatusAcknowledged, IncidentStatusResolved:
+		return true
+	}
+	return false
+}
+
+// IncidentWorkload 根据告警标签得到关联的工作负载名, 没有工作负载标签时由 pod 名推断
+func IncidentWorkload(labels map[string]string) string {
+	for _, l := range incidentWorkloadLabels {
+		if v := labels[l]; v != "" {
+			return v
+		}
+	}
+	pod := labels["pod"]
+	if pod == "" {
+		return ""
+	}
+	for _, reg := range []*regexp.Regexp{podOfDeploymentReg, podOfStatefulSetReg, podOfDaemonSetReg} {
+		if sub := reg.FindStringSubmatch(pod); sub != nil {
+			return sub[1]
+		}
+	}
+	return pod
+}
+
+// IncidentCorrelationKey 告警的关联键, 优先使用显式指定的关联键, 否则使用工作负载名
+func IncidentCorrelationKey(labels map[string]string) string {
+	if key := labels[IncidentCorrelationKeyLabel]; key != "" {
+		return key
+	}
+	return IncidentWorkload(labels)
+}
+
+// IncidentOpenKey 未恢复事件的唯一键, 没有关联键时使用首个告警的指纹
+func IncidentOpenKey(cluster, namespace, correlationKey, fingerprint string) string {
+	if correlationKey == "" {
+		correlationKey = "fingerprint:" + fingerprint
+	}
+	key := cluster + "/" + namespace + "/" + correlationKey
+	if len(key) > incidentOpenKeyMaxLen {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+// Correlated 告警是否和事件相关: 关联键相同, 或者共享节点、实例、服务等标签
+func (i *Incident) Correlated(labels map[string]string, correlationKey string) bool {
+	if i.CorrelationKey != "" && i.CorrelationKey == correlationKey {
+		return true
+	}
+	for _, l := range incidentCorrelationLabels {
+		if v := labels[l]; v != "" && i.CommonLabels[l] == v {
+			return true
+		}
+	}
+	return false
+}
+
+// AddAlert 更新事件的共有标签、级别和告警数
+func (i *Incident) AddAlert(labels map[string]string, severity string, at time.Time) {
+	common := gormdatatypes.JSONMap{}
+	for k, v := range i.CommonLabels {
+		if labels[k] == v {
+			common[k] = v
+		}
+	}
+	i.CommonLabels = common
+	if IncidentSeverityRank(severity) > IncidentSeverityRank(i.Severity) {
+		i.Severity = severity
+	}
+	i.AlertCount++
+	i.LastAlertAt = &at
+}
+
+func IncidentSeverityRank(severity string) int {
+	switch severity {
+	case prometheus.SeverityCritical:
+		return 2
+	case prometheus.SeverityError:
+		return 1
+	}
+	return 0
+}
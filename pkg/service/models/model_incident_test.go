@@ -0,0 +1,83 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+)
+
+func TestIncidentWorkload(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{name: "deployment label", labels: map[string]string{"deployment": "api", "pod": "other-0"}, want: "api"},
+		{name: "deployment pod", labels: map[string]string{"pod": "nginx-7d9f8b6c5d-x2k4p"}, want: "nginx"},
+		{name: "statefulset pod", labels: map[string]string{"pod": "mysql-0"}, want: "mysql"},
+		{name: "daemonset pod", labels: map[string]string{"pod": "node-exporter-x2k4p"}, want: "node-exporter"},
+		{name: "unknown pod", labels: map[string]string{"pod": "my-app-server"}, want: "my-app-server"},
+		{name: "no workload", labels: map[string]string{"node": "node1"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IncidentWorkload(tt.labels); got != tt.want {
+				t.Errorf("IncidentWorkload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncident_Correlated(t *testing.T) {
+	incident := &Incident{
+		Workload:       "nginx",
+		CorrelationKey: "nginx",
+		CommonLabels:   gormdatatypes.JSONMap{"alertname": "PodRestart", "node": "node1", "pod": "nginx-7d9f8b6c5d-x2k4p"},
+	}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   bool
+	}{
+		{name: "same workload", labels: map[string]string{"pod": "nginx-7d9f8b6c5d-z9k2p"}, want: true},
+		{name: "same explicit key", labels: map[string]string{"incident_key": "nginx", "pod": "redis-0"}, want: true},
+		{name: "same node", labels: map[string]string{"node": "node1"}, want: true},
+		{name: "other workload", labels: map[string]string{"node": "node2", "pod": "redis-0"}, want: false},
+		{name: "no workload", labels: map[string]string{"alertname": "PodRestart"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := incident.Correlated(tt.labels, IncidentCorrelationKey(tt.labels)); got != tt.want {
+				t.Errorf("Incident.Correlated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 两者都没有工作负载时不再视为相关
+	if (&Incident{}).Correlated(map[string]string{"alertname": "PodRestart"}, "") {
+		t.Errorf("Incident.Correlated() without correlation key = true, want false")
+	}
+
+	incident.AddAlert(map[string]string{"alertname": "OOMKilled", "node": "node1", "severity": "critical"}, "critical", time.Now())
+	if len(incident.CommonLabels) != 1 || incident.CommonLabels["node"] != "node1" {
+		t.Errorf("Incident.AddAlert() common labels = %v", incident.CommonLabels)
+	}
+	if incident.Severity != "critical" || incident.AlertCount != 1 {
+		t.Errorf("Incident.AddAlert() severity = %s, alert count = %d", incident.Severity, incident.AlertCount)
+	}
+}
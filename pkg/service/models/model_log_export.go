@@ -0,0 +1,225 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	LogExportDestinationS3  = "s3"
+	LogExportDestinationPVC = "pvc"
+
+	// LogExportLocalDir pvc 导出时 worker 中挂载的目录
+	LogExportLocalDir = "data/logexport"
+	// LogExportObjectSuffix 每小时导出一个 gzip 压缩的 ndjson 文件
+	LogExportObjectSuffix = ".ndjson.gz"
+
+	// 等待 loki 写入完成后再导出
+	logExportDelay = 5 * time.Minute
+	// 每次最多导出的小时数, 剩余的下次继续
+	logExportMaxHoursPerRun = 24
+	logExportHourLayout     = "2006/01/02/15"
+)
+
+// LogExport 定时日志导出, worker 按小时将日志导出到对象存储或者 pvc, 并按保留时间清理
+type LogExport struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Cluster     string `gorm:"type:varchar(50);uniqueIndex:uniq_log_export" json:"cluster"`
+	Namespace   string `gorm:"type:varchar(50);uniqueIndex:uniq_log_export" json:"namespace"`
+	Name        string `gorm:"type:varchar(50);uniqueIndex:uniq_log_export" binding:"required,max=50" json:"name"`
+	Description string `json:"description"`
+
+	Query       string               `binding:"required" json:"query"`                             // LogQL, eg. {container="app"} |= "audit", 自动限定在当前命名空间
+	Schedule    string               `gorm:"type:varchar(100)" binding:"required" json:"schedule"` // cron 表达式, eg. 0 * * * *
+	Destination LogExportDestination `gorm:"type:json" json:"destination"`
+	Retention   string               `gorm:"type:varchar(20)" json:"retention"` // 导出文件的保留时间, eg. 30d, 为空表示不清理
+
+	StartAt *time.Time `json:"startAt"` // 导出的开始时间, 为空时从创建时开始
+	Enabled bool       `gorm:"default:true" json:"enabled"`
+	Creator string     `gorm:"type:varchar(50)" json:"creator"`
+
+	// 导出进度, 已经导出到的时间, 失败后从这里继续
+	ExportedUntil *time.Time `json:"exportedUntil"`
+	LastRunAt     *time.Time `json:"lastRunAt"`
+	LastError     string     `json:"lastError"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// LogExportDestination 导出目的地, s3 兼容的对象存储或者 pvc
+type LogExportDestination struct {
+	Type string `json:"type"` // s3, pvc
+
+	Endpoint        string `json:"endpoint,omitempty"`
+	Region          string `json:"region,omitempty"`
+	Bucket          string `json:"bucket,omitempty"`
+	AccessKeyID     string `json:"accessKeyID,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+
+	// 对象前缀, pvc 导出时为 LogExportLocalDir 下的相对路径
+	Path string `json:"path,omitempty"`
+}
+
+func (m LogExportDestination) Value() (driver.Value, error) {
+	ba, err := json.Marshal(m)
+	return string(ba), err
+}
+
+func (m *LogExportDestination) Scan(val interface{}) error {
+	return scanJSON(val, m)
+}
+
+func (m LogExportDestination) GormDataType() string {
+	return "json"
+}
+
+func (e *LogExport) Validate() error {
+	// 名称会作为导出文件路径的一部分
+	if errs := validation.IsDNS1123Label(e.Name); len(errs) > 0 {
+		return errors.Errorf("log export name not valid: %s", strings.Join(errs, ", "))
+	}
+	if _, err := cron.ParseStandard(e.Schedule); err != nil {
+		return errors.Wrapf(err, "schedule %s not valid", e.Schedule)
+	}
+	if e.Retention != "" {
+		if _, err := prommodel.ParseDuration(e.Retention); err != nil {
+			return errors.Wrapf(err, "retention %s not valid", e.Retention)
+		}
+	}
+	if !strings.HasPrefix(strings.TrimSpace(e.Query), "{") {
+		return errors.New("query must start with a stream selector")
+	}
+	dest := &e.Destination
+	switch dest.Type {
+	case LogExportDestinationS3:
+		if dest.Endpoint == "" || dest.Bucket == "" {
+			return errors.New("endpoint and bucket are required for s3 destination")
+		}
+	case LogExportDestinationPVC:
+	default:
+		return errors.Errorf("destination type %s not supported", dest.Type)
+	}
+	if strings.Contains("/"+dest.Path+"/", "/../") {
+		return errors.Errorf("destination path %s not valid", dest.Path)
+	}
+	dest.Path = strings.Trim(path.Clean("/"+dest.Path), "/")
+	return nil
+}
+
+// NamespacedQuery 在日志流选择器中加入命名空间, 只能导出当前命名空间的日志
+func (e *LogExport) NamespacedQuery() string {
+	query := strings.TrimSpace(e.Query)
+	selector := strings.TrimSpace(strings.TrimPrefix(query, "{"))
+	sep := ", "
+	if strings.HasPrefix(selector, "}") {
+		sep = ""
+	}
+	return fmt.Sprintf(`{namespace=%q%s%s`, e.Namespace, sep, selector)
+}
+
+// Due 是否到了执行时间
+func (e *LogExport) Due(now time.Time) bool {
+	sched, err := cron.ParseStandard(e.Schedule)
+	if err != nil {
+		return false
+	}
+	last := e.LastRunAt
+	if last == nil {
+		last = e.CreatedAt
+	}
+	if last == nil {
+		return true
+	}
+	return !sched.Next(*last).After(now)
+}
+
+// PendingHours 需要导出的小时, 从已导出的进度开始到当前完整的小时为止
+func (e *LogExport) PendingHours(now time.Time) []time.Time {
+	var from time.Time
+	switch {
+	case e.ExportedUntil != nil:
+		from = *e.ExportedUntil
+	case e.StartAt != nil:
+		from = *e.StartAt
+	case e.CreatedAt != nil:
+		from = *e.CreatedAt
+	default:
+		from = now
+	}
+	from = from.Truncate(time.Hour)
+	until := now.Add(-logExportDelay).Truncate(time.Hour)
+	hours := []time.Time{}
+	for h := from; h.Before(until) && len(hours) < logExportMaxHoursPerRun; h = h.Add(time.Hour) {
+		hours = append(hours, h)
+	}
+	return hours
+}
+
+// ObjectPrefix 导出文件的前缀, 路径为 <path>/<cluster>/<namespace>/<name>/, 不允许超出 <path>
+func (e *LogExport) ObjectPrefix() (string, error) {
+	for _, seg := range []string{e.Cluster, e.Namespace, e.Name} {
+		if seg == "" || seg == "." || seg == ".." || strings.ContainsAny(seg, `/\`) {
+			return "", errors.Errorf("log export path segment %q not valid", seg)
+		}
+	}
+	base := path.Clean("/" + e.Destination.Path)
+	prefix := path.Join(base, e.Cluster, e.Namespace, e.Name) + "/"
+	if !strings.HasPrefix(prefix, strings.TrimSuffix(base, "/")+"/") {
+		return "", errors.Errorf("log export prefix %s escapes destination path %s", prefix, e.Destination.Path)
+	}
+	return strings.TrimPrefix(prefix, "/"), nil
+}
+
+// ObjectKey 某一个小时的导出文件, eg. logs/cluster/ns/audit/2022/01/08/15.ndjson.gz
+func (e *LogExport) ObjectKey(hour time.Time) (string, error) {
+	prefix, err := e.ObjectPrefix()
+	if err != nil {
+		return "", err
+	}
+	return prefix + hour.UTC().Format(logExportHourLayout) + LogExportObjectSuffix, nil
+}
+
+// ObjectExpired 根据文件名中的时间判断是否超过保留时间
+func (e *LogExport) ObjectExpired(key string, now time.Time) bool {
+	if e.Retention == "" {
+		return false
+	}
+	retention, err := prommodel.ParseDuration(e.Retention)
+	if err != nil {
+		return false
+	}
+	prefix, err := e.ObjectPrefix()
+	if err != nil || !strings.HasPrefix(key, prefix) {
+		return false
+	}
+	rel := strings.TrimSuffix(strings.TrimPrefix(key, prefix), LogExportObjectSuffix)
+	hour, err := time.Parse(logExportHourLayout, rel)
+	if err != nil {
+		return false
+	}
+	return hour.Add(time.Hour).Before(now.Add(-time.Duration(retention)))
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogExport(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2022, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	created := at(8, 10, 30)
+	e := &LogExport{
+		Cluster:     "c1",
+		Namespace:   "ns",
+		Name:        "audit",
+		Query:       `{container="app"} |= "audit"`,
+		Schedule:    "0 * * * *",
+		Retention:   "1d",
+		Destination: LogExportDestination{Type: LogExportDestinationPVC, Path: "/compliance/"},
+		CreatedAt:   &created,
+	}
+	if err := e.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if e.Destination.Path != "compliance" {
+		t.Errorf("Validate() path = %s, want compliance", e.Destination.Path)
+	}
+	if got, want := e.NamespacedQuery(), `{namespace="ns", container="app"} |= "audit"`; got != want {
+		t.Errorf("NamespacedQuery() = %s, want %s", got, want)
+	}
+
+	// 下一次执行时间为 11:00
+	if e.Due(at(8, 10, 59)) || !e.Due(at(8, 11, 0)) {
+		t.Errorf("Due() not match schedule")
+	}
+
+	// 11:03 时 10 点的日志还在等待写入完成
+	if hours := e.PendingHours(at(8, 11, 3)); len(hours) != 0 {
+		t.Errorf("PendingHours() = %v, want none", hours)
+	}
+	hours := e.PendingHours(at(8, 12, 10))
+	if len(hours) != 2 || !hours[0].Equal(at(8, 10, 0)) || !hours[1].Equal(at(8, 11, 0)) {
+		t.Errorf("PendingHours() = %v, want 10:00 and 11:00", hours)
+	}
+	// 从导出进度继续, 每次最多导出 24 小时
+	until := at(8, 11, 0)
+	e.ExportedUntil = &until
+	if hours := e.PendingHours(at(12, 0, 0)); len(hours) != 24 || !hours[0].Equal(until) {
+		t.Errorf("PendingHours() = %v, want 24 hours from %v", hours, until)
+	}
+
+	key, err := e.ObjectKey(at(8, 10, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "compliance/c1/ns/audit/2022/01/08/10.ndjson.gz"; key != want {
+		t.Errorf("ObjectKey() = %s, want %s", key, want)
+	}
+	if e.ObjectExpired(key, at(9, 10, 0)) || !e.ObjectExpired(key, at(9, 11, 1)) {
+		t.Errorf("ObjectExpired() not match retention")
+	}
+	if e.ObjectExpired("compliance/c1/ns/audit/other.txt", at(20, 0, 0)) {
+		t.Errorf("ObjectExpired() should ignore unknown files")
+	}
+
+	e.Destination.Path = "../etc"
+	if err := e.Validate(); err == nil {
+		t.Errorf("Validate() should reject path outside export dir")
+	}
+	e.Destination.Path = "compliance"
+	e.Name = "../../other"
+	if err := e.Validate(); err == nil {
+		t.Errorf("Validate() should reject name that is not a dns label")
+	}
+	if _, err := e.ObjectPrefix(); err == nil {
+		t.Errorf("ObjectPrefix() should reject prefix outside destination path")
+	}
+	e.Name, e.Namespace = "audit", ".."
+	if _, err := e.ObjectPrefix(); err == nil {
+		t.Errorf("ObjectPrefix() should reject prefix outside destination path")
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+	"kubegems.io/kubegems/pkg/utils/prometheus/promql"
+)
+
+const (
+	LogMetricTypeCount = "count" // 匹配的日志条数
+	LogMetricTypeRate  = "rate"  // 每秒匹配的日志条数
+	LogMetricTypeSum   = "sum"   // 提取的数值求和
+	LogMetricTypeAvg   = "avg"   // 提取的数值平均值
+	LogMetricTypeMax   = "max"   // 提取的数值最大值
+	LogMetricTypeMin   = "min"   // 提取的数值最小值
+
+	// LogMetricRecordPrefix 日志指标 recording rule 生成的指标名前缀
+	LogMetricRecordPrefix = "log_metric:"
+	logMetricMaxInterval  = 10 * time.Minute
+)
+
+var logMetricLabelReg = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LogMetric 日志指标, 由 LogQL 生成 loki recording rule, 结果 remote write 到集群的 prometheus,
+// 并作为监控模板加入指标目录
+type LogMetric struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Cluster     string `gorm:"type:varchar(50);uniqueIndex:uniq_log_metric" json:"cluster"`
+	Namespace   string `gorm:"type:varchar(50);uniqueIndex:uniq_log_metric" json:"namespace"`
+	Name        string `gorm:"type:varchar(50);uniqueIndex:uniq_log_metric" binding:"min=1,max=50" json:"name"`
+	Description string `json:"description"`
+	Unit        string `gorm:"type:varchar(50)" json:"unit"`
+
+	Type  string         `gorm:"type:varchar(20)" binding:"oneof=count rate sum avg max min" json:"type"`
+	Query LogMetricQuery `gorm:"type:json" json:"query"`
+
+	// 生成的指标目录模板
+	PromqlTplRuleID *uint          `json:"promqlTplRuleID"`
+	PromqlTplRule   *PromqlTplRule `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"promqlTplRule,omitempty"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// LogMetricQuery 日志指标的查询条件, eg. nginx 日志中按接口统计的 5xx 错误率:
+// {labelMatchers: [{name: container, value: nginx}], parser: json, labelFilter: "status >= 500", groupBy: [path]}
+type LogMetricQuery struct {
+	LabelMatchers []promql.LabelMatcher `json:"labelMatchers"` // 日志流标签筛选器
+	Match         string                `json:"match"`         // 正则匹配的字符串
+	Parser        string                `json:"parser"`        // 日志解析器, json, logfmt, regexp, pattern
+	ParserExpr    string                `json:"parserExpr"`    // regexp 和 pattern 解析器的表达式
+	LabelFilter   string                `json:"labelFilter"`   // 解析后的标签过滤, eg. status >= 500
+	Unwrap        string                `json:"unwrap"`        // 提取数值的标签, sum/avg/max/min 类型必填
+	GroupBy       []string              `json:"groupBy"`       // 聚合的标签
+	Interval      string                `json:"interval"`      // 统计的时间范围, 默认 1m
+}
+
+func (m LogMetricQuery) Value() (driver.Value, error) {
+	ba, err := json.Marshal(m)
+	return string(ba), err
+}
+
+func (m *LogMetricQuery) Scan(val interface{}) error {
+	return scanJSON(val, m)
+}
+
+func (m LogMetricQuery) GormDataType() string {
+	return "json"
+}
+
+// RecordName recording rule 生成的指标名
+func (m *LogMetric) RecordName() string {
+	return LogMetricRecordPrefix + strings.ReplaceAll(m.Name, "-", "_")
+}
+
+// TplLabels 生成的指标带有的标签
+func (m *LogMetric) TplLabels() gormdatatypes.JSONSlice {
+	return append(gormdatatypes.JSONSlice{"namespace"}, m.Query.GroupBy...)
+}
+
+// LogQL 校验查询条件并生成 recording rule 的 LogQL
+func (m *LogMetric) LogQL() (string, error) {
+	q := &m.Query
+	if q.Interval == "" {
+		q.Interval = "1m"
+	}
+	dur, err := prommodel.ParseDuration(q.Interval)
+	if err != nil {
+		return "", fmt.Errorf("interval %s not valid: %w", q.Interval, err)
+	}
+	if time.Duration(dur) > logMetricMaxInterval {
+		return "", fmt.Errorf("interval can't be longer than %s", logMetricMaxInterval)
+	}
+	if q.Match != "" {
+		if _, err := regexp.Compile(q.Match); err != nil {
+			return "", fmt.Errorf("match %s not valid: %w", q.Match, err)
+		}
+		if strings.Contains(q.Match, "`") {
+			return "", fmt.Errorf("match can't contain `")
+		}
+	}
+	if len(q.LabelMatchers) == 0 {
+		return "", fmt.Errorf("labelMatchers can't be null")
+	}
+	for _, l := range q.GroupBy {
+		if !logMetricLabelReg.MatchString(l) || l == "namespace" {
+			return "", fmt.Errorf("groupBy label %s not valid", l)
+		}
+	}
+
+	labelvalues := []string{}
+	for _, v := range q.LabelMatchers {
+		if v.Name == "namespace" {
+			continue
+		}
+		labelvalues = append(labelvalues, v.String())
+	}
+	sort.Strings(labelvalues)
+	labelvalues = append(labelvalues, fmt.Sprintf(`namespace="%s"`, m.Namespace))
+
+	pipeline := fmt.Sprintf("{%s}", strings.Join(labelvalues, ", "))
+	if q.Match != "" {
+		pipeline += fmt.Sprintf(" |~ `%s`", q.Match)
+	}
+	switch q.Parser {
+	case "":
+	case "json", "logfmt":
+		pipeline += " | " + q.Parser
+	case "regexp", "pattern":
+		if q.ParserExpr == "" {
+			return "", fmt.Errorf("parserExpr can't be null when using %s parser", q.Parser)
+		}
+		pipeline += fmt.Sprintf(" | %s %s", q.Parser, strconv.Quote(q.ParserExpr))
+	default:
+		return "", fmt.Errorf("parser %s not supported", q.Parser)
+	}
+	if q.LabelFilter != "" {
+		if strings.ContainsAny(q.LabelFilter, "|{}[]") {
+			return "", fmt.Errorf("labelFilter %s not valid", q.LabelFilter)
+		}
+		pipeline += " | " + q.LabelFilter
+	}
+
+	var rangeFunc, aggr string
+	switch m.Type {
+	case LogMetricTypeCount:
+		rangeFunc, aggr = "count_over_time", "sum"
+	case LogMetricTypeRate:
+		rangeFunc, aggr = "rate", "sum"
+	case LogMetricTypeSum, LogMetricTypeAvg, LogMetricTypeMax, LogMetricTypeMin:
+		if !logMetricLabelReg.MatchString(q.Unwrap) {
+			return "", fmt.Errorf("unwrap label %s not valid", q.Unwrap)
+		}
+		rangeFunc, aggr = m.Type+"_over_time", m.Type
+		// 丢弃数值转换失败的日志
+		pipeline += fmt.Sprintf(` | unwrap %s | __error__=""`, q.Unwrap)
+	default:
+		return "", fmt.Errorf("log metric type %s not supported", m.Type)
+	}
+
+	by := append([]string{"namespace"}, q.GroupBy...)
+	return fmt.Sprintf("%s by (%s) (%s(%s [%s]))", aggr, strings.Join(by, ", "), rangeFunc, pipeline, q.Interval), nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"kubegems.io/kubegems/pkg/utils/prometheus/promql"
+)
+
+func TestLogMetricLogQL(t *testing.T) {
+	nginx := []promql.LabelMatcher{{Type: promql.MatchEqual, Name: "container", Value: "nginx"}}
+	tests := []struct {
+		name    string
+		metric  *LogMetric
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "error rate by path",
+			metric: &LogMetric{
+				Namespace: "ns",
+				Name:      "nginx-errors",
+				Type:      LogMetricTypeRate,
+				Query: LogMetricQuery{
+					LabelMatchers: nginx,
+					Parser:        "json",
+					LabelFilter:   "status >= 500",
+					GroupBy:       []string{"path"},
+				},
+			},
+			want: "sum by (namespace, path) (rate({container=\"nginx\", namespace=\"ns\"} | json | status >= 500 [1m]))",
+		},
+		{
+			name: "count with match and regexp parser",
+			metric: &LogMetric{
+				Namespace: "ns",
+				Name:      "orders",
+				Type:      LogMetricTypeCount,
+				Query: LogMetricQuery{
+					LabelMatchers: append(nginx, promql.LabelMatcher{Type: promql.MatchEqual, Name: "namespace", Value: "other"}),
+					Match:         "order created",
+					Parser:        "regexp",
+					ParserExpr:    `user=(?P<user>\w+)`,
+					Interval:      "5m",
+				},
+			},
+			want: "sum by (namespace) (count_over_time({container=\"nginx\", namespace=\"ns\"} |~ `order created` | regexp \"user=(?P<user>\\\\w+)\" [5m]))",
+		},
+		{
+			name: "avg with unwrap",
+			metric: &LogMetric{
+				Namespace: "ns",
+				Name:      "latency",
+				Type:      LogMetricTypeAvg,
+				Query:     LogMetricQuery{LabelMatchers: nginx, Parser: "logfmt", Unwrap: "duration"},
+			},
+			want: "avg by (namespace) (avg_over_time({container=\"nginx\", namespace=\"ns\"} | logfmt | unwrap duration | __error__=\"\" [1m]))",
+		},
+		{
+			name: "unwrap required",
+			metric: &LogMetric{
+				Namespace: "ns",
+				Type:      LogMetricTypeSum,
+				Query:     LogMetricQuery{LabelMatchers: nginx},
+			},
+			wantErr: true,
+		},
+		{
+			name: "label matchers required",
+			metric: &LogMetric{
+				Namespace: "ns",
+				Type:      LogMetricTypeCount,
+			},
+			wantErr: true,
+		},
+		{
+			name: "interval too long",
+			metric: &LogMetric{
+				Namespace: "ns",
+				Type:      LogMetricTypeCount,
+				Query:     LogMetricQuery{LabelMatchers: nginx, Interval: "1h"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.metric.LogQL()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LogMetric.LogQL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LogMetric.LogQL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
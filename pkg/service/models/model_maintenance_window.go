@@ -0,0 +1,213 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/robfig/cron/v3"
+	"kubegems.io/kubegems/pkg/utils/prometheus/promql"
+)
+
+// MaintenanceWindow 维护窗口, worker 按周期自动创建和过期 alertmanager 静默规则
+type MaintenanceWindow struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Cluster     string `gorm:"type:varchar(50);uniqueIndex:uniq_maintenance_window" json:"cluster"`
+	Namespace   string `gorm:"type:varchar(50);uniqueIndex:uniq_maintenance_window" json:"namespace"` // 为空表示整个集群
+	Name        string `gorm:"type:varchar(50);uniqueIndex:uniq_maintenance_window" binding:"required,max=50" json:"name"`
+	Description string `json:"description"`
+
+	AlertRule     string                 `gorm:"type:varchar(50)" json:"alertRule"`                   // 告警规则名, 为空表示所有告警规则
+	LabelMatchers MaintenanceMatchers    `gorm:"type:json" json:"labelMatchers"`                      // 告警标签筛选
+	Cron          string                 `gorm:"type:varchar(100)" json:"cron"`                       // 每次维护开始的 cron 表达式, eg. 0 2 * * 6
+	Weekly        *MaintenanceRecurrence `gorm:"type:json" json:"weekly"`                             // 每周重复, 与 cron 二选一
+	Duration      string                 `gorm:"type:varchar(20)" binding:"required" json:"duration"` // 每次维护的时长, eg. 2h
+	Timezone      string                 `gorm:"type:varchar(50)" json:"timezone"`                    // 时区, 默认为服务所在时区
+
+	StartsAt *time.Time `json:"startsAt"` // 生效时间, 为空表示立即生效
+	EndsAt   *time.Time `json:"endsAt"`   // 失效时间, 为空表示一直有效
+	Enabled  bool       `gorm:"default:true" json:"enabled"`
+	Creator  string     `gorm:"type:varchar(50)" json:"creator"`
+	// 进行中或者下一次维护的开始时间
+	NextStartsAt *time.Time `gorm:"-" json:"nextStartsAt,omitempty"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// MaintenanceRecurrence 每周重复的维护时间
+type MaintenanceRecurrence struct {
+	Weekdays []int  `json:"weekdays"` // 0-6, 0 为周日
+	Time     string `json:"time"`     // 开始时间, eg. 02:00
+}
+
+func (m MaintenanceRecurrence) Value() (driver.Value, error) {
+	ba, err := json.Marshal(m)
+	return string(ba), err
+}
+
+func (m *MaintenanceRecurrence) Scan(val interface{}) error {
+	return scanJSON(val, m)
+}
+
+func (m MaintenanceRecurrence) GormDataType() string {
+	return "json"
+}
+
+type MaintenanceMatchers []promql.LabelMatcher
+
+func (m MaintenanceMatchers) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	ba, err := json.Marshal(m)
+	return string(ba), err
+}
+
+func (m *MaintenanceMatchers) Scan(val interface{}) error {
+	return scanJSON(val, m)
+}
+
+func (m MaintenanceMatchers) GormDataType() string {
+	return "json"
+}
+
+// CronExpr 维护开始时间的 cron 表达式, 每周重复时转换为 cron 表达式
+func (w *MaintenanceWindow) CronExpr() (string, error) {
+	expr := w.Cron
+	if w.Weekly != nil {
+		if len(w.Weekly.Weekdays) == 0 {
+			return "", errors.New("weekdays can't be empty")
+		}
+		t, err := time.Parse("15:04", w.Weekly.Time)
+		if err != nil {
+			return "", errors.Wrapf(err, "time %s not valid", w.Weekly.Time)
+		}
+		days := []string{}
+		weekdays := append([]int{}, w.Weekly.Weekdays...)
+		sort.Ints(weekdays)
+		for _, d := range weekdays {
+			if d < 0 || d > 6 {
+				return "", errors.Errorf("weekday %d not valid", d)
+			}
+			days = append(days, strconv.Itoa(d))
+		}
+		expr = fmt.Sprintf("%d %d * * %s", t.Minute(), t.Hour(), strings.Join(days, ","))
+	}
+	if expr == "" {
+		return "", errors.New("cron or weekly is required")
+	}
+	if w.Timezone != "" {
+		expr = fmt.Sprintf("CRON_TZ=%s %s", w.Timezone, expr)
+	}
+	return expr, nil
+}
+
+// Validate 校验重复规则和时长
+func (w *MaintenanceWindow) Validate() error {
+	if _, err := w.schedule(); err != nil {
+		return err
+	}
+	if _, err := w.duration(); err != nil {
+		return err
+	}
+	if w.StartsAt != nil && w.EndsAt != nil && !w.EndsAt.After(*w.StartsAt) {
+		return errors.New("endsAt must be after startsAt")
+	}
+	for _, m := range w.LabelMatchers {
+		if m.Name == "" {
+			return errors.New("label matcher name can't be empty")
+		}
+	}
+	return nil
+}
+
+func (w *MaintenanceWindow) schedule() (cron.Schedule, error) {
+	expr, err := w.CronExpr()
+	if err != nil {
+		return nil, err
+	}
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cron %s not valid", expr)
+	}
+	return sched, nil
+}
+
+func (w *MaintenanceWindow) duration() (time.Duration, error) {
+	d, err := prommodel.ParseDuration(w.Duration)
+	if err != nil {
+		return 0, errors.Wrapf(err, "duration %s not valid", w.Duration)
+	}
+	if d <= 0 {
+		return 0, errors.Errorf("duration %s must be positive", w.Duration)
+	}
+	return time.Duration(d), nil
+}
+
+// Occurrence 返回 now 时正在进行或者下一次的维护时间段
+func (w *MaintenanceWindow) Occurrence(now time.Time) (start, end time.Time, ok bool) {
+	sched, err := w.schedule()
+	if err != nil {
+		return
+	}
+	dur, err := w.duration()
+	if err != nil {
+		return
+	}
+	from := now.Add(-dur)
+	if w.StartsAt != nil && w.StartsAt.After(from) {
+		from = w.StartsAt.Add(-time.Second)
+	}
+	start = sched.Next(from)
+	if start.IsZero() {
+		return
+	}
+	end = start.Add(dur)
+	if w.EndsAt != nil {
+		if !start.Before(*w.EndsAt) {
+			return
+		}
+		if end.After(*w.EndsAt) {
+			end = *w.EndsAt
+		}
+	}
+	return start, end, true
+}
+
+// SilenceMatchers 静默规则的匹配条件
+func (w *MaintenanceWindow) SilenceMatchers(namespaceLabel, alertNameLabel string) []promql.LabelMatcher {
+	ret := []promql.LabelMatcher{}
+	if w.Namespace != "" {
+		ret = append(ret, promql.LabelMatcher{Type: promql.MatchEqual, Name: namespaceLabel, Value: w.Namespace})
+	}
+	if w.AlertRule != "" {
+		ret = append(ret, promql.LabelMatcher{Type: promql.MatchEqual, Name: alertNameLabel, Value: w.AlertRule})
+	}
+	ret = append(ret, w.LabelMatchers...)
+	if len(ret) == 0 {
+		// alertmanager 要求至少有一个不匹配空值的条件
+		ret = append(ret, promql.LabelMatcher{Type: promql.MatchRegexp, Name: prommodel.AlertNameLabel, Value: ".+"})
+	}
+	return ret
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowOccurrence(t *testing.T) {
+	// 2022-01-08 是周六
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2022, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	endsAt := at(15, 0, 0)
+	weekly := &MaintenanceWindow{
+		Weekly:   &MaintenanceRecurrence{Weekdays: []int{6}, Time: "02:00"},
+		Duration: "2h",
+		Timezone: "UTC",
+	}
+	tests := []struct {
+		name      string
+		window    *MaintenanceWindow
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+		wantOK    bool
+	}{
+		{
+			name:      "before window",
+			window:    weekly,
+			now:       at(7, 12, 0),
+			wantStart: at(8, 2, 0),
+			wantEnd:   at(8, 4, 0),
+			wantOK:    true,
+		},
+		{
+			name:      "in window",
+			window:    weekly,
+			now:       at(8, 3, 0),
+			wantStart: at(8, 2, 0),
+			wantEnd:   at(8, 4, 0),
+			wantOK:    true,
+		},
+		{
+			name:      "after window",
+			window:    weekly,
+			now:       at(8, 4, 0),
+			wantStart: at(15, 2, 0),
+			wantEnd:   at(15, 4, 0),
+			wantOK:    true,
+		},
+		{
+			name:      "cron",
+			window:    &MaintenanceWindow{Cron: "30 1 * * *", Duration: "1h", Timezone: "UTC"},
+			now:       at(8, 1, 45),
+			wantStart: at(8, 1, 30),
+			wantEnd:   at(8, 2, 30),
+			wantOK:    true,
+		},
+		{
+			name:   "expired",
+			window: &MaintenanceWindow{Cron: "0 2 * * 6", Duration: "2h", Timezone: "UTC", EndsAt: &endsAt},
+			now:    at(8, 5, 0),
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := tt.window.Occurrence(tt.now)
+			if ok != tt.wantOK {
+				t.Fatalf("Occurrence() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (!start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd)) {
+				t.Errorf("Occurrence() = %v - %v, want %v - %v", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  *MaintenanceWindow
+		wantErr bool
+	}{
+		{name: "no recurrence", window: &MaintenanceWindow{Duration: "1h"}, wantErr: true},
+		{name: "bad cron", window: &MaintenanceWindow{Cron: "* *", Duration: "1h"}, wantErr: true},
+		{name: "bad weekday", window: &MaintenanceWindow{Weekly: &MaintenanceRecurrence{Weekdays: []int{7}, Time: "02:00"}, Duration: "1h"}, wantErr: true},
+		{name: "bad duration", window: &MaintenanceWindow{Cron: "0 2 * * *", Duration: "1x"}, wantErr: true},
+		{name: "ok", window: &MaintenanceWindow{Cron: "0 2 * * *", Duration: "1h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.window.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
+)
+
+const (
+	OIDCApplicationTypeWeb       = "web"
+	OIDCApplicationTypeUserAgent = "user_agent"
+	OIDCApplicationTypeNative    = "native"
+
+	OIDCAccessTokenTypeBearer = "bearer"
+	OIDCAccessTokenTypeJWT    = "jwt"
+
+	OIDCGrantTypeCode         = "authorization_code"
+	OIDCGrantTypeRefreshToken = "refresh_token"
+)
+
+// OIDCClient 注册到 kubegems 内置 OIDC Provider 的客户端, eg. grafana、argocd、kiali
+type OIDCClient struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	ClientID string `gorm:"type:varchar(64);uniqueIndex" json:"clientID" binding:"required"`
+	// 客户端密钥, 仅存储hash; 明文只在创建和重置时返回一次
+	ClientSecret string `gorm:"type:varchar(255)" json:"-"`
+	Name         string `gorm:"type:varchar(50)" json:"name"`
+	// web, user_agent, native; 非web类型的客户端为公开客户端, 必须使用PKCE
+	ApplicationType        string                  `gorm:"type:varchar(20);default:web" json:"applicationType" binding:"omitempty,oneof=web user_agent native"`
+	RedirectURIs           gormdatatypes.JSONSlice `json:"redirectURIs" binding:"required,min=1"`
+	PostLogoutRedirectURIs gormdatatypes.JSONSlice `json:"postLogoutRedirectURIs"`
+	// authorization_code, refresh_token
+	GrantTypes gormdatatypes.JSONSlice `json:"grantTypes"`
+	// bearer, jwt
+	AccessTokenType string `gorm:"type:varchar(20);default:bearer" json:"accessTokenType" binding:"omitempty,oneof=bearer jwt"`
+	Enabled         bool   `gorm:"default:true" json:"enabled"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// OIDCAuthRequest 授权码流程中的授权请求
+type OIDCAuthRequest struct {
+	ID                  string `gorm:"type:varchar(64);primarykey"`
+	ClientID            string `gorm:"type:varchar(64)"`
+	RedirectURI         string `gorm:"type:varchar(512)"`
+	State               string `gorm:"type:varchar(512)"`
+	Nonce               string `gorm:"type:varchar(512)"`
+	ResponseType        string `gorm:"type:varchar(50)"`
+	ResponseMode        string `gorm:"type:varchar(50)"`
+	Scopes              gormdatatypes.JSONSlice
+	CodeChallenge       string `gorm:"type:varchar(255)"`
+	CodeChallengeMethod string `gorm:"type:varchar(10)"`
+	// 授权码, 登录完成后生成
+	Code string `gorm:"type:varchar(255);index"`
+	// 登录用户, 登录完成前为空
+	Subject  string `gorm:"type:varchar(50)"`
+	AuthTime *time.Time
+	AMR      gormdatatypes.JSONSlice
+	Done     bool
+
+	CreatedAt *time.Time
+}
+
+// OIDCToken 签发的 access token
+type OIDCToken struct {
+	ID             string `gorm:"type:varchar(64);primarykey"`
+	ClientID       string `gorm:"type:varchar(64);index"`
+	Subject        string `gorm:"type:varchar(50);index"`
+	RefreshTokenID string `gorm:"type:varchar(64);index"`
+	Audience       gormdatatypes.JSONSlice
+	Scopes         gormdatatypes.JSONSlice
+	Expiration     time.Time
+
+	CreatedAt *time.Time
+}
+
+// OIDCRefreshToken 签发的 refresh token, 每次刷新时 Token 会轮换, ID 保持不变
+type OIDCRefreshToken struct {
+	ID         string `gorm:"type:varchar(64);primarykey"`
+	Token      string `gorm:"type:varchar(64);uniqueIndex"`
+	ClientID   string `gorm:"type:varchar(64);index"`
+	Subject    string `gorm:"type:varchar(50);index"`
+	Audience   gormdatatypes.JSONSlice
+	Scopes     gormdatatypes.JSONSlice
+	AMR        gormdatatypes.JSONSlice
+	AuthTime   time.Time
+	Expiration time.Time
+
+	CreatedAt *time.Time
+}
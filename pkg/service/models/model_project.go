@@ -52,6 +52,19 @@ type Project struct {
 	TenantID uint `gorm:"uniqueIndex:uniq_idx_tenant_project_name"`
 }
 
+// ProjectResourceQuota 项目在集群中的资源限制, 不能超过租户在该集群的资源限制; 修改申请复用租户的资源申请表
+type ProjectResourceQuota struct {
+	ID      uint
+	Content datatypes.JSON
+
+	ProjectID                  uint                      `gorm:"uniqueIndex:uniq_project_cluster" binding:"required"`
+	ClusterID                  uint                      `gorm:"uniqueIndex:uniq_project_cluster" binding:"required"`
+	Project                    *Project                  `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;"`
+	Cluster                    *Cluster                  `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;"`
+	TenantResourceQuotaApply   *TenantResourceQuotaApply `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:SET NULL;"`
+	TenantResourceQuotaApplyID *uint
+}
+
 // ProjectUserRels
 type ProjectUserRels struct {
 	ID      uint     `gorm:"primarykey"`
@@ -0,0 +1,158 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/mail"
+	"time"
+
+	"github.com/pkg/errors"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/robfig/cron/v3"
+	"kubegems.io/kubegems/pkg/utils/slice"
+)
+
+const (
+	ReportSectionAlerts        = "alerts"        // 按级别统计的告警数
+	ReportSectionNoisyRules    = "noisyRules"    // 告警最多的规则
+	ReportSectionQuota         = "quota"         // 配额的分配和使用
+	ReportSectionIdleWorkloads = "idleWorkloads" // 最空闲的工作负载
+	ReportSectionCost          = "cost"          // 按分配的资源估算的费用
+
+	// 默认每周一 9 点发送最近一周的报告
+	ReportDefaultSchedule = "0 9 * * 1"
+	ReportDefaultPeriod   = "7d"
+)
+
+var ReportSections = []string{
+	ReportSectionAlerts,
+	ReportSectionNoisyRules,
+	ReportSectionQuota,
+	ReportSectionIdleWorkloads,
+	ReportSectionCost,
+}
+
+// Report 定时报告, worker 按计划统计租户或者项目的告警、配额、空闲负载和费用, 通过邮件渠道发送给收件人
+type Report struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Name        string `gorm:"type:varchar(50);uniqueIndex:uniq_report" binding:"required,max=50" json:"name"`
+	Description string `json:"description"`
+
+	TenantID  uint     `gorm:"uniqueIndex:uniq_report" json:"tenantID"`
+	Tenant    *Tenant  `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;" json:"tenant,omitempty"`
+	ProjectID *uint    `json:"projectID"` // 为空时为租户报告
+	Project   *Project `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:CASCADE;" json:"project,omitempty"`
+
+	Schedule   string           `gorm:"type:varchar(100)" json:"schedule"` // cron 表达式, 默认每周一 9 点
+	Period     string           `gorm:"type:varchar(20)" json:"period"`    // 统计的时间范围, 默认 7d
+	ChannelID  uint             `binding:"required" json:"channelID"`      // 发送使用的邮件告警渠道
+	Channel    *AlertChannel    `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT;" json:"channel,omitempty"`
+	Recipients ReportRecipients `gorm:"type:json" json:"recipients"`
+
+	Enabled   bool       `gorm:"default:true" json:"enabled"`
+	Creator   string     `gorm:"type:varchar(50)" json:"creator"`
+	LastRunAt *time.Time `json:"lastRunAt"`
+	LastError string     `json:"lastError"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// ReportRecipient 一组收件人和他们关心的内容, 内容为空时包含所有内容
+type ReportRecipient struct {
+	Emails   []string `json:"emails"`
+	Sections []string `json:"sections"`
+}
+
+type ReportRecipients []ReportRecipient
+
+func (m ReportRecipients) Value() (driver.Value, error) {
+	ba, err := json.Marshal(m)
+	return string(ba), err
+}
+
+func (m *ReportRecipients) Scan(val interface{}) error {
+	return scanJSON(val, m)
+}
+
+func (m ReportRecipients) GormDataType() string {
+	return "json"
+}
+
+func (r *Report) Validate() error {
+	if r.Schedule == "" {
+		r.Schedule = ReportDefaultSchedule
+	}
+	if r.Period == "" {
+		r.Period = ReportDefaultPeriod
+	}
+	if _, err := cron.ParseStandard(r.Schedule); err != nil {
+		return errors.Wrapf(err, "schedule %s not valid", r.Schedule)
+	}
+	if _, err := prommodel.ParseDuration(r.Period); err != nil {
+		return errors.Wrapf(err, "period %s not valid", r.Period)
+	}
+	if len(r.Recipients) == 0 {
+		return errors.New("recipients can't be empty")
+	}
+	for _, rcpt := range r.Recipients {
+		if len(rcpt.Emails) == 0 {
+			return errors.New("recipient emails can't be empty")
+		}
+		for _, email := range rcpt.Emails {
+			if _, err := mail.ParseAddress(email); err != nil {
+				return errors.Wrapf(err, "email %s not valid", email)
+			}
+		}
+		for _, section := range rcpt.Sections {
+			if !slice.ContainStr(ReportSections, section) {
+				return errors.Errorf("section %s not supported", section)
+			}
+		}
+	}
+	return nil
+}
+
+// Due 是否到了发送时间
+func (r *Report) Due(now time.Time) bool {
+	sched, err := cron.ParseStandard(r.Schedule)
+	if err != nil {
+		return false
+	}
+	last := r.LastRunAt
+	if last == nil {
+		last = r.CreatedAt
+	}
+	if last == nil {
+		return true
+	}
+	return !sched.Next(*last).After(now)
+}
+
+// TimeRange 报告统计的时间范围, 截止到 now
+func (r *Report) TimeRange(now time.Time) (start, end time.Time) {
+	period, err := prommodel.ParseDuration(r.Period)
+	if err != nil || period <= 0 {
+		period, _ = prommodel.ParseDuration(ReportDefaultPeriod)
+	}
+	return now.Add(-time.Duration(period)), now
+}
+
+// HasSection 收件人是否需要某部分内容
+func (r ReportRecipient) HasSection(section string) bool {
+	return len(r.Sections) == 0 || slice.ContainStr(r.Sections, section)
+}
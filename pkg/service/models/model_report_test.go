@@ -0,0 +1,77 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReport_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  Report
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			report: Report{Recipients: ReportRecipients{{Emails: []string{"ops@example.com"}}}},
+		},
+		{
+			name:    "no recipients",
+			report:  Report{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid email",
+			report:  Report{Recipients: ReportRecipients{{Emails: []string{"ops"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid section",
+			report:  Report{Recipients: ReportRecipients{{Emails: []string{"ops@example.com"}, Sections: []string{"unknown"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid schedule",
+			report:  Report{Schedule: "every monday", Recipients: ReportRecipients{{Emails: []string{"ops@example.com"}}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.report.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Report.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReport_Due(t *testing.T) {
+	// 2022-01-10 是周一
+	created := time.Date(2022, 1, 5, 0, 0, 0, 0, time.Local)
+	r := &Report{Schedule: ReportDefaultSchedule, CreatedAt: &created}
+	if r.Due(time.Date(2022, 1, 10, 8, 59, 0, 0, time.Local)) {
+		t.Errorf("Report.Due() before monday 9:00 should be false")
+	}
+	if !r.Due(time.Date(2022, 1, 10, 9, 0, 0, 0, time.Local)) {
+		t.Errorf("Report.Due() at monday 9:00 should be true")
+	}
+	last := time.Date(2022, 1, 10, 9, 0, 0, 0, time.Local)
+	r.LastRunAt = &last
+	if r.Due(time.Date(2022, 1, 10, 10, 0, 0, 0, time.Local)) {
+		t.Errorf("Report.Due() after sent should be false")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	SLITypeAvailability = "availability"
+	SLITypeLatency      = "latency"
+
+	// SLOWindowPlaceholder SLI 表达式中的时间窗口占位符, 生成 recording rules 时替换为具体的窗口
+	SLOWindowPlaceholder = "{{.window}}"
+)
+
+// SLO 服务等级目标, 由 SLI 生成 recording rules 和多窗口多燃烧率告警
+type SLO struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Cluster     string `gorm:"type:varchar(50);uniqueIndex:uniq_slo" json:"cluster"`
+	Namespace   string `gorm:"type:varchar(50);uniqueIndex:uniq_slo" json:"namespace"`
+	Name        string `gorm:"type:varchar(50);uniqueIndex:uniq_slo" binding:"min=1,max=50" json:"name"`
+	Service     string `gorm:"type:varchar(100)" binding:"required" json:"service"` // 服务名
+	Description string `json:"description"`
+
+	SLIType string       `gorm:"type:varchar(20)" binding:"oneof=availability latency" json:"sliType"`
+	SLI     SLOIndicator `gorm:"type:json" json:"sli"`
+	Target  float64      `binding:"gt=0,lt=100" json:"target"`               // 目标, 百分比, eg. 99.9
+	Window  string       `gorm:"type:varchar(10);default:30d" json:"window"` // 统计周期, eg. 7d, 28d, 30d
+
+	AlertRuleID *uint      `json:"alertRuleID"`
+	AlertRule   *AlertRule `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"alertRule,omitempty"`
+	// 告警接收器, 保存在生成的告警规则上
+	Receivers []*AlertReceiver `gorm:"-" json:"receivers,omitempty"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// SLOIndicator SLI 定义, 二选一:
+// 1. ErrorQuery/TotalQuery: 错误事件和总事件的 promql, 使用 {{.window}} 作为时间窗口,
+// eg. sum(rate(http_requests_total{namespace="ns",code=~"5.."}[{{.window}}]))
+// 2. PromqlGenerator: 选择 PromqlTplRule, 指标满足比较条件的时间占比作为错误率, 适用于延迟类的 SLI
+type SLOIndicator struct {
+	ErrorQuery string `json:"errorQuery,omitempty"`
+	TotalQuery string `json:"totalQuery,omitempty"`
+
+	PromqlGenerator *PromqlGenerator `json:"promqlGenerator,omitempty"`
+	CompareOp       string           `json:"compareOp,omitempty"`
+	CompareValue    string           `json:"compareValue,omitempty"`
+}
+
+func (m SLOIndicator) Value() (driver.Value, error) {
+	ba, err := json.Marshal(m)
+	return string(ba), err
+}
+
+func (m *SLOIndicator) Scan(val interface{}) error {
+	return scanJSON(val, m)
+}
+
+func (m SLOIndicator) GormDataType() string {
+	return "json"
+}
+
+// SLOGenerator 由 SLO 生成的告警规则, 记录生成 recording rules 和燃烧率告警所需的参数
+type SLOGenerator struct {
+	SLO        string  `json:"slo"`
+	ErrorRatio string  `json:"errorRatio"` // 错误率表达式, 包含 {{.window}} 占位符
+	Target     float64 `json:"target"`
+	Window     string  `json:"window"`
+}
+
+func (m SLOGenerator) Value() (driver.Value, error) {
+	ba, err := json.Marshal(m)
+	return string(ba), err
+}
+
+func (m *SLOGenerator) Scan(val interface{}) error {
+	return scanJSON(val, m)
+}
+
+func (m SLOGenerator) GormDataType() string {
+	return "json"
+}
+
+func scanJSON(val interface{}, dest interface{}) error {
+	if val == nil {
+		return nil
+	}
+	var ba []byte
+	switch v := val.(type) {
+	case []byte:
+		ba = v
+	case string:
+		ba = []byte(v)
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", val))
+	}
+	return json.Unmarshal(ba, dest)
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+)
+
+// 租户模板中的名称和命名空间可以使用的占位符
+const (
+	BlueprintPlaceholderTenant      = "{tenant}"
+	BlueprintPlaceholderProject     = "{project}"
+	BlueprintPlaceholderEnvironment = "{environment}"
+)
+
+// TenantBlueprint 租户模板, 一次性创建租户及其集群配额、项目、环境、网络策略、网关、镜像仓库、告警规则和监控面板
+type TenantBlueprint struct {
+	ID          uint                `gorm:"primarykey" json:"id"`
+	Name        string              `gorm:"type:varchar(50);uniqueIndex" binding:"required,max=50" json:"name"`
+	Description string              `json:"description"`
+	Spec        TenantBlueprintSpec `gorm:"type:json" json:"spec"`
+	Creator     string              `gorm:"type:varchar(50)" json:"creator"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+type TenantBlueprintSpec struct {
+	Clusters   []BlueprintCluster   `json:"clusters"`             // 租户可用的集群及配额
+	Projects   []BlueprintProject   `json:"projects"`             // 项目及环境骨架
+	Registries []BlueprintRegistry  `json:"registries,omitempty"` // 添加到每个项目的镜像仓库
+	AlertRules []BlueprintAlertRule `json:"alertRules,omitempty"` // 在匹配的环境中创建的告警规则
+	Dashboards []BlueprintDashboard `json:"dashboards,omitempty"` // 在匹配的环境中创建的监控面板
+}
+
+func (s TenantBlueprintSpec) Value() (driver.Value, error) {
+	ba, err := json.Marshal(s)
+	return string(ba), err
+}
+
+func (s *TenantBlueprintSpec) Scan(val interface{}) error {
+	return scanJSON(val, s)
+}
+
+func (s TenantBlueprintSpec) GormDataType() string {
+	return "json"
+}
+
+type BlueprintCluster struct {
+	Cluster       string                  `json:"cluster"`       // 集群名
+	ResourceQuota v1.ResourceList         `json:"resourceQuota"` // 租户在该集群的资源限制
+	NetworkPolicy *BlueprintNetworkPolicy `json:"networkPolicy,omitempty"`
+	Gateways      []BlueprintGateway      `json:"gateways,omitempty"`
+}
+
+type BlueprintNetworkPolicy struct {
+	TenantIsolated bool                        `json:"tenantIsolated"`
+	Rules          []v1beta1.NetworkPolicyRule `json:"rules,omitempty"`
+}
+
+type BlueprintGateway struct {
+	Name string                    `json:"name"` // 网关名, 集群内唯一, eg. {tenant}-gateway
+	Spec v1beta1.TenantGatewaySpec `json:"spec"`
+}
+
+type BlueprintProject struct {
+	Name            string                     `json:"name"`
+	Alias           string                     `json:"alias,omitempty"`
+	Remark          string                     `json:"remark,omitempty"`
+	NetworkIsolated bool                       `json:"networkIsolated,omitempty"`
+	ResourceQuotas  map[string]v1.ResourceList `json:"resourceQuotas,omitempty"` // 集群名 -> 项目在该集群的资源限制
+	Environments    []BlueprintEnvironment     `json:"environments,omitempty"`
+}
+
+type BlueprintEnvironment struct {
+	Name            string                 `json:"name"`                // 环境名, 全局唯一, eg. {tenant}-{project}-dev
+	Namespace       string                 `json:"namespace,omitempty"` // 为空时和环境名相同
+	Cluster         string                 `json:"cluster"`
+	MetaType        string                 `json:"metaType"`
+	Remark          string                 `json:"remark,omitempty"`
+	DeletePolicy    string                 `json:"deletePolicy,omitempty"`
+	ResourceQuota   v1.ResourceList        `json:"resourceQuota,omitempty"`
+	NetworkIsolated bool                   `json:"networkIsolated,omitempty"`
+	SleepSchedule   *v1beta1.SleepSchedule `json:"sleepSchedule,omitempty"`
+}
+
+type BlueprintRegistry struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	IsDefault bool   `json:"isDefault,omitempty"`
+}
+
+type BlueprintAlertRule struct {
+	MetaTypes []string  `json:"metaTypes,omitempty"` // 环境元类型, 为空表示所有环境
+	AlertRule AlertRule `json:"alertRule"`           // 不需要填写集群和命名空间
+}
+
+type BlueprintDashboard struct {
+	MetaTypes []string `json:"metaTypes,omitempty"` // 环境元类型, 为空表示所有环境
+	Name      string   `json:"name"`
+	Template  string   `json:"template"` // 监控面板模板名
+}
+
+// MatchMetaType 判断环境元类型是否匹配
+func MatchMetaType(metaTypes []string, metaType string) bool {
+	if len(metaTypes) == 0 {
+		return true
+	}
+	for _, v := range metaTypes {
+		if v == metaType {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderBlueprintName 替换名称中的占位符
+func RenderBlueprintName(name, tenant, project, environment string) string {
+	return strings.NewReplacer(
+		BlueprintPlaceholderTenant, tenant,
+		BlueprintPlaceholderProject, project,
+		BlueprintPlaceholderEnvironment, environment,
+	).Replace(name)
+}
@@ -15,8 +15,13 @@
 package models
 
 import (
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
+
+	"kubegems.io/kubegems/pkg/utils/gormdatatypes"
 )
 
 const (
@@ -40,11 +45,13 @@ type User struct {
 	// 最后登录时间
 	LastLoginAt *time.Time `sql:"DEFAULT:'current_timestamp'"`
 
-	Source       string    `gorm:"type:varchar(50)"`
-	SourceVendor string    `gorm:"type:varchar(50)"`
-	Tenants      []*Tenant `gorm:"many2many:tenant_user_rels;"`
-	SystemRole   *SystemRole
-	SystemRoleID uint
+	Source       string `gorm:"type:varchar(50)"`
+	SourceVendor string `gorm:"type:varchar(50)"`
+	// 用户在外部登录源中的唯一标识, 如 OIDC 的 sub, SAML 的 NameID, LDAP 的 DN
+	SourceSubject string    `gorm:"type:varchar(255);index" json:"-"`
+	Tenants       []*Tenant `gorm:"many2many:tenant_user_rels;"`
+	SystemRole    *SystemRole
+	SystemRoleID  uint
 
 	// 角色，不同关联对象下表示的角色不同, 用来做join查询的时候处理角色字段的(请勿删除)
 	Role string `sql:"-" json:",omitempty"`
@@ -52,11 +59,21 @@ type User struct {
 
 type UserToken struct {
 	ID        uint       `gorm:"primarykey" json:"id"`
+	Name      string     `gorm:"type:varchar(50)" json:"name"`
 	Token     string     `json:"token"`
 	GrantType string     `gorm:"type:varchar(50)" json:"grantType"`
 	Scope     string     `gorm:"type:varchar(50)" json:"scope"`
 	ExpireAt  *time.Time `json:"expireAt"`
 
+	// 可访问的租户、项目、环境, 为空时拥有用户的全部权限
+	Scopes UserTokenScopes `gorm:"type:json" json:"scopes"`
+	// 只读令牌只允许 GET/HEAD/OPTIONS 请求
+	ReadOnly bool `json:"readOnly"`
+	// 允许使用令牌的来源 IP 或 CIDR, 为空时不限制
+	AllowedIPs gormdatatypes.JSONSlice `json:"allowedIPs"`
+	LastUsedAt *time.Time              `json:"lastUsedAt"`
+	LastUsedIP string                  `gorm:"type:varchar(50)" json:"lastUsedIP"`
+
 	UserID    *uint      `json:"userID"`
 	User      *User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user,omitempty"`
 	CreatedAt *time.Time `json:"createdAt"`
@@ -64,6 +81,89 @@ type UserToken struct {
 	Expired bool `gorm:"-" json:"expired"`
 }
 
+// Restricted 令牌是否有额外限制, 受限的令牌不能用于签发新的令牌
+func (t *UserToken) Restricted() bool {
+	return len(t.Scopes) > 0 || t.ReadOnly || len(t.AllowedIPs) > 0
+}
+
+// UserTokenScope 令牌可访问的资源, 包含该资源下的所有子资源
+type UserTokenScope struct {
+	Kind string `json:"kind" binding:"oneof=tenant project environment"`
+	ID   uint   `json:"id" binding:"required"`
+}
+
+type UserTokenScopes []UserTokenScope
+
+// Contains 判断资源是否在令牌的范围内
+func (s UserTokenScopes) Contains(kind string, id uint) bool {
+	for _, scope := range s {
+		if scope.Kind == kind && scope.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *UserTokenScopes) Scan(value interface{}) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
+	}
+	result := UserTokenScopes{}
+	err := json.Unmarshal(bytes, &result)
+	*s = result
+	return err
+}
+
+func (s UserTokenScopes) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	bytes, err := json.Marshal(s)
+	return string(bytes), err
+}
+
+// UserTOTP 本地账号的两步验证(TOTP)绑定信息
+type UserTOTP struct {
+	ID     uint  `gorm:"primarykey" json:"id"`
+	UserID uint  `gorm:"uniqueIndex" json:"userID"`
+	User   *User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	// base32 编码的密钥
+	Secret string `gorm:"type:varchar(64)" json:"-"`
+	// 绑定后需要校验一次验证码才会开启
+	Enabled bool `json:"enabled"`
+	// 恢复码, 仅存储hash, 使用后删除
+	RecoveryCodes gormdatatypes.JSONSlice `json:"-"`
+	// 最后一次使用的时间步, 防止验证码在有效期内被重复使用
+	LastUsedStep int64 `json:"-"`
+	// 连续校验失败的次数, 达到上限后锁定到 LockedUntil
+	FailedAttempts int        `json:"-"`
+	LockedUntil    *time.Time `json:"-"`
+
+	EnabledAt *time.Time `json:"enabledAt"`
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// SecurityPolicy 系统安全策略, 全局只有一条记录
+type SecurityPolicy struct {
+	ID uint `gorm:"primarykey" json:"-"`
+	// 系统管理员必须开启两步验证
+	RequireTwoFactorForSysAdmin bool `json:"requireTwoFactorForSysAdmin"`
+	// 租户管理员必须开启两步验证
+	RequireTwoFactorForTenantAdmin bool `json:"requireTwoFactorForTenantAdmin"`
+
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
 type UserSel struct {
 	ID       uint
 	Username string
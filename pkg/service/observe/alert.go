@@ -361,7 +361,7 @@ func (base *BaseAlertResource) UpdateRoutes(alertrules AlertRuleList[AlertRule])
 		}
 	}
 	base.AMConfig.Spec.Route.Receiver = prometheus.NullReceiverName
-	base.AMConfig.Spec.Route.GroupBy = []string{prometheus.AlertNamespaceLabel, prometheus.AlertNameLabel}
+	base.AMConfig.Spec.Route.GroupBy = []string{prometheus.AlertNamespaceLabel, prometheus.AlertNameLabel, models.IncidentCorrelationKeyLabel}
 	base.AMConfig.Spec.Route.GroupInterval = "30s" // ref. https://zhuanlan.zhihu.com/p/63270049. group_interval设短点好
 	base.AMConfig.Spec.Route.GroupWait = "30s"     // 使用默认值
 	base.AMConfig.Spec.Route.Matchers = nil
@@ -225,7 +225,7 @@ func (c *ObserveClient) ListLoggingAlertRules(ctx context.Context, namespace str
 	groupNamespaceMap := map[string]*rulefmt.RuleGroups{}
 	for k, v := range cm.Data {
 		// skip recording rule
-		if k == LokiRecordingRulesKey {
+		if k == LokiRecordingRulesKey || k == LokiLogMetricsKey {
 			continue
 		}
 		if namespace != v1.NamespaceAll && namespace != k {
@@ -580,6 +580,104 @@ func (c *ObserveClient) DeleteSilenceIfExist(ctx context.Context, info models.Al
 	}
 }
 
+// ListSilencesByCommentPrefix 列出未过期(active 和 pending)的静默规则
+func (c *ObserveClient) ListSilencesByCommentPrefix(ctx context.Context, commentPrefix string) ([]alertmanagertypes.Silence, error) {
+	allSilences := []alertmanagertypes.Silence{}
+	req := extend.Request{
+		Path:    "/v1/service-proxy/api/v2/silences",
+		Headers: extend.HeadersFrom(alertProxyHeader),
+		Into:    &allSilences,
+	}
+	if err := c.Extend().DoRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("list silences, %w", err)
+	}
+	ret := []alertmanagertypes.Silence{}
+	for _, v := range allSilences {
+		if v.Status.State != alertmanagertypes.SilenceStateExpired && strings.HasPrefix(v.Comment, commentPrefix) {
+			ret = append(ret, v)
+		}
+	}
+	return ret, nil
+}
+
+// use for maintenance window
+func (c *ObserveClient) CreateSilence(ctx context.Context, silence alertmanagertypes.Silence) error {
+	agentreq := extend.Request{
+		Method:  http.MethodPost,
+		Path:    "/v1/service-proxy/api/v2/silences",
+		Body:    silence,
+		Headers: extend.HeadersFrom(alertProxyHeader),
+	}
+	if err := c.Extend().DoRequest(ctx, agentreq); err != nil {
+		return fmt.Errorf("create silence:%w", err)
+	}
+	return nil
+}
+
+// ExpireSilence 过期静默规则, alertmanager 中删除即为过期
+func (c *ObserveClient) ExpireSilence(ctx context.Context, id string) error {
+	agentreq := extend.Request{
+		Method:  http.MethodDelete,
+		Path:    fmt.Sprintf("/v1/service-proxy/api/v2/silence/%s", id),
+		Headers: extend.HeadersFrom(alertProxyHeader),
+	}
+	return c.Extend().DoRequest(ctx, agentreq)
+}
+
+type SilencedAlert struct {
+	Annotations map[string]string `json:"annotations"`
+	Labels      map[string]string `json:"labels"`
+	StartsAt    *time.Time        `json:"startsAt"`
+	EndsAt      *time.Time        `json:"endsAt"`
+	Fingerprint string            `json:"fingerprint"`
+	Status      struct {
+		SilencedBy []string `json:"silencedBy"`
+		State      string   `json:"state"`
+	} `json:"status"`
+}
+
+// ListSilencedAlerts 列出被静默的告警
+func (c *ObserveClient) ListSilencedAlerts(ctx context.Context) ([]SilencedAlert, error) {
+	ret := []SilencedAlert{}
+	req := extend.Request{
+		Path: "/v1/service-proxy/api/v2/alerts",
+		Query: extend.QueryFrom(map[string]string{
+			"active":      "false",
+			"silenced":    "true",
+			"inhibited":   "false",
+			"unprocessed": "false",
+		}),
+		Headers: extend.HeadersFrom(alertProxyHeader),
+		Into:    &ret,
+	}
+	if err := c.Extend().DoRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("list silenced alerts, %w", err)
+	}
+	return ret, nil
+}
+
+// PostableAlert 直接发送到 alertmanager 的告警, 超过 EndsAt 没有再次发送时自动恢复
+type PostableAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt,omitempty"`
+	EndsAt      time.Time         `json:"endsAt,omitempty"`
+}
+
+// PostAlerts 发送告警到 alertmanager, 用于 worker 检测产生的告警
+func (c *ObserveClient) PostAlerts(ctx context.Context, alerts []PostableAlert) error {
+	agentreq := extend.Request{
+		Method:  http.MethodPost,
+		Path:    "/v1/service-proxy/api/v2/alerts",
+		Body:    alerts,
+		Headers: extend.HeadersFrom(alertProxyHeader),
+	}
+	if err := c.Extend().DoRequest(ctx, agentreq); err != nil {
+		return fmt.Errorf("post alerts:%w", err)
+	}
+	return nil
+}
+
 func (c ObserveClient) SearchTrace(
 	ctx context.Context,
 	service string,
@@ -36,6 +36,8 @@ import (
 const (
 	LoggingAlertRuleCMName = "kubegems-loki-rules"
 	LokiRecordingRulesKey  = "kubegems-loki-recording-rules.yaml"
+	// 日志指标的 recording rules
+	LokiLogMetricsKey = "kubegems-loki-log-metrics.yaml"
 )
 
 type RawLoggingAlertRule struct {
@@ -0,0 +1,186 @@
+// Copyright 2023 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubegems.io/kubegems/pkg/utils/loki"
+)
+
+const (
+	// 日志的时间可能与 span 的时间有偏差, 查询时前后各扩展一段时间
+	traceLogTimePadding = time.Minute
+	traceLogLimit       = 200
+)
+
+// process tags 中的 pod 和命名空间, 优先使用 otel 的 resource attributes, jaeger 客户端使用 hostname 作为 pod 名
+var (
+	tracePodTagKeys       = []string{"k8s.pod.name", "hostname"}
+	traceNamespaceTagKeys = []string{"k8s.namespace.name"}
+)
+
+// TraceLogTarget 产生 span 的服务所在的 pod, 用于查询关联的日志
+type TraceLogTarget struct {
+	Service   string   `json:"service"`
+	Namespace string   `json:"namespace,omitempty"`
+	Pod       string   `json:"pod"`
+	SpanIDs   []SpanID `json:"spanIDs"`
+}
+
+type TraceLogEntry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Labels    map[string]string `json:"labels"`
+	Line      string            `json:"line"`
+}
+
+// TraceLogs 一个服务的 pod 中包含 trace id 的日志
+type TraceLogs struct {
+	TraceLogTarget
+	Entries []TraceLogEntry `json:"entries"`
+	Error   string          `json:"error,omitempty"`
+}
+
+func processTag(p *Process, keys []string) string {
+	for _, key := range keys {
+		for _, tag := range p.Tags {
+			if tag.Key != key {
+				continue
+			}
+			if v, ok := tag.Value.(string); ok && v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// LogTargets 按服务和 pod 分组 span, 找不到 pod 的 span 无法关联日志
+func (t *Trace) LogTargets() []TraceLogTarget {
+	targets := map[string]*TraceLogTarget{}
+	for _, span := range t.Spans {
+		process, ok := t.Processes[span.ProcessID]
+		if !ok {
+			if span.Process == nil {
+				continue
+			}
+			process = *span.Process
+		}
+		pod := processTag(&process, tracePodTagKeys)
+		if pod == "" {
+			continue
+		}
+		namespace := processTag(&process, traceNamespaceTagKeys)
+		key := strings.Join([]string{process.ServiceName, namespace, pod}, "/")
+		target, ok := targets[key]
+		if !ok {
+			target = &TraceLogTarget{Service: process.ServiceName, Namespace: namespace, Pod: pod}
+			targets[key] = target
+		}
+		target.SpanIDs = append(target.SpanIDs, span.SpanID)
+	}
+	ret := make([]TraceLogTarget, 0, len(targets))
+	for _, target := range targets {
+		ret = append(ret, *target)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Service != ret[j].Service {
+			return ret[i].Service < ret[j].Service
+		}
+		return ret[i].Pod < ret[j].Pod
+	})
+	return ret
+}
+
+// TimeRange trace 中最早开始和最晚结束的 span 的时间
+func (t *Trace) TimeRange() (start, end time.Time) {
+	var minStart, maxEnd uint64
+	for i, span := range t.Spans {
+		if i == 0 || span.StartTime < minStart {
+			minStart = span.StartTime
+		}
+		if spanEnd := span.StartTime + span.Duration; spanEnd > maxEnd {
+			maxEnd = spanEnd
+		}
+	}
+	return time.UnixMicro(int64(minStart)), time.UnixMicro(int64(maxEnd))
+}
+
+// LogQuery 查询 pod 中包含 trace id 的日志
+func (target *TraceLogTarget) LogQuery(traceID TraceID) string {
+	// 标签值来自上报的 span, 需要转义
+	selector := fmt.Sprintf(`pod=%q`, target.Pod)
+	if target.Namespace != "" {
+		selector = fmt.Sprintf(`namespace=%q, %s`, target.Namespace, selector)
+	}
+	// jaeger 返回的 64 位 trace id 不包含前导 0, 使用包含匹配同时兼容 128 位的格式
+	return fmt.Sprintf("{%s} |= %q", selector, strings.TrimLeft(string(traceID), "0"))
+}
+
+// GetTraceLogs 从 loki 查询 trace 中每个服务的 pod 中包含 trace id 的日志, 单个服务查询失败不影响其他服务
+// 只查询 allowed 返回 true 的命名空间
+func (c ObserveClient) GetTraceLogs(ctx context.Context, trace *Trace, allowed func(namespace string) bool) []TraceLogs {
+	start, end := trace.TimeRange()
+	start, end = start.Add(-traceLogTimePadding), end.Add(traceLogTimePadding)
+	ret := []TraceLogs{}
+	for _, target := range trace.LogTargets() {
+		if !allowed(target.Namespace) {
+			continue
+		}
+		logs := TraceLogs{TraceLogTarget: target, Entries: []TraceLogEntry{}}
+		data, err := c.Extend().LokiQueryRangeWithParam(ctx, loki.QueryRangeParam{
+			Query:     target.LogQuery(trace.TraceID),
+			Start:     strconv.FormatInt(start.UnixNano(), 10),
+			End:       strconv.FormatInt(end.UnixNano(), 10),
+			Direction: "forward",
+			Limit:     strconv.Itoa(traceLogLimit),
+		})
+		if err != nil {
+			logs.Error = err.Error()
+			ret = append(ret, logs)
+			continue
+		}
+		for _, result := range data.Result {
+			m, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var stream loki.Stream
+			stream = stream.ToStruct(m)
+			for _, value := range stream.Entries {
+				if len(value) < 2 {
+					continue
+				}
+				ts, err := strconv.ParseInt(value[0], 10, 64)
+				if err != nil {
+					continue
+				}
+				logs.Entries = append(logs.Entries, TraceLogEntry{
+					Timestamp: time.Unix(0, ts),
+					Labels:    stream.Labels,
+					Line:      value[1],
+				})
+			}
+		}
+		sort.SliceStable(logs.Entries, func(i, j int) bool { return logs.Entries[i].Timestamp.Before(logs.Entries[j].Timestamp) })
+		ret = append(ret, logs)
+	}
+	return ret
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observe
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrace_LogTargets(t *testing.T) {
+	trace := &Trace{
+		TraceID: "00f067aa0ba902b7",
+		Spans: []Span{
+			{SpanID: "s1", ProcessID: "p1", StartTime: 1000000, Duration: 500000},
+			{SpanID: "s2", ProcessID: "p2", StartTime: 1100000, Duration: 100000},
+			{SpanID: "s3", ProcessID: "p1", StartTime: 1200000, Duration: 600000},
+			{SpanID: "s4", ProcessID: "p3", StartTime: 1300000, Duration: 100000},
+		},
+		Processes: map[ProcessID]Process{
+			"p1": {ServiceName: "gateway", Tags: []KeyValue{
+				{Key: "k8s.pod.name", Value: "gateway-7d9f-abcde"},
+				{Key: "k8s.namespace.name", Value: "shop"},
+			}},
+			"p2": {ServiceName: "cart", Tags: []KeyValue{{Key: "hostname", Value: "cart-5c8b-xyz"}}},
+			"p3": {ServiceName: "external", Tags: []KeyValue{{Key: "ip", Value: "10.0.0.1"}}},
+		},
+	}
+	want := []TraceLogTarget{
+		{Service: "cart", Pod: "cart-5c8b-xyz", SpanIDs: []SpanID{"s2"}},
+		{Service: "gateway", Namespace: "shop", Pod: "gateway-7d9f-abcde", SpanIDs: []SpanID{"s1", "s3"}},
+	}
+	targets := trace.LogTargets()
+	if !reflect.DeepEqual(targets, want) {
+		t.Fatalf("LogTargets() = %+v, want %+v", targets, want)
+	}
+	if got, want := targets[1].LogQuery(trace.TraceID), `{namespace="shop", pod="gateway-7d9f-abcde"} |= "f067aa0ba902b7"`; got != want {
+		t.Errorf("LogQuery() = %s, want %s", got, want)
+	}
+	injected := TraceLogTarget{Namespace: `shop"} or {namespace=~".+`, Pod: "gateway"}
+	if got, want := injected.LogQuery(trace.TraceID), `{namespace="shop\"} or {namespace=~\".+", pod="gateway"} |= "f067aa0ba902b7"`; got != want {
+		t.Errorf("LogQuery() = %s, want %s", got, want)
+	}
+	start, end := trace.TimeRange()
+	if !start.Equal(time.UnixMicro(1000000)) || !end.Equal(time.UnixMicro(1800000)) {
+		t.Errorf("TimeRange() = %v, %v", start, end)
+	}
+}
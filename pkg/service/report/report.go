@@ -0,0 +1,444 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prommodel "github.com/prometheus/common/model"
+	"gorm.io/gorm"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"kubegems.io/kubegems/pkg/apis/gems/v1beta1"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/prometheus/channels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	noisyRulesLimit    = 10
+	idleWorkloadsLimit = 10
+
+	// 工作负载最近一段时间 p95 的 cpu 使用率, 越低越空闲
+	idleWorkloadCPUPercent = `bottomk(%[3]d, max by (namespace, workload) (quantile_over_time(0.95, gems_container_cpu_usage_percent{namespace=~"%[1]s", owner_kind=~"Deployment|StatefulSet|DaemonSet", container!~"istio-proxy|"}[%[2]s:5m])))`
+	workloadMemoryPercent  = `max by (namespace, workload) (quantile_over_time(0.95, gems_container_memory_usage_percent{namespace=~"%[1]s", owner_kind=~"Deployment|StatefulSet|DaemonSet", container!~"istio-proxy|"}[%[2]s:5m]))`
+	workloadCPULimitCores  = `sum by (namespace, workload) (gems_container_cpu_limit_cores{namespace=~"%[1]s", owner_kind=~"Deployment|StatefulSet|DaemonSet", container!~"istio-proxy|"})`
+)
+
+// 报告中统计的配额资源
+var quotaResources = []v1.ResourceName{v1.ResourceLimitsCPU, v1.ResourceLimitsMemory, v1.ResourceRequestsStorage}
+
+type Reporter struct {
+	DB     *gorm.DB
+	Agents *agents.ClientSet
+}
+
+type SeverityCount struct {
+	Severity string `json:"severity"`
+	Firing   int    `json:"firing"`
+	Resolved int    `json:"resolved"`
+}
+
+type NoisyRule struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Count     int    `json:"count"`
+}
+
+// QuotaUsage 租户报告为每个集群的租户配额, 项目报告为每个环境的配额
+type QuotaUsage struct {
+	Cluster     string  `json:"cluster"`
+	Environment string  `json:"environment,omitempty"`
+	Resource    string  `json:"resource"`
+	Hard        string  `json:"hard"`
+	Allocated   string  `json:"allocated,omitempty"`
+	Used        string  `json:"used"`
+	UsedPercent float64 `json:"usedPercent"`
+}
+
+type IdleWorkload struct {
+	Cluster       string  `json:"cluster"`
+	Namespace     string  `json:"namespace"`
+	Workload      string  `json:"workload"`
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryPercent float64 `json:"memoryPercent"`
+	CPULimitCores float64 `json:"cpuLimitCores"`
+}
+
+// CostItem 一种资源的费用, 用量为请求量, 单位为核时、GB 时和卡时
+type CostItem struct {
+	Resource string  `json:"resource"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+	Amount   float64 `json:"amount"`
+}
+
+// Cost 统计时间范围内费用统计任务按天汇总的费用, 不同币种分别统计
+type Cost struct {
+	Currency string     `json:"currency"`
+	Days     int        `json:"days"`
+	Items    []CostItem `json:"items"`
+	Total    float64    `json:"total"`
+}
+
+type Data struct {
+	Title         string          `json:"title"`
+	Start         time.Time       `json:"start"`
+	End           time.Time       `json:"end"`
+	Alerts        []SeverityCount `json:"alerts"`
+	NoisyRules    []NoisyRule     `json:"noisyRules"`
+	Quotas        []QuotaUsage    `json:"quotas"`
+	IdleWorkloads []IdleWorkload  `json:"idleWorkloads"`
+	Costs         []Cost          `json:"costs,omitempty"`
+	Errors        []string        `json:"errors,omitempty"` // 单个集群或者部分内容统计失败不影响其他内容
+
+	report       *models.Report
+	environments []*models.Environment
+}
+
+func (d *Data) addError(err error) {
+	d.Errors = append(d.Errors, err.Error())
+}
+
+// Build 统计报告的所有内容
+func (r *Reporter) Build(ctx context.Context, report *models.Report, now time.Time) (*Data, error) {
+	tenant := &models.Tenant{}
+	if err := r.DB.WithContext(ctx).First(tenant, "id = ?", report.TenantID).Error; err != nil {
+		return nil, err
+	}
+	start, end := report.TimeRange(now)
+	data := &Data{Title: tenant.TenantName, Start: start, End: end, report: report}
+
+	projectName := ""
+	envQuery := r.DB.WithContext(ctx).Preload("Cluster")
+	if report.ProjectID != nil {
+		project := &models.Project{}
+		if err := r.DB.WithContext(ctx).First(project, "id = ? and tenant_id = ?", *report.ProjectID, report.TenantID).Error; err != nil {
+			return nil, err
+		}
+		projectName = project.ProjectName
+		data.Title = tenant.TenantName + "/" + project.ProjectName
+		envQuery = envQuery.Where("project_id = ?", project.ID)
+	} else {
+		envQuery = envQuery.Where("project_id in (?)", r.DB.Model(&models.Project{}).Select("id").Where("tenant_id = ?", tenant.ID))
+	}
+	alertQuery := func() *gorm.DB {
+		query := r.DB.WithContext(ctx).Table("alert_messages").
+			Joins("join alert_infos on alert_messages.fingerprint = alert_infos.fingerprint").
+			Where("created_at >= ?", start).
+			Where("created_at < ?", end).
+			Where("alert_infos.tenant_name = ?", tenant.TenantName)
+		if projectName != "" {
+			query = query.Where("alert_infos.project_name = ?", projectName)
+		}
+		return query
+	}
+	if err := envQuery.Find(&data.environments).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.countAlerts(alertQuery(), data); err != nil {
+		return nil, err
+	}
+	if err := r.noisyRules(alertQuery(), data); err != nil {
+		return nil, err
+	}
+	if err := r.quotaUsages(ctx, tenant, data); err != nil {
+		return nil, err
+	}
+	r.idleWorkloads(ctx, data)
+	if err := r.costs(ctx, tenant.TenantName, projectName, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (r *Reporter) countAlerts(query *gorm.DB, data *Data) error {
+	type result struct {
+		Severity string
+		Status   string
+		Count    int
+	}
+	results := []result{}
+	if err := query.Select(`alert_infos.labels ->> '$.severity' as severity, alert_messages.status as status, count(alert_messages.id) as count`).
+		Group(`alert_infos.labels ->> '$.severity'`).Group("alert_messages.status").
+		Scan(&results).Error; err != nil {
+		return err
+	}
+	counts := map[string]*SeverityCount{}
+	for _, v := range results {
+		c, ok := counts[v.Severity]
+		if !ok {
+			c = &SeverityCount{Severity: v.Severity}
+			counts[v.Severity] = c
+		}
+		if v.Status == "firing" {
+			c.Firing += v.Count
+		} else {
+			c.Resolved += v.Count
+		}
+	}
+	data.Alerts = []SeverityCount{}
+	for _, c := range counts {
+		data.Alerts = append(data.Alerts, *c)
+	}
+	sort.Slice(data.Alerts, func(i, j int) bool { return data.Alerts[i].Severity < data.Alerts[j].Severity })
+	return nil
+}
+
+func (r *Reporter) noisyRules(query *gorm.DB, data *Data) error {
+	data.NoisyRules = []NoisyRule{}
+	return query.Select(`alert_infos.cluster_name as cluster, alert_infos.namespace as namespace, alert_infos.labels ->> '$.gems_alertname' as name, count(alert_messages.id) as count`).
+		Where("alert_messages.status = ?", "firing").
+		Group("alert_infos.cluster_name").Group("alert_infos.namespace").Group(`alert_infos.labels ->> '$.gems_alertname'`).
+		Order("count desc").Limit(noisyRulesLimit).
+		Scan(&data.NoisyRules).Error
+}
+
+func (r *Reporter) quotaUsages(ctx context.Context, tenant *models.Tenant, data *Data) error {
+	data.Quotas = []QuotaUsage{}
+	if data.report.ProjectID != nil {
+		for _, env := range data.environments {
+			if env.Cluster == nil {
+				continue
+			}
+			cli, err := r.Agents.ClientOf(ctx, env.Cluster.ClusterName)
+			if err != nil {
+				data.addError(errors.Wrapf(err, "environment %s", env.EnvironmentName))
+				continue
+			}
+			rq := &v1.ResourceQuota{}
+			if err := cli.Get(ctx, client.ObjectKey{Namespace: env.Namespace, Name: "default"}, rq); err != nil {
+				data.addError(errors.Wrapf(err, "environment %s", env.EnvironmentName))
+				continue
+			}
+			for _, name := range quotaResources {
+				hard, ok := rq.Status.Hard[name]
+				if !ok {
+					continue
+				}
+				data.Quotas = append(data.Quotas, newQuotaUsage(env.Cluster.ClusterName, env.EnvironmentName, name, hard, nil, rq.Status.Used[name]))
+			}
+		}
+		return nil
+	}
+
+	trqs := []*models.TenantResourceQuota{}
+	if err := r.DB.WithContext(ctx).Preload("Cluster").Find(&trqs, "tenant_id = ?", tenant.ID).Error; err != nil {
+		return err
+	}
+	for _, trq := range trqs {
+		if trq.Cluster == nil {
+			continue
+		}
+		cli, err := r.Agents.ClientOf(ctx, trq.Cluster.ClusterName)
+		if err != nil {
+			data.addError(errors.Wrapf(err, "cluster %s", trq.Cluster.ClusterName))
+			continue
+		}
+		tquota := &v1beta1.TenantResourceQuota{}
+		if err := cli.Get(ctx, client.ObjectKey{Name: tenant.TenantName}, tquota); err != nil {
+			data.addError(errors.Wrapf(err, "cluster %s", trq.Cluster.ClusterName))
+			continue
+		}
+		for _, name := range quotaResources {
+			hard, ok := tquota.Status.Hard[name]
+			if !ok {
+				continue
+			}
+			allocated := tquota.Status.Allocated[name]
+			data.Quotas = append(data.Quotas, newQuotaUsage(trq.Cluster.ClusterName, "", name, hard, &allocated, tquota.Status.Used[name]))
+		}
+	}
+	return nil
+}
+
+func newQuotaUsage(cluster, env string, name v1.ResourceName, hard resource.Quantity, allocated *resource.Quantity, used resource.Quantity) QuotaUsage {
+	q := QuotaUsage{
+		Cluster:     cluster,
+		Environment: env,
+		Resource:    string(name),
+		Hard:        hard.String(),
+		Used:        used.String(),
+	}
+	if allocated != nil {
+		q.Allocated = allocated.String()
+	}
+	if hardValue := hard.AsApproximateFloat64(); hardValue > 0 {
+		q.UsedPercent = math.Round(used.AsApproximateFloat64()/hardValue*1000) / 10
+	}
+	return q
+}
+
+// idleWorkloads 从每个集群中查询 cpu 使用率最低的工作负载, 合并后取最低的几个
+func (r *Reporter) idleWorkloads(ctx context.Context, data *Data) {
+	data.IdleWorkloads = []IdleWorkload{}
+	namespaces := map[string][]string{}
+	for _, env := range data.environments {
+		if env.Cluster != nil {
+			namespaces[env.Cluster.ClusterName] = append(namespaces[env.Cluster.ClusterName], env.Namespace)
+		}
+	}
+	for cluster, nss := range namespaces {
+		workloads, err := r.clusterIdleWorkloads(ctx, cluster, nss, data.report.Period)
+		if err != nil {
+			data.addError(errors.Wrapf(err, "cluster %s", cluster))
+			continue
+		}
+		data.IdleWorkloads = append(data.IdleWorkloads, workloads...)
+	}
+	sort.SliceStable(data.IdleWorkloads, func(i, j int) bool {
+		return data.IdleWorkloads[i].CPUPercent < data.IdleWorkloads[j].CPUPercent
+	})
+	if len(data.IdleWorkloads) > idleWorkloadsLimit {
+		data.IdleWorkloads = data.IdleWorkloads[:idleWorkloadsLimit]
+	}
+}
+
+func (r *Reporter) clusterIdleWorkloads(ctx context.Context, cluster string, namespaces []string, period string) ([]IdleWorkload, error) {
+	cli, err := r.Agents.ClientOf(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	nsRegex := strings.Join(namespaces, "|")
+	cpuPercent, err := cli.Extend().PrometheusVector(ctx, fmt.Sprintf(idleWorkloadCPUPercent, nsRegex, period, idleWorkloadsLimit))
+	if err != nil {
+		return nil, err
+	}
+	memoryPercent, err := cli.Extend().PrometheusVector(ctx, fmt.Sprintf(workloadMemoryPercent, nsRegex, period))
+	if err != nil {
+		return nil, err
+	}
+	cpuLimit, err := cli.Extend().PrometheusVector(ctx, fmt.Sprintf(workloadCPULimitCores, nsRegex))
+	if err != nil {
+		return nil, err
+	}
+	key := func(m prommodel.Metric) string {
+		return string(m["namespace"]) + "/" + string(m["workload"])
+	}
+	memoryMap, limitMap := map[string]float64{}, map[string]float64{}
+	for _, sample := range memoryPercent {
+		memoryMap[key(sample.Metric)] = float64(sample.Value)
+	}
+	for _, sample := range cpuLimit {
+		limitMap[key(sample.Metric)] = float64(sample.Value)
+	}
+	ret := []IdleWorkload{}
+	for _, sample := range cpuPercent {
+		if math.IsNaN(float64(sample.Value)) || math.IsInf(float64(sample.Value), 0) {
+			continue
+		}
+		k := key(sample.Metric)
+		ret = append(ret, IdleWorkload{
+			Cluster:       cluster,
+			Namespace:     string(sample.Metric["namespace"]),
+			Workload:      string(sample.Metric["workload"]),
+			CPUPercent:    math.Round(float64(sample.Value)*10) / 10,
+			MemoryPercent: math.Round(memoryMap[k]*10) / 10,
+			CPULimitCores: limitMap[k],
+		})
+	}
+	return ret, nil
+}
+
+// costs 汇总统计时间范围内每天的费用, 费用由费用统计任务按集群单价计算, 没有统计费用的集群不包含在内
+func (r *Reporter) costs(ctx context.Context, tenantName, projectName string, data *Data) error {
+	query := r.DB.WithContext(ctx).Model(&models.CostDaily{}).
+		Select(`currency, count(distinct date) as days,
+		sum(cpu_request_core_hours) as cpu_request_core_hours, sum(memory_request_gb_hours) as memory_request_gb_hours,
+		sum(storage_gb_hours) as storage_gb_hours, sum(gpu_hours) as gpu_hours,
+		sum(cpu_cost) as cpu_cost, sum(memory_cost) as memory_cost, sum(storage_cost) as storage_cost,
+		sum(gpu_cost) as gpu_cost, sum(cost) as cost`).
+		Where("date >= ? and date < ?", data.Start.UTC().Format(models.CostDateLayout), data.End.UTC().Format(models.CostDateLayout)).
+		Where("tenant_name = ?", tenantName)
+	if projectName != "" {
+		query = query.Where("project_name = ?", projectName)
+	}
+	sums := []costSum{}
+	if err := query.Group("currency").Order("currency").Scan(&sums).Error; err != nil {
+		return err
+	}
+	data.Costs = costsOf(sums)
+	return nil
+}
+
+// costSum 按币种汇总的用量和费用
+type costSum struct {
+	Currency string
+	Days     int
+	models.CostUsage
+	CPUCost     float64
+	MemoryCost  float64
+	StorageCost float64
+	GPUCost     float64
+	Cost        float64
+}
+
+func costsOf(sums []costSum) []Cost {
+	costs := []Cost{}
+	for _, sum := range sums {
+		cost := Cost{Currency: sum.Currency, Days: sum.Days, Items: []CostItem{}, Total: math.Round(sum.Cost*100) / 100}
+		for _, item := range []CostItem{
+			{Resource: "cpu", Quantity: sum.CPURequestCoreHours, Unit: "core·h", Amount: sum.CPUCost},
+			{Resource: "memory", Quantity: sum.MemoryRequestGBHours, Unit: "GB·h", Amount: sum.MemoryCost},
+			{Resource: "storage", Quantity: sum.StorageGBHours, Unit: "GB·h", Amount: sum.StorageCost},
+			{Resource: "gpu", Quantity: sum.GPUHours, Unit: "GPU·h", Amount: sum.GPUCost},
+		} {
+			if item.Amount == 0 {
+				continue
+			}
+			item.Amount = math.Round(item.Amount*100) / 100
+			cost.Items = append(cost.Items, item)
+		}
+		costs = append(costs, cost)
+	}
+	return costs
+}
+
+// Send 统计一次报告并按收件人的内容配置分别发送
+func (r *Reporter) Send(ctx context.Context, report *models.Report, now time.Time) error {
+	ch := &models.AlertChannel{}
+	if err := r.DB.WithContext(ctx).First(ch, "id = ?", report.ChannelID).Error; err != nil {
+		return err
+	}
+	email, ok := ch.ChannelConfig.ChannelIf.(*channels.Email)
+	if !ok {
+		return errors.Errorf("channel %s is not an email channel", ch.Name)
+	}
+	data, err := r.Build(ctx, report, now)
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("Kubegems report [%s] %s ~ %s", data.Title,
+		data.Start.Format("2006-01-02"), data.End.Format("2006-01-02"))
+	for _, rcpt := range report.Recipients {
+		html, err := Render(data, rcpt.Sections)
+		if err != nil {
+			return err
+		}
+		if err := email.SendHTML(rcpt.Emails, subject, html); err != nil {
+			return errors.Wrapf(err, "send to %s", strings.Join(rcpt.Emails, ","))
+		}
+	}
+	return nil
+}
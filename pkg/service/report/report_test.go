@@ -0,0 +1,130 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+func testData() *Data {
+	end := time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC)
+	allocated := resource.MustParse("2")
+	data := &Data{
+		Title:  "tenant",
+		Start:  end.Add(-10 * time.Hour),
+		End:    end,
+		Alerts: []SeverityCount{{Severity: "critical", Firing: 3, Resolved: 2}},
+		Quotas: []QuotaUsage{
+			newQuotaUsage("cluster", "", v1.ResourceLimitsCPU, resource.MustParse("4"), &allocated, resource.MustParse("1")),
+			newQuotaUsage("cluster", "", v1.ResourceLimitsMemory, resource.MustParse("8Gi"), resource.NewQuantity(4<<30, resource.BinarySI), resource.MustParse("1Gi")),
+		},
+		report: &models.Report{},
+	}
+	return data
+}
+
+func TestNewQuotaUsage(t *testing.T) {
+	data := testData()
+	if data.Quotas[0].UsedPercent != 25 || data.Quotas[0].Allocated != "2" {
+		t.Errorf("quota usage = %+v, want 25%% used and 2 allocated", data.Quotas[0])
+	}
+}
+
+func TestReporter_costs(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.CostDaily{}); err != nil {
+		t.Fatal(err)
+	}
+	daily := func(date, project string, cpuCost float64) *models.CostDaily {
+		return &models.CostDaily{
+			Date: date, ClusterName: "cluster", Namespace: project + "-" + date, TenantName: "tenant", ProjectName: project, Currency: "CNY",
+			CostUsage: models.CostUsage{CPURequestCoreHours: 24},
+			CPUCost:   cpuCost, Cost: cpuCost,
+		}
+	}
+	for _, d := range []*models.CostDaily{
+		daily("2022-01-08", "p1", 1.5),
+		daily("2022-01-09", "p1", 2),
+		daily("2022-01-09", "p2", 3),
+		// 不在统计时间范围内
+		daily("2022-01-10", "p1", 100),
+	} {
+		if err := db.Create(d).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := &Data{Start: time.Date(2022, 1, 8, 9, 0, 0, 0, time.UTC), End: time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC)}
+	r := &Reporter{DB: db}
+	if err := r.costs(context.Background(), "tenant", "p1", data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Costs) != 1 {
+		t.Fatalf("costs = %+v, want one currency", data.Costs)
+	}
+	cost := data.Costs[0]
+	if cost.Currency != "CNY" || cost.Days != 2 || cost.Total != 3.5 || len(cost.Items) != 1 || cost.Items[0].Quantity != 48 {
+		t.Errorf("costs = %+v", cost)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "all sections",
+			contains: []string{"Alerts by severity", "critical", "Quota usage", "Allocated", "Most idle workloads"},
+		},
+		{
+			name:     "alerts only",
+			sections: []string{models.ReportSectionAlerts},
+			contains: []string{"Alerts by severity"},
+			excludes: []string{"Quota usage", "Top noisy alert rules"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Render(testData(), tt.sections)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(html, s) {
+					t.Errorf("Render() missing %q", s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(html, s) {
+					t.Errorf("Render() should not contain %q", s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"html/template"
+
+	"kubegems.io/kubegems/pkg/service/models"
+)
+
+// 邮件客户端大多不支持外部样式, 使用内联样式
+const reportTemplate = `<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>Kubegems report {{ .Title }}</title></head>
+<body style="font-family: Arial, sans-serif; font-size: 14px; color: #333;">
+<h2>Kubegems report: {{ .Title }}</h2>
+<p>{{ .Start.Format "2006-01-02 15:04" }} ~ {{ .End.Format "2006-01-02 15:04" }}</p>
+{{- define "th" }}style="border: 1px solid #ddd; padding: 4px 8px; background: #f5f5f5; text-align: left;"{{ end }}
+{{- define "td" }}style="border: 1px solid #ddd; padding: 4px 8px;"{{ end }}
+
+{{- if .Show "alerts" }}
+<h3>Alerts by severity</h3>
+{{- if .Alerts }}
+<table style="border-collapse: collapse;">
+<tr><th {{ template "th" }}>Severity</th><th {{ template "th" }}>Firing</th><th {{ template "th" }}>Resolved</th></tr>
+{{- range .Alerts }}
+<tr><td {{ template "td" }}>{{ .Severity }}</td><td {{ template "td" }}>{{ .Firing }}</td><td {{ template "td" }}>{{ .Resolved }}</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No alerts.</p>
+{{- end }}
+{{- end }}
+
+{{- if .Show "noisyRules" }}
+<h3>Top noisy alert rules</h3>
+{{- if .NoisyRules }}
+<table style="border-collapse: collapse;">
+<tr><th {{ template "th" }}>Cluster</th><th {{ template "th" }}>Namespace</th><th {{ template "th" }}>Rule</th><th {{ template "th" }}>Firing count</th></tr>
+{{- range .NoisyRules }}
+<tr><td {{ template "td" }}>{{ .Cluster }}</td><td {{ template "td" }}>{{ .Namespace }}</td><td {{ template "td" }}>{{ .Name }}</td><td {{ template "td" }}>{{ .Count }}</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No alerts.</p>
+{{- end }}
+{{- end }}
+
+{{- if .Show "quota" }}
+<h3>Quota usage</h3>
+{{- if .Quotas }}
+<table style="border-collapse: collapse;">
+<tr><th {{ template "th" }}>Cluster</th>{{ if .ProjectReport }}<th {{ template "th" }}>Environment</th>{{ end }}<th {{ template "th" }}>Resource</th><th {{ template "th" }}>Hard</th>{{ if not .ProjectReport }}<th {{ template "th" }}>Allocated</th>{{ end }}<th {{ template "th" }}>Used</th><th {{ template "th" }}>Used / Hard</th></tr>
+{{- $project := .ProjectReport }}
+{{- range .Quotas }}
+<tr><td {{ template "td" }}>{{ .Cluster }}</td>{{ if $project }}<td {{ template "td" }}>{{ .Environment }}</td>{{ end }}<td {{ template "td" }}>{{ .Resource }}</td><td {{ template "td" }}>{{ .Hard }}</td>{{ if not $project }}<td {{ template "td" }}>{{ .Allocated }}</td>{{ end }}<td {{ template "td" }}>{{ .Used }}</td><td {{ template "td" }}>{{ .UsedPercent }}%</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No quota.</p>
+{{- end }}
+{{- end }}
+
+{{- if .Show "idleWorkloads" }}
+<h3>Most idle workloads</h3>
+{{- if .IdleWorkloads }}
+<table style="border-collapse: collapse;">
+<tr><th {{ template "th" }}>Cluster</th><th {{ template "th" }}>Namespace</th><th {{ template "th" }}>Workload</th><th {{ template "th" }}>CPU usage (p95)</th><th {{ template "th" }}>Memory usage (p95)</th><th {{ template "th" }}>CPU limit (cores)</th></tr>
+{{- range .IdleWorkloads }}
+<tr><td {{ template "td" }}>{{ .Cluster }}</td><td {{ template "td" }}>{{ .Namespace }}</td><td {{ template "td" }}>{{ .Workload }}</td><td {{ template "td" }}>{{ .CPUPercent }}%</td><td {{ template "td" }}>{{ .MemoryPercent }}%</td><td {{ template "td" }}>{{ .CPULimitCores }}</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No workloads.</p>
+{{- end }}
+{{- end }}
+
+{{- if and (.Show "cost") .Costs }}
+<h3>Cost</h3>
+{{- range .Costs }}
+<p>Daily cost of {{ .Days }} days in {{ .Currency }}.</p>
+<table style="border-collapse: collapse;">
+<tr><th {{ template "th" }}>Resource</th><th {{ template "th" }}>Requested</th><th {{ template "th" }}>Amount</th></tr>
+{{- $currency := .Currency }}
+{{- range .Items }}
+<tr><td {{ template "td" }}>{{ .Resource }}</td><td {{ template "td" }}>{{ printf "%.2f" .Quantity }} {{ .Unit }}</td><td {{ template "td" }}>{{ printf "%.2f" .Amount }} {{ $currency }}</td></tr>
+{{- end }}
+<tr><td {{ template "td" }} colspan="2"><b>Total</b></td><td {{ template "td" }}><b>{{ printf "%.2f" .Total }} {{ $currency }}</b></td></tr>
+</table>
+{{- end }}
+{{- end }}
+
+{{- if .Errors }}
+<h3>Errors</h3>
+<ul>
+{{- range .Errors }}
+<li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- end }}
+</body>
+</html>
+`
+
+var reportTpl = template.Must(template.New("report").Parse(reportTemplate))
+
+type view struct {
+	*Data
+	sections []string
+}
+
+func (v view) Show(section string) bool {
+	return models.ReportRecipient{Sections: v.sections}.HasSection(section)
+}
+
+func (v view) ProjectReport() bool {
+	return v.report != nil && v.report.ProjectID != nil
+}
+
+// Render 渲染 html 格式的报告, sections 为空时包含所有内容
+func Render(data *Data, sections []string) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := reportTpl.Execute(buf, view{Data: data, sections: sections}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -35,6 +35,7 @@ func (r *Router) AddRestAPI(ctx context.Context, deps apis.Dependencies) error {
 	r.gin.Any("/v1/plugins", apifun)
 	r.gin.Any("/.well-known/openid-configuration", apifun) // oidc discovery
 	r.gin.Any("/keys", apifun)                             // oidc keys
+	r.gin.Any("/oidc/*path", apifun)                       // builtin oidc provider
 
 	// just hardcode the path for now
 	p, err := proxy.NewProxy(deps.Opts.Models.Addr)
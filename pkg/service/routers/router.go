@@ -16,7 +16,6 @@ package routers
 
 import (
 	"context"
-	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -44,6 +43,7 @@ import (
 	authsource "kubegems.io/kubegems/pkg/service/handlers/authsource"
 	"kubegems.io/kubegems/pkg/service/handlers/base"
 	clusterhandler "kubegems.io/kubegems/pkg/service/handlers/cluster"
+	costhandler "kubegems.io/kubegems/pkg/service/handlers/cost"
 	environmenthandler "kubegems.io/kubegems/pkg/service/handlers/environment"
 	eventhandler "kubegems.io/kubegems/pkg/service/handlers/event"
 	loginhandler "kubegems.io/kubegems/pkg/service/handlers/login"
@@ -56,6 +56,7 @@ import (
 	noproxyhandler "kubegems.io/kubegems/pkg/service/handlers/noproxy"
 	"kubegems.io/kubegems/pkg/service/handlers/oauthserver"
 	"kubegems.io/kubegems/pkg/service/handlers/observability"
+	"kubegems.io/kubegems/pkg/service/handlers/oidcclient"
 	projecthandler "kubegems.io/kubegems/pkg/service/handlers/project"
 	proxyhandler "kubegems.io/kubegems/pkg/service/handlers/proxy"
 	registryhandler "kubegems.io/kubegems/pkg/service/handlers/registry"
@@ -76,25 +77,15 @@ import (
 	"kubegems.io/kubegems/pkg/utils/prometheus/exporter"
 	"kubegems.io/kubegems/pkg/utils/redis"
 	"kubegems.io/kubegems/pkg/utils/system"
+	"kubegems.io/kubegems/pkg/utils/workflow"
 	"kubegems.io/kubegems/pkg/version"
 )
 
-func getClientIP(c *gin.Context) string {
-	forwardHeader := c.Request.Header.Get("x-forwarded-for")
-	if len(forwardHeader) > 0 {
-		firstAddress := strings.Split(forwardHeader, ",")[0]
-		if net.ParseIP(strings.TrimSpace(firstAddress)) != nil {
-			return firstAddress
-		}
-	}
-	return c.ClientIP()
-}
-
+// RealClientIPMiddleware 将 RemoteAddr 设置为客户端的真实 IP, 仅信任 TrustedProxies 中代理设置的 X-Forwarded-For
 func RealClientIPMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		_, port, _ := net.SplitHostPort(strings.TrimSpace(ctx.Request.RemoteAddr))
-		ip := getClientIP(ctx)
-		ctx.Request.RemoteAddr = fmt.Sprintf("%s:%s", ip, port)
+		ctx.Request.RemoteAddr = net.JoinHostPort(ctx.ClientIP(), port)
 		ctx.Next()
 	}
 }
@@ -165,6 +156,9 @@ func (r *Router) Complete(ctx context.Context, rediscli *redis.Client) error {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r.gin = gin.New()
+	if err := r.gin.SetTrustedProxies(r.Opts.System.TrustedProxies); err != nil {
+		return err
+	}
 	router := r.gin
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/swagger/doc.json")))
@@ -212,10 +206,14 @@ func (r *Router) Complete(ctx context.Context, rediscli *redis.Client) error {
 		DB:         r.Database.DB(),
 		AuthModule: *auth.NewAuthenticateModule(r.Database.DB()),
 		JWTOptions: r.Opts.JWT,
+		ModelCache: basehandler.ModelCache(),
 	}
 	router.POST("/v1/login", oauth.LoginHandler)
+	router.POST("/v1/login/2fa/enroll", oauth.LoginTwoFactorEnroll)
+	router.POST("/v1/login/2fa/activate", oauth.LoginTwoFactorActivate)
 	router.GET("/v1/oauth/addr", oauth.GetOauthAddr)
 	router.GET("/v1/oauth/callback", oauth.GetOauthToken)
+	router.POST("/v1/saml/acs", oauth.SAMLACS)
 
 	tracer := otel.GetTracerProvider().Tracer("kubegems.io/kubegems")
 	oauthserver := oauthserver.NewOauthServer(r.Opts.JWT, basehandler, tracer)
@@ -230,7 +228,9 @@ func (r *Router) Complete(ctx context.Context, rediscli *redis.Client) error {
 	// 注册中间件
 	apiMidwares := []func(*gin.Context){
 		// authc
-		auth.NewAuthMiddleware(r.Opts.JWT, userif, tracer).FilterFunc,
+		auth.NewAuthMiddleware(r.Opts.JWT, r.Database.DB(), userif, tracer).FilterFunc,
+		// 限定范围的令牌默认拒绝
+		authorization.ScopedTokenFilter,
 		// audit
 		r.auditInstance.Middleware(),
 	}
@@ -271,7 +271,7 @@ func (r *Router) Complete(ctx context.Context, rediscli *redis.Client) error {
 	messageHandler.RegistRouter(rg)
 
 	// 消息
-	approveHandler := &approveHandler.ApproveHandler{BaseHandler: basehandler}
+	approveHandler := &approveHandler.ApproveHandler{BaseHandler: basehandler, GitProvider: r.GitProvider}
 	approveHandler.RegistRouter(rg)
 
 	// 日志
@@ -289,7 +289,10 @@ func (r *Router) Complete(ctx context.Context, rediscli *redis.Client) error {
 	alertRuleHandler.RegistRouter(rg)
 
 	// 环境
-	environmentHandler := &environmenthandler.EnvironmentHandler{BaseHandler: basehandler}
+	environmentHandler := &environmenthandler.EnvironmentHandler{
+		BaseHandler: basehandler,
+		Workflowcli: workflow.NewDefaultRemoteClient(),
+	}
 	environmentHandler.RegistRouter(rg)
 
 	// 当前个人信息
@@ -331,6 +334,10 @@ func (r *Router) Complete(ctx context.Context, rediscli *redis.Client) error {
 	// authsource
 	authSourceHandler.RegistRouter(rg)
 
+	// 内置 OIDC Provider 客户端
+	oidcClientHandler := &oidcclient.OIDCClientHandler{BaseHandler: basehandler}
+	oidcClientHandler.RegistRouter(rg)
+
 	// microservice  handler
 	// TODO: kiali在每个集群配置可能不相同，先写死，后面看要不要支持配置
 	microservicehandler := microservice.NewMicroServiceHandler(basehandler, r.Opts.Microservice)
@@ -345,6 +352,9 @@ func (r *Router) Complete(ctx context.Context, rediscli *redis.Client) error {
 
 	(&announcement.AnnouncementHandler{BaseHandler: basehandler}).RegistRouter(rg)
 
+	// 费用分摊
+	(&costhandler.CostHandler{BaseHandler: basehandler}).RegistRouter(rg)
+
 	// app center and app store
 	apps.RegistRouter(rg, r.GitProvider, r.Argo, r.Opts.Appstore, basehandler)
 
@@ -242,6 +242,36 @@ func (c *ExtendClient) LokiQuery(ctx context.Context, logql string) (loki.QueryR
 	return ret, nil
 }
 
+func (c *ExtendClient) LokiQueryRange(ctx context.Context, logql, start, end, step string) (loki.QueryResponseData, error) {
+	ret := loki.QueryResponseData{}
+	values := url.Values{}
+	values.Add("query", logql)
+	values.Add("start", start)
+	values.Add("end", end)
+	values.Add("step", step)
+	if err := c.DoRequest(ctx, Request{
+		Path:  "/custom/loki/v1/queryrange",
+		Query: values,
+		Into:  WrappedResponse(&ret),
+	}); err != nil {
+		return ret, err
+	}
+	return ret, nil
+}
+
+// LokiQueryRangeWithParam 支持 direction 和 limit 等参数的日志查询
+func (c *ExtendClient) LokiQueryRangeWithParam(ctx context.Context, param loki.QueryRangeParam) (loki.QueryResponseData, error) {
+	ret := loki.QueryResponseData{}
+	if err := c.DoRequest(ctx, Request{
+		Path:  "/custom/loki/v1/queryrange",
+		Query: QueryFrom(param.ToMap()),
+		Into:  WrappedResponse(&ret),
+	}); err != nil {
+		return ret, err
+	}
+	return ret, nil
+}
+
 func WrappedResponse(intodata interface{}) *response.Response {
 	return &response.Response{Data: intodata}
 }
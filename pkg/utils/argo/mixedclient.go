@@ -113,10 +113,12 @@ func (c *Client) EnsureArgoApp(ctx context.Context, app *v1alpha1.Application) (
 	})
 }
 
-func (c *Client) Sync(ctx context.Context, name string, resources []v1alpha1.SyncOperationResource) error {
+// Sync 同步应用, revision 为空时同步应用的 targetRevision
+func (c *Client) Sync(ctx context.Context, name string, revision string, resources []v1alpha1.SyncOperationResource) error {
 	_, err := appfunc(c, ctx, func(cli application.ApplicationServiceClient) (*v1alpha1.Application, error) {
 		return cli.Sync(ctx, &application.ApplicationSyncRequest{
 			Name:      &name,
+			Revision:  revision,
 			Resources: resources,
 			Strategy:  &v1alpha1.SyncStrategy{Apply: &v1alpha1.SyncStrategyApply{Force: true}},
 			Prune:     true,
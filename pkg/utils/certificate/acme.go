@@ -0,0 +1,181 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certificate
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+const (
+	ChallengeHTTP01 = "http-01"
+	ChallengeDNS01  = "dns-01"
+)
+
+// ACMESolver 发布和清理 ACME 验证的内容
+type ACMESolver interface {
+	// Present http-01 时 value 为 token 对应的响应内容, dns-01 时为 TXT 记录的值
+	Present(ctx context.Context, domain, token, value string) error
+	CleanUp(ctx context.Context, domain, token string) error
+}
+
+type ACMEOptions struct {
+	Server     string
+	Email      string
+	AccountKey crypto.Signer
+	// SkipTLSVerify 本地测试的 ACME 服务通常使用自签名证书
+	SkipTLSVerify bool
+	// ChallengeType http-01 或 dns-01
+	ChallengeType string
+	Solver        ACMESolver
+}
+
+// GenerateACMEAccountKey 生成 ACME 账号的私钥
+func GenerateACMEAccountKey() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return keyutil.MarshalPrivateKeyToPEM(key)
+}
+
+func ParseACMEAccountKey(keyPEM []byte) (crypto.Signer, error) {
+	key, err := keyutil.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("invalid acme account key")
+	}
+	return signer, nil
+}
+
+// IssueACMECertificate 通过 ACME 签发证书, 返回证书链和私钥
+func IssueACMECertificate(ctx context.Context, options ACMEOptions, hosts []string) ([]byte, []byte, error) {
+	if len(hosts) == 0 {
+		return nil, nil, fmt.Errorf("no hosts to issue certificate")
+	}
+	cli := &acme.Client{Key: options.AccountKey, DirectoryURL: options.Server}
+	if options.SkipTLSVerify {
+		cli.HTTPClient = &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // nolint: gosec
+		}}
+	}
+	account := &acme.Account{}
+	if options.Email != "" {
+		account.Contact = []string{"mailto:" + options.Email}
+	}
+	if _, err := cli.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, nil, fmt.Errorf("register acme account: %w", err)
+	}
+
+	order, err := cli.AuthorizeOrder(ctx, acme.DomainIDs(hosts...))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, u := range order.AuthzURLs {
+		if err := authorizeACME(ctx, cli, options, u); err != nil {
+			return nil, nil, err
+		}
+	}
+	if order, err = cli.WaitOrder(ctx, order.URI); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hosts[0]},
+		DNSNames: hosts,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	ders, _, err := cli.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := []byte{}
+	for _, der := range ders {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: cert.CertificateBlockType, Bytes: der})...)
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+func authorizeACME(ctx context.Context, cli *acme.Client, options ACMEOptions, url string) error {
+	authz, err := cli.GetAuthorization(ctx, url)
+	if err != nil {
+		return err
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == options.ChallengeType {
+			challenge = c
+			break
+		}
+	}
+	domain := authz.Identifier.Value
+	if challenge == nil {
+		return fmt.Errorf("no %s challenge for %s", options.ChallengeType, domain)
+	}
+
+	var value string
+	switch options.ChallengeType {
+	case ChallengeHTTP01:
+		value, err = cli.HTTP01ChallengeResponse(challenge.Token)
+	case ChallengeDNS01:
+		value, err = cli.DNS01ChallengeRecord(challenge.Token)
+	default:
+		err = fmt.Errorf("unsupported challenge type %s", options.ChallengeType)
+	}
+	if err != nil {
+		return err
+	}
+	if err := options.Solver.Present(ctx, domain, challenge.Token, value); err != nil {
+		return fmt.Errorf("present %s challenge for %s: %w", options.ChallengeType, domain, err)
+	}
+	defer func() {
+		_ = options.Solver.CleanUp(ctx, domain, challenge.Token)
+	}()
+	if _, err := cli.Accept(ctx, challenge); err != nil {
+		return err
+	}
+	_, err = cli.WaitAuthorization(ctx, authz.URI)
+	return err
+}
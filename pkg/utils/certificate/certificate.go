@@ -82,6 +82,27 @@ func ParseCertInfo(certPEM []byte) (*Certificate, error) {
 	}, nil
 }
 
+// NeedRenew 证书不包含所有的域名或者即将过期时需要重新签发
+func NeedRenew(certPEM []byte, hosts []string, renewBefore time.Duration) bool {
+	info, err := ParseCertInfo(certPEM)
+	if err != nil {
+		return true
+	}
+	if time.Until(info.NotAfter) < renewBefore {
+		return true
+	}
+	sans := map[string]bool{}
+	for _, san := range info.SANs {
+		sans[san] = true
+	}
+	for _, host := range hosts {
+		if !sans[host] {
+			return true
+		}
+	}
+	return false
+}
+
 func ParseName(name pkix.Name) Name {
 	return Name{
 		CommonName:         name.CommonName,
@@ -16,6 +16,9 @@ package certificate
 
 import (
 	"testing"
+	"time"
+
+	"k8s.io/client-go/util/cert"
 )
 
 func TestParseCertInfo(t *testing.T) {
@@ -69,3 +72,38 @@ RaDPwmn9di6rji+Q4BVN1qKhh2AOgGiEK/qVNywAg7s4OOLVN4Se2gwuxlYOlNEn
 		})
 	}
 }
+
+func TestNeedRenew(t *testing.T) {
+	caCert, caKey, err := cert.GenerateSelfSignedCertKey("ca.example.com", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expireAt := time.Now().Add(30 * 24 * time.Hour)
+	certPEM, _, err := IssueCertificate(nil, caCert, caKey, CertOptions{
+		Hosts:    []string{"a.example.com", "b.example.com"},
+		ExpireAt: &expireAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		certPEM     []byte
+		hosts       []string
+		renewBefore time.Duration
+		want        bool
+	}{
+		{name: "valid", certPEM: certPEM, hosts: []string{"a.example.com"}, renewBefore: 24 * time.Hour},
+		{name: "all hosts", certPEM: certPEM, hosts: []string{"a.example.com", "b.example.com"}, renewBefore: 24 * time.Hour},
+		{name: "missing host", certPEM: certPEM, hosts: []string{"c.example.com"}, renewBefore: 24 * time.Hour, want: true},
+		{name: "expiring", certPEM: certPEM, hosts: []string{"a.example.com"}, renewBefore: 60 * 24 * time.Hour, want: true},
+		{name: "invalid", certPEM: []byte("invalid"), hosts: []string{"a.example.com"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedRenew(tt.certPEM, tt.hosts, tt.renewBefore); got != tt.want {
+				t.Errorf("NeedRenew() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
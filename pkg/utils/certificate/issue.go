@@ -67,8 +67,13 @@ func IssueCertificate(caPEMBlock, certPEMBlock, keyPEMBlock []byte, options Cert
 	if options.ExpireAt != nil && !options.ExpireAt.IsZero() {
 		validTo = *options.ExpireAt
 	}
+	// 同一个 CA 签发的证书序列号不能重复
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
 	template := &x509.Certificate{
-		SerialNumber: big.NewInt(2),
+		SerialNumber: serialNumber,
 		Subject:      pkix.Name{CommonName: options.CommonName},
 		NotBefore:    validFrom,
 		NotAfter:     validTo,
@@ -78,7 +83,7 @@ func IssueCertificate(caPEMBlock, certPEMBlock, keyPEMBlock []byte, options Cert
 			x509.KeyUsageCertSign |
 			x509.KeyUsageCRLSign,
 		ExtKeyUsage: []x509.ExtKeyUsage{
-			x509.ExtKeyUsageServerAuth | x509.ExtKeyUsageClientAuth,
+			x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth,
 		},
 		BasicConstraintsValid: true,
 		Extensions:            []pkix.Extension{},
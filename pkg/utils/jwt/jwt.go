@@ -40,6 +40,9 @@ type Options struct {
 	Cert       string        `yaml:"cert" default:"certs/jwt/tls.crt" help:"jwt cert file"`
 	Key        string        `yaml:"key" default:"certs/jwt/tls.key" help:"jwt key file"`
 	IssuerAddr string        `json:"issuerAddr" description:"oidc provider issuer address"`
+	// AllowInsecureIssuer 允许 http 的 issuer 和客户端回调地址, 仅用于开发测试
+	AllowInsecureIssuer bool   `json:"allowInsecureIssuer" description:"allow http oidc provider issuer and client redirect uris, for development only"`
+	LoginURL            string `json:"loginURL" description:"login page of the builtin oidc provider, a path or an url on the same origin as the issuer"`
 }
 
 func DefaultOptions() *Options {
@@ -48,6 +51,7 @@ func DefaultOptions() *Options {
 		Cert:       "certs/jwt/tls.crt",
 		Key:        "certs/jwt/tls.key",
 		IssuerAddr: "http://kubegems-api.kubegems",
+		LoginURL:   "/login",
 	}
 }
 
@@ -80,10 +84,16 @@ func (opts *Options) ToJWT() *JWT {
 
 // GenerateToken Generate new jwt token
 func (t *JWT) GenerateToken(payload interface{}, sub string, isAdmin bool, expire time.Duration) (token string, claims JWTClaims, err error) {
+	return t.GenerateTokenWithID("", payload, sub, isAdmin, expire)
+}
+
+// GenerateTokenWithID Generate new jwt token with jti, used by persisted tokens which can be revoked
+func (t *JWT) GenerateTokenWithID(id string, payload interface{}, sub string, isAdmin bool, expire time.Duration) (token string, claims JWTClaims, err error) {
 	now := time.Now()
 	jwtClaims := JWTClaims{
 		Payload: payload,
 		StandardClaims: &jwt.StandardClaims{
+			Id:        id,
 			IssuedAt:  now.Unix(),
 			ExpiresAt: now.Add(expire).Unix(),
 			Subject:   sub,
@@ -219,3 +219,20 @@ func GetExpr(label string, value string) string {
 	}
 	return queryExpr
 }
+
+var (
+	// trace_id=xxx, traceId: xxx, "traceID":"xxx", uber-trace-id: xxx:span:parent:flags
+	traceIDFieldRegex = regexp.MustCompile(`(?i)(?:trace[_-]?id|uber-trace-id)["']?\s*[:=]\s*["']?([0-9a-f]{16,32})\b`)
+	// w3c traceparent, eg. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	traceparentRegex = regexp.MustCompile(`\b00-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}\b`)
+)
+
+// TraceID 从日志中识别 trace id, 没有时返回空
+func TraceID(message string) string {
+	for _, reg := range []*regexp.Regexp{traceIDFieldRegex, traceparentRegex} {
+		if match := reg.FindStringSubmatch(message); len(match) == 2 && strings.Trim(match[1], "0") != "" {
+			return strings.ToLower(match[1])
+		}
+	}
+	return ""
+}
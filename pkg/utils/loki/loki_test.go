@@ -0,0 +1,61 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import "testing"
+
+func TestTraceID(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "logfmt",
+			message: `level=info msg="request done" trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7`,
+			want:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:    "json",
+			message: `{"level":"info","traceID":"4BF92F3577B34DA6","msg":"ok"}`,
+			want:    "4bf92f3577b34da6",
+		},
+		{
+			name:    "jaeger header",
+			message: `uber-trace-id: 4bf92f3577b34da6:00f067aa0ba902b7:0:1`,
+			want:    "4bf92f3577b34da6",
+		},
+		{
+			name:    "traceparent",
+			message: `GET /api traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01`,
+			want:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:    "invalid trace id",
+			message: `trace_id=00000000000000000000000000000000`,
+		},
+		{
+			name:    "no trace id",
+			message: `level=error msg="connection refused"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TraceID(tt.message); got != tt.want {
+				t.Errorf("TraceID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
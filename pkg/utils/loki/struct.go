@@ -150,6 +150,8 @@ type Info struct {
 	Timestamp    string `json:"timestamp"`
 	Timestampstr string `json:"timestampstr"`
 	Index        string `json:"index"`
+	TraceID      string `json:"traceID,omitempty"`   // 日志中的 trace id
+	TraceLink    string `json:"traceLink,omitempty"` // trace 详情的链接
 }
 
 type SeriesForm struct {
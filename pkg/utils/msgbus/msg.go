@@ -48,7 +48,9 @@ const (
 	Cluster      ResourceType = "cluster"
 	User         ResourceType = "user"
 
-	TenantResourceQuota ResourceType = "tenant-resource-quota"
+	TenantResourceQuota  ResourceType = "tenant-resource-quota"
+	ProjectResourceQuota ResourceType = "project-resource-quota"
+	Approval             ResourceType = "approval"
 )
 
 type InvolvedObject struct {
@@ -29,11 +29,15 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
 	oteltrace "go.opentelemetry.io/otel/trace"
+	"kubegems.io/kubegems/pkg/log"
 )
 
 const (
 	tracerKey  = "otel-go-contrib-tracer"
 	tracerName = "go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
+	// TraceIDHeader response header with the trace id of the request
+	TraceIDHeader = "X-Trace-Id"
 )
 
 // TraceMiddleware returns middleware that will trace incoming requests.
@@ -89,8 +93,14 @@ func TraceMiddleware(service string, opts ...Option) gin.HandlerFunc {
 		span.SetAttributes(
 			attribute.String("user.name", reqBaggage.Member("user.name").Value()),
 		)
-		// pass the span through the request context
-		c.Request = c.Request.WithContext(ctx)
+		// pass the span through the request context, with a logger carrying the trace id
+		c.Request = c.Request.WithContext(log.WithTraceContext(ctx))
+		// expose the trace id to the access log and the client, so one request can be followed
+		// from the response through the logs and traces of every component
+		if sc := span.SpanContext(); sc.HasTraceID() {
+			c.Set(log.TraceIDKey, sc.TraceID().String())
+			c.Header(TraceIDHeader, sc.TraceID().String())
+		}
 
 		// serve the request to the next middleware
 		c.Next()
@@ -0,0 +1,176 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+)
+
+const (
+	// 限制规则的执行开销, 避免大列表的嵌套遍历阻塞准入请求
+	celCostLimit = 1000000
+	// 每执行多少次遍历检查一次超时
+	celInterruptCheckFrequency = 100
+)
+
+// CELEngine 规则为一个 CEL 表达式, 可以使用的变量:
+//   - request: 与 Starlark 规则的 request 相同
+//   - podSpec: object 中 Pod 或者工作负载的 pod spec, 其他资源为空 map
+//
+// 表达式返回字符串, 字符串列表或者布尔值, 为空或者 false 表示通过, 例如:
+//
+//	has(podSpec.containers) ? podSpec.containers.filter(c, !c.image.startsWith("registry.example.com/")).map(c, "image " + c.image + " is not allowed") : []
+type CELEngine struct {
+	once sync.Once
+	env  *cel.Env
+	err  error
+}
+
+type celProgram struct {
+	program cel.Program
+}
+
+func (e *CELEngine) getEnv() (*cel.Env, error) {
+	e.once.Do(func() {
+		e.env, e.err = cel.NewEnv(
+			cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable("podSpec", cel.MapType(cel.StringType, cel.DynType)),
+			ext.Strings(),
+		)
+	})
+	return e.env, e.err
+}
+
+func (e *CELEngine) Compile(rule string) (Program, error) {
+	env, err := e.getEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(rule)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	program, err := env.Program(ast,
+		cel.CostLimit(celCostLimit),
+		cel.InterruptCheckFrequency(celInterruptCheckFrequency),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &celProgram{program: program}, nil
+}
+
+func (p *celProgram) Evaluate(ctx context.Context, req *Request) ([]string, error) {
+	request, _ := jsonToCEL(req.toMap()).(map[string]interface{})
+	podSpec, _ := podSpecOfObject(request["object"]).(map[string]interface{})
+	if podSpec == nil {
+		podSpec = map[string]interface{}{}
+	}
+	ret, _, err := p.program.ContextEval(ctx, map[string]interface{}{"request": request, "podSpec": podSpec})
+	if err != nil {
+		return nil, err
+	}
+	return violationsFromCEL(ret)
+}
+
+// jsonToCEL 与 Starlark 一致, 将 json 中的整数转换为 int, 便于与整数字面量比较
+func jsonToCEL(v interface{}) interface{} {
+	switch val := v.(type) {
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < math.MaxInt64 {
+			return int64(val)
+		}
+		return val
+	case []interface{}:
+		items := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			items = append(items, jsonToCEL(item))
+		}
+		return items
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			ret[k] = jsonToCEL(item)
+		}
+		return ret
+	default:
+		return val
+	}
+}
+
+// podSpecOfObject 返回 Pod 或者工作负载中的 pod spec
+func podSpecOfObject(obj interface{}) interface{} {
+	get := func(v interface{}, key string) interface{} {
+		if m, ok := v.(map[string]interface{}); ok {
+			return m[key]
+		}
+		return nil
+	}
+	kind, _ := get(obj, "kind").(string)
+	spec := get(obj, "spec")
+	switch kind {
+	case "Pod":
+		return spec
+	case "CronJob":
+		return get(get(get(get(spec, "jobTemplate"), "spec"), "template"), "spec")
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		return get(get(spec, "template"), "spec")
+	default:
+		return nil
+	}
+}
+
+var stringsType = reflect.TypeOf([]string{})
+
+func violationsFromCEL(v ref.Val) ([]string, error) {
+	switch val := v.(type) {
+	case types.Null:
+		return nil, nil
+	case types.Bool:
+		if val {
+			return []string{""}, nil
+		}
+		return nil, nil
+	case types.String:
+		if val == "" {
+			return nil, nil
+		}
+		return []string{string(val)}, nil
+	case traits.Lister:
+		native, err := val.ConvertToNative(stringsType)
+		if err != nil {
+			return nil, fmt.Errorf("rule must return a list of strings: %w", err)
+		}
+		ret := []string{}
+		for _, s := range native.([]string) {
+			if s != "" {
+				ret = append(ret, s)
+			}
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("rule must return a string, list of strings or bool, got %s", v.Type().TypeName())
+	}
+}
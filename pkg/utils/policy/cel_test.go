@@ -0,0 +1,99 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const restrictRegistryCEL = `has(podSpec.containers) ? podSpec.containers.filter(c, !c.image.startsWith("registry.example.com/")).map(c, "image " + c.image + " is not allowed") : []`
+
+func TestCELEvaluate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		req     *Request
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "registry restricted",
+			rule: restrictRegistryCEL,
+			req: &Request{Object: deployment(map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "registry.example.com/app:v1"},
+					map[string]interface{}{"name": "sidecar", "image": "docker.io/busybox"},
+				},
+			})},
+			want: []string{"image docker.io/busybox is not allowed"},
+		},
+		{
+			name: "registry not pod",
+			rule: restrictRegistryCEL,
+			req:  &Request{Object: map[string]interface{}{"kind": "Service"}},
+			want: []string{},
+		},
+		{
+			name: "bool result with integer compare",
+			rule: `request.object.spec.replicas > 0`,
+			req:  &Request{Object: deployment(nil)},
+			want: []string{""},
+		},
+		{
+			name: "string result",
+			rule: `request.operation == "DELETE" ? "" : request.tenant + " is read only"`,
+			req:  &Request{Operation: "CREATE", Tenant: "t1", Object: deployment(nil)},
+			want: []string{"t1 is read only"},
+		},
+		{
+			name: "passed",
+			rule: `request.environment == "prod" && !has(podSpec.containers)`,
+			req:  &Request{Environment: "test", Object: deployment(nil)},
+			want: nil,
+		},
+		{
+			name:    "invalid result",
+			rule:    `1`,
+			req:     &Request{Object: deployment(nil)},
+			wantErr: true,
+		},
+		{
+			name:    "missing field",
+			rule:    `request.object.metadata.name == "a"`,
+			req:     &Request{Object: deployment(nil)},
+			wantErr: true,
+		},
+		{
+			name:    "cost limit",
+			rule:    `[1,2,3,4,5,6,7,8,9,10].all(a, [1,2,3,4,5,6,7,8,9,10].all(b, [1,2,3,4,5,6,7,8,9,10].all(c, [1,2,3,4,5,6,7,8,9,10].all(d, [1,2,3,4,5,6,7,8,9,10].all(e, [1,2,3,4,5,6,7,8,9,10].all(f, a+b+c+d+e+f > 0))))))`,
+			req:     &Request{Object: deployment(nil)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(context.Background(), EngineCEL, tt.rule, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
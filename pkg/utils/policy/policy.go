@@ -0,0 +1,103 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy 执行用户定义的准入策略规则
+package policy
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	EngineCEL      = "CEL"
+	EngineStarlark = "Starlark"
+	// DefaultEngine 未指定引擎时使用的引擎
+	DefaultEngine = EngineCEL
+)
+
+// Request 策略规则的输入
+type Request struct {
+	Operation   string
+	Kind        string
+	Namespace   string
+	Name        string
+	User        string
+	Tenant      string
+	Project     string
+	Environment string
+	Object      map[string]interface{}
+	OldObject   map[string]interface{}
+}
+
+func (req *Request) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"operation":   req.Operation,
+		"kind":        req.Kind,
+		"namespace":   req.Namespace,
+		"name":        req.Name,
+		"user":        req.User,
+		"tenant":      req.Tenant,
+		"project":     req.Project,
+		"environment": req.Environment,
+		"object":      req.Object,
+		"oldObject":   req.OldObject,
+	}
+}
+
+// Program 编译后的规则, 可以并发执行
+type Program interface {
+	// Evaluate 返回违规信息, 为空表示通过, 违规但没有信息时返回空字符串
+	Evaluate(ctx context.Context, req *Request) ([]string, error)
+}
+
+// Engine 规则引擎
+type Engine interface {
+	// Compile 检查规则是否合法并编译
+	Compile(rule string) (Program, error)
+}
+
+// Rego 的依赖未引入, 需要时在这里注册
+var engines = map[string]Engine{
+	EngineCEL:      &CELEngine{},
+	EngineStarlark: &StarlarkEngine{},
+}
+
+func GetEngine(name string) (Engine, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+	engine, ok := engines[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported policy engine %s", name)
+	}
+	return engine, nil
+}
+
+func Compile(engine, rule string) (Program, error) {
+	e, err := GetEngine(engine)
+	if err != nil {
+		return nil, err
+	}
+	return e.Compile(rule)
+}
+
+// Evaluate 编译并执行规则, 用于只执行一次的场景
+func Evaluate(ctx context.Context, engine, rule string, req *Request) ([]string, error) {
+	program, err := Compile(engine, rule)
+	if err != nil {
+		return nil, err
+	}
+	return program.Evaluate(ctx, req)
+}
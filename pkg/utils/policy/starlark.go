@@ -0,0 +1,198 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.starlark.net/starlark"
+)
+
+const (
+	starlarkEntrypoint = "violation"
+	// 避免规则中的死循环阻塞准入请求
+	starlarkMaxExecutionSteps = 1000000
+)
+
+// StarlarkEngine 规则中需要定义 violation(request) 函数,
+// 返回字符串, 字符串列表或者布尔值, 为空或者 False 表示通过
+type StarlarkEngine struct{}
+
+// starlarkProgram 规则顶层代码只在编译时执行一次, 执行后的全局变量是冻结的, 可以在多个 thread 中调用
+type starlarkProgram struct {
+	fn starlark.Callable
+}
+
+func (e *StarlarkEngine) Compile(rule string) (Program, error) {
+	thread := &starlark.Thread{Name: "compile"}
+	thread.SetMaxExecutionSteps(starlarkMaxExecutionSteps)
+	globals, err := starlark.ExecFile(thread, "policy.star", rule, starlarkBuiltins)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := globals[starlarkEntrypoint].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("function %s(request) is not defined", starlarkEntrypoint)
+	}
+	return &starlarkProgram{fn: fn}, nil
+}
+
+func (p *starlarkProgram) Evaluate(ctx context.Context, req *Request) ([]string, error) {
+	thread := &starlark.Thread{Name: "policy"}
+	thread.SetMaxExecutionSteps(starlarkMaxExecutionSteps)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	ret, err := starlark.Call(thread, p.fn, starlark.Tuple{toStarlark(req.toMap())}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return violationsFromStarlark(ret)
+}
+
+var starlarkBuiltins = starlark.StringDict{
+	"pod_spec":        starlark.NewBuiltin("pod_spec", builtinPodSpec),
+	"containers":      starlark.NewBuiltin("containers", builtinContainers("containers")),
+	"init_containers": starlark.NewBuiltin("init_containers", builtinContainers("initContainers")),
+}
+
+// builtinPodSpec 返回 Pod 或者工作负载中的 pod spec, 其他资源返回 None
+func builtinPodSpec(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var obj starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &obj); err != nil {
+		return nil, err
+	}
+	return podSpecOf(obj), nil
+}
+
+func builtinContainers(field string) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var obj starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &obj); err != nil {
+			return nil, err
+		}
+		if containers, ok := dictGet(podSpecOf(obj), field).(*starlark.List); ok {
+			return containers, nil
+		}
+		return starlark.NewList(nil), nil
+	}
+}
+
+func podSpecOf(obj starlark.Value) starlark.Value {
+	kind, _ := starlark.AsString(dictGet(obj, "kind"))
+	spec := dictGet(obj, "spec")
+	switch kind {
+	case "Pod":
+		return spec
+	case "CronJob":
+		spec = dictGet(dictGet(spec, "jobTemplate"), "spec")
+		return dictGet(dictGet(spec, "template"), "spec")
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		return dictGet(dictGet(spec, "template"), "spec")
+	default:
+		return starlark.None
+	}
+}
+
+func dictGet(v starlark.Value, key string) starlark.Value {
+	dict, ok := v.(*starlark.Dict)
+	if !ok {
+		return starlark.None
+	}
+	val, found, _ := dict.Get(starlark.String(key))
+	if !found {
+		return starlark.None
+	}
+	return val
+}
+
+// toStarlark 将 json 解析的结果转换为 starlark 的值
+func toStarlark(v interface{}) starlark.Value {
+	switch val := v.(type) {
+	case nil:
+		return starlark.None
+	case bool:
+		return starlark.Bool(val)
+	case string:
+		return starlark.String(val)
+	case int64:
+		return starlark.MakeInt64(val)
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < math.MaxInt64 {
+			return starlark.MakeInt64(int64(val))
+		}
+		return starlark.Float(val)
+	case []interface{}:
+		items := make([]starlark.Value, 0, len(val))
+		for _, item := range val {
+			items = append(items, toStarlark(item))
+		}
+		return starlark.NewList(items)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(val))
+		for _, k := range keys {
+			_ = dict.SetKey(starlark.String(k), toStarlark(val[k]))
+		}
+		return dict
+	default:
+		return starlark.String(fmt.Sprint(val))
+	}
+}
+
+func violationsFromStarlark(v starlark.Value) ([]string, error) {
+	switch val := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		if val {
+			return []string{""}, nil
+		}
+		return nil, nil
+	case starlark.String:
+		if val == "" {
+			return nil, nil
+		}
+		return []string{string(val)}, nil
+	case starlark.Indexable:
+		ret := []string{}
+		for i := 0; i < val.Len(); i++ {
+			s, ok := starlark.AsString(val.Index(i))
+			if !ok {
+				return nil, fmt.Errorf("%s() must return a list of strings, got %s", starlarkEntrypoint, val.Index(i).Type())
+			}
+			if s != "" {
+				ret = append(ret, s)
+			}
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("%s() must return a string, list of strings or bool, got %s", starlarkEntrypoint, v.Type())
+	}
+}
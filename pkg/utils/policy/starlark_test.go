@@ -0,0 +1,173 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const (
+	forbidHostPath = `
+def violation(request):
+    spec = pod_spec(request["object"])
+    if not spec:
+        return None
+    return ["volume %s uses hostPath" % v["name"] for v in spec.get("volumes", []) if "hostPath" in v]
+`
+	requireProbes = `
+def violation(request):
+    return ["container %s has no readinessProbe" % c["name"] for c in containers(request["object"]) if "readinessProbe" not in c]
+`
+	restrictRegistry = `
+def violation(request):
+    for c in containers(request["object"]) + init_containers(request["object"]):
+        if not c["image"].startswith("registry.example.com/"):
+            return "image %s is not allowed" % c["image"]
+`
+)
+
+func deployment(podspec map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"kind": "Deployment",
+		"spec": map[string]interface{}{
+			"replicas": float64(1),
+			"template": map[string]interface{}{
+				"spec": podspec,
+			},
+		},
+	}
+}
+
+func TestStarlarkEvaluate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		object  map[string]interface{}
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "hostPath forbidden",
+			rule: forbidHostPath,
+			object: deployment(map[string]interface{}{
+				"volumes": []interface{}{
+					map[string]interface{}{"name": "data", "hostPath": map[string]interface{}{"path": "/data"}},
+					map[string]interface{}{"name": "cache", "emptyDir": map[string]interface{}{}},
+				},
+			}),
+			want: []string{"volume data uses hostPath"},
+		},
+		{
+			name:   "hostPath not pod",
+			rule:   forbidHostPath,
+			object: map[string]interface{}{"kind": "Service"},
+			want:   nil,
+		},
+		{
+			name: "probes required",
+			rule: requireProbes,
+			object: map[string]interface{}{
+				"kind": "Pod",
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "app", "readinessProbe": map[string]interface{}{}},
+						map[string]interface{}{"name": "sidecar"},
+					},
+				},
+			},
+			want: []string{"container sidecar has no readinessProbe"},
+		},
+		{
+			name: "registry allowed",
+			rule: restrictRegistry,
+			object: deployment(map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "registry.example.com/app:v1"},
+				},
+			}),
+			want: nil,
+		},
+		{
+			name: "registry restricted",
+			rule: restrictRegistry,
+			object: deployment(map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "registry.example.com/app:v1"},
+				},
+				"initContainers": []interface{}{
+					map[string]interface{}{"name": "init", "image": "docker.io/busybox"},
+				},
+			}),
+			want: []string{"image docker.io/busybox is not allowed"},
+		},
+		{
+			name:   "bool result",
+			rule:   "def violation(request):\n    return request[\"object\"][\"spec\"][\"replicas\"] > 0\n",
+			object: deployment(nil),
+			want:   []string{""},
+		},
+		{
+			name:    "invalid result",
+			rule:    "def violation(request):\n    return 1\n",
+			object:  deployment(nil),
+			wantErr: true,
+		},
+		{
+			name:    "endless loop",
+			rule:    "def violation(request):\n    for i in range(100000000):\n        pass\n",
+			object:  deployment(nil),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(context.Background(), EngineStarlark, tt.rule, &Request{Object: tt.object})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  string
+		rule    string
+		wantErr bool
+	}{
+		{name: "valid", engine: EngineStarlark, rule: forbidHostPath},
+		{name: "syntax error", engine: EngineStarlark, rule: "def violation(request)\n", wantErr: true},
+		{name: "no entrypoint", engine: EngineStarlark, rule: "x = 1\n", wantErr: true},
+		{name: "valid cel", engine: EngineCEL, rule: restrictRegistryCEL},
+		{name: "default engine", rule: restrictRegistryCEL},
+		{name: "cel syntax error", engine: EngineCEL, rule: "request.object.", wantErr: true},
+		{name: "cel undeclared variable", engine: EngineCEL, rule: "object.kind == 'Pod'", wantErr: true},
+		{name: "unknown engine", engine: "Rego", rule: "true", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.engine, tt.rule); (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
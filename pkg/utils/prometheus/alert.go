@@ -32,6 +32,7 @@ const (
 
 	AlertTypeMonitor = "monitor"
 	AlertTypeLogging = "logging"
+	AlertTypeAnomaly = "anomaly" // 由 worker 检测指标异常后直接发送到 alertmanager
 
 	SeverityLabel    = "severity"
 	SeverityError    = "error"    // 错误
@@ -51,8 +52,9 @@ const (
 	ScopeSystemUser  = "system-user"  // 所有用户
 	ScopeNormal      = "normal"       // 普通租户用户
 
-	SilenceCommentForBlackListPrefix = "fingerprint-"
-	SilenceCommentForAlertrulePrefix = "silence for"
+	SilenceCommentForBlackListPrefix   = "fingerprint-"
+	SilenceCommentForAlertrulePrefix   = "silence for"
+	SilenceCommentForMaintenancePrefix = "maintenance-"
 	// 全局告警命名空间，非此命名空间强制加上namespace筛选
 	GlobalAlertNamespace = gems.NamespaceMonitor
 	// namespace
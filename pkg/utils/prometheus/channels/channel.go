@@ -20,7 +20,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
@@ -194,3 +197,43 @@ func testAlertproxy(u string, alert prometheus.WebhookAlert) error {
 	log.Info("test alertproxy success", "url", u, "resp", string(bts))
 	return nil
 }
+
+const testDialTimeout = 10 * time.Second
+
+// testDialer 测试渠道时由 kubegems 直接发起请求, 只允许访问解析后不是本机、链路本地(如云厂商元数据服务)的地址
+var testDialer = &net.Dialer{
+	Timeout: testDialTimeout,
+	Control: func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if !allowedOutboundIP(net.ParseIP(host)) {
+			return fmt.Errorf("channel target address %s is not allowed", address)
+		}
+		return nil
+	},
+}
+
+func allowedOutboundIP(ip net.IP) bool {
+	return ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+// checkOutboundHost 检查 host:port 解析出的地址是否都允许访问
+func checkOutboundHost(hostport string) error {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return err
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if !allowedOutboundIP(ip) {
+			return fmt.Errorf("channel target address %s(%s) is not allowed", host, ip)
+		}
+	}
+	return nil
+}
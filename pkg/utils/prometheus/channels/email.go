@@ -17,6 +17,9 @@ package channels
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
 	"strings"
 
 	"github.com/emersion/go-sasl"
@@ -77,10 +80,16 @@ func (e *Email) ToReceiver(name string) v1alpha1.Receiver {
 }
 
 func (e *Email) Check() error {
+	if _, _, err := net.SplitHostPort(e.SMTPServer); err != nil {
+		return fmt.Errorf("smtp server %s must be host:port: %w", e.SMTPServer, err)
+	}
 	return nil
 }
 
 func (e *Email) Test(alert prometheus.WebhookAlert) error {
+	if err := checkOutboundHost(e.SMTPServer); err != nil {
+		return err
+	}
 	auth := sasl.NewPlainClient("", e.From, e.AuthPassword)
 	receivers := strings.Split(e.To, ",")
 	buf := bytes.NewBufferString("To: " + e.To + "\r\n" +
@@ -95,6 +104,22 @@ func (e *Email) Test(alert prometheus.WebhookAlert) error {
 	return smtp.SendMail(e.SMTPServer, auth, e.From, receivers, buf)
 }
 
+// SendHTML 使用渠道的 smtp 配置发送 html 邮件, to 为空时发送给渠道配置的收件人
+func (e *Email) SendHTML(to []string, subject, html string) error {
+	if len(to) == 0 {
+		to = strings.Split(e.To, ",")
+	}
+	auth := sasl.NewPlainClient("", e.From, e.AuthPassword)
+	buf := bytes.NewBufferString("From: " + e.From + "\r\n" +
+		"To: " + strings.Join(to, ",") + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=UTF-8\r\n" +
+		"\r\n")
+	buf.WriteString(html)
+	return smtp.SendMail(e.SMTPServer, auth, e.From, to, buf)
+}
+
 func (e *Email) String() string {
 	return e.SMTPServer + e.From + e.To
 }
@@ -21,6 +21,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/pkg/errors"
 	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
@@ -30,6 +31,8 @@ import (
 	"kubegems.io/kubegems/pkg/utils/prometheus"
 )
 
+const webhookTimeout = 30 * time.Second
+
 type Webhook struct {
 	BaseChannel        `json:",inline"`
 	URL                string `json:"url" binding:"required"`
@@ -55,36 +58,47 @@ func (w *Webhook) ToReceiver(name string) v1alpha1.Receiver {
 }
 
 func (w *Webhook) Check() error {
-	if _, err := url.ParseRequestURI(w.URL); err != nil {
+	u, err := url.ParseRequestURI(w.URL)
+	if err != nil {
 		return errors.Wrap(err, "url 不合法")
 	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url 只支持 http 或 https 地址")
+	}
 	return nil
 }
 
 func (w *Webhook) Test(alert prometheus.WebhookAlert) error {
+	resp, err := w.post(alert)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	bts, _ := io.ReadAll(resp.Body)
+	log.Info("test webhook success", "url", w.URL, "resp", string(bts))
+	return nil
+}
+
+func (w *Webhook) post(alert prometheus.WebhookAlert) (*http.Response, error) {
 	buf := bytes.NewBuffer(nil)
 	if err := json.NewEncoder(buf).Encode(alert); err != nil {
-		return err
+		return nil, err
 	}
 	req, err := http.NewRequest(http.MethodPost, w.URL, buf)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	testCli := &http.Client{}
-	if w.InsecureSkipVerify {
-		testCli.Transport = &http.Transport{
+	req.Header.Set("Content-Type", "application/json")
+	cli := &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext: testDialer.DialContext,
 			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
+				InsecureSkipVerify: w.InsecureSkipVerify,
 			},
-		}
+		},
 	}
-	resp, err := testCli.Do(req)
-	if err != nil {
-		return err
-	}
-	bts, _ := io.ReadAll(resp.Body)
-	log.Info("test webhook success", "url", w.URL, "resp", string(bts))
-	return nil
+	return cli.Do(req)
 }
 
 func (w *Webhook) String() string {
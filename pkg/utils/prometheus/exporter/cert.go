@@ -15,26 +15,37 @@
 package exporter
 
 import (
+	"context"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"kubegems.io/kubegems/pkg/agent/cluster"
+	"kubegems.io/kubegems/pkg/apis/networking"
 	"kubegems.io/kubegems/pkg/log"
 	"kubegems.io/kubegems/pkg/utils/clusterinfo"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type CertCollector struct {
-	certExpiredAt *prometheus.Desc
-	mutex         sync.Mutex
+	certExpiredAt        *prometheus.Desc
+	ingressCertExpiredAt *prometheus.Desc
+	ingressCertRenewedAt *prometheus.Desc
+	ingressCertRenewals  *prometheus.Desc
+	clus                 cluster.Interface
+	mutex                sync.Mutex
 }
 
-func NewCertCollectorFunc() func(*log.Logger) (Collector, error) {
+func NewCertCollectorFunc(cluster cluster.Interface) func(*log.Logger) (Collector, error) {
 	return func(logger *log.Logger) (Collector, error) {
-		return NewCertCollector(logger)
+		return NewCertCollector(logger, cluster)
 	}
 }
 
-func NewCertCollector(_ *log.Logger) (Collector, error) {
+func NewCertCollector(_ *log.Logger, clus cluster.Interface) (Collector, error) {
+	ingressCertLabels := []string{"namespace", "secret", "issuer"}
 	c := &CertCollector{
 		certExpiredAt: prometheus.NewDesc(
 			prometheus.BuildFQName(getNamespace(), "cluster_component_cert", "expiration_remain_seconds"),
@@ -42,6 +53,25 @@ func NewCertCollector(_ *log.Logger) (Collector, error) {
 			[]string{"component"},
 			nil,
 		),
+		ingressCertExpiredAt: prometheus.NewDesc(
+			prometheus.BuildFQName(getNamespace(), "ingress_cert", "expiration_remain_seconds"),
+			"Gems auto issued ingress cert expiration remain seconds",
+			ingressCertLabels,
+			nil,
+		),
+		ingressCertRenewedAt: prometheus.NewDesc(
+			prometheus.BuildFQName(getNamespace(), "ingress_cert", "renewed_timestamp_seconds"),
+			"Gems auto issued ingress cert last issued or renewed time",
+			ingressCertLabels,
+			nil,
+		),
+		ingressCertRenewals: prometheus.NewDesc(
+			prometheus.BuildFQName(getNamespace(), "ingress_cert", "renewals"),
+			"Gems auto issued ingress cert renewal count",
+			ingressCertLabels,
+			nil,
+		),
+		clus: clus,
 	}
 	return c, nil
 }
@@ -61,5 +91,29 @@ func (c *CertCollector) Update(ch chan<- prometheus.Metric) error {
 		"apiserver",
 	)
 
+	return c.updateIngressCerts(ch)
+}
+
+// updateIngressCerts 自动签发的 ingress 证书
+func (c *CertCollector) updateIngressCerts(ch chan<- prometheus.Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	secrets := &corev1.SecretList{}
+	if err := c.clus.GetClient().List(ctx, secrets, client.HasLabels{networking.LabelCertificateIssuer}); err != nil {
+		log.Error(err, "list ingress cert secrets failed")
+		return err
+	}
+	for _, secret := range secrets.Items {
+		labels := []string{secret.Namespace, secret.Name, secret.Labels[networking.LabelCertificateIssuer]}
+		if notAfter, err := time.Parse(time.RFC3339, secret.Annotations[networking.AnnotationCertificateNotAfter]); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.ingressCertExpiredAt, prometheus.GaugeValue, time.Until(notAfter).Seconds(), labels...)
+		}
+		if renewedAt, err := time.Parse(time.RFC3339, secret.Annotations[networking.AnnotationCertificateRenewedAt]); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.ingressCertRenewedAt, prometheus.GaugeValue, float64(renewedAt.Unix()), labels...)
+		}
+		renewals, _ := strconv.Atoi(secret.Annotations[networking.AnnotationCertificateRenewals])
+		ch <- prometheus.MustNewConstMetric(c.ingressCertRenewals, prometheus.GaugeValue, float64(renewals), labels...)
+	}
 	return nil
 }
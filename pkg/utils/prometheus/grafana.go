@@ -0,0 +1,563 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"kubegems.io/kubegems/pkg/utils/prometheus/promql"
+	"kubegems.io/kubegems/pkg/utils/prometheus/templates"
+)
+
+const (
+	GrafanaDatasourceInput = "DS_PROMETHEUS"
+	grafanaSchemaVersion   = 36
+	// 变量引用在解析 promql 前替换成的占位符
+	grafanaVarPlaceholder = "__grafana_var_%s__"
+)
+
+var (
+	// $var, ${var}, ${var:format}, [[var]]
+	grafanaVarReg = regexp.MustCompile(`\$\{(\w+)(?::[^}]*)?\}|\$(\w+)|\[\[(\w+)(?::[^\]]*)?\]\]`)
+
+	grafanaSupportedPanels = map[string]bool{
+		"timeseries": true,
+		"graph":      true,
+		"stat":       true,
+		"singlestat": true,
+	}
+
+	// kubegems 单位 -> grafana 单位
+	grafanaUnits = map[string]string{
+		"short":           "short",
+		"bytes-B":         "bytes",
+		"bytes-KB":        "kbytes",
+		"bytes-MB":        "mbytes",
+		"bytes-GB":        "gbytes",
+		"bytes-TB":        "tbytes",
+		"bytes-PB":        "pbytes",
+		"bytes/sec-B/s":   "Bps",
+		"bytes/sec-KB/s":  "KBs",
+		"bytes/sec-MB/s":  "MBs",
+		"bytes/sec-GB/s":  "GBs",
+		"bytes/sec-TB/s":  "TBs",
+		"bytes/sec-PB/s":  "PBs",
+		"duration-us":     "µs",
+		"duration-ms":     "ms",
+		"duration-s":      "s",
+		"duration-m":      "m",
+		"duration-h":      "h",
+		"duration-d":      "d",
+		"percent-0.0-1.0": "percentunit",
+		"percent-0-100":   "percent",
+	}
+)
+
+// GrafanaDashboard grafana dashboard json, 只包含转换需要的字段
+type GrafanaDashboard struct {
+	Inputs        []GrafanaInput    `json:"__inputs,omitempty"`
+	Title         string            `json:"title"`
+	Description   string            `json:"description,omitempty"`
+	Tags          []string          `json:"tags,omitempty"`
+	Editable      bool              `json:"editable"`
+	Refresh       interface{}       `json:"refresh,omitempty"` // string 或 false
+	Time          *GrafanaTimeRange `json:"time,omitempty"`
+	Templating    GrafanaTemplating `json:"templating"`
+	Panels        []GrafanaPanel    `json:"panels"`
+	Rows          []GrafanaRow      `json:"rows,omitempty"` // schemaVersion < 16 的旧格式
+	SchemaVersion int               `json:"schemaVersion"`
+}
+
+type GrafanaInput struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	PluginID string `json:"pluginId"`
+}
+
+type GrafanaTimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type GrafanaTemplating struct {
+	List []GrafanaVariable `json:"list"`
+}
+
+type GrafanaVariable struct {
+	Name       string                  `json:"name"`
+	Label      string                  `json:"label,omitempty"`
+	Type       string                  `json:"type"`
+	Datasource interface{}             `json:"datasource,omitempty"`
+	Query      interface{}             `json:"query,omitempty"` // string 或 {"query": "..."}
+	Definition string                  `json:"definition,omitempty"`
+	Current    *GrafanaVariableCurrent `json:"current,omitempty"`
+	IncludeAll bool                    `json:"includeAll"`
+	AllValue   string                  `json:"allValue,omitempty"`
+	Multi      bool                    `json:"multi"`
+	Refresh    int                     `json:"refresh,omitempty"`
+}
+
+type GrafanaVariableCurrent struct {
+	Text  interface{} `json:"text"`
+	Value interface{} `json:"value"` // string 或 []string
+}
+
+type GrafanaPanel struct {
+	ID          int                 `json:"id"`
+	Type        string              `json:"type"`
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	Datasource  interface{}         `json:"datasource,omitempty"`
+	GridPos     *GrafanaGridPos     `json:"gridPos,omitempty"`
+	Targets     []GrafanaTarget     `json:"targets,omitempty"`
+	FieldConfig *GrafanaFieldConfig `json:"fieldConfig,omitempty"`
+	Yaxes       []GrafanaYaxis      `json:"yaxes,omitempty"`  // graph
+	Format      string              `json:"format,omitempty"` // singlestat
+	Collapsed   bool                `json:"collapsed,omitempty"`
+	Panels      []GrafanaPanel      `json:"panels,omitempty"` // 折叠的 row
+}
+
+type GrafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type GrafanaTarget struct {
+	RefID        string      `json:"refId"`
+	Expr         string      `json:"expr"`
+	LegendFormat string      `json:"legendFormat,omitempty"`
+	Hide         bool        `json:"hide,omitempty"`
+	Datasource   interface{} `json:"datasource,omitempty"`
+}
+
+type GrafanaFieldConfig struct {
+	Defaults  GrafanaFieldDefaults `json:"defaults"`
+	Overrides []interface{}        `json:"overrides"`
+}
+
+type GrafanaFieldDefaults struct {
+	Unit string `json:"unit,omitempty"`
+}
+
+type GrafanaYaxis struct {
+	Format string `json:"format"`
+}
+
+type GrafanaRow struct {
+	Title  string         `json:"title"`
+	Panels []GrafanaPanel `json:"panels"`
+}
+
+// GrafanaUnsupportedPanel 导入时无法转换(或部分无法转换)的面板
+type GrafanaUnsupportedPanel struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// DashboardContent MonitorDashboard 和 MonitorDashboardTpl 共有的内容
+type DashboardContent struct {
+	Title       string
+	Description string
+	Refresh     string
+	Start       string
+	End         string
+	Graphs      MonitorGraphs
+	Variables   map[string]string
+}
+
+// ParseGrafanaDashboard 解析 grafana dashboard json, 兼容 grafana api 返回的 {"dashboard": {...}} 格式
+func ParseGrafanaDashboard(data []byte) (*GrafanaDashboard, error) {
+	wrapper := struct {
+		Dashboard *GrafanaDashboard `json:"dashboard"`
+	}{}
+	if err := json.Unmarshal(data, &wrapper); err == nil && wrapper.Dashboard != nil {
+		return wrapper.Dashboard, nil
+	}
+	dash := &GrafanaDashboard{}
+	if err := json.Unmarshal(data, dash); err != nil {
+		return nil, errors.Wrap(err, "parse grafana dashboard")
+	}
+	return dash, nil
+}
+
+type grafanaVars struct {
+	// 直接替换的变量, interval/constant/custom/textbox 以及内置变量
+	consts map[string]string
+	// query 类型的变量(如 label_values), 转换为 kubegems 的变量
+	labels map[string]string
+}
+
+func newGrafanaVars(dash *GrafanaDashboard) *grafanaVars {
+	vars := &grafanaVars{
+		consts: map[string]string{
+			"__interval":      "1m",
+			"__rate_interval": "5m",
+			"__range":         "30m",
+		},
+		labels: map[string]string{},
+	}
+	if dash.Time != nil && dash.Time.To == "now" && strings.HasPrefix(dash.Time.From, "now-") {
+		if _, err := prommodel.ParseDuration(strings.TrimPrefix(dash.Time.From, "now-")); err == nil {
+			vars.consts["__range"] = strings.TrimPrefix(dash.Time.From, "now-")
+		}
+	}
+	for _, v := range dash.Templating.List {
+		switch v.Type {
+		case "query":
+			vars.labels[v.Name] = v.currentValue()
+		case "interval", "constant", "custom", "textbox":
+			value := v.currentValue()
+			if v.Type == "constant" && value == "" {
+				value = v.queryString()
+			}
+			vars.consts[v.Name] = value
+		}
+	}
+	return vars
+}
+
+func (v GrafanaVariable) queryString() string {
+	switch q := v.Query.(type) {
+	case string:
+		return q
+	case map[string]interface{}:
+		if s, ok := q["query"].(string); ok {
+			return s
+		}
+	}
+	return v.Definition
+}
+
+// currentValue 变量的当前值, 多选时转换为正则, 全选时为空
+func (v GrafanaVariable) currentValue() string {
+	if v.Current == nil {
+		return ""
+	}
+	values := []string{}
+	switch val := v.Current.Value.(type) {
+	case string:
+		values = append(values, val)
+	case []interface{}:
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	for _, s := range values {
+		if s == "$__all" {
+			return ""
+		}
+	}
+	return strings.Join(values, "|")
+}
+
+// convertExpr 替换 grafana 变量, 删除引用了 label 变量的 matcher, 返回新的 promql 和用到的 kubegems 变量
+func (vars *grafanaVars) convertExpr(expr string) (string, map[string]string, error) {
+	unknown := []string{}
+	expr = grafanaVarReg.ReplaceAllStringFunc(expr, func(s string) string {
+		sub := grafanaVarReg.FindStringSubmatch(s)
+		name := sub[1] + sub[2] + sub[3]
+		if value, ok := vars.consts[name]; ok {
+			return value
+		}
+		if _, ok := vars.labels[name]; ok {
+			return fmt.Sprintf(grafanaVarPlaceholder, name)
+		}
+		unknown = append(unknown, name)
+		return s
+	})
+	if len(unknown) > 0 {
+		return "", nil, errors.Errorf("unknown variables: %s", strings.Join(unknown, ", "))
+	}
+	parsed, err := parser.ParseExpr(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	used := map[string]string{}
+	var inspectErr error
+	parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		matchers := []*labels.Matcher{}
+		for _, m := range vs.LabelMatchers {
+			name, ok := vars.placeholderIn(m.Value)
+			if !ok {
+				matchers = append(matchers, m)
+				continue
+			}
+			// 由 kubegems 的变量按 label 过滤
+			if _, exist := used[m.Name]; !exist {
+				used[m.Name] = vars.labels[name]
+			}
+		}
+		if len(matchers) == 0 {
+			inspectErr = errors.Errorf("selector %s has no matchers without variables", vs.String())
+		}
+		vs.LabelMatchers = matchers
+		return nil
+	})
+	if inspectErr != nil {
+		return "", nil, inspectErr
+	}
+	ret := parsed.String()
+	if strings.Contains(ret, "__grafana_var_") {
+		return "", nil, errors.New("variables can only be used in label matchers")
+	}
+	return ret, used, nil
+}
+
+func (vars *grafanaVars) placeholderIn(value string) (string, bool) {
+	for name := range vars.labels {
+		if strings.Contains(value, fmt.Sprintf(grafanaVarPlaceholder, name)) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func grafanaDatasourceType(ds interface{}) string {
+	if m, ok := ds.(map[string]interface{}); ok {
+		if t, ok := m["type"].(string); ok {
+			return t
+		}
+	}
+	return ""
+}
+
+func (p GrafanaPanel) unit() string {
+	unit := ""
+	switch {
+	case p.FieldConfig != nil && p.FieldConfig.Defaults.Unit != "":
+		unit = p.FieldConfig.Defaults.Unit
+	case len(p.Yaxes) > 0:
+		unit = p.Yaxes[0].Format
+	default:
+		unit = p.Format
+	}
+	if unit == "" || unit == "none" || unit == "short" {
+		return ""
+	}
+	for k, v := range grafanaUnits {
+		if v == unit {
+			return k
+		}
+	}
+	return "custom-" + unit
+}
+
+// ImportGrafanaDashboard 将 grafana dashboard 转换为 kubegems 的图表,
+// 只支持 prometheus 数据源的 timeseries/graph/stat 面板, 其余面板在返回值中说明原因
+func ImportGrafanaDashboard(dash *GrafanaDashboard) (*DashboardContent, []GrafanaUnsupportedPanel) {
+	ret := &DashboardContent{
+		Title:       dash.Title,
+		Description: dash.Description,
+		Graphs:      MonitorGraphs{},
+		Variables:   map[string]string{},
+	}
+	if refresh, ok := dash.Refresh.(string); ok {
+		ret.Refresh = refresh
+	}
+	if dash.Time != nil {
+		ret.Start, ret.End = dash.Time.From, dash.Time.To
+	}
+	panels := dash.Panels
+	for _, row := range dash.Rows {
+		panels = append(panels, row.Panels...)
+	}
+
+	vars := newGrafanaVars(dash)
+	unsupported := []GrafanaUnsupportedPanel{}
+	var convert func(panels []GrafanaPanel)
+	convert = func(panels []GrafanaPanel) {
+		for _, panel := range panels {
+			if panel.Type == "row" {
+				convert(panel.Panels)
+				continue
+			}
+			report := func(reason string) {
+				unsupported = append(unsupported, GrafanaUnsupportedPanel{
+					ID: panel.ID, Title: panel.Title, Type: panel.Type, Reason: reason,
+				})
+			}
+			if !grafanaSupportedPanels[panel.Type] {
+				report(fmt.Sprintf("panel type %s is not supported", panel.Type))
+				continue
+			}
+			if t := grafanaDatasourceType(panel.Datasource); t != "" && t != "prometheus" && t != "datasource" {
+				report(fmt.Sprintf("datasource type %s is not supported", t))
+				continue
+			}
+			graph := MetricGraph{Name: panel.Title, Unit: panel.unit()}
+			if graph.Name == "" {
+				graph.Name = fmt.Sprintf("Panel %d", panel.ID)
+			}
+			reasons := []string{}
+			for i, target := range panel.Targets {
+				if target.Hide {
+					continue
+				}
+				if target.RefID == "" {
+					target.RefID = string(rune('A' + i%26))
+				}
+				if t := grafanaDatasourceType(target.Datasource); t != "" && t != "prometheus" && t != "datasource" {
+					reasons = append(reasons, fmt.Sprintf("target %s: datasource type %s is not supported", target.RefID, t))
+					continue
+				}
+				if target.Expr == "" {
+					reasons = append(reasons, fmt.Sprintf("target %s: promql is empty", target.RefID))
+					continue
+				}
+				expr, used, err := vars.convertExpr(target.Expr)
+				if err != nil {
+					reasons = append(reasons, fmt.Sprintf("target %s: %v", target.RefID, err))
+					continue
+				}
+				for k, v := range used {
+					if _, exist := ret.Variables[k]; !exist {
+						ret.Variables[k] = v
+					}
+				}
+				graph.Targets = append(graph.Targets, Target{TargetName: target.RefID, Expr: expr})
+			}
+			if len(reasons) > 0 {
+				report(strings.Join(reasons, "; "))
+			}
+			if len(graph.Targets) > 0 {
+				ret.Graphs = append(ret.Graphs, graph)
+			}
+		}
+	}
+	convert(panels)
+	return ret, unsupported
+}
+
+// ExportGrafanaDashboard 将 kubegems 的图表导出为 grafana dashboard, 模板生成的 promql 会展开,
+// kubegems 的变量导出为 label_values 变量并添加到所有的 selector 上
+func ExportGrafanaDashboard(content *DashboardContent, namespace string, tplGetter templates.TplGetter) (*GrafanaDashboard, error) {
+	ds := map[string]interface{}{"type": "prometheus", "uid": "${" + GrafanaDatasourceInput + "}"}
+	ret := &GrafanaDashboard{
+		Inputs: []GrafanaInput{{
+			Name:     GrafanaDatasourceInput,
+			Label:    "Prometheus",
+			Type:     "datasource",
+			PluginID: "prometheus",
+		}},
+		Title:         content.Title,
+		Description:   content.Description,
+		Tags:          []string{"kubegems"},
+		Editable:      true,
+		Refresh:       content.Refresh,
+		Time:          &GrafanaTimeRange{From: content.Start, To: content.End},
+		Templating:    GrafanaTemplating{List: []GrafanaVariable{}},
+		Panels:        []GrafanaPanel{},
+		SchemaVersion: grafanaSchemaVersion,
+	}
+	if ret.Time.From == "" || ret.Time.To == "" {
+		ret.Time = &GrafanaTimeRange{From: "now-30m", To: "now"}
+	}
+
+	varNames := []string{}
+	for k := range content.Variables {
+		varNames = append(varNames, k)
+	}
+	sort.Strings(varNames)
+	varMatchers := []*labels.Matcher{}
+	for _, name := range varNames {
+		value := content.Variables[name]
+		if value == "" {
+			value = "$__all"
+		}
+		query := fmt.Sprintf("label_values(%s)", name)
+		ret.Templating.List = append(ret.Templating.List, GrafanaVariable{
+			Name:       name,
+			Label:      name,
+			Type:       "query",
+			Datasource: ds,
+			Query:      map[string]interface{}{"query": query, "refId": "StandardVariableQuery"},
+			Definition: query,
+			Current:    &GrafanaVariableCurrent{Text: value, Value: value},
+			IncludeAll: true,
+			AllValue:   ".*",
+			Refresh:    2,
+		})
+		varMatchers = append(varMatchers, &labels.Matcher{Type: labels.MatchRegexp, Name: name, Value: "$" + name})
+	}
+
+	for i, graph := range content.Graphs {
+		panel := GrafanaPanel{
+			ID:          i + 1,
+			Type:        "timeseries",
+			Title:       graph.Name,
+			Datasource:  ds,
+			GridPos:     &GrafanaGridPos{H: 8, W: 12, X: (i % 2) * 12, Y: (i / 2) * 8},
+			FieldConfig: &GrafanaFieldConfig{Overrides: []interface{}{}},
+		}
+		if unit, ok := grafanaUnits[graph.Unit]; ok {
+			panel.FieldConfig.Defaults.Unit = unit
+		} else if strings.HasPrefix(graph.Unit, "custom-") {
+			panel.FieldConfig.Defaults.Unit = "suffix:" + strings.TrimPrefix(graph.Unit, "custom-")
+		}
+		for _, target := range graph.Targets {
+			query, err := exportTargetQuery(target, namespace, tplGetter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "graph %s target %s", graph.Name, target.TargetName)
+			}
+			query.AddLabelMatchers(varMatchers...)
+			panel.Targets = append(panel.Targets, GrafanaTarget{
+				RefID:      target.TargetName,
+				Expr:       query.String(),
+				Datasource: ds,
+			})
+		}
+		ret.Panels = append(ret.Panels, panel)
+	}
+	return ret, nil
+}
+
+func exportTargetQuery(target Target, namespace string, tplGetter templates.TplGetter) (*promql.Query, error) {
+	if target.PromqlGenerator.Notpl() {
+		return promql.New(target.Expr)
+	}
+	if err := target.PromqlGenerator.SetTpl(tplGetter); err != nil {
+		return nil, err
+	}
+	query, err := promql.New(target.PromqlGenerator.Tpl.Expr)
+	if err != nil {
+		return nil, err
+	}
+	if namespace != "" && target.PromqlGenerator.Tpl.Namespaced {
+		query.AddLabelMatchers(&labels.Matcher{Type: labels.MatchEqual, Name: PromqlNamespaceKey, Value: namespace})
+	}
+	for k, v := range target.PromqlGenerator.LabelPairs {
+		query.AddLabelMatchers(&labels.Matcher{Type: labels.MatchRegexp, Name: k, Value: v})
+	}
+	return query.Sumby(target.PromqlGenerator.Tpl.Labels...), nil
+}
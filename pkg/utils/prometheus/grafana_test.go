@@ -0,0 +1,130 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testGrafanaDashboard = `{
+  "dashboard": {
+    "title": "Node",
+    "refresh": false,
+    "time": {"from": "now-1h", "to": "now"},
+    "templating": {"list": [
+      {"name": "DS", "type": "datasource", "query": "prometheus"},
+      {"name": "instance", "type": "query", "query": {"query": "label_values(up, instance)"}, "current": {"value": "$__all"}},
+      {"name": "job", "type": "query", "query": "label_values(job)", "current": {"value": ["node", "kubelet"]}},
+      {"name": "window", "type": "interval", "current": {"value": "10m"}}
+    ]},
+    "panels": [
+      {"id": 1, "type": "timeseries", "title": "CPU", "fieldConfig": {"defaults": {"unit": "percentunit"}},
+       "targets": [
+         {"refId": "A", "expr": "rate(node_cpu_seconds_total{mode!=\"idle\",instance=~\"$instance\"}[$__rate_interval])"},
+         {"refId": "B", "expr": "", "hide": true}
+       ]},
+      {"id": 2, "type": "row", "collapsed": true, "panels": [
+        {"id": 3, "type": "graph", "title": "Load", "yaxes": [{"format": "short"}],
+         "targets": [{"refId": "A", "expr": "avg_over_time(node_load1{job=~\"${job:regex}\"}[$window])"}]},
+        {"id": 4, "type": "table", "title": "Table", "targets": [{"refId": "A", "expr": "up"}]}
+      ]},
+      {"id": 5, "type": "stat", "title": "Up",
+       "targets": [
+         {"refId": "A", "expr": "up"},
+         {"refId": "B", "expr": "sum by ($label) (up)"}
+       ]},
+      {"id": 6, "type": "timeseries", "title": "Logs", "datasource": {"type": "loki", "uid": "x"},
+       "targets": [{"refId": "A", "expr": "rate({app=\"x\"}[5m])"}]}
+    ]
+  }
+}`
+
+func TestImportGrafanaDashboard(t *testing.T) {
+	dash, err := ParseGrafanaDashboard([]byte(testGrafanaDashboard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, unsupported := ImportGrafanaDashboard(dash)
+	wantGraphs := MonitorGraphs{
+		{
+			Name:    "CPU",
+			Unit:    "percent-0.0-1.0",
+			Targets: []Target{{TargetName: "A", Expr: `rate(node_cpu_seconds_total{mode!="idle"}[5m])`}},
+		},
+		{
+			Name:    "Load",
+			Targets: []Target{{TargetName: "A", Expr: `avg_over_time(node_load1[10m])`}},
+		},
+		{
+			Name:    "Up",
+			Targets: []Target{{TargetName: "A", Expr: `up`}},
+		},
+	}
+	if !reflect.DeepEqual(got.Graphs, wantGraphs) {
+		t.Errorf("ImportGrafanaDashboard() graphs = %+v, want %+v", got.Graphs, wantGraphs)
+	}
+	wantVars := map[string]string{"instance": "", "job": "node|kubelet"}
+	if !reflect.DeepEqual(got.Variables, wantVars) {
+		t.Errorf("ImportGrafanaDashboard() variables = %v, want %v", got.Variables, wantVars)
+	}
+	if got.Refresh != "" || got.Start != "now-1h" || got.End != "now" {
+		t.Errorf("ImportGrafanaDashboard() refresh/start/end = %s/%s/%s", got.Refresh, got.Start, got.End)
+	}
+	wantUnsupported := []int{4, 5, 6}
+	if len(unsupported) != len(wantUnsupported) {
+		t.Fatalf("ImportGrafanaDashboard() unsupported = %+v, want panels %v", unsupported, wantUnsupported)
+	}
+	for i, id := range wantUnsupported {
+		if unsupported[i].ID != id {
+			t.Errorf("ImportGrafanaDashboard() unsupported[%d] = %+v, want panel %d", i, unsupported[i], id)
+		}
+	}
+}
+
+func TestExportGrafanaDashboard(t *testing.T) {
+	content := &DashboardContent{
+		Title: "Node",
+		Graphs: MonitorGraphs{{
+			Name:    "CPU",
+			Unit:    "percent-0.0-1.0",
+			Targets: []Target{{TargetName: "A", Expr: `rate(node_cpu_seconds_total{mode!="idle"}[5m])`}},
+		}},
+		Variables: map[string]string{"instance": ""},
+	}
+	got, err := ExportGrafanaDashboard(content, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Panels) != 1 || len(got.Panels[0].Targets) != 1 {
+		t.Fatalf("ExportGrafanaDashboard() panels = %+v", got.Panels)
+	}
+	wantExpr := `rate(node_cpu_seconds_total{instance=~"$instance",mode!="idle"}[5m])`
+	if expr := got.Panels[0].Targets[0].Expr; expr != wantExpr {
+		t.Errorf("ExportGrafanaDashboard() expr = %s, want %s", expr, wantExpr)
+	}
+	if unit := got.Panels[0].FieldConfig.Defaults.Unit; unit != "percentunit" {
+		t.Errorf("ExportGrafanaDashboard() unit = %s, want percentunit", unit)
+	}
+
+	// 导出后再导入应该得到相同的图表和变量
+	back, unsupported := ImportGrafanaDashboard(got)
+	if len(unsupported) > 0 {
+		t.Errorf("ImportGrafanaDashboard() unsupported = %+v", unsupported)
+	}
+	if !reflect.DeepEqual(back.Graphs, content.Graphs) || !reflect.DeepEqual(back.Variables, content.Variables) {
+		t.Errorf("round trip = %+v %v, want %+v %v", back.Graphs, back.Variables, content.Graphs, content.Variables)
+	}
+}
@@ -184,6 +184,15 @@ func ResourceEnough(total, used, need corev1.ResourceList) (bool, []string) {
 }
 
 func ResourceIsEnough(total, used, need corev1.ResourceList, resources []corev1.ResourceName) (bool, []string) {
+	return resourceIsEnough("tenant", total, used, need, resources)
+}
+
+// ProjectResourceIsEnough 项目的资源是否足够
+func ProjectResourceIsEnough(total, used, need corev1.ResourceList, resources []corev1.ResourceName) (bool, []string) {
+	return resourceIsEnough("project", total, used, need, resources)
+}
+
+func resourceIsEnough(scope string, total, used, need corev1.ResourceList, resources []corev1.ResourceName) (bool, []string) {
 	ret := true
 	msgs := []string{}
 	for _, resource := range resources {
@@ -198,7 +207,7 @@ func ResourceIsEnough(total, used, need corev1.ResourceList, resources []corev1.
 		if tmp.Cmp(needv) == -1 {
 			l, _ := tmp.MarshalJSON()
 			n, _ := needv.MarshalJSON()
-			msg := fmt.Sprintf("%s not enough to apply, tenant left %s but need %s", resource, string(l), string(n))
+			msg := fmt.Sprintf("%s not enough to apply, %s left %s but need %s", resource, scope, string(l), string(n))
 			msgs = append(msgs, msg)
 			ret = false
 		}
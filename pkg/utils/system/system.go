@@ -26,6 +26,8 @@ type Options struct {
 	CAFile   string `json:"caFile,omitempty" description:"ca file path"`
 	CertFile string `json:"certFile,omitempty" description:"cert file path"`
 	KeyFile  string `json:"keyFile,omitempty" description:"key file path"`
+	// 为空时不信任任何代理, 来源 IP 只取连接地址
+	TrustedProxies []string `json:"trustedProxies,omitempty" description:"trusted proxy ips or cidrs, X-Forwarded-For is only honored from these proxies"`
 }
 
 func NewDefaultOptions() *Options {
@@ -45,6 +45,7 @@ type Step struct {
 	Args     []interface{} `json:"args,omitempty"`     // 对应的参数
 	SubSteps []Step        `json:"subSteps,omitempty"` // 子任务
 	Status   *TaskStatus   `json:"status,omitempty"`
+	Timeout  time.Duration `json:"timeout,omitempty"` // 任务执行超时, 默认为 DefaultTaskTimeout
 }
 
 type jsonArgsTask struct {
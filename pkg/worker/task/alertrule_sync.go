@@ -80,7 +80,8 @@ func (s *AlertRuleSyncTasker) Crontasks() map[string]Task {
 
 func (t *AlertRuleSyncTasker) SyncAlertRuleState(ctx context.Context) error {
 	alertrules := []*models.AlertRule{}
-	if err := t.DB.DB().Find(&alertrules).Error; err != nil {
+	// 异常告警的状态由异常检测任务更新
+	if err := t.DB.DB().Find(&alertrules, "alert_type <> ?", prometheus.AlertTypeAnomaly).Error; err != nil {
 		return err
 	}
 	alertStatusMap := sync.Map{} // key: cluster/gems-namespace-name
@@ -125,7 +126,8 @@ func (t *AlertRuleSyncTasker) SyncAlertRuleState(ctx context.Context) error {
 
 func (t *AlertRuleSyncTasker) CheckAlertRuleConfig(ctx context.Context) error {
 	alertrules := []*models.AlertRule{}
-	if err := t.DB.DB().Preload("Receivers.AlertChannel").Find(&alertrules).Error; err != nil {
+	// 异常告警没有对应的 rule group, 只同步 AlertmanagerConfig
+	if err := t.DB.DB().Preload("Receivers.AlertChannel").Find(&alertrules, "alert_type <> ?", prometheus.AlertTypeAnomaly).Error; err != nil {
 		return err
 	}
 	allK8sAlertCfg := sync.Map{}
@@ -0,0 +1,300 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prommodel "github.com/prometheus/common/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/service/observe"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/database"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+)
+
+const (
+	TaskFunction_DetectAnomalies = "detect-anomalies"
+
+	anomalyTimeLayout  = "2006-01-02T15:04:05Z"
+	anomalyDetectStep  = time.Minute
+	anomalyMaxSeries   = 200
+	anomalyBatchSize   = 500
+	anomalyStateFiring = "firing"
+	anomalyStateNormal = "inactive"
+	// 告警的有效期, 超过该时间没有再次检测到异常时 alertmanager 自动恢复
+	anomalyAlertTTL = 3 * time.Minute
+)
+
+type AnomalyDetectionTasker struct {
+	DB *database.Database
+	cs *agents.ClientSet
+}
+
+func (t *AnomalyDetectionTasker) ProvideFuntions() map[string]interface{} {
+	return map[string]interface{}{
+		TaskFunction_DetectAnomalies: t.DetectAnomalies,
+	}
+}
+
+func (t *AnomalyDetectionTasker) Crontasks() map[string]Task {
+	return map[string]Task{
+		"@every 1m": {
+			Name:  "detect anomalies",
+			Group: "anomaly",
+			Steps: []workflow.Step{{Function: TaskFunction_DetectAnomalies}},
+		},
+	}
+}
+
+// DetectAnomalies 学习过期的基线, 并检测当前值是否偏离基线
+func (t *AnomalyDetectionTasker) DetectAnomalies(ctx context.Context) error {
+	detectors := []*models.AnomalyDetector{}
+	if err := t.DB.DB().WithContext(ctx).Preload("AlertRule").Find(&detectors, "enabled = ?", true).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, d := range detectors {
+		if d.AlertRule == nil {
+			continue
+		}
+		lastError := ""
+		if err := t.detectAnomaly(ctx, d, now); err != nil {
+			log.Error(err, "detect anomaly", "cluster", d.Cluster, "namespace", d.Namespace, "name", d.Name)
+			lastError = err.Error()
+		}
+		if lastError != d.LastError {
+			if err := t.DB.DB().WithContext(ctx).Model(d).Omit(clause.Associations).Update("last_error", lastError).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *AnomalyDetectionTasker) detectAnomaly(ctx context.Context, d *models.AnomalyDetector, now time.Time) error {
+	cli, err := t.cs.ClientOf(ctx, d.Cluster)
+	if err != nil {
+		return err
+	}
+	if d.NeedTrain(now) {
+		if err := t.trainAnomalyBaselines(ctx, cli, d, now); err != nil {
+			return errors.Wrap(err, "train baselines")
+		}
+	}
+	if d.State != models.AnomalyStateReady {
+		return nil
+	}
+	alerts, err := t.evaluateAnomaly(ctx, cli, d, now)
+	if err != nil {
+		return errors.Wrap(err, "evaluate")
+	}
+	if len(alerts) > 0 {
+		if err := observe.NewClient(cli, t.DB.DB()).PostAlerts(ctx, alerts); err != nil {
+			return err
+		}
+	}
+	state := anomalyStateNormal
+	if len(alerts) > 0 {
+		state = anomalyStateFiring
+	}
+	if d.AlertRule.State != state {
+		return t.DB.DB().WithContext(ctx).Model(d.AlertRule).Omit(clause.Associations).Update("state", state).Error
+	}
+	return nil
+}
+
+type anomalySeries struct {
+	labels  map[string]string
+	samples []prommodel.SamplePair
+}
+
+func anomalySeriesLabels(metric prommodel.Metric) map[string]string {
+	ret := map[string]string{}
+	for k, v := range metric {
+		if k != prommodel.MetricNameLabel {
+			ret[string(k)] = string(v)
+		}
+	}
+	return ret
+}
+
+// trainAnomalyBaselines 按周查询最近几周的数据, 避免单次查询的点数超过 prometheus 的限制
+func (t *AnomalyDetectionTasker) trainAnomalyBaselines(ctx context.Context, cli agents.Client, d *models.AnomalyDetector, now time.Time) error {
+	series := map[string]*anomalySeries{}
+	end := now.Truncate(models.AnomalyBaselineStep)
+	step := strconv.Itoa(int(models.AnomalyBaselineStep.Seconds()))
+	for week := d.Weeks; week > 0; week-- {
+		start := end.AddDate(0, 0, -7*week)
+		stop := start.AddDate(0, 0, 7).Add(-models.AnomalyBaselineStep)
+		matrix, err := cli.Extend().PrometheusQueryRange(ctx, d.AlertRule.Expr,
+			start.UTC().Format(anomalyTimeLayout), stop.UTC().Format(anomalyTimeLayout), step)
+		if err != nil {
+			return err
+		}
+		for _, stream := range matrix {
+			key := models.AnomalySeriesKey(stream.Metric)
+			s, ok := series[key]
+			if !ok {
+				if len(series) >= anomalyMaxSeries {
+					return errors.Errorf("too many series, at most %d series are supported", anomalyMaxSeries)
+				}
+				s = &anomalySeries{labels: anomalySeriesLabels(stream.Metric)}
+				series[key] = s
+			}
+			s.samples = append(s.samples, stream.Values...)
+		}
+	}
+
+	baselines := []*models.AnomalyBaseline{}
+	state := models.AnomalyStateLearning
+	for key, s := range series {
+		for _, b := range models.LearnAnomalyBaselines(s.samples) {
+			b.DetectorID = d.ID
+			b.SeriesKey = key
+			b.Labels = s.labels
+			b.UpdatedAt = &now
+			if b.Ready() {
+				state = models.AnomalyStateReady
+			}
+			baselines = append(baselines, b)
+		}
+	}
+	err := t.DB.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("detector_id = ?", d.ID).Delete(&models.AnomalyBaseline{}).Error; err != nil {
+			return err
+		}
+		if len(baselines) > 0 {
+			if err := tx.CreateInBatches(baselines, anomalyBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(d).Omit(clause.Associations).Updates(map[string]interface{}{
+			"state":      state,
+			"trained_at": now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	d.State, d.TrainedAt = state, &now
+	return nil
+}
+
+// evaluateAnomaly 查询持续时间内的数据, 所有的点都偏离基线时产生告警
+func (t *AnomalyDetectionTasker) evaluateAnomaly(ctx context.Context, cli agents.Client, d *models.AnomalyDetector, now time.Time) ([]observe.PostableAlert, error) {
+	dur, err := prommodel.ParseDuration(d.For)
+	if err != nil {
+		return nil, err
+	}
+	start := now.Add(-time.Duration(dur))
+	matrix, err := cli.Extend().PrometheusQueryRange(ctx, d.AlertRule.Expr,
+		start.UTC().Format(anomalyTimeLayout), now.UTC().Format(anomalyTimeLayout), strconv.Itoa(int(anomalyDetectStep.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+	baselines := []*models.AnomalyBaseline{}
+	if err := t.DB.DB().WithContext(ctx).Find(&baselines, "detector_id = ?", d.ID).Error; err != nil {
+		return nil, err
+	}
+	baselineMap := map[string]map[int]*models.AnomalyBaseline{} // series key -> hour of week -> baseline
+	for _, b := range baselines {
+		if baselineMap[b.SeriesKey] == nil {
+			baselineMap[b.SeriesKey] = map[int]*models.AnomalyBaseline{}
+		}
+		baselineMap[b.SeriesKey][b.HourOfWeek] = b
+	}
+
+	alerts := []observe.PostableAlert{}
+	for _, stream := range matrix {
+		hours := baselineMap[models.AnomalySeriesKey(stream.Metric)]
+		// 新出现的序列或者数据不完整时不检测
+		if hours == nil || len(stream.Values) == 0 || stream.Values[0].Timestamp.Time().After(start.Add(anomalyDetectStep)) {
+			continue
+		}
+		anomalous := true
+		var (
+			last     prommodel.SamplePair
+			baseline *models.AnomalyBaseline
+			score    float64
+		)
+		for _, v := range stream.Values {
+			baseline = hours[models.AnomalyHourOfWeek(v.Timestamp.Time())]
+			if baseline == nil || !baseline.Ready() {
+				anomalous = false
+				break
+			}
+			score = baseline.Score(float64(v.Value))
+			if !d.IsAnomaly(score) {
+				anomalous = false
+				break
+			}
+			last = v
+		}
+		if anomalous {
+			alerts = append(alerts, anomalyAlert(d, anomalySeriesLabels(stream.Metric), float64(last.Value), baseline, score, start, now))
+		}
+	}
+	return alerts, nil
+}
+
+// anomalyAlert 生成发送到 alertmanager 的告警, 带有告警规则的标签以匹配 AlertmanagerConfig 中的路由
+func anomalyAlert(d *models.AnomalyDetector, labels map[string]string, value float64, baseline *models.AnomalyBaseline,
+	score float64, start, now time.Time,
+) observe.PostableAlert {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "%s: [cluster:%s] ", d.Name, d.Cluster)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "[%s:%s] ", k, labels[k])
+	}
+	unit := ""
+	if unitValue, err := prometheus.ParseUnit(d.Unit); err == nil {
+		unit = unitValue.Show
+	}
+	fmt.Fprintf(&sb, "metric deviates from its seasonal baseline, value: %.1f%s, baseline: %.1f%s, score: %.1f",
+		value, unit, baseline.Median, unit, score)
+
+	labels[prometheus.AlertClusterKey] = d.Cluster
+	labels[prometheus.PromqlNamespaceKey] = d.Namespace
+	labels[prometheus.AlertNamespaceLabel] = d.Namespace
+	labels[prometheus.AlertNameLabel] = d.Name
+	labels[prometheus.AlertFromLabel] = prometheus.AlertTypeAnomaly
+	labels[prometheus.SeverityLabel] = d.Severity
+	return observe.PostableAlert{
+		Labels: labels,
+		Annotations: map[string]string{
+			prometheus.MessageAnnotationsKey: sb.String(),
+			prometheus.ValueAnnotationKey:    strconv.FormatFloat(value, 'f', 1, 64),
+		},
+		// alertmanager 合并告警时保留更早的开始时间
+		StartsAt: start,
+		EndsAt:   now.Add(anomalyAlertTTL),
+	}
+}
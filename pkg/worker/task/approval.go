@@ -0,0 +1,59 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"time"
+
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/approval"
+	"kubegems.io/kubegems/pkg/utils/database"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+)
+
+const TaskFunction_ExpireApprovals = "expire-approvals"
+
+type ApprovalTasker struct {
+	DB *database.Database
+}
+
+func (t *ApprovalTasker) ProvideFuntions() map[string]interface{} {
+	return map[string]interface{}{
+		TaskFunction_ExpireApprovals: t.ExpireApprovals,
+	}
+}
+
+func (t *ApprovalTasker) Crontasks() map[string]Task {
+	return map[string]Task{
+		"@every 5m": {
+			Name:  "expire approvals",
+			Group: "approval",
+			Steps: []workflow.Step{{Function: TaskFunction_ExpireApprovals}},
+		},
+	}
+}
+
+// ExpireApprovals 超过有效期未完成审批的申请设置为过期
+func (t *ApprovalTasker) ExpireApprovals(ctx context.Context) error {
+	count, err := (&approval.Engine{DB: t.DB.DB()}).Expire(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.FromContextOrDiscard(ctx).Info("expired approvals", "count", count)
+	}
+	return nil
+}
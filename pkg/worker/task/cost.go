@@ -0,0 +1,197 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/database"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+)
+
+const (
+	TaskFunction_SampleCosts = "sample-costs"
+
+	// 每次最多统计的小时数, 剩余的下次继续
+	costMaxHoursPerRun = 24
+	costTimeLayout     = "2006-01-02T15:04:05Z"
+)
+
+// 每个命名空间一个小时内的平均用量和请求量
+var costQueries = map[string]string{
+	"cpuUsage":      `sum by (namespace) (avg_over_time(gems_namespace_cpu_usage_cores[1h]))`,
+	"cpuRequest":    `sum by (namespace) (avg_over_time(kube_pod_container_resource_requests{resource="cpu"}[1h]))`,
+	"memoryUsage":   `sum by (namespace) (avg_over_time(gems_namespace_memory_usage_bytes[1h]))`,
+	"memoryRequest": `sum by (namespace) (avg_over_time(kube_pod_container_resource_requests{resource="memory"}[1h]))`,
+	"storage":       `sum by (namespace) (avg_over_time(kube_persistentvolumeclaim_resource_requests_storage_bytes[1h]))`,
+	"gpu":           `sum by (namespace) (avg_over_time(kube_pod_container_resource_requests{resource="nvidia_com_gpu"}[1h]))`,
+}
+
+type CostTasker struct {
+	DB *database.Database
+	cs *agents.ClientSet
+}
+
+func (t *CostTasker) ProvideFuntions() map[string]interface{} {
+	return map[string]interface{}{
+		TaskFunction_SampleCosts: t.SampleCosts,
+	}
+}
+
+func (t *CostTasker) Crontasks() map[string]Task {
+	return map[string]Task{
+		"@every 10m": {
+			Name:  "sample costs",
+			Group: "cost",
+			Steps: []workflow.Step{{Function: TaskFunction_SampleCosts}},
+		},
+	}
+}
+
+// SampleCosts 统计配置了单价的集群中每个环境命名空间的用量, 按小时累加到每天的汇总中
+func (t *CostTasker) SampleCosts(ctx context.Context) error {
+	prices := []*models.CostPrice{}
+	if err := t.DB.DB().WithContext(ctx).Preload("Cluster").Find(&prices).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, price := range prices {
+		if price.Cluster == nil {
+			continue
+		}
+		hours := price.PendingHours(now, costMaxHoursPerRun)
+		if len(hours) == 0 {
+			continue
+		}
+		lastError := ""
+		if err := t.sampleClusterCost(ctx, price, hours); err != nil {
+			log.Error(err, "sample cost", "cluster", price.Cluster.ClusterName)
+			lastError = err.Error()
+		}
+		if lastError != price.LastError {
+			if err := t.DB.DB().WithContext(ctx).Model(price).Omit(clause.Associations).Update("last_error", lastError).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *CostTasker) sampleClusterCost(ctx context.Context, price *models.CostPrice, hours []time.Time) error {
+	cli, err := t.cs.ClientOf(ctx, price.Cluster.ClusterName)
+	if err != nil {
+		return err
+	}
+	start, end := hours[0], hours[len(hours)-1]
+	// hour -> namespace -> sample
+	samples := map[int64]map[string]*models.CostSample{}
+	for name, query := range costQueries {
+		matrix, err := cli.Extend().PrometheusQueryRange(ctx, query,
+			start.UTC().Format(costTimeLayout), end.UTC().Format(costTimeLayout), strconv.Itoa(int(time.Hour.Seconds())))
+		if err != nil {
+			return err
+		}
+		for _, stream := range matrix {
+			namespace := string(stream.Metric["namespace"])
+			for _, v := range stream.Values {
+				value := float64(v.Value)
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					continue
+				}
+				ts := v.Timestamp.Time().Truncate(time.Hour).Unix()
+				if samples[ts] == nil {
+					samples[ts] = map[string]*models.CostSample{}
+				}
+				s := samples[ts][namespace]
+				if s == nil {
+					s = &models.CostSample{}
+					samples[ts][namespace] = s
+				}
+				setCostSample(s, name, value)
+			}
+		}
+	}
+
+	// 只统计属于环境的命名空间
+	envs := []*models.Environment{}
+	if err := t.DB.DB().WithContext(ctx).Preload("Project.Tenant").Find(&envs, "cluster_id = ?", price.ClusterID).Error; err != nil {
+		return err
+	}
+	envMap := map[string]*models.Environment{}
+	for _, env := range envs {
+		if env.Project != nil && env.Project.Tenant != nil {
+			envMap[env.Namespace] = env
+		}
+	}
+
+	return t.DB.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		dailies := map[string]*models.CostDaily{} // date/namespace -> daily
+		for _, hour := range hours {
+			date := models.CostDate(hour)
+			for namespace, s := range samples[hour.Unix()] {
+				env, ok := envMap[namespace]
+				if !ok {
+					continue
+				}
+				key := date + "/" + namespace
+				daily, ok := dailies[key]
+				if !ok {
+					daily = &models.CostDaily{}
+					if err := tx.Where(models.CostDaily{Date: date, ClusterName: price.Cluster.ClusterName, Namespace: namespace}).
+						FirstOrInit(daily).Error; err != nil {
+						return err
+					}
+					dailies[key] = daily
+				}
+				// 环境可能被移动或者重命名, 使用最新的归属
+				daily.TenantName = env.Project.Tenant.TenantName
+				daily.ProjectName = env.Project.ProjectName
+				daily.EnvironmentName = env.EnvironmentName
+				daily.Add(*s, price)
+			}
+		}
+		for _, daily := range dailies {
+			if err := tx.Save(daily).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(price).Omit(clause.Associations).Update("sampled_until", end).Error
+	})
+}
+
+func setCostSample(s *models.CostSample, name string, value float64) {
+	switch name {
+	case "cpuUsage":
+		s.CPUUsageCores = value
+	case "cpuRequest":
+		s.CPURequestCores = value
+	case "memoryUsage":
+		s.MemoryUsageBytes = value
+	case "memoryRequest":
+		s.MemoryRequestBytes = value
+	case "storage":
+		s.StorageBytes = value
+	case "gpu":
+		s.GPUs = value
+	}
+}
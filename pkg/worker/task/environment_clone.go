@@ -0,0 +1,42 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+
+	"kubegems.io/kubegems/pkg/service/handlers/environment"
+	"kubegems.io/kubegems/pkg/utils/agents"
+)
+
+// EnvironmentCloneTasker 克隆环境时异步克隆资源和清理 PVC 数据快照
+type EnvironmentCloneTasker struct {
+	cs *agents.ClientSet
+}
+
+func (t *EnvironmentCloneTasker) ProvideFuntions() map[string]interface{} {
+	return map[string]interface{}{
+		environment.TaskFunction_CloneEnvironmentResources: t.CloneResources,
+		environment.TaskFunction_CleanupCloneSnapshots:     t.CleanupSnapshots,
+	}
+}
+
+func (t *EnvironmentCloneTasker) CloneResources(ctx context.Context, args environment.CloneTaskArgs, kind string) ([]environment.CloneResourceResult, error) {
+	return environment.CloneEnvironmentResources(ctx, t.cs, args, kind)
+}
+
+func (t *EnvironmentCloneTasker) CleanupSnapshots(ctx context.Context, args environment.CloneTaskArgs) ([]environment.CloneResourceResult, error) {
+	return environment.CleanupCloneSnapshots(ctx, t.cs, args)
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/aaa/auth"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/service/models/cache"
+	"kubegems.io/kubegems/pkg/utils/database"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+)
+
+// 定时根据登录源中的组同步用户的租户、项目、环境角色
+type GroupSyncTasker struct {
+	DB    *database.Database
+	Cache cache.ModelCache
+}
+
+const TaskFunction_SyncGroupRoles = "sync-group-roles"
+
+func (t *GroupSyncTasker) ProvideFuntions() map[string]interface{} {
+	return map[string]interface{}{
+		TaskFunction_SyncGroupRoles: t.SyncGroupRoles,
+	}
+}
+
+func (t *GroupSyncTasker) Crontasks() map[string]Task {
+	return map[string]Task{
+		"@every 30m": {
+			Name:  "sync group roles",
+			Group: "authsource",
+			Steps: []workflow.Step{{Function: TaskFunction_SyncGroupRoles}},
+		},
+	}
+}
+
+// SyncGroupRoles LDAP 登录源会重新查询目录中的组; OIDC/SAML 无法离线查询, 使用用户最近一次登录时的组, 以便组映射的变更及时生效
+func (t *GroupSyncTasker) SyncGroupRoles(ctx context.Context) error {
+	log := log.FromContextOrDiscard(ctx)
+	db := t.DB.DB().WithContext(ctx)
+	var sources []models.AuthSource
+	if err := db.Find(&sources, "kind in ? and enabled = ?", []string{"LDAP", "OIDC", "SAML"}, true).Error; err != nil {
+		return err
+	}
+	for i := range sources {
+		source := &sources[i]
+		if !source.Config.SyncGroupRoles {
+			continue
+		}
+		var users []*models.User
+		if err := db.Find(&users, "source = ?", source.Name).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			continue
+		}
+		groups := map[string][]string{}
+		if source.Kind == "LDAP" {
+			usernames := make([]string, 0, len(users))
+			for _, u := range users {
+				usernames = append(usernames, u.Username)
+			}
+			ldapgroups, err := auth.NewLdapLoginUtils(source).SearchUserGroups(ctx, usernames)
+			if err != nil {
+				// 目录不可用时跳过, 避免误回收权限
+				log.Error(err, "search ldap user groups", "source", source.Name)
+				continue
+			}
+			groups = ldapgroups
+		} else {
+			var states []models.UserSourceGroups
+			if err := db.Joins("User").Find(&states, "User.source = ?", source.Name).Error; err != nil {
+				return err
+			}
+			for _, state := range states {
+				if state.User != nil {
+					groups[state.User.Username] = state.Groups
+				}
+			}
+		}
+		for _, u := range users {
+			// 无法确定组的用户(LDAP 匹配到多个条目或从未登录)跳过, 避免误回收权限
+			usergroups, ok := groups[u.Username]
+			if !ok {
+				continue
+			}
+			changed, err := auth.SyncGroupRoles(ctx, t.DB.DB(), u, usergroups)
+			if err != nil {
+				log.Error(err, "sync group roles", "source", source.Name, "user", u.Username)
+				continue
+			}
+			if changed {
+				log.Info("user roles changed by group sync", "source", source.Name, "user", u.Username)
+				if t.Cache != nil {
+					t.Cache.FlushUserAuthority(u)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/errors"
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/database"
+	"kubegems.io/kubegems/pkg/utils/loki"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+)
+
+const (
+	TaskFunction_RunLogExports = "run-log-exports"
+
+	// 每次从 loki 查询的日志条数
+	logExportPageLimit = 5000
+)
+
+type LogExportTasker struct {
+	DB *database.Database
+	cs *agents.ClientSet
+}
+
+func (t *LogExportTasker) ProvideFuntions() map[string]interface{} {
+	return map[string]interface{}{
+		TaskFunction_RunLogExports: t.RunLogExports,
+	}
+}
+
+func (t *LogExportTasker) Crontasks() map[string]Task {
+	return map[string]Task{
+		"@every 5m": {
+			Name:  "run log exports",
+			Group: "logexport",
+			Steps: []workflow.Step{{Function: TaskFunction_RunLogExports}},
+		},
+	}
+}
+
+// RunLogExports 执行到期的日志导出
+func (t *LogExportTasker) RunLogExports(ctx context.Context) error {
+	exports := []*models.LogExport{}
+	if err := t.DB.DB().WithContext(ctx).Find(&exports, "enabled = ?", true).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, e := range exports {
+		if !e.Due(now) {
+			continue
+		}
+		lastError := ""
+		if err := t.runLogExport(ctx, e, now); err != nil {
+			log.Error(err, "run log export", "cluster", e.Cluster, "namespace", e.Namespace, "name", e.Name)
+			lastError = err.Error()
+		}
+		if err := t.DB.DB().WithContext(ctx).Model(e).Updates(map[string]interface{}{
+			"last_run_at": now,
+			"last_error":  lastError,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *LogExportTasker) runLogExport(ctx context.Context, e *models.LogExport, now time.Time) error {
+	cli, err := t.cs.ClientOf(ctx, e.Cluster)
+	if err != nil {
+		return err
+	}
+	store, err := newLogExportStore(ctx, &e.Destination)
+	if err != nil {
+		return err
+	}
+	for _, hour := range e.PendingHours(now) {
+		if err := exportLogHour(ctx, cli, store, e, hour); err != nil {
+			return errors.Wrapf(err, "export logs at %s", hour.Format(time.RFC3339))
+		}
+		// 每导出一个小时记录一次进度, 失败后从这里继续
+		until := hour.Add(time.Hour)
+		if err := t.DB.DB().WithContext(ctx).Model(e).Update("exported_until", until).Error; err != nil {
+			return err
+		}
+		e.ExportedUntil = &until
+	}
+	return cleanExpiredLogExports(ctx, store, e, now)
+}
+
+type logExportEntry struct {
+	Timestamp string            `json:"timestamp"`
+	Labels    map[string]string `json:"labels"`
+	Line      string            `json:"line"`
+
+	ts  int64
+	key string // 日志流和内容, 用于分页时去重
+}
+
+// logExportBoundary 已经导出的最后一个时间戳的日志
+type logExportBoundary struct {
+	ts   int64
+	keys map[string]bool
+}
+
+func (b *logExportBoundary) seen(entry logExportEntry) bool {
+	return entry.ts == b.ts && b.keys[entry.key]
+}
+
+// update 记录一页中最后一个时间戳的日志, 与上一页相同时间戳时合并
+func (b *logExportBoundary) update(entries []logExportEntry) {
+	last := entries[len(entries)-1].ts
+	if last != b.ts || b.keys == nil {
+		b.ts, b.keys = last, map[string]bool{}
+	}
+	for i := len(entries) - 1; i >= 0 && entries[i].ts == last; i-- {
+		b.keys[entries[i].key] = true
+	}
+}
+
+// exportLogHour 分页查询一个小时的日志, 写入 gzip 压缩的 ndjson 文件后上传
+func exportLogHour(ctx context.Context, cli agents.Client, store logExportStore, e *models.LogExport, hour time.Time) error {
+	f, err := os.CreateTemp("", "logexport-*"+models.LogExportObjectSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	enc := json.NewEncoder(gz)
+	query := e.NamespacedQuery()
+	start, end := hour, hour.Add(time.Hour)
+	// 分页时下一页从上一页最后的时间戳开始, 跳过该时间戳已经导出的日志, 避免同一时间戳的日志因分页丢失
+	boundary := &logExportBoundary{}
+	for {
+		data, err := cli.Extend().LokiQueryRangeWithParam(ctx, loki.QueryRangeParam{
+			Query:     query,
+			Start:     strconv.FormatInt(start.UnixNano(), 10),
+			End:       strconv.FormatInt(end.UnixNano(), 10),
+			Direction: "forward",
+			Limit:     strconv.Itoa(logExportPageLimit),
+		})
+		if err != nil {
+			return err
+		}
+		if data.ResultType == loki.ResultTypeMatrix || data.ResultType == loki.ResultTypeVector {
+			return errors.New("metric query can't be exported")
+		}
+		entries := []logExportEntry{}
+		for _, result := range data.Result {
+			m, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var stream loki.Stream
+			stream = stream.ToStruct(m)
+			streamKey, _ := json.Marshal(stream.Labels)
+			for _, value := range stream.Entries {
+				if len(value) < 2 {
+					continue
+				}
+				ts, err := strconv.ParseInt(value[0], 10, 64)
+				if err != nil {
+					continue
+				}
+				entries = append(entries, logExportEntry{
+					Timestamp: time.Unix(0, ts).UTC().Format(time.RFC3339Nano),
+					Labels:    stream.Labels,
+					Line:      value[1],
+					ts:        ts,
+					key:       string(streamKey) + "\x00" + value[1],
+				})
+			}
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].ts < entries[j].ts })
+		written := 0
+		for _, entry := range entries {
+			if boundary.seen(entry) {
+				continue
+			}
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+			written++
+		}
+		if len(entries) < logExportPageLimit {
+			break
+		}
+		last := entries[len(entries)-1].ts
+		boundary.update(entries)
+		if written == 0 {
+			// 同一时间戳的日志超过了分页大小, 无法继续分页, 跳过该时间戳
+			log.Info("too many log entries at the same timestamp", "name", e.Name, "timestamp", last)
+			start = time.Unix(0, last+1)
+			continue
+		}
+		start = time.Unix(0, last)
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key, err := e.ObjectKey(hour)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, key, f)
+}
+
+// cleanExpiredLogExports 删除超过保留时间的导出文件
+func cleanExpiredLogExports(ctx context.Context, store logExportStore, e *models.LogExport, now time.Time) error {
+	if e.Retention == "" {
+		return nil
+	}
+	prefix, err := e.ObjectPrefix()
+	if err != nil {
+		return err
+	}
+	keys, err := store.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if !e.ObjectExpired(key, now) {
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type logExportStore interface {
+	Put(ctx context.Context, key string, f *os.File) error
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func newLogExportStore(ctx context.Context, dest *models.LogExportDestination) (logExportStore, error) {
+	switch dest.Type {
+	case models.LogExportDestinationS3:
+		cfg, err := config.LoadDefaultConfig(ctx,
+			config.WithCredentialsProvider(
+				credentials.NewStaticCredentialsProvider(dest.AccessKeyID, dest.SecretAccessKey, ""),
+			),
+			config.WithEndpointResolverWithOptions(
+				aws.EndpointResolverWithOptionsFunc(
+					func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+						return aws.Endpoint{URL: dest.Endpoint}, nil
+					},
+				),
+			),
+		)
+		if err != nil {
+			return nil, err
+		}
+		cli := s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.Region = dest.Region
+			o.UsePathStyle = true
+		})
+		return &s3LogExportStore{cli: cli, bucket: dest.Bucket}, nil
+	case models.LogExportDestinationPVC:
+		return &localLogExportStore{root: models.LogExportLocalDir}, nil
+	default:
+		return nil, errors.Errorf("destination type %s not supported", dest.Type)
+	}
+}
+
+type s3LogExportStore struct {
+	cli    *s3.Client
+	bucket string
+}
+
+func (s *s3LogExportStore) Put(ctx context.Context, key string, f *os.File) error {
+	_, err := s.cli.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		Body:            f,
+		ContentType:     aws.String("application/x-ndjson"),
+		ContentEncoding: aws.String("gzip"),
+	})
+	return err
+}
+
+func (s *s3LogExportStore) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	paginator := s3.NewListObjectsV2Paginator(s.cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
+
+func (s *s3LogExportStore) Delete(ctx context.Context, key string) error {
+	_, err := s.cli.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// localLogExportStore 导出到 worker 挂载的 pvc
+type localLogExportStore struct {
+	root string
+}
+
+func (s *localLogExportStore) Put(ctx context.Context, key string, f *os.File) error {
+	target := filepath.Join(s.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名, 避免留下不完整的文件
+	tmp := target + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, f); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+func (s *localLogExportStore) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	dir := filepath.Join(s.root, filepath.FromSlash(prefix))
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	return keys, err
+}
+
+func (s *localLogExportStore) Delete(ctx context.Context, key string) error {
+	return os.Remove(filepath.Join(s.root, filepath.FromSlash(key)))
+}
@@ -0,0 +1,229 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
+	alertmanagertypes "github.com/prometheus/alertmanager/types"
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/service/observe"
+	"kubegems.io/kubegems/pkg/utils"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/database"
+	"kubegems.io/kubegems/pkg/utils/prometheus"
+	"kubegems.io/kubegems/pkg/utils/prometheus/promql"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+)
+
+const (
+	TaskFunction_SyncMaintenanceWindow = "sync-maintenance-window"
+
+	// 提前创建即将开始的静默, 需要大于同步周期
+	maintenanceLookahead = 2 * time.Minute
+)
+
+type MaintenanceWindowTasker struct {
+	DB *database.Database
+	cs *agents.ClientSet
+}
+
+func (t *MaintenanceWindowTasker) ProvideFuntions() map[string]interface{} {
+	return map[string]interface{}{
+		TaskFunction_SyncMaintenanceWindow: t.SyncMaintenanceWindow,
+	}
+}
+
+func (t *MaintenanceWindowTasker) Crontasks() map[string]Task {
+	return map[string]Task{
+		"@every 1m": {
+			Name:  "sync maintenance window",
+			Group: "alertrule",
+			Steps: []workflow.Step{{Function: TaskFunction_SyncMaintenanceWindow}},
+		},
+	}
+}
+
+// maintenanceSilenceComment 静默规则的注释, 包含窗口的更新时间, 窗口修改后重新创建静默
+func maintenanceSilenceComment(w *models.MaintenanceWindow, start time.Time) string {
+	var updated int64
+	if w.UpdatedAt != nil {
+		updated = w.UpdatedAt.Unix()
+	}
+	return fmt.Sprintf("%s%d-%d-%d", prometheus.SilenceCommentForMaintenancePrefix, w.ID, start.Unix(), updated)
+}
+
+func maintenanceSilence(w *models.MaintenanceWindow, start, end time.Time) alertmanagertypes.Silence {
+	silence := alertmanagertypes.Silence{
+		StartsAt:  start,
+		EndsAt:    end,
+		CreatedBy: w.Creator,
+	}
+	for _, m := range w.SilenceMatchers(prometheus.AlertNamespaceLabel, prometheus.AlertNameLabel) {
+		matcher := &labels.Matcher{Name: m.Name, Value: m.Value}
+		switch m.Type {
+		case promql.MatchNotEqual:
+			matcher.Type = labels.MatchNotEqual
+		case promql.MatchRegexp:
+			matcher.Type = labels.MatchRegexp
+		case promql.MatchNotRegexp:
+			matcher.Type = labels.MatchNotRegexp
+		default:
+			matcher.Type = labels.MatchEqual
+		}
+		silence.Matchers = append(silence.Matchers, matcher)
+	}
+	return silence
+}
+
+// SyncMaintenanceWindow 为进行中和即将开始的维护窗口创建静默, 过期已删除或修改的窗口的静默, 并记录被静默的告警
+func (t *MaintenanceWindowTasker) SyncMaintenanceWindow(ctx context.Context) error {
+	windows := []*models.MaintenanceWindow{}
+	if err := t.DB.DB().WithContext(ctx).Find(&windows, "enabled = ?", true).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	desired := map[string]map[string]alertmanagertypes.Silence{} // cluster -> comment -> silence
+	windowNames := map[string]string{}                           // comment -> window name
+	for _, w := range windows {
+		start, end, ok := w.Occurrence(now)
+		if !ok || start.After(now.Add(maintenanceLookahead)) {
+			continue
+		}
+		// 注释使用本次维护的开始时间, 保证同一次维护只创建一个静默
+		comment := maintenanceSilenceComment(w, start)
+		if start.Before(now) {
+			// alertmanager 不允许创建开始时间早于当前时间的静默
+			start = now
+		}
+		silence := maintenanceSilence(w, start, end)
+		silence.Comment = comment
+		if desired[w.Cluster] == nil {
+			desired[w.Cluster] = map[string]alertmanagertypes.Silence{}
+		}
+		desired[w.Cluster][silence.Comment] = silence
+		windowNames[silence.Comment] = w.Name
+	}
+
+	clusterNS2EnvMap, err := t.DB.ClusterNS2EnvMap()
+	if err != nil {
+		log.Error(err, "get ClusterNS2EnvMap")
+	}
+	return t.cs.ExecuteInEachCluster(ctx, func(ctx context.Context, cli agents.Client) error {
+		observecli := observe.NewClient(cli, t.DB.DB())
+		existing, err := observecli.ListSilencesByCommentPrefix(ctx, prometheus.SilenceCommentForMaintenancePrefix)
+		if err != nil {
+			log.Warnf("list maintenance silences in cluster: %s failed, %v", cli.Name(), err)
+			return nil
+		}
+		want := desired[cli.Name()]
+		silenceWindows := map[string]string{} // silence id -> window name
+		existingComments := map[string]bool{}
+		for _, s := range existing {
+			if _, ok := want[s.Comment]; !ok {
+				if err := observecli.ExpireSilence(ctx, s.ID); err != nil {
+					log.Error(err, "expire maintenance silence", "cluster", cli.Name(), "comment", s.Comment)
+				}
+				continue
+			}
+			existingComments[s.Comment] = true
+			silenceWindows[s.ID] = windowNames[s.Comment]
+		}
+		for comment, s := range want {
+			if existingComments[comment] {
+				continue
+			}
+			if err := observecli.CreateSilence(ctx, s); err != nil {
+				log.Error(err, "create maintenance silence", "cluster", cli.Name(), "comment", comment)
+			}
+		}
+		if len(silenceWindows) == 0 {
+			return nil
+		}
+		return t.recordSilencedAlerts(ctx, cli.Name(), observecli, silenceWindows, clusterNS2EnvMap)
+	})
+}
+
+// recordSilencedAlerts 被维护窗口静默的告警不会发送通知, 单独记录到告警历史中
+func (t *MaintenanceWindowTasker) recordSilencedAlerts(ctx context.Context, cluster string, observecli *observe.ObserveClient,
+	silenceWindows map[string]string, clusterNS2EnvMap map[string]database.EnvInfo,
+) error {
+	alerts, err := observecli.ListSilencedAlerts(ctx)
+	if err != nil {
+		log.Warnf("list silenced alerts in cluster: %s failed, %v", cluster, err)
+		return nil
+	}
+	now := time.Now()
+	for _, alert := range alerts {
+		window := ""
+		for _, id := range alert.Status.SilencedBy {
+			if name, ok := silenceWindows[id]; ok {
+				window = name
+				break
+			}
+		}
+		if window == "" || alert.Labels[prometheus.AlertNameLabel] == "" {
+			continue
+		}
+		var count int64
+		if err := t.DB.DB().WithContext(ctx).Model(&models.AlertMessage{}).
+			Where("fingerprint = ? and starts_at = ? and maintenance_window = ?", alert.Fingerprint, alert.StartsAt, window).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		clusterName := alert.Labels[prometheus.AlertClusterKey]
+		if clusterName == "" {
+			clusterName = cluster
+		}
+		labelbyts, _ := json.Marshal(alert.Labels)
+		envinfo := clusterNS2EnvMap[fmt.Sprintf("%s/%s", clusterName, alert.Labels[prometheus.AlertNamespaceLabel])]
+		info := models.AlertInfo{
+			Fingerprint:     alert.Fingerprint,
+			Name:            alert.Labels[prometheus.AlertNameLabel],
+			Namespace:       alert.Labels[prometheus.AlertNamespaceLabel],
+			ClusterName:     clusterName,
+			TenantName:      envinfo.TenantName,
+			ProjectName:     envinfo.ProjectName,
+			EnvironmentName: envinfo.EnvironmentName,
+			Labels:          labelbyts,
+		}
+		if err := t.DB.DB().WithContext(ctx).Save(&info).Error; err != nil {
+			return err
+		}
+		msg := models.AlertMessage{
+			InfoFingerprint:   alert.Fingerprint,
+			Value:             alert.Annotations["value"],
+			Message:           strings.TrimSpace(alert.Annotations["message"]),
+			StartsAt:          utils.TimeZeroToNull(alert.StartsAt),
+			EndsAt:            utils.TimeZeroToNull(alert.EndsAt),
+			CreatedAt:         &now,
+			Status:            "firing",
+			MaintenanceWindow: window,
+		}
+		if err := t.DB.DB().WithContext(ctx).Create(&msg).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The kubegems.io Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"time"
+
+	"kubegems.io/kubegems/pkg/log"
+	"kubegems.io/kubegems/pkg/service/models"
+	"kubegems.io/kubegems/pkg/service/report"
+	"kubegems.io/kubegems/pkg/utils/agents"
+	"kubegems.io/kubegems/pkg/utils/database"
+	"kubegems.io/kubegems/pkg/utils/workflow"
+)
+
+const TaskFunction_SendReports = "send-reports"
+
+type ReportTasker struct {
+	DB *database.Database
+	cs *agents.ClientSet
+}
+
+func (t *ReportTasker) ProvideFuntions() map[string]interface{} {
+	return map[string]interface{}{
+		TaskFunction_SendReports: t.SendReports,
+	}
+}
+
+func (t *ReportTasker) Crontasks() map[string]Task {
+	return map[string]Task{
+		"@every 1m": {
+			Name:  "send reports",
+			Group: "report",
+			Steps: []workflow.Step{{Function: TaskFunction_SendReports}},
+		},
+	}
+}
+
+// SendReports 发送到期的定时报告
+func (t *ReportTasker) SendReports(ctx context.Context) error {
+	reports := []*models.Report{}
+	if err := t.DB.DB().WithContext(ctx).Find(&reports, "enabled = ?", true).Error; err != nil {
+		return err
+	}
+	reporter := &report.Reporter{DB: t.DB.DB(), Agents: t.cs}
+	now := time.Now()
+	for _, r := range reports {
+		if !r.Due(now) {
+			continue
+		}
+		lastError := ""
+		if err := reporter.Send(ctx, r, now); err != nil {
+			log.Error(err, "send report", "tenant", r.TenantID, "name", r.Name)
+			lastError = err.Error()
+		}
+		if err := t.DB.DB().WithContext(ctx).Model(r).Updates(map[string]interface{}{
+			"last_run_at": now,
+			"last_error":  lastError,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/go-redsync/redsync/v4"
 	"github.com/go-redsync/redsync/v4/redis/goredis/v8"
 	"golang.org/x/sync/errgroup"
+	"kubegems.io/kubegems/pkg/service/models/cache"
 	"kubegems.io/kubegems/pkg/utils/agents"
 	"kubegems.io/kubegems/pkg/utils/argo"
 	"kubegems.io/kubegems/pkg/utils/database"
@@ -70,6 +71,10 @@ func Run(ctx context.Context,
 		backend = workflow.NewInmemoryBackend(ctx)
 	}
 	workflowcli := workflow.NewClientFromBackend(backend)
+	var modelCache cache.ModelCache
+	if rediscli != nil {
+		modelCache = cache.NewRedisModelCache(db.DB(), rediscli)
+	}
 	p := &ProcessorContext{
 		server:    workflow.NewServerFromBackend(backend),
 		client:    workflow.NewCronSubmiter(workflowcli),
@@ -90,6 +95,22 @@ func Run(ctx context.Context,
 		&ClusterSyncTasker{DB: db, cs: agents},
 		// alertrule
 		&AlertRuleSyncTasker{DB: db, cs: agents},
+		// 维护窗口静默
+		&MaintenanceWindowTasker{DB: db, cs: agents},
+		// 指标异常检测
+		&AnomalyDetectionTasker{DB: db, cs: agents},
+		// 定时日志导出
+		&LogExportTasker{DB: db, cs: agents},
+		// 定时报告
+		&ReportTasker{DB: db, cs: agents},
+		// 费用统计
+		&CostTasker{DB: db, cs: agents},
+		// 审批过期
+		&ApprovalTasker{DB: db},
+		// 登录源组到角色的同步
+		&GroupSyncTasker{DB: db, Cache: modelCache},
+		// 环境克隆
+		&EnvironmentCloneTasker{cs: agents},
 	}
 	if err := p.RegisterTasker(taskers...); err != nil {
 		return err